@@ -2,10 +2,17 @@ package ai
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strings"
 
 	"github.com/arpxspace/smartcommit/internal/config"
+	"github.com/arpxspace/smartcommit/internal/logging"
+	"github.com/arpxspace/smartcommit/internal/network"
 
 	"github.com/invopop/jsonschema"
 	"github.com/openai/openai-go"
@@ -16,26 +23,227 @@ import (
 type Provider interface {
 	GenerateQuestions(ctx context.Context, diff string, history string) ([]string, error)
 	GenerateCommitMessage(ctx context.Context, diff string, history string, answers map[string]string) (string, error)
+	// GenerateCommitMessageStream is GenerateCommitMessage, reporting the
+	// message as it arrives. The channel is closed after the Done chunk.
+	GenerateCommitMessageStream(ctx context.Context, diff string, history string, answers map[string]string) <-chan StreamChunk
 	AnalyzeHistory(ctx context.Context, diff string, history string) (*HistoryAnalysisResponse, error)
+	// SummarizeDiff condenses one part of a diff too large to send whole.
+	SummarizeDiff(ctx context.Context, diff string, part, total int) (string, error)
+	// ScoreMessage judges a finished commit message against its diff.
+	ScoreMessage(ctx context.Context, diff string, message string) (*ScoreResponse, error)
+	// ProposeSplit suggests how to split numbered hunks into logical commits.
+	ProposeSplit(ctx context.Context, hunks string) (*SplitResponse, error)
+	// CritiqueMessage reviews a message the user wrote against its diff.
+	CritiqueMessage(ctx context.Context, diff string, message string) (*CritiqueResponse, error)
+	// ShortenSubject rewrites the subject line of message to at most limit
+	// characters, returning the new subject.
+	ShortenSubject(ctx context.Context, message string, limit int) (string, error)
+	// RewriteSentence rewrites one vague sentence of message to say
+	// specifically what diff changes, returning the new sentence.
+	RewriteSentence(ctx context.Context, diff string, message string, sentence string) (string, error)
+	// DescribePullRequest writes a pull request title and description for
+	// a branch from its commits and cumulative diff.
+	DescribePullRequest(ctx context.Context, diff string, commits string) (*PullRequestResponse, error)
+	// ExplainCommits explains in plain language what existing commits do
+	// and why, from their messages and cumulative diff.
+	ExplainCommits(ctx context.Context, diff string, commits string) (*ExplanationResponse, error)
+	// WriteReleaseNotes turns commits grouped by type into release notes.
+	WriteReleaseNotes(ctx context.Context, commits string) (*ReleaseNotesResponse, error)
+	// SuggestBranchNames proposes names for a branch for the changes in
+	// diff or the work ticket describes; either may be empty.
+	SuggestBranchNames(ctx context.Context, diff string, ticket string) (*BranchNamesResponse, error)
+	// Embed returns an embedding of each text from the embedding model, in
+	// the same order.
+	Embed(ctx context.Context, texts []string) ([][]float64, error)
+	// PreviewPrompts returns the prompts each stage would send, without sending them.
+	PreviewPrompts(diff string, history string, answers map[string]string) []Prompt
+	// Model returns the name of the model requests are sent to.
+	Model() string
+	// Usage returns the tokens used by this client so far.
+	Usage() Usage
+	// Retrying reports the retry a request is waiting for, if any.
+	Retrying() (RetryStatus, bool)
+}
+
+// Usage counts the tokens a client has used, as reported by the provider.
+type Usage struct {
+	Requests         int
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// Sub returns the usage accrued since before.
+func (u Usage) Sub(before Usage) Usage {
+	return Usage{
+		Requests:         u.Requests - before.Requests,
+		PromptTokens:     u.PromptTokens - before.PromptTokens,
+		CompletionTokens: u.CompletionTokens - before.CompletionTokens,
+	}
 }
 
 // NewClient creates a new AI provider based on the configuration.
 func NewClient(cfg *config.Config) (Provider, error) {
+	base, err := transport(cfg)
+	if err != nil {
+		return nil, err
+	}
+	retry := newRetryTransport(base, cfg.Retries(), cfg.Timeout())
+	p, err := newClient(cfg, requestOptions(&http.Client{Transport: retry}))
+	if err != nil {
+		return nil, err
+	}
+	if c, ok := p.(interface{ watchRetries(*retryTransport) }); ok {
+		c.watchRetries(retry)
+	}
+	lookupContextWindow(cfg, base)
+	dir, err := config.Dir()
+	if err != nil {
+		return nil, err
+	}
+	t, err := loadTemplates(filepath.Join(dir, PromptTemplatesDir))
+	if err != nil {
+		return nil, err
+	}
+	if c, ok := p.(interface{ usePrompts(templates) }); ok {
+		c.usePrompts(t)
+	}
+	if n := cfg.Questions(); n != config.DefaultQuestionCount || cfg.AdaptiveQuestions {
+		if c, ok := p.(interface{ askQuestions(n int, adaptive bool) }); ok {
+			c.askQuestions(n, cfg.AdaptiveQuestions)
+		}
+	}
+	if err := cfg.Generation.Check(); err != nil {
+		return nil, err
+	}
+	if c, ok := p.(interface{ generateWith(config.Generation) }); ok {
+		c.generateWith(cfg.Generation)
+	}
+	if cfg.Language != "" {
+		if c, ok := p.(interface{ writeIn(language string) }); ok {
+			c.writeIn(cfg.Language)
+		}
+	}
+	switch cfg.Depth {
+	case "":
+	case config.DepthBrief, config.DepthDeep:
+		if c, ok := p.(interface{ digTo(depth string) }); ok {
+			c.digTo(cfg.Depth)
+		}
+	default:
+		return nil, fmt.Errorf("unknown depth %q; use %q or %q", cfg.Depth, config.DepthBrief, config.DepthDeep)
+	}
+	if cfg.Examples() > 0 || cfg.RelatedHistory {
+		if c, ok := p.(interface{ embedWith(model string) }); ok {
+			c.embedWith(cfg.Embedder())
+		}
+	}
+	switch cfg.MessageStyle {
+	case "", config.MessageStyleConventional:
+		if cfg.Conventions.Enabled() {
+			if c, ok := p.(interface{ constrain(types, scopes []string) }); ok {
+				c.constrain(cfg.Conventions.AllowedTypes(), cfg.Conventions.Scopes)
+			}
+		}
+	case config.MessageStyleGitmoji:
+		if c, ok := p.(interface {
+			useGitmoji(table map[string]string, types, scopes []string)
+		}); ok {
+			c.useGitmoji(cfg.GitmojiTable(), cfg.Conventions.AllowedTypes(), cfg.Conventions.Scopes)
+		}
+	default:
+		return nil, fmt.Errorf("unknown message_style %q; use %q or %q", cfg.MessageStyle, config.MessageStyleConventional, config.MessageStyleGitmoji)
+	}
+	return p, nil
+}
+
+func newClient(cfg *config.Config, opts []option.RequestOption) (Provider, error) {
 	switch cfg.Provider {
 	case config.ProviderOpenAI:
-		return NewOpenAIClient(cfg.OpenAIAPIKey), nil
+		return NewOpenAIClient(cfg.OpenAIAPIKey, cfg.Model(), opts...), nil
 	case config.ProviderOllama:
-		return NewOllamaClient(cfg.OllamaURL, cfg.OllamaModel), nil
+		c := NewOllamaClient(cfg.OllamaURL, cfg.OllamaModel, opts...)
+		if s := cfg.OllamaStructuredOutput; s != nil {
+			c.schemaless = !*s
+			c.fallback = !*s
+		}
+		return c, nil
+	case config.ProviderMock:
+		return NewMockClient(cfg.MockFixtures)
+	case config.ProviderAzure:
+		if cfg.AzureEndpoint == "" || cfg.AzureDeployment == "" || cfg.AzureAPIKey == "" {
+			return nil, fmt.Errorf("azure provider needs azure_endpoint, azure_deployment, and azure_api_key (or AZURE_OPENAI_API_KEY) in the config")
+		}
+		return NewAzureClient(cfg.AzureEndpoint, cfg.AzureDeployment, cfg.AzureAPIVersion, cfg.AzureAPIKey, opts...), nil
+	case config.ProviderOpenRouter:
+		if cfg.OpenRouterAPIKey == "" {
+			return nil, fmt.Errorf("openrouter provider needs openrouter_api_key (or OPENROUTER_API_KEY)")
+		}
+		return NewOpenRouterClient(cfg.OpenRouterAPIKey, cfg.Model(), opts...), nil
+	case config.ProviderCustom:
+		if cfg.CustomURL == "" || cfg.CustomModel == "" {
+			return nil, fmt.Errorf("custom provider needs custom_url and custom_model in the config")
+		}
+		return NewCompatibleClient(cfg.CustomURL, cfg.CustomAPIKey, cfg.CustomModel, cfg.CustomStructuredOutput, opts...), nil
 	default:
 		// Default to OpenAI if unknown, or error?
 		// For backward compatibility, if key is present, assume OpenAI.
 		if cfg.OpenAIAPIKey != "" {
-			return NewOpenAIClient(cfg.OpenAIAPIKey), nil
+			return NewOpenAIClient(cfg.OpenAIAPIKey, config.DefaultOpenAIModel, opts...), nil
 		}
 		return nil, fmt.Errorf("unknown provider: %s", cfg.Provider)
 	}
 }
 
+// LookupContextWindow asks the provider for the context window of the
+// configured model and sets cfg.ModelWindow to it, when the provider can
+// tell and context_window doesn't override it. Only Ollama can; a failed
+// lookup is logged and leaves the window to the table of known models.
+func LookupContextWindow(cfg *config.Config) {
+	base, err := transport(cfg)
+	if err != nil {
+		return
+	}
+	lookupContextWindow(cfg, base)
+}
+
+func lookupContextWindow(cfg *config.Config, t http.RoundTripper) {
+	if cfg.Provider != config.ProviderOllama || cfg.ContextWindow > 0 || cfg.ModelWindow > 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), ollamaShowTimeout)
+	defer cancel()
+	n, err := OllamaContextLength(ctx, &http.Client{Transport: t}, cfg.OllamaURL, cfg.OllamaModel)
+	if err != nil {
+		logging.Warn("context window lookup failed", "model", cfg.OllamaModel, "err", err)
+		return
+	}
+	cfg.ModelWindow = n
+}
+
+// transport returns the round tripper provider requests are sent through,
+// before retries: the network's, as the config sets it up.
+func transport(cfg *config.Config) (http.RoundTripper, error) {
+	t, err := network.Transport(cfg.Network)
+	if err != nil {
+		return nil, err
+	}
+	base := logTransport{base: t}
+	if cfg.TraceFile != "" {
+		return newTraceTransport(base, cfg.TraceFile, cfg.OpenAIAPIKey, cfg.AzureAPIKey, cfg.OpenRouterAPIKey, cfg.CustomAPIKey), nil
+	}
+	return base, nil
+}
+
+// requestOptions returns the client options shared by every provider,
+// which send their requests with client. Retries are left to the client's
+// transport so they can be reported, not to the SDK.
+func requestOptions(client *http.Client) []option.RequestOption {
+	return []option.RequestOption{
+		option.WithHTTPClient(client),
+		option.WithMaxRetries(0),
+	}
+}
+
 // GenerateSchema creates a JSON schema for a given type T.
 // This is used for OpenAI Structured Outputs.
 func GenerateSchema[T any]() interface{} {
@@ -51,67 +259,59 @@ func GenerateSchema[T any]() interface{} {
 // --- OpenAI Implementation ---
 
 type OpenAIClient struct {
-	client *openai.Client
+	chat
+}
+
+// NewOpenAIClient creates a client for model on OpenAI. Requests are
+// adapted to the model's family: reasoning models take the system prompt
+// as developer instructions, and models without Structured Outputs get the
+// schema in the prompt.
+func NewOpenAIClient(apiKey, model string, opts ...option.RequestOption) *OpenAIClient {
+	client := openai.NewClient(append([]option.RequestOption{option.WithAPIKey(apiKey)}, opts...)...)
+	c := &OpenAIClient{
+		chat: chat{client: &client, model: model},
+	}
+	c.useTraits(openAITraits(model))
+	return c
 }
 
-func NewOpenAIClient(apiKey string) *OpenAIClient {
-	client := openai.NewClient(option.WithAPIKey(apiKey))
+// NewAzureClient creates a client for an Azure OpenAI deployment. Azure
+// serves the same API under a per-deployment path, authenticated with an
+// api-key header, so it's an OpenAI client with a different base URL.
+func NewAzureClient(endpoint, deployment, apiVersion, apiKey string, opts ...option.RequestOption) *OpenAIClient {
+	if apiVersion == "" {
+		apiVersion = config.DefaultAzureAPIVersion
+	}
+	baseURL := strings.TrimRight(endpoint, "/") + "/openai/deployments/" + url.PathEscape(deployment) + "/"
+	client := openai.NewClient(append([]option.RequestOption{
+		option.WithBaseURL(baseURL),
+		option.WithQuery("api-version", apiVersion),
+		option.WithHeader("api-key", apiKey),
+		// Don't forward OpenAI credentials picked up from the environment.
+		option.WithHeaderDel("authorization"),
+		option.WithHeaderDel("openai-organization"),
+		option.WithHeaderDel("openai-project"),
+	}, opts...)...)
 	return &OpenAIClient{
-		client: &client,
+		chat: chat{client: &client, model: deployment},
 	}
 }
 
 type QuestionsResponse struct {
-	Questions []string `json:"questions" jsonschema_description:"A list of 3 short, specific questions to ask the user to clarify the intent and 'why' behind the changes."`
+	Questions []string `json:"questions" jsonschema_description:"A list of short, specific questions to ask the user to clarify the intent and 'why' behind the changes."`
 }
 
 // Generate the JSON schema at initialization time
 var QuestionsResponseSchema = GenerateSchema[QuestionsResponse]()
 
-func (c *OpenAIClient) GenerateQuestions(ctx context.Context, diff string, history string) ([]string, error) {
-	systemPrompt := `
-You are an expert software developer assisting a user in writing a commit message.
-Your goal is to understand the "why" behind the changes.
-Analyze the provided git diff and recent project history.
-Generate 3 short, specific questions to ask the user to clarify the intent and 'why' behind the changes.
-The questions should focus on the "why" and "how" if it's not obvious. Try to look at the changes holistically and
-not get fixated on irrelevant changes that aren't worth getting clarification from.
-(Example: "Why did you decide to comment out the line regarding array initialization")
-
-`
-
-	userPrompt := fmt.Sprintf("Diff:\n%s\n\nRecent History:\n%s", diff, history)
-
-	schemaParam := openai.ResponseFormatJSONSchemaJSONSchemaParam{
-		Name:        "questions_response",
-		Description: openai.String("List of clarifying questions"),
-		Schema:      QuestionsResponseSchema,
-		Strict:      openai.Bool(true),
-	}
-
-	resp, err := c.client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
-		Messages: []openai.ChatCompletionMessageParamUnion{
-			openai.SystemMessage(systemPrompt),
-			openai.UserMessage(userPrompt),
-		},
-		Model: openai.ChatModelGPT4o2024_08_06,
-		ResponseFormat: openai.ChatCompletionNewParamsResponseFormatUnion{
-			OfJSONSchema: &openai.ResponseFormatJSONSchemaParam{
-				JSONSchema: schemaParam,
-			},
-		},
-	})
-
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate questions: %w", err)
-	}
-
-	var result QuestionsResponse
-	if err := json.Unmarshal([]byte(resp.Choices[0].Message.Content), &result); err != nil {
-		return nil, fmt.Errorf("failed to parse JSON response: %w", err)
-	}
+var questionsSchema = responseSchema{
+	Name:        "questions_response",
+	Description: "List of clarifying questions",
+	Schema:      QuestionsResponseSchema,
+}
 
-	return result.Questions, nil
+func (c *OpenAIClient) GenerateQuestions(ctx context.Context, diff string, history string) ([]string, error) {
+	return c.generateQuestions(ctx, openAIQuestionsPrompt, diff, history)
 }
 
 type CommitMessageResponse struct {
@@ -122,163 +322,332 @@ type CommitMessageResponse struct {
 // Generate the JSON schema at initialization time
 var CommitMessageResponseSchema = GenerateSchema[CommitMessageResponse]()
 
+var commitMessageSchema = responseSchema{
+	Name:        "commit_message_response",
+	Description: "A structured commit message",
+	Schema:      CommitMessageResponseSchema,
+}
+
+// ConventionalMessageResponse is CommitMessageResponse with the subject
+// split into its parts, so the type and scope can be restricted to the
+// project's allowed values.
+type ConventionalMessageResponse struct {
+	Type        string `json:"type" jsonschema_description:"The Conventional Commits type of the change."`
+	Scope       string `json:"scope" jsonschema_description:"The scope of the change, or an empty string for none."`
+	Breaking    bool   `json:"breaking" jsonschema_description:"Whether the change breaks backward compatibility."`
+	Description string `json:"description" jsonschema_description:"The subject line's description, which follows the type and scope."`
+	Body        string `json:"body" jsonschema_description:"The detailed commit message body explaining the 'what' and 'why'."`
+}
+
+// conventionalMessageSchema restricts the type and scope of a message to
+// the given values. No scopes allows any scope.
+func conventionalMessageSchema(types, scopes []string) responseSchema {
+	schema := GenerateSchema[ConventionalMessageResponse]().(*jsonschema.Schema)
+	if prop, ok := schema.Properties.Get("type"); ok {
+		prop.Enum = enum(types)
+	}
+	if prop, ok := schema.Properties.Get("scope"); ok && len(scopes) > 0 {
+		prop.Enum = enum(append([]string{""}, scopes...))
+	}
+	return responseSchema{
+		Name:        "conventional_commit_message_response",
+		Description: "A commit message following the project's Conventional Commits rules",
+		Schema:      schema,
+	}
+}
+
+// GitmojiMessageResponse is ConventionalMessageResponse with the gitmoji
+// that leads the subject.
+type GitmojiMessageResponse struct {
+	Emoji       string `json:"emoji" jsonschema_description:"The gitmoji for the type of the change, which leads the subject line."`
+	Type        string `json:"type" jsonschema_description:"The Conventional Commits type of the change."`
+	Scope       string `json:"scope" jsonschema_description:"The scope of the change, or an empty string for none."`
+	Breaking    bool   `json:"breaking" jsonschema_description:"Whether the change breaks backward compatibility."`
+	Description string `json:"description" jsonschema_description:"The subject line's description, which follows the type and scope."`
+	Body        string `json:"body" jsonschema_description:"The detailed commit message body explaining the 'what' and 'why'."`
+}
+
+// gitmojiMessageSchema restricts the emoji of a message to those in table,
+// and its type and scope as conventionalMessageSchema does.
+func gitmojiMessageSchema(table map[string]string, types, scopes []string) responseSchema {
+	schema := GenerateSchema[GitmojiMessageResponse]().(*jsonschema.Schema)
+	if prop, ok := schema.Properties.Get("emoji"); ok {
+		emojis := slices.Sorted(maps.Values(table))
+		prop.Enum = enum(slices.Compact(emojis))
+		var mapping []string
+		for _, t := range slices.Sorted(maps.Keys(table)) {
+			mapping = append(mapping, table[t]+" "+t)
+		}
+		prop.Description += " By type: " + strings.Join(mapping, ", ") + "."
+	}
+	if prop, ok := schema.Properties.Get("type"); ok {
+		prop.Enum = enum(types)
+	}
+	if prop, ok := schema.Properties.Get("scope"); ok && len(scopes) > 0 {
+		prop.Enum = enum(append([]string{""}, scopes...))
+	}
+	return responseSchema{
+		Name:        "gitmoji_commit_message_response",
+		Description: "A commit message with a gitmoji before its Conventional Commits subject",
+		Schema:      schema,
+	}
+}
+
+func enum(values []string) []any {
+	out := make([]any, len(values))
+	for i, v := range values {
+		out[i] = v
+	}
+	return out
+}
+
 func (c *OpenAIClient) GenerateCommitMessage(ctx context.Context, diff string, history string, answers map[string]string) (string, error) {
-	systemPrompt := `
-You are an expert software developer.
-Generate a commit message following the Conventional Commits specification.
-Use the provided diff, recent project history, and user answers to context questions.
-The commit message should have a clear subject line and a detailed body explaining the "why" only.
-Try to paint a narrative (using the history of the project inline with the recent changes), rather than a prescriptive description.
-Think of the signal:noise ratio. You want the reader of the commit to truly understand the 'why' behind the changes.
-Ensure the tone is professional and consistent with the project history.
+	return c.generateCommitMessage(ctx, openAICommitMessagePrompt, diff, history, answers)
+}
+
+func (c *OpenAIClient) GenerateCommitMessageStream(ctx context.Context, diff string, history string, answers map[string]string) <-chan StreamChunk {
+	return c.generateCommitMessageStream(ctx, openAICommitMessagePrompt, diff, history, answers)
+}
+
+type HistoryAnalysisResponse struct {
+	IsRelevant bool     `json:"is_relevant" jsonschema_description:"Whether the recent history is relevant to the current changes."`
+	KeyContext []string `json:"key_context" jsonschema_description:"A list of key context points from the history that are relevant to the current changes."`
+}
 
-DO NOT:
-- Describe what's in the diff
-- Use marketing language
-- Be verbose
+// Generate the JSON schema at initialization time
+var HistoryAnalysisResponseSchema = GenerateSchema[HistoryAnalysisResponse]()
+
+var historyAnalysisSchema = responseSchema{
+	Name:        "history_analysis_response",
+	Description: "Analysis of project history relevance",
+	Schema:      HistoryAnalysisResponseSchema,
+}
 
-Examples:
-1. Comprehensive commit message
-fix: convert template to US-ASCII to fix error
-While working on a feature branch, I added test coverage for
-'/etc/nginx/router_routes.conf'. Running 'bundle exec rake spec' or
-'bundle exec rspec modules/router/spec' worked perfectly, but executing
-'bundle exec rake' caused every test block to fail with:
+func (c *OpenAIClient) AnalyzeHistory(ctx context.Context, diff string, history string) (*HistoryAnalysisResponse, error) {
+	return c.analyzeHistory(ctx, openAIHistoryPrompt, diff, history)
+}
 
-    ArgumentError:
-      invalid byte sequence in US-ASCII
+type ScoreResponse struct {
+	WhyCoverage int    `json:"why_coverage" jsonschema_description:"0-10: how well the message explains why the change was made."`
+	Clarity     int    `json:"clarity" jsonschema_description:"0-10: how concise and specific the message is."`
+	Convention  int    `json:"convention" jsonschema_description:"0-10: how well the message follows Conventional Commits and subject-line conventions."`
+	Cohesion    int    `json:"cohesion" jsonschema_description:"0-10: how well the diff holds one logical change that the message covers as a whole."`
+	Verdict     string `json:"verdict" jsonschema_description:"A one-sentence verdict naming the single most useful improvement."`
+}
 
-After some investigation, I discovered that deleting the '.with_content(//)'
-matchers eliminated the failures. The spec file itself appeared clean - no
-visible unusual characters. I could trigger the same issue by loading Puppet
-in the interpreter:
+// Generate the JSON schema at initialization time
+var ScoreResponseSchema = GenerateSchema[ScoreResponse]()
 
-    rake -E 'require "puppet"' spec
+var scoreSchema = responseSchema{
+	Name:        "score_response",
+	Description: "Scores for a commit message",
+	Schema:      ScoreResponseSchema,
+}
 
-Turns out this specific template was uniquely encoded in our repository.
-Everything else was 'us-ascii':
+type CritiqueResponse struct {
+	VagueLanguage    []string `json:"vague_language" jsonschema_description:"Vague words or phrases in the message, each with a more specific alternative."`
+	MissingWhy       string   `json:"missing_why" jsonschema_description:"What the reader still won't know about why the change was made, or empty if the message explains it."`
+	UnrelatedChanges []string `json:"unrelated_changes" jsonschema_description:"Changes in the diff that the message doesn't cover or that look unrelated to the rest of the commit."`
+	Suggestions      []string `json:"suggestions" jsonschema_description:"Other concrete edits that would improve the message."`
+}
 
-    $ find modules -type f -exec file --mime {} \+ | grep utf
-    modules/router/templates/routes.conf.erb:                          text/plain; charset=utf-8
+// Generate the JSON schema at initialization time
+var CritiqueResponseSchema = GenerateSchema[CritiqueResponse]()
 
-To pinpoint the problematic byte, I attempted a conversion to US-ASCII, which
-revealed what appeared to be invisible whitespace:
+var critiqueSchema = responseSchema{
+	Name:        "critique_response",
+	Description: "Review of a commit message written by the user",
+	Schema:      CritiqueResponseSchema,
+}
 
-    $ iconv -f UTF8 -t US-ASCII modules/router/templates/routes.conf.erb 2>&1 | tail -n5
-    proxy_intercept_errors off;
+type SubjectResponse struct {
+	Subject string `json:"subject" jsonschema_description:"The shortened subject line, keeping any type, scope, emoji, and ticket key."`
+}
 
-    # Set proxy timeout to 50 seconds as a quick fix for problems
+// Generate the JSON schema at initialization time
+var SubjectResponseSchema = GenerateSchema[SubjectResponse]()
 
-    iconv: modules/router/templates/routes.conf.erb:458:3: cannot convert
+var subjectSchema = responseSchema{
+	Name:        "subject_response",
+	Description: "A shortened commit message subject line",
+	Schema:      SubjectResponseSchema,
+}
 
-Once I manually corrected it, the encoding returned to 'US-ASCII':
+type SentenceResponse struct {
+	Sentence string `json:"sentence" jsonschema_description:"The rewritten sentence, saying specifically what changed."`
+}
 
-    $ file --mime modules/router/templates/routes.conf.erb
-    modules/router/templates/routes.conf.erb: text/plain; charset=us-ascii
+// Generate the JSON schema at initialization time
+var SentenceResponseSchema = GenerateSchema[SentenceResponse]()
 
-2. Smaller commit message
-feat(database): semantic similarity matching of chosen personalisation role against user query
-This commit introduces a role-based access control feature using embedding similarity into the database interaction layers. It establishes a system where user roles, extracted from a newly created Database module, are utilized to determine access and personalize responses based on cosine similarity of embeddings between user roles and their input queries.
+var sentenceSchema = responseSchema{
+	Name:        "sentence_response",
+	Description: "A more specific rewrite of one sentence of a commit message",
+	Schema:      SentenceResponseSchema,
+}
 
-These changes address the need for a more personalized AI interaction by closely aligning the query processing with user-specific role information. This ensures that responses are tailored to what users would expect based on their data access rights, reducing unnecessary agent calls to data sources that users do not have access to, thus improving system efficiency and user satisfaction.
-`
+type PullRequestResponse struct {
+	Title      string `json:"title" jsonschema_description:"A short pull request title in the imperative mood, without a Conventional Commits prefix."`
+	Summary    string `json:"summary" jsonschema_description:"What the pull request changes, as a few Markdown bullet points."`
+	Motivation string `json:"motivation" jsonschema_description:"Why the change is needed, in a short paragraph drawn from the commit messages."`
+	Testing    string `json:"testing" jsonschema_description:"How the change was or should be tested, as Markdown bullet points."`
+}
 
-	qaPairs := ""
-	for q, a := range answers {
-		qaPairs += fmt.Sprintf("Q: %s\nA: %s\n", q, a)
-	}
+// Generate the JSON schema at initialization time
+var PullRequestResponseSchema = GenerateSchema[PullRequestResponse]()
 
-	userPrompt := fmt.Sprintf("Diff:\n%s\n\nRecent History:\n%s\n\nUser Context:\n%s", diff, history, qaPairs)
+var pullRequestSchema = responseSchema{
+	Name:        "pull_request_response",
+	Description: "A pull request title and description",
+	Schema:      PullRequestResponseSchema,
+}
 
-	schemaParam := openai.ResponseFormatJSONSchemaJSONSchemaParam{
-		Name:        "commit_message_response",
-		Description: openai.String("A structured commit message"),
-		Schema:      CommitMessageResponseSchema,
-		Strict:      openai.Bool(true),
+// Body formats the description as Markdown with a section for each part.
+func (r PullRequestResponse) Body() string {
+	var b strings.Builder
+	for _, s := range []struct{ title, text string }{
+		{"Summary", r.Summary},
+		{"Motivation", r.Motivation},
+		{"Testing", r.Testing},
+	} {
+		if text := strings.TrimSpace(s.text); text != "" {
+			fmt.Fprintf(&b, "## %s\n\n%s\n\n", s.title, text)
+		}
 	}
+	return strings.TrimRight(b.String(), "\n")
+}
 
-	resp, err := c.client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
-		Messages: []openai.ChatCompletionMessageParamUnion{
-			openai.SystemMessage(systemPrompt),
-			openai.UserMessage(userPrompt),
-		},
-		Model: openai.ChatModelGPT4o2024_08_06,
-		ResponseFormat: openai.ChatCompletionNewParamsResponseFormatUnion{
-			OfJSONSchema: &openai.ResponseFormatJSONSchemaParam{
-				JSONSchema: schemaParam,
-			},
-		},
-	})
+type ExplanationResponse struct {
+	Summary string   `json:"summary" jsonschema_description:"What the commits do as a whole, in two or three plain sentences for someone new to the code."`
+	Changes []string `json:"changes" jsonschema_description:"The main changes, one plain sentence each, naming the files, functions, or behavior involved."`
+	Why     string   `json:"why" jsonschema_description:"Why the change was made, as the messages and diff show it, or empty if neither says."`
+	Notes   []string `json:"notes" jsonschema_description:"Anything a reader should know to understand or review the change, such as side effects, risks, or follow-ups. Empty if there's nothing."`
+}
 
-	if err != nil {
-		return "", fmt.Errorf("failed to generate commit message: %w", err)
-	}
+// Generate the JSON schema at initialization time
+var ExplanationResponseSchema = GenerateSchema[ExplanationResponse]()
+
+var explanationSchema = responseSchema{
+	Name:        "explanation_response",
+	Description: "A plain-language explanation of existing commits",
+	Schema:      ExplanationResponseSchema,
+}
 
-	var result CommitMessageResponse
-	if err := json.Unmarshal([]byte(resp.Choices[0].Message.Content), &result); err != nil {
-		return "", fmt.Errorf("failed to parse JSON response: %w", err)
+// Text formats the explanation for the terminal: the summary, then a section
+// for each other part that isn't empty.
+func (r ExplanationResponse) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n\n", strings.TrimSpace(r.Summary))
+	if len(r.Changes) > 0 {
+		b.WriteString("Changes:\n")
+		for _, c := range r.Changes {
+			fmt.Fprintf(&b, "- %s\n", c)
+		}
+		b.WriteString("\n")
+	}
+	if why := strings.TrimSpace(r.Why); why != "" {
+		fmt.Fprintf(&b, "Why:\n%s\n\n", why)
+	}
+	if len(r.Notes) > 0 {
+		b.WriteString("Notes:\n")
+		for _, n := range r.Notes {
+			fmt.Fprintf(&b, "- %s\n", n)
+		}
 	}
+	return strings.TrimRight(b.String(), "\n")
+}
 
-	return fmt.Sprintf("%s\n\n%s", result.Subject, result.Body), nil
+// ReleaseNotesResponse groups release notes under the Keep a Changelog
+// categories.
+type ReleaseNotesResponse struct {
+	Added      []string `json:"added" jsonschema_description:"New features, one user-facing sentence each."`
+	Changed    []string `json:"changed" jsonschema_description:"Changes in existing functionality."`
+	Deprecated []string `json:"deprecated" jsonschema_description:"Features that will be removed in a later release."`
+	Removed    []string `json:"removed" jsonschema_description:"Features removed in this release."`
+	Fixed      []string `json:"fixed" jsonschema_description:"Bug fixes."`
+	Security   []string `json:"security" jsonschema_description:"Fixes for vulnerabilities."`
 }
 
-type HistoryAnalysisResponse struct {
-	IsRelevant bool     `json:"is_relevant" jsonschema_description:"Whether the recent history is relevant to the current changes."`
-	KeyContext []string `json:"key_context" jsonschema_description:"A list of key context points from the history that are relevant to the current changes."`
+// Generate the JSON schema at initialization time
+var ReleaseNotesResponseSchema = GenerateSchema[ReleaseNotesResponse]()
+
+var releaseNotesSchema = responseSchema{
+	Name:        "release_notes_response",
+	Description: "Release notes grouped by Keep a Changelog category",
+	Schema:      ReleaseNotesResponseSchema,
+}
+
+type BranchNamesResponse struct {
+	Names []string `json:"names" jsonschema_description:"Two or three branch names, best first, like feat/auth-token-rotation."`
 }
 
 // Generate the JSON schema at initialization time
-var HistoryAnalysisResponseSchema = GenerateSchema[HistoryAnalysisResponse]()
+var BranchNamesResponseSchema = GenerateSchema[BranchNamesResponse]()
 
-func (c *OpenAIClient) AnalyzeHistory(ctx context.Context, diff string, history string) (*HistoryAnalysisResponse, error) {
-	systemPrompt := `You are an expert software developer.
-Analyze the provided git diff and recent project history.
-Determine if the recent history is relevant to the current changes (e.g., similar files, related features, bug fixes).
-If relevant, extract key context points that should be kept in mind when writing the commit message.
-If not relevant, indicate so.`
-
-	userPrompt := fmt.Sprintf("Diff:\n%s\n\nRecent History:\n%s", diff, history)
-
-	schemaParam := openai.ResponseFormatJSONSchemaJSONSchemaParam{
-		Name:        "history_analysis_response",
-		Description: openai.String("Analysis of project history relevance"),
-		Schema:      HistoryAnalysisResponseSchema,
-		Strict:      openai.Bool(true),
-	}
-
-	resp, err := c.client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
-		Messages: []openai.ChatCompletionMessageParamUnion{
-			openai.SystemMessage(systemPrompt),
-			openai.UserMessage(userPrompt),
-		},
-		Model: openai.ChatModelGPT4o2024_08_06,
-		ResponseFormat: openai.ChatCompletionNewParamsResponseFormatUnion{
-			OfJSONSchema: &openai.ResponseFormatJSONSchemaParam{
-				JSONSchema: schemaParam,
-			},
-		},
-	})
+var branchNamesSchema = responseSchema{
+	Name:        "branch_names_response",
+	Description: "Proposed names for a git branch",
+	Schema:      BranchNamesResponseSchema,
+}
 
-	if err != nil {
-		return nil, fmt.Errorf("failed to analyze history: %w", err)
-	}
+type SplitResponse struct {
+	ShouldSplit bool         `json:"should_split" jsonschema_description:"Whether the hunks contain unrelated changes that belong in separate commits."`
+	Reason      string       `json:"reason" jsonschema_description:"One sentence explaining why the changes should or shouldn't be split."`
+	Groups      []SplitGroup `json:"groups" jsonschema_description:"The proposed commits, in the order they should be made. Empty if the changes shouldn't be split."`
+}
 
-	var result HistoryAnalysisResponse
-	if err := json.Unmarshal([]byte(resp.Choices[0].Message.Content), &result); err != nil {
-		return nil, fmt.Errorf("failed to parse JSON response: %w", err)
-	}
+type SplitGroup struct {
+	Subject string `json:"subject" jsonschema_description:"The commit message subject line, following Conventional Commits specification."`
+	Body    string `json:"body" jsonschema_description:"A short commit message body explaining the change."`
+	Hunks   []int  `json:"hunks" jsonschema_description:"The numbers of the hunks that belong in this commit."`
+}
+
+// Generate the JSON schema at initialization time
+var SplitResponseSchema = GenerateSchema[SplitResponse]()
+
+var splitSchema = responseSchema{
+	Name:        "split_response",
+	Description: "A proposed split of a change into logical commits",
+	Schema:      SplitResponseSchema,
+}
+
+type DiffSummaryResponse struct {
+	Summary string `json:"summary" jsonschema_description:"A concise, file-by-file summary of what this part of the diff changes."`
+}
+
+// Generate the JSON schema at initialization time
+var DiffSummaryResponseSchema = GenerateSchema[DiffSummaryResponse]()
+
+var diffSummarySchema = responseSchema{
+	Name:        "diff_summary_response",
+	Description: "Summary of part of a diff",
+	Schema:      DiffSummaryResponseSchema,
+}
 
-	return &result, nil
+func (c *OpenAIClient) PreviewPrompts(diff string, history string, answers map[string]string) []Prompt {
+	return c.previewPrompts(openAIHistoryPrompt, openAIQuestionsPrompt, openAICommitMessagePrompt, diff, history, answers)
 }
 
 // --- Ollama Implementation ---
 
 type OllamaClient struct {
-	client *openai.Client
-	model  string
+	chat
+}
+
+// NewOllamaClient creates a client for model on the Ollama server at
+// baseURL. Many Ollama models ignore the response schema, so requests whose
+// reply can't be parsed are retried once asking for JSON in the prompt.
+func NewOllamaClient(baseURL, model string, opts ...option.RequestOption) *OllamaClient {
+	c := &OllamaClient{
+		chat: compatibleChat(baseURL, "ollama", model, opts), // Key required but unused by Ollama
+	}
+	c.fallback = true
+	return c
 }
 
-func NewOllamaClient(baseURL, model string) *OllamaClient {
+// compatibleChat creates the chat for a server with an OpenAI-compatible
+// API at baseURL. Without apiKey no credentials are sent.
+func compatibleChat(baseURL, apiKey, model string, opts []option.RequestOption) chat {
 	// Ensure BaseURL ends with /v1/ for OpenAI compatibility
 	// Simple heuristic: if it doesn't contain /v1, append it.
 	// This handles the default "http://localhost:11434" -> "http://localhost:11434/v1/"
@@ -289,170 +658,75 @@ func NewOllamaClient(baseURL, model string) *OllamaClient {
 		baseURL += "v1/"
 	}
 
-	client := openai.NewClient(
-		option.WithBaseURL(baseURL),
-		option.WithAPIKey("ollama"), // Required but unused by Ollama
-	)
-
-	return &OllamaClient{
-		client: &client,
-		model:  model,
+	auth := option.WithAPIKey(apiKey)
+	if apiKey == "" {
+		// Don't forward an OpenAI key picked up from the environment.
+		auth = option.WithHeaderDel("authorization")
 	}
+	client := openai.NewClient(append([]option.RequestOption{
+		option.WithBaseURL(baseURL),
+		auth,
+	}, opts...)...)
+	return chat{client: &client, model: model}
 }
 
 func (c *OllamaClient) GenerateQuestions(ctx context.Context, diff string, history string) ([]string, error) {
-	systemPrompt := `You are an expert software developer assisting a user in writing a commit message.
-Your goal is to understand the "why" behind the changes.
-Analyze the provided git diff and recent project history.
-
-IMPORTANT:
-- Your primary focus MUST be on the STAGED CHANGES (the diff).
-- The recent project history is provided ONLY as supporting context to understand the project's style and ongoing work.
-- Do NOT ask questions about the history unless it directly relates to the current changes.
-
-Generate 3 short, specific questions to ask the user to clarify the intent and context of the changes.
-
-Guidelines:
-- Focus on the "why" and "intent", not just the "what".
-- Avoid generic questions like "What does this change do?".
-- If the changes are self-explanatory, ask for any extra context or side effects.
-
-Examples of GOOD questions:
-- "Why was the timeout increased to 5 seconds?"
-- "What edge case does this nil check handle?"
-- "Is this refactor part of a larger cleanup?"
-
-Examples of BAD questions:
-- "Did you update the file?"
-- "What is the new value of X?"`
-
-	userPrompt := fmt.Sprintf("Diff:\n%s\n\nRecent History:\n%s", diff, history)
-
-	schemaParam := openai.ResponseFormatJSONSchemaJSONSchemaParam{
-		Name:        "questions_response",
-		Description: openai.String("List of clarifying questions"),
-		Schema:      QuestionsResponseSchema,
-		Strict:      openai.Bool(true),
-	}
-
-	resp, err := c.client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
-		Messages: []openai.ChatCompletionMessageParamUnion{
-			openai.SystemMessage(systemPrompt),
-			openai.UserMessage(userPrompt),
-		},
-		Model: c.model,
-		ResponseFormat: openai.ChatCompletionNewParamsResponseFormatUnion{
-			OfJSONSchema: &openai.ResponseFormatJSONSchemaParam{
-				JSONSchema: schemaParam,
-			},
-		},
-	})
-
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate questions: %w", err)
-	}
-
-	var result QuestionsResponse
-	if err := json.Unmarshal([]byte(resp.Choices[0].Message.Content), &result); err != nil {
-		return nil, fmt.Errorf("failed to parse JSON response: %w", err)
-	}
-
-	return result.Questions, nil
+	return c.generateQuestions(ctx, ollamaQuestionsPrompt, diff, history)
 }
 
 func (c *OllamaClient) GenerateCommitMessage(ctx context.Context, diff string, history string, answers map[string]string) (string, error) {
-	systemPrompt := `You are an expert software developer.
-Generate a commit message following the Conventional Commits specification.
-Use the provided diff, recent project history, and user answers to context questions.
-
-Rules:
-1. The subject line MUST be in the format: <type>(<scope>): <description>
-2. Allowed types: feat, fix, docs, style, refactor, perf, test, build, ci, chore, revert.
-3. Keep the subject under 50 characters if possible.
-4. The body should explain "what" and "why", not just "how".
-5. Use the user's answers to provide specific context.
-
-Template:
-<type>(<scope>): <subject>
-
-<body>`
+	return c.generateCommitMessage(ctx, ollamaCommitMessagePrompt, diff, history, answers)
+}
 
-	qaPairs := ""
-	for q, a := range answers {
-		qaPairs += fmt.Sprintf("Q: %s\nA: %s\n", q, a)
-	}
+func (c *OllamaClient) GenerateCommitMessageStream(ctx context.Context, diff string, history string, answers map[string]string) <-chan StreamChunk {
+	return c.generateCommitMessageStream(ctx, ollamaCommitMessagePrompt, diff, history, answers)
+}
 
-	userPrompt := fmt.Sprintf("Diff:\n%s\n\nRecent History:\n%s\n\nUser Context:\n%s", diff, history, qaPairs)
+func (c *OllamaClient) AnalyzeHistory(ctx context.Context, diff string, history string) (*HistoryAnalysisResponse, error) {
+	return c.analyzeHistory(ctx, ollamaHistoryPrompt, diff, history)
+}
 
-	schemaParam := openai.ResponseFormatJSONSchemaJSONSchemaParam{
-		Name:        "commit_message_response",
-		Description: openai.String("A structured commit message"),
-		Schema:      CommitMessageResponseSchema,
-		Strict:      openai.Bool(true),
-	}
+func (c *OllamaClient) PreviewPrompts(diff string, history string, answers map[string]string) []Prompt {
+	return c.previewPrompts(ollamaHistoryPrompt, ollamaQuestionsPrompt, ollamaCommitMessagePrompt, diff, history, answers)
+}
 
-	resp, err := c.client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
-		Messages: []openai.ChatCompletionMessageParamUnion{
-			openai.SystemMessage(systemPrompt),
-			openai.UserMessage(userPrompt),
-		},
-		Model: c.model,
-		ResponseFormat: openai.ChatCompletionNewParamsResponseFormatUnion{
-			OfJSONSchema: &openai.ResponseFormatJSONSchemaParam{
-				JSONSchema: schemaParam,
-			},
-		},
-	})
+// --- OpenAI-compatible Endpoint Implementation ---
 
-	if err != nil {
-		return "", fmt.Errorf("failed to generate commit message: %w", err)
-	}
+// CompatibleClient talks to any server with an OpenAI-compatible chat API,
+// such as llama.cpp's server, LM Studio, or vLLM. Like Ollama, these mostly
+// run smaller local models, so the Ollama prompts are used.
+type CompatibleClient struct {
+	chat
+}
 
-	var result CommitMessageResponse
-	if err := json.Unmarshal([]byte(resp.Choices[0].Message.Content), &result); err != nil {
-		return "", fmt.Errorf("failed to parse JSON response: %w", err)
-	}
+// NewCompatibleClient creates a client for model at baseURL. apiKey may be
+// empty for servers without authentication. Unless structured is set, the
+// server is assumed not to support JSON schema response formats: the schema
+// is given in the prompt instead and replies are repaired as needed. Either
+// way, a request whose reply can't be parsed is retried once as plain text.
+func NewCompatibleClient(baseURL, apiKey, model string, structured bool, opts ...option.RequestOption) *CompatibleClient {
+	c := &CompatibleClient{chat: compatibleChat(baseURL, apiKey, model, opts)}
+	c.schemaless = !structured
+	c.fallback = true
+	return c
+}
 
-	return fmt.Sprintf("%s\n\n%s", result.Subject, result.Body), nil
+func (c *CompatibleClient) GenerateQuestions(ctx context.Context, diff string, history string) ([]string, error) {
+	return c.generateQuestions(ctx, ollamaQuestionsPrompt, diff, history)
 }
 
-func (c *OllamaClient) AnalyzeHistory(ctx context.Context, diff string, history string) (*HistoryAnalysisResponse, error) {
-	systemPrompt := `You are an expert software developer.
-Analyze the provided git diff and recent project history.
-Determine if the recent history is relevant to the current changes (e.g., similar files, related features, bug fixes).
-If relevant, extract key context points that should be kept in mind when writing the commit message.
-If not relevant, indicate so.`
-
-	userPrompt := fmt.Sprintf("Diff:\n%s\n\nRecent History:\n%s", diff, history)
-
-	schemaParam := openai.ResponseFormatJSONSchemaJSONSchemaParam{
-		Name:        "history_analysis_response",
-		Description: openai.String("Analysis of project history relevance"),
-		Schema:      HistoryAnalysisResponseSchema,
-		Strict:      openai.Bool(true),
-	}
-
-	resp, err := c.client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
-		Messages: []openai.ChatCompletionMessageParamUnion{
-			openai.SystemMessage(systemPrompt),
-			openai.UserMessage(userPrompt),
-		},
-		Model: c.model,
-		ResponseFormat: openai.ChatCompletionNewParamsResponseFormatUnion{
-			OfJSONSchema: &openai.ResponseFormatJSONSchemaParam{
-				JSONSchema: schemaParam,
-			},
-		},
-	})
+func (c *CompatibleClient) GenerateCommitMessage(ctx context.Context, diff string, history string, answers map[string]string) (string, error) {
+	return c.generateCommitMessage(ctx, ollamaCommitMessagePrompt, diff, history, answers)
+}
 
-	if err != nil {
-		return nil, fmt.Errorf("failed to analyze history: %w", err)
-	}
+func (c *CompatibleClient) GenerateCommitMessageStream(ctx context.Context, diff string, history string, answers map[string]string) <-chan StreamChunk {
+	return c.generateCommitMessageStream(ctx, ollamaCommitMessagePrompt, diff, history, answers)
+}
 
-	var result HistoryAnalysisResponse
-	if err := json.Unmarshal([]byte(resp.Choices[0].Message.Content), &result); err != nil {
-		return nil, fmt.Errorf("failed to parse JSON response: %w", err)
-	}
+func (c *CompatibleClient) AnalyzeHistory(ctx context.Context, diff string, history string) (*HistoryAnalysisResponse, error) {
+	return c.analyzeHistory(ctx, ollamaHistoryPrompt, diff, history)
+}
 
-	return &result, nil
+func (c *CompatibleClient) PreviewPrompts(diff string, history string, answers map[string]string) []Prompt {
+	return c.previewPrompts(ollamaHistoryPrompt, ollamaQuestionsPrompt, ollamaCommitMessagePrompt, diff, history, answers)
 }
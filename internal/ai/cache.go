@@ -0,0 +1,132 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// Cache keeps provider responses by key. It's best effort: a failed lookup
+// is a miss, and a failed save is ignored.
+type Cache interface {
+	Response(key string) (string, bool)
+	SaveResponse(key, value string)
+}
+
+type freshKey struct{}
+
+// Fresh returns a context whose requests skip cached responses, still
+// caching what comes back, for when the user asks for another attempt.
+func Fresh(ctx context.Context) context.Context {
+	return context.WithValue(ctx, freshKey{}, true)
+}
+
+// Cached returns p with its history analyses, questions, and commit messages
+// kept in cache, keyed by the model and the prompt each request sends, so
+// asking again for the same change doesn't send the same request twice.
+func Cached(p Provider, cache Cache) Provider {
+	return &cached{Provider: p, cache: cache}
+}
+
+type cached struct {
+	Provider
+	cache Cache
+}
+
+// key identifies the request p would send for stage. The prompt covers the
+// diff, history, answers, and everything configured that shapes them.
+func (c *cached) key(stage Stage, diff, history string, answers map[string]string) string {
+	for _, p := range c.PreviewPrompts(diff, history, answers) {
+		if p.Stage == stage {
+			sum := sha256.Sum256([]byte(c.Model() + "\x00" + string(stage) + "\x00" + p.System + "\x00" + p.User))
+			return hex.EncodeToString(sum[:])
+		}
+	}
+	return ""
+}
+
+// lookup returns the response cached under key, unless ctx asks for a fresh one.
+func lookup[T any](ctx context.Context, cache Cache, key string) (T, bool) {
+	var v T
+	if key == "" || ctx.Value(freshKey{}) != nil {
+		return v, false
+	}
+	raw, ok := cache.Response(key)
+	if !ok || json.Unmarshal([]byte(raw), &v) != nil {
+		return v, false
+	}
+	return v, true
+}
+
+func save(cache Cache, key string, v any) {
+	if key == "" {
+		return
+	}
+	if raw, err := json.Marshal(v); err == nil {
+		cache.SaveResponse(key, string(raw))
+	}
+}
+
+func (c *cached) AnalyzeHistory(ctx context.Context, diff string, history string) (*HistoryAnalysisResponse, error) {
+	key := c.key(StageHistory, diff, history, nil)
+	if resp, ok := lookup[*HistoryAnalysisResponse](ctx, c.cache, key); ok && resp != nil {
+		return resp, nil
+	}
+	resp, err := c.Provider.AnalyzeHistory(ctx, diff, history)
+	if err == nil {
+		save(c.cache, key, resp)
+	}
+	return resp, err
+}
+
+func (c *cached) GenerateQuestions(ctx context.Context, diff string, history string) ([]string, error) {
+	key := c.key(StageQuestions, diff, history, nil)
+	if questions, ok := lookup[[]string](ctx, c.cache, key); ok {
+		return questions, nil
+	}
+	questions, err := c.Provider.GenerateQuestions(ctx, diff, history)
+	if err == nil {
+		save(c.cache, key, questions)
+	}
+	return questions, err
+}
+
+func (c *cached) GenerateCommitMessage(ctx context.Context, diff string, history string, answers map[string]string) (string, error) {
+	key := c.key(StageMessage, diff, history, answers)
+	if message, ok := lookup[string](ctx, c.cache, key); ok {
+		return message, nil
+	}
+	message, err := c.Provider.GenerateCommitMessage(ctx, diff, history, answers)
+	if err == nil {
+		save(c.cache, key, message)
+	}
+	return message, err
+}
+
+// GenerateCommitMessageStream sends a cached message whole, as the Done chunk.
+func (c *cached) GenerateCommitMessageStream(ctx context.Context, diff string, history string, answers map[string]string) <-chan StreamChunk {
+	key := c.key(StageMessage, diff, history, answers)
+	if message, ok := lookup[string](ctx, c.cache, key); ok {
+		ch := make(chan StreamChunk, 1)
+		ch <- StreamChunk{Done: true, Message: message}
+		close(ch)
+		return ch
+	}
+	in := c.Provider.GenerateCommitMessageStream(ctx, diff, history, answers)
+	out := make(chan StreamChunk)
+	go func() {
+		defer close(out)
+		for chunk := range in {
+			if chunk.Done && chunk.Err == nil {
+				save(c.cache, key, chunk.Message)
+			}
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,479 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"sync"
+
+	"github.com/arpxspace/smartcommit/internal/config"
+	"github.com/arpxspace/smartcommit/internal/conventional"
+	"github.com/arpxspace/smartcommit/internal/logging"
+
+	"github.com/openai/openai-go"
+)
+
+// chat is the OpenAI-compatible client every provider sends requests through.
+// Providers differ only in their generation prompts, so the stages whose
+// prompts don't vary (scoring, splitting, critiques, pull requests, and the
+// rest) are implemented here once, along with Embed.
+type chat struct {
+	client *openai.Client
+	model  string
+
+	// templates replace the built-in prompts of some stages.
+	templates templates
+
+	// questions is how many clarifying questions are asked for, or 0 for
+	// the number in the built-in prompts. When adaptive, it's the most the
+	// model may ask.
+	questions int
+	adaptive  bool
+
+	// embeddingModel is the model Embed uses.
+	embeddingModel string
+
+	// messageSchema, when set, replaces commitMessageSchema to restrict
+	// the type and scope of generated messages.
+	messageSchema *responseSchema
+
+	// gitmoji, when set, maps types to the emoji that lead subjects.
+	gitmoji map[string]string
+
+	// language, when set, is the language questions and messages are
+	// written in.
+	language string
+
+	// depth, when set, is how far questions dig and how long message
+	// bodies run; see config.Depth.
+	depth string
+
+	// schemaless is set for servers that don't support JSON schema response
+	// formats. The schema is described in the system prompt instead, and
+	// replies are repaired before they're decoded.
+	schemaless bool
+
+	// systemRole is the role system prompts are sent with, and reasoning
+	// is set for reasoning models; see modelTraits.
+	systemRole string
+	reasoning  bool
+
+	// generation tunes requests per stage.
+	generation config.Generation
+
+	// fallback retries a request once when the reply can't be decoded, or
+	// the server rejects the response format, switching to schemaless for
+	// it and every later request. Many local models ignore the schema.
+	fallback bool
+
+	// retry reports requests waiting to be retried.
+	retry *retryTransport
+
+	mu    sync.Mutex
+	usage Usage
+}
+
+// usePrompts replaces built-in prompts with the user's templates.
+func (c *chat) usePrompts(t templates) {
+	c.templates = t
+}
+
+// prompt builds the prompt for stage from its built-in system prompt,
+// applying the user's template if there is one.
+func (c *chat) prompt(stage Stage, system, diff, history string, answers map[string]string) (Prompt, error) {
+	user := analysisUserPrompt(diff, history)
+	if stage == StageMessage {
+		user = commitMessageUserPrompt(diff, history, answers)
+	}
+	if stage == StageQuestions && c.questions > 0 {
+		system = questionCount(system, c.questions, c.adaptive)
+	}
+	if c.depth != "" && (stage == StageQuestions || stage == StageMessage) {
+		system = atDepth(system, stage, c.depth)
+	}
+	if c.language != "" && (stage == StageQuestions || stage == StageMessage) {
+		system = inLanguage(system, stage, c.language)
+	}
+	p := Prompt{Stage: stage, System: system, User: user}
+	return c.templates.apply(p, PromptData{Diff: diff, History: history, Answers: answers, Language: c.language})
+}
+
+// askQuestions sets how many clarifying questions are asked for, or with
+// adaptive, the most that may be asked.
+func (c *chat) askQuestions(n int, adaptive bool) {
+	c.questions = n
+	c.adaptive = adaptive
+}
+
+// writeIn sets the language questions and messages are written in.
+func (c *chat) writeIn(language string) {
+	c.language = language
+}
+
+// digTo sets how far questions dig and how long message bodies run.
+func (c *chat) digTo(depth string) {
+	c.depth = depth
+}
+
+// embedWith sets the model Embed uses.
+func (c *chat) embedWith(model string) {
+	c.embeddingModel = model
+}
+
+// constrain restricts generated messages to the given types and scopes.
+func (c *chat) constrain(types, scopes []string) {
+	schema := conventionalMessageSchema(types, scopes)
+	c.messageSchema = &schema
+}
+
+// useGitmoji leads generated subjects with the gitmoji for their type, and
+// restricts the type and scope to the given values as constrain does.
+func (c *chat) useGitmoji(table map[string]string, types, scopes []string) {
+	schema := gitmojiMessageSchema(table, types, scopes)
+	c.messageSchema = &schema
+	c.gitmoji = table
+}
+
+// commitMessageSchema returns the schema commit messages are generated with.
+func (c *chat) commitMessageSchema() responseSchema {
+	if c.messageSchema != nil {
+		return *c.messageSchema
+	}
+	return commitMessageSchema
+}
+
+// parseMessage decodes a reply to commitMessageSchema into a commit message.
+func (c *chat) parseMessage(raw string) (string, error) {
+	if c.gitmoji != nil {
+		var result GitmojiMessageResponse
+		if err := decodeJSON(raw, c.commitMessageSchema(), &result); err != nil {
+			return "", err
+		}
+		return formatMessage(result.subject(c.gitmoji), result.Body), nil
+	}
+	if c.messageSchema != nil {
+		var result ConventionalMessageResponse
+		if err := decodeJSON(raw, c.commitMessageSchema(), &result); err != nil {
+			return "", err
+		}
+		return formatMessage(result.subject(), result.Body), nil
+	}
+	var result CommitMessageResponse
+	if err := decodeJSON(raw, c.commitMessageSchema(), &result); err != nil {
+		return "", err
+	}
+	return formatMessage(result.Subject, result.Body), nil
+}
+
+func (r ConventionalMessageResponse) subject() string {
+	return conventional.Header{Type: r.Type, Scope: r.Scope, Breaking: r.Breaking, Description: r.Description}.String()
+}
+
+// subject renders the subject with the model's emoji, or the one table maps
+// the type to if the model's isn't in it.
+func (r GitmojiMessageResponse) subject(table map[string]string) string {
+	emoji := r.Emoji
+	if !slices.Contains(slices.Collect(maps.Values(table)), emoji) {
+		emoji = table[r.Type]
+	}
+	return conventional.Header{Emoji: emoji, Type: r.Type, Scope: r.Scope, Breaking: r.Breaking, Description: r.Description}.String()
+}
+
+// responseSchema names a JSON schema for Structured Outputs.
+type responseSchema struct {
+	Name        string
+	Description string
+	Schema      interface{}
+}
+
+func (c *chat) Model() string {
+	return c.model
+}
+
+// watchRetries reports the retries of retry through Retrying.
+func (c *chat) watchRetries(retry *retryTransport) {
+	c.retry = retry
+}
+
+func (c *chat) Retrying() (RetryStatus, bool) {
+	if c.retry == nil {
+		return RetryStatus{}, false
+	}
+	return c.retry.status()
+}
+
+// Usage returns the tokens used by every request made so far.
+func (c *chat) Usage() Usage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.usage
+}
+
+// params builds a request for stage with the system and user messages,
+// constraining the reply to schema.
+func (c *chat) params(stage Stage, system, user string, schema responseSchema) openai.ChatCompletionNewParams {
+	c.mu.Lock()
+	schemaless := c.schemaless
+	c.mu.Unlock()
+	var params openai.ChatCompletionNewParams
+	if schemaless {
+		params = openai.ChatCompletionNewParams{
+			Messages: []openai.ChatCompletionMessageParamUnion{
+				c.systemMessage(system + schemaInstruction(schema)),
+				openai.UserMessage(user),
+			},
+			Model: c.model,
+		}
+	} else {
+		params = openai.ChatCompletionNewParams{
+			Messages: []openai.ChatCompletionMessageParamUnion{
+				c.systemMessage(system),
+				openai.UserMessage(user),
+			},
+			Model: c.model,
+			ResponseFormat: openai.ChatCompletionNewParamsResponseFormatUnion{
+				OfJSONSchema: &openai.ResponseFormatJSONSchemaParam{
+					JSONSchema: openai.ResponseFormatJSONSchemaJSONSchemaParam{
+						Name:        schema.Name,
+						Description: openai.String(schema.Description),
+						Schema:      schema.Schema,
+						Strict:      openai.Bool(true),
+					},
+				},
+			},
+		}
+	}
+	c.applyOptions(&params, stage)
+	return params
+}
+
+// Embed returns an embedding vector for each of texts, in the same order.
+func (c *chat) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+	model := c.embeddingModel
+	if model == "" {
+		model = config.DefaultEmbeddingModel
+	}
+	resp, err := c.client.Embeddings.New(ctx, openai.EmbeddingNewParams{
+		Input: openai.EmbeddingNewParamsInputUnion{OfArrayOfStrings: texts},
+		Model: model,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed: %w", err)
+	}
+	c.mu.Lock()
+	c.usage.PromptTokens += int(resp.Usage.PromptTokens)
+	c.usage.Requests++
+	c.mu.Unlock()
+
+	out := make([][]float64, len(texts))
+	for _, e := range resp.Data {
+		if e.Index < 0 || int(e.Index) >= len(out) {
+			return nil, fmt.Errorf("failed to embed: provider returned an embedding for input %d of %d", e.Index, len(out))
+		}
+		out[e.Index] = e.Embedding
+	}
+	for i, v := range out {
+		if v == nil {
+			return nil, fmt.Errorf("failed to embed: provider returned no embedding for input %d", i)
+		}
+	}
+	return out, nil
+}
+
+// record adds the usage reported for one request.
+func (c *chat) record(u openai.CompletionUsage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.usage.PromptTokens += int(u.PromptTokens)
+	c.usage.CompletionTokens += int(u.CompletionTokens)
+	c.usage.Requests++
+	logging.Info("provider usage", "model", c.model, "prompt_tokens", u.PromptTokens, "completion_tokens", u.CompletionTokens)
+}
+
+// structured sends the system and user messages, constraining the reply to
+// schema, and decodes it into out, falling back to a request as plain text
+// if the first attempt fails and fallback is set.
+func (c *chat) structured(ctx context.Context, stage Stage, system, user string, schema responseSchema, out any) error {
+	err := c.complete(ctx, stage, system, user, schema, out)
+	if err != nil && c.fallBack(err) {
+		err = c.complete(ctx, stage, system, user, schema, out)
+	}
+	return err
+}
+
+// complete makes one request for structured and decodes its reply.
+func (c *chat) complete(ctx context.Context, stage Stage, system, user string, schema responseSchema, out any) error {
+	resp, err := c.client.Chat.Completions.New(ctx, c.params(stage, system, user, schema))
+	if err != nil {
+		return err
+	}
+	c.record(resp.Usage)
+	if len(resp.Choices) == 0 {
+		return errors.New("provider returned no choices")
+	}
+	return decodeJSON(resp.Choices[0].Message.Content, schema, out)
+}
+
+// fallBack reports whether a request that failed with err is worth one
+// more try as plain text, and makes requests plain text from now on if so.
+// That's when the reply couldn't be decoded, or when the server rejected
+// the request, likely for its response format.
+func (c *chat) fallBack(err error) bool {
+	if !c.fallback {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var parseErr *parseError
+	var apiErr *openai.Error
+	switch {
+	case errors.As(err, &parseErr):
+	case errors.As(err, &apiErr) && !c.schemaless &&
+		(apiErr.StatusCode == http.StatusBadRequest || apiErr.StatusCode == http.StatusUnprocessableEntity):
+	default:
+		return false
+	}
+	c.schemaless = true
+	return true
+}
+
+func (c *chat) generateQuestions(ctx context.Context, systemPrompt, diff, history string) ([]string, error) {
+	p, err := c.prompt(StageQuestions, systemPrompt, diff, history, nil)
+	if err != nil {
+		return nil, err
+	}
+	var result QuestionsResponse
+	if err := c.structured(ctx, StageQuestions, p.System, p.User, questionsSchema, &result); err != nil {
+		return nil, fmt.Errorf("failed to generate questions: %w", err)
+	}
+	return result.Questions, nil
+}
+
+func (c *chat) generateCommitMessage(ctx context.Context, systemPrompt, diff, history string, answers map[string]string) (string, error) {
+	p, err := c.prompt(StageMessage, systemPrompt, diff, history, answers)
+	if err != nil {
+		return "", err
+	}
+	var raw json.RawMessage
+	if err := c.structured(ctx, StageMessage, p.System, p.User, c.commitMessageSchema(), &raw); err != nil {
+		return "", fmt.Errorf("failed to generate commit message: %w", err)
+	}
+	message, err := c.parseMessage(string(raw))
+	if err != nil {
+		return "", fmt.Errorf("failed to generate commit message: %w", err)
+	}
+	return message, nil
+}
+
+func (c *chat) analyzeHistory(ctx context.Context, systemPrompt, diff, history string) (*HistoryAnalysisResponse, error) {
+	p, err := c.prompt(StageHistory, systemPrompt, diff, history, nil)
+	if err != nil {
+		return nil, err
+	}
+	var result HistoryAnalysisResponse
+	if err := c.structured(ctx, StageHistory, p.System, p.User, historyAnalysisSchema, &result); err != nil {
+		return nil, fmt.Errorf("failed to analyze history: %w", err)
+	}
+	return &result, nil
+}
+
+// ScoreMessage rates message on why-coverage, clarity, and convention,
+// with a one-sentence verdict.
+func (c *chat) ScoreMessage(ctx context.Context, diff, message string) (*ScoreResponse, error) {
+	var result ScoreResponse
+	if err := c.structured(ctx, "", scoreMessagePrompt, scoreUserPrompt(diff, message), scoreSchema, &result); err != nil {
+		return nil, fmt.Errorf("failed to score commit message: %w", err)
+	}
+	return &result, nil
+}
+
+// ProposeSplit groups the numbered hunks into proposed commits.
+func (c *chat) ProposeSplit(ctx context.Context, hunks string) (*SplitResponse, error) {
+	var result SplitResponse
+	if err := c.structured(ctx, "", proposeSplitPrompt, hunks, splitSchema, &result); err != nil {
+		return nil, fmt.Errorf("failed to propose a split: %w", err)
+	}
+	return &result, nil
+}
+
+// CritiqueMessage returns the vague language, missing why, and unrelated
+// changes found in message, with suggested edits.
+func (c *chat) CritiqueMessage(ctx context.Context, diff, message string) (*CritiqueResponse, error) {
+	var result CritiqueResponse
+	if err := c.structured(ctx, "", critiqueMessagePrompt, scoreUserPrompt(diff, message), critiqueSchema, &result); err != nil {
+		return nil, fmt.Errorf("failed to critique commit message: %w", err)
+	}
+	return &result, nil
+}
+
+// ShortenSubject returns message's subject rewritten to at most limit
+// characters.
+func (c *chat) ShortenSubject(ctx context.Context, message string, limit int) (string, error) {
+	var result SubjectResponse
+	if err := c.structured(ctx, "", shortenSubjectPrompt, shortenUserPrompt(message, limit), subjectSchema, &result); err != nil {
+		return "", fmt.Errorf("failed to shorten the subject: %w", err)
+	}
+	return result.Subject, nil
+}
+
+// RewriteSentence returns a replacement for sentence that names what diff
+// changes.
+func (c *chat) RewriteSentence(ctx context.Context, diff, message, sentence string) (string, error) {
+	var result SentenceResponse
+	if err := c.structured(ctx, "", rewriteSentencePrompt, rewriteUserPrompt(diff, message, sentence), sentenceSchema, &result); err != nil {
+		return "", fmt.Errorf("failed to rewrite the sentence: %w", err)
+	}
+	return result.Sentence, nil
+}
+
+// DescribePullRequest returns a title and description for a branch.
+func (c *chat) DescribePullRequest(ctx context.Context, diff, commits string) (*PullRequestResponse, error) {
+	var result PullRequestResponse
+	if err := c.structured(ctx, "", describePullRequestPrompt, pullRequestUserPrompt(diff, commits), pullRequestSchema, &result); err != nil {
+		return nil, fmt.Errorf("failed to describe pull request: %w", err)
+	}
+	return &result, nil
+}
+
+// ExplainCommits returns a plain-language explanation of the commits.
+func (c *chat) ExplainCommits(ctx context.Context, diff, commits string) (*ExplanationResponse, error) {
+	var result ExplanationResponse
+	if err := c.structured(ctx, "", explainCommitsPrompt, pullRequestUserPrompt(diff, commits), explanationSchema, &result); err != nil {
+		return nil, fmt.Errorf("failed to explain the commits: %w", err)
+	}
+	return &result, nil
+}
+
+// WriteReleaseNotes returns release notes for the grouped commits.
+func (c *chat) WriteReleaseNotes(ctx context.Context, commits string) (*ReleaseNotesResponse, error) {
+	var result ReleaseNotesResponse
+	if err := c.structured(ctx, "", releaseNotesPrompt, commits, releaseNotesSchema, &result); err != nil {
+		return nil, fmt.Errorf("failed to write release notes: %w", err)
+	}
+	return &result, nil
+}
+
+// SuggestBranchNames returns candidate branch names, best first.
+func (c *chat) SuggestBranchNames(ctx context.Context, diff, ticket string) (*BranchNamesResponse, error) {
+	var result BranchNamesResponse
+	if err := c.structured(ctx, "", suggestBranchPrompt, branchUserPrompt(diff, ticket), branchNamesSchema, &result); err != nil {
+		return nil, fmt.Errorf("failed to suggest branch names: %w", err)
+	}
+	return &result, nil
+}
+
+// SummarizeDiff returns a summary of part of total of an oversized diff.
+func (c *chat) SummarizeDiff(ctx context.Context, diff string, part, total int) (string, error) {
+	var result DiffSummaryResponse
+	if err := c.structured(ctx, "", summarizeDiffPrompt, summarizeUserPrompt(part, total, diff), diffSummarySchema, &result); err != nil {
+		return "", fmt.Errorf("failed to summarize part %d of the diff: %w", part, err)
+	}
+	return result.Summary, nil
+}
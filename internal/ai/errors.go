@@ -0,0 +1,70 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/openai/openai-go"
+)
+
+// ErrorKind classifies why a provider request failed, so the user can be
+// offered a fix that fits.
+type ErrorKind int
+
+const (
+	// ErrorOther is any failure not covered below.
+	ErrorOther ErrorKind = iota
+	// ErrorAuth means the API key is missing, invalid, or lacks access.
+	ErrorAuth
+	// ErrorQuota means the account is out of credits or over its quota.
+	ErrorQuota
+	// ErrorRateLimited means requests were still rate limited after retrying.
+	ErrorRateLimited
+	// ErrorModelNotFound means the configured model doesn't exist or isn't
+	// pulled.
+	ErrorModelNotFound
+	// ErrorNetwork means the provider couldn't be reached or didn't answer
+	// in time.
+	ErrorNetwork
+)
+
+// Classify returns the kind of a provider error.
+func Classify(err error) ErrorKind {
+	var apiErr *openai.Error
+	if errors.As(err, &apiErr) {
+		switch {
+		case apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden:
+			return ErrorAuth
+		case apiErr.Code == "insufficient_quota":
+			return ErrorQuota
+		case apiErr.StatusCode == http.StatusTooManyRequests:
+			return ErrorRateLimited
+		case apiErr.Code == "model_not_found", apiErr.Code == "DeploymentNotFound",
+			apiErr.StatusCode == http.StatusNotFound && strings.Contains(apiErr.Error(), "model"):
+			return ErrorModelNotFound
+		}
+		return ErrorOther
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return ErrorNetwork
+	}
+	return ErrorOther
+}
+
+// timeoutError is returned when an attempt runs past the request timeout.
+// It's a net.Error so it classifies as ErrorNetwork.
+type timeoutError struct {
+	timeout time.Duration
+}
+
+func (e timeoutError) Error() string {
+	return fmt.Sprintf("provider didn't respond within %s; raise request_timeout to wait longer", e.timeout)
+}
+
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
@@ -0,0 +1,35 @@
+package ai
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/arpxspace/smartcommit/internal/logging"
+)
+
+// logTransport logs the metadata of every provider round trip: never
+// bodies or headers, which may hold the diff or the key.
+type logTransport struct {
+	base http.RoundTripper
+}
+
+func (t logTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !logging.Enabled(slog.LevelInfo) {
+		return t.base.RoundTrip(req)
+	}
+	start := time.Now()
+	endpoint := req.URL.Scheme + "://" + req.URL.Host + req.URL.Path
+	resp, err := t.base.RoundTrip(req)
+	elapsed := time.Since(start).Round(time.Millisecond)
+	if err != nil {
+		logging.Warn("provider request failed", "method", req.Method, "endpoint", endpoint, "duration", elapsed, "sent", req.ContentLength, "err", err)
+		return nil, err
+	}
+	requestID := resp.Header.Get("X-Request-Id")
+	if requestID == "" {
+		requestID = resp.Header.Get("Apim-Request-Id")
+	}
+	logging.Info("provider request", "method", req.Method, "endpoint", endpoint, "duration", elapsed, "sent", req.ContentLength, "status", resp.StatusCode, "request_id", requestID)
+	return resp, nil
+}
@@ -0,0 +1,311 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+)
+
+// MockModel is the model name the mock provider reports.
+const MockModel = "mock"
+
+// MockFixtures are the canned responses of the mock provider. Every field
+// is optional; the zero value of a field uses the built-in fixture.
+type MockFixtures struct {
+	Questions    []string                 `json:"questions,omitempty"`
+	Message      string                   `json:"message,omitempty"`
+	History      *HistoryAnalysisResponse `json:"history,omitempty"`
+	Summary      string                   `json:"summary,omitempty"`
+	Score        *ScoreResponse           `json:"score,omitempty"`
+	Split        *SplitResponse           `json:"split,omitempty"`
+	Critique     *CritiqueResponse        `json:"critique,omitempty"`
+	Subject      string                   `json:"subject,omitempty"`
+	Sentence     string                   `json:"sentence,omitempty"`
+	PullRequest  *PullRequestResponse     `json:"pull_request,omitempty"`
+	Explanation  *ExplanationResponse     `json:"explanation,omitempty"`
+	ReleaseNotes *ReleaseNotesResponse    `json:"release_notes,omitempty"`
+	Branches     []string                 `json:"branches,omitempty"`
+}
+
+// defaultMockFixtures are the built-in canned responses.
+var defaultMockFixtures = MockFixtures{
+	Questions: []string{
+		"What problem does this change solve?",
+		"Why was this approach chosen over the alternatives?",
+		"Is there anything reviewers should watch out for?",
+	},
+	Message: "feat: add the requested change\n\nThe mock provider wrote this message; no request was sent.",
+	History: &HistoryAnalysisResponse{IsRelevant: false},
+	Summary: "- The mock provider summarized this part; no request was sent.",
+	Score:   &ScoreResponse{WhyCoverage: 7, Clarity: 8, Convention: 10, Cohesion: 9, Verdict: "Scored by the mock provider."},
+	Split:   &SplitResponse{ShouldSplit: false, Reason: "The mock provider never proposes a split."},
+	Critique: &CritiqueResponse{
+		Suggestions: []string{"Reviewed by the mock provider; no request was sent."},
+	},
+	Subject:  "feat: add the requested change",
+	Sentence: "The mock provider rewrote this sentence; no request was sent.",
+	PullRequest: &PullRequestResponse{
+		Title:      "Add the requested change",
+		Summary:    "- Written by the mock provider",
+		Motivation: "No request was sent.",
+		Testing:    "- Nothing to test",
+	},
+	Explanation: &ExplanationResponse{
+		Summary: "The mock provider explained these commits; no request was sent.",
+		Changes: []string{"Written by the mock provider"},
+	},
+	ReleaseNotes: &ReleaseNotesResponse{Added: []string{"Written by the mock provider."}},
+	Branches:     []string{"feat/requested-change", "chore/mock-branch"},
+}
+
+// MockClient is a Provider that answers from fixtures without any network
+// access, for tests, demos, and offline development. Its answers don't
+// depend on the input, so a run is fully deterministic; prompts are still
+// built as for OpenAI, so previews and prompt hashes work as usual.
+type MockClient struct {
+	chat
+	fixtures MockFixtures
+}
+
+// NewMockClient creates a mock provider answering from the fixtures file at
+// path, or the built-in fixtures when path is empty.
+func NewMockClient(path string) (*MockClient, error) {
+	fixtures := defaultMockFixtures
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read mock fixtures: %w", err)
+		}
+		var f MockFixtures
+		if err := json.Unmarshal(data, &f); err != nil {
+			return nil, fmt.Errorf("failed to parse mock fixtures %s: %w", path, err)
+		}
+		fixtures.merge(f)
+	}
+	return &MockClient{chat: chat{model: MockModel}, fixtures: fixtures}, nil
+}
+
+// merge replaces the fixtures f sets.
+func (m *MockFixtures) merge(f MockFixtures) {
+	if f.Questions != nil {
+		m.Questions = f.Questions
+	}
+	if f.Message != "" {
+		m.Message = f.Message
+	}
+	if f.History != nil {
+		m.History = f.History
+	}
+	if f.Summary != "" {
+		m.Summary = f.Summary
+	}
+	if f.Score != nil {
+		m.Score = f.Score
+	}
+	if f.Split != nil {
+		m.Split = f.Split
+	}
+	if f.Critique != nil {
+		m.Critique = f.Critique
+	}
+	if f.Subject != "" {
+		m.Subject = f.Subject
+	}
+	if f.Sentence != "" {
+		m.Sentence = f.Sentence
+	}
+	if f.PullRequest != nil {
+		m.PullRequest = f.PullRequest
+	}
+	if f.Explanation != nil {
+		m.Explanation = f.Explanation
+	}
+	if f.ReleaseNotes != nil {
+		m.ReleaseNotes = f.ReleaseNotes
+	}
+	if f.Branches != nil {
+		m.Branches = f.Branches
+	}
+}
+
+// answer counts a request and reports ctx's error, so the mock cancels like
+// a real provider.
+func (c *MockClient) answer(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.usage.Requests++
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *MockClient) GenerateQuestions(ctx context.Context, diff string, history string) ([]string, error) {
+	if err := c.answer(ctx); err != nil {
+		return nil, fmt.Errorf("failed to generate questions: %w", err)
+	}
+	n := len(c.fixtures.Questions)
+	if c.questions > 0 {
+		n = min(n, c.questions)
+	}
+	return append([]string(nil), c.fixtures.Questions[:n]...), nil
+}
+
+func (c *MockClient) GenerateCommitMessage(ctx context.Context, diff string, history string, answers map[string]string) (string, error) {
+	if err := c.answer(ctx); err != nil {
+		return "", fmt.Errorf("failed to generate commit message: %w", err)
+	}
+	return c.fixtures.Message, nil
+}
+
+// GenerateCommitMessageStream delivers the message a line at a time, so the
+// streaming view can be seen working.
+func (c *MockClient) GenerateCommitMessageStream(ctx context.Context, diff string, history string, answers map[string]string) <-chan StreamChunk {
+	ch := make(chan StreamChunk)
+	go func() {
+		defer close(ch)
+		send := func(chunk StreamChunk) bool {
+			select {
+			case ch <- chunk:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		message, err := c.GenerateCommitMessage(ctx, diff, history, answers)
+		if err != nil {
+			send(StreamChunk{Done: true, Err: err})
+			return
+		}
+		lines := strings.SplitAfter(message, "\n")
+		for i := range lines {
+			if !send(StreamChunk{Partial: strings.Join(lines[:i+1], "")}) {
+				return
+			}
+		}
+		send(StreamChunk{Done: true, Message: message})
+	}()
+	return ch
+}
+
+func (c *MockClient) AnalyzeHistory(ctx context.Context, diff string, history string) (*HistoryAnalysisResponse, error) {
+	if err := c.answer(ctx); err != nil {
+		return nil, fmt.Errorf("failed to analyze history: %w", err)
+	}
+	result := *c.fixtures.History
+	return &result, nil
+}
+
+func (c *MockClient) SummarizeDiff(ctx context.Context, diff string, part, total int) (string, error) {
+	if err := c.answer(ctx); err != nil {
+		return "", fmt.Errorf("failed to summarize part %d of the diff: %w", part, err)
+	}
+	return c.fixtures.Summary, nil
+}
+
+func (c *MockClient) ScoreMessage(ctx context.Context, diff string, message string) (*ScoreResponse, error) {
+	if err := c.answer(ctx); err != nil {
+		return nil, fmt.Errorf("failed to score commit message: %w", err)
+	}
+	result := *c.fixtures.Score
+	return &result, nil
+}
+
+func (c *MockClient) ProposeSplit(ctx context.Context, hunks string) (*SplitResponse, error) {
+	if err := c.answer(ctx); err != nil {
+		return nil, fmt.Errorf("failed to propose a split: %w", err)
+	}
+	result := *c.fixtures.Split
+	return &result, nil
+}
+
+func (c *MockClient) CritiqueMessage(ctx context.Context, diff string, message string) (*CritiqueResponse, error) {
+	if err := c.answer(ctx); err != nil {
+		return nil, fmt.Errorf("failed to critique commit message: %w", err)
+	}
+	result := *c.fixtures.Critique
+	return &result, nil
+}
+
+func (c *MockClient) ShortenSubject(ctx context.Context, message string, limit int) (string, error) {
+	if err := c.answer(ctx); err != nil {
+		return "", fmt.Errorf("failed to shorten the subject: %w", err)
+	}
+	return c.fixtures.Subject, nil
+}
+
+func (c *MockClient) RewriteSentence(ctx context.Context, diff string, message string, sentence string) (string, error) {
+	if err := c.answer(ctx); err != nil {
+		return "", fmt.Errorf("failed to rewrite the sentence: %w", err)
+	}
+	return c.fixtures.Sentence, nil
+}
+
+func (c *MockClient) DescribePullRequest(ctx context.Context, diff string, commits string) (*PullRequestResponse, error) {
+	if err := c.answer(ctx); err != nil {
+		return nil, fmt.Errorf("failed to describe pull request: %w", err)
+	}
+	result := *c.fixtures.PullRequest
+	return &result, nil
+}
+
+func (c *MockClient) ExplainCommits(ctx context.Context, diff string, commits string) (*ExplanationResponse, error) {
+	if err := c.answer(ctx); err != nil {
+		return nil, fmt.Errorf("failed to explain the commits: %w", err)
+	}
+	result := *c.fixtures.Explanation
+	return &result, nil
+}
+
+func (c *MockClient) WriteReleaseNotes(ctx context.Context, commits string) (*ReleaseNotesResponse, error) {
+	if err := c.answer(ctx); err != nil {
+		return nil, fmt.Errorf("failed to write release notes: %w", err)
+	}
+	result := *c.fixtures.ReleaseNotes
+	return &result, nil
+}
+
+func (c *MockClient) SuggestBranchNames(ctx context.Context, diff string, ticket string) (*BranchNamesResponse, error) {
+	if err := c.answer(ctx); err != nil {
+		return nil, fmt.Errorf("failed to suggest branch names: %w", err)
+	}
+	return &BranchNamesResponse{Names: append([]string(nil), c.fixtures.Branches...)}, nil
+}
+
+// mockDimensions is the length of the mock provider's embeddings.
+const mockDimensions = 16
+
+// Embed derives a unit vector from the hash of each text, so identical
+// texts embed identically and the cache behaves as with a real model.
+func (c *MockClient) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+	if err := c.answer(ctx); err != nil {
+		return nil, fmt.Errorf("failed to embed: %w", err)
+	}
+	out := make([][]float64, len(texts))
+	for i, text := range texts {
+		sum := sha256.Sum256([]byte(text))
+		v := make([]float64, mockDimensions)
+		var norm float64
+		for j := range v {
+			v[j] = float64(int16(binary.BigEndian.Uint16(sum[2*j:])))
+			norm += v[j] * v[j]
+		}
+		for j := range v {
+			v[j] /= math.Sqrt(norm)
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+func (c *MockClient) PreviewPrompts(diff string, history string, answers map[string]string) []Prompt {
+	return c.previewPrompts(openAIHistoryPrompt, openAIQuestionsPrompt, openAICommitMessagePrompt, diff, history, answers)
+}
@@ -0,0 +1,138 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/arpxspace/smartcommit/internal/config"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+	"github.com/openai/openai-go/shared"
+)
+
+// modelTraits are how an OpenAI model family differs in the requests it
+// accepts.
+type modelTraits struct {
+	// structured is whether the model supports JSON schema response
+	// formats. Without them, the schema is described in the prompt.
+	structured bool
+	// systemRole is the role system prompts are sent with: "system",
+	// "developer" for reasoning models, or "user" for those that take
+	// neither.
+	systemRole string
+	// reasoning models take a reasoning effort but no temperature, and
+	// count their reasoning against max_completion_tokens.
+	reasoning bool
+}
+
+// openAITraits returns the traits of an OpenAI model by its name.
+func openAITraits(model string) modelTraits {
+	name := strings.ToLower(model)
+	switch {
+	case strings.HasPrefix(name, "o1-mini"), strings.HasPrefix(name, "o1-preview"):
+		return modelTraits{structured: false, systemRole: "user", reasoning: true}
+	case strings.HasPrefix(name, "o1"), strings.HasPrefix(name, "o3"), strings.HasPrefix(name, "o4"), strings.HasPrefix(name, "gpt-5"):
+		return modelTraits{structured: true, systemRole: "developer", reasoning: true}
+	case name == "gpt-4o-2024-05-13", name == "gpt-4", strings.HasPrefix(name, "gpt-4-"), strings.HasPrefix(name, "gpt-3.5"):
+		return modelTraits{structured: false, systemRole: "system"}
+	}
+	return modelTraits{structured: true, systemRole: "system"}
+}
+
+// useTraits adapts requests to a model's traits.
+func (c *chat) useTraits(t modelTraits) {
+	c.systemRole = t.systemRole
+	c.reasoning = t.reasoning
+	if !t.structured {
+		c.schemaless = true
+		c.fallback = true
+	}
+}
+
+// systemMessage is the system prompt in the role the model takes it in.
+func (c *chat) systemMessage(text string) openai.ChatCompletionMessageParamUnion {
+	switch c.systemRole {
+	case "developer":
+		return openai.DeveloperMessage(text)
+	case "user":
+		return openai.UserMessage(text)
+	}
+	return openai.SystemMessage(text)
+}
+
+// generationOptions returns the options of stage, with Default applied.
+// Requests outside the three stages get Default.
+func (c *chat) generationOptions(stage Stage) config.GenerationOptions {
+	g := c.generation
+	switch stage {
+	case StageHistory:
+		return g.Over(g.History)
+	case StageQuestions:
+		return g.Over(g.Questions)
+	case StageMessage:
+		return g.Over(g.Message)
+	}
+	return g.Default
+}
+
+// generateWith sets the generation options of each stage.
+func (c *chat) generateWith(g config.Generation) {
+	c.generation = g
+}
+
+// applyOptions sets the options of stage on a request, leaving out those
+// the model doesn't take.
+func (c *chat) applyOptions(params *openai.ChatCompletionNewParams, stage Stage) {
+	o := c.generationOptions(stage)
+	if o.Temperature != nil && !c.reasoning {
+		params.Temperature = openai.Float(*o.Temperature)
+	}
+	if o.MaxTokens > 0 {
+		if c.reasoning {
+			params.MaxCompletionTokens = openai.Int(int64(o.MaxTokens))
+		} else {
+			params.MaxTokens = openai.Int(int64(o.MaxTokens))
+		}
+	}
+	if o.ReasoningEffort != "" && c.reasoning {
+		params.ReasoningEffort = shared.ReasoningEffort(o.ReasoningEffort)
+	}
+}
+
+// chatModelPrefixes are the names of OpenAI models that can write messages.
+var chatModelPrefixes = []string{"gpt-3.5-turbo", "gpt-4", "gpt-5", "chatgpt-", "o1", "o3", "o4"}
+
+// nonChatModelMarkers mark models of the chat families that serve other
+// endpoints, such as audio or image generation.
+var nonChatModelMarkers = []string{"audio", "realtime", "transcribe", "tts", "search", "image", "instruct"}
+
+// ListOpenAIModels returns the chat models apiKey has access to, sorted by
+// name, asking with httpClient.
+func ListOpenAIModels(ctx context.Context, httpClient *http.Client, apiKey string) ([]string, error) {
+	client := openai.NewClient(option.WithAPIKey(apiKey), option.WithHTTPClient(httpClient))
+	pager := client.Models.ListAutoPaging(ctx)
+	var models []string
+	for pager.Next() {
+		id := pager.Current().ID
+		chat := slices.ContainsFunc(chatModelPrefixes, func(p string) bool { return strings.HasPrefix(id, p) })
+		other := slices.ContainsFunc(nonChatModelMarkers, func(m string) bool { return strings.Contains(id, m) })
+		if chat && !other {
+			models = append(models, id)
+		}
+	}
+	if err := pager.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list OpenAI models: %w", err)
+	}
+	slices.Sort(models)
+	return models, nil
+}
+
+// SupportsStructuredOutputs reports whether an OpenAI model can be held to
+// a JSON schema.
+func SupportsStructuredOutputs(model string) bool {
+	return openAITraits(model).structured
+}
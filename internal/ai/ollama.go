@@ -0,0 +1,109 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RecommendedOllamaModels are models that work well with smartcommit,
+// offered during setup even when they aren't installed yet.
+var RecommendedOllamaModels = []string{"llama3.1", "qwen2.5-coder", "mistral-nemo", "gemma3"}
+
+// OllamaModel is a model installed on an Ollama server.
+type OllamaModel struct {
+	Name string
+	// Size is the size of the model on disk, in bytes.
+	Size int64
+}
+
+// ListOllamaModels returns the models installed on the Ollama server at
+// baseURL, in the order Ollama lists them, asking with client.
+func ListOllamaModels(ctx context.Context, client *http.Client, baseURL string) ([]OllamaModel, error) {
+	// The configured URL may point at the OpenAI-compatible API under /v1.
+	base := strings.TrimSuffix(strings.TrimRight(baseURL, "/"), "/v1")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, base+"/api/tags", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Ollama models: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to list Ollama models: %s", resp.Status)
+	}
+
+	var body struct {
+		Models []struct {
+			Name string `json:"name"`
+			Size int64  `json:"size"`
+		} `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to parse Ollama models: %w", err)
+	}
+	models := make([]OllamaModel, len(body.Models))
+	for i, m := range body.Models {
+		models[i] = OllamaModel{Name: m.Name, Size: m.Size}
+	}
+	return models, nil
+}
+
+// ollamaShowTimeout bounds asking an Ollama server about a model, which
+// shouldn't hold up a commit when the server is slow to answer.
+const ollamaShowTimeout = 5 * time.Second
+
+// OllamaContextLength returns the context window, in tokens, that model
+// runs with on the Ollama server at baseURL, asking with client: the
+// num_ctx parameter of its Modelfile when it sets one, or else the context
+// length the model was trained with.
+func OllamaContextLength(ctx context.Context, client *http.Client, baseURL, model string) (int, error) {
+	base := strings.TrimSuffix(strings.TrimRight(baseURL, "/"), "/v1")
+	reqBody, err := json.Marshal(map[string]string{"model": model})
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, base+"/api/show", bytes.NewReader(reqBody))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to show Ollama model %s: %w", model, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("failed to show Ollama model %s: %s", model, resp.Status)
+	}
+
+	var body struct {
+		Parameters string         `json:"parameters"`
+		ModelInfo  map[string]any `json:"model_info"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return 0, fmt.Errorf("failed to parse Ollama model %s: %w", model, err)
+	}
+	// Parameters are one "name value" pair per line.
+	for _, line := range strings.Split(body.Parameters, "\n") {
+		if name, value, ok := strings.Cut(strings.TrimSpace(line), " "); ok && name == "num_ctx" {
+			if n, err := strconv.Atoi(strings.TrimSpace(value)); err == nil && n > 0 {
+				return n, nil
+			}
+		}
+	}
+	// Model info is keyed by architecture, like "llama.context_length".
+	for key, value := range body.ModelInfo {
+		if n, ok := value.(float64); ok && n > 0 && strings.HasSuffix(key, ".context_length") {
+			return int(n), nil
+		}
+	}
+	return 0, fmt.Errorf("no context length reported for Ollama model %s", model)
+}
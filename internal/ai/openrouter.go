@@ -0,0 +1,96 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/arpxspace/smartcommit/internal/config"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+)
+
+// OpenRouterURL is the OpenAI-compatible API OpenRouter serves.
+const OpenRouterURL = "https://openrouter.ai/api/v1/"
+
+// NewOpenRouterClient creates a client for model on OpenRouter, which serves
+// models from many vendors behind the OpenAI API. The attribution headers
+// name smartcommit as the app making the requests.
+func NewOpenRouterClient(apiKey, model string, opts ...option.RequestOption) *OpenAIClient {
+	client := openai.NewClient(append([]option.RequestOption{
+		option.WithBaseURL(OpenRouterURL),
+		option.WithAPIKey(apiKey),
+		option.WithHeader("HTTP-Referer", "https://github.com/arpxspace/smartcommit"),
+		option.WithHeader("X-Title", "smartcommit"),
+		// Don't forward OpenAI settings picked up from the environment.
+		option.WithHeaderDel("openai-organization"),
+		option.WithHeaderDel("openai-project"),
+	}, opts...)...)
+	return &OpenAIClient{
+		chat: chat{client: &client, model: model},
+	}
+}
+
+// OpenRouterModel is a model offered on OpenRouter.
+type OpenRouterModel struct {
+	ID            string
+	Name          string
+	ContextLength int
+	// Price is in USD per million tokens. Priced is false when OpenRouter
+	// doesn't list a fixed price.
+	Price  config.Price
+	Priced bool
+}
+
+// ListOpenRouterModels returns the models OpenRouter offers, sorted by ID,
+// asking with client. The list is public, so no API key is needed.
+func ListOpenRouterModels(ctx context.Context, client *http.Client) ([]OpenRouterModel, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, OpenRouterURL+"models", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list OpenRouter models: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to list OpenRouter models: %s", resp.Status)
+	}
+
+	var body struct {
+		Data []struct {
+			ID            string `json:"id"`
+			Name          string `json:"name"`
+			ContextLength int    `json:"context_length"`
+			Pricing       struct {
+				Prompt     string `json:"prompt"`
+				Completion string `json:"completion"`
+			} `json:"pricing"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to parse OpenRouter models: %w", err)
+	}
+
+	models := make([]OpenRouterModel, 0, len(body.Data))
+	for _, d := range body.Data {
+		m := OpenRouterModel{ID: d.ID, Name: d.Name, ContextLength: d.ContextLength}
+		// Prices are listed in USD per token, as strings; negative ones
+		// vary by request.
+		prompt, perr := strconv.ParseFloat(d.Pricing.Prompt, 64)
+		completion, cerr := strconv.ParseFloat(d.Pricing.Completion, 64)
+		if perr == nil && cerr == nil && prompt >= 0 && completion >= 0 {
+			m.Price = config.Price{Prompt: prompt * 1e6, Completion: completion * 1e6}
+			m.Priced = true
+		}
+		models = append(models, m)
+	}
+	slices.SortFunc(models, func(a, b OpenRouterModel) int { return strings.Compare(a.ID, b.ID) })
+	return models, nil
+}
@@ -0,0 +1,420 @@
+package ai
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/arpxspace/smartcommit/internal/config"
+)
+
+// System prompts for each provider and pipeline stage.
+const (
+	openAIQuestionsPrompt = `
+You are an expert software developer assisting a user in writing a commit message.
+Your goal is to understand the "why" behind the changes.
+Analyze the provided git diff and recent project history.
+Generate 3 short, specific questions to ask the user to clarify the intent and 'why' behind the changes.
+The questions should focus on the "why" and "how" if it's not obvious. Try to look at the changes holistically and
+not get fixated on irrelevant changes that aren't worth getting clarification from.
+(Example: "Why did you decide to comment out the line regarding array initialization")
+
+`
+
+	openAICommitMessagePrompt = `
+You are an expert software developer.
+Generate a commit message following the Conventional Commits specification.
+Use the provided diff, recent project history, and user answers to context questions.
+The commit message should have a clear subject line and a detailed body explaining the "why" only.
+Try to paint a narrative (using the history of the project inline with the recent changes), rather than a prescriptive description.
+Think of the signal:noise ratio. You want the reader of the commit to truly understand the 'why' behind the changes.
+Ensure the tone is professional and consistent with the project history.
+
+DO NOT:
+- Describe what's in the diff
+- Use marketing language
+- Be verbose
+
+Examples:
+1. Comprehensive commit message
+fix: convert template to US-ASCII to fix error
+While working on a feature branch, I added test coverage for
+'/etc/nginx/router_routes.conf'. Running 'bundle exec rake spec' or
+'bundle exec rspec modules/router/spec' worked perfectly, but executing
+'bundle exec rake' caused every test block to fail with:
+
+    ArgumentError:
+      invalid byte sequence in US-ASCII
+
+After some investigation, I discovered that deleting the '.with_content(//)'
+matchers eliminated the failures. The spec file itself appeared clean - no
+visible unusual characters. I could trigger the same issue by loading Puppet
+in the interpreter:
+
+    rake -E 'require "puppet"' spec
+
+Turns out this specific template was uniquely encoded in our repository.
+Everything else was 'us-ascii':
+
+    $ find modules -type f -exec file --mime {} \+ | grep utf
+    modules/router/templates/routes.conf.erb:                          text/plain; charset=utf-8
+
+To pinpoint the problematic byte, I attempted a conversion to US-ASCII, which
+revealed what appeared to be invisible whitespace:
+
+    $ iconv -f UTF8 -t US-ASCII modules/router/templates/routes.conf.erb 2>&1 | tail -n5
+    proxy_intercept_errors off;
+
+    # Set proxy timeout to 50 seconds as a quick fix for problems
+
+    iconv: modules/router/templates/routes.conf.erb:458:3: cannot convert
+
+Once I manually corrected it, the encoding returned to 'US-ASCII':
+
+    $ file --mime modules/router/templates/routes.conf.erb
+    modules/router/templates/routes.conf.erb: text/plain; charset=us-ascii
+
+2. Smaller commit message
+feat(database): semantic similarity matching of chosen personalisation role against user query
+This commit introduces a role-based access control feature using embedding similarity into the database interaction layers. It establishes a system where user roles, extracted from a newly created Database module, are utilized to determine access and personalize responses based on cosine similarity of embeddings between user roles and their input queries.
+
+These changes address the need for a more personalized AI interaction by closely aligning the query processing with user-specific role information. This ensures that responses are tailored to what users would expect based on their data access rights, reducing unnecessary agent calls to data sources that users do not have access to, thus improving system efficiency and user satisfaction.
+`
+
+	openAIHistoryPrompt = `You are an expert software developer.
+Analyze the provided git diff and recent project history.
+Determine if the recent history is relevant to the current changes (e.g., similar files, related features, bug fixes).
+If relevant, extract key context points that should be kept in mind when writing the commit message.
+If not relevant, indicate so.`
+
+	ollamaQuestionsPrompt = `You are an expert software developer assisting a user in writing a commit message.
+Your goal is to understand the "why" behind the changes.
+Analyze the provided git diff and recent project history.
+
+IMPORTANT:
+- Your primary focus MUST be on the STAGED CHANGES (the diff).
+- The recent project history is provided ONLY as supporting context to understand the project's style and ongoing work.
+- Do NOT ask questions about the history unless it directly relates to the current changes.
+
+Generate 3 short, specific questions to ask the user to clarify the intent and context of the changes.
+
+Guidelines:
+- Focus on the "why" and "intent", not just the "what".
+- Avoid generic questions like "What does this change do?".
+- If the changes are self-explanatory, ask for any extra context or side effects.
+
+Examples of GOOD questions:
+- "Why was the timeout increased to 5 seconds?"
+- "What edge case does this nil check handle?"
+- "Is this refactor part of a larger cleanup?"
+
+Examples of BAD questions:
+- "Did you update the file?"
+- "What is the new value of X?"`
+
+	ollamaCommitMessagePrompt = `You are an expert software developer.
+Generate a commit message following the Conventional Commits specification.
+Use the provided diff, recent project history, and user answers to context questions.
+
+Rules:
+1. The subject line MUST be in the format: <type>(<scope>): <description>
+2. Allowed types: feat, fix, docs, style, refactor, perf, test, build, ci, chore, revert.
+3. Keep the subject under 50 characters if possible.
+4. The body should explain "what" and "why", not just "how".
+5. Use the user's answers to provide specific context.
+
+Template:
+<type>(<scope>): <subject>
+
+<body>`
+
+	ollamaHistoryPrompt = `You are an expert software developer.
+Analyze the provided git diff and recent project history.
+Determine if the recent history is relevant to the current changes (e.g., similar files, related features, bug fixes).
+If relevant, extract key context points that should be kept in mind when writing the commit message.
+If not relevant, indicate so.`
+)
+
+// scoreMessagePrompt is shared by every provider since it judges a finished
+// message rather than generating one.
+const scoreMessagePrompt = `You are a strict reviewer of git commit messages.
+Score the commit message against the diff it describes, from 0 to 10 on each of:
+- why_coverage: does it explain WHY the change was made, not just what changed?
+- clarity: is it concise and specific, free of vague words like "improve", "enhance", "update"?
+- convention: does the subject follow Conventional Commits (<type>(<scope>): <description>), stay under 72 characters, and use the imperative mood?
+- cohesion: is the diff one logical change that the message covers as a whole, rather than unrelated changes that belong in separate commits?
+
+Then give a one-sentence verdict naming the single most useful improvement, or what was done well if nothing needs improving.`
+
+// proposeSplitPrompt is shared by every provider. Hunks are numbered so the
+// reply can refer to them.
+const proposeSplitPrompt = `You are an expert software developer who keeps git history clean.
+You are given the staged changes of a commit, split into numbered hunks.
+Decide whether they contain unrelated changes that belong in separate commits, for example a bug fix mixed with a refactor, or two independent features.
+Do not split changes that only make sense together, and do not split just because several files changed.
+If they should be split, group the hunks into logical commits, ordered so each commit builds on the ones before it, and write a Conventional Commits message for each.
+Every hunk must belong to exactly one group. If they shouldn't be split, set should_split to false and return no groups.`
+
+// critiqueMessagePrompt is shared by every provider. The user wrote the
+// message; the model only points out problems and leaves the rewriting to them.
+const critiqueMessagePrompt = `You are a careful reviewer of git commit messages.
+The user wrote the commit message below for the diff that follows. Review it; do not rewrite it.
+Point out:
+- vague_language: words or phrases like "improve", "update", "fix stuff", or "various changes" that hide what actually happened, each with a more specific alternative drawn from the diff.
+- missing_why: what a future reader still won't know about WHY the change was made. Leave it empty if the message explains the motivation.
+- unrelated_changes: changes in the diff the message doesn't mention, or that look unrelated to the rest of the commit and might belong in a separate commit.
+- suggestions: any other concrete edits, such as following Conventional Commits or keeping the subject under 72 characters.
+Be brief and specific. Leave a list empty when there is nothing to say.`
+
+// shortenSubjectPrompt is shared by every provider. Only the subject is
+// rewritten; the body stays as generated.
+const shortenSubjectPrompt = `You are an expert software developer editing a git commit message.
+The subject line of the commit message below is too long. Rewrite it to fit the character limit given.
+- Keep any Conventional Commits type and scope, gitmoji, and ticket key exactly as they are.
+- Keep the imperative mood, and do not end the subject with a period.
+- Say the same thing in fewer words: drop filler and details the body already covers, but keep what the change does.
+Return only the new subject line.`
+
+// rewriteSentencePrompt is shared by every provider. Only the sentence is
+// rewritten, so the rest of a message the user may have edited stays theirs.
+const rewriteSentencePrompt = `You are an expert software developer editing a git commit message.
+One sentence of the commit message below uses vague words like "improve", "enhance", "stuff", or "various fixes" that hide what actually changed.
+Rewrite only that sentence to say specifically what the diff changes: name the behavior, function, option, or bug involved.
+- If the sentence is the subject line, keep any Conventional Commits type and scope, gitmoji, and ticket key exactly as they are, keep the imperative mood, and keep it under 72 characters.
+- Keep the meaning and roughly the length; do not add claims the diff and message don't support.
+Return only the rewritten sentence.`
+
+// summarizeDiffPrompt is shared by every provider. It condenses one part of a
+// change too large to send whole, so later stages can reason about all of it.
+const summarizeDiffPrompt = `You are an expert software developer.
+You are given one part of a git diff that is too large to review at once.
+Summarize what this part changes, file by file, in a few short bullet points.
+Be concrete: name the functions, types, configuration keys, and behavior that changed.
+Note anything that looks like a bug fix, a breaking change, or a behavior change for users.
+Do not speculate about why the change was made.`
+
+// describePullRequestPrompt is shared by every provider. It turns a
+// branch's commits into a pull request a reviewer can read first.
+const describePullRequestPrompt = `You are an expert software developer opening a pull request.
+You are given the commit messages on a branch, oldest first, and the cumulative diff against the base branch.
+Write a pull request title and description for reviewers:
+- title: one short line saying what the branch does as a whole.
+- summary: the main changes, a few bullet points; group small related commits.
+- motivation: why the change is needed, taken from the commit messages. Do not invent reasons they don't give.
+- testing: how the change was tested if the commits say so, otherwise what a reviewer should check.
+Be concise and concrete. Do not describe the diff line by line or use marketing language.`
+
+// explainCommitsPrompt is shared by every provider. It explains history
+// someone didn't write, so it keeps to what the commits show.
+const explainCommitsPrompt = `You are an expert software developer helping a colleague understand history they didn't write.
+You are given one or more existing commit messages, oldest first, and the diff the commits make together.
+Explain in plain language what the commits do and why:
+- summary: what the change does as a whole, in two or three sentences someone new to the code can follow.
+- changes: the main changes, one sentence each, naming the files, functions, or behavior involved; group small related edits.
+- why: the reason for the change as the messages or the diff show it. Leave it empty rather than guess.
+- notes: side effects, risks, or loose ends worth knowing about, if any.
+Describe what the diff actually does, even where the messages say otherwise, and point out where they disagree.
+Be concise and concrete; do not describe the diff line by line.`
+
+// releaseNotesPrompt is shared by every provider. It rewrites commit
+// subjects for the people using a release rather than its developers.
+const releaseNotesPrompt = `You are writing the release notes for a new version of a software project.
+You are given the commits in the release, grouped by their Conventional Commits type, with any details from their bodies.
+Write the notes for users of the project, sorted into the Keep a Changelog categories: added, changed, deprecated, removed, fixed, and security.
+- Write each entry as one short, plain sentence about the effect on users, not the implementation.
+- Merge commits that describe the same change into one entry, and leave out changes users won't notice, like refactoring, tests, and CI.
+- Start breaking changes with "BREAKING:" and say what users must do.
+- Keep any scope only if it helps users find the feature.
+Leave a category empty when nothing belongs in it.`
+
+// suggestBranchPrompt is shared by every provider. It names a branch for
+// work that may not have started yet, following Conventional Branch.
+const suggestBranchPrompt = `You are an expert software developer naming a git branch.
+You are given the uncommitted changes in a working tree, a ticket describing the work, or both.
+Propose two or three branch names, best first, of the form <type>/<description>:
+- type: the Conventional Commits type of the work, such as feat, fix, docs, refactor, perf, test, build, ci, or chore.
+- description: two to five lowercase words joined by hyphens, saying what the work does, like auth-token-rotation.
+- If the ticket has a key like PROJ-123 or an issue number, start the description with it, like fix/PROJ-123-null-session or feat/42-dark-mode.
+Use only lowercase letters, digits, and hyphens apart from a ticket key, and keep names under 50 characters.
+Make the names differ in wording or type, not just word order.`
+
+// adaptiveQuestionsGuidance is added to the questions prompt when the model
+// decides how many questions a change deserves.
+const adaptiveQuestionsGuidance = `
+Decide how many questions the change deserves:
+- Ask none when the change is small and self-explanatory, such as a typo fix, a dependency bump, or a rename.
+- Ask one or two when the intent of a focused change isn't obvious from the diff.
+- Ask the most for large, cross-cutting, or architectural changes, where the reasons and trade-offs matter most.
+Return an empty list when there is nothing worth asking.`
+
+// languageGuidance is added to the questions and message prompts when they
+// should be written in a language other than English. Conventional Commits
+// types and scopes stay as they are so the subject can still be parsed.
+const languageGuidance = `
+Write %s in %s.
+Keep Conventional Commits types and scopes, code identifiers, file paths, and commands exactly as they are; do not translate them.`
+
+// inLanguage adds languageGuidance to a built-in prompt for the stage.
+func inLanguage(system string, stage Stage, language string) string {
+	what := "the commit message subject and body"
+	if stage == StageQuestions {
+		what = "the questions"
+	}
+	return strings.TrimRight(system, "\n") + "\n" + fmt.Sprintf(languageGuidance, what, language)
+}
+
+// depthGuidance is added to the questions and message prompts for each
+// config.Depth other than the default.
+var depthGuidance = map[string]map[Stage]string{
+	config.DepthBrief: {
+		StageQuestions: `
+Only ask about what the diff leaves truly unclear, and keep each question to one line.`,
+		StageMessage: `
+Keep the body brief: one or two sentences on why the change was made, or none when the subject says it all.`,
+	},
+	config.DepthDeep: {
+		StageQuestions: `
+Dig deep: ask about the motivation, the alternatives that were considered and why they were rejected, the trade-offs accepted, and any risks or follow-up work.`,
+		StageMessage: `
+Write a thorough body: explain the motivation, the approach taken and the alternatives rejected, the trade-offs accepted, and anything reviewers or future readers should watch out for.`,
+	},
+}
+
+// atDepth adds depthGuidance to a built-in prompt for the stage.
+func atDepth(system string, stage Stage, depth string) string {
+	return strings.TrimRight(system, "\n") + "\n" + depthGuidance[depth][stage]
+}
+
+// questionCount rewrites a built-in questions prompt to ask for n questions,
+// or with adaptive, for as many as the change deserves up to n.
+func questionCount(system string, n int, adaptive bool) string {
+	if !adaptive {
+		return strings.Replace(system, "Generate 3 short", fmt.Sprintf("Generate %d short", n), 1)
+	}
+	system = strings.Replace(system, "Generate 3 short", fmt.Sprintf("Generate between 0 and %d short", n), 1)
+	return strings.TrimRight(system, "\n") + "\n" + adaptiveQuestionsGuidance
+}
+
+// Stage identifies a step of the generation pipeline.
+type Stage string
+
+const (
+	StageHistory   Stage = "history analysis"
+	StageQuestions Stage = "questions"
+	StageMessage   Stage = "commit message"
+)
+
+// Prompt is exactly what a provider sends for one pipeline stage.
+type Prompt struct {
+	Stage  Stage
+	System string
+	User   string
+}
+
+// Bytes returns the combined size of the system and user messages.
+func (p Prompt) Bytes() int {
+	return len(p.System) + len(p.User)
+}
+
+// EstimateTokens approximates the token count using the common
+// four-characters-per-token rule of thumb.
+func (p Prompt) EstimateTokens() int {
+	return (p.Bytes() + 3) / 4
+}
+
+// Hash returns the hex SHA-256 of the system and user messages.
+func (p Prompt) Hash() string {
+	sum := sha256.Sum256([]byte(p.System + "\x00" + p.User))
+	return hex.EncodeToString(sum[:])
+}
+
+// analysisUserPrompt is the user message for history analysis and question generation.
+func analysisUserPrompt(diff, history string) string {
+	return fmt.Sprintf("Diff:\n%s\n\nRecent History:\n%s", diff, history)
+}
+
+// commitMessageUserPrompt is the user message for commit message generation.
+func commitMessageUserPrompt(diff, history string, answers map[string]string) string {
+	// Sort questions so the same session always produces the same prompt.
+	questions := make([]string, 0, len(answers))
+	for q := range answers {
+		questions = append(questions, q)
+	}
+	sort.Strings(questions)
+
+	var qaPairs strings.Builder
+	for _, q := range questions {
+		fmt.Fprintf(&qaPairs, "Q: %s\nA: %s\n", q, answers[q])
+	}
+	return fmt.Sprintf("Diff:\n%s\n\nRecent History:\n%s\n\nUser Context:\n%s", diff, history, qaPairs.String())
+}
+
+// scoreUserPrompt is the user message for scoring a finished commit message.
+func scoreUserPrompt(diff, message string) string {
+	return fmt.Sprintf("Commit Message:\n%s\n\nDiff:\n%s", message, diff)
+}
+
+func shortenUserPrompt(message string, limit int) string {
+	return fmt.Sprintf("Limit: %d characters\n\nCommit Message:\n%s", limit, message)
+}
+
+func rewriteUserPrompt(diff, message, sentence string) string {
+	return fmt.Sprintf("Sentence:\n%s\n\nCommit Message:\n%s\n\nDiff:\n%s", sentence, message, diff)
+}
+
+func pullRequestUserPrompt(diff, commits string) string {
+	return fmt.Sprintf("Commits:\n%s\n\nDiff:\n%s", commits, diff)
+}
+
+func branchUserPrompt(diff, ticket string) string {
+	var parts []string
+	if ticket != "" {
+		parts = append(parts, "Ticket:\n"+ticket)
+	}
+	if diff != "" {
+		parts = append(parts, "Changes:\n"+diff)
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+func summarizeUserPrompt(part, total int, diff string) string {
+	return fmt.Sprintf("Part %d of %d:\n%s", part, total, diff)
+}
+
+// MessagePromptHash returns the hash of the prompt client sends to generate
+// a commit message, as recorded in provenance notes.
+func MessagePromptHash(client Provider, diff, history string, answers map[string]string) string {
+	for _, p := range client.PreviewPrompts(diff, history, answers) {
+		if p.Stage == StageMessage {
+			return p.Hash()
+		}
+	}
+	return ""
+}
+
+// previewPrompts assembles the prompts for every stage using the given
+// system prompts. A template that fails to render is shown as the built-in
+// prompt; generating reports the error.
+func (c *chat) previewPrompts(historyPrompt, questionsPrompt, messagePrompt, diff, history string, answers map[string]string) []Prompt {
+	var prompts []Prompt
+	for _, stage := range []struct {
+		stage  Stage
+		system string
+	}{
+		{StageHistory, historyPrompt},
+		{StageQuestions, questionsPrompt},
+		{StageMessage, messagePrompt},
+	} {
+		p, _ := c.prompt(stage.stage, stage.system, diff, history, answers)
+		prompts = append(prompts, p)
+	}
+	return prompts
+}
+
+// SplitPromptHash returns the hash of the prompt sent to propose a split of
+// hunks, as recorded in provenance notes for the resulting commits.
+func SplitPromptHash(hunks string) string {
+	return Prompt{System: proposeSplitPrompt, User: hunks}.Hash()
+}
@@ -0,0 +1,110 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/invopop/jsonschema"
+)
+
+// parseError is a reply that isn't the JSON object asked for.
+type parseError struct {
+	err error
+}
+
+func (e *parseError) Error() string {
+	return "failed to parse JSON response: " + e.err.Error()
+}
+
+func (e *parseError) Unwrap() error {
+	return e.err
+}
+
+// decodeJSON decodes a model's JSON reply to schema into out. A reply that
+// isn't valid JSON as it stands, as from servers without Structured
+// Outputs, is repaired first: Markdown fences and prose around the object
+// are dropped and trailing commas removed. Replies missing a required field
+// are rejected, since a model ignoring the schema may leave any out.
+func decodeJSON(raw string, schema responseSchema, out any) error {
+	if err := json.Unmarshal([]byte(raw), out); err != nil {
+		repaired := repairJSON(raw)
+		if repaired == raw || json.Unmarshal([]byte(repaired), out) != nil {
+			return &parseError{err}
+		}
+		raw = repaired
+	}
+	if missing := missingFields(raw, schema); len(missing) > 0 {
+		return &parseError{fmt.Errorf("missing %s", strings.Join(missing, ", "))}
+	}
+	return nil
+}
+
+// missingFields returns the required top-level fields of schema that the
+// object in raw lacks.
+func missingFields(raw string, schema responseSchema) []string {
+	s, ok := schema.Schema.(*jsonschema.Schema)
+	if !ok {
+		return nil
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(raw), &fields); err != nil {
+		return nil
+	}
+	var missing []string
+	for _, name := range s.Required {
+		if _, ok := fields[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	slices.Sort(missing)
+	return missing
+}
+
+// repairJSON returns the outermost object in raw without trailing commas.
+func repairJSON(raw string) string {
+	s := strings.TrimSpace(raw)
+	if start, end := strings.Index(s, "{"), strings.LastIndex(s, "}"); start >= 0 && end > start {
+		s = s[start : end+1]
+	}
+
+	var b strings.Builder
+	inString, escaped := false, false
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case ch == '\\':
+				escaped = true
+			case ch == '"':
+				inString = false
+			}
+			b.WriteByte(ch)
+			continue
+		}
+		switch ch {
+		case '"':
+			inString = true
+		case ',':
+			next := strings.TrimLeft(s[i+1:], " \t\r\n")
+			if strings.HasPrefix(next, "}") || strings.HasPrefix(next, "]") {
+				continue
+			}
+		}
+		b.WriteByte(ch)
+	}
+	return b.String()
+}
+
+// schemaInstruction asks for a reply matching schema in the system prompt,
+// for servers that can't be made to follow one.
+func schemaInstruction(schema responseSchema) string {
+	data, err := json.MarshalIndent(schema.Schema, "", "  ")
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("\n\nRespond with only a JSON object (%s) matching this JSON schema, without Markdown fences or any other text:\n%s", schema.Description, data)
+}
@@ -0,0 +1,62 @@
+package ai
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRepairJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{"valid", `{"a": 1}`, `{"a": 1}`},
+		{"markdown fence", "```json\n{\"a\": 1}\n```", `{"a": 1}`},
+		{"surrounding prose", "Here you go:\n{\"a\": 1}\nHope that helps!", `{"a": 1}`},
+		{"trailing comma in object", `{"a": 1, "b": 2,}`, `{"a": 1, "b": 2}`},
+		{"trailing comma in array", `{"a": [1, 2, ]}`, `{"a": [1, 2 ]}`},
+		{"trailing comma before newline", "{\"a\": 1,\n}", "{\"a\": 1\n}"},
+		{"comma in string is kept", `{"a": "x,}"}`, `{"a": "x,}"}`},
+		{"escaped quote in string", `{"a": "say \",}\"",}`, `{"a": "say \",}\""}`},
+		{"nested objects", "```\n{\"a\": {\"b\": [1,],},}\n```", `{"a": {"b": [1]}}`},
+		{"no object", "no JSON here", "no JSON here"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := repairJSON(tt.raw); got != tt.want {
+				t.Errorf("repairJSON(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want CommitMessageResponse
+		ok   bool
+	}{
+		{"valid", `{"subject": "fix: x", "body": "y"}`, CommitMessageResponse{Subject: "fix: x", Body: "y"}, true},
+		{"repaired", "```json\n{\"subject\": \"fix: x\", \"body\": \"y\",}\n```", CommitMessageResponse{Subject: "fix: x", Body: "y"}, true},
+		{"missing field", `{"subject": "fix: x"}`, CommitMessageResponse{}, false},
+		{"not JSON", "fix: x", CommitMessageResponse{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got CommitMessageResponse
+			err := decodeJSON(tt.raw, commitMessageSchema, &got)
+			if (err == nil) != tt.ok {
+				t.Fatalf("decodeJSON(%q) = %v, want ok %v", tt.raw, err, tt.ok)
+			}
+			var parseErr *parseError
+			if err != nil && !errors.As(err, &parseErr) {
+				t.Errorf("decodeJSON(%q) = %T, want *parseError", tt.raw, err)
+			}
+			if tt.ok && got != tt.want {
+				t.Errorf("decodeJSON(%q) decoded %+v, want %+v", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
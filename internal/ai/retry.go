@@ -0,0 +1,200 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/arpxspace/smartcommit/internal/logging"
+)
+
+// Backoff bounds for retried requests. The wait doubles with each attempt,
+// from retryBaseDelay up to retryMaxDelay, unless the provider says how
+// long to wait.
+const (
+	retryBaseDelay = time.Second
+	retryMaxDelay  = 30 * time.Second
+)
+
+// RetryStatus describes a request waiting to be retried.
+type RetryStatus struct {
+	// Attempt is the retry being waited for, from 1.
+	Attempt int
+	// Reason is why the last attempt failed, e.g. "rate limited".
+	Reason string
+	// At is when the retry is sent.
+	At time.Time
+}
+
+// String describes the status for a spinner line, e.g. "rate limited,
+// retrying in 4s…".
+func (s RetryStatus) String() string {
+	wait := time.Until(s.At).Round(time.Second)
+	if wait < time.Second {
+		return fmt.Sprintf("%s, retrying…", s.Reason)
+	}
+	return fmt.Sprintf("%s, retrying in %s…", s.Reason, wait)
+}
+
+// retryTransport retries requests the provider rejected as rate limited or
+// failed with a server error, with exponential backoff and jitter, honoring
+// Retry-After when the provider sends it. It gives up after retries
+// attempts and returns the last response as is. Each attempt, including
+// reading its response, must finish within timeout; one that doesn't fails
+// without being retried.
+type retryTransport struct {
+	base    http.RoundTripper
+	retries int
+	timeout time.Duration
+
+	mu      sync.Mutex
+	waiting *RetryStatus
+}
+
+func newRetryTransport(base http.RoundTripper, retries int, timeout time.Duration) *retryTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &retryTransport{base: base, retries: retries, timeout: timeout}
+}
+
+// status returns the retry being waited for, if any.
+func (t *retryTransport) status() (RetryStatus, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.waiting == nil {
+		return RetryStatus{}, false
+	}
+	return *t.waiting, true
+}
+
+func (t *retryTransport) setStatus(s *RetryStatus) {
+	t.mu.Lock()
+	t.waiting = s
+	t.mu.Unlock()
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Every attempt needs the body again.
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := t.attempt(req, body)
+		if errors.Is(err, context.DeadlineExceeded) && req.Context().Err() == nil {
+			logging.Warn("provider request timed out", "timeout", t.timeout)
+			return nil, timeoutError{timeout: t.timeout}
+		}
+		reason := retryReason(resp, err)
+		if reason == "" || attempt >= t.retries || req.Context().Err() != nil {
+			return resp, err
+		}
+		wait := backoff(attempt, resp)
+		if resp != nil {
+			// Drain so the connection can be reused.
+			io.Copy(io.Discard, resp.Body) // Ignore error, the response is discarded
+			resp.Body.Close()
+		}
+
+		logging.Warn("provider request retried", "attempt", attempt+1, "reason", reason, "wait", wait.Round(time.Millisecond))
+		t.setStatus(&RetryStatus{Attempt: attempt + 1, Reason: reason, At: time.Now().Add(wait)})
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+			t.setStatus(nil)
+		case <-req.Context().Done():
+			timer.Stop()
+			t.setStatus(nil)
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+// attempt sends req with body once, bounded by the timeout until its
+// response body is closed.
+func (t *retryTransport) attempt(req *http.Request, body []byte) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+	req = req.WithContext(ctx)
+	if body != nil {
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelOnClose releases an attempt's timeout once its body is closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b cancelOnClose) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
+
+// retryReason says why a round trip is worth retrying, or returns "" if it
+// isn't.
+func retryReason(resp *http.Response, err error) string {
+	if err != nil {
+		return "connection failed"
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		return "rate limited"
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return fmt.Sprintf("provider error %d", resp.StatusCode)
+	}
+	return ""
+}
+
+// backoff returns how long to wait before retrying after attempt: what the
+// provider asked for, or an exponential delay with jitter.
+func backoff(attempt int, resp *http.Response) time.Duration {
+	if wait, ok := retryAfter(resp); ok {
+		return min(wait, retryMaxDelay)
+	}
+	delay := min(retryBaseDelay<<attempt, retryMaxDelay)
+	// Jitter over the upper half so concurrent clients spread out.
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// retryAfter reads the wait the provider asked for from retry-after-ms or
+// Retry-After, in seconds or as an HTTP date.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+	if ms, err := strconv.ParseFloat(resp.Header.Get("Retry-After-Ms"), 64); err == nil && ms > 0 {
+		return time.Duration(ms * float64(time.Millisecond)), true
+	}
+	value := resp.Header.Get("Retry-After")
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+		return time.Duration(seconds * float64(time.Second)), true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(at); wait > 0 {
+			return wait, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,166 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/openai/openai-go"
+)
+
+// StreamChunk is one update of a streamed commit message. Every chunk but
+// the last carries the message as far as it has arrived; the last has Done
+// set along with the complete message or an error.
+type StreamChunk struct {
+	Partial string
+	Done    bool
+	Message string
+	Err     error
+}
+
+func (c *chat) generateCommitMessageStream(ctx context.Context, systemPrompt, diff, history string, answers map[string]string) <-chan StreamChunk {
+	ch := make(chan StreamChunk)
+	go func() {
+		defer close(ch)
+		send := func(chunk StreamChunk) bool {
+			select {
+			case ch <- chunk:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		fail := func(err error) {
+			send(StreamChunk{Done: true, Err: fmt.Errorf("failed to generate commit message: %w", err)})
+		}
+
+		p, err := c.prompt(StageMessage, systemPrompt, diff, history, answers)
+		if err != nil {
+			send(StreamChunk{Done: true, Err: err})
+			return
+		}
+		params := c.params(StageMessage, p.System, p.User, c.commitMessageSchema())
+		params.StreamOptions = openai.ChatCompletionStreamOptionsParam{IncludeUsage: openai.Bool(true)}
+		stream := c.client.Chat.Completions.NewStreaming(ctx, params)
+		defer stream.Close()
+
+		var raw strings.Builder
+		var usage openai.CompletionUsage
+		for stream.Next() {
+			chunk := stream.Current()
+			if chunk.Usage.TotalTokens > 0 {
+				usage = chunk.Usage
+			}
+			if len(chunk.Choices) == 0 || chunk.Choices[0].Delta.Content == "" {
+				continue
+			}
+			raw.WriteString(chunk.Choices[0].Delta.Content)
+			if !send(StreamChunk{Partial: c.partialMessage(raw.String())}) {
+				return
+			}
+		}
+		c.record(usage)
+		var message string
+		if err = stream.Err(); err == nil {
+			message, err = c.parseMessage(raw.String())
+		}
+		if err != nil && c.fallBack(err) {
+			// Ask again as plain text, without streaming: the reply is
+			// only shown once it can be decoded.
+			var retry json.RawMessage
+			if err = c.complete(ctx, StageMessage, p.System, p.User, c.commitMessageSchema(), &retry); err == nil {
+				message, err = c.parseMessage(string(retry))
+			}
+		}
+		if err != nil {
+			fail(err)
+			return
+		}
+		send(StreamChunk{Done: true, Message: message})
+	}()
+	return ch
+}
+
+// formatMessage joins a subject and body into a commit message.
+func formatMessage(subject, body string) string {
+	if body == "" {
+		return subject
+	}
+	return fmt.Sprintf("%s\n\n%s", subject, body)
+}
+
+// partialMessage formats the message in a reply to commitMessageSchema
+// that may still be arriving.
+func (c *chat) partialMessage(raw string) string {
+	if c.messageSchema == nil {
+		return formatMessage(partialString(raw, "subject"), partialString(raw, "body"))
+	}
+	var subject string
+	if typ := partialString(raw, "type"); typ != "" {
+		subject = typ
+		if emoji := partialString(raw, "emoji"); c.gitmoji != nil && emoji != "" {
+			subject = emoji + " " + typ
+		}
+		if scope := partialString(raw, "scope"); scope != "" {
+			subject += "(" + scope + ")"
+		}
+		if strings.Contains(raw, `"breaking":true`) || strings.Contains(raw, `"breaking": true`) {
+			subject += "!"
+		}
+		subject += ": " + partialString(raw, "description")
+	}
+	return formatMessage(subject, partialString(raw, "body"))
+}
+
+// partialString returns the value of the string field key in an incomplete
+// JSON object, as far as it has been written.
+func partialString(raw, key string) string {
+	i := strings.Index(raw, `"`+key+`"`)
+	if i < 0 {
+		return ""
+	}
+	rest := strings.TrimLeft(raw[i+len(key)+2:], " \t\r\n")
+	if !strings.HasPrefix(rest, ":") {
+		return ""
+	}
+	rest = strings.TrimLeft(rest[1:], " \t\r\n")
+	if !strings.HasPrefix(rest, `"`) {
+		return ""
+	}
+	rest = rest[1:]
+
+	var b strings.Builder
+	for i := 0; i < len(rest); i++ {
+		switch ch := rest[i]; ch {
+		case '"':
+			return b.String()
+		case '\\':
+			if i+1 >= len(rest) {
+				return b.String()
+			}
+			i++
+			switch esc := rest[i]; esc {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r', 'b', 'f':
+			case 'u':
+				if i+4 >= len(rest) {
+					return b.String()
+				}
+				if r, err := strconv.ParseUint(rest[i+1:i+5], 16, 32); err == nil {
+					b.WriteRune(rune(r))
+				}
+				i += 4
+			default:
+				b.WriteByte(esc)
+			}
+		default:
+			b.WriteByte(ch)
+		}
+	}
+	return b.String()
+}
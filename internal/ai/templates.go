@@ -0,0 +1,81 @@
+package ai
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// PromptTemplatesDir is the directory, under the config directory, that
+// prompt templates are read from.
+const PromptTemplatesDir = "prompts"
+
+// templateFiles names the template that replaces each stage's prompt.
+var templateFiles = map[Stage]string{
+	StageHistory:   "history.tmpl",
+	StageQuestions: "questions.tmpl",
+	StageMessage:   "message.tmpl",
+}
+
+// PromptData is what prompt templates are executed with.
+type PromptData struct {
+	Diff    string
+	History string
+	Answers map[string]string
+	// Language is the configured language, or empty for English.
+	Language string
+	// Default is the built-in system prompt, for templates that extend it.
+	Default string
+}
+
+// templates are the user's prompt templates by stage. A template's output
+// is the system prompt; if it defines a "user" template, that replaces the
+// user message too.
+type templates map[Stage]*template.Template
+
+// loadTemplates reads the templates in dir. Stages without one keep their
+// built-in prompts.
+func loadTemplates(dir string) (templates, error) {
+	t := make(templates)
+	for stage, name := range templateFiles {
+		path := filepath.Join(dir, name)
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		tmpl, err := template.New(name).Option("missingkey=error").Parse(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse prompt template %s: %w", path, err)
+		}
+		t[stage] = tmpl
+	}
+	return t, nil
+}
+
+// apply renders the template for p's stage, if there is one.
+func (t templates) apply(p Prompt, data PromptData) (Prompt, error) {
+	tmpl := t[p.Stage]
+	if tmpl == nil {
+		return p, nil
+	}
+	data.Default = p.System
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, data); err != nil {
+		return p, fmt.Errorf("failed to render %s prompt template: %w", p.Stage, err)
+	}
+	p.System = strings.TrimSpace(b.String())
+	if user := tmpl.Lookup("user"); user != nil {
+		b.Reset()
+		if err := user.Execute(&b, data); err != nil {
+			return p, fmt.Errorf("failed to render %s prompt template: %w", p.Stage, err)
+		}
+		p.User = strings.TrimSpace(b.String())
+	}
+	return p, nil
+}
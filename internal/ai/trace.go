@@ -0,0 +1,174 @@
+package ai
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/arpxspace/smartcommit/internal/redact"
+)
+
+// sensitiveHeaders are never written to a trace file.
+var sensitiveHeaders = map[string]bool{
+	"authorization":       true,
+	"api-key":             true,
+	"x-api-key":           true,
+	"openai-organization": true,
+	"openai-project":      true,
+	"cookie":              true,
+	"set-cookie":          true,
+}
+
+// traceEntry is one request/response round trip as written to the trace file.
+type traceEntry struct {
+	Time           time.Time         `json:"time"`
+	Method         string            `json:"method"`
+	URL            string            `json:"url"`
+	RequestHeader  map[string]string `json:"request_headers"`
+	RequestBody    json.RawMessage   `json:"request_body,omitempty"`
+	Status         int               `json:"status,omitempty"`
+	ResponseHeader map[string]string `json:"response_headers,omitempty"`
+	ResponseBody   json.RawMessage   `json:"response_body,omitempty"`
+	Error          string            `json:"error,omitempty"`
+	DurationMS     int64             `json:"duration_ms"`
+}
+
+// traceTransport records every provider round trip as a JSON line in path.
+// Credentials are stripped from headers and any known secret values are
+// replaced in both request and response bodies before anything is written.
+type traceTransport struct {
+	base    http.RoundTripper
+	path    string
+	secrets []string
+	mu      sync.Mutex
+}
+
+func newTraceTransport(base http.RoundTripper, path string, secrets ...string) *traceTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &traceTransport{base: base, path: path, secrets: secrets}
+}
+
+func (t *traceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	entry := traceEntry{
+		Time:          time.Now(),
+		Method:        req.Method,
+		URL:           t.redact(req.URL.String()),
+		RequestHeader: t.headers(req.Header),
+	}
+
+	if req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		entry.RequestBody = t.body(body)
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		entry.Error = err.Error()
+		entry.DurationMS = time.Since(entry.Time).Milliseconds()
+		t.write(entry)
+		return nil, err
+	}
+
+	entry.Status = resp.StatusCode
+	entry.ResponseHeader = t.headers(resp.Header)
+
+	// Tee the body so streamed responses reach the caller unchanged; the entry
+	// is written once the caller has finished reading.
+	resp.Body = &traceBody{
+		ReadCloser: resp.Body,
+		done: func(body []byte) {
+			entry.ResponseBody = t.body(body)
+			entry.DurationMS = time.Since(entry.Time).Milliseconds()
+			t.write(entry)
+		},
+	}
+	return resp, nil
+}
+
+func (t *traceTransport) headers(h http.Header) map[string]string {
+	out := make(map[string]string, len(h))
+	for k, v := range h {
+		if sensitiveHeaders[strings.ToLower(k)] {
+			out[k] = redact.Placeholder
+			continue
+		}
+		out[k] = t.redact(strings.Join(v, ", "))
+	}
+	return out
+}
+
+// body returns the redacted payload as raw JSON, quoting it as a string when
+// the payload isn't JSON (e.g. server-sent events or an HTML error page).
+func (t *traceTransport) body(b []byte) json.RawMessage {
+	if len(b) == 0 {
+		return nil
+	}
+	clean := t.redact(string(b))
+	if json.Valid([]byte(clean)) {
+		return json.RawMessage(clean)
+	}
+	quoted, _ := json.Marshal(clean)
+	return quoted
+}
+
+// redact strips the configured credentials and anything else that looks like
+// a secret (e.g. a key pasted into a diff) from s.
+func (t *traceTransport) redact(s string) string {
+	return redact.Secrets(s, t.secrets...)
+}
+
+func (t *traceTransport) write(entry traceEntry) {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	f, err := os.OpenFile(t.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	f.Write(append(line, '\n'))
+}
+
+// traceBody buffers everything read from the wrapped body and hands it to
+// done exactly once, on EOF or Close, whichever comes first.
+type traceBody struct {
+	io.ReadCloser
+	buf  bytes.Buffer
+	done func([]byte)
+	once sync.Once
+}
+
+func (b *traceBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.buf.Write(p[:n])
+	if err == io.EOF {
+		b.finish()
+	}
+	return n, err
+}
+
+func (b *traceBody) Close() error {
+	b.finish()
+	return b.ReadCloser.Close()
+}
+
+func (b *traceBody) finish() {
+	b.once.Do(func() { b.done(b.buf.Bytes()) })
+}
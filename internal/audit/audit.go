@@ -0,0 +1,134 @@
+// Package audit reports on the quality of a repository's commit messages,
+// for teams tracking how their history reads over time.
+package audit
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/arpxspace/smartcommit/internal/git"
+	"github.com/arpxspace/smartcommit/internal/guard"
+	"github.com/arpxspace/smartcommit/internal/quality"
+	"github.com/arpxspace/smartcommit/internal/style"
+)
+
+// Rated is a commit with the quality of its message.
+type Rated struct {
+	Commit git.Commit
+	Result quality.Result
+}
+
+// Report summarizes the messages of a run of commits.
+type Report struct {
+	// Profile holds the counts of commits, bodies, and Conventional
+	// Commits, and the average subject length.
+	Profile style.Profile
+	// LongSubjects counts subjects over SubjectLimit characters.
+	LongSubjects int
+	SubjectLimit int
+	// AverageScore is the mean quality score, from 0 to 100.
+	AverageScore int
+	// Worst are the lowest-scoring commits, worst first.
+	Worst []Rated
+	// Newest and Oldest are the hashes the report covers.
+	Newest, Oldest string
+}
+
+// Build audits commits, newest first, skipping merge commits as
+// style.Analyze does. Subjects are held to limit characters, or
+// guard.DefaultSubjectLength when it's 0; vague words count against a
+// message's score; and the worst n are listed.
+func Build(commits []git.Commit, limit int, vague []string, n int) Report {
+	if limit == 0 {
+		limit = guard.DefaultSubjectLength
+	}
+	r := Report{Profile: style.Analyze(commits), SubjectLimit: limit}
+	var rated []Rated
+	total := 0
+	for _, c := range commits {
+		if strings.HasPrefix(c.Subject, "Merge ") {
+			continue
+		}
+		if r.Newest == "" {
+			r.Newest = c.Hash
+		}
+		r.Oldest = c.Hash
+		if utf8.RuneCountInString(c.Subject) > limit {
+			r.LongSubjects++
+		}
+		message := c.Subject
+		if c.Body != "" {
+			message += "\n\n" + c.Body
+		}
+		result := quality.Rate(message, c.Files, vague)
+		total += result.Score
+		rated = append(rated, Rated{Commit: c, Result: result})
+	}
+	if len(rated) == 0 {
+		return r
+	}
+	r.AverageScore = total / len(rated)
+	// Stable, so equally bad commits stay newest first.
+	slices.SortStableFunc(rated, func(a, b Rated) int { return a.Result.Score - b.Result.Score })
+	r.Worst = rated[:min(n, len(rated))]
+	return r
+}
+
+// Markdown formats the report with a table of measures and one of the
+// worst offenders, dated now.
+func (r Report) Markdown(now time.Time) string {
+	var b strings.Builder
+	b.WriteString("# Commit Message Audit\n\n")
+	p := r.Profile
+	if p.Commits == 0 {
+		b.WriteString("No commits to audit.\n")
+		return b.String()
+	}
+	fmt.Fprintf(&b, "The last %d commits, %s to %s, audited on %s. Merge commits are left out.\n\n",
+		p.Commits, short(r.Oldest), short(r.Newest), now.Format("2006-01-02"))
+
+	b.WriteString("| Measure | Value |\n|---|---|\n")
+	fmt.Fprintf(&b, "| Commits with a body | %s |\n", percent(p.WithBody, p.Commits))
+	fmt.Fprintf(&b, "| Conventional Commits | %s |\n", percent(p.Conventional, p.Commits))
+	fmt.Fprintf(&b, "| Average subject length | %d characters |\n", p.AvgSubjectLength)
+	fmt.Fprintf(&b, "| Subjects over %d characters | %s |\n", r.SubjectLimit, percent(r.LongSubjects, p.Commits))
+	fmt.Fprintf(&b, "| Average quality score | %d/100 |\n", r.AverageScore)
+	if len(p.Types) > 0 {
+		var types []string
+		for _, t := range p.Types[:min(len(p.Types), 5)] {
+			types = append(types, fmt.Sprintf("%s (%d)", t.Value, t.N))
+		}
+		fmt.Fprintf(&b, "| Most used types | %s |\n", strings.Join(types, ", "))
+	}
+
+	if len(r.Worst) > 0 {
+		b.WriteString("\n## Worst Offenders\n\n")
+		b.WriteString("| Commit | Subject | Score | Tip |\n|---|---|---|---|\n")
+		for _, w := range r.Worst {
+			fmt.Fprintf(&b, "| %s | %s | %d | %s |\n", short(w.Commit.Hash), cell(w.Commit.Subject), w.Result.Score, cell(w.Result.Suggestion))
+		}
+	}
+	return b.String()
+}
+
+func percent(n, of int) string {
+	return fmt.Sprintf("%d%% (%d of %d)", n*100/of, n, of)
+}
+
+func short(hash string) string {
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+	return hash
+}
+
+// cell escapes text for a Markdown table cell.
+func cell(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return strings.ReplaceAll(s, "|", `\|`)
+}
@@ -0,0 +1,125 @@
+package changelog
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/arpxspace/smartcommit/internal/conventional"
+	"github.com/arpxspace/smartcommit/internal/git"
+)
+
+// Entry is one commit as it appears in a changelog.
+type Entry struct {
+	Hash        string `json:"hash"`
+	Scope       string `json:"scope,omitempty"`
+	Description string `json:"description"`
+	Breaking    bool   `json:"breaking,omitempty"`
+	// Body is the commit's body, as context for writing release notes.
+	Body string `json:"-"`
+}
+
+// Section groups the entries of one commit type.
+type Section struct {
+	Type    string  `json:"type"`
+	Title   string  `json:"title"`
+	Entries []Entry `json:"entries"`
+}
+
+// sectionTitles orders the sections and names them. Types not listed here
+// are collected under "Other Changes".
+var sectionTitles = []struct{ Type, Title string }{
+	{"feat", "Features"},
+	{"fix", "Bug Fixes"},
+	{"perf", "Performance"},
+	{"refactor", "Refactoring"},
+	{"docs", "Documentation"},
+	{"revert", "Reverts"},
+}
+
+const otherType = "other"
+
+// Build groups commits into changelog sections. Commits that don't follow
+// Conventional Commits, and chore/test/ci/build/style commits, are listed
+// under "Other Changes" unless they are breaking.
+func Build(commits []git.Commit) []Section {
+	byType := map[string][]Entry{}
+	for _, c := range commits {
+		h, ok := conventional.Parse(c.Subject)
+		if !ok {
+			byType[otherType] = append(byType[otherType], Entry{Hash: c.Hash, Description: c.Subject, Body: c.Body})
+			continue
+		}
+		e := Entry{
+			Hash:        c.Hash,
+			Scope:       h.Scope,
+			Description: h.Description,
+			Breaking:    h.Breaking || strings.Contains(c.Body, "BREAKING CHANGE:"),
+			Body:        c.Body,
+		}
+		t := h.Type
+		if !known(t) {
+			t = otherType
+		}
+		byType[t] = append(byType[t], e)
+	}
+
+	var sections []Section
+	for _, st := range sectionTitles {
+		if entries := byType[st.Type]; len(entries) > 0 {
+			sections = append(sections, Section{Type: st.Type, Title: st.Title, Entries: entries})
+		}
+	}
+	if entries := byType[otherType]; len(entries) > 0 {
+		sections = append(sections, Section{Type: otherType, Title: "Other Changes", Entries: entries})
+	}
+	return sections
+}
+
+func known(t string) bool {
+	for _, st := range sectionTitles {
+		if st.Type == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Markdown renders sections as a Markdown changelog, with breaking changes
+// repeated in their own section at the top.
+func Markdown(sections []Section) string {
+	var b strings.Builder
+	var breaking []Entry
+	for _, s := range sections {
+		for _, e := range s.Entries {
+			if e.Breaking {
+				breaking = append(breaking, e)
+			}
+		}
+	}
+	if len(breaking) > 0 {
+		writeSection(&b, "⚠ Breaking Changes", breaking)
+	}
+	for _, s := range sections {
+		writeSection(&b, s.Title, s.Entries)
+	}
+	return b.String()
+}
+
+func writeSection(b *strings.Builder, title string, entries []Entry) {
+	fmt.Fprintf(b, "### %s\n\n", title)
+	for _, e := range entries {
+		b.WriteString("- ")
+		if e.Scope != "" {
+			fmt.Fprintf(b, "**%s:** ", e.Scope)
+		}
+		fmt.Fprintf(b, "%s (%s)\n", e.Description, short(e.Hash))
+	}
+	b.WriteString("\n")
+}
+
+func short(hash string) string {
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+	return hash
+}
@@ -0,0 +1,131 @@
+package changelog
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// maxBodyLength bounds how much of each commit body is given to the model.
+const maxBodyLength = 600
+
+// Text renders sections for the model writing release notes: each commit's
+// description, scope, and whether it breaks compatibility, with the start of
+// its body.
+func Text(sections []Section) string {
+	var b strings.Builder
+	for _, s := range sections {
+		fmt.Fprintf(&b, "%s:\n", s.Title)
+		for _, e := range s.Entries {
+			b.WriteString("- ")
+			if e.Scope != "" {
+				fmt.Fprintf(&b, "(%s) ", e.Scope)
+			}
+			b.WriteString(e.Description)
+			if e.Breaking {
+				b.WriteString(" [BREAKING]")
+			}
+			b.WriteString("\n")
+			if body := strings.TrimSpace(e.Body); body != "" {
+				if len(body) > maxBodyLength {
+					body = body[:maxBodyLength] + "..."
+				}
+				b.WriteString("  " + strings.ReplaceAll(body, "\n", "\n  ") + "\n")
+			}
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// Unreleased is the version heading for changes not yet released.
+const Unreleased = "Unreleased"
+
+// Release is one version's entry in a Keep a Changelog file.
+type Release struct {
+	// Version is the version number, or Unreleased.
+	Version string
+	// Date is the release date as YYYY-MM-DD; it's left out for Unreleased.
+	Date string
+	// Groups are the categories of changes in Keep a Changelog order.
+	Groups []Group
+}
+
+// Group is one category of changes, such as "Added" or "Fixed".
+type Group struct {
+	Title string
+	Items []string
+}
+
+// Markdown renders r as a Keep a Changelog section.
+func (r Release) Markdown() string {
+	var b strings.Builder
+	b.WriteString("## [" + r.Version + "]")
+	if r.Date != "" && r.Version != Unreleased {
+		b.WriteString(" - " + r.Date)
+	}
+	b.WriteString("\n\n")
+	for _, g := range r.Groups {
+		if len(g.Items) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "### %s\n\n", g.Title)
+		for _, item := range g.Items {
+			b.WriteString("- " + strings.TrimSpace(item) + "\n")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// header starts a new Keep a Changelog file.
+const header = `# Changelog
+
+All notable changes to this project will be documented in this file.
+
+The format is based on [Keep a Changelog](https://keepachangelog.com/en/1.1.0/),
+and this project adheres to [Semantic Versioning](https://semver.org/spec/v2.0.0.html).
+
+`
+
+// versionHeading matches a release heading such as "## [1.2.0] - 2024-01-01".
+var versionHeading = regexp.MustCompile(`(?m)^## \[([^\]]+)\]`)
+
+// linkDefinitions matches the start of the version links that end a
+// Keep a Changelog file, such as "[1.2.0]: https://...".
+var linkDefinitions = regexp.MustCompile(`(?m)^\[[^\]]+\]: `)
+
+// Update adds r to the Keep a Changelog file content, replacing the section
+// for the same version if there is one. Otherwise a release goes below the
+// Unreleased section and above earlier releases. Empty content starts a new
+// file.
+func Update(content string, r Release) string {
+	section := r.Markdown()
+	if strings.TrimSpace(content) == "" {
+		return header + section
+	}
+	headings := versionHeading.FindAllStringSubmatchIndex(content, -1)
+	for i, h := range headings {
+		if content[h[2]:h[3]] != r.Version {
+			continue
+		}
+		end := len(content)
+		if i+1 < len(headings) {
+			end = headings[i+1][0]
+		} else if loc := linkDefinitions.FindStringIndex(content[h[0]:]); loc != nil {
+			end = h[0] + loc[0]
+		}
+		return content[:h[0]] + section + strings.TrimLeft(content[end:], "\n")
+	}
+	for _, h := range headings {
+		if content[h[2]:h[3]] == Unreleased && r.Version != Unreleased {
+			continue
+		}
+		return content[:h[0]] + section + content[h[0]:]
+	}
+	end := len(content)
+	if loc := linkDefinitions.FindStringIndex(content); loc != nil {
+		end = loc[0]
+	}
+	return strings.TrimRight(content[:end], "\n") + "\n\n" + section + content[end:]
+}
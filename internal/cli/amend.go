@@ -0,0 +1,122 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/arpxspace/smartcommit/internal/ai"
+	"github.com/arpxspace/smartcommit/internal/diff"
+	"github.com/arpxspace/smartcommit/internal/git"
+	"github.com/arpxspace/smartcommit/internal/provenance"
+	"github.com/arpxspace/smartcommit/internal/staged"
+)
+
+// runAmend folds the staged changes into HEAD, revising HEAD's message to
+// cover the amended change rather than writing a new one from scratch.
+func runAmend(args []string) int {
+	fs := newFlagSet("smartcommit amend")
+	noEdit := fs.Bool("no-edit", false, "amend with the revised message without opening the editor")
+	force := fs.Bool("force", false, "amend even if HEAD has already been pushed")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: smartcommit amend [--no-edit] [--force]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return 2
+	}
+
+	if !git.IsRepo() {
+		return fail(fmt.Errorf("not a git repository"))
+	}
+	if !git.HasHead() {
+		return fail(fmt.Errorf("there is no commit to amend yet"))
+	}
+	pushed, err := git.IsPushed("HEAD")
+	if err != nil {
+		return fail(err)
+	}
+	if pushed && !*force {
+		return fail(fmt.Errorf("HEAD has already been pushed; amending it rewrites shared history (use --force to do it anyway)"))
+	}
+
+	cfg, client, err := newProvider()
+	if err != nil {
+		return fail(err)
+	}
+	old, err := git.CommitMessage("HEAD")
+	if err != nil {
+		return fail(err)
+	}
+	change, err := staged.CollectAmend(cfg)
+	if err != nil {
+		return fail(err)
+	}
+	sc := change.Context(cfg)
+	if secrets := sc.Secrets(); len(secrets) > 0 {
+		fmt.Fprintf(os.Stderr, "smartcommit: redacting %d possible secret(s) before sending, in %s\n", len(secrets), strings.Join(secretPaths(secrets), ", "))
+	}
+	d := sc.Diff()
+	if trimmed := sc.Trimmed(); len(trimmed) > 0 {
+		fmt.Fprintf(os.Stderr, "smartcommit: trimmed to fit the context window: %s\n", strings.Join(trimmed, ", "))
+	}
+	if sc.TooLarge() {
+		if d, err = summarize(client, sc); err != nil {
+			recordUsage(cfg, "amend", client)
+			return fail(err)
+		}
+	}
+	history, err := git.GetRecentHistory(10)
+	if err != nil {
+		return fail(err)
+	}
+	history = sc.FitHistory(relatedHistory(cfg, client, sc, d, history))
+
+	answers := map[string]string{
+		"What does the commit's current message say? (Revise it to cover the amended change; keep what still applies.)": old,
+	}
+	if rawStaged, err := git.GetStagedDiff(); err == nil && strings.TrimSpace(rawStaged) != "" {
+		answers["Which files does the amendment add changes to?"] = strings.Join(diff.Paths(diff.Parse(rawStaged)), ", ")
+	}
+
+	fmt.Fprintln(os.Stderr, "Revising the message...")
+	message, err := generateConventional(cfg, client, d, history, answers)
+	recordUsage(cfg, "amend", client)
+	if err != nil {
+		return fail(err)
+	}
+	if cfg.APIChangesInBody {
+		if section := sc.APIChanges(); section != "" {
+			message = strings.TrimRight(message, "\n") + "\n\n" + strings.TrimRight(section, "\n")
+		}
+	}
+	if message, err = sc.Render(message); err != nil {
+		return fail(err)
+	}
+	message = guardMessage(cfg, client, message)
+
+	if !*noEdit {
+		if message, err = git.EditMessage(message); err != nil {
+			return fail(err)
+		}
+		if message == "" {
+			return fail(fmt.Errorf("aborting amend due to empty message"))
+		}
+	}
+	if err := git.Amend(message, cfg.SignArgs()...); err != nil {
+		return fail(err)
+	}
+	if cfg.Provenance {
+		promptHash := ai.MessagePromptHash(client, d, history, answers)
+		if err := provenance.WriteHead(string(cfg.Provider), client.Model(), promptHash); err != nil {
+			fmt.Fprintf(os.Stderr, "smartcommit: could not record provenance: %v\n", err)
+		}
+	}
+	subject, _, _ := strings.Cut(strings.TrimSpace(message), "\n")
+	fmt.Fprintf(os.Stderr, "Amended: %s\n", subject)
+	return 0
+}
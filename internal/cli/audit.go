@@ -0,0 +1,61 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/arpxspace/smartcommit/internal/audit"
+	"github.com/arpxspace/smartcommit/internal/config"
+	"github.com/arpxspace/smartcommit/internal/git"
+	"github.com/arpxspace/smartcommit/internal/staged"
+)
+
+// runAudit reports on the messages of recent commits as Markdown: how many
+// have a body and follow Conventional Commits, how long subjects run, and
+// which messages score worst. Teams can commit the report to follow the
+// trend.
+func runAudit(args []string) int {
+	fs := newFlagSet("smartcommit audit")
+	last := fs.Int("last", 100, "audit the last `n` commits")
+	worst := fs.Int("worst", 10, "list the `n` worst messages")
+	output := fs.String("output", "", "write the report to `file` instead of stdout")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: smartcommit audit [--last n] [--worst n] [--output file]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return 2
+	}
+	if *last < 1 {
+		return fail(fmt.Errorf("--last must be at least 1"))
+	}
+	if !git.IsRepo() {
+		return fail(fmt.Errorf("not a git repository"))
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return fail(err)
+	}
+	if cfg, err = staged.Configure(cfg); err != nil {
+		return fail(err)
+	}
+
+	commits, err := git.GetLogFiles(*last)
+	if err != nil {
+		return fail(err)
+	}
+	report := audit.Build(commits, cfg.Conventions.MaxSubjectLength, cfg.Vague(), max(*worst, 0)).Markdown(time.Now())
+	if *output == "" {
+		fmt.Print(report)
+		return 0
+	}
+	if err := os.WriteFile(*output, []byte(report), 0o644); err != nil {
+		return fail(err)
+	}
+	return 0
+}
@@ -0,0 +1,256 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/arpxspace/smartcommit/internal/ai"
+	"github.com/arpxspace/smartcommit/internal/config"
+	"github.com/arpxspace/smartcommit/internal/diff"
+	"github.com/arpxspace/smartcommit/internal/git"
+	"github.com/arpxspace/smartcommit/internal/guard"
+	"github.com/arpxspace/smartcommit/internal/provenance"
+	"github.com/arpxspace/smartcommit/internal/related"
+	"github.com/arpxspace/smartcommit/internal/staged"
+)
+
+// autoOptions are the command line options of an --auto run.
+type autoOptions struct {
+	// Trace records provider requests to this file, if set.
+	Trace string
+	// Profile is applied in place of the configured one, if set.
+	Profile string
+	// DiffFile is described in place of the staged changes, if set; "-"
+	// reads the diff from stdin.
+	DiffFile string
+	// Output is written in place of stdout, if set, in Format.
+	Output string
+	Format string
+	// Privacy redacts the diff whether or not the config asks to.
+	Privacy bool
+	// Commit commits with the message instead of printing it.
+	Commit bool
+	// Issue is the number of a GitHub issue fetched as context, or 0.
+	Issue int
+	// CoAuthors are credited in addition to the configured co-authors.
+	CoAuthors []string
+}
+
+// runAuto generates a message for the staged changes, or the diff given in
+// opts, without the TUI or clarifying questions, for use in aliases, hooks,
+// and bots. The message is printed, or committed when opts asks to;
+// progress and warnings go to stderr so the output can be captured.
+func runAuto(opts autoOptions) int {
+	if opts.DiffFile == "" && !git.IsRepo() {
+		return fail(fmt.Errorf("not a git repository"))
+	}
+	cfg, client, err := newTracedProvider(opts.Trace, opts.Profile)
+	if err != nil {
+		return fail(err)
+	}
+	cfg.CoAuthors = append(cfg.CoAuthors, opts.CoAuthors...)
+
+	message, d, history, err := autoMessage(cfg, client, opts.DiffFile, opts.Privacy, opts.Issue)
+	recordUsage(cfg, "auto", client)
+	if err != nil {
+		return fail(err)
+	}
+
+	if !opts.Commit {
+		if err := writeResult(opts.Output, opts.Format, newResult(cfg, client, message, nil, nil)); err != nil {
+			return fail(err)
+		}
+		return 0
+	}
+	if err := git.CommitWithMessage(message, cfg.SignArgs()...); err != nil {
+		return fail(err)
+	}
+	if cfg.Provenance {
+		promptHash := ai.MessagePromptHash(client, d, history, nil)
+		if err := provenance.WriteHead(string(cfg.Provider), client.Model(), promptHash); err != nil {
+			fmt.Fprintf(os.Stderr, "smartcommit: could not record provenance: %v\n", err)
+		}
+	}
+	subject, _, _ := strings.Cut(strings.TrimSpace(message), "\n")
+	fmt.Fprintf(os.Stderr, "Committed: %s\n", subject)
+	if opts.Format == formatJSON {
+		if err := writeResult(opts.Output, opts.Format, newResult(cfg, client, message, nil, nil)); err != nil {
+			return fail(err)
+		}
+	}
+	return 0
+}
+
+// autoMessage generates a message for the staged changes, or the diff in
+// diffFile if set, without questions, returning the diff and history it was
+// generated from. Warnings go to stderr. A non-zero issue is fetched from
+// GitHub as context.
+func autoMessage(cfg *config.Config, client ai.Provider, diffFile string, privacy bool, issue int) (message, d, history string, err error) {
+	change, err := collectChange(cfg, diffFile)
+	if err != nil {
+		return "", "", "", err
+	}
+	// The branch name says nothing about a diff made elsewhere.
+	if diffFile == "" || issue != 0 {
+		if err := change.FetchIssue(context.Background(), cfg, issue); err != nil {
+			if issue != 0 {
+				return "", "", "", err
+			}
+			fmt.Fprintf(os.Stderr, "smartcommit: continuing without issue context: %v\n", err)
+		}
+	}
+	sc := change.Context(cfg)
+	sc.Redact = sc.Redact || privacy
+	if secrets := sc.Secrets(); len(secrets) > 0 {
+		fmt.Fprintf(os.Stderr, "smartcommit: redacting %d possible secret(s) before sending, in %s\n", len(secrets), strings.Join(secretPaths(secrets), ", "))
+	}
+	d = sc.Diff()
+	if trimmed := sc.Trimmed(); len(trimmed) > 0 {
+		fmt.Fprintf(os.Stderr, "smartcommit: trimmed to fit the context window: %s\n", strings.Join(trimmed, ", "))
+	}
+	if len(sc.Included()) == 0 {
+		return "", "", "", fmt.Errorf("every staged file is excluded or ignored in %s", config.RepoConfigFile)
+	}
+	if sc.TooLarge() {
+		if d, err = summarize(client, sc); err != nil {
+			return "", "", "", err
+		}
+	}
+	if git.IsRepo() {
+		if history, err = git.GetRecentHistory(10); err != nil {
+			return "", "", "", err
+		}
+		history = sc.FitHistory(relatedHistory(cfg, client, sc, d, history))
+	}
+
+	message, err = generateConventional(cfg, client, d, history, nil)
+	if err != nil {
+		return "", "", "", err
+	}
+	if cfg.APIChangesInBody {
+		if section := sc.APIChanges(); section != "" {
+			message = strings.TrimRight(message, "\n") + "\n\n" + strings.TrimRight(section, "\n")
+		}
+	}
+	if message, err = sc.Render(message); err != nil {
+		return "", "", "", err
+	}
+	message = guardMessage(cfg, client, message)
+
+	return message, d, history, nil
+}
+
+// summarize condenses a change too large for one request into per-part
+// summaries, reporting progress on stderr.
+func summarize(client ai.Provider, sc staged.Context) (string, error) {
+	parts := sc.Parts()
+	if len(parts) > staged.MaxParts {
+		return "", fmt.Errorf("staged diff is too large to summarize (%d parts, at most %d); split the commit or write the message yourself", len(parts), staged.MaxParts)
+	}
+	summaries := make([]string, len(parts))
+	for i, part := range parts {
+		fmt.Fprintf(os.Stderr, "Summarizing part %d of %d...\n", i+1, len(parts))
+		summary, err := client.SummarizeDiff(context.Background(), part, i+1, len(parts))
+		if err != nil {
+			return "", err
+		}
+		summaries[i] = summary
+	}
+	return sc.Summarized(summaries), nil
+}
+
+// relatedHistory returns the related commits or style examples to send in
+// place of history when either is on, or history otherwise or if they can't
+// be chosen.
+func relatedHistory(cfg *config.Config, client ai.Provider, sc staged.Context, d, history string) string {
+	h, err := related.History(context.Background(), client, cfg, diff.Paths(sc.Included()), d)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "smartcommit: using recent history instead: %v\n", err)
+		return history
+	}
+	if h == "" {
+		return history
+	}
+	return h
+}
+
+// maxConventionAttempts bounds how often non-interactive commands regenerate a message
+// whose subject breaks the configured conventions.
+const maxConventionAttempts = 3
+
+// generateConventional generates a message, regenerating it while its
+// subject breaks the configured conventions.
+func generateConventional(cfg *config.Config, client ai.Provider, d, history string, answers map[string]string) (string, error) {
+	var violation error
+	for range maxConventionAttempts {
+		message, err := client.GenerateCommitMessage(context.Background(), d, history, answers)
+		if err != nil {
+			return "", err
+		}
+		if violation = cfg.Conventions.Check(message); violation == nil {
+			return message, nil
+		}
+		fmt.Fprintf(os.Stderr, "smartcommit: regenerating: %v\n", violation)
+	}
+	return "", fmt.Errorf("generated subject still breaks the project's conventions: %w", violation)
+}
+
+// guardMessage fixes the form of a generated message and, when its subject
+// is still over the limit, has the provider shorten it once. What was fixed
+// goes to stderr.
+func guardMessage(cfg *config.Config, client ai.Provider, message string) string {
+	if cfg.DisableGuardrails {
+		return message
+	}
+	message, fixes := guard.Fix(message)
+	if limit := cfg.Conventions.MaxSubjectLength; !guard.SubjectFits(message, limit) {
+		if limit == 0 {
+			limit = guard.DefaultSubjectLength
+		}
+		subject, err := client.ShortenSubject(context.Background(), message, limit)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "smartcommit: keeping the long subject: %v\n", err)
+		} else if shortened, more := guard.Fix(guard.ReplaceSubject(message, subject)); len(shortened) < len(message) {
+			message = shortened
+			fixes = append(append(fixes, "shortened the subject"), more...)
+		}
+	}
+	if len(fixes) > 0 {
+		fmt.Fprintf(os.Stderr, "smartcommit: %s\n", strings.Join(fixes, "; "))
+	}
+	return message
+}
+
+// secretPaths lists the files secrets were found in, once each.
+func secretPaths(secrets []staged.Secret) []string {
+	var paths []string
+	for _, s := range secrets {
+		if !slices.Contains(paths, s.Path) {
+			paths = append(paths, s.Path)
+		}
+	}
+	return paths
+}
+
+// collectChange reads the staged change or, if diffFile is set, the diff in
+// it, or on stdin when it's "-".
+func collectChange(cfg *config.Config, diffFile string) (*staged.Change, error) {
+	if diffFile == "" {
+		return staged.Collect(cfg)
+	}
+	var raw []byte
+	var err error
+	if diffFile == "-" {
+		raw, err = io.ReadAll(os.Stdin)
+	} else {
+		raw, err = os.ReadFile(diffFile)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the diff: %w", err)
+	}
+	return staged.FromDiff(cfg, string(raw))
+}
@@ -0,0 +1,213 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/arpxspace/smartcommit/internal/config"
+	"github.com/arpxspace/smartcommit/internal/diff"
+	"github.com/arpxspace/smartcommit/internal/git"
+	"github.com/arpxspace/smartcommit/internal/redact"
+	"github.com/arpxspace/smartcommit/internal/staged"
+	"github.com/arpxspace/smartcommit/internal/tokens"
+)
+
+// runBranch proposes names for a branch for the uncommitted changes or a
+// ticket, creates the chosen one, and carries the changes onto it or
+// stashes them.
+func runBranch(args []string) int {
+	fs := newFlagSet("smartcommit branch")
+	from := fs.String("from", "", "start the branch at `rev` instead of HEAD, carrying the changes over")
+	stash := fs.Bool("stash", false, "stash the changes instead of carrying them onto the new branch")
+	yes := fs.Bool("yes", false, "create the first proposed branch without asking")
+	ticketKey := fs.String("ticket", "", "the issue `key` the work is for, like PROJ-123")
+	issue := fs.Int("issue", 0, "fetch GitHub issue `number` to describe the work")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: smartcommit branch [--from <rev>] [--stash] [--yes] [--ticket <key>] [--issue <number>] [<description>]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if !git.IsRepo() {
+		return fail(fmt.Errorf("not a git repository"))
+	}
+	cfg, client, err := newProvider()
+	if err != nil {
+		return fail(err)
+	}
+
+	var ticket []string
+	if *ticketKey != "" {
+		ticket = append(ticket, "Key: "+*ticketKey)
+	}
+	if *issue != 0 {
+		change := &staged.Change{}
+		if err := change.FetchIssue(context.Background(), cfg, *issue); err != nil {
+			return fail(err)
+		}
+		ticket = append(ticket, "GitHub issue "+change.Issue)
+	}
+	if fs.NArg() > 0 {
+		ticket = append(ticket, strings.Join(fs.Args(), " "))
+	}
+
+	raw, err := git.WorkingTreeDiff(cfg.Diff.Args()...)
+	if err != nil {
+		return fail(err)
+	}
+	untracked, err := git.UntrackedFiles()
+	if err != nil {
+		return fail(err)
+	}
+	if raw == "" && len(untracked) == 0 && len(ticket) == 0 {
+		return fail(fmt.Errorf("no changes to name a branch after; describe the work or pass --ticket or --issue"))
+	}
+	d := branchDiff(cfg, raw, untracked)
+
+	fmt.Fprintln(os.Stderr, "Suggesting branch names...")
+	resp, err := client.SuggestBranchNames(context.Background(), d, strings.Join(ticket, "\n"))
+	recordUsage(cfg, "branch", client)
+	if err != nil {
+		return fail(err)
+	}
+	var names []string
+	for _, n := range resp.Names {
+		n = branchName(n)
+		if n != "" && !slices.Contains(names, n) && git.CheckBranchName(n) == nil && !git.BranchExists(n) {
+			names = append(names, n)
+		}
+	}
+	if len(names) == 0 {
+		return fail(fmt.Errorf("the model proposed no usable branch names"))
+	}
+
+	name := names[0]
+	if !*yes {
+		if name = chooseBranch(names); name == "" {
+			fmt.Println("No branch created.")
+			return 0
+		}
+	}
+	if err := switchToBranch(name, *from, *stash); err != nil {
+		return fail(err)
+	}
+	return 0
+}
+
+// branchDiff is the working tree as sent for naming a branch: the diff and
+// the names of new files, or just the names of changed files when the diff
+// is over budget. Likely secrets are redacted, as there's no one to confirm
+// sending them.
+func branchDiff(cfg *config.Config, raw string, untracked []string) string {
+	sc := staged.Context{Config: cfg, Files: diff.Parse(raw)}
+	files := sc.Included()
+	d := diff.Join(files)
+	if tokens.Estimate(d) > sc.Budget() {
+		d = "Changed files:\n" + strings.Join(diff.Paths(files), "\n")
+	}
+	if len(untracked) > 0 {
+		d = strings.TrimSpace(d + "\n\nNew files:\n" + strings.Join(untracked, "\n"))
+	}
+	return redact.Secrets(d)
+}
+
+// invalidBranchChars are the characters that don't belong in a branch name
+// as this command writes them.
+var invalidBranchChars = regexp.MustCompile(`[^A-Za-z0-9/._-]+`)
+
+// branchName tidies a proposed name: spaces and odd characters become
+// hyphens, and hyphens don't repeat or start or end a part.
+func branchName(s string) string {
+	s = invalidBranchChars.ReplaceAllString(strings.TrimSpace(s), "-")
+	parts := strings.Split(s, "/")
+	for i, p := range parts {
+		for strings.Contains(p, "--") {
+			p = strings.ReplaceAll(p, "--", "-")
+		}
+		parts[i] = strings.Trim(p, "-.")
+	}
+	return strings.Trim(strings.Join(parts, "/"), "/")
+}
+
+// chooseBranch asks which of names to create, or for a name of the user's
+// own. It returns "" when the user declines.
+func chooseBranch(names []string) string {
+	fmt.Println("Proposed branches:")
+	for i, n := range names {
+		fmt.Printf("  %d. %s\n", i+1, n)
+	}
+	in := bufio.NewReader(os.Stdin)
+	for {
+		fmt.Printf("Create which? [1-%d, a name of your own, or empty to cancel] ", len(names))
+		line, err := in.ReadString('\n')
+		if err != nil && line == "" {
+			// Input ended without an answer.
+			fmt.Println()
+			return ""
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			return ""
+		}
+		if i, err := strconv.Atoi(line); err == nil {
+			if i >= 1 && i <= len(names) {
+				return names[i-1]
+			}
+			fmt.Printf("Choose a number from 1 to %d.\n", len(names))
+			continue
+		}
+		if err := git.CheckBranchName(line); err != nil {
+			fmt.Println(err)
+			continue
+		}
+		if git.BranchExists(line) {
+			fmt.Printf("%s already exists.\n", line)
+			continue
+		}
+		return line
+	}
+}
+
+// switchToBranch creates name and switches to it. Uncommitted changes come
+// along unless stash is set, when they're left in the stash; when the
+// branch starts elsewhere than HEAD, they're stashed on the way and restored.
+func switchToBranch(name, from string, stash bool) error {
+	if !stash && from == "" {
+		if err := git.CreateBranch(name, ""); err != nil {
+			return err
+		}
+		fmt.Printf("Switched to a new branch %s, with your changes.\n", name)
+		return nil
+	}
+
+	stashed, err := git.Stash("smartcommit: before creating " + name)
+	if err != nil {
+		return err
+	}
+	if err := git.CreateBranch(name, from); err != nil {
+		if stashed {
+			return fmt.Errorf("%w; your changes are in the stash, run `git stash pop` to restore them", err)
+		}
+		return err
+	}
+	switch {
+	case !stashed:
+		fmt.Printf("Switched to a new branch %s.\n", name)
+	case stash:
+		fmt.Printf("Switched to a new branch %s. Your changes are stashed; run `git stash pop` to restore them.\n", name)
+	default:
+		if err := git.StashPop(); err != nil {
+			return fmt.Errorf("switched to %s, but %w; your changes are still in the stash", name, err)
+		}
+		fmt.Printf("Switched to a new branch %s, with your changes.\n", name)
+	}
+	return nil
+}
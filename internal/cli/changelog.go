@@ -0,0 +1,107 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/arpxspace/smartcommit/internal/changelog"
+	"github.com/arpxspace/smartcommit/internal/git"
+)
+
+// runChangelog writes release notes for a range of commits, grouped by their
+// Conventional Commits type and rewritten by the AI for users, into a Keep a
+// Changelog file.
+func runChangelog(args []string) int {
+	fs := newFlagSet("smartcommit changelog")
+	from := fs.String("from", "", "the release to start after (default: the latest tag before --to)")
+	to := fs.String("to", "HEAD", "the last commit of the release")
+	version := fs.String("version", "", "the version heading (default: --to if it's a tag, otherwise Unreleased)")
+	file := fs.String("file", "CHANGELOG.md", "the changelog `path`, relative to the repository root")
+	printOnly := fs.Bool("print", false, "print the release's section instead of updating the changelog")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: smartcommit changelog [--from <rev>] [--to <rev>] [--version <version>] [--file <path>] [--print]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return 2
+	}
+	if strings.HasPrefix(*from, "-") || strings.HasPrefix(*to, "-") {
+		return fail(fmt.Errorf("invalid revision"))
+	}
+
+	if !git.IsRepo() {
+		return fail(fmt.Errorf("not a git repository"))
+	}
+	if *from == "" {
+		*from = git.LatestTag(*to + "^")
+	}
+	revRange := *to
+	if *from != "" {
+		revRange = *from + ".." + *to
+	}
+	commits, err := git.GetLogRange(revRange)
+	if err != nil {
+		return fail(err)
+	}
+	if len(commits) == 0 {
+		return fail(fmt.Errorf("no commits in %s", revRange))
+	}
+
+	release := changelog.Release{Version: *version}
+	if release.Version == "" {
+		release.Version = changelog.Unreleased
+		if git.IsTag(*to) {
+			release.Version = strings.TrimPrefix(*to, "v")
+		}
+	}
+	if release.Version != changelog.Unreleased {
+		if release.Date, err = git.CommitDate(*to); err != nil {
+			return fail(err)
+		}
+	}
+
+	cfg, client, err := newProvider()
+	if err != nil {
+		return fail(err)
+	}
+	fmt.Fprintf(os.Stderr, "Writing release notes for %d commit(s) in %s...\n", len(commits), revRange)
+	notes, err := client.WriteReleaseNotes(context.Background(), changelog.Text(changelog.Build(commits)))
+	recordUsage(cfg, "changelog", client)
+	if err != nil {
+		return fail(err)
+	}
+	release.Groups = []changelog.Group{
+		{Title: "Added", Items: notes.Added},
+		{Title: "Changed", Items: notes.Changed},
+		{Title: "Deprecated", Items: notes.Deprecated},
+		{Title: "Removed", Items: notes.Removed},
+		{Title: "Fixed", Items: notes.Fixed},
+		{Title: "Security", Items: notes.Security},
+	}
+
+	if *printOnly {
+		fmt.Print(release.Markdown())
+		return 0
+	}
+	root, err := git.RepoRoot()
+	if err != nil {
+		return fail(err)
+	}
+	path := filepath.Join(root, *file)
+	content, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fail(err)
+	}
+	if err := os.WriteFile(path, []byte(changelog.Update(string(content), release)), 0644); err != nil {
+		return fail(err)
+	}
+	fmt.Fprintf(os.Stderr, "Updated %s with %s.\n", *file, release.Version)
+	return 0
+}
@@ -0,0 +1,329 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/arpxspace/smartcommit/internal/ai"
+	"github.com/arpxspace/smartcommit/internal/config"
+	"github.com/arpxspace/smartcommit/internal/git"
+	"github.com/arpxspace/smartcommit/internal/logging"
+	"github.com/arpxspace/smartcommit/internal/staged"
+	"github.com/arpxspace/smartcommit/internal/trailer"
+	"github.com/arpxspace/smartcommit/internal/tui"
+	"github.com/arpxspace/smartcommit/internal/usage"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Run executes smartcommit with the given arguments (without the program
+// name) and returns the process exit code.
+func Run(args []string) int {
+	if err := startLog(args); err != nil {
+		// Logging is no reason not to commit.
+		fmt.Fprintf(os.Stderr, "smartcommit: not logging: %v\n", err)
+	}
+	defer logging.Close()
+	useGitBackend()
+	if len(args) > 0 {
+		if c, ok := findCommand(commands(), args[0]); ok {
+			return c.run(args[1:])
+		}
+	}
+	return runTUI(args)
+}
+
+// useGitBackend has the repository read and committed to through the
+// backend the config picks.
+func useGitBackend() {
+	switch name := config.GitBackend(); name {
+	case "":
+	case config.GitBackendExec:
+		git.Use(git.ExecBackend())
+	case config.GitBackendGoGit:
+		git.Use(git.GoGitBackend())
+	default:
+		fmt.Fprintf(os.Stderr, "smartcommit: unknown git_backend %q; using the default\n", name)
+	}
+}
+
+// runTUI starts the interactive commit flow.
+func runTUI(args []string) int {
+	fs := newFlagSet("smartcommit")
+	trace := fs.String("trace", "", "record provider requests and responses (with secrets redacted) to `file`")
+	privacy := fs.Bool("privacy", false, "review which files are sent to the provider before the first request")
+	auto := fs.Bool("auto", false, "generate a message without the TUI or questions and print it")
+	commit := fs.Bool("commit", false, "with --auto, commit with the generated message instead of printing it")
+	dryRun := fs.Bool("dry-run", false, "print the prompts that would be sent for the staged changes, without sending them")
+	issue := fs.Int("issue", 0, "fetch GitHub issue `number` as context for the message")
+	stack := fs.Bool("stack", false, "stage all uncommitted work and commit it as a series of logical commits")
+	profile := fs.String("profile", "", "run with `profile`: quick, standard, thorough, or one added in the config")
+	messageOnly := fs.Bool("message-only", false, "print the accepted message instead of committing, drawing the TUI on stderr")
+	output := fs.String("output", "", "with --message-only or --auto, write the message to `file` instead of stdout")
+	format := fs.String("format", formatText, "with --message-only or --auto, print the message as `text` or as json with its type, scope, questions, answers, and cost")
+	stdin := fs.Bool("stdin", false, "describe the diff read from stdin instead of the staged changes, as --auto does")
+	diffFile := fs.String("diff-file", "", "describe the diff in `file` instead of the staged changes, as --auto does")
+	logFile := fs.String("log-file", "", "log every git command and provider request to `file`, for bug reports")
+	var coAuthors identList
+	fs.Var(&coAuthors, "co-author", "credit `\"Name <email>\"` with a Co-authored-by trailer (repeatable)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: smartcommit [options]")
+		fmt.Fprintln(fs.Output(), "       smartcommit <command> [options]")
+		fmt.Fprintln(fs.Output())
+		fmt.Fprintln(fs.Output(), "Without a command, smartcommit helps write a message for the staged changes.")
+		fmt.Fprintln(fs.Output())
+		fmt.Fprintln(fs.Output(), "Options:")
+		fs.PrintDefaults()
+		fmt.Fprintln(fs.Output())
+		printCommands(fs.Output())
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *logFile != "" {
+		if err := logging.Open(*logFile, slog.LevelDebug); err != nil {
+			return fail(err)
+		}
+		logging.Info("started", "args", args)
+	}
+	if *stdin && *diffFile != "" {
+		fmt.Fprintln(os.Stderr, "smartcommit: use either --stdin or --diff-file")
+		return 2
+	}
+	if *stdin {
+		*diffFile = "-"
+	}
+	if *diffFile != "" {
+		// There's nothing staged to commit it with, or to ask about.
+		if *commit || *stack || *messageOnly {
+			fmt.Fprintln(os.Stderr, "smartcommit: --stdin and --diff-file can't be combined with --commit, --stack, or --message-only")
+			return 2
+		}
+		*auto = !*dryRun
+	}
+	if *commit && !*auto {
+		fmt.Fprintln(os.Stderr, "smartcommit: --commit requires --auto")
+		return 2
+	}
+	if *stack && (*auto || *dryRun || *messageOnly) {
+		fmt.Fprintln(os.Stderr, "smartcommit: --stack can't be combined with --auto, --dry-run, or --message-only")
+		return 2
+	}
+	if *messageOnly && (*commit || *dryRun) {
+		fmt.Fprintln(os.Stderr, "smartcommit: --message-only can't be combined with --commit or --dry-run")
+		return 2
+	}
+	if *format != formatText && *format != formatJSON {
+		fmt.Fprintf(os.Stderr, "smartcommit: unknown --format %q; use %q or %q\n", *format, formatText, formatJSON)
+		return 2
+	}
+	if *format == formatJSON && !(*messageOnly || *auto) {
+		fmt.Fprintln(os.Stderr, "smartcommit: --format json requires --message-only or --auto")
+		return 2
+	}
+	if *output != "" && (!(*messageOnly || *auto) || (*commit && *format != formatJSON)) {
+		fmt.Fprintln(os.Stderr, "smartcommit: --output requires --message-only, or --auto without --commit unless --format json")
+		return 2
+	}
+	if *dryRun {
+		return runDryRun(*profile, *diffFile, *privacy, *issue, coAuthors)
+	}
+	if *auto {
+		return runAuto(autoOptions{
+			Trace:     *trace,
+			Profile:   *profile,
+			DiffFile:  *diffFile,
+			Output:    *output,
+			Format:    *format,
+			Privacy:   *privacy,
+			Commit:    *commit,
+			Issue:     *issue,
+			CoAuthors: coAuthors,
+		})
+	}
+	var opts []tea.ProgramOption
+	if *messageOnly {
+		// stdout is left for the message, so a tool can capture it.
+		lipgloss.SetDefaultRenderer(lipgloss.NewRenderer(os.Stderr))
+		opts = append(opts, tea.WithOutput(os.Stderr), tea.WithInputTTY())
+	}
+
+	// A config that doesn't load is left for the TUI to report.
+	if cfg, err := config.Load(); err == nil {
+		if err := configureTUI(cfg); err != nil {
+			return fail(err)
+		}
+		if _, err := cfg.WithProfile(*profile); err != nil {
+			return fail(err)
+		}
+	}
+	p := tea.NewProgram(tui.NewModel(tui.Options{
+		TraceFile:     *trace,
+		PrivacyReview: *privacy,
+		Issue:         *issue,
+		CoAuthors:     coAuthors,
+		Stack:         *stack,
+		Profile:       *profile,
+		MessageOnly:   *messageOnly,
+	}), opts...)
+	final, err := p.Run()
+	m, ok := final.(tui.Model)
+	if ok && m.Config != nil && m.AIClient != nil {
+		usage.Record(m.Config, "commit", m.AIClient, m.AIClient.Usage()) // Ignore error, not critical
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Alas, there's been an error: %v", err)
+		return 1
+	}
+	if *messageOnly {
+		if !ok || m.Output == "" {
+			// Tell the calling tool there's nothing to commit.
+			fmt.Fprintln(os.Stderr, "smartcommit: no message was accepted")
+			return 1
+		}
+		if err := writeResult(*output, *format, newResult(m.Config, m.AIClient, m.Output, m.Questions, m.Answers)); err != nil {
+			return fail(err)
+		}
+	}
+	return 0
+}
+
+// configureTUI has the TUI draw with cfg's theme and respond to its keys.
+func configureTUI(cfg *config.Config) error {
+	t, err := tui.ThemeFor(cfg)
+	if err != nil {
+		return err
+	}
+	k, err := tui.KeysFor(cfg)
+	if err != nil {
+		return err
+	}
+	tui.UseTheme(t)
+	tui.UseKeys(k)
+	return nil
+}
+
+// newProvider loads the config and creates the configured AI provider for
+// commands that run outside the TUI.
+func newProvider() (*config.Config, ai.Provider, error) {
+	return newTracedProvider("", "")
+}
+
+// newTracedProvider is newProvider with provider requests recorded to
+// traceFile, if set, and the named profile applied in place of the
+// configured one, if set. Inside a repository, its repo config applies.
+func newTracedProvider(traceFile, profile string) (*config.Config, ai.Provider, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, nil, err
+	}
+	if git.IsRepo() {
+		if cfg, err = staged.Configure(cfg); err != nil {
+			return nil, nil, err
+		}
+	}
+	if cfg, err = cfg.WithProfile(profile); err != nil {
+		return nil, nil, err
+	}
+	cfg.TraceFile = traceFile
+	client, err := newClient(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cfg, client, nil
+}
+
+// newClient creates the provider configured in cfg, falling back to the
+// environment for API keys.
+func newClient(cfg *config.Config) (ai.Provider, error) {
+	var invalid *config.Invalid
+	if err := cfg.Validate(); errors.As(err, &invalid) {
+		return nil, fmt.Errorf("%w; change it with `smartcommit config set %s` or by running smartcommit", err, invalid.Key)
+	}
+	if cfg.Provider == config.ProviderOpenAI && cfg.OpenAIAPIKey == "" {
+		cfg.OpenAIAPIKey = os.Getenv("OPENAI_API_KEY")
+		if cfg.OpenAIAPIKey == "" {
+			return nil, fmt.Errorf("no OpenAI API key configured; run smartcommit to set up a provider")
+		}
+	}
+	if cfg.Provider == config.ProviderAzure && cfg.AzureAPIKey == "" {
+		cfg.AzureAPIKey = os.Getenv("AZURE_OPENAI_API_KEY")
+	}
+	if cfg.Provider == config.ProviderOpenRouter && cfg.OpenRouterAPIKey == "" {
+		cfg.OpenRouterAPIKey = os.Getenv("OPENROUTER_API_KEY")
+		if cfg.OpenRouterAPIKey == "" {
+			return nil, fmt.Errorf("no OpenRouter API key configured; run smartcommit to set up a provider")
+		}
+	}
+	return ai.NewClient(cfg)
+}
+
+// recordUsage adds the tokens client used to the local usage log and warns
+// on stderr if this month's estimated spend is near the budget.
+func recordUsage(cfg *config.Config, command string, client ai.Provider) {
+	usage.Record(cfg, command, client, client.Usage()) // Ignore error, not critical
+	if warning := usage.Warning(cfg); warning != "" {
+		fmt.Fprintf(os.Stderr, "smartcommit: %s\n", warning)
+	}
+}
+
+// startLog starts the log: everything to the file in $SMARTCOMMIT_DEBUG,
+// or "1" for the default one, or else records at the configured level to
+// the default one, rotated as it grows.
+func startLog(args []string) error {
+	name := os.Getenv(logging.EnvLevel)
+	if name == "" {
+		name = config.LogLevel()
+	}
+	level, on, err := logging.ParseLevel(name)
+	if err != nil {
+		return err
+	}
+	path := os.Getenv(logging.EnvFile)
+	if path != "" {
+		level, on = slog.LevelDebug, true
+	}
+	if !on {
+		return nil
+	}
+	if path == "" || path == "1" {
+		dir, err := config.DataDir()
+		if err != nil {
+			return err
+		}
+		path = filepath.Join(dir, logging.FileName)
+	}
+	if err := logging.Open(path, level); err != nil {
+		return err
+	}
+	logging.Info("started", "args", args)
+	return nil
+}
+
+// fail prints err to stderr and returns the generic failure exit code.
+func fail(err error) int {
+	logging.Error("failed", "err", err)
+	fmt.Fprintf(os.Stderr, "smartcommit: %v\n", err)
+	return 1
+}
+
+// identList collects a repeated flag of git identities, "Name <email>".
+type identList []string
+
+func (l *identList) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *identList) Set(s string) error {
+	s = strings.TrimSpace(s)
+	if !trailer.ValidIdent(s) {
+		return fmt.Errorf("%q is not of the form \"Name <email>\"", s)
+	}
+	*l = append(*l, s)
+	return nil
+}
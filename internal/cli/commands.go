@@ -0,0 +1,171 @@
+package cli
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// command is a subcommand of smartcommit.
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) int
+	// sub are the command's own subcommands, as for hook.
+	sub []command
+}
+
+// commands returns smartcommit's subcommands, which are dispatched, listed
+// in help, and described to shell completions and the man page from here.
+func commands() []command {
+	return []command{
+		{name: "reword", summary: "rewrite the messages of existing commits", run: runReword},
+		{name: "describe", summary: "propose a message for existing commits, or explain them", run: runDescribe},
+		{name: "amend", summary: "fold the staged changes into HEAD with a revised message", run: runAmend},
+		{name: "pr", summary: "write a pull request description for the current branch", run: runPR},
+		{name: "branch", summary: "create a branch named after the uncommitted changes", run: runBranch},
+		{name: "changelog", summary: "write release notes from the commits between two revisions", run: runChangelog},
+		{name: "template", summary: "write a .gitmessage template in the repository's style", run: runTemplate},
+		{name: "hook", summary: "install or remove the prepare-commit-msg hook", run: runHook, sub: []command{
+			{name: "install", summary: "install the hook in the current repository", run: runHookInstall},
+			{name: "uninstall", summary: "remove the hook if smartcommit installed it", run: runHookUninstall},
+		}},
+		{name: "config", summary: "view and change settings", run: runConfig, sub: configCommands()},
+		{name: "lint", summary: "check a commit message against the conventions", run: runLint},
+		{name: "score", summary: "score the messages of existing commits", run: runScore},
+		{name: "audit", summary: "report on the quality of recent commit messages", run: runAudit},
+		{name: "history", summary: "browse the sessions recorded in the local store", run: runHistory},
+		{name: "usage", summary: "show this month's token use and estimated spend", run: runUsage},
+		{name: "stats", summary: "show token use and estimated spend by month", run: runStats},
+		{name: "serve", summary: "serve message generation to other tools over HTTP", run: runServe},
+		{name: "eval", summary: "evaluate prompts and models against a corpus", run: runEval},
+		{name: "update", summary: "install the latest release", run: runUpdate},
+		{name: "completion", summary: "print a completion script for bash, zsh, or fish", run: runCompletion},
+		{name: "docs", summary: "print the man page", run: runDocs},
+		{name: "help", summary: "show the usage of smartcommit or of a command", run: runHelp},
+	}
+}
+
+// findCommand returns the command in cmds called name.
+func findCommand(cmds []command, name string) (command, bool) {
+	for _, c := range cmds {
+		if c.name == name {
+			return c, true
+		}
+	}
+	return command{}, false
+}
+
+// describing collects the flag sets commands create while they're being
+// described rather than run; see flagsOf.
+var describing *[]*flag.FlagSet
+
+// newFlagSet returns the flag set of the command called name, which is
+// also where flagsOf finds its flags.
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	if describing != nil {
+		fs.SetOutput(io.Discard)
+		*describing = append(*describing, fs)
+	}
+	return fs
+}
+
+// flagsOf returns the flag set run parses, found by running it with -h:
+// every command parses its flags before doing anything else, and stops at
+// -h. It's nil for a command without flags.
+func flagsOf(run func([]string) int) *flag.FlagSet {
+	var sets []*flag.FlagSet
+	describing = &sets
+	defer func() { describing = nil }()
+	run([]string{"-h"})
+	if len(sets) == 0 {
+		return nil
+	}
+	return sets[0]
+}
+
+// synopsis returns the usage line of a command, as its flag set prints it,
+// without "Usage: ".
+func synopsis(name string, fs *flag.FlagSet) string {
+	if fs == nil {
+		return name
+	}
+	var b bytes.Buffer
+	fs.SetOutput(&b)
+	fs.Usage()
+	fs.SetOutput(io.Discard)
+	if line, ok := strings.CutPrefix(strings.SplitN(b.String(), "\n", 2)[0], "Usage: "); ok {
+		return line
+	}
+	return name + " [options]"
+}
+
+// flagInfo is a flag as completions and the man page describe it.
+type flagInfo struct {
+	name  string
+	usage string
+	// arg names the flag's value, or is "" for a boolean flag.
+	arg string
+}
+
+// flagInfos returns the flags in fs, in the order flag prints them.
+func flagInfos(fs *flag.FlagSet) []flagInfo {
+	var flags []flagInfo
+	if fs == nil {
+		return flags
+	}
+	fs.VisitAll(func(f *flag.Flag) {
+		arg, usage := flag.UnquoteUsage(f)
+		flags = append(flags, flagInfo{name: f.Name, usage: usage, arg: arg})
+	})
+	return flags
+}
+
+// takesFile reports whether the flag's value is a file, and takesDir a
+// directory, so completions can offer paths.
+func (f flagInfo) takesFile() bool {
+	return f.arg == "file" || f.arg == "path"
+}
+
+func (f flagInfo) takesDir() bool {
+	return f.arg == "dir"
+}
+
+// runHelp prints the usage of smartcommit, or of the command named.
+func runHelp(args []string) int {
+	fs := newFlagSet("smartcommit help")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: smartcommit help [<command> [<subcommand>]]")
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	run, cmds := runTUI, commands()
+	for _, name := range fs.Args() {
+		c, ok := findCommand(cmds, name)
+		if !ok {
+			return fail(fmt.Errorf("unknown command %q; see `smartcommit help`", name))
+		}
+		run, cmds = c.run, c.sub
+	}
+	if target := flagsOf(run); target != nil {
+		target.SetOutput(os.Stdout)
+		target.Usage()
+	}
+	return 0
+}
+
+// printCommands writes the list of commands to w, for the usage of
+// smartcommit itself.
+func printCommands(w io.Writer) {
+	fmt.Fprintln(w, "Commands:")
+	for _, c := range commands() {
+		fmt.Fprintf(w, "  %-12s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run `smartcommit help <command>` for a command's options.")
+}
@@ -0,0 +1,245 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// runCompletion prints a script that completes smartcommit's commands and
+// flags in bash, zsh, or fish.
+func runCompletion(args []string) int {
+	fs := newFlagSet("smartcommit completion")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: smartcommit completion bash|zsh|fish")
+		fmt.Fprintln(fs.Output())
+		fmt.Fprintln(fs.Output(), "Load it in the current shell with, for example:")
+		fmt.Fprintln(fs.Output(), "  source <(smartcommit completion bash)")
+		fmt.Fprintln(fs.Output(), "  source <(smartcommit completion zsh)")
+		fmt.Fprintln(fs.Output(), "  smartcommit completion fish | source")
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	var script string
+	switch fs.Arg(0) {
+	case "bash":
+		script = bashCompletion()
+	case "zsh":
+		script = zshCompletion()
+	case "fish":
+		script = fishCompletion()
+	default:
+		return fail(fmt.Errorf("no completion for %q; use bash, zsh, or fish", fs.Arg(0)))
+	}
+	if _, err := os.Stdout.WriteString(script); err != nil {
+		return fail(err)
+	}
+	return 0
+}
+
+// completable is a command, or smartcommit itself, as completions see it.
+type completable struct {
+	// path is the command's words after "smartcommit", empty for
+	// smartcommit itself.
+	path  []string
+	flags []flagInfo
+	sub   []command
+}
+
+// completables returns smartcommit itself and each command and subcommand.
+func completables() []completable {
+	all := []completable{{flags: flagInfos(flagsOf(runTUI)), sub: commands()}}
+	var walk func(path []string, cmds []command)
+	walk = func(path []string, cmds []command) {
+		for _, c := range cmds {
+			p := append(path[:len(path):len(path)], c.name)
+			cc := completable{path: p, sub: c.sub}
+			if c.sub == nil {
+				cc.flags = flagInfos(flagsOf(c.run))
+			}
+			all = append(all, cc)
+			walk(p, c.sub)
+		}
+	}
+	walk(nil, commands())
+	return all
+}
+
+func bashCompletion() string {
+	var b strings.Builder
+	b.WriteString(`# bash completion for smartcommit; load with: source <(smartcommit completion bash)
+
+_smartcommit() {
+    local cur=${COMP_WORDS[COMP_CWORD]} prev=${COMP_WORDS[COMP_CWORD-1]}
+    local cmd="" i
+    # Commands come first, before any flags.
+    for ((i = 1; i < COMP_CWORD; i++)); do
+        case ${COMP_WORDS[i]} in
+            -*) break ;;
+            *) cmd+=" ${COMP_WORDS[i]}" ;;
+        esac
+    done
+    cmd=${cmd# }
+
+    local words="" files="" dirs="" values=""
+    case $cmd in
+`)
+	for _, c := range completables() {
+		var words, files, dirs, values []string
+		for _, s := range c.sub {
+			words = append(words, s.name)
+		}
+		for _, f := range c.flags {
+			words = append(words, "--"+f.name)
+			switch {
+			case f.takesFile():
+				files = append(files, "--"+f.name)
+			case f.takesDir():
+				dirs = append(dirs, "--"+f.name)
+			case f.arg != "":
+				values = append(values, "--"+f.name)
+			}
+		}
+		fmt.Fprintf(&b, "        %q)\n", strings.Join(c.path, " "))
+		fmt.Fprintf(&b, "            words=%q files=%q dirs=%q values=%q ;;\n", strings.Join(words, " "), strings.Join(files, " "), strings.Join(dirs, " "), strings.Join(values, " "))
+	}
+	b.WriteString(`    esac
+
+    if [[ " $files " == *" $prev "* ]]; then
+        COMPREPLY=($(compgen -f -- "$cur"))
+        return
+    fi
+    if [[ " $dirs " == *" $prev "* ]]; then
+        COMPREPLY=($(compgen -d -- "$cur"))
+        return
+    fi
+    if [[ " $values " == *" $prev "* ]]; then
+        COMPREPLY=()
+        return
+    fi
+    COMPREPLY=($(compgen -W "$words" -- "$cur"))
+}
+
+complete -o default -F _smartcommit smartcommit
+`)
+	return b.String()
+}
+
+func zshCompletion() string {
+	var b strings.Builder
+	b.WriteString(`#compdef smartcommit
+# zsh completion for smartcommit; load with: source <(smartcommit completion zsh)
+
+_smartcommit() {
+    local -a cmd subs
+    local i
+    # Commands come first, before any flags.
+    for ((i = 2; i < CURRENT; i++)); do
+        [[ $words[i] == -* ]] && break
+        cmd+=($words[i])
+    done
+    # Complete the command's own arguments as if it were run alone.
+    words=($words[1] $words[$#cmd+2,-1])
+    (( CURRENT -= $#cmd ))
+
+    case "${cmd[*]}" in
+`)
+	for _, c := range completables() {
+		fmt.Fprintf(&b, "        %q)\n", strings.Join(c.path, " "))
+		if len(c.sub) > 0 {
+			var subs []string
+			for _, s := range c.sub {
+				subs = append(subs, zshQuote(s.name+":"+s.summary))
+			}
+			fmt.Fprintf(&b, "            subs=(%s)\n", strings.Join(subs, " "))
+			b.WriteString("            if [[ $PREFIX != -* ]]; then\n")
+			b.WriteString("                _describe -t commands 'smartcommit command' subs\n")
+			b.WriteString("                return\n")
+			b.WriteString("            fi\n")
+		}
+		specs := []string{"'*::arg:_files'"}
+		if len(c.sub) > 0 {
+			specs = nil
+		}
+		for _, f := range c.flags {
+			spec := "--" + f.name + "[" + zshEscape(f.usage) + "]"
+			switch {
+			case f.takesFile():
+				spec += ":" + f.arg + ":_files"
+			case f.takesDir():
+				spec += ":" + f.arg + ":_directories"
+			case f.arg != "":
+				spec += ":" + zshEscape(f.arg) + ": "
+			}
+			specs = append(specs, zshQuote(spec))
+		}
+		if len(specs) > 0 {
+			fmt.Fprintf(&b, "            _arguments %s\n", strings.Join(specs, " "))
+		}
+		b.WriteString("            ;;\n")
+	}
+	b.WriteString(`    esac
+}
+
+compdef _smartcommit smartcommit
+`)
+	return b.String()
+}
+
+// zshEscape escapes the characters _arguments treats specially in a
+// description.
+func zshEscape(s string) string {
+	return strings.NewReplacer(`[`, `\[`, `]`, `\]`, `:`, `\:`).Replace(s)
+}
+
+// zshQuote quotes s for the shell in single quotes.
+func zshQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func fishCompletion() string {
+	var b strings.Builder
+	b.WriteString("# fish completion for smartcommit; load with: smartcommit completion fish | source\n\n")
+	for _, c := range completables() {
+		// The condition under which this command's words are offered: its
+		// path has been typed, and nothing further down it.
+		cond := "__fish_use_subcommand"
+		if len(c.path) > 0 {
+			cond = "__fish_seen_subcommand_from " + c.path[len(c.path)-1]
+		}
+		if len(c.sub) > 0 && len(c.path) > 0 {
+			var names []string
+			for _, s := range c.sub {
+				names = append(names, s.name)
+			}
+			cond += "; and not __fish_seen_subcommand_from " + strings.Join(names, " ")
+		}
+		for _, s := range c.sub {
+			fmt.Fprintf(&b, "complete -c smartcommit -f -n %s -a %s -d %s\n", fishQuote(cond), s.name, fishQuote(s.summary))
+		}
+		for _, f := range c.flags {
+			line := fmt.Sprintf("complete -c smartcommit -n %s -l %s", fishQuote(cond), f.name)
+			switch {
+			case f.takesFile():
+				line += " -r -F"
+			case f.takesDir():
+				line += " -r -a '(__fish_complete_directories)'"
+			case f.arg != "":
+				line += " -r -f"
+			}
+			fmt.Fprintf(&b, "%s -d %s\n", line, fishQuote(f.usage))
+		}
+	}
+	return b.String()
+}
+
+// fishQuote quotes s for fish in single quotes.
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
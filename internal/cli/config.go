@@ -0,0 +1,277 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"text/tabwriter"
+
+	"github.com/arpxspace/smartcommit/internal/config"
+	"github.com/arpxspace/smartcommit/internal/git"
+)
+
+// configCommands are the subcommands of `smartcommit config`.
+func configCommands() []command {
+	return []command{
+		{name: "list", summary: "list the settings that are set", run: runConfigList},
+		{name: "get", summary: "print a setting", run: runConfigGet},
+		{name: "set", summary: "change a setting", run: runConfigSet},
+		{name: "unset", summary: "return a setting to its default", run: runConfigUnset},
+		{name: "edit", summary: "open the config file in the editor", run: runConfigEdit},
+	}
+}
+
+// runConfig views and changes settings without the setup screens or
+// editing JSON by hand.
+func runConfig(args []string) int {
+	usage := func() int {
+		fmt.Fprintln(os.Stderr, "Usage: smartcommit config list | get <key> | set <key> <value> | unset <key> | edit  [--repo]")
+		return 2
+	}
+	if len(args) == 0 {
+		return usage()
+	}
+	c, ok := findCommand(configCommands(), args[0])
+	if !ok {
+		return usage()
+	}
+	return c.run(args[1:])
+}
+
+// configFile is the config file `smartcommit config` works on: the global
+// one, or with --repo the current repository's.
+type configFile struct {
+	// settings is a *config.Config or a *config.RepoConfig.
+	settings any
+	path     string
+	save     func() error
+}
+
+// fromEnv returns the environment variable overriding key, if any. A
+// repo config is shown as saved; the environment applies once it's merged
+// with the global one.
+func (f *configFile) fromEnv(key string) (string, bool) {
+	if cfg, ok := f.settings.(*config.Config); ok {
+		return cfg.FromEnv(key)
+	}
+	return "", false
+}
+
+func openConfig(repo bool) (*configFile, error) {
+	if repo {
+		if !git.IsRepo() {
+			return nil, fmt.Errorf("not a git repository")
+		}
+		root, err := git.RepoRoot()
+		if err != nil {
+			return nil, err
+		}
+		cfg, err := config.LoadRepo(root)
+		if err != nil {
+			return nil, err
+		}
+		return &configFile{settings: cfg, path: config.RepoConfigPath(root), save: func() error { return cfg.Save(root) }}, nil
+	}
+	path, err := config.Path()
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	cfg.LoadAllKeys()
+	if !config.Exists() {
+		// Load offers the key in the environment for setup; it isn't a
+		// setting until setup saves it.
+		cfg.OpenAIAPIKey = ""
+	}
+	return &configFile{settings: cfg, path: path, save: cfg.Save}, nil
+}
+
+// configFlags parses the flags every config subcommand takes, returning the
+// config file they choose and the remaining arguments, or the exit status
+// for bad usage.
+func configFlags(name, usage string, args []string, nargs int) (*configFile, []string, int) {
+	fs := newFlagSet("smartcommit config " + name)
+	repo := fs.Bool("repo", false, "use the repository's .smartcommit.json instead of the global config")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: smartcommit config %s\n", usage)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, 2
+	}
+	if fs.NArg() != nargs {
+		fs.Usage()
+		return nil, nil, 2
+	}
+	f, err := openConfig(*repo)
+	if err != nil {
+		return nil, nil, fail(err)
+	}
+	return f, fs.Args(), 0
+}
+
+func runConfigList(args []string) int {
+	fs := newFlagSet("smartcommit config list")
+	repo := fs.Bool("repo", false, "use the repository's .smartcommit.json instead of the global config")
+	all := fs.Bool("all", false, "list every setting, including those left at their defaults")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: smartcommit config list [--all] [--repo]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	f, err := openConfig(*repo)
+	if err != nil {
+		return fail(err)
+	}
+	keys, values, err := config.Keys(f.settings)
+	if err != nil {
+		return fail(err)
+	}
+	if *all {
+		keys = config.Names(f.settings)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, key := range keys {
+		value, ok := values[key]
+		if !ok && *all {
+			// Maps and unset objects are listed by their own name.
+			if value, err = config.Get(f.settings, key); err != nil {
+				return fail(err)
+			}
+		}
+		text := formatSetting(value)
+		if config.IsAPIKey(key) || key == "serve_token" {
+			text = maskKey(text)
+		}
+		if env, ok := f.fromEnv(key); ok {
+			text += "\t(from " + env + ")"
+		}
+		fmt.Fprintf(w, "%s\t%s\n", key, text)
+	}
+	w.Flush()
+	return 0
+}
+
+func runConfigGet(args []string) int {
+	f, rest, status := configFlags("get", "get [--repo] <key>", args, 1)
+	if f == nil {
+		return status
+	}
+	value, err := config.Get(f.settings, rest[0])
+	if err != nil {
+		return fail(err)
+	}
+	fmt.Println(formatSetting(value))
+	return 0
+}
+
+func runConfigSet(args []string) int {
+	f, rest, status := configFlags("set", "set [--repo] <key> <value>", args, 2)
+	if f == nil {
+		return status
+	}
+	if _, ok := f.settings.(*config.RepoConfig); ok && config.IsAPIKey(rest[0]) {
+		return fail(fmt.Errorf("API keys never belong in a repository; set %s without --repo", rest[0]))
+	}
+	if err := config.Set(f.settings, rest[0], rest[1]); err != nil {
+		return fail(err)
+	}
+	if err := f.save(); err != nil {
+		return fail(err)
+	}
+	if env, ok := f.fromEnv(rest[0]); ok {
+		fmt.Fprintf(os.Stderr, "smartcommit: saved, but %s overrides it here\n", env)
+	}
+	return 0
+}
+
+func runConfigUnset(args []string) int {
+	f, rest, status := configFlags("unset", "unset [--repo] <key>", args, 1)
+	if f == nil {
+		return status
+	}
+	if err := config.Unset(f.settings, rest[0]); err != nil {
+		return fail(err)
+	}
+	if cfg, ok := f.settings.(*config.Config); ok {
+		if err := cfg.ForgetKey(rest[0]); err != nil {
+			return fail(err)
+		}
+	}
+	if err := f.save(); err != nil {
+		return fail(err)
+	}
+	return 0
+}
+
+func runConfigEdit(args []string) int {
+	f, _, status := configFlags("edit", "edit [--repo]", args, 0)
+	if f == nil {
+		return status
+	}
+	if _, err := os.Stat(f.path); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(f.path), 0o755); err != nil {
+			return fail(err)
+		}
+		if err := f.save(); err != nil {
+			return fail(err)
+		}
+	}
+	if err := git.EditFile(f.path); err != nil {
+		return fail(err)
+	}
+
+	// Catch mistakes now rather than at the next commit.
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return fail(err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var check any = &config.Config{}
+	if _, ok := f.settings.(*config.RepoConfig); ok {
+		check = &config.RepoConfig{}
+	}
+	if err := dec.Decode(check); err != nil {
+		return fail(fmt.Errorf("%s: %w; it's saved as you left it, so edit it again to fix it", f.path, err))
+	}
+	return 0
+}
+
+// formatSetting prints text as it is, anything else as JSON, and nothing
+// for a setting that isn't set.
+func formatSetting(value any) string {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Pointer, reflect.Slice, reflect.Map:
+		if v.IsNil() {
+			return ""
+		}
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+// maskKey hides all but the ends of an API key.
+func maskKey(key string) string {
+	if key == "" {
+		return ""
+	}
+	if len(key) <= 12 {
+		return "********"
+	}
+	return key[:3] + "…" + key[len(key)-4:]
+}
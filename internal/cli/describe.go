@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/arpxspace/smartcommit/internal/diff"
+	"github.com/arpxspace/smartcommit/internal/git"
+	"github.com/arpxspace/smartcommit/internal/redact"
+	"github.com/arpxspace/smartcommit/internal/staged"
+)
+
+// runDescribe proposes a better message for a commit that already exists,
+// or for a range of them as one, from their diff and current messages, or
+// explains them in plain language. Nothing is rewritten; see runReword.
+func runDescribe(args []string) int {
+	fs := newFlagSet("smartcommit describe")
+	explain := fs.Bool("explain", false, "explain what the commits do and why instead of proposing a message")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: smartcommit describe [--explain] [<commit> | <range>]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return 2
+	}
+
+	if !git.IsRepo() {
+		return fail(fmt.Errorf("not a git repository"))
+	}
+	rev := "HEAD"
+	if fs.NArg() == 1 {
+		rev = fs.Arg(0)
+	}
+	cfg, client, err := newProvider()
+	if err != nil {
+		return fail(err)
+	}
+	defer recordUsage(cfg, "describe", client)
+
+	var raw, log, what string
+	if strings.Contains(rev, "..") {
+		commits, err := git.GetLogRange(rev)
+		if err != nil {
+			return fail(err)
+		}
+		if len(commits) == 0 {
+			return fail(fmt.Errorf("%s has no commits", rev))
+		}
+		if raw, err = git.RangeDiff(rev, cfg.Diff.Args()...); err != nil {
+			return fail(err)
+		}
+		log, what = commitLog(commits), fmt.Sprintf("%d commit(s) in %q", len(commits), rev)
+	} else {
+		sha, err := git.ResolveCommit(rev)
+		if err != nil {
+			return fail(err)
+		}
+		if raw, err = git.CommitDiff(sha); err != nil {
+			return fail(err)
+		}
+		if log, err = git.CommitMessage(sha); err != nil {
+			return fail(err)
+		}
+		what = sha[:7]
+	}
+
+	sc := staged.Context{Config: cfg, Files: diff.Parse(raw), Redact: cfg.PrivacyReview}
+	if len(sc.Files) == 0 {
+		return fail(fmt.Errorf("%s changes no files", rev))
+	}
+	if secrets := sc.Secrets(); len(secrets) > 0 {
+		sc.Redact = true
+		fmt.Fprintf(os.Stderr, "smartcommit: redacting %d possible secret(s) before sending, in %s\n", len(secrets), strings.Join(secretPaths(secrets), ", "))
+	}
+	d := sc.Diff()
+	if sc.TooLarge() {
+		if d, err = summarize(client, sc); err != nil {
+			return fail(err)
+		}
+	}
+	if sc.Redact {
+		log = redact.Secrets(log)
+	}
+
+	if *explain {
+		fmt.Fprintf(os.Stderr, "Explaining %s...\n", what)
+		explanation, err := client.ExplainCommits(context.Background(), d, log)
+		if err != nil {
+			return fail(err)
+		}
+		fmt.Println(explanation.Text())
+		return 0
+	}
+
+	history, err := git.GetRecentHistory(10)
+	if err != nil {
+		return fail(err)
+	}
+	question := "What does the commit's current message say? (Keep any facts it states.)"
+	if strings.Contains(rev, "..") {
+		question = "What do the commits' current messages say, oldest first? (Keep any facts they state.)"
+	}
+	fmt.Fprintf(os.Stderr, "Proposing a message for %s...\n", what)
+	message, err := client.GenerateCommitMessage(context.Background(), d, sc.FitHistory(history), map[string]string{question: log})
+	if err != nil {
+		return fail(err)
+	}
+	fmt.Println(strings.TrimSpace(message))
+	return 0
+}
@@ -0,0 +1,123 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/arpxspace/smartcommit/internal/config"
+	"github.com/arpxspace/smartcommit/internal/logging"
+)
+
+// runDocs prints smartcommit's man page, generated from its commands and
+// flags so it can't fall behind them.
+func runDocs(args []string) int {
+	fs := newFlagSet("smartcommit docs")
+	output := fs.String("output", "", "write the man page to `file` instead of stdout")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: smartcommit docs [--output file]")
+		fmt.Fprintln(fs.Output())
+		fmt.Fprintln(fs.Output(), "Install it with, for example:")
+		fmt.Fprintln(fs.Output(), "  smartcommit docs --output /usr/local/share/man/man1/smartcommit.1")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	page := manPage()
+	if *output == "" {
+		if _, err := os.Stdout.WriteString(page); err != nil {
+			return fail(err)
+		}
+		return 0
+	}
+	if err := os.WriteFile(*output, []byte(page), 0o644); err != nil {
+		return fail(err)
+	}
+	return 0
+}
+
+// manPage returns the man page in roff.
+func manPage() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, ".TH SMARTCOMMIT 1 %q smartcommit \"User Commands\"\n", time.Now().Format("January 2006"))
+	b.WriteString(".SH NAME\nsmartcommit \\- write git commit messages with an AI that asks why\n")
+	b.WriteString(".SH SYNOPSIS\n.B smartcommit\n[\\fIoptions\\fR]\n.br\n.B smartcommit\n\\fIcommand\\fR [\\fIoptions\\fR]\n")
+	b.WriteString(".SH DESCRIPTION\n")
+	b.WriteString("Without a command, smartcommit reads the staged changes and the repository's history, " +
+		"asks a few questions about why the change was made, and writes a commit message from the answers " +
+		"for review before committing. The commands below work on existing commits, branches and releases.\n")
+	b.WriteString(".SH OPTIONS\n")
+	manFlags(&b, flagInfos(flagsOf(runTUI)))
+	b.WriteString(".SH COMMANDS\n")
+	var walk func(path []string, cmds []command)
+	walk = func(path []string, cmds []command) {
+		for _, c := range cmds {
+			p := append(path[:len(path):len(path)], c.name)
+			if c.sub != nil {
+				walk(p, c.sub)
+				continue
+			}
+			fs := flagsOf(c.run)
+			fmt.Fprintf(&b, ".SS %s\n", roffEscape(synopsis("smartcommit "+strings.Join(p, " "), fs)))
+			fmt.Fprintf(&b, "%s.\n", roffEscape(capitalize(c.summary)))
+			manFlags(&b, flagInfos(fs))
+		}
+	}
+	walk(nil, commands())
+	b.WriteString(".SH ENVIRONMENT\n")
+	for _, env := range [][2]string{
+		{"OPENAI_API_KEY", "Offered during setup as the OpenAI API key."},
+		{"AZURE_OPENAI_API_KEY", "The Azure OpenAI key when azure_api_key isn't set."},
+		{"GITHUB_TOKEN, GH_TOKEN", "Used to fetch issues and open pull requests on GitHub."},
+		{"HTTPS_PROXY, HTTP_PROXY, NO_PROXY", "The proxy requests go through, and the hosts they reach directly, unless network.proxy is set."},
+		{logging.EnvFile, "Log everything to this file, or to the usual log when set to 1."},
+		{logging.EnvLevel, "The least severe level logged: debug, info, warn, error, or off."},
+		{config.EnvPrefix + "<SETTING>", "Overrides a setting, named by its key in upper case with dots as underscores, like " + config.EnvName("ollama_url") + "."},
+		{config.EnvModel, "Overrides the model of the provider in use."},
+		{"XDG_DATA_HOME", "Where the data directory is, instead of ~/.local/share."},
+	} {
+		fmt.Fprintf(&b, ".TP\n.B %s\n%s\n", env[0], roffEscape(env[1]))
+	}
+	b.WriteString(".SH FILES\n")
+	for _, file := range [][2]string{
+		{"~/.config/smartcommit/config.json", "The configuration."},
+		{".smartcommit.json", "Settings for one repository, at its root."},
+		{"~/.local/share/smartcommit/sessions.db", "The local session store."},
+		{"~/.local/share/smartcommit/" + logging.FileName, "The log."},
+	} {
+		fmt.Fprintf(&b, ".TP\n.I %s\n%s\n", file[0], roffEscape(file[1]))
+	}
+	b.WriteString(".SH SEE ALSO\n.BR git\\-commit (1)\n")
+	return b.String()
+}
+
+// manFlags writes flags as a list of options.
+func manFlags(b *strings.Builder, flags []flagInfo) {
+	for _, f := range flags {
+		fmt.Fprintf(b, ".TP\n\\fB\\-\\-%s\\fR", roffEscape(f.name))
+		if f.arg != "" {
+			fmt.Fprintf(b, " \\fI%s\\fR", roffEscape(f.arg))
+		}
+		fmt.Fprintf(b, "\n%s\n", roffEscape(capitalize(f.usage)))
+	}
+}
+
+// roffEscape keeps s from being read as roff: backslashes and hyphens are
+// escaped, and a leading dot or quote can't start a request.
+func roffEscape(s string) string {
+	s = strings.NewReplacer(`\`, `\e`, "-", `\-`).Replace(s)
+	if strings.HasPrefix(s, ".") || strings.HasPrefix(s, "'") {
+		s = `\&` + s
+	}
+	return s
+}
+
+// capitalize upper-cases the first letter of s.
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/arpxspace/smartcommit/internal/ai"
+	"github.com/arpxspace/smartcommit/internal/config"
+	"github.com/arpxspace/smartcommit/internal/git"
+	"github.com/arpxspace/smartcommit/internal/staged"
+)
+
+// runDryRun prints every prompt the commit flow would send for the staged
+// changes, or the diff in diffFile if set, exactly as sent, without
+// contacting the provider. Notes about what changes once the provider
+// answers go to stderr.
+func runDryRun(profile, diffFile string, privacy bool, issue int, coAuthors []string) int {
+	if diffFile == "" && !git.IsRepo() {
+		return fail(fmt.Errorf("not a git repository"))
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return fail(err)
+	}
+	if git.IsRepo() {
+		if cfg, err = staged.Configure(cfg); err != nil {
+			return fail(err)
+		}
+	}
+	if cfg, err = cfg.WithProfile(profile); err != nil {
+		return fail(err)
+	}
+	// Nothing is sent, so no API key is needed to see what would be.
+	client, err := ai.NewClient(cfg)
+	if err != nil {
+		return fail(err)
+	}
+	cfg.CoAuthors = append(cfg.CoAuthors, coAuthors...)
+
+	change, err := collectChange(cfg, diffFile)
+	if err != nil {
+		return fail(err)
+	}
+	if diffFile == "" || issue != 0 {
+		if err := change.FetchIssue(context.Background(), cfg, issue); err != nil {
+			if issue != 0 {
+				return fail(err)
+			}
+			fmt.Fprintf(os.Stderr, "smartcommit: continuing without issue context: %v\n", err)
+		}
+	}
+	sc := change.Context(cfg)
+	sc.Redact = sc.Redact || privacy
+	if len(sc.Included()) == 0 {
+		return fail(fmt.Errorf("every staged file is excluded or ignored in %s", config.RepoConfigFile))
+	}
+	if secrets := sc.Secrets(); len(secrets) > 0 && !sc.Redact {
+		fmt.Fprintf(os.Stderr, "smartcommit: %d possible secret(s) are shown unredacted; the TUI asks before sending them\n", len(secrets))
+	}
+	if trimmed := sc.Trimmed(); len(trimmed) > 0 {
+		fmt.Fprintf(os.Stderr, "smartcommit: trimmed to fit the context window: %s\n", strings.Join(trimmed, ", "))
+	}
+	if sc.TooLarge() {
+		fmt.Fprintf(os.Stderr, "smartcommit: the diff would first be summarized in %d parts, and the summaries sent in its place\n", len(sc.Parts()))
+	}
+	if cfg.Examples() > 0 || cfg.RelatedHistory {
+		fmt.Fprintln(os.Stderr, "smartcommit: related commits or style examples are chosen with the provider; the recent history is shown instead")
+	}
+	if cfg.Questions() > 0 {
+		fmt.Fprintln(os.Stderr, "smartcommit: answers to the clarifying questions would be added under User Context")
+	}
+
+	var history string
+	if git.IsRepo() {
+		if history, err = git.GetRecentHistory(10); err != nil {
+			return fail(err)
+		}
+		history = sc.FitHistory(history)
+	}
+
+	// Stages the config turns off aren't sent.
+	var prompts []ai.Prompt
+	for _, p := range client.PreviewPrompts(sc.Diff(), history, nil) {
+		if (p.Stage == ai.StageHistory && !cfg.AnalyzeHistory()) || (p.Stage == ai.StageQuestions && cfg.Questions() == 0) {
+			continue
+		}
+		prompts = append(prompts, p)
+	}
+	totalBytes, totalTokens := 0, 0
+	for _, p := range prompts {
+		totalBytes += p.Bytes()
+		totalTokens += p.EstimateTokens()
+	}
+	fmt.Printf("%d requests to %s (%s), %d bytes, ~%d tokens in total\n", len(prompts), cfg.Provider, client.Model(), totalBytes, totalTokens)
+	for _, p := range prompts {
+		fmt.Printf("\n=== %s (%d bytes, ~%d tokens) ===\n\n", strings.ToUpper(string(p.Stage)), p.Bytes(), p.EstimateTokens())
+		fmt.Printf("--- system ---\n%s\n\n", strings.TrimSpace(p.System))
+		fmt.Printf("--- user ---\n%s\n", strings.TrimSpace(p.User))
+	}
+	return 0
+}
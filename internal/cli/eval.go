@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/arpxspace/smartcommit/internal/eval"
+)
+
+// runEval runs the pipeline against a corpus of recorded diffs and reports
+// how the generated messages compare with the references and a baseline.
+func runEval(args []string) int {
+	fs := newFlagSet("smartcommit eval")
+	corpus := fs.String("corpus", "", "`dir` of NAME.diff and NAME.msg cases (required)")
+	judge := fs.Bool("judge", true, "have the provider score each message with the rubric")
+	baseline := fs.String("baseline", "", "compare against a report previously written with --save `file`")
+	save := fs.String("save", "", "write the report to `file`")
+	tolerance := fs.Float64("tolerance", 0.1, "score drop per case that counts as a regression")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *corpus == "" {
+		fmt.Fprintln(fs.Output(), "Usage: smartcommit eval --corpus dir/ [--baseline report.json] [--save report.json]")
+		fs.PrintDefaults()
+		return 2
+	}
+
+	cases, err := eval.LoadCorpus(*corpus)
+	if err != nil {
+		return fail(err)
+	}
+	var base *eval.Report
+	if *baseline != "" {
+		b, err := eval.LoadReport(*baseline)
+		if err != nil {
+			return fail(err)
+		}
+		base = &b
+	}
+	cfg, client, err := newProvider()
+	if err != nil {
+		return fail(err)
+	}
+
+	report := eval.Run(context.Background(), client, cases, eval.Options{
+		Judge: *judge,
+		Progress: func(done, total int, r eval.Result) {
+			status := "ok"
+			switch {
+			case r.Error != "":
+				status = "error: " + r.Error
+			case !r.FormatOK:
+				status = "format: " + r.Problems[0]
+			}
+			judge := "-"
+			if r.Judge >= 0 {
+				judge = fmt.Sprintf("%.2f", r.Judge)
+			}
+			fmt.Printf("[%d/%d] %-30s score %.2f  sim %.2f  judge %s  %s\n", done, total, r.Case, r.Score, r.Similarity, judge, status)
+		},
+	})
+	recordUsage(cfg, "eval", client)
+	fmt.Printf("\n%s: mean score %.3f over %d cases\n", report.Model, report.Mean, len(report.Results))
+
+	if *save != "" {
+		if err := report.Save(*save); err != nil {
+			return fail(err)
+		}
+		fmt.Printf("Wrote %s\n", *save)
+	}
+
+	if base == nil {
+		return 0
+	}
+	fmt.Printf("Baseline (%s): mean score %.3f\n", base.Model, base.Mean)
+	regressions := eval.Compare(*base, report, *tolerance)
+	if len(regressions) == 0 {
+		fmt.Println("No regressions.")
+		return 0
+	}
+	fmt.Printf("\n%d regression(s):\n", len(regressions))
+	for _, r := range regressions {
+		fmt.Printf("  %-30s %.2f -> %.2f  %s\n", r.Case, r.Baseline, r.Current, r.Reason)
+	}
+	return 1
+}
@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/arpxspace/smartcommit/internal/config"
+	"github.com/arpxspace/smartcommit/internal/git"
+	"github.com/arpxspace/smartcommit/internal/store"
+	"github.com/arpxspace/smartcommit/internal/tui"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// runHistory browses the messages recorded in the session store, so one from
+// an aborted commit can be recovered or an earlier one reused. With --print,
+// the latest message of a session is printed instead.
+func runHistory(args []string) int {
+	fs := newFlagSet("smartcommit history")
+	all := fs.Bool("all", false, "list sessions from every repository, not just this one")
+	limit := fs.Int("limit", 50, "list at most `n` sessions")
+	printID := fs.Int64("print", 0, "print the latest message of session `id` and exit")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: smartcommit history [--all] [--limit n] [--print id]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return 2
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fail(err)
+	}
+	if cfg.DisableStore {
+		fmt.Println("History isn't recorded because disable_store is set.")
+		return 0
+	}
+	s, err := store.Open()
+	if err != nil {
+		return fail(err)
+	}
+	defer s.Close()
+
+	if *printID != 0 {
+		sess, err := s.Get(*printID)
+		if err != nil {
+			return fail(err)
+		}
+		messages := sess.Messages()
+		if len(messages) == 0 {
+			return fail(fmt.Errorf("session %d has no message", sess.ID))
+		}
+		fmt.Println(messages[0])
+		return 0
+	}
+
+	var sessions []*store.Session
+	if *all || !git.IsRepo() {
+		sessions, err = s.Recent(*limit)
+	} else {
+		var root string
+		if root, err = git.RepoRoot(); err != nil {
+			return fail(err)
+		}
+		sessions, err = s.ByRepo(root, *limit)
+	}
+	if err != nil {
+		return fail(err)
+	}
+
+	if err := configureTUI(cfg); err != nil {
+		return fail(err)
+	}
+	final, err := tea.NewProgram(tui.NewHistory(sessions)).Run()
+	if err != nil {
+		return fail(err)
+	}
+	h := final.(tui.History)
+	if h.Printed != "" {
+		fmt.Println(h.Printed)
+	}
+	if h.Committed {
+		if message, err := git.HeadMessage(); err == nil {
+			subject, _, _ := strings.Cut(strings.TrimSpace(message), "\n")
+			fmt.Fprintf(os.Stderr, "Committed: %s\n", subject)
+		}
+	}
+	return 0
+}
@@ -0,0 +1,152 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/arpxspace/smartcommit/internal/git"
+)
+
+// hookMarker identifies a hook script written by smartcommit, so it isn't
+// overwritten or removed by mistake.
+const hookMarker = "# Installed by smartcommit."
+
+// hookScript runs smartcommit from the prepare-commit-msg hook.
+const hookScript = `#!/bin/sh
+` + hookMarker + ` Pre-fills the commit message; remove this file to stop.
+command -v smartcommit >/dev/null 2>&1 || exit 0
+exec smartcommit hook prepare-commit-msg "$@"
+`
+
+// runHook installs, removes, or runs the prepare-commit-msg hook.
+func runHook(args []string) int {
+	usage := func() int {
+		fmt.Fprintln(os.Stderr, "Usage: smartcommit hook install [--force] | uninstall")
+		return 2
+	}
+	if len(args) == 0 {
+		return usage()
+	}
+	switch args[0] {
+	case "install":
+		return runHookInstall(args[1:])
+	case "uninstall":
+		return runHookUninstall(args[1:])
+	case "prepare-commit-msg":
+		return runPrepareCommitMsg(args[1:])
+	default:
+		return usage()
+	}
+}
+
+// runHookInstall writes the prepare-commit-msg hook for the current repository.
+func runHookInstall(args []string) int {
+	fs := newFlagSet("smartcommit hook install")
+	force := fs.Bool("force", false, "replace an existing prepare-commit-msg hook")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if !git.IsRepo() {
+		return fail(fmt.Errorf("not a git repository"))
+	}
+	path, err := git.HookPath("prepare-commit-msg")
+	if err != nil {
+		return fail(err)
+	}
+	if existing, err := os.ReadFile(path); err == nil && !bytes.Contains(existing, []byte(hookMarker)) && !*force {
+		return fail(fmt.Errorf("%s already exists; pass --force to replace it", path))
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fail(err)
+	}
+	if err := os.WriteFile(path, []byte(hookScript), 0755); err != nil {
+		return fail(err)
+	}
+	// WriteFile keeps the mode of an existing file.
+	if err := os.Chmod(path, 0755); err != nil {
+		return fail(err)
+	}
+	fmt.Printf("Installed %s. `git commit` now opens the editor with a generated message.\n", path)
+	return 0
+}
+
+// runHookUninstall removes the prepare-commit-msg hook if smartcommit wrote it.
+func runHookUninstall(args []string) int {
+	fs := newFlagSet("smartcommit hook uninstall")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if !git.IsRepo() {
+		return fail(fmt.Errorf("not a git repository"))
+	}
+	path, err := git.HookPath("prepare-commit-msg")
+	if err != nil {
+		return fail(err)
+	}
+	existing, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		fmt.Println("No prepare-commit-msg hook is installed.")
+		return 0
+	}
+	if err != nil {
+		return fail(err)
+	}
+	if !bytes.Contains(existing, []byte(hookMarker)) {
+		return fail(fmt.Errorf("%s wasn't installed by smartcommit; remove it by hand", path))
+	}
+	if err := os.Remove(path); err != nil {
+		return fail(err)
+	}
+	fmt.Printf("Removed %s.\n", path)
+	return 0
+}
+
+// runPrepareCommitMsg is the hook itself: for a plain `git commit`, it puts
+// a generated message above the comments git wrote to the message file.
+// It never fails the commit; problems are reported and the file is left as is.
+func runPrepareCommitMsg(args []string) int {
+	if len(args) == 0 {
+		return 2
+	}
+	file := args[0]
+	// Messages given with -m or -F, templates, merges, squashes, and amends
+	// already have a message worth keeping.
+	if len(args) > 1 && args[1] != "" {
+		return 0
+	}
+	if os.Getenv(git.NoHookEnv) != "" {
+		return 0
+	}
+	existing, err := os.ReadFile(file)
+	if err != nil {
+		return warnHook(err)
+	}
+	if cleanMessage(string(existing), git.CommentString()) != "" {
+		return 0
+	}
+
+	cfg, client, err := newProvider()
+	if err != nil {
+		return warnHook(err)
+	}
+	fmt.Fprintln(os.Stderr, "smartcommit: generating a commit message...")
+	message, _, _, err := autoMessage(cfg, client, "", false, 0)
+	recordUsage(cfg, "hook", client)
+	if err != nil {
+		return warnHook(err)
+	}
+	content := strings.TrimSpace(message) + "\n" + string(existing)
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		return warnHook(err)
+	}
+	return 0
+}
+
+// warnHook reports err without failing the commit.
+func warnHook(err error) int {
+	fmt.Fprintf(os.Stderr, "smartcommit: not pre-filling the message: %v\n", err)
+	return 0
+}
@@ -0,0 +1,140 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/arpxspace/smartcommit/internal/ai"
+	"github.com/arpxspace/smartcommit/internal/config"
+	"github.com/arpxspace/smartcommit/internal/conventional"
+	"github.com/arpxspace/smartcommit/internal/git"
+	"github.com/arpxspace/smartcommit/internal/guard"
+	"github.com/arpxspace/smartcommit/internal/staged"
+)
+
+// runLint checks a commit message file, as passed to a commit-msg hook,
+// against Conventional Commits, the configured conventions, the form
+// guard.Check expects, and, unless disabled, an AI review for vague language. It exits
+// non-zero with the problems on stderr if any are found.
+func runLint(args []string) int {
+	fs := newFlagSet("smartcommit lint")
+	noAI := fs.Bool("no-ai", false, "skip the AI review for vague language")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: smartcommit lint [--no-ai] <msgfile>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	data, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		return fail(err)
+	}
+	message := cleanMessage(string(data), git.CommentString())
+	if message == "" || generatedByGit(message) {
+		return 0
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fail(err)
+	}
+	if git.IsRepo() {
+		if cfg, err = staged.Configure(cfg); err != nil {
+			return fail(err)
+		}
+	}
+
+	subject, _, _ := strings.Cut(message, "\n")
+	var problems []string
+	// A message template decides the form of the subject.
+	if cfg.MessageTemplate == "" {
+		if err := conventional.Check(subject, cfg.Conventions.AllowedTypes(), cfg.Conventions.Scopes); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	problems = append(problems, guard.Check(message, cfg.Conventions.MaxSubjectLength)...)
+
+	if !*noAI {
+		if critique, err := review(cfg, message); err != nil {
+			// The rules above still apply without a provider.
+			fmt.Fprintf(os.Stderr, "smartcommit: skipping the AI review: %v\n", err)
+		} else {
+			for _, vague := range critique.VagueLanguage {
+				problems = append(problems, "vague: "+vague)
+			}
+			if critique.MissingWhy != "" {
+				fmt.Fprintf(os.Stderr, "smartcommit: consider explaining why: %s\n", critique.MissingWhy)
+			}
+		}
+	}
+
+	if len(problems) == 0 {
+		return 0
+	}
+	fmt.Fprintln(os.Stderr, "smartcommit: the commit message needs work:")
+	for _, p := range problems {
+		fmt.Fprintf(os.Stderr, "  - %s\n", p)
+	}
+	fmt.Fprintln(os.Stderr, "Edit the message and commit again, or bypass the check with --no-verify.")
+	return 1
+}
+
+// review has the AI critique message against the staged diff, if any.
+func review(cfg *config.Config, message string) (*ai.CritiqueResponse, error) {
+	client, err := newClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var d string
+	if git.IsRepo() {
+		change, err := staged.Collect(cfg)
+		if err != nil && !errors.Is(err, staged.ErrNothingStaged) {
+			return nil, err
+		}
+		if change != nil {
+			d = change.Context(cfg).Diff()
+		}
+	}
+	critique, err := client.CritiqueMessage(context.Background(), d, message)
+	recordUsage(cfg, "lint", client)
+	return critique, err
+}
+
+// scissors is the line below which git drops everything in a message file,
+// as written by commit --verbose, after the comment string.
+const scissors = " ------------------------ >8 ------------------------"
+
+// cleanMessage strips what git strips from a message file: lines starting
+// with comment, anything below the scissors line, and surrounding blank
+// lines.
+func cleanMessage(content, comment string) string {
+	var lines []string
+	for _, line := range strings.Split(content, "\n") {
+		if strings.TrimRight(line, "\r") == comment+scissors {
+			break
+		}
+		if !strings.HasPrefix(line, comment) {
+			lines = append(lines, strings.TrimRight(line, " \t\r"))
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// generatedByGit reports whether message was written by git or for an
+// autosquash, which don't follow the project's conventions.
+func generatedByGit(message string) bool {
+	for _, prefix := range []string{"Merge ", "Revert \"", "fixup! ", "squash! ", "amend! "} {
+		if strings.HasPrefix(message, prefix) {
+			return true
+		}
+	}
+	return false
+}
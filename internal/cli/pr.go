@@ -0,0 +1,139 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/arpxspace/smartcommit/internal/config"
+	"github.com/arpxspace/smartcommit/internal/diff"
+	"github.com/arpxspace/smartcommit/internal/git"
+	"github.com/arpxspace/smartcommit/internal/github"
+	"github.com/arpxspace/smartcommit/internal/gitlab"
+	"github.com/arpxspace/smartcommit/internal/network"
+	"github.com/arpxspace/smartcommit/internal/redact"
+	"github.com/arpxspace/smartcommit/internal/staged"
+)
+
+// runPR writes a pull request title and description for the current branch
+// from its commits and cumulative diff against the base branch, printing it
+// or opening the pull request on GitHub or GitLab.
+func runPR(args []string) int {
+	fs := newFlagSet("smartcommit pr")
+	base := fs.String("base", "", "the `branch` the pull request merges into (default: the remote's default branch)")
+	create := fs.Bool("create", false, "open the pull request on GitHub or GitLab instead of printing it")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: smartcommit pr [--base <branch>] [--create]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return 2
+	}
+
+	if !git.IsRepo() {
+		return fail(fmt.Errorf("not a git repository"))
+	}
+	cfg, client, err := newProvider()
+	if err != nil {
+		return fail(err)
+	}
+	branch, err := git.CurrentBranch()
+	if err != nil {
+		return fail(err)
+	}
+	if branch == "" {
+		return fail(fmt.Errorf("HEAD is detached; check out the branch to open a pull request for"))
+	}
+	if *base == "" {
+		if *base, err = git.DefaultBranch(); err != nil {
+			return fail(err)
+		}
+	}
+	if branch == *base {
+		return fail(fmt.Errorf("%s is the base branch; run this on the branch to open a pull request for", branch))
+	}
+
+	commits, err := git.GetLogRange(*base + "..HEAD")
+	if err != nil {
+		return fail(err)
+	}
+	if len(commits) == 0 {
+		return fail(fmt.Errorf("%s has no commits that aren't on %s", branch, *base))
+	}
+	raw, err := git.BranchDiff(*base, cfg.Diff.Args()...)
+	if err != nil {
+		return fail(err)
+	}
+	sc := staged.Context{Config: cfg, Files: diff.Parse(raw), Redact: cfg.PrivacyReview}
+	if secrets := sc.Secrets(); len(secrets) > 0 {
+		sc.Redact = true
+		fmt.Fprintf(os.Stderr, "smartcommit: redacting %d possible secret(s) before sending, in %s\n", len(secrets), strings.Join(secretPaths(secrets), ", "))
+	}
+	d := sc.Diff()
+	if sc.TooLarge() {
+		if d, err = summarize(client, sc); err != nil {
+			recordUsage(cfg, "pr", client)
+			return fail(err)
+		}
+	}
+	log := commitLog(commits)
+	if sc.Redact {
+		log = redact.Secrets(log)
+	}
+
+	fmt.Fprintf(os.Stderr, "Describing %d commit(s) on %s against %s...\n", len(commits), branch, *base)
+	pr, err := client.DescribePullRequest(context.Background(), d, log)
+	recordUsage(cfg, "pr", client)
+	if err != nil {
+		return fail(err)
+	}
+
+	if !*create {
+		fmt.Printf("%s\n\n%s\n", strings.TrimSpace(pr.Title), pr.Body())
+		return 0
+	}
+	url, err := openPullRequest(cfg, branch, *base, strings.TrimSpace(pr.Title), pr.Body())
+	if err != nil {
+		return fail(err)
+	}
+	fmt.Println(url)
+	return 0
+}
+
+// commitLog formats commits, given newest first, oldest first for the model.
+func commitLog(commits []git.Commit) string {
+	var b strings.Builder
+	for i := len(commits) - 1; i >= 0; i-- {
+		b.WriteString(commits[i].Subject + "\n")
+		if commits[i].Body != "" {
+			b.WriteString("\n" + commits[i].Body + "\n")
+		}
+		b.WriteString("---\n")
+	}
+	return b.String()
+}
+
+// openPullRequest opens a pull request from head into base on the forge the
+// origin remote is on, returning its URL.
+func openPullRequest(cfg *config.Config, head, base, title, body string) (string, error) {
+	httpClient, err := network.Client(cfg.Network, 10*time.Second)
+	if err != nil {
+		return "", err
+	}
+	remote := git.RemoteURL("origin")
+	if repo, ok := github.Repo(remote, github.Host(cfg.GitHub.APIURL)); ok {
+		client := github.NewClient(cfg.GitHub.APIURL, cfg.GitHub.Token(), httpClient)
+		return client.CreatePullRequest(context.Background(), repo, github.PullRequest{Title: title, Body: body, Head: head, Base: base})
+	}
+	if project, ok := gitlab.Project(remote, gitlab.Host(cfg.GitLab.APIURL)); ok {
+		client := gitlab.NewClient(cfg.GitLab.APIURL, cfg.GitLab.Token(), httpClient)
+		return client.CreateMergeRequest(context.Background(), project, gitlab.MergeRequest{Title: title, Description: body, SourceBranch: head, TargetBranch: base})
+	}
+	return "", fmt.Errorf("the origin remote isn't on GitHub or GitLab; run without --create and open the pull request by hand")
+}
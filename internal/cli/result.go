@@ -0,0 +1,92 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/arpxspace/smartcommit/internal/ai"
+	"github.com/arpxspace/smartcommit/internal/config"
+	"github.com/arpxspace/smartcommit/internal/conventional"
+	"github.com/arpxspace/smartcommit/internal/usage"
+)
+
+// Output formats of headless runs.
+const (
+	formatText = "text"
+	formatJSON = "json"
+)
+
+// result is a generated message as --format json reports it, for scripts and
+// CI pipelines.
+type result struct {
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+	// Type and Scope are empty when the subject isn't a Conventional
+	// Commits one.
+	Type      string            `json:"type"`
+	Scope     string            `json:"scope"`
+	Questions []string          `json:"questions"`
+	Answers   map[string]string `json:"answers"`
+	Model     string            `json:"model"`
+	Tokens    resultTokens      `json:"tokens"`
+	// Cost is the estimated cost in US dollars, or null when the model's
+	// price isn't known.
+	Cost *float64 `json:"cost"`
+
+	message string
+}
+
+type resultTokens struct {
+	Prompt     int `json:"prompt"`
+	Completion int `json:"completion"`
+}
+
+// newResult describes message, generated by client after asking questions
+// and getting answers.
+func newResult(cfg *config.Config, client ai.Provider, message string, questions []string, answers map[string]string) result {
+	subject, body, _ := strings.Cut(strings.TrimSpace(message), "\n")
+	r := result{
+		message:   message,
+		Subject:   subject,
+		Body:      strings.TrimSpace(body),
+		Questions: questions,
+		Answers:   answers,
+		Model:     client.Model(),
+	}
+	if r.Questions == nil {
+		r.Questions = []string{}
+	}
+	if r.Answers == nil {
+		r.Answers = map[string]string{}
+	}
+	if h, ok := conventional.Parse(subject); ok {
+		r.Type, r.Scope = h.Type, h.Scope
+	}
+	u := client.Usage()
+	r.Tokens = resultTokens{Prompt: u.PromptTokens, Completion: u.CompletionTokens}
+	if cost, ok := usage.Cost(cfg, string(cfg.Provider), r.Model, u.PromptTokens, u.CompletionTokens); ok {
+		r.Cost = &cost
+	}
+	return r
+}
+
+// writeResult writes r to file, or to stdout if file is "": the message as
+// plain text or, for formatJSON, all of r.
+func writeResult(file, format string, r result) error {
+	text := strings.TrimSpace(r.message)
+	if format == formatJSON {
+		out, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return err
+		}
+		text = string(out)
+	}
+	text += "\n"
+	if file == "" {
+		_, err := fmt.Print(text)
+		return err
+	}
+	return os.WriteFile(file, []byte(text), 0o644)
+}
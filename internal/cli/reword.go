@@ -0,0 +1,235 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/arpxspace/smartcommit/internal/ai"
+	"github.com/arpxspace/smartcommit/internal/config"
+	"github.com/arpxspace/smartcommit/internal/git"
+	"github.com/arpxspace/smartcommit/internal/redact"
+	"github.com/arpxspace/smartcommit/internal/staged"
+)
+
+// runReword edits the message of HEAD or an older unpushed commit, optionally
+// regenerating it from the commit's diff first. Given a range, it walks the
+// commits in it instead; see runRewordRange.
+func runReword(args []string) int {
+	fs := newFlagSet("smartcommit reword")
+	generate := fs.Bool("generate", false, "regenerate the message from the commit's diff before editing")
+	force := fs.Bool("force", false, "reword even if the commit has already been pushed")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: smartcommit reword [--generate] [--force] [<commit> | <range>]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return 2
+	}
+
+	if !git.IsRepo() {
+		return fail(fmt.Errorf("not a git repository"))
+	}
+	rev := "HEAD"
+	if fs.NArg() == 1 {
+		rev = fs.Arg(0)
+	}
+	if strings.Contains(rev, "..") {
+		return runRewordRange(rev, *force)
+	}
+	sha, err := git.ResolveCommit(rev)
+	if err != nil {
+		return fail(err)
+	}
+
+	pushed, err := git.IsPushed(sha)
+	if err != nil {
+		return fail(err)
+	}
+	if pushed && !*force {
+		return fail(fmt.Errorf("%s has already been pushed; rewording it rewrites shared history (use --force to do it anyway)", rev))
+	}
+
+	old, err := git.CommitMessage(sha)
+	if err != nil {
+		return fail(err)
+	}
+
+	message := old
+	if *generate {
+		cfg, client, err := newProvider()
+		if err != nil {
+			return fail(err)
+		}
+		history, err := git.GetRecentHistory(10)
+		if err != nil {
+			return fail(err)
+		}
+		fmt.Println("Generating a new message...")
+		message, err = regenerateMessage(client, sha, old, history)
+		recordUsage(cfg, "reword", client)
+		if err != nil {
+			return fail(err)
+		}
+	}
+
+	edited, err := git.EditMessage(message)
+	if err != nil {
+		return fail(err)
+	}
+	if edited == "" {
+		return fail(fmt.Errorf("aborting reword due to empty message"))
+	}
+	if edited == old {
+		fmt.Println("Message unchanged.")
+		return 0
+	}
+
+	sign, err := signArgs()
+	if err != nil {
+		return fail(err)
+	}
+	if err := git.Reword(sha, edited, sign...); err != nil {
+		return fail(err)
+	}
+	fmt.Printf("Reworded %s\n", rev)
+	return 0
+}
+
+// signArgs returns the git commit arguments for signing that the config,
+// with the repo's, asks for.
+func signArgs() ([]string, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	if cfg, err = staged.Configure(cfg); err != nil {
+		return nil, err
+	}
+	return cfg.SignArgs(), nil
+}
+
+// regenerateMessage writes a new message for commit sha from its diff,
+// keeping the facts its old message states.
+func regenerateMessage(client ai.Provider, sha, old, history string) (string, error) {
+	diff, err := git.CommitDiff(sha)
+	if err != nil {
+		return "", err
+	}
+	if redact.ContainsSecret(diff) {
+		fmt.Println("Redacting possible secrets in the commit's diff before sending.")
+		diff = redact.Secrets(diff)
+	}
+	return client.GenerateCommitMessage(context.Background(), diff, history, map[string]string{
+		"What does the commit's current message say? (Keep any facts it states.)": old,
+	})
+}
+
+// runRewordRange walks the commits in revRange, oldest first, showing each
+// one's changes and message next to a regenerated one to take, edit, or
+// skip, then rewrites the chosen messages with one interactive rebase.
+func runRewordRange(revRange string, force bool) int {
+	commits, err := git.RangeCommits(revRange)
+	if err != nil {
+		return fail(err)
+	}
+	if len(commits) == 0 {
+		return fail(fmt.Errorf("%s has no commits", revRange))
+	}
+	for _, c := range commits {
+		pushed, err := git.IsPushed(c)
+		if err != nil {
+			return fail(err)
+		}
+		if pushed && !force {
+			return fail(fmt.Errorf("%s has already been pushed; rewording it rewrites shared history (use --force to do it anyway)", c[:7]))
+		}
+	}
+
+	cfg, client, err := newProvider()
+	if err != nil {
+		return fail(err)
+	}
+	defer recordUsage(cfg, "reword", client)
+	history, err := git.GetRecentHistory(10)
+	if err != nil {
+		return fail(err)
+	}
+
+	in := bufio.NewReader(os.Stdin)
+	messages := make(map[string]string)
+	for i, sha := range commits {
+		old, err := git.CommitMessage(sha)
+		if err != nil {
+			return fail(err)
+		}
+		stat, err := git.CommitStat(sha)
+		if err != nil {
+			return fail(err)
+		}
+		fmt.Printf("\n[%d/%d] %s\n%s\n\nCurrent message:\n%s\n\n", i+1, len(commits), sha[:7], stat, indent(old))
+		fmt.Println("Generating a new message...")
+		proposed, err := regenerateMessage(client, sha, old, history)
+		if err != nil {
+			return fail(err)
+		}
+		fmt.Printf("\nProposed message:\n%s\n\n", indent(proposed))
+
+	ask:
+		for {
+			fmt.Print("Use it? [y]es, [e]dit, [n]o, show [d]iff, [q]uit without rewording: ")
+			line, err := in.ReadString('\n')
+			if err != nil && line == "" {
+				fmt.Println()
+				return 0
+			}
+			switch strings.ToLower(strings.TrimSpace(line)) {
+			case "y", "yes":
+				if proposed != old {
+					messages[sha] = proposed
+				}
+				break ask
+			case "e", "edit":
+				edited, err := git.EditMessage(proposed)
+				if err != nil {
+					return fail(err)
+				}
+				if edited != "" && edited != old {
+					messages[sha] = edited
+				}
+				break ask
+			case "n", "no":
+				break ask
+			case "d", "diff":
+				show := git.ShowCmd(sha)
+				show.Stdin, show.Stdout, show.Stderr = os.Stdin, os.Stdout, os.Stderr
+				show.Run() // Ignore error, only for looking
+			case "q", "quit":
+				fmt.Println("No commits reworded.")
+				return 0
+			}
+		}
+	}
+
+	if len(messages) == 0 {
+		fmt.Println("\nNo messages changed.")
+		return 0
+	}
+	fmt.Printf("\nRewording %d commit(s)...\n", len(messages))
+	if err := git.RewordAll(messages, cfg.SignArgs()...); err != nil {
+		return fail(err)
+	}
+	fmt.Printf("Reworded %d commit(s) in %s\n", len(messages), revRange)
+	return 0
+}
+
+// indent indents every line of text for display.
+func indent(text string) string {
+	return "    " + strings.ReplaceAll(text, "\n", "\n    ")
+}
@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/arpxspace/smartcommit/internal/ai"
+	"github.com/arpxspace/smartcommit/internal/config"
+	"github.com/arpxspace/smartcommit/internal/diff"
+	"github.com/arpxspace/smartcommit/internal/git"
+	"github.com/arpxspace/smartcommit/internal/quality"
+	"github.com/arpxspace/smartcommit/internal/redact"
+	"github.com/arpxspace/smartcommit/internal/staged"
+)
+
+// runScore rates the messages of past commits, HEAD by default, on why the
+// change was made, specificity, and cohesion, as the review screen does.
+// The AI judges them too with --ai or score_judge.
+func runScore(args []string) int {
+	fs := newFlagSet("smartcommit score")
+	judge := fs.Bool("ai", false, "have the AI judge each message alongside the local heuristics")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: smartcommit score [--ai] [<commit>...]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if !git.IsRepo() {
+		return fail(fmt.Errorf("not a git repository"))
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return fail(err)
+	}
+	if cfg, err = staged.Configure(cfg); err != nil {
+		return fail(err)
+	}
+	var client ai.Provider
+	if *judge || cfg.ScoreJudge {
+		if client, err = newClient(cfg); err != nil {
+			return fail(err)
+		}
+	}
+
+	revs := fs.Args()
+	if len(revs) == 0 {
+		revs = []string{"HEAD"}
+	}
+	for i, rev := range revs {
+		if i > 0 {
+			fmt.Println()
+		}
+		if err := scoreCommit(cfg, client, rev); err != nil {
+			if client != nil {
+				recordUsage(cfg, "score", client)
+			}
+			return fail(err)
+		}
+	}
+	if client != nil {
+		recordUsage(cfg, "score", client)
+	}
+	return 0
+}
+
+// scoreCommit prints the quality score of rev's message, judged by client
+// as well unless it's nil.
+func scoreCommit(cfg *config.Config, client ai.Provider, rev string) error {
+	sha, err := git.ResolveCommit(rev)
+	if err != nil {
+		return err
+	}
+	message, err := git.CommitMessage(sha)
+	if err != nil {
+		return err
+	}
+	d, err := git.CommitDiff(sha)
+	if err != nil {
+		return err
+	}
+	r := quality.Rate(message, diff.Paths(diff.Parse(d)), cfg.Vague())
+	if client != nil {
+		if redact.ContainsSecret(d) {
+			d = redact.Secrets(d)
+		}
+		judged, err := client.ScoreMessage(context.Background(), d, message)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "smartcommit: scoring %s without the AI judge: %v\n", sha[:7], err)
+		} else {
+			r = r.WithJudge(judged)
+		}
+	}
+
+	subject, _, _ := strings.Cut(message, "\n")
+	fmt.Printf("%s %s\n", sha[:7], subject)
+	fmt.Printf("  Quality %d/100 (why %d, specificity %d, cohesion %d)", r.Score, r.Why, r.Specificity, r.Cohesion)
+	if r.Judged {
+		fmt.Print(", judged by AI")
+	}
+	fmt.Println()
+	if r.Suggestion != "" {
+		fmt.Printf("  Tip: %s\n", r.Suggestion)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/arpxspace/smartcommit/internal/config"
+	"github.com/arpxspace/smartcommit/internal/server"
+)
+
+// runServe exposes the pipeline over HTTP for the repositories listed in
+// serve_repos, so bots and editor extensions can share one configured instance.
+func runServe(args []string) int {
+	fs := newFlagSet("smartcommit serve")
+	addr := fs.String("http", "localhost:7345", "listen on `address`")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	host, port, err := net.SplitHostPort(*addr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "smartcommit: invalid address %q: %v\n", *addr, err)
+		return 2
+	}
+	// Without a host, listen on localhost rather than every interface.
+	if host == "" {
+		*addr = net.JoinHostPort("localhost", port)
+	}
+
+	// The server works across repositories, so only the global config
+	// applies, whatever directory it's started from.
+	cfg, err := config.Load()
+	if err != nil {
+		return fail(err)
+	}
+	client, err := newClient(cfg)
+	if err != nil {
+		return fail(err)
+	}
+	token := cfg.ServeToken
+	if token == "" {
+		token = rand.Text()
+	}
+	srv, err := server.New(cfg, client, *addr, token)
+	if err != nil {
+		return fail(err)
+	}
+
+	fmt.Printf("smartcommit listening on %s\n", *addr)
+	if cfg.ServeToken == "" {
+		fmt.Printf("Send requests with the header \"Authorization: Bearer %s\"\n", token)
+	}
+	if err := http.ListenAndServe(*addr, srv.Handler()); err != nil {
+		return fail(err)
+	}
+	return 0
+}
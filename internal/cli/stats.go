@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/arpxspace/smartcommit/internal/config"
+	"github.com/arpxspace/smartcommit/internal/usage"
+)
+
+// runStats prints the tokens used and estimated spend per model for each
+// month, and the total across them.
+func runStats(args []string) int {
+	fs := newFlagSet("smartcommit stats")
+	months := fs.Int("months", 12, "cover the last `n` months, including this one")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *months < 1 {
+		return fail(fmt.Errorf("--months must be at least 1"))
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fail(err)
+	}
+	if cfg.DisableStore {
+		fmt.Println("Usage isn't recorded because disable_store is set.")
+		return 0
+	}
+	since := usage.MonthStart(time.Now()).AddDate(0, 1-*months, 0)
+	sums, err := usage.ByMonth(cfg, since)
+	if err != nil {
+		return fail(err)
+	}
+	if len(sums) == 0 {
+		fmt.Printf("No provider requests recorded since %s.\n", since.Format("January 2006"))
+		return 0
+	}
+
+	total := usage.Row{Priced: true}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "MONTH\tPROVIDER\tMODEL\tRUNS\tREQUESTS\tPROMPT\tCOMPLETION\tEST. COST\t")
+	for _, sum := range sums {
+		month := sum.Month.Format("2006-01")
+		for _, r := range sum.Rows {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%d\t%d\t%s\t\n", month, r.Provider, r.Model, r.Runs, r.Requests, r.PromptTokens, r.CompletionTokens, formatCost(r))
+		}
+		t := sum.Total
+		total.Runs += t.Runs
+		total.Requests += t.Requests
+		total.PromptTokens += t.PromptTokens
+		total.CompletionTokens += t.CompletionTokens
+		total.Cost += t.Cost
+		total.Priced = total.Priced && t.Priced
+	}
+	fmt.Fprintf(w, "total\t\t\t%d\t%d\t%d\t%d\t%s\t\n", total.Runs, total.Requests, total.PromptTokens, total.CompletionTokens, formatCost(total))
+	w.Flush()
+
+	if !total.Priced {
+		fmt.Println("\n* some models have no known price; add them under \"prices\" in the config")
+	}
+	fmt.Printf("\nAverage per month: $%.2f over %d month(s) with usage\n", total.Cost/float64(len(sums)), len(sums))
+	return 0
+}
@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/arpxspace/smartcommit/internal/git"
+	"github.com/arpxspace/smartcommit/internal/style"
+)
+
+// runTemplate writes a .gitmessage template tailored to the repo's history
+// and points commit.template at it.
+func runTemplate(args []string) int {
+	fs := newFlagSet("smartcommit template")
+	last := fs.Int("last", 200, "number of recent commits to learn the style from")
+	output := fs.String("output", "", "write the template to `path` (default: .gitmessage at the repo root)")
+	stdout := fs.Bool("stdout", false, "print the template instead of writing it")
+	noConfig := fs.Bool("no-config", false, "don't set commit.template")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if !git.IsRepo() {
+		return fail(fmt.Errorf("not a git repository"))
+	}
+	commits, err := git.GetLog(*last)
+	if err != nil {
+		return fail(err)
+	}
+	tmpl := style.Analyze(commits).Template()
+
+	if *stdout {
+		fmt.Print(tmpl)
+		return 0
+	}
+
+	path := *output
+	if path == "" {
+		root, err := git.RepoRoot()
+		if err != nil {
+			return fail(err)
+		}
+		path = filepath.Join(root, ".gitmessage")
+	}
+	if err := os.WriteFile(path, []byte(tmpl), 0644); err != nil {
+		return fail(err)
+	}
+	fmt.Printf("Wrote %s\n", path)
+
+	if !*noConfig {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return fail(err)
+		}
+		if err := git.SetConfig("commit.template", abs); err != nil {
+			return fail(err)
+		}
+		fmt.Printf("Set commit.template to %s\n", abs)
+	}
+	return 0
+}
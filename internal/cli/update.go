@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/arpxspace/smartcommit/internal/config"
+	"github.com/arpxspace/smartcommit/internal/network"
+	"github.com/arpxspace/smartcommit/internal/update"
+)
+
+// runUpdate replaces the running binary with the latest release, once its
+// checksum, and signature if the build carries a release key, check out.
+func runUpdate(args []string) int {
+	fs := newFlagSet("smartcommit update")
+	check := fs.Bool("check", false, "only report whether a newer release is out")
+	force := fs.Bool("force", false, "install the latest release even over a development build or the same version")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fail(err)
+	}
+	api, err := network.Client(cfg.Network, 10*time.Second)
+	if err != nil {
+		return fail(err)
+	}
+	ctx := context.Background()
+	release, err := update.Latest(ctx, api)
+	if err != nil {
+		return fail(err)
+	}
+	// A development build has no version to compare, so it's never told
+	// to update, and is only replaced with --force.
+	version := update.Current()
+	newer := version != "" && update.Newer(release.Tag, version)
+	current := version
+	if current == "" {
+		current = "a development build"
+	}
+	if *check {
+		if newer {
+			fmt.Printf("smartcommit %s is available (this is %s); run `smartcommit update` to install it.\n", release.Tag, current)
+		} else {
+			fmt.Printf("smartcommit %s is the latest release (this is %s).\n", release.Tag, current)
+		}
+		return 0
+	}
+	if !newer && !*force {
+		if version == "" {
+			return fail(fmt.Errorf("this is a development build; use --force to replace it with %s", release.Tag))
+		}
+		fmt.Printf("smartcommit %s is already the latest release.\n", current)
+		return 0
+	}
+
+	fmt.Fprintf(os.Stderr, "Downloading smartcommit %s...\n", release.Tag)
+	if update.PublicKey == "" {
+		fmt.Fprintln(os.Stderr, "This build carries no release key, so only the download's checksum is verified.")
+	}
+	downloads, err := network.Client(cfg.Network, 5*time.Minute)
+	if err != nil {
+		return fail(err)
+	}
+	binary, err := update.Download(ctx, downloads, release)
+	if err != nil {
+		return fail(err)
+	}
+	path, err := update.Install(binary)
+	if err != nil {
+		return fail(fmt.Errorf("failed to install %s: %w", release.Tag, err))
+	}
+	fmt.Printf("Updated %s from %s to %s.\n", path, current, release.Tag)
+	return 0
+}
@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/arpxspace/smartcommit/internal/config"
+	"github.com/arpxspace/smartcommit/internal/usage"
+)
+
+// runUsage prints the tokens used and estimated spend per provider and model
+// for a month, along with the budget if one is configured.
+func runUsage(args []string) int {
+	fs := newFlagSet("smartcommit usage")
+	month := fs.String("month", "", "show `YYYY-MM` instead of the current month")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	t := time.Now()
+	if *month != "" {
+		var err error
+		if t, err = time.ParseInLocation("2006-01", *month, time.Local); err != nil {
+			return fail(fmt.Errorf("invalid month %q, expected YYYY-MM", *month))
+		}
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fail(err)
+	}
+	if cfg.DisableStore {
+		fmt.Println("Usage isn't recorded because disable_store is set.")
+		return 0
+	}
+	sum, err := usage.ForMonth(cfg, t)
+	if err != nil {
+		return fail(err)
+	}
+
+	fmt.Printf("Usage for %s\n\n", sum.Month.Format("January 2006"))
+	if len(sum.Rows) == 0 {
+		fmt.Println("No provider requests recorded.")
+		return 0
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PROVIDER\tMODEL\tRUNS\tREQUESTS\tPROMPT\tCOMPLETION\tEST. COST\t")
+	for _, r := range sum.Rows {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%d\t%s\t\n", r.Provider, r.Model, r.Runs, r.Requests, r.PromptTokens, r.CompletionTokens, formatCost(r))
+	}
+	total := sum.Total
+	fmt.Fprintf(w, "total\t\t%d\t%d\t%d\t%d\t%s\t\n", total.Runs, total.Requests, total.PromptTokens, total.CompletionTokens, formatCost(total))
+	w.Flush()
+
+	if !sum.Total.Priced {
+		fmt.Println("\n* some models have no known price; add them under \"prices\" in the config")
+	}
+	if cfg.MonthlyBudget > 0 {
+		fmt.Printf("\nBudget: $%.2f of $%.2f (%.0f%%)\n", sum.Total.Cost, cfg.MonthlyBudget, 100*sum.Total.Cost/cfg.MonthlyBudget)
+		if warning := sum.BudgetWarning(cfg.MonthlyBudget); warning != "" {
+			fmt.Println(warning)
+		}
+	}
+	return 0
+}
+
+func formatCost(r usage.Row) string {
+	s := fmt.Sprintf("$%.4f", r.Cost)
+	if !r.Priced {
+		s += "*"
+	}
+	return s
+}
@@ -2,8 +2,19 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/arpxspace/smartcommit/internal/conventional"
+	"github.com/arpxspace/smartcommit/internal/logging"
+	"github.com/arpxspace/smartcommit/internal/secret"
 )
 
 type ProviderType string
@@ -11,16 +22,846 @@ type ProviderType string
 const (
 	ProviderOpenAI ProviderType = "openai"
 	ProviderOllama ProviderType = "ollama"
+	ProviderAzure  ProviderType = "azure"
+	// ProviderOpenRouter reaches models from many vendors with one key
+	// through OpenRouter.
+	ProviderOpenRouter ProviderType = "openrouter"
+	// ProviderCustom is any server with an OpenAI-compatible API, such as
+	// llama.cpp's server or LM Studio.
+	ProviderCustom ProviderType = "custom"
+	// ProviderMock answers from canned fixtures without network access,
+	// for tests, demos, and offline development.
+	ProviderMock ProviderType = "mock"
+)
+
+// DefaultAzureAPIVersion is the Azure OpenAI API version used when none is
+// configured. It's the first GA version supporting Structured Outputs.
+const DefaultAzureAPIVersion = "2024-10-21"
+
+// Message styles.
+const (
+	// MessageStyleConventional writes Conventional Commits subjects. It's
+	// the default.
+	MessageStyleConventional = "conventional"
+	// MessageStyleGitmoji leads each subject with the gitmoji for its type,
+	// as in "✨ feat: add login".
+	MessageStyleGitmoji = "gitmoji"
 )
 
+// DefaultOpenAIModel is the model requests to OpenAI are sent to unless
+// another is configured.
+const DefaultOpenAIModel = "gpt-4o-2024-08-06"
+
+// DefaultOpenRouterModel is the OpenRouter model used when none is chosen.
+const DefaultOpenRouterModel = "openai/gpt-4o"
+
 type Config struct {
+	// Version is the version of the file's format; see CurrentVersion.
+	Version int `json:"version"`
+
 	Provider     ProviderType `json:"provider"`
 	OpenAIAPIKey string       `json:"openai_api_key"`
 	OllamaModel  string       `json:"ollama_model"`
 	OllamaURL    string       `json:"ollama_url"`
+
+	// OpenAIModel is the OpenAI model requests are sent to. Empty uses
+	// DefaultOpenAIModel.
+	OpenAIModel string `json:"openai_model,omitempty"`
+
+	// OllamaStructuredOutput says whether the Ollama model follows JSON
+	// schema response formats. nil tries the schema and falls back to
+	// asking for JSON in the prompt when a reply can't be parsed; false
+	// always asks in the prompt; true never falls back.
+	OllamaStructuredOutput *bool `json:"ollama_structured_output,omitempty"`
+
+	// Azure OpenAI settings, used when Provider is "azure". Requests go to
+	// the deployment rather than a model; the key falls back to the
+	// AZURE_OPENAI_API_KEY environment variable.
+	AzureEndpoint   string `json:"azure_endpoint,omitempty"`
+	AzureDeployment string `json:"azure_deployment,omitempty"`
+	AzureAPIVersion string `json:"azure_api_version,omitempty"`
+	AzureAPIKey     string `json:"azure_api_key,omitempty"`
+
+	// OpenRouter settings, used when Provider is "openrouter". The model is
+	// an OpenRouter ID such as "anthropic/claude-3.5-sonnet"; the key falls
+	// back to the OPENROUTER_API_KEY environment variable.
+	OpenRouterAPIKey string `json:"openrouter_api_key,omitempty"`
+	OpenRouterModel  string `json:"openrouter_model,omitempty"`
+
+	// Custom endpoint settings, used when Provider is "custom". The key is
+	// optional. CustomStructuredOutput says the server supports JSON schema
+	// response formats; without it the schema is described in the prompt
+	// and replies are repaired before they're parsed.
+	CustomURL              string `json:"custom_url,omitempty"`
+	CustomAPIKey           string `json:"custom_api_key,omitempty"`
+	CustomModel            string `json:"custom_model,omitempty"`
+	CustomStructuredOutput bool   `json:"custom_structured_output,omitempty"`
+
+	// MockFixtures is a JSON file of canned responses for the mock
+	// provider; see ai.MockFixtures. Empty uses the built-in ones.
+	MockFixtures string `json:"mock_fixtures,omitempty"`
+
+	// PlaintextKeys stores API keys in this file instead of the OS keychain,
+	// for systems without one.
+	PlaintextKeys bool `json:"plaintext_keys,omitempty"`
+
+	// Keychain names the API keys Save stored in the OS keychain. A run
+	// only reads back those its provider uses, and knows from the rest
+	// which other providers are set up.
+	Keychain []string `json:"keychain,omitempty"`
+
+	// PrivacyReview asks the user to confirm which files are sent before the first API call.
+	PrivacyReview bool `json:"privacy_review,omitempty"`
+
+	// SensitivePaths are glob patterns for areas where a change deserves a
+	// risk note. nil uses DefaultSensitivePaths; an empty list disables flagging.
+	SensitivePaths []string `json:"sensitive_paths"`
+
+	// IgnorePaths are glob patterns for noise, like lockfiles, minified
+	// bundles, and generated code, whose diff is never sent. The prompt only
+	// notes how many files were left out.
+	IgnorePaths []string `json:"ignore_paths,omitempty"`
+
+	// Ticket finds an issue key in the branch name to mention in the prompt
+	// and, when a footer is set, reference in every message.
+	Ticket Ticket `json:"ticket,omitzero"`
+
+	// CoAuthors are credited with a Co-authored-by trailer on every
+	// message, as "Name <email>".
+	CoAuthors []string `json:"co_authors,omitempty"`
+
+	// SignOff adds a Signed-off-by trailer with the git user's identity,
+	// for projects that require the Developer Certificate of Origin.
+	SignOff bool `json:"sign_off,omitempty"`
+
+	// SignCommits signs every commit with the key git is configured to
+	// sign with when true, or never signs when false. Unset, git's own
+	// commit.gpgsign decides.
+	SignCommits *bool `json:"sign_commits,omitempty"`
+
+	// GitHub fetches the issue a change is for as context.
+	GitHub GitHub `json:"github,omitzero"`
+
+	// GitLab is where `smartcommit pr --create` opens merge requests for
+	// GitLab remotes.
+	GitLab GitLab `json:"gitlab,omitzero"`
+
+	// Network adapts requests to corporate networks, with a proxy or a CA
+	// of their own.
+	Network Network `json:"network,omitzero"`
+
+	// Provenance records a signed note (provider, model, prompt hash, time)
+	// on every AI-assisted commit under refs/notes/smartcommit.
+	Provenance bool `json:"provenance,omitempty"`
+
+	// ScoreCommits rates the final message after every commit and shows a
+	// one-line verdict on the success screen.
+	ScoreCommits bool `json:"score_commits,omitempty"`
+
+	// ScoreJudge has the AI judge the message alongside the local heuristics
+	// for the quality score shown in the review and by `smartcommit score`.
+	ScoreJudge bool `json:"score_judge,omitempty"`
+
+	// Diff controls how the staged diff is collected.
+	Diff DiffOptions `json:"diff,omitzero"`
+
+	// DiffFilters name the kinds of hunk collapsed to a one-line note in the
+	// diff sent, as noise: "whitespace", "imports", and "formatting". nil
+	// uses all of them; an empty list sends every hunk.
+	DiffFilters []string `json:"diff_filters"`
+
+	// Editor is where the final message is edited: EditorGit for git's
+	// editor, EditorTUI for the editor built into the TUI. "" uses git's
+	// editor when it's installed and the TUI's otherwise.
+	Editor string `json:"editor,omitempty"`
+
+	// GitBackend picks how the repository is read and committed to:
+	// GitBackendExec runs git, and GitBackendGoGit uses the built-in go-git
+	// for where git isn't installed. "" runs git when it's installed and
+	// go-git otherwise.
+	GitBackend string `json:"git_backend,omitempty"`
+
+	// DisableGuardrails keeps generated messages as the model wrote them,
+	// without fixing their form or shortening a long subject.
+	DisableGuardrails bool `json:"disable_guardrails,omitempty"`
+
+	// VagueWords are words and phrases highlighted in the review for saying
+	// little, like "improve" or "various fixes". nil uses DefaultVagueWords;
+	// an empty list highlights nothing.
+	VagueWords []string `json:"vague_words"`
+
+	// Theme picks the TUI's colors: ThemeDark, ThemeLight, or
+	// ThemeHighContrast. "" adapts to the terminal's background.
+	Theme string `json:"theme,omitempty"`
+
+	// Colors override the theme's colors by role ("accent", "muted",
+	// "warning", "error", "success", "command", "border", "diff_hunk",
+	// "syntax_string", "syntax_number"), as "#rrggbb" or an ANSI color
+	// number.
+	Colors map[string]string `json:"colors,omitempty"`
+
+	// Keys remap the TUI's keys by action, like "accept" or "scroll_down",
+	// to the keys that trigger it, replacing the defaults. Two-key
+	// sequences like "ZZ" are allowed; an empty list unbinds the action.
+	Keys map[string][]string `json:"keys,omitempty"`
+
+	// DisableStore stops sessions from being recorded in the local session store.
+	DisableStore bool `json:"disable_store,omitempty"`
+
+	// DisableUpdateCheck stops the welcome screen from checking, once a
+	// day, whether a newer release of smartcommit is out.
+	DisableUpdateCheck bool `json:"disable_update_check,omitempty"`
+
+	// LogLevel is the least severe level logged to smartcommit.log in the
+	// data directory: "debug", "info", "warn", "error", or "off". "" logs
+	// at "info".
+	LogLevel string `json:"log_level,omitempty"`
+
+	// DisableCache stops provider responses from being cached in the local
+	// session store.
+	DisableCache bool `json:"disable_cache,omitempty"`
+
+	// APIChangesInBody appends a summary of exported Go API changes to the message body.
+	APIChangesInBody bool `json:"api_changes_in_body,omitempty"`
+
+	// ServeRepos are the repositories `smartcommit serve` will operate on.
+	// Requests naming any other path are refused.
+	ServeRepos []string `json:"serve_repos,omitempty"`
+
+	// ServeToken is the bearer token `smartcommit serve` requires. A new
+	// one is generated each time the server starts when it's empty.
+	ServeToken string `json:"serve_token,omitempty"`
+
+	// MonthlyBudget is the estimated spend in USD per calendar month above
+	// which smartcommit warns. 0 means no budget.
+	MonthlyBudget float64 `json:"monthly_budget,omitempty"`
+
+	// Prices overrides or adds per-model prices used to estimate spend.
+	Prices map[string]Price `json:"prices,omitempty"`
+
+	// Conventions restricts the Conventional Commits types and scopes
+	// generated messages may use.
+	Conventions Conventions `json:"conventions,omitzero"`
+
+	// MessageStyle is how subjects are written: MessageStyleConventional
+	// (or "") or MessageStyleGitmoji.
+	MessageStyle string `json:"message_style,omitempty"`
+
+	// Gitmoji overrides or adds emoji by type for MessageStyleGitmoji, on
+	// top of conventional.DefaultGitmoji.
+	Gitmoji map[string]string `json:"gitmoji,omitempty"`
+
+	// MessageTemplate lays out generated messages with text/template in
+	// place of "subject, blank line, body". See staged.MessageData for the
+	// fields, e.g. "[{{.Ticket}}] {{.Type}}: {{.Subject}}\n\n{{.Body}}".
+	MessageTemplate string `json:"message_template,omitempty"`
+
+	// Language is the natural language questions and messages are written
+	// in, such as "Japanese" or "German". Empty means English.
+	Language string `json:"language,omitempty"`
+
+	// QuestionCount is how many clarifying questions are asked, from 0 to
+	// MaxQuestionCount. 0 skips straight to the message; nil asks
+	// DefaultQuestionCount.
+	QuestionCount *int `json:"question_count,omitempty"`
+
+	// AdaptiveQuestions lets the model decide how many questions the change
+	// deserves, from none for a self-evident change up to QuestionCount.
+	AdaptiveQuestions bool `json:"adaptive_questions,omitempty"`
+
+	// HistoryAnalysis, when false, skips analyzing the history for context
+	// before the message is written. nil analyzes it.
+	HistoryAnalysis *bool `json:"history_analysis,omitempty"`
+
+	// Depth is how far the questions dig and how long message bodies run:
+	// DepthBrief, DepthDeep, or "" for in between.
+	Depth string `json:"depth,omitempty"`
+
+	// Profile is the profile used unless --profile or the welcome screen
+	// picks another: ProfileQuick, ProfileStandard (or ""), ProfileThorough,
+	// or one defined in Profiles.
+	Profile string `json:"profile,omitempty"`
+
+	// Profiles overrides the settings of the built-in profiles, or adds
+	// new ones, by name.
+	Profiles map[string]ProfileSettings `json:"profiles,omitempty"`
+
+	// StyleExamples is how many past commits most similar to the change are
+	// sent as examples of the project's style, in place of the most recent
+	// ones, up to MaxStyleExamples. 0 sends the recent history.
+	StyleExamples int `json:"style_examples,omitempty"`
+
+	// RelatedHistory sends the commits that touched the changed files whose
+	// messages are most similar to the change, in place of the most recent
+	// commits.
+	RelatedHistory bool `json:"related_history,omitempty"`
+
+	// EmbeddingModel is the model style examples and related history are
+	// selected with. Empty uses DefaultEmbeddingModel, or
+	// DefaultOllamaEmbeddingModel for Ollama.
+	EmbeddingModel string `json:"embedding_model,omitempty"`
+
+	// ContextWindow overrides the context window, in tokens, of the
+	// configured model, e.g. to match an Ollama server's num_ctx. 0 uses
+	// the model's known window.
+	ContextWindow int `json:"context_window,omitempty"`
+
+	// Generation tunes the temperature, output length, and reasoning
+	// effort of requests, per stage.
+	Generation Generation `json:"generation,omitzero"`
+
+	// MaxRetries is how often a request that was rate limited or failed
+	// with a server error is retried before giving up. nil retries
+	// DefaultMaxRetries times; 0 doesn't retry.
+	MaxRetries *int `json:"max_retries,omitempty"`
+
+	// RequestTimeout is how many seconds a provider request may take,
+	// including reading the reply, before it's abandoned. 0 waits
+	// DefaultRequestTimeout.
+	RequestTimeout int `json:"request_timeout,omitempty"`
+
+	// TraceFile is set from the --trace flag and is never persisted.
+	TraceFile string `json:"-"`
+
+	// ModelWindow is the context window, in tokens, the provider reported
+	// for the model, if it was asked. It's never persisted.
+	ModelWindow int `json:"-"`
+
+	// env holds the settings overridden from the environment, which Save
+	// leaves as they were in the file.
+	env map[string]envOverride
 }
 
-func getConfigPath() (string, error) {
+// Price is what a model costs in USD per million tokens.
+type Price struct {
+	Prompt     float64 `json:"prompt"`
+	Completion float64 `json:"completion"`
+}
+
+// Conventions are a project's allowed Conventional Commits types and scopes.
+// Empty lists allow anything.
+type Conventions struct {
+	Types  []string `json:"types,omitempty"`
+	Scopes []string `json:"scopes,omitempty"`
+	// ScopesFromDirs also allows the repository's top-level directory names as scopes.
+	ScopesFromDirs bool `json:"scopes_from_dirs,omitempty"`
+	// MaxSubjectLength limits the subject line, in characters. 0 allows any length.
+	MaxSubjectLength int `json:"max_subject_length,omitempty"`
+}
+
+// Enabled reports whether any restriction is configured.
+func (c Conventions) Enabled() bool {
+	return len(c.Types) > 0 || len(c.Scopes) > 0 || c.ScopesFromDirs
+}
+
+// AllowedTypes returns the allowed types, defaulting to the standard ones.
+func (c Conventions) AllowedTypes() []string {
+	if len(c.Types) == 0 {
+		return conventional.DefaultTypes
+	}
+	return c.Types
+}
+
+// Check reports why the subject line of message breaks these conventions,
+// or nil if it doesn't or none are configured.
+func (c Conventions) Check(message string) error {
+	subject, _, _ := strings.Cut(strings.TrimSpace(message), "\n")
+	if n := utf8.RuneCountInString(subject); c.MaxSubjectLength > 0 && n > c.MaxSubjectLength {
+		return fmt.Errorf("subject is %d characters long; the limit is %d", n, c.MaxSubjectLength)
+	}
+	if !c.Enabled() {
+		return nil
+	}
+	return conventional.Check(subject, c.AllowedTypes(), c.Scopes)
+}
+
+// CheckMessage checks message against the conventions. A message template
+// decides the form of the subject, so with one only its length is checked.
+func (c *Config) CheckMessage(message string) error {
+	conventions := c.Conventions
+	if c.MessageTemplate != "" {
+		conventions = Conventions{MaxSubjectLength: conventions.MaxSubjectLength}
+	}
+	return conventions.Check(message)
+}
+
+// Ticket configures issue keys taken from the branch name.
+type Ticket struct {
+	// Pattern is a regular expression matching the key in the branch name;
+	// its first capture group is the key if it has one. "" uses
+	// ticket.DefaultPattern, which matches keys like JIRA-1234.
+	Pattern string `json:"pattern,omitempty"`
+	// Footer is added to every message when a key is found, with "{ticket}"
+	// replaced by the key, e.g. "Refs: {ticket}". "" adds none.
+	Footer string `json:"footer,omitempty"`
+}
+
+// GitHub configures fetching issues for context. The token is read from
+// GITHUB_TOKEN or GH_TOKEN and is only needed for private repositories.
+type GitHub struct {
+	// Issues fetches the issue whose number is in the branch name, as in
+	// "123-fix-login". An issue given with --issue is fetched regardless.
+	Issues bool `json:"issues,omitempty"`
+	// APIURL is the REST API to use, for GitHub Enterprise. "" uses github.com.
+	APIURL string `json:"api_url,omitempty"`
+}
+
+// Token returns the GitHub token from the environment, or "" if unset.
+func (g GitHub) Token() string {
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		return token
+	}
+	return os.Getenv("GH_TOKEN")
+}
+
+// GitLab configures opening merge requests. The token is read from
+// GITLAB_TOKEN.
+type GitLab struct {
+	// APIURL is the REST API to use, for self-hosted GitLab. "" uses gitlab.com.
+	APIURL string `json:"api_url,omitempty"`
+}
+
+// Token returns the GitLab token from the environment, or "" if unset.
+func (g GitLab) Token() string {
+	return os.Getenv("GITLAB_TOKEN")
+}
+
+// Network configures how requests reach providers, GitHub, and GitLab.
+// Without a proxy set here, HTTPS_PROXY, HTTP_PROXY, and NO_PROXY are
+// honored from the environment.
+type Network struct {
+	// Proxy is the URL of the proxy requests go through, except to hosts
+	// in NO_PROXY and to the local machine.
+	Proxy string `json:"proxy,omitempty"`
+	// CABundle is a PEM file of certificates to trust besides the
+	// system's, such as that of a proxy that inspects TLS.
+	CABundle string `json:"ca_bundle,omitempty"`
+	// InsecureSkipVerify accepts any TLS certificate. It's a last resort,
+	// as anyone on the network can then read requests, API keys included.
+	InsecureSkipVerify bool `json:"insecure_skip_verify,omitempty"`
+}
+
+// DiffOptions shape the diff sent to the provider, trading token usage
+// against how much the model can see.
+type DiffOptions struct {
+	// ContextLines is the number of unchanged lines around each hunk (git's -U).
+	// nil uses git's default of 3.
+	ContextLines *int `json:"context_lines,omitempty"`
+	// FunctionContext shows the whole enclosing function for each hunk (-W).
+	FunctionContext bool `json:"function_context,omitempty"`
+	// IgnoreWhitespace ignores whitespace-only changes (-w).
+	IgnoreWhitespace bool `json:"ignore_whitespace,omitempty"`
+	// RenameThreshold is the similarity percentage for rename detection (-M).
+	// 0 uses git's default of 50%.
+	RenameThreshold int `json:"rename_threshold,omitempty"`
+	// NoRenames turns rename detection off, showing renames as delete + add.
+	NoRenames bool `json:"no_renames,omitempty"`
+	// EnclosingLines shows up to this many lines from the start of the
+	// function or type enclosing each hunk, when the hunk doesn't reach it,
+	// so the model knows what was modified. It isn't a git option, and is
+	// ignored with FunctionContext, which shows all of it.
+	EnclosingLines int `json:"enclosing_lines,omitempty"`
+}
+
+// Args returns the git diff arguments for these options.
+func (d DiffOptions) Args() []string {
+	var args []string
+	if d.ContextLines != nil {
+		args = append(args, fmt.Sprintf("-U%d", *d.ContextLines))
+	}
+	if d.FunctionContext {
+		args = append(args, "-W")
+	}
+	if d.IgnoreWhitespace {
+		args = append(args, "-w")
+	}
+	switch {
+	case d.NoRenames:
+		args = append(args, "--no-renames")
+	case d.RenameThreshold > 0:
+		args = append(args, fmt.Sprintf("-M%d%%", d.RenameThreshold))
+	}
+	return args
+}
+
+// DefaultSensitivePaths cover the areas most teams treat as high risk.
+var DefaultSensitivePaths = []string{
+	"auth",
+	"oauth*",
+	"*_auth.*",
+	"*crypto*",
+	"*payment*",
+	"*billing*",
+	"migrations",
+	"*migration*",
+}
+
+// SignArgs returns the git commit arguments that make SignCommits hold.
+func (c *Config) SignArgs() []string {
+	switch {
+	case c.SignCommits == nil:
+		return nil
+	case *c.SignCommits:
+		return []string{"--gpg-sign"}
+	}
+	return []string{"--no-gpg-sign"}
+}
+
+// Sensitive returns the configured sensitive path patterns.
+func (c *Config) Sensitive() []string {
+	if c.SensitivePaths == nil {
+		return DefaultSensitivePaths
+	}
+	return c.SensitivePaths
+}
+
+// DefaultVagueWords are words and phrases that hide what a change does.
+var DefaultVagueWords = []string{
+	"improve",
+	"enhance",
+	"tweak",
+	"stuff",
+	"things",
+	"misc",
+	"various fixes",
+	"various changes",
+	"minor changes",
+	"some changes",
+	"small fixes",
+	"cleanup",
+	"refactor code",
+	"update code",
+	"fix bug",
+	"fix issue",
+	"wip",
+}
+
+// Vague returns the configured vague words, or the defaults if none are set.
+func (c *Config) Vague() []string {
+	if c.VagueWords == nil {
+		return DefaultVagueWords
+	}
+	return c.VagueWords
+}
+
+// Editors for the final message.
+const (
+	EditorGit = "git"
+	EditorTUI = "tui"
+)
+
+// Git backends.
+const (
+	GitBackendExec  = "exec"
+	GitBackendGoGit = "go-git"
+)
+
+// Themes for the TUI.
+const (
+	ThemeDark         = "dark"
+	ThemeLight        = "light"
+	ThemeHighContrast = "high-contrast"
+)
+
+// DefaultDiffFilters are the filters used when none are configured.
+var DefaultDiffFilters = []string{"whitespace", "imports", "formatting"}
+
+// Filters returns the configured diff filters, or the defaults if none are set.
+func (c *Config) Filters() []string {
+	if c.DiffFilters == nil {
+		return DefaultDiffFilters
+	}
+	return c.DiffFilters
+}
+
+// Question counts.
+const (
+	DefaultQuestionCount = 3
+	MaxQuestionCount     = 5
+)
+
+// Questions returns how many clarifying questions to ask.
+func (c *Config) Questions() int {
+	if c.QuestionCount == nil {
+		return DefaultQuestionCount
+	}
+	return min(max(*c.QuestionCount, 0), MaxQuestionCount)
+}
+
+// AnalyzeHistory reports whether the history is analyzed for context.
+func (c *Config) AnalyzeHistory() bool {
+	return c.HistoryAnalysis == nil || *c.HistoryAnalysis
+}
+
+// Depths of questions and message bodies.
+const (
+	DepthBrief = "brief"
+	DepthDeep  = "deep"
+)
+
+// Profiles trade the cost and time of a run against how much it asks and
+// writes.
+const (
+	ProfileQuick    = "quick"
+	ProfileStandard = "standard"
+	ProfileThorough = "thorough"
+)
+
+// ProfileSettings are what a profile changes. Unset fields keep the
+// config's own.
+type ProfileSettings struct {
+	// Model replaces the configured provider's model.
+	Model           string `json:"model,omitempty"`
+	QuestionCount   *int   `json:"question_count,omitempty"`
+	HistoryAnalysis *bool  `json:"history_analysis,omitempty"`
+	Depth           string `json:"depth,omitempty"`
+}
+
+// over returns s with the settings made in o replacing its own.
+func (s ProfileSettings) over(o ProfileSettings) ProfileSettings {
+	if o.Model != "" {
+		s.Model = o.Model
+	}
+	if o.QuestionCount != nil {
+		s.QuestionCount = o.QuestionCount
+	}
+	if o.HistoryAnalysis != nil {
+		s.HistoryAnalysis = o.HistoryAnalysis
+	}
+	if o.Depth != "" {
+		s.Depth = o.Depth
+	}
+	return s
+}
+
+// builtinProfile returns the settings of a built-in profile for provider.
+// Only OpenAI and OpenRouter get another model; other providers have no
+// smaller or larger model that's sure to be there.
+func builtinProfile(name string, provider ProviderType) (ProfileSettings, bool) {
+	models := map[string]map[ProviderType]string{
+		ProfileQuick:    {ProviderOpenAI: "gpt-4o-mini", ProviderOpenRouter: "openai/gpt-4o-mini"},
+		ProfileThorough: {ProviderOpenAI: "gpt-4.1", ProviderOpenRouter: "openai/gpt-4.1"},
+	}
+	none, most := 0, MaxQuestionCount
+	analyze := false
+	switch name {
+	case ProfileQuick:
+		return ProfileSettings{Model: models[name][provider], QuestionCount: &none, HistoryAnalysis: &analyze, Depth: DepthBrief}, true
+	case ProfileStandard:
+		return ProfileSettings{}, true
+	case ProfileThorough:
+		return ProfileSettings{Model: models[name][provider], QuestionCount: &most, Depth: DepthDeep}, true
+	}
+	return ProfileSettings{}, false
+}
+
+// ProfileNames returns the names of every profile: the built-in ones from
+// quickest to most thorough, then those added in Profiles.
+func (c *Config) ProfileNames() []string {
+	names := []string{ProfileQuick, ProfileStandard, ProfileThorough}
+	var added []string
+	for name := range c.Profiles {
+		if !slices.Contains(names, name) {
+			added = append(added, name)
+		}
+	}
+	slices.Sort(added)
+	return append(names, added...)
+}
+
+// WithProfile returns a copy of c with the named profile applied, or
+// Profile when name is "", and Profile set to the name applied.
+func (c *Config) WithProfile(name string) (*Config, error) {
+	if name == "" {
+		name = c.Profile
+	}
+	if name == "" {
+		name = ProfileStandard
+	}
+	settings, ok := builtinProfile(name, c.Provider)
+	if added, ok2 := c.Profiles[name]; ok2 {
+		settings, ok = settings.over(added), true
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown profile %q; use one of %s", name, strings.Join(c.ProfileNames(), ", "))
+	}
+
+	out := *c
+	out.Profile = name
+	if settings.Model != "" {
+		out.setModel(settings.Model)
+	}
+	if settings.QuestionCount != nil {
+		out.QuestionCount = settings.QuestionCount
+	}
+	if settings.HistoryAnalysis != nil {
+		out.HistoryAnalysis = settings.HistoryAnalysis
+	}
+	if settings.Depth != "" {
+		out.Depth = settings.Depth
+	}
+	return &out, nil
+}
+
+// setModel sets the model requests to the configured provider are sent to.
+func (c *Config) setModel(model string) {
+	switch c.Provider {
+	case ProviderOllama:
+		c.OllamaModel = model
+	case ProviderAzure:
+		c.AzureDeployment = model
+	case ProviderOpenRouter:
+		c.OpenRouterModel = model
+	case ProviderCustom:
+		c.CustomModel = model
+	case ProviderMock:
+		// The mock answers the same whatever the model.
+	default:
+		c.OpenAIModel = model
+	}
+}
+
+// GenerationOptions tune how a model writes its reply. Unset fields leave
+// the provider's defaults in place, and options a model doesn't take, like
+// a temperature for reasoning models, are left out of its requests.
+type GenerationOptions struct {
+	// Temperature is the sampling temperature, usually from 0 to 2.
+	Temperature *float64 `json:"temperature,omitempty"`
+	// MaxTokens caps the tokens of the reply, including any reasoning.
+	MaxTokens int `json:"max_tokens,omitempty"`
+	// ReasoningEffort is how hard reasoning models think: "minimal",
+	// "low", "medium", or "high".
+	ReasoningEffort string `json:"reasoning_effort,omitempty"`
+}
+
+// ReasoningEfforts are the valid values of ReasoningEffort.
+var ReasoningEfforts = []string{"minimal", "low", "medium", "high"}
+
+// Generation holds the generation options of each stage. A stage's options
+// apply over Default, which also covers requests outside the three stages,
+// like scoring and summaries.
+type Generation struct {
+	Default   GenerationOptions `json:"default,omitzero"`
+	History   GenerationOptions `json:"history,omitzero"`
+	Questions GenerationOptions `json:"questions,omitzero"`
+	Message   GenerationOptions `json:"message,omitzero"`
+}
+
+// Over returns Default with the options set in stage replacing its own.
+func (g Generation) Over(stage GenerationOptions) GenerationOptions {
+	o := g.Default
+	if stage.Temperature != nil {
+		o.Temperature = stage.Temperature
+	}
+	if stage.MaxTokens > 0 {
+		o.MaxTokens = stage.MaxTokens
+	}
+	if stage.ReasoningEffort != "" {
+		o.ReasoningEffort = stage.ReasoningEffort
+	}
+	return o
+}
+
+// Check reports the first invalid option.
+func (g Generation) Check() error {
+	stages := []struct {
+		name string
+		o    GenerationOptions
+	}{{"default", g.Default}, {"history", g.History}, {"questions", g.Questions}, {"message", g.Message}}
+	for _, stage := range stages {
+		name, o := stage.name, stage.o
+		if o.ReasoningEffort != "" && !slices.Contains(ReasoningEfforts, o.ReasoningEffort) {
+			return fmt.Errorf("unknown generation.%s.reasoning_effort %q; use one of %s", name, o.ReasoningEffort, strings.Join(ReasoningEfforts, ", "))
+		}
+		if o.MaxTokens < 0 {
+			return fmt.Errorf("generation.%s.max_tokens must not be negative", name)
+		}
+	}
+	return nil
+}
+
+// DefaultMaxRetries is how often failed requests are retried by default.
+const DefaultMaxRetries = 4
+
+// Retries returns how often a failed request is retried.
+func (c *Config) Retries() int {
+	if c.MaxRetries == nil {
+		return DefaultMaxRetries
+	}
+	return max(*c.MaxRetries, 0)
+}
+
+// DefaultRequestTimeout is how long a provider request may take by default.
+const DefaultRequestTimeout = 2 * time.Minute
+
+// Timeout returns how long a provider request may take.
+func (c *Config) Timeout() time.Duration {
+	if c.RequestTimeout <= 0 {
+		return DefaultRequestTimeout
+	}
+	return time.Duration(c.RequestTimeout) * time.Second
+}
+
+// GitmojiTable returns the emoji for each type, with the configured ones
+// applied on top of the defaults.
+func (c *Config) GitmojiTable() map[string]string {
+	table := maps.Clone(conventional.DefaultGitmoji)
+	maps.Copy(table, c.Gitmoji)
+	return table
+}
+
+// Style examples.
+const (
+	MaxStyleExamples            = 5
+	DefaultEmbeddingModel       = "text-embedding-3-small"
+	DefaultOllamaEmbeddingModel = "nomic-embed-text"
+)
+
+// Examples returns how many style examples to send, or 0 for none.
+func (c *Config) Examples() int {
+	return min(max(c.StyleExamples, 0), MaxStyleExamples)
+}
+
+// Embedder returns the name of the model style examples and related history
+// are selected with.
+func (c *Config) Embedder() string {
+	switch {
+	case c.EmbeddingModel != "":
+		return c.EmbeddingModel
+	case c.Provider == ProviderOllama:
+		return DefaultOllamaEmbeddingModel
+	default:
+		return DefaultEmbeddingModel
+	}
+}
+
+// Model returns the name of the model requests are sent to. For Azure it's
+// the deployment, which is often named after its model.
+func (c *Config) Model() string {
+	switch c.Provider {
+	case ProviderOllama:
+		return c.OllamaModel
+	case ProviderAzure:
+		return c.AzureDeployment
+	case ProviderOpenRouter:
+		if c.OpenRouterModel == "" {
+			return DefaultOpenRouterModel
+		}
+		return c.OpenRouterModel
+	case ProviderCustom:
+		return c.CustomModel
+	case ProviderMock:
+		return "mock"
+	default:
+		if c.OpenAIModel != "" {
+			return c.OpenAIModel
+		}
+		return DefaultOpenAIModel
+	}
+}
+
+// Dir returns the smartcommit config directory, creating it if needed.
+func Dir() (string, error) {
 	home, err := os.UserHomeDir()
 	if err != nil {
 		return "", err
@@ -29,9 +870,91 @@ func getConfigPath() (string, error) {
 	if err := os.MkdirAll(configDir, 0755); err != nil {
 		return "", err
 	}
+	return configDir, nil
+}
+
+// DataDir returns the directory for local state such as the session store,
+// honoring $XDG_DATA_HOME and creating it if needed.
+func DataDir() (string, error) {
+	base := os.Getenv("XDG_DATA_HOME")
+	if base == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		base = filepath.Join(home, ".local", "share")
+	}
+	dataDir := filepath.Join(base, "smartcommit")
+	if err := os.MkdirAll(dataDir, 0700); err != nil {
+		return "", err
+	}
+	return dataDir, nil
+}
+
+// Path returns where the config file is, whether or not it exists yet.
+func Path() (string, error) {
+	return getConfigPath()
+}
+
+func getConfigPath() (string, error) {
+	configDir, err := Dir()
+	if err != nil {
+		return "", err
+	}
 	return filepath.Join(configDir, "config.json"), nil
 }
 
+// Exists reports whether a config file has been saved, i.e. whether this
+// isn't the first run.
+func Exists() bool {
+	configPath, err := getConfigPath()
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(configPath)
+	return err == nil
+}
+
+// LogLevel returns the log_level saved in the config, without loading API
+// keys, so logging can start before anything else runs.
+func LogLevel() string {
+	configPath, err := getConfigPath()
+	if err != nil {
+		return ""
+	}
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return ""
+	}
+	var cfg struct {
+		LogLevel string `json:"log_level"`
+	}
+	json.Unmarshal(data, &cfg) // Ignore error, Load reports it
+	return cfg.LogLevel
+}
+
+// GitBackend returns the git_backend saved in the config, or set in the
+// environment, without loading the rest, so it can be picked before any
+// command runs.
+func GitBackend() string {
+	if value := os.Getenv(EnvName("git_backend")); value != "" {
+		return value
+	}
+	configPath, err := getConfigPath()
+	if err != nil {
+		return ""
+	}
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return ""
+	}
+	var cfg struct {
+		GitBackend string `json:"git_backend"`
+	}
+	json.Unmarshal(data, &cfg) // Ignore error, Load reports it
+	return cfg.GitBackend
+}
+
 func Load() (*Config, error) {
 	configPath, err := getConfigPath()
 	if err != nil {
@@ -44,34 +967,407 @@ func Load() (*Config, error) {
 		if err != nil {
 			return nil, err
 		}
+		migrated, changed, err := migrate(data)
+		if err != nil {
+			logging.Error("config unreadable", "path", configPath, "err", err)
+			return nil, err
+		}
 		var cfg Config
-		if err := json.Unmarshal(data, &cfg); err != nil {
+		if err := json.Unmarshal(migrated, &cfg); err != nil {
+			logging.Error("config unreadable", "path", configPath, "err", err)
 			return nil, err
 		}
+		logging.Debug("config loaded", "path", configPath, "provider", cfg.Provider)
+		if changed && !cfg.PlaintextKeys {
+			// Files from before version 2 don't list the keys in the
+			// keychain, so they're all read once for Save to list them.
+			cfg.loadKeys(slices.Sorted(maps.Keys(cfg.keys()))...)
+		}
+		if changed {
+			// Keep the original in case the migration lost something.
+			if err := os.WriteFile(configPath+".bak", data, 0600); err == nil {
+				cfg.Save() // Ignore error, not critical
+			}
+		}
+		if !cfg.PlaintextKeys {
+			if cfg.OpenAIAPIKey != "" || cfg.AzureAPIKey != "" || cfg.OpenRouterAPIKey != "" || cfg.CustomAPIKey != "" {
+				// Move keys saved by older versions into the keychain.
+				cfg.Save() // Ignore error, not critical
+			}
+		}
+		if err := cfg.applyEnv(); err != nil {
+			return nil, err
+		}
+		if !cfg.PlaintextKeys {
+			cfg.loadKeys(cfg.storedKeys(cfg.Provider)...)
+		}
 		return &cfg, nil
 	}
 
 	// Fallback to defaults / env vars for backward compatibility or first run
+	logging.Debug("no config, using defaults", "path", configPath)
 	cfg := &Config{
 		Provider:     ProviderOpenAI,
 		OpenAIAPIKey: os.Getenv("OPENAI_API_KEY"),
 		OllamaModel:  "llama3",
 		OllamaURL:    "http://localhost:11434",
 	}
-
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
 	return cfg, nil
 }
 
+// Keychain entries API keys are stored under.
+const (
+	openAIKeyName     = "openai_api_key"
+	azureKeyName      = "azure_api_key"
+	openRouterKeyName = "openrouter_api_key"
+	customKeyName     = "custom_api_key"
+)
+
+// keys returns the API keys in c by the keychain entry they're stored under.
+func (c *Config) keys() map[string]*string {
+	return map[string]*string{
+		openAIKeyName:     &c.OpenAIAPIKey,
+		azureKeyName:      &c.AzureAPIKey,
+		openRouterKeyName: &c.OpenRouterAPIKey,
+		customKeyName:     &c.CustomAPIKey,
+	}
+}
+
+// ErrKeychain is returned by Save when API keys can't be stored in the OS
+// keychain.
+var ErrKeychain = errors.New("API keys couldn't be saved to the keychain")
+
+// keyNames returns the keychain entries of the API keys provider p uses.
+func keyNames(p ProviderType) []string {
+	switch p {
+	case ProviderOpenAI, "":
+		// Configs from before providers were chosen are for OpenAI.
+		return []string{openAIKeyName}
+	case ProviderAzure:
+		return []string{azureKeyName}
+	case ProviderOpenRouter:
+		return []string{openRouterKeyName}
+	case ProviderCustom:
+		return []string{customKeyName}
+	}
+	return nil
+}
+
+// storedKeys returns the API keys provider p uses that Keychain lists.
+func (c *Config) storedKeys(p ProviderType) []string {
+	var names []string
+	for _, name := range keyNames(p) {
+		if slices.Contains(c.Keychain, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// LoadAllKeys fills in every API key Keychain lists, for showing and
+// changing settings; a run only reads those its provider uses.
+func (c *Config) LoadAllKeys() {
+	if !c.PlaintextKeys {
+		c.loadKeys(c.Keychain...)
+	}
+}
+
+// loadKeys fills in the named API keys missing from the file from the OS
+// keychain. A keychain that can't be read is logged and treated as holding
+// nothing, so the keys fall back to the environment or setup asks for them.
+func (c *Config) loadKeys(names ...string) {
+	keys := c.keys()
+	names = slices.DeleteFunc(slices.Clone(names), func(name string) bool {
+		key, ok := keys[name]
+		return !ok || *key != ""
+	})
+	if len(names) == 0 {
+		return
+	}
+	store, err := secret.Keyring()
+	if errors.Is(err, secret.ErrUnsupported) {
+		// Nothing can have been stored.
+		return
+	}
+	if err != nil {
+		logging.Warn("keychain unavailable", "err", err)
+		return
+	}
+	for _, name := range names {
+		value, err := store.Get(name)
+		if err != nil {
+			if !errors.Is(err, secret.ErrNotFound) {
+				logging.Warn("failed to read API key from the keychain", "key", name, "err", err)
+			}
+			continue
+		}
+		*keys[name] = value
+	}
+}
+
+// storeKeys saves the API keys in c to the OS keychain.
+func (c *Config) storeKeys() error {
+	if !slices.ContainsFunc(slices.Collect(maps.Values(c.keys())), func(key *string) bool { return *key != "" }) {
+		// Nothing to store, so no keychain needed.
+		return nil
+	}
+	store, err := secret.Keyring()
+	if errors.Is(err, secret.ErrUnsupported) {
+		return fmt.Errorf(`%w: %w; set "plaintext_keys": true in the config file to keep them there instead`, ErrKeychain, err)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrKeychain, err)
+	}
+	for name, key := range c.keys() {
+		if *key == "" {
+			continue
+		}
+		if err := store.Set(name, *key); err != nil {
+			return fmt.Errorf("%w: %w", ErrKeychain, err)
+		}
+		if !slices.Contains(c.Keychain, name) {
+			c.Keychain = append(c.Keychain, name)
+		}
+	}
+	slices.Sort(c.Keychain)
+	return nil
+}
+
+// Save writes the config file. Unless PlaintextKeys is set, API keys go to
+// the OS keychain and are left out of the file. Settings overridden from
+// the environment are saved as the file had them, unless changed since.
 func (c *Config) Save() error {
 	configPath, err := getConfigPath()
 	if err != nil {
 		return err
 	}
 
+	out := c.withoutEnv()
+	if out.Version < CurrentVersion {
+		out.Version = CurrentVersion
+	}
+	if !out.PlaintextKeys {
+		if err := out.storeKeys(); err != nil {
+			return err
+		}
+		c.Keychain = out.Keychain
+		out.OpenAIAPIKey = ""
+		out.AzureAPIKey = ""
+		out.OpenRouterAPIKey = ""
+		out.CustomAPIKey = ""
+	}
+
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(configPath, data, 0600); err != nil {
+		return err
+	}
+	// WriteFile keeps the mode of an existing file, which older versions
+	// created world-readable.
+	return os.Chmod(configPath, 0600)
+}
+
+// RepoConfigFile is the name of the per-repository config file, stored at the repo root.
+const RepoConfigFile = ".smartcommit.json"
+
+// RepoConfigDirFile is where the repo config lives for projects that keep
+// it in a .smartcommit directory instead.
+var RepoConfigDirFile = filepath.Join(".smartcommit", "config.json")
+
+// RepoConfig holds settings that travel with a repository. Every field but
+// Exclude overrides the global config when set, so a project's conventions
+// apply to everyone who works on it. Credentials are deliberately absent:
+// they never belong in a repository. So is the choice of provider and the
+// hosts requests go to, which the user's credentials are sent with: a repo
+// can't have them sent anywhere the user didn't pick.
+type RepoConfig struct {
+	// Exclude lists paths whose diff content is never sent to the provider.
+	Exclude []string `json:"exclude,omitempty"`
+
+	OpenAIModel            string `json:"openai_model,omitempty"`
+	OllamaModel            string `json:"ollama_model,omitempty"`
+	OllamaStructuredOutput *bool  `json:"ollama_structured_output,omitempty"`
+	AzureDeployment        string `json:"azure_deployment,omitempty"`
+	AzureAPIVersion        string `json:"azure_api_version,omitempty"`
+	OpenRouterModel        string `json:"openrouter_model,omitempty"`
+	CustomModel            string `json:"custom_model,omitempty"`
+
+	SensitivePaths   []string          `json:"sensitive_paths,omitempty"`
+	IgnorePaths      []string          `json:"ignore_paths,omitempty"`
+	Ticket           *Ticket           `json:"ticket,omitempty"`
+	SignOff          *bool             `json:"sign_off,omitempty"`
+	SignCommits      *bool             `json:"sign_commits,omitempty"`
+	GitHub           *GitHub           `json:"github,omitempty"`
+	GitLab           *GitLab           `json:"gitlab,omitempty"`
+	Diff             *DiffOptions      `json:"diff,omitempty"`
+	DiffFilters      []string          `json:"diff_filters,omitempty"`
+	VagueWords       []string          `json:"vague_words,omitempty"`
+	APIChangesInBody *bool             `json:"api_changes_in_body,omitempty"`
+	PrivacyReview    *bool             `json:"privacy_review,omitempty"`
+	Provenance       *bool             `json:"provenance,omitempty"`
+	Conventions      *Conventions      `json:"conventions,omitempty"`
+	MessageStyle     string            `json:"message_style,omitempty"`
+	Gitmoji          map[string]string `json:"gitmoji,omitempty"`
+	MessageTemplate  string            `json:"message_template,omitempty"`
+	Language         string            `json:"language,omitempty"`
+	StyleExamples    *int              `json:"style_examples,omitempty"`
+	RelatedHistory   *bool             `json:"related_history,omitempty"`
+}
+
+// RepoConfigPath returns the repo config file under root: RepoConfigFile,
+// or RepoConfigDirFile if only that exists.
+func RepoConfigPath(root string) string {
+	path := filepath.Join(root, RepoConfigFile)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if dirPath := filepath.Join(root, RepoConfigDirFile); fileExists(dirPath) {
+			return dirPath
+		}
+	}
+	return path
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// untrustedRepoKeys are the settings a repo config can't make, which older
+// versions let it: they choose where the user's credentials are sent.
+var untrustedRepoKeys = []string{"provider", "ollama_url", "azure_endpoint", "custom_url"}
+
+// LoadRepo reads the repo config from root, returning an empty config if none exists.
+func LoadRepo(root string) (*RepoConfig, error) {
+	path := RepoConfigPath(root)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &RepoConfig{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cfg RepoConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		logging.Error("repo config unreadable", "path", path, "err", err)
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	var file map[string]json.RawMessage
+	if json.Unmarshal(data, &file) == nil {
+		for _, key := range untrustedRepoKeys {
+			if _, ok := file[key]; ok {
+				logging.Warn("repo config setting ignored; set it in your own config", "path", path, "key", key)
+			}
+		}
+	}
+	logging.Debug("repo config loaded", "path", path)
+	return &cfg, nil
+}
+
+// Save writes the repo config to root.
+func (c *RepoConfig) Save(root string) error {
 	data, err := json.MarshalIndent(c, "", "  ")
 	if err != nil {
 		return err
 	}
+	return os.WriteFile(RepoConfigPath(root), append(data, '\n'), 0644)
+}
 
-	return os.WriteFile(configPath, data, 0644)
+// WithRepo returns a copy of c with the overrides in r applied. c itself is
+// left alone so that saving it never writes repo settings to the global config.
+func (c *Config) WithRepo(r *RepoConfig) *Config {
+	out := *c
+	if r == nil {
+		return &out
+	}
+	if r.OpenAIModel != "" {
+		out.OpenAIModel = r.OpenAIModel
+	}
+	if r.OllamaModel != "" {
+		out.OllamaModel = r.OllamaModel
+	}
+	if r.OllamaStructuredOutput != nil {
+		out.OllamaStructuredOutput = r.OllamaStructuredOutput
+	}
+	if r.AzureDeployment != "" {
+		out.AzureDeployment = r.AzureDeployment
+	}
+	if r.AzureAPIVersion != "" {
+		out.AzureAPIVersion = r.AzureAPIVersion
+	}
+	if r.OpenRouterModel != "" {
+		out.OpenRouterModel = r.OpenRouterModel
+	}
+	if r.CustomModel != "" {
+		out.CustomModel = r.CustomModel
+	}
+	if r.SensitivePaths != nil {
+		out.SensitivePaths = r.SensitivePaths
+	}
+	if r.IgnorePaths != nil {
+		out.IgnorePaths = r.IgnorePaths
+	}
+	if r.Ticket != nil {
+		out.Ticket = *r.Ticket
+	}
+	if r.SignOff != nil {
+		out.SignOff = *r.SignOff
+	}
+	if r.SignCommits != nil {
+		out.SignCommits = r.SignCommits
+	}
+	if r.GitHub != nil {
+		// The token goes to the API, so its URL stays the user's.
+		out.GitHub = *r.GitHub
+		out.GitHub.APIURL = c.GitHub.APIURL
+	}
+	if r.GitLab != nil {
+		out.GitLab = *r.GitLab
+		out.GitLab.APIURL = c.GitLab.APIURL
+	}
+	if r.Diff != nil {
+		out.Diff = *r.Diff
+	}
+	if r.DiffFilters != nil {
+		out.DiffFilters = r.DiffFilters
+	}
+	if r.VagueWords != nil {
+		out.VagueWords = r.VagueWords
+	}
+	if r.APIChangesInBody != nil {
+		out.APIChangesInBody = *r.APIChangesInBody
+	}
+	if r.PrivacyReview != nil {
+		out.PrivacyReview = *r.PrivacyReview
+	}
+	if r.Provenance != nil {
+		out.Provenance = *r.Provenance
+	}
+	if r.Conventions != nil {
+		out.Conventions = *r.Conventions
+	}
+	if r.MessageStyle != "" {
+		out.MessageStyle = r.MessageStyle
+	}
+	if r.Gitmoji != nil {
+		out.Gitmoji = r.Gitmoji
+	}
+	if r.MessageTemplate != "" {
+		out.MessageTemplate = r.MessageTemplate
+	}
+	if r.Language != "" {
+		out.Language = r.Language
+	}
+	if r.StyleExamples != nil {
+		out.StyleExamples = *r.StyleExamples
+	}
+	if r.RelatedHistory != nil {
+		out.RelatedHistory = *r.RelatedHistory
+	}
+	out.reapplyEnv()
+	return &out
 }
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/arpxspace/smartcommit/internal/logging"
+)
+
+// EnvPrefix starts the names of the environment variables that override
+// settings, so CI jobs and one-off runs can change them without touching
+// the config file: SMARTCOMMIT_PROVIDER for "provider",
+// SMARTCOMMIT_DIFF_CONTEXT_LINES for "diff.context_lines", and so on. Values
+// are written as for `smartcommit config set`.
+const EnvPrefix = "SMARTCOMMIT_"
+
+// EnvModel overrides the model of whichever provider is configured.
+const EnvModel = EnvPrefix + "MODEL"
+
+// EnvName returns the environment variable that overrides the setting key.
+func EnvName(key string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// envOverride is a setting overridden from the environment: the value the
+// file had, and the one the environment gave it.
+type envOverride struct {
+	saved, value any
+}
+
+// applyEnv overrides settings with the environment variables set for
+// them. The provider goes first, so SMARTCOMMIT_MODEL picks the model of
+// the provider chosen.
+func (c *Config) applyEnv() error {
+	keys := Names(c)
+	for i, key := range keys {
+		if key == "provider" {
+			keys[0], keys[i] = keys[i], keys[0]
+		}
+	}
+	for _, key := range keys {
+		if value := os.Getenv(EnvName(key)); value != "" {
+			if err := c.override(key, value); err != nil {
+				return fmt.Errorf("%s: %w", EnvName(key), err)
+			}
+		}
+	}
+	if model := os.Getenv(EnvModel); model != "" {
+		if key := c.modelKey(); key != "" {
+			if err := c.override(key, model); err != nil {
+				return fmt.Errorf("%s: %w", EnvModel, err)
+			}
+		}
+	}
+	return nil
+}
+
+// override sets key to value, remembering what it was.
+func (c *Config) override(key, value string) error {
+	saved, err := Get(c, key)
+	if err != nil {
+		return err
+	}
+	if err := Set(c, key, value); err != nil {
+		return err
+	}
+	set, _ := Get(c, key)
+	if c.env == nil {
+		c.env = make(map[string]envOverride)
+	}
+	if o, ok := c.env[key]; ok {
+		saved = o.saved
+	}
+	c.env[key] = envOverride{saved: saved, value: set}
+	logging.Debug("setting from environment", "key", key)
+	return nil
+}
+
+// FromEnv returns the environment variable key was overridden by, if it
+// was.
+func (c *Config) FromEnv(key string) (string, bool) {
+	if _, ok := c.env[key]; !ok {
+		return "", false
+	}
+	if key == c.modelKey() && os.Getenv(EnvName(key)) == "" {
+		return EnvModel, true
+	}
+	return EnvName(key), true
+}
+
+// reapplyEnv sets the overridden settings back to the environment's
+// values, after something else like a repo config has changed them.
+func (c *Config) reapplyEnv() {
+	for key, o := range c.env {
+		if st, err := lookup(reflect.ValueOf(c).Elem(), key, true); err == nil {
+			st.set(reflect.ValueOf(o.value))
+		}
+	}
+}
+
+// withoutEnv returns a copy of c with the settings the environment
+// overrode as the file had them, unless they've been changed since.
+func (c *Config) withoutEnv() Config {
+	out := *c
+	out.env = nil
+	for key, o := range c.env {
+		st, err := lookup(reflect.ValueOf(&out).Elem(), key, true)
+		if err != nil || !reflect.DeepEqual(st.get().Interface(), o.value) {
+			continue
+		}
+		st.set(reflect.ValueOf(o.saved))
+	}
+	return out
+}
+
+// modelKey returns the setting holding the configured provider's model.
+func (c *Config) modelKey() string {
+	switch c.Provider {
+	case ProviderOllama:
+		return "ollama_model"
+	case ProviderAzure:
+		return "azure_deployment"
+	case ProviderOpenRouter:
+		return "openrouter_model"
+	case ProviderCustom:
+		return "custom_model"
+	case ProviderMock:
+		return ""
+	default:
+		return "openai_model"
+	}
+}
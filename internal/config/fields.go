@@ -0,0 +1,309 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/arpxspace/smartcommit/internal/secret"
+)
+
+// Settings are named by their JSON keys, with nested ones joined by dots,
+// as in "provider", "diff.context", or "colors.accent". These functions
+// read and change them in a Config or RepoConfig by that name, for
+// `smartcommit config`.
+
+// Get returns the setting key of cfg, a pointer to a Config or RepoConfig.
+func Get(cfg any, key string) (any, error) {
+	st, err := lookup(reflect.ValueOf(cfg).Elem(), key, false)
+	if err != nil {
+		return nil, err
+	}
+	return st.get().Interface(), nil
+}
+
+// Set changes the setting key of cfg to value: text as it's written for a
+// string, JSON for anything else, or a comma-separated list for a list of
+// strings.
+func Set(cfg any, key, value string) error {
+	st, err := lookup(reflect.ValueOf(cfg).Elem(), key, true)
+	if err != nil {
+		return err
+	}
+	parsed := reflect.New(st.typ()).Elem()
+	target := parsed
+	if target.Kind() == reflect.Pointer {
+		// Pointers distinguish unset from the zero value; set means set.
+		target.Set(reflect.New(target.Type().Elem()))
+		target = target.Elem()
+	}
+	if err := parseValue(target, value); err != nil {
+		return fmt.Errorf("invalid value for %s: %w", key, err)
+	}
+	if err := checkValue(key, parsed.Interface()); err != nil {
+		return err
+	}
+	st.set(parsed)
+	return nil
+}
+
+// Unset returns the setting key of cfg to its default.
+func Unset(cfg any, key string) error {
+	st, err := lookup(reflect.ValueOf(cfg).Elem(), key, true)
+	if err != nil {
+		return err
+	}
+	st.set(reflect.Zero(st.typ()))
+	return nil
+}
+
+// Keys returns the settings that are set in cfg, sorted, with their
+// values; empty text counts as unset. Objects are listed setting by setting; lists and other values
+// whole.
+func Keys(cfg any) ([]string, map[string]any, error) {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	var tree map[string]any
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil, nil, err
+	}
+	values := make(map[string]any)
+	var flatten func(prefix string, m map[string]any)
+	flatten = func(prefix string, m map[string]any) {
+		for k, v := range m {
+			if sub, ok := v.(map[string]any); ok {
+				flatten(prefix+k+".", sub)
+				continue
+			}
+			if v != nil && v != "" {
+				values[prefix+k] = v
+			}
+		}
+	}
+	flatten("", tree)
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys, values, nil
+}
+
+// Names returns the name of every setting cfg can hold, sorted, whether
+// it's set or not. Maps and lists are named whole.
+func Names(cfg any) []string {
+	var names []string
+	var walk func(prefix string, t reflect.Type)
+	walk = func(prefix string, t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			tag, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+			if tag == "" || tag == "-" {
+				continue
+			}
+			ft := t.Field(i).Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct && ft.NumField() > 0 && ft.PkgPath() == t.PkgPath() {
+				walk(prefix+tag+".", ft)
+				continue
+			}
+			names = append(names, prefix+tag)
+		}
+	}
+	walk("", reflect.TypeOf(cfg).Elem())
+	sort.Strings(names)
+	return names
+}
+
+// IsAPIKey reports whether key is a setting holding an API key, which is
+// kept in the OS keychain rather than the file unless plaintext_keys is set.
+func IsAPIKey(key string) bool {
+	_, ok := (&Config{}).keys()[key]
+	return ok
+}
+
+// ForgetKey removes the API key setting key from the OS keychain, as Save
+// only ever adds keys to it.
+func (c *Config) ForgetKey(key string) error {
+	if c.PlaintextKeys || !IsAPIKey(key) {
+		return nil
+	}
+	keyring, err := secret.Keyring()
+	if err != nil {
+		return err
+	}
+	if err := keyring.Delete(key); err != nil && !errors.Is(err, secret.ErrNotFound) {
+		return err
+	}
+	c.Keychain = slices.DeleteFunc(c.Keychain, func(name string) bool { return name == key })
+	return nil
+}
+
+// setting is a setting lookup found: a field, or an entry of a map field
+// like "colors.accent", which can't be set in place.
+type setting struct {
+	field reflect.Value
+	// m and key are the map and key of an entry.
+	m   reflect.Value
+	key string
+}
+
+func (s setting) typ() reflect.Type {
+	if s.m.IsValid() {
+		return s.m.Type().Elem()
+	}
+	return s.field.Type()
+}
+
+func (s setting) get() reflect.Value {
+	if !s.m.IsValid() {
+		return s.field
+	}
+	if !s.m.IsNil() {
+		if v := s.m.MapIndex(reflect.ValueOf(s.key)); v.IsValid() {
+			return v
+		}
+	}
+	return reflect.Zero(s.typ())
+}
+
+// set changes the setting to v; a map entry set to the zero value is
+// removed.
+func (s setting) set(v reflect.Value) {
+	if !s.m.IsValid() {
+		s.field.Set(v)
+		return
+	}
+	if v.IsZero() {
+		if !s.m.IsNil() {
+			s.m.SetMapIndex(reflect.ValueOf(s.key), reflect.Value{})
+		}
+		return
+	}
+	if s.m.IsNil() {
+		s.m.Set(reflect.MakeMap(s.m.Type()))
+	}
+	s.m.SetMapIndex(reflect.ValueOf(s.key), v)
+}
+
+// lookup finds the setting named by key in v. Nil pointers to objects on
+// the way are allocated when create is set, so the setting can be changed.
+func lookup(v reflect.Value, key string, create bool) (setting, error) {
+	parts := strings.Split(key, ".")
+	for i, part := range parts {
+		for v.Kind() == reflect.Pointer && v.Type().Elem().Kind() == reflect.Struct {
+			if v.IsNil() {
+				if !create {
+					v = reflect.New(v.Type().Elem()).Elem()
+					continue
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		switch v.Kind() {
+		case reflect.Struct:
+			field, ok := fieldByJSONName(v, part)
+			if !ok {
+				return setting{}, unknownKey(strings.Join(parts[:i+1], "."))
+			}
+			v = field
+		case reflect.Map:
+			if v.Type().Key().Kind() != reflect.String {
+				return setting{}, unknownKey(key)
+			}
+			if i != len(parts)-1 {
+				// Entries of maps of objects, like a profile's settings,
+				// are set whole.
+				return setting{}, fmt.Errorf("set %s as a whole, as JSON", strings.Join(parts[:i+1], "."))
+			}
+			return setting{m: v, key: part}, nil
+		default:
+			return setting{}, unknownKey(key)
+		}
+	}
+	return setting{field: v}, nil
+}
+
+// fieldByJSONName returns the field of struct v whose JSON name is name.
+func fieldByJSONName(v reflect.Value, name string) (reflect.Value, bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		tag, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if tag == name && tag != "-" {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+func unknownKey(key string) error {
+	return fmt.Errorf("unknown setting %q; see `smartcommit config list --all`", key)
+}
+
+// parseValue reads text into v by its type.
+func parseValue(v reflect.Value, text string) error {
+	switch {
+	case v.Kind() == reflect.String:
+		v.SetString(text)
+		return nil
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String && !strings.HasPrefix(strings.TrimSpace(text), "["):
+		var items []string
+		for _, item := range strings.Split(text, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		list := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			list.Index(i).SetString(item)
+		}
+		v.Set(list)
+		return nil
+	}
+	dec := json.NewDecoder(strings.NewReader(text))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v.Addr().Interface())
+}
+
+// checkValue rejects values a setting can't take, where that's known up
+// front.
+func checkValue(key string, value any) error {
+	switch key {
+	case "ollama_url", "azure_endpoint", "custom_url", "github.api_url", "gitlab.api_url":
+		if s := value.(string); s != "" {
+			if err := CheckURL(s); err != nil {
+				return fmt.Errorf("invalid value for %s: %w", key, err)
+			}
+		}
+		return nil
+	case "network.proxy":
+		if s := value.(string); s != "" {
+			if err := CheckProxy(s); err != nil {
+				return fmt.Errorf("invalid value for %s: %w", key, err)
+			}
+		}
+		return nil
+	case "git_backend":
+		if s := value.(string); s != "" && s != GitBackendExec && s != GitBackendGoGit {
+			return fmt.Errorf("unknown git_backend %q; use %s or %s", s, GitBackendExec, GitBackendGoGit)
+		}
+		return nil
+	case "provider":
+	default:
+		return nil
+	}
+	switch p := reflect.Indirect(reflect.ValueOf(value)).Interface().(ProviderType); p {
+	case ProviderOpenAI, ProviderOllama, ProviderAzure, ProviderOpenRouter, ProviderCustom, ProviderMock, "":
+		return nil
+	default:
+		return fmt.Errorf("unknown provider %q; use openai, ollama, azure, openrouter, custom, or mock", p)
+	}
+}
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/arpxspace/smartcommit/internal/logging"
+)
+
+// CurrentVersion is the version of the config file this build writes, in
+// its "version" setting. Older files are migrated when they're loaded.
+const CurrentVersion = 2
+
+// migrations upgrade a config file from each version to the next:
+// migrations[v] from version v to v+1. They work on the file's JSON rather
+// than a Config, so they can see settings that have since gone.
+var migrations = []func(map[string]json.RawMessage) error{
+	migrateProvider,
+	listKeychain,
+}
+
+// migrateProvider upgrades files from before versions were recorded, which
+// are version 0. The earliest of those predate choosing a provider, and are
+// for the provider whose settings they hold.
+func migrateProvider(file map[string]json.RawMessage) error {
+	if _, ok := file["provider"]; ok {
+		return nil
+	}
+	for _, p := range []struct {
+		key      string
+		provider ProviderType
+	}{
+		{"openai_api_key", ProviderOpenAI},
+		{"azure_endpoint", ProviderAzure},
+		{"openrouter_api_key", ProviderOpenRouter},
+		{"custom_url", ProviderCustom},
+	} {
+		var value string
+		if json.Unmarshal(file[p.key], &value) == nil && value != "" {
+			file["provider"], _ = json.Marshal(p.provider)
+			return nil
+		}
+	}
+	// Setup asks for one.
+	return nil
+}
+
+// listKeychain upgrades version 1 files, which don't list the API keys in
+// the keychain. The file itself doesn't change: Load reads every key once,
+// and Save lists those it finds.
+func listKeychain(map[string]json.RawMessage) error {
+	return nil
+}
+
+// migrate upgrades the config file data to CurrentVersion, reporting
+// whether it had to. A file from a newer version is read as it is, as far
+// as this build understands it.
+func migrate(data []byte) ([]byte, bool, error) {
+	var file map[string]json.RawMessage
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, false, err
+	}
+	var version int
+	if v, ok := file["version"]; ok {
+		if err := json.Unmarshal(v, &version); err != nil {
+			return nil, false, fmt.Errorf("version: %w", err)
+		}
+	}
+	if version > CurrentVersion {
+		logging.Warn("config from a newer version", "version", version, "understood", CurrentVersion)
+		return data, false, nil
+	}
+	if version == CurrentVersion {
+		return data, false, nil
+	}
+	for v := version; v < CurrentVersion; v++ {
+		if err := migrations[v](file); err != nil {
+			return nil, false, fmt.Errorf("migrating config from version %d: %w", v, err)
+		}
+	}
+	file["version"], _ = json.Marshal(CurrentVersion)
+	logging.Info("config migrated", "from", version, "to", CurrentVersion)
+	data, err := json.Marshal(file)
+	return data, true, err
+}
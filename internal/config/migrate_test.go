@@ -0,0 +1,60 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestMigrate(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		want     string
+		migrated bool
+		ok       bool
+	}{
+		{"unversioned OpenAI", `{"openai_api_key": "sk"}`, `{"openai_api_key": "sk", "provider": "openai", "version": 2}`, true, true},
+		{"unversioned Azure", `{"azure_endpoint": "https://x"}`, `{"azure_endpoint": "https://x", "provider": "azure", "version": 2}`, true, true},
+		{"unversioned OpenRouter", `{"openrouter_api_key": "sk"}`, `{"openrouter_api_key": "sk", "provider": "openrouter", "version": 2}`, true, true},
+		{"unversioned custom", `{"custom_url": "http://x"}`, `{"custom_url": "http://x", "provider": "custom", "version": 2}`, true, true},
+		{"empty key is skipped", `{"openai_api_key": "", "azure_endpoint": "https://x"}`, `{"openai_api_key": "", "azure_endpoint": "https://x", "provider": "azure", "version": 2}`, true, true},
+		{"unversioned provider kept", `{"provider": "ollama", "openai_api_key": "sk"}`, `{"provider": "ollama", "openai_api_key": "sk", "version": 2}`, true, true},
+		{"unversioned without provider", `{}`, `{"version": 2}`, true, true},
+		{"version 1", `{"version": 1, "provider": "openai"}`, `{"version": 2, "provider": "openai"}`, true, true},
+		{"current", `{"version": 2, "provider": "openai"}`, `{"version": 2, "provider": "openai"}`, false, true},
+		{"newer", `{"version": 3, "provider": "openai", "future": true}`, `{"version": 3, "provider": "openai", "future": true}`, false, true},
+		{"invalid version", `{"version": "2"}`, "", false, false},
+		{"not JSON", `provider: openai`, "", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, migrated, err := migrate([]byte(tt.data))
+			if (err == nil) != tt.ok {
+				t.Fatalf("migrate(%s) = %v, want ok %v", tt.data, err, tt.ok)
+			}
+			if !tt.ok {
+				return
+			}
+			if migrated != tt.migrated {
+				t.Errorf("migrate(%s) migrated = %v, want %v", tt.data, migrated, tt.migrated)
+			}
+			var gotFile, wantFile map[string]any
+			if err := json.Unmarshal(got, &gotFile); err != nil {
+				t.Fatalf("migrate(%s) = %s: %v", tt.data, got, err)
+			}
+			if err := json.Unmarshal([]byte(tt.want), &wantFile); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(gotFile, wantFile) {
+				t.Errorf("migrate(%s) = %s, want %s", tt.data, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMigrationsReachCurrentVersion(t *testing.T) {
+	if len(migrations) != CurrentVersion {
+		t.Errorf("%d migrations for CurrentVersion %d", len(migrations), CurrentVersion)
+	}
+}
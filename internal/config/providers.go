@@ -0,0 +1,83 @@
+package config
+
+import (
+	"fmt"
+	"os"
+)
+
+// Providers are the providers setup offers, in its order. The settings of
+// each are kept side by side, so setting up one leaves the others as they
+// were.
+var Providers = []ProviderType{ProviderOpenAI, ProviderOllama, ProviderOpenRouter, ProviderCustom, ProviderAzure}
+
+// keyEnv is the environment variable a provider's API key falls back to.
+var keyEnv = map[ProviderType]string{
+	ProviderOpenAI:     "OPENAI_API_KEY",
+	ProviderAzure:      "AZURE_OPENAI_API_KEY",
+	ProviderOpenRouter: "OPENROUTER_API_KEY",
+}
+
+// apiKey returns the setting holding p's API key, or nil for a provider
+// without one.
+func (c *Config) apiKey(p ProviderType) *string {
+	switch p {
+	case ProviderOpenAI:
+		return &c.OpenAIAPIKey
+	case ProviderAzure:
+		return &c.AzureAPIKey
+	case ProviderOpenRouter:
+		return &c.OpenRouterAPIKey
+	}
+	return nil
+}
+
+// Configured reports whether c holds what provider p needs: valid
+// settings and, for providers that require one, an API key in the config,
+// the keychain, or the environment.
+func (c *Config) Configured(p ProviderType) bool {
+	out := *c
+	out.Provider = p
+	if out.Validate() != nil {
+		return false
+	}
+	if key := c.apiKey(p); key != nil && *key == "" && len(c.storedKeys(p)) == 0 && os.Getenv(keyEnv[p]) == "" {
+		return false
+	}
+	return true
+}
+
+// ConfiguredProviders returns the providers a run can switch among: those
+// c holds the settings for, with its own provider always among them.
+func (c *Config) ConfiguredProviders() []ProviderType {
+	var out []ProviderType
+	for _, p := range Providers {
+		if p == c.Provider || c.Configured(p) {
+			out = append(out, p)
+		}
+	}
+	if c.Provider == ProviderMock {
+		out = append(out, ProviderMock)
+	}
+	return out
+}
+
+// WithProvider returns a copy of c using provider p, for one run, with
+// its API key read from the keychain, or taken from the environment if
+// neither has one. An empty p leaves the configured provider.
+func (c *Config) WithProvider(p ProviderType) (*Config, error) {
+	out := *c
+	if p == "" || p == c.Provider {
+		return &out, nil
+	}
+	if !c.Configured(p) {
+		return nil, fmt.Errorf("provider %s isn't set up; set it up from the welcome screen or with `smartcommit config set`", p)
+	}
+	out.Provider = p
+	if !out.PlaintextKeys {
+		out.loadKeys(out.storedKeys(p)...)
+	}
+	if key := out.apiKey(p); key != nil && *key == "" {
+		*key = os.Getenv(keyEnv[p])
+	}
+	return &out, nil
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWithRepoKeepsHosts(t *testing.T) {
+	user := &Config{
+		Provider:      ProviderAzure,
+		OllamaURL:     "http://localhost:11434",
+		AzureEndpoint: "https://mine.openai.azure.com",
+		CustomURL:     "https://llm.example.com/v1",
+		GitHub:        GitHub{APIURL: "https://github.example.com/api/v3"},
+		GitLab:        GitLab{APIURL: "https://gitlab.example.com/api/v4"},
+	}
+	data := []byte(`{
+		"provider": "ollama",
+		"ollama_url": "https://attacker.example",
+		"azure_endpoint": "https://attacker.example",
+		"azure_deployment": "gpt-4o",
+		"custom_url": "https://attacker.example",
+		"github": {"issues": true, "api_url": "https://attacker.example"},
+		"gitlab": {"api_url": "https://attacker.example"}
+	}`)
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, RepoConfigFile), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	repo, err := LoadRepo(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := user.WithRepo(repo)
+	if got.Provider != user.Provider || got.OllamaURL != user.OllamaURL || got.AzureEndpoint != user.AzureEndpoint || got.CustomURL != user.CustomURL {
+		t.Errorf("repo config changed the provider or its host: %s %s %s %s", got.Provider, got.OllamaURL, got.AzureEndpoint, got.CustomURL)
+	}
+	if got.GitHub.APIURL != user.GitHub.APIURL || got.GitLab.APIURL != user.GitLab.APIURL {
+		t.Errorf("repo config changed the API URLs: %s %s", got.GitHub.APIURL, got.GitLab.APIURL)
+	}
+	if got.AzureDeployment != "gpt-4o" || !got.GitHub.Issues {
+		t.Errorf("repo config's other settings weren't applied: %q %v", got.AzureDeployment, got.GitHub.Issues)
+	}
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+)
+
+// Invalid is a setting the config can't work with as it is.
+type Invalid struct {
+	// Key names the setting, as for Get.
+	Key string
+	Err error
+}
+
+func (e *Invalid) Error() string {
+	return fmt.Sprintf("invalid %s: %v", e.Key, e.Err)
+}
+
+func (e *Invalid) Unwrap() error {
+	return e.Err
+}
+
+var errNoModel = errors.New("no model is set")
+
+// Validate returns an *Invalid for the first setting c can't work with, so
+// a mistake is caught when the config is loaded rather than when the first
+// request fails on it. Missing API keys aren't its concern, as they may
+// come from the environment.
+func (c *Config) Validate() error {
+	if c.Provider == "" {
+		return &Invalid{Key: "provider", Err: errors.New("no provider is chosen")}
+	}
+	if err := checkValue("provider", c.Provider); err != nil {
+		return &Invalid{Key: "provider", Err: err}
+	}
+	var urls, models []string
+	switch c.Provider {
+	case ProviderOllama:
+		urls, models = []string{"ollama_url"}, []string{"ollama_model"}
+	case ProviderAzure:
+		urls, models = []string{"azure_endpoint"}, []string{"azure_deployment"}
+	case ProviderCustom:
+		urls, models = []string{"custom_url"}, []string{"custom_model"}
+	}
+	for _, key := range urls {
+		value, _ := Get(c, key)
+		if err := CheckURL(value.(string)); err != nil {
+			return &Invalid{Key: key, Err: err}
+		}
+	}
+	for _, key := range models {
+		if value, _ := Get(c, key); value == "" {
+			return &Invalid{Key: key, Err: errNoModel}
+		}
+	}
+	for _, key := range []string{"github.api_url", "gitlab.api_url"} {
+		if value, _ := Get(c, key); value != "" {
+			if err := CheckURL(value.(string)); err != nil {
+				return &Invalid{Key: key, Err: err}
+			}
+		}
+	}
+	if c.Network.Proxy != "" {
+		if err := CheckProxy(c.Network.Proxy); err != nil {
+			return &Invalid{Key: "network.proxy", Err: err}
+		}
+	}
+	return nil
+}
+
+// CheckProxy returns an error unless s is the URL of a proxy: http,
+// https, or SOCKS5.
+func CheckProxy(s string) error {
+	u, err := url.Parse(s)
+	if err != nil || !slices.Contains([]string{"http", "https", "socks5", "socks5h"}, u.Scheme) || u.Host == "" {
+		return fmt.Errorf("%q isn't a proxy URL, like http://proxy.example:8080 or socks5://proxy.example:1080", s)
+	}
+	return nil
+}
+
+// CheckURL returns an error unless s is an absolute http or https URL.
+func CheckURL(s string) error {
+	if s == "" {
+		return errors.New("no URL is set")
+	}
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%q isn't an http or https URL, like http://localhost:11434", s)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package conventional
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// DefaultTypes are the commit types allowed by the Conventional Commits
+// specification and the Angular convention it grew out of.
+var DefaultTypes = []string{"feat", "fix", "docs", "style", "refactor", "perf", "test", "build", "ci", "chore", "revert"}
+
+// DefaultGitmoji maps each default type to its gitmoji, for subjects like
+// "✨ feat: add login".
+var DefaultGitmoji = map[string]string{
+	"feat":     "✨",
+	"fix":      "🐛",
+	"docs":     "📝",
+	"style":    "🎨",
+	"refactor": "♻️",
+	"perf":     "⚡️",
+	"test":     "✅",
+	"build":    "📦️",
+	"ci":       "👷",
+	"chore":    "🔧",
+	"revert":   "⏪️",
+}
+
+// Header is a parsed Conventional Commits subject line.
+type Header struct {
+	// Emoji is the gitmoji before the type, if any.
+	Emoji       string
+	Type        string
+	Scope       string
+	Breaking    bool
+	Description string
+}
+
+// headerPattern matches "type(scope)!: description", optionally after a
+// gitmoji: an emoji or a shortcode like ":sparkles:".
+var headerPattern = regexp.MustCompile(`^(?:([^\x00-\x7F]\S*|:[a-z0-9_+-]+:) )?([a-zA-Z]+)(?:\(([^()\r\n]*)\))?(!)?: (.+)$`)
+
+// Parse parses a subject line of the form "type(scope)!: description",
+// optionally led by a gitmoji, reporting false if it doesn't follow the
+// convention.
+func Parse(subject string) (Header, bool) {
+	m := headerPattern.FindStringSubmatch(strings.TrimSpace(subject))
+	if m == nil {
+		return Header{}, false
+	}
+	return Header{
+		Emoji:       m[1],
+		Type:        strings.ToLower(m[2]),
+		Scope:       m[3],
+		Breaking:    m[4] == "!",
+		Description: m[5],
+	}, true
+}
+
+// String formats the header back into a subject line.
+func (h Header) String() string {
+	var b strings.Builder
+	if h.Emoji != "" {
+		b.WriteString(h.Emoji + " ")
+	}
+	b.WriteString(h.Type)
+	if h.Scope != "" {
+		b.WriteString("(" + h.Scope + ")")
+	}
+	if h.Breaking {
+		b.WriteString("!")
+	}
+	b.WriteString(": ")
+	b.WriteString(h.Description)
+	return b.String()
+}
+
+// Check reports why subject breaks the convention or uses a type or scope
+// outside the allowed lists, or nil if it doesn't. Empty lists allow
+// anything, and a missing scope is always allowed.
+func Check(subject string, types, scopes []string) error {
+	h, ok := Parse(subject)
+	if !ok {
+		return fmt.Errorf("subject doesn't follow Conventional Commits (type(scope): description)")
+	}
+	if len(types) > 0 && !slices.Contains(types, h.Type) {
+		return fmt.Errorf("type %q isn't allowed; use one of: %s", h.Type, strings.Join(types, ", "))
+	}
+	if h.Scope != "" && len(scopes) > 0 && !slices.Contains(scopes, h.Scope) {
+		return fmt.Errorf("scope %q isn't allowed; use one of: %s", h.Scope, strings.Join(scopes, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package conventional
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		subject string
+		want    Header
+		ok      bool
+	}{
+		{"feat: add login", Header{Type: "feat", Description: "add login"}, true},
+		{"fix(auth): expire tokens", Header{Type: "fix", Scope: "auth", Description: "expire tokens"}, true},
+		{"refactor(api)!: drop v1", Header{Type: "refactor", Scope: "api", Breaking: true, Description: "drop v1"}, true},
+		{"chore!: bump go", Header{Type: "chore", Breaking: true, Description: "bump go"}, true},
+		{"Feat: uppercase type", Header{Type: "feat", Description: "uppercase type"}, true},
+		{"  docs: padded  ", Header{Type: "docs", Description: "padded"}, true},
+		{"✨ feat: sparkle", Header{Emoji: "✨", Type: "feat", Description: "sparkle"}, true},
+		{":bug: fix: shortcode", Header{Emoji: ":bug:", Type: "fix", Description: "shortcode"}, true},
+		{"feat(): empty scope", Header{Type: "feat", Description: "empty scope"}, true},
+		{"Add login page", Header{}, false},
+		{"feat:missing space", Header{}, false},
+		{"feat(a(b)): nested", Header{}, false},
+		{"feat 2: digits", Header{}, false},
+		{"", Header{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := Parse(tt.subject)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("Parse(%q) = %+v, %v; want %+v, %v", tt.subject, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestHeaderStringRoundTrip(t *testing.T) {
+	for _, subject := range []string{
+		"feat: add login",
+		"fix(auth)!: expire tokens",
+		"✨ feat(ui): sparkle",
+	} {
+		h, ok := Parse(subject)
+		if !ok {
+			t.Fatalf("Parse(%q) failed", subject)
+		}
+		if got := h.String(); got != subject {
+			t.Errorf("Parse(%q).String() = %q", subject, got)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		subject       string
+		types, scopes []string
+		ok            bool
+	}{
+		{"feat: x", nil, nil, true},
+		{"whatever", nil, nil, false},
+		{"feat: x", DefaultTypes, nil, true},
+		{"wip: x", DefaultTypes, nil, false},
+		{"fix(db): x", nil, []string{"db", "api"}, true},
+		{"fix(ui): x", nil, []string{"db", "api"}, false},
+		{"fix: x", nil, []string{"db"}, true},
+	}
+	for _, tt := range tests {
+		if err := Check(tt.subject, tt.types, tt.scopes); (err == nil) != tt.ok {
+			t.Errorf("Check(%q, %q, %q) = %v, want ok %v", tt.subject, tt.types, tt.scopes, err, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package diff
+
+import (
+	"path"
+	"strings"
+)
+
+// File is the portion of a unified diff that belongs to a single file.
+type File struct {
+	// Path is the file's path after the change (its old path when deleted).
+	Path string
+	// OldPath is the file's path before the change; it differs from Path on renames.
+	OldPath string
+	// Content is the raw diff text for this file, starting at its "diff --git" line.
+	Content string
+}
+
+// Parse splits the output of `git diff` into per-file sections.
+func Parse(raw string) []File {
+	var files []File
+	var current *File
+	var b strings.Builder
+
+	flush := func() {
+		if current != nil {
+			current.Content = b.String()
+			files = append(files, *current)
+		}
+		b.Reset()
+	}
+
+	for _, line := range strings.SplitAfter(raw, "\n") {
+		// Content lines keep a CR from files with CRLF line endings, so
+		// patches still apply; headers don't need it.
+		if strings.HasPrefix(line, "diff --git ") {
+			flush()
+			oldPath, newPath := parseHeader(strings.TrimRight(line, "\r\n"))
+			current = &File{Path: newPath, OldPath: oldPath}
+		} else if current != nil {
+			trimmed := strings.TrimRight(line, "\r\n")
+			switch {
+			case strings.HasPrefix(trimmed, "rename from "):
+				current.OldPath = strings.TrimPrefix(trimmed, "rename from ")
+			case strings.HasPrefix(trimmed, "rename to "):
+				current.Path = strings.TrimPrefix(trimmed, "rename to ")
+			}
+		}
+		if current != nil {
+			b.WriteString(line)
+		}
+	}
+	flush()
+
+	return files
+}
+
+// Join reassembles per-file sections into a single diff.
+func Join(files []File) string {
+	var b strings.Builder
+	for _, f := range files {
+		b.WriteString(f.Content)
+	}
+	return b.String()
+}
+
+// lockfiles are the names of dependency lock files: generated, long, and
+// rarely worth reading.
+var lockfiles = map[string]bool{
+	"go.sum": true, "package-lock.json": true, "npm-shrinkwrap.json": true, "yarn.lock": true,
+	"pnpm-lock.yaml": true, "bun.lock": true, "bun.lockb": true, "Cargo.lock": true,
+	"Gemfile.lock": true, "poetry.lock": true, "Pipfile.lock": true, "uv.lock": true,
+	"composer.lock": true, "Podfile.lock": true, "mix.lock": true, "flake.lock": true,
+	"pubspec.lock": true, "packages.lock.json": true, "gradle.lockfile": true, "Package.resolved": true,
+}
+
+// IsLockfile reports whether path is a dependency lock file.
+func IsLockfile(p string) bool {
+	return lockfiles[path.Base(p)]
+}
+
+// Omit returns f with everything after its header replaced by a note.
+func (f File) Omit(note string) File {
+	header, _, _ := strings.Cut(f.Content, "\n@@")
+	f.Content = strings.TrimSuffix(header, "\n") + "\n[" + note + "]\n"
+	return f
+}
+
+// Truncate returns f cut to at most n bytes of its diff, at a line
+// boundary, followed by a note that it was cut.
+func (f File) Truncate(n int) File {
+	const note = "[... truncated ...]\n"
+	if len(f.Content) <= n {
+		return f
+	}
+	cut := f.Content[:max(n-len(note), 0)]
+	if i := strings.LastIndex(cut, "\n"); i >= 0 {
+		cut = cut[:i+1]
+	}
+	f.Content = cut + note
+	return f
+}
+
+// Paths returns the path of every file in files.
+func Paths(files []File) []string {
+	paths := make([]string, len(files))
+	for i, f := range files {
+		paths[i] = f.Path
+	}
+	return paths
+}
+
+// parseHeader extracts the old and new paths from a "diff --git a/x b/y" line.
+func parseHeader(line string) (string, string) {
+	rest := strings.TrimPrefix(line, "diff --git ")
+	// Paths without spaces are the common case; for paths with spaces the
+	// header is ambiguous, so split on the " b/" that starts the new path.
+	if idx := strings.LastIndex(rest, " b/"); idx >= 0 {
+		return strings.TrimPrefix(rest[:idx], "a/"), rest[idx+len(" b/"):]
+	}
+	return rest, rest
+}
+
+// Hunk is one "@@" section of a file's diff. Changes without sections,
+// such as pure renames, mode changes, and binary files, are a single hunk
+// with no Content.
+type Hunk struct {
+	Path string
+	// Header is the file's diff text before its first section.
+	Header string
+	// Content is the section, starting at its "@@" line.
+	Content string
+}
+
+// Title is a one-line description of the hunk: its path and "@@" line.
+func (h Hunk) Title() string {
+	line, _, _ := strings.Cut(h.Content, "\n")
+	line = strings.TrimSuffix(line, "\r")
+	if line == "" {
+		return h.Path
+	}
+	return h.Path + " " + line
+}
+
+// Hunks splits the file's diff into its sections.
+func (f File) Hunks() []Hunk {
+	var header strings.Builder
+	var hunks []Hunk
+	var cur *strings.Builder
+	for _, line := range strings.SplitAfter(f.Content, "\n") {
+		if strings.HasPrefix(line, "@@") {
+			if cur != nil {
+				hunks = append(hunks, Hunk{Path: f.Path, Content: cur.String()})
+			}
+			cur = &strings.Builder{}
+		}
+		if cur != nil {
+			cur.WriteString(line)
+		} else {
+			header.WriteString(line)
+		}
+	}
+	if cur != nil {
+		hunks = append(hunks, Hunk{Path: f.Path, Content: cur.String()})
+	}
+	if len(hunks) == 0 {
+		return []Hunk{{Path: f.Path, Header: header.String()}}
+	}
+	for i := range hunks {
+		hunks[i].Header = header.String()
+	}
+	return hunks
+}
+
+// Patch assembles hunks, in the order they appeared in the diff, into a
+// patch that applies only those hunks.
+func Patch(hunks []Hunk) string {
+	var b strings.Builder
+	for i, h := range hunks {
+		if i == 0 || hunks[i-1].Path != h.Path || hunks[i-1].Header != h.Header {
+			b.WriteString(h.Header)
+		}
+		b.WriteString(h.Content)
+	}
+	return b.String()
+}
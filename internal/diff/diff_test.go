@@ -0,0 +1,123 @@
+package diff
+
+import (
+	"slices"
+	"testing"
+)
+
+const modified = "diff --git a/main.go b/main.go\n" +
+	"index 1111111..2222222 100644\n" +
+	"--- a/main.go\n" +
+	"+++ b/main.go\n" +
+	"@@ -1,3 +1,3 @@\n" +
+	" package main\n" +
+	"-var a = 1\n" +
+	"+var a = 2\n" +
+	" \n" +
+	"@@ -10,2 +10,3 @@ func main() {\n" +
+	" \tprintln(a)\n" +
+	"+\tprintln(b)\n" +
+	" }\n"
+
+const renamed = "diff --git a/old name.txt b/new name.txt\n" +
+	"similarity index 100%\n" +
+	"rename from old name.txt\n" +
+	"rename to new name.txt\n"
+
+const crlf = "diff --git a/win.txt b/win.txt\r\n" +
+	"--- a/win.txt\r\n" +
+	"+++ b/win.txt\r\n" +
+	"@@ -1 +1 @@\r\n" +
+	"-one\r\n" +
+	"+two\r\n"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		raw   string
+		paths []string
+		old   []string
+	}{
+		{"empty", "", nil, nil},
+		{"single file", modified, []string{"main.go"}, []string{"main.go"}},
+		{"rename with spaces", renamed, []string{"new name.txt"}, []string{"old name.txt"}},
+		{"crlf", crlf, []string{"win.txt"}, []string{"win.txt"}},
+		{"several files", modified + renamed + crlf,
+			[]string{"main.go", "new name.txt", "win.txt"},
+			[]string{"main.go", "old name.txt", "win.txt"}},
+		{"leading noise is dropped", "warning: something\n" + modified, []string{"main.go"}, []string{"main.go"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := Parse(tt.raw)
+			if got := Paths(files); !slices.Equal(got, tt.paths) {
+				t.Errorf("Paths = %q, want %q", got, tt.paths)
+			}
+			var old []string
+			for _, f := range files {
+				old = append(old, f.OldPath)
+			}
+			if !slices.Equal(old, tt.old) {
+				t.Errorf("OldPaths = %q, want %q", old, tt.old)
+			}
+		})
+	}
+}
+
+func TestParseJoinRoundTrip(t *testing.T) {
+	for _, raw := range []string{modified, renamed, crlf, modified + renamed + crlf} {
+		if got := Join(Parse(raw)); got != raw {
+			t.Errorf("Join(Parse(raw)) = %q, want %q", got, raw)
+		}
+	}
+}
+
+func TestHunks(t *testing.T) {
+	tests := []struct {
+		name   string
+		raw    string
+		titles []string
+	}{
+		{"two sections", modified, []string{"main.go @@ -1,3 +1,3 @@", "main.go @@ -10,2 +10,3 @@ func main() {"}},
+		{"rename has no sections", renamed, []string{"new name.txt"}},
+		{"crlf", crlf, []string{"win.txt @@ -1 +1 @@"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var titles []string
+			for _, h := range Parse(tt.raw)[0].Hunks() {
+				titles = append(titles, h.Title())
+			}
+			if !slices.Equal(titles, tt.titles) {
+				t.Errorf("titles = %q, want %q", titles, tt.titles)
+			}
+		})
+	}
+}
+
+func TestPatchRoundTrip(t *testing.T) {
+	for _, raw := range []string{modified, renamed, crlf, modified + renamed + crlf} {
+		var hunks []Hunk
+		for _, f := range Parse(raw) {
+			hunks = append(hunks, f.Hunks()...)
+		}
+		if got := Patch(hunks); got != raw {
+			t.Errorf("Patch(all hunks) = %q, want %q", got, raw)
+		}
+	}
+}
+
+func TestPatchSubset(t *testing.T) {
+	hunks := Parse(modified)[0].Hunks()
+	want := "diff --git a/main.go b/main.go\n" +
+		"index 1111111..2222222 100644\n" +
+		"--- a/main.go\n" +
+		"+++ b/main.go\n" +
+		"@@ -10,2 +10,3 @@ func main() {\n" +
+		" \tprintln(a)\n" +
+		"+\tprintln(b)\n" +
+		" }\n"
+	if got := Patch(hunks[1:]); got != want {
+		t.Errorf("Patch(second hunk) = %q, want %q", got, want)
+	}
+}
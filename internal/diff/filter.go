@@ -0,0 +1,117 @@
+package diff
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Filters recognize hunks that are noise to the model.
+const (
+	// FilterWhitespace matches hunks that change only whitespace, including
+	// blank lines.
+	FilterWhitespace = "whitespace"
+	// FilterImports matches hunks that only reorder imports.
+	FilterImports = "imports"
+	// FilterFormatting matches formatter churn: hunks that change only
+	// whitespace, trailing commas and semicolons, and quote style.
+	FilterFormatting = "formatting"
+)
+
+// FilterNames lists every filter.
+var FilterNames = []string{FilterWhitespace, FilterImports, FilterFormatting}
+
+// importLine matches a line importing something in the common languages,
+// including a path alone on its line in a Go import block.
+var importLine = regexp.MustCompile(`^\s*(import\b|from\s+\S+\s+import\b|use\s|using\s|require\b|#\s*include\b|(\w+\s+|[._]\s+)?"[^"]+"$)|\brequire\(`)
+
+// formatting matches what formatters commonly add, remove, or change.
+var formatting = strings.NewReplacer(",", "", ";", "", "'", `"`, "`", `"`)
+
+// Noise returns a note saying why the hunk is noise to filters, or "" if
+// it isn't.
+func (h Hunk) Noise(filters []string) string {
+	var removed, added []string
+	for _, line := range strings.Split(h.Content, "\n")[1:] {
+		line = strings.TrimSuffix(line, "\r")
+		switch {
+		case strings.HasPrefix(line, "-"):
+			removed = append(removed, line[1:])
+		case strings.HasPrefix(line, "+"):
+			added = append(added, line[1:])
+		}
+	}
+	if len(removed) == 0 && len(added) == 0 {
+		return ""
+	}
+	before, after := strings.Join(removed, "\n"), strings.Join(added, "\n")
+	switch {
+	case slices.Contains(filters, FilterImports) && reordered(removed, added):
+		return "imports reordered"
+	case slices.Contains(filters, FilterWhitespace) && stripSpace(before) == stripSpace(after):
+		return "whitespace-only change"
+	case slices.Contains(filters, FilterFormatting) &&
+		stripSpace(formatting.Replace(before)) == stripSpace(formatting.Replace(after)):
+		return "formatting-only change"
+	}
+	return ""
+}
+
+// Filter returns f with each hunk that's noise to filters collapsed to its
+// "@@" line and a note. Only for sending: the result no longer applies as a
+// patch.
+func (f File) Filter(filters []string) File {
+	if len(filters) == 0 {
+		return f
+	}
+	hunks := f.Hunks()
+	if hunks[0].Content == "" {
+		return f
+	}
+	var b strings.Builder
+	b.WriteString(hunks[0].Header)
+	for _, h := range hunks {
+		note := h.Noise(filters)
+		if note == "" {
+			b.WriteString(h.Content)
+			continue
+		}
+		line, _, _ := strings.Cut(h.Content, "\n")
+		b.WriteString(line + "\n[" + note + ", omitted]\n")
+	}
+	f.Content = b.String()
+	return f
+}
+
+// reordered reports whether removed and added are the same import lines in
+// a different order.
+func reordered(removed, added []string) bool {
+	removed, added = nonBlank(removed), nonBlank(added)
+	if len(removed) == 0 || slices.Equal(removed, added) {
+		return false
+	}
+	for _, line := range removed {
+		if !importLine.MatchString(line) {
+			return false
+		}
+	}
+	slices.Sort(removed)
+	slices.Sort(added)
+	return slices.Equal(removed, added)
+}
+
+// nonBlank returns the trimmed lines that aren't blank.
+func nonBlank(lines []string) []string {
+	var out []string
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); line != "" {
+			out = append(out, line)
+		}
+	}
+	return out
+}
+
+// stripSpace removes all whitespace from s.
+func stripSpace(s string) string {
+	return strings.Join(strings.Fields(s), "")
+}
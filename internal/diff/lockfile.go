@@ -0,0 +1,188 @@
+package diff
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// lockFormat reads dependency names and versions from the lines of a lock
+// file. Formats that put both on one line set entry; formats that name a
+// package and give its version on a later line set name and version.
+type lockFormat struct {
+	entry   *regexp.Regexp
+	name    *regexp.Regexp
+	version *regexp.Regexp
+}
+
+var (
+	tomlLock = lockFormat{
+		name:    regexp.MustCompile(`^name = "([^"]+)"`),
+		version: regexp.MustCompile(`^version = "([^"]+)"`),
+	}
+	jsonLock = lockFormat{
+		name:    regexp.MustCompile(`^\s*"name": "([^"]+)"`),
+		version: regexp.MustCompile(`^\s*"version": "([^"]+)"`),
+	}
+)
+
+var lockFormats = map[string]lockFormat{
+	"go.sum": {entry: regexp.MustCompile(`^(\S+) (v[^/\s]+)(?:/go\.mod)? h1:`)},
+	"package-lock.json": {
+		name:    regexp.MustCompile(`^\s*"(?:[^"]*/)?node_modules/((?:@[^/"]+/)?[^/"]+)": \{`),
+		version: regexp.MustCompile(`^\s*"version": "([^"]+)"`),
+	},
+	"npm-shrinkwrap.json": {
+		name:    regexp.MustCompile(`^\s*"(?:[^"]*/)?node_modules/((?:@[^/"]+/)?[^/"]+)": \{`),
+		version: regexp.MustCompile(`^\s*"version": "([^"]+)"`),
+	},
+	"yarn.lock": {
+		name:    regexp.MustCompile(`^"?((?:@[^@/\s"]+/)?[^@\s"]+)@`),
+		version: regexp.MustCompile(`^\s+version:? "?([^"\s]+)"?`),
+	},
+	"pnpm-lock.yaml": {entry: regexp.MustCompile(`^\s{2}'?/?((?:@[^@/\s]+/)?[^@/\s']+)[@/]([0-9][^:('\s]*)`)},
+	"Gemfile.lock":   {entry: regexp.MustCompile(`^\s{4}(\S+) \(([^)]+)\)$`)},
+	"Cargo.lock":     tomlLock,
+	"poetry.lock":    tomlLock,
+	"uv.lock":        tomlLock,
+	"composer.lock":  jsonLock,
+	"Pipfile.lock": {
+		name:    regexp.MustCompile(`^\s{8}"([^"]+)": \{`),
+		version: regexp.MustCompile(`^\s*"version": "==([^"]+)"`),
+	},
+	"mix.lock": {entry: regexp.MustCompile(`^\s*"([^"]+)": \{:\w+, :\w+, "([^"]+)"`)},
+}
+
+// maxListed is how many dependencies of each kind a summary names.
+const maxListed = 10
+
+// SummarizeLockfile returns f with its diff replaced by a summary of the
+// dependencies it adds, removes, and updates, read from the diff itself.
+// When the format isn't known or no versions can be read, the summary is
+// just the counts of lines changed. ok is false when f isn't a lock file.
+func (f File) SummarizeLockfile() (File, bool) {
+	s := f.Stat()
+	if !IsLockfile(f.Path) || s.Binary {
+		return f, false
+	}
+	format, known := lockFormats[path.Base(f.Path)]
+	var before, after map[string]string
+	if known {
+		before, after = format.read(f.Content)
+	}
+	var added, removed, updated []string
+	for name, v := range after {
+		switch old, ok := before[name]; {
+		case !ok:
+			added = append(added, name+" "+v)
+		case old != v:
+			updated = append(updated, name+" "+old+" → "+v)
+		}
+	}
+	for name, v := range before {
+		if _, ok := after[name]; !ok {
+			removed = append(removed, name+" "+v)
+		}
+	}
+	n := len(added) + len(removed) + len(updated)
+	if n == 0 {
+		return f.Omit(fmt.Sprintf("lockfile changes omitted: +%d -%d lines", s.Added, s.Removed)), true
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "lockfile summary: %d %s changed", n, plural(n, "dependency", "dependencies"))
+	for _, group := range []struct {
+		label string
+		names []string
+	}{{"added", added}, {"removed", removed}, {"updated", updated}} {
+		if len(group.names) == 0 {
+			continue
+		}
+		slices.Sort(group.names)
+		fmt.Fprintf(&b, "\n%d %s: %s", len(group.names), group.label, listed(group.names))
+	}
+	return f.Omit(b.String()), true
+}
+
+// read collects the name and version of each dependency on the removed and
+// added sides of a lock file's diff. Context lines name a package on both.
+func (lf lockFormat) read(content string) (before, after map[string]string) {
+	before, after = make(map[string]string), make(map[string]string)
+	var oldName, newName string
+	inHunk := false
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if strings.HasPrefix(line, "@@") {
+			inHunk = true
+			oldName, newName = "", ""
+			continue
+		}
+		if !inHunk || line == "" {
+			continue
+		}
+		side, text := line[0], line[1:]
+		if lf.entry != nil {
+			m := lf.entry.FindStringSubmatch(text)
+			switch {
+			case m == nil:
+			case side == '-':
+				before[m[1]] = m[2]
+			case side == '+':
+				after[m[1]] = m[2]
+			}
+			continue
+		}
+		if m := lf.name.FindStringSubmatch(text); m != nil {
+			if side != '+' {
+				oldName = m[1]
+			}
+			if side != '-' {
+				newName = m[1]
+			}
+			continue
+		}
+		if m := lf.version.FindStringSubmatch(text); m != nil {
+			switch {
+			case side == '-' && oldName != "":
+				before[oldName] = m[1]
+			case side == '+' && newName != "":
+				after[newName] = m[1]
+			}
+		}
+	}
+	return before, after
+}
+
+// listed joins names, cutting the list at maxListed.
+func listed(names []string) string {
+	if len(names) <= maxListed {
+		return strings.Join(names, ", ")
+	}
+	return strings.Join(names[:maxListed], ", ") + fmt.Sprintf(", and %d more", len(names)-maxListed)
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
+
+// SummarizeBinary returns f with git's "Binary files differ" line replaced
+// by a note of how the file changed. ok is false when f isn't binary.
+func (f File) SummarizeBinary() (File, bool) {
+	s := f.Stat()
+	if !s.Binary {
+		return f, false
+	}
+	header, _, _ := strings.Cut(f.Content, "\nBinary files ")
+	header, _, _ = strings.Cut(header, "\nGIT binary patch")
+	kind := ""
+	if ext := path.Ext(f.Path); ext != "" {
+		kind = " (" + strings.TrimPrefix(ext, ".") + ")"
+	}
+	f.Content = strings.TrimSuffix(header, "\n") + "\n[binary file" + kind + " " + s.Status + ", content not shown]\n"
+	return f, true
+}
@@ -0,0 +1,148 @@
+package diff
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Declaration patterns by language, close to the function name patterns git
+// uses for hunk headers, but matching indented methods too.
+var (
+	goDecl     = regexp.MustCompile(`^(func|type|var|const)\b`)
+	pythonDecl = regexp.MustCompile(`^\s*(async\s+def|def|class)\s`)
+	rubyDecl   = regexp.MustCompile(`^\s*(def|class|module)\s`)
+	jsDecl     = regexp.MustCompile(`^\s*(export\s+)?(default\s+)?(async\s+)?(function\b|class\b|(const|let|var)\s+[\w$]+\s*=\s*(async\s*)?(\([^)]*\)|[\w$]+)\s*=>|(static\s+|async\s+|get\s+|set\s+|public\s+|private\s+|protected\s+)*[\w$]+\s*\([^;]*\)\s*(:\s*[^;{]+)?\{\s*$)`)
+	rustDecl   = regexp.MustCompile(`^\s*(pub(\([^)]*\))?\s+)?(async\s+|const\s+|unsafe\s+)*(fn|struct|enum|trait|impl|mod)\b`)
+	// cDecl covers C-like languages: a line that names something and opens
+	// a block, or a class-like declaration.
+	cDecl = regexp.MustCompile(`^\s*((public|private|protected|internal|static|final|abstract|override|virtual|inline|export|open|data|sealed)\s+)*(class|interface|struct|enum|record|namespace|fun|func)\b|^[A-Za-z_][\w:<>,*&\s]*\([^;]*\)\s*(const\s*)?(\{\s*)?$|^\s+((public|private|protected|internal|static|final|abstract|override|virtual|async|synchronized)\s+)+[\w<>\[\],.\s]+\([^;]*\)\s*(throws\s+[\w.,\s]+)?\{?\s*$`)
+	// otherDecl is git's default: any line starting with a letter, _, or $.
+	otherDecl = regexp.MustCompile(`^[A-Za-z_$]`)
+)
+
+var declPatterns = map[string]*regexp.Regexp{
+	".go": goDecl,
+	".py": pythonDecl, ".pyi": pythonDecl,
+	".rb": rubyDecl,
+	".js": jsDecl, ".jsx": jsDecl, ".mjs": jsDecl, ".cjs": jsDecl, ".ts": jsDecl, ".tsx": jsDecl,
+	".rs": rustDecl,
+	".c":  cDecl, ".h": cDecl, ".cc": cDecl, ".cpp": cDecl, ".hpp": cDecl, ".cs": cDecl,
+	".java": cDecl, ".kt": cDecl, ".kts": cDecl, ".scala": cDecl, ".swift": cDecl, ".php": cDecl,
+}
+
+// hunkStart matches a hunk's "@@" line, capturing where it starts in the
+// new file.
+var hunkStart = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)(?:,\d+)? @@`)
+
+// Scopes finds, for each of the file's hunks, the start of the function or
+// type enclosing its first change when the hunk doesn't show it: up to n
+// lines of src, the file after the change, from the declaration on. A hunk
+// with nothing to add gets "". It's a guess from indentation and each
+// language's declaration syntax, so code it can't read gets nothing.
+func (f File) Scopes(src string, n int) []string {
+	hunks := f.Hunks()
+	scopes := make([]string, len(hunks))
+	if n <= 0 || src == "" {
+		return scopes
+	}
+	decl, ok := declPatterns[strings.ToLower(path.Ext(f.Path))]
+	if !ok {
+		decl = otherDecl
+	}
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+	for i, h := range hunks {
+		first, at, text, ok := firstChange(h.Content)
+		if !ok {
+			continue
+		}
+		start := enclosing(lines, at, indent(text), decl)
+		if start < 0 || start+1 >= first {
+			// Nothing encloses the change, or the hunk shows where it starts.
+			continue
+		}
+		end := min(start+n, first-1)
+		scopes[i] = fmt.Sprintf("[from line %d, the start of the declaration enclosing the next hunk:\n%s\n]\n",
+			start+1, strings.Join(lines[start:end], "\n"))
+	}
+	return scopes
+}
+
+// WithScopes returns f with each non-empty scope inserted before the hunk
+// it belongs to. Only for sending: the result no longer applies as a patch.
+func (f File) WithScopes(scopes []string) File {
+	if len(scopes) == 0 {
+		return f
+	}
+	var b strings.Builder
+	i := 0
+	for _, line := range strings.SplitAfter(f.Content, "\n") {
+		if strings.HasPrefix(line, "@@") {
+			if i < len(scopes) {
+				b.WriteString(scopes[i])
+			}
+			i++
+		}
+		b.WriteString(line)
+	}
+	f.Content = b.String()
+	return f
+}
+
+// firstChange returns the line in the new file where the hunk starts, and
+// where its first added or removed line that isn't blank is along with that
+// line's text. Both lines count from 1.
+func firstChange(hunk string) (first, at int, text string, ok bool) {
+	lines := strings.Split(hunk, "\n")
+	m := hunkStart.FindStringSubmatch(lines[0])
+	if m == nil {
+		return 0, 0, "", false
+	}
+	first, _ = strconv.Atoi(m[1])
+	at = first
+	for _, line := range lines[1:] {
+		changed := strings.HasPrefix(line, "+") || strings.HasPrefix(line, "-")
+		if changed && strings.TrimSpace(line[1:]) != "" {
+			return first, at, strings.TrimRight(line[1:], "\r"), true
+		}
+		if !strings.HasPrefix(line, "-") && !strings.HasPrefix(line, "\\") {
+			at++
+		}
+	}
+	return 0, 0, "", false
+}
+
+// enclosing returns the index in lines of the declaration enclosing line
+// at, counting from 1, whose text is indented by depth, or -1 if there's
+// none. It's the nearest declaration above indented less than the change;
+// reaching anything else at the top level first means there's none, as
+// does a change at the top level itself.
+func enclosing(lines []string, at, depth int, decl *regexp.Regexp) int {
+	if depth == 0 {
+		return -1
+	}
+	for i := min(at-2, len(lines)-1); i >= 0; i-- {
+		line := lines[i]
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		d := indent(line)
+		if d >= depth {
+			continue
+		}
+		if decl.MatchString(line) {
+			return i
+		}
+		if d == 0 {
+			return -1
+		}
+	}
+	return -1
+}
+
+// indent is the number of leading spaces and tabs in line.
+func indent(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " \t"))
+}
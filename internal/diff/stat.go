@@ -0,0 +1,120 @@
+package diff
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Stat is the shape of one file's change, read from its diff.
+type Stat struct {
+	Path    string
+	OldPath string
+	// Status is "added", "deleted", "renamed", "copied", "mode changed", or
+	// "modified".
+	Status string
+	// Similarity is the percentage git reports for a rename or copy.
+	Similarity int
+	Added      int
+	Removed    int
+	Binary     bool
+}
+
+// Stat reads the file's status and counts of added and removed lines from
+// its diff.
+func (f File) Stat() Stat {
+	s := Stat{Path: f.Path, OldPath: f.OldPath, Status: "modified"}
+	inHunk := false
+	for _, line := range strings.Split(f.Content, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if inHunk {
+			switch {
+			case strings.HasPrefix(line, "+"):
+				s.Added++
+			case strings.HasPrefix(line, "-"):
+				s.Removed++
+			}
+			continue
+		}
+		switch {
+		case strings.HasPrefix(line, "@@"):
+			inHunk = true
+		case strings.HasPrefix(line, "new file mode"):
+			s.Status = "added"
+		case strings.HasPrefix(line, "deleted file mode"):
+			s.Status = "deleted"
+		case strings.HasPrefix(line, "rename from "):
+			s.Status = "renamed"
+		case strings.HasPrefix(line, "copy from "):
+			s.Status = "copied"
+		case strings.HasPrefix(line, "old mode ") && s.Status == "modified":
+			s.Status = "mode changed"
+		case strings.HasPrefix(line, "similarity index "):
+			fmt.Sscanf(strings.TrimPrefix(line, "similarity index "), "%d%%", &s.Similarity)
+		case strings.HasPrefix(line, "Binary files "), line == "GIT binary patch":
+			s.Binary = true
+		}
+	}
+	// A mode change with edits is still a modification.
+	if s.Status == "mode changed" && (s.Added > 0 || s.Removed > 0) {
+		s.Status = "modified"
+	}
+	return s
+}
+
+// languages names the language of a file by its extension.
+var languages = map[string]string{
+	".go": "Go", ".py": "Python", ".pyi": "Python", ".rb": "Ruby", ".rs": "Rust",
+	".js": "JavaScript", ".jsx": "JavaScript", ".mjs": "JavaScript", ".cjs": "JavaScript",
+	".ts": "TypeScript", ".tsx": "TypeScript", ".java": "Java", ".kt": "Kotlin", ".kts": "Kotlin",
+	".scala": "Scala", ".swift": "Swift", ".c": "C", ".h": "C", ".cc": "C++", ".cpp": "C++",
+	".hpp": "C++", ".cs": "C#", ".php": "PHP", ".ex": "Elixir", ".exs": "Elixir", ".erl": "Erlang",
+	".hs": "Haskell", ".lua": "Lua", ".dart": "Dart", ".sh": "Shell", ".bash": "Shell", ".zsh": "Shell",
+	".sql": "SQL", ".html": "HTML", ".css": "CSS", ".scss": "SCSS", ".vue": "Vue", ".svelte": "Svelte",
+	".md": "Markdown", ".rst": "reStructuredText", ".txt": "Text", ".json": "JSON", ".yaml": "YAML",
+	".yml": "YAML", ".toml": "TOML", ".xml": "XML", ".proto": "Protocol Buffers", ".tf": "Terraform",
+	".mod": "Go module", ".sum": "Go checksums",
+}
+
+// languageNames names files that have no telling extension.
+var languageNames = map[string]string{
+	"makefile": "Makefile", "dockerfile": "Dockerfile", "gemfile": "Ruby", "rakefile": "Ruby",
+	"justfile": "Justfile", "jenkinsfile": "Groovy",
+}
+
+// Language names the language of the file at p, or "" if it isn't known.
+func Language(p string) string {
+	base := strings.ToLower(path.Base(p))
+	if lang, ok := languageNames[base]; ok {
+		return lang
+	}
+	return languages[strings.ToLower(path.Ext(base))]
+}
+
+// StatTable summarizes files as a table with one row per file: its path,
+// how it changed, the counts of added and removed lines, and its language.
+func StatTable(files []File) string {
+	var b strings.Builder
+	b.WriteString("| File | Change | Lines | Language |\n|---|---|---|---|\n")
+	for _, f := range files {
+		s := f.Stat()
+		name := s.Path
+		change := s.Status
+		if s.Status == "renamed" || s.Status == "copied" {
+			name = s.OldPath + " → " + s.Path
+			if s.Similarity > 0 {
+				change += fmt.Sprintf(" (%d%% similar)", s.Similarity)
+			}
+		}
+		lines := fmt.Sprintf("+%d -%d", s.Added, s.Removed)
+		if s.Binary {
+			lines = "binary"
+		}
+		lang := Language(s.Path)
+		if lang == "" {
+			lang = "-"
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", name, change, lines, lang)
+	}
+	return b.String()
+}
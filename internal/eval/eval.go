@@ -0,0 +1,273 @@
+package eval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/arpxspace/smartcommit/internal/ai"
+	"github.com/arpxspace/smartcommit/internal/conventional"
+)
+
+// A corpus is a directory of cases. Each case is a NAME.diff file with the
+// recorded diff, a NAME.msg file with the reference message, and optionally
+// NAME.answers.json with the answers to replay as {"question": "answer"}.
+const (
+	diffExt    = ".diff"
+	messageExt = ".msg"
+	answersExt = ".answers.json"
+)
+
+// maxSubjectLength is the longest subject line considered well formed.
+const maxSubjectLength = 72
+
+// Case is one recorded diff with its reference message.
+type Case struct {
+	Name      string
+	Diff      string
+	Reference string
+	Answers   map[string]string
+}
+
+// LoadCorpus reads every case in dir, sorted by name.
+func LoadCorpus(dir string) ([]Case, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "*"+diffExt))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(matches)
+
+	var cases []Case
+	for _, path := range matches {
+		name := strings.TrimSuffix(filepath.Base(path), diffExt)
+		d, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		ref, err := os.ReadFile(filepath.Join(dir, name+messageExt))
+		if err != nil {
+			return nil, fmt.Errorf("case %s has no reference message: %w", name, err)
+		}
+		c := Case{Name: name, Diff: string(d), Reference: strings.TrimSpace(string(ref))}
+
+		answers, err := os.ReadFile(filepath.Join(dir, name+answersExt))
+		switch {
+		case err == nil:
+			if err := json.Unmarshal(answers, &c.Answers); err != nil {
+				return nil, fmt.Errorf("failed to parse answers for %s: %w", name, err)
+			}
+		case !os.IsNotExist(err):
+			return nil, err
+		}
+		cases = append(cases, c)
+	}
+	if len(cases) == 0 {
+		return nil, fmt.Errorf("no *%s files found in %s", diffExt, dir)
+	}
+	return cases, nil
+}
+
+// Result is the outcome of running one case.
+type Result struct {
+	Case     string   `json:"case"`
+	Message  string   `json:"message,omitempty"`
+	Error    string   `json:"error,omitempty"`
+	FormatOK bool     `json:"format_ok"`
+	Problems []string `json:"problems,omitempty"`
+	// Similarity is the word-overlap F1 between the generated and reference
+	// messages, from 0 to 1.
+	Similarity float64 `json:"similarity"`
+	// Judge is the provider's rubric score normalized to 0-1, or -1 when
+	// judging was skipped or failed.
+	Judge float64 `json:"judge"`
+	// Score combines the three metrics, from 0 to 1.
+	Score float64 `json:"score"`
+}
+
+// Report is the result of running a corpus.
+type Report struct {
+	Model   string    `json:"model"`
+	RunAt   time.Time `json:"run_at"`
+	Results []Result  `json:"results"`
+	Mean    float64   `json:"mean"`
+}
+
+// Options control a run.
+type Options struct {
+	// Judge asks the provider to score each generated message against its diff.
+	Judge bool
+	// Progress, if set, is called after each case.
+	Progress func(done, total int, r Result)
+}
+
+// Run generates a message for every case and scores it.
+func Run(ctx context.Context, client ai.Provider, cases []Case, opts Options) Report {
+	report := Report{Model: client.Model(), RunAt: time.Now()}
+	total := 0.0
+	for i, c := range cases {
+		r := runCase(ctx, client, c, opts)
+		report.Results = append(report.Results, r)
+		total += r.Score
+		if opts.Progress != nil {
+			opts.Progress(i+1, len(cases), r)
+		}
+	}
+	if len(cases) > 0 {
+		report.Mean = total / float64(len(cases))
+	}
+	return report
+}
+
+func runCase(ctx context.Context, client ai.Provider, c Case, opts Options) Result {
+	r := Result{Case: c.Name, Judge: -1}
+	msg, err := client.GenerateCommitMessage(ctx, c.Diff, "", c.Answers)
+	if err != nil {
+		r.Error = err.Error()
+		return r
+	}
+	r.Message = strings.TrimSpace(msg)
+	r.Problems = FormatProblems(r.Message)
+	r.FormatOK = len(r.Problems) == 0
+	r.Similarity = Similarity(r.Message, c.Reference)
+
+	if opts.Judge {
+		if score, err := client.ScoreMessage(ctx, c.Diff, r.Message); err == nil {
+			r.Judge = float64(score.WhyCoverage+score.Clarity+score.Convention) / 30
+		}
+	}
+	r.Score = combine(r)
+	return r
+}
+
+// combine weighs the metrics into one score. Format is pass/fail and counts
+// for a fifth; the rest is split between similarity and the judge, or is all
+// similarity when there's no judge score.
+func combine(r Result) float64 {
+	format := 0.0
+	if r.FormatOK {
+		format = 1
+	}
+	if r.Judge < 0 {
+		return 0.2*format + 0.8*r.Similarity
+	}
+	return 0.2*format + 0.4*r.Similarity + 0.4*r.Judge
+}
+
+// FormatProblems lists the ways message deviates from a well-formed
+// Conventional Commits message.
+func FormatProblems(message string) []string {
+	var problems []string
+	lines := strings.Split(message, "\n")
+	subject := lines[0]
+	if _, ok := conventional.Parse(subject); !ok {
+		problems = append(problems, "subject is not a Conventional Commits header")
+	}
+	if len(subject) > maxSubjectLength {
+		problems = append(problems, fmt.Sprintf("subject is %d characters (max %d)", len(subject), maxSubjectLength))
+	}
+	if strings.HasSuffix(subject, ".") {
+		problems = append(problems, "subject ends with a period")
+	}
+	if len(lines) > 1 && strings.TrimSpace(lines[1]) != "" {
+		problems = append(problems, "no blank line between subject and body")
+	}
+	return problems
+}
+
+// Similarity is the F1 score of the word multisets of a and b, ignoring case
+// and punctuation.
+func Similarity(a, b string) float64 {
+	wa, wb := words(a), words(b)
+	if len(wa) == 0 || len(wb) == 0 {
+		return 0
+	}
+	counts := map[string]int{}
+	for _, w := range wb {
+		counts[w]++
+	}
+	common := 0
+	for _, w := range wa {
+		if counts[w] > 0 {
+			counts[w]--
+			common++
+		}
+	}
+	if common == 0 {
+		return 0
+	}
+	precision := float64(common) / float64(len(wa))
+	recall := float64(common) / float64(len(wb))
+	return 2 * precision * recall / (precision + recall)
+}
+
+func words(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Regression is a case that scored worse than in the baseline.
+type Regression struct {
+	Case     string
+	Baseline float64
+	Current  float64
+	Reason   string
+}
+
+// Compare returns the cases in current that regressed against baseline:
+// those that now fail, lost format validity, or dropped by more than tolerance.
+func Compare(baseline, current Report, tolerance float64) []Regression {
+	before := make(map[string]Result, len(baseline.Results))
+	for _, r := range baseline.Results {
+		before[r.Case] = r
+	}
+
+	var regressions []Regression
+	for _, r := range current.Results {
+		b, ok := before[r.Case]
+		if !ok {
+			continue
+		}
+		reg := Regression{Case: r.Case, Baseline: b.Score, Current: r.Score}
+		switch {
+		case r.Error != "" && b.Error == "":
+			reg.Reason = "generation failed: " + r.Error
+		case b.FormatOK && !r.FormatOK:
+			reg.Reason = "format: " + strings.Join(r.Problems, "; ")
+		case b.Score-r.Score > tolerance:
+			reg.Reason = fmt.Sprintf("score dropped by %.2f", b.Score-r.Score)
+		default:
+			continue
+		}
+		regressions = append(regressions, reg)
+	}
+	return regressions
+}
+
+// LoadReport reads a report previously written with Save.
+func LoadReport(path string) (Report, error) {
+	var r Report
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return r, fmt.Errorf("failed to read baseline: %w", err)
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return r, fmt.Errorf("failed to parse baseline %s: %w", path, err)
+	}
+	return r, nil
+}
+
+// Save writes the report as JSON so it can be used as a later baseline.
+func (r Report) Save(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
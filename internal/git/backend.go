@@ -0,0 +1,70 @@
+package git
+
+import (
+	"os/exec"
+	"sync"
+)
+
+// Backend reads and writes the repository in the current directory for the
+// commit flow: the staged change and what's around it, the history, and
+// the commit itself. The package's functions of the same names use the
+// backend set with Use. Everything else, like rewording, splitting, hooks,
+// and signing, always runs git.
+type Backend interface {
+	IsRepo() bool
+	RepoRoot() (string, error)
+	TopLevelDirs() ([]string, error)
+	GetStagedDiff(args ...string) (string, error)
+	GetHeadFile(path string) (string, error)
+	GetStagedFile(path string) (string, error)
+	GetUnstagedDiff() (string, error)
+	UntrackedFiles() ([]string, error)
+	GetRecentHistory(n int) (string, error)
+	GetLog(n int) ([]Commit, error)
+	HasHead() bool
+	HeadCommit() (string, error)
+	HeadMessage() (string, error)
+	CurrentBranch() (string, error)
+	UserIdent() (string, error)
+	RemoteURL(name string) string
+	CommitWithMessage(message string, args ...string) error
+}
+
+// ExecBackend runs the git binary, as every other command does.
+func ExecBackend() Backend {
+	return execGit{}
+}
+
+// execGit is the Backend that runs git.
+type execGit struct{}
+
+// backend is the Backend set with Use, if any.
+var backend Backend
+
+// defaultBackend runs git when it's installed, and go-git otherwise.
+var defaultBackend = sync.OnceValue(func() Backend {
+	if _, err := exec.LookPath("git"); err != nil {
+		return GoGitBackend()
+	}
+	return ExecBackend()
+})
+
+// Use has the package's functions work through b. It's meant to be called
+// once, before anything else in the package.
+func Use(b Backend) {
+	backend = b
+}
+
+func current() Backend {
+	if backend != nil {
+		return backend
+	}
+	return defaultBackend()
+}
+
+// UsesExec reports whether the backend in use runs git, so git's editor,
+// hooks, and signing are available to commits.
+func UsesExec() bool {
+	_, ok := current().(execGit)
+	return ok
+}
@@ -0,0 +1,142 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultBranch returns the branch the origin remote's HEAD points at, such
+// as "main", falling back to a local main or master.
+func DefaultBranch() (string, error) {
+	cmd := command("symbolic-ref", "--short", "-q", "refs/remotes/origin/HEAD")
+	if out, err := cmd.Output(); err == nil {
+		return strings.TrimPrefix(strings.TrimSpace(string(out)), "origin/"), nil
+	}
+	for _, name := range []string{"main", "master"} {
+		if command("rev-parse", "--verify", "-q", "refs/heads/"+name).Run() == nil {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("can't tell the default branch; pass the base branch explicitly")
+}
+
+// BranchDiff returns the change the current branch makes since it forked
+// from base: the diff from their merge base to HEAD. Extra arguments are
+// passed through to git diff.
+func BranchDiff(base string, args ...string) (string, error) {
+	cmd := command(append(append([]string{"diff"}, args...), base+"...HEAD")...)
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get diff against %s: %w", base, err)
+	}
+	return string(out), nil
+}
+
+// RangeDiff returns the change the commits in revRange, such as
+// main..feature, make together: the diff from where their tip forked from
+// the range's base to the tip. A missing side of the range is HEAD. Extra
+// arguments are passed through to git diff.
+func RangeDiff(revRange string, args ...string) (string, error) {
+	base, tip, ok := strings.Cut(revRange, "...")
+	if !ok {
+		base, tip, _ = strings.Cut(revRange, "..")
+	}
+	if base == "" {
+		base = "HEAD"
+	}
+	if tip == "" {
+		tip = "HEAD"
+	}
+	cmd := command(append(append([]string{"diff"}, args...), base+"..."+tip)...)
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get diff of %s: %w", revRange, err)
+	}
+	return string(out), nil
+}
+
+// LatestTag returns the most recent tag reachable from rev, or "" if there's
+// none.
+func LatestTag(rev string) string {
+	cmd := command("describe", "--tags", "--abbrev=0", rev)
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// IsTag reports whether name is a tag.
+func IsTag(name string) bool {
+	return command("rev-parse", "--verify", "-q", "refs/tags/"+name).Run() == nil
+}
+
+// CommitDate returns the committer date of rev as YYYY-MM-DD.
+func CommitDate(rev string) (string, error) {
+	cmd := command("log", "-1", "--format=%cs", rev)
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to read date of %s: %w", rev, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// WorkingTreeDiff returns every uncommitted change to tracked files, staged
+// or not. Extra arguments are passed through to git diff.
+func WorkingTreeDiff(args ...string) (string, error) {
+	rev := "HEAD"
+	if !HasHead() {
+		// Nothing is committed, so compare against the empty tree.
+		rev = "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+	}
+	cmd := command(append(append([]string{"diff"}, args...), rev)...)
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get working tree diff: %w", err)
+	}
+	return string(out), nil
+}
+
+// CheckBranchName reports whether name is a valid branch name.
+func CheckBranchName(name string) error {
+	if command("check-ref-format", "--branch", name).Run() != nil {
+		return fmt.Errorf("%q isn't a valid branch name", name)
+	}
+	return nil
+}
+
+// BranchExists reports whether the local branch name exists.
+func BranchExists(name string) bool {
+	return command("rev-parse", "--verify", "-q", "refs/heads/"+name).Run() == nil
+}
+
+// CreateBranch creates branch name at from, or HEAD if from is empty, and
+// switches to it. Uncommitted changes come along, as with git switch.
+func CreateBranch(name, from string) error {
+	args := []string{"switch", "-c", name}
+	if from != "" {
+		args = append(args, from)
+	}
+	if out, err := command(args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to create branch %s: %w: %s", name, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// Stash stashes every uncommitted change, untracked files included, with
+// message. It reports false if there was nothing to stash.
+func Stash(message string) (bool, error) {
+	out, err := command("stash", "push", "--include-untracked", "-m", message).CombinedOutput()
+	if err != nil {
+		return false, fmt.Errorf("failed to stash changes: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return !strings.Contains(string(out), "No local changes to save"), nil
+}
+
+// StashPop restores the latest stash, keeping it if that conflicts.
+func StashPop() error {
+	if out, err := command("stash", "pop").CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to restore stashed changes: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
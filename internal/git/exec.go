@@ -0,0 +1,113 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/arpxspace/smartcommit/internal/logging"
+)
+
+// gitPath is the git executable, looked up once. On Windows this finds
+// git.exe on PATH; a git in the current directory is never run.
+var gitPath = sync.OnceValue(func() string {
+	if path, err := exec.LookPath("git"); err == nil {
+		return path
+	}
+	return "git"
+})
+
+// command returns a git command with args. Paths in its output aren't
+// quoted, so names with non-ASCII characters read as they are.
+func command(args ...string) *Cmd {
+	return &Cmd{Cmd: exec.Command(gitPath(), append([]string{"-c", "core.quotepath=off"}, args...)...)}
+}
+
+// Cmd is a git command whose errors say what git printed to stderr, and
+// whose runs are logged.
+type Cmd struct {
+	*exec.Cmd
+}
+
+// Error is a git command that failed, with what it printed to stderr.
+type Error struct {
+	Err    error
+	Stderr string
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error() + ": " + e.Stderr
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (c *Cmd) Output() ([]byte, error) {
+	start := time.Now()
+	out, err := c.Cmd.Output()
+	var stderr []byte
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		stderr = exitErr.Stderr
+	}
+	return out, c.done(start, err, stderr)
+}
+
+func (c *Cmd) Run() error {
+	var stderr bytes.Buffer
+	if c.Stderr == nil {
+		c.Stderr = &stderr
+	}
+	start := time.Now()
+	err := c.Cmd.Run()
+	return c.done(start, err, stderr.Bytes())
+}
+
+// CombinedOutput returns what git printed to stdout and stderr; as that
+// holds any error message already, the error doesn't repeat it.
+func (c *Cmd) CombinedOutput() ([]byte, error) {
+	start := time.Now()
+	out, err := c.Cmd.CombinedOutput()
+	c.done(start, err, nil)
+	return out, err
+}
+
+// done logs the finished command and attaches stderr to its error.
+func (c *Cmd) done(start time.Time, err error, stderr []byte) error {
+	msg := strings.TrimSpace(string(stderr))
+	if logging.Enabled(slog.LevelDebug) {
+		attrs := []any{"args", strings.Join(c.Args[3:], " "), "duration", time.Since(start).Round(time.Millisecond)}
+		if err != nil {
+			// Failures are often expected, like rev-parse outside a repo,
+			// so they're only logged at debug level too.
+			attrs = append(attrs, "err", err, "stderr", msg)
+		}
+		logging.Debug("git", attrs...)
+	}
+	if err == nil || msg == "" {
+		return err
+	}
+	return &Error{Err: err, Stderr: msg}
+}
+
+// shellCommand runs script through the shell, as git does for editors and
+// hooks, passing args as its positional parameters. Windows without a sh
+// on PATH, as when Git for Windows only adds git itself, uses cmd instead.
+func shellCommand(script string, args ...string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		if _, err := exec.LookPath("sh"); err != nil {
+			line := script
+			for _, a := range args {
+				line += ` "` + a + `"`
+			}
+			return exec.Command("cmd", "/C", line)
+		}
+	}
+	return exec.Command("sh", append([]string{"-c", script + ` "$@"`, script}, args...)...)
+}
@@ -1,19 +1,80 @@
 package git
 
 import (
+	"errors"
 	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
 )
 
-// IsRepo checks if the current directory is a git repository.
+// IsRepo checks if the current directory is in the working tree of a git
+// repository: a clone, a linked worktree, or a submodule. A bare repository,
+// or the .git directory itself, has no working tree to commit from.
 func IsRepo() bool {
-	cmd := exec.Command("git", "rev-parse", "--is-inside-work-tree")
-	return cmd.Run() == nil
+	return current().IsRepo()
+}
+
+func (execGit) IsRepo() bool {
+	out, err := command("rev-parse", "--is-inside-work-tree").Output()
+	return err == nil && strings.TrimSpace(string(out)) == "true"
+}
+
+// IsBare reports whether the current directory is a bare repository.
+func IsBare() bool {
+	out, err := command("rev-parse", "--is-bare-repository").Output()
+	return err == nil && strings.TrimSpace(string(out)) == "true"
+}
+
+// RepoRoot returns the absolute path of the top-level directory of the
+// working tree: of the worktree or submodule when in one, not of the main
+// repository or superproject.
+func RepoRoot() (string, error) {
+	return current().RepoRoot()
+}
+
+func (execGit) RepoRoot() (string, error) {
+	cmd := command("rev-parse", "--show-toplevel")
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to find repository root: %w", err)
+	}
+	// Git for Windows prints forward slashes.
+	return filepath.Clean(filepath.FromSlash(strings.TrimSpace(string(out)))), nil
+}
+
+// TopLevelDirs returns the directories at the root of HEAD's tree.
+func TopLevelDirs() ([]string, error) {
+	return current().TopLevelDirs()
 }
 
-// GetStagedDiff returns the diff of staged changes.
-func GetStagedDiff() (string, error) {
-	cmd := exec.Command("git", "diff", "--cached")
+func (execGit) TopLevelDirs() ([]string, error) {
+	cmd := command("ls-tree", "-d", "--name-only", "HEAD")
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list top-level directories: %w", err)
+	}
+	var dirs []string
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if line != "" {
+			dirs = append(dirs, line)
+		}
+	}
+	return dirs, nil
+}
+
+// GetStagedDiff returns the diff of staged changes. Extra arguments (e.g.
+// "-U10" or "-w") are passed through to git diff.
+func GetStagedDiff(args ...string) (string, error) {
+	return current().GetStagedDiff(args...)
+}
+
+func (execGit) GetStagedDiff(args ...string) (string, error) {
+	cmd := command(append([]string{"diff", "--cached"}, args...)...)
 	out, err := cmd.Output()
 	if err != nil {
 		return "", fmt.Errorf("failed to get staged diff: %w", err)
@@ -21,15 +82,54 @@ func GetStagedDiff() (string, error) {
 	return string(out), nil
 }
 
+// GetHeadFile returns the content of path as of HEAD, or "" if it doesn't exist there.
+func GetHeadFile(path string) (string, error) {
+	return current().GetHeadFile(path)
+}
+
+func (execGit) GetHeadFile(path string) (string, error) {
+	return showFile("HEAD:" + path)
+}
+
+// GetStagedFile returns the staged (index) content of path, or "" if it isn't staged.
+func GetStagedFile(path string) (string, error) {
+	return current().GetStagedFile(path)
+}
+
+func (execGit) GetStagedFile(path string) (string, error) {
+	return showFile(":" + path)
+}
+
+func showFile(spec string) (string, error) {
+	// Check existence first so a missing file isn't reported as an error.
+	if command("cat-file", "-e", spec).Run() != nil {
+		return "", nil
+	}
+	cmd := command("show", spec)
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", spec, err)
+	}
+	return string(out), nil
+}
+
 // GetRecentHistory returns the last n commit messages with their bodies.
 func GetRecentHistory(n int) (string, error) {
+	return current().GetRecentHistory(n)
+}
+
+func (e execGit) GetRecentHistory(n int) (string, error) {
+	if !e.HasHead() {
+		// The first commit has no history to follow.
+		return "", nil
+	}
 	// Format: Hash | Subject | Body
 	// We use a custom format to make parsing easier if needed, but for AI context, raw text is often fine.
 	// %h: abbreviated commit hash
 	// %s: subject
 	// %b: body
 	format := "Commit: %h\nSubject: %s\nBody:\n%b\n---"
-	cmd := exec.Command("git", "log", fmt.Sprintf("-n%d", n), fmt.Sprintf("--pretty=format:%s", format))
+	cmd := command("log", fmt.Sprintf("-n%d", n), fmt.Sprintf("--pretty=format:%s", format))
 	out, err := cmd.Output()
 	if err != nil {
 		return "", fmt.Errorf("failed to get git history: %w", err)
@@ -37,14 +137,530 @@ func GetRecentHistory(n int) (string, error) {
 	return string(out), nil
 }
 
+// Commit is a commit's hash and message.
+type Commit struct {
+	Hash    string
+	Subject string
+	Body    string
+	// Files are the paths the commit changed, when the log was read with
+	// them.
+	Files []string
+}
+
+// GetLog returns the last n commits reachable from HEAD, newest first.
+func GetLog(n int) ([]Commit, error) {
+	return current().GetLog(n)
+}
+
+func (e execGit) GetLog(n int) ([]Commit, error) {
+	if !e.HasHead() {
+		return nil, nil
+	}
+	// Fields are separated by a unit separator and commits by a record
+	// separator so that arbitrary message text can't break parsing.
+	cmd := command("log", fmt.Sprintf("-n%d", n), "--pretty=format:%H%x1f%s%x1f%b%x1e")
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get git log: %w", err)
+	}
+	return parseLog(string(out)), nil
+}
+
+// GetLogRange returns the commits in revRange (e.g. "v1.2.0..HEAD"), newest first.
+func GetLogRange(revRange string) ([]Commit, error) {
+	cmd := command("log", "--no-merges", "--pretty=format:%H%x1f%s%x1f%b%x1e", revRange, "--")
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get git log for %s: %w", revRange, err)
+	}
+	return parseLog(string(out)), nil
+}
+
+// GetLogFiles is GetLog with the paths each commit changed.
+func GetLogFiles(n int) ([]Commit, error) {
+	// The record separator leads each commit, since the paths follow it.
+	cmd := command("log", fmt.Sprintf("-n%d", n), "--name-only", "--pretty=format:%x1e%H%x1f%s%x1f%b%x1f")
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get git log: %w", err)
+	}
+	var commits []Commit
+	for _, record := range strings.Split(string(out), "\x1e") {
+		fields := strings.SplitN(record, "\x1f", 4)
+		if len(fields) < 4 {
+			continue
+		}
+		c := Commit{Hash: fields[0], Subject: fields[1], Body: strings.TrimSpace(fields[2])}
+		for _, line := range strings.Split(fields[3], "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				c.Files = append(c.Files, line)
+			}
+		}
+		commits = append(commits, c)
+	}
+	return commits, nil
+}
+
+// GetCommitsTouchingFiles returns up to n commits that changed any of paths,
+// newest first. Paths are relative to the repository root, and each file is
+// followed across renames.
+func GetCommitsTouchingFiles(paths []string, n int) ([]Commit, error) {
+	type dated struct {
+		Commit
+		time int64
+	}
+	var found []dated
+	seen := make(map[string]bool)
+	for _, path := range paths {
+		// --follow only works with a single path, so each is logged on its own.
+		cmd := command("log", "--follow", fmt.Sprintf("-n%d", n), "--pretty=format:%ct%x1f%H%x1f%s%x1f%b%x1e", "--", ":(top)"+path)
+		out, err := cmd.Output()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get git log for %s: %w", path, err)
+		}
+		for _, record := range strings.Split(string(out), "\x1e") {
+			ts, rest, ok := strings.Cut(strings.TrimLeft(record, "\n"), "\x1f")
+			if !ok {
+				continue
+			}
+			t, err := strconv.ParseInt(ts, 10, 64)
+			if err != nil {
+				continue
+			}
+			for _, c := range parseLog(rest) {
+				if !seen[c.Hash] {
+					seen[c.Hash] = true
+					found = append(found, dated{c, t})
+				}
+			}
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool { return found[i].time > found[j].time })
+
+	commits := make([]Commit, 0, min(len(found), n))
+	for _, d := range found[:min(len(found), n)] {
+		commits = append(commits, d.Commit)
+	}
+	return commits, nil
+}
+
+// FormatHistory lays out commits like GetRecentHistory.
+func FormatHistory(commits []Commit) string {
+	entries := make([]string, len(commits))
+	for i, c := range commits {
+		hash := c.Hash
+		if len(hash) > 7 {
+			hash = hash[:7]
+		}
+		body := c.Body
+		if body != "" {
+			body += "\n"
+		}
+		entries[i] = fmt.Sprintf("Commit: %s\nSubject: %s\nBody:\n%s\n---", hash, c.Subject, body)
+	}
+	return strings.Join(entries, "\n")
+}
+
+func parseLog(out string) []Commit {
+	var commits []Commit
+	for _, record := range strings.Split(out, "\x1e") {
+		fields := strings.SplitN(strings.TrimLeft(record, "\n"), "\x1f", 3)
+		if len(fields) < 3 {
+			continue
+		}
+		commits = append(commits, Commit{
+			Hash:    fields[0],
+			Subject: fields[1],
+			Body:    strings.TrimSpace(fields[2]),
+		})
+	}
+	return commits
+}
+
+// SetConfig sets a repository-local git config value.
+func SetConfig(key, value string) error {
+	cmd := command("config", key, value)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to set %s: %w: %s", key, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// CommentString returns what starts the comment lines git writes into
+// commit messages: core.commentString or core.commentChar, or "#" when
+// neither is set. "auto" is "#" too, as git only picks another character
+// when a line of the message already starts with "#".
+func CommentString() string {
+	for _, key := range []string{"core.commentString", "core.commentChar"} {
+		out, err := command("config", "--get", key).Output()
+		if err != nil {
+			continue
+		}
+		if s := strings.TrimSpace(string(out)); s != "" && s != "auto" {
+			return s
+		}
+		break
+	}
+	return "#"
+}
+
+// GetGlobalConfig returns a value from the user's global git config, or "" if unset.
+func GetGlobalConfig(key string) string {
+	out, err := command("config", "--global", "--get", key).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// SetGlobalConfig sets a value in the user's global git config.
+func SetGlobalConfig(key, value string) error {
+	cmd := command("config", "--global", key, value)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to set %s: %w: %s", key, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
 // CommitCmd returns the exec.Cmd for the git commit command with the given message.
 // It uses the -e flag to open the editor.
 // If message is empty, it runs 'git commit' without -m, opening the editor for a manual commit.
-func CommitCmd(message string) *exec.Cmd {
+// Extra arguments, such as --gpg-sign, are passed through to git commit.
+func CommitCmd(message string, args ...string) *exec.Cmd {
 	if message == "" {
-		return exec.Command("git", "commit")
+		// The user chose to write the message; don't let smartcommit's
+		// prepare-commit-msg hook fill it in.
+		cmd := command(append([]string{"commit"}, args...)...)
+		cmd.Env = append(os.Environ(), NoHookEnv+"=1")
+		return cmd.Cmd
+	}
+	return command(append([]string{"commit", "-e", "-m", message}, args...)...).Cmd
+}
+
+// NoHookEnv, when set in a commit's environment, stops smartcommit's
+// prepare-commit-msg hook from generating a message.
+const NoHookEnv = "SMARTCOMMIT_NO_HOOK"
+
+// CommitNoEditCmd returns the exec.Cmd that commits with message as-is,
+// reading it from stdin instead of opening the editor. Extra arguments are
+// passed through to git commit.
+func CommitNoEditCmd(message string, args ...string) *exec.Cmd {
+	cmd := command(append([]string{"commit", "--cleanup=strip", "-F", "-"}, args...)...)
+	cmd.Stdin = strings.NewReader(message)
+	return cmd.Cmd
+}
+
+// CommitWithMessage commits the staged changes with message without opening an editor.
+// Hooks run as usual; their output is included in the error if they fail.
+// Extra arguments are passed through to git commit.
+func CommitWithMessage(message string, args ...string) error {
+	return current().CommitWithMessage(message, args...)
+}
+
+func (execGit) CommitWithMessage(message string, args ...string) error {
+	cmd := &Cmd{Cmd: CommitNoEditCmd(message, args...)}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git commit failed: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// HasHead reports whether HEAD points at a commit, i.e. the branch isn't unborn.
+func HasHead() bool {
+	return current().HasHead()
+}
+
+func (execGit) HasHead() bool {
+	cmd := command("rev-parse", "--verify", "-q", "HEAD")
+	return cmd.Run() == nil
+}
+
+// UnstageAll removes every change from the index, leaving the working tree alone.
+func UnstageAll() error {
+	cmd := command("reset", "-q", "--")
+	if !HasHead() {
+		cmd = command("rm", "-r", "-q", "--cached", "--ignore-unmatch", ".")
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to unstage changes: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// ApplyCached applies patch to the index only, as `git add -p` does for
+// the hunks it's given.
+func ApplyCached(patch string) error {
+	cmd := command("apply", "--cached", "-")
+	cmd.Stdin = strings.NewReader(patch)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to stage hunks: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// UnapplyCached removes patch from the index, leaving the working tree alone.
+func UnapplyCached(patch string) error {
+	cmd := command("apply", "--cached", "-R", "-")
+	cmd.Stdin = strings.NewReader(patch)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to unstage hunks: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// GetUnstagedDiff returns the diff of changes in the working tree that
+// haven't been staged.
+func GetUnstagedDiff() (string, error) {
+	return current().GetUnstagedDiff()
+}
+
+func (execGit) GetUnstagedDiff() (string, error) {
+	cmd := command("diff")
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get unstaged diff: %w", err)
+	}
+	return string(out), nil
+}
+
+// UntrackedFiles lists files git doesn't track and doesn't ignore.
+func UntrackedFiles() ([]string, error) {
+	return current().UntrackedFiles()
+}
+
+func (execGit) UntrackedFiles() ([]string, error) {
+	cmd := command("ls-files", "--others", "--exclude-standard", "-z")
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list untracked files: %w", err)
+	}
+	var files []string
+	for _, f := range strings.Split(string(out), "\x00") {
+		if f != "" {
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
+
+// StageAll stages every change in the working tree, including untracked
+// files and deletions.
+func StageAll() error {
+	cmd := command("add", "-A")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to stage changes: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// StagePaths stages every change to paths, including deletions.
+func StagePaths(paths ...string) error {
+	cmd := command(append([]string{"add", "-A", "--"}, paths...)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to stage files: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// UnstagePaths removes the staged changes to paths from the index.
+func UnstagePaths(paths ...string) error {
+	cmd := command(append([]string{"reset", "-q", "--"}, paths...)...)
+	if !HasHead() {
+		cmd = command(append([]string{"rm", "-r", "-q", "--cached", "--"}, paths...)...)
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to unstage files: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// HeadCommit returns the full hash of HEAD.
+func HeadCommit() (string, error) {
+	return current().HeadCommit()
+}
+
+func (execGit) HeadCommit() (string, error) {
+	cmd := command("rev-parse", "HEAD")
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// IsDetachedHead reports whether HEAD points at a commit rather than a
+// branch, as during a rebase or after checking out a tag.
+func IsDetachedHead() (bool, error) {
+	branch, err := CurrentBranch()
+	return err == nil && branch == "", err
+}
+
+// CurrentBranch returns the short name of the checked-out branch, or "" when
+// HEAD is detached.
+func CurrentBranch() (string, error) {
+	return current().CurrentBranch()
+}
+
+func (execGit) CurrentBranch() (string, error) {
+	cmd := command("symbolic-ref", "--short", "-q", "HEAD")
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to read current branch: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// UserIdent returns the configured author identity, "Name <email>".
+func UserIdent() (string, error) {
+	return current().UserIdent()
+}
+
+func (execGit) UserIdent() (string, error) {
+	name, _ := command("config", "user.name").Output()
+	email, _ := command("config", "user.email").Output()
+	if strings.TrimSpace(string(name)) == "" || strings.TrimSpace(string(email)) == "" {
+		return "", fmt.Errorf("user.name and user.email must be set in git config to sign off")
+	}
+	return fmt.Sprintf("%s <%s>", strings.TrimSpace(string(name)), strings.TrimSpace(string(email))), nil
+}
+
+// RecentAuthors returns the distinct authors of the last n commits as
+// "Name <email>", most recent first, honoring .mailmap.
+func RecentAuthors(n int) ([]string, error) {
+	out, err := command("log", fmt.Sprintf("-n%d", n), "--pretty=format:%aN <%aE>").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list recent authors: %w", err)
+	}
+	var authors []string
+	seen := make(map[string]bool)
+	for _, a := range strings.Split(string(out), "\n") {
+		if a = strings.TrimSpace(a); a != "" && !seen[a] {
+			seen[a] = true
+			authors = append(authors, a)
+		}
+	}
+	return authors, nil
+}
+
+// RemoteURL returns the URL of the named remote, or "" if there's no such
+// remote.
+func RemoteURL(name string) string {
+	return current().RemoteURL(name)
+}
+
+func (execGit) RemoteURL(name string) string {
+	cmd := command("remote", "get-url", name)
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// HeadMessage returns the full commit message of HEAD.
+func HeadMessage() (string, error) {
+	return current().HeadMessage()
+}
+
+func (execGit) HeadMessage() (string, error) {
+	cmd := command("log", "-1", "--format=%B")
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to read HEAD message: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// AddNote attaches note to rev under refs/notes/<ref>, replacing any existing note.
+func AddNote(ref, rev, note string) error {
+	cmd := command("notes", "--ref="+ref, "add", "-f", "-m", note, rev)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to add note: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// Editor returns the editor git opens for commit messages, chosen as git
+// does from $GIT_EDITOR, core.editor, $VISUAL, and $EDITOR, falling back to
+// vi.
+func Editor() (string, error) {
+	out, err := command("var", "GIT_EDITOR").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine editor: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// HasEditor reports whether git's editor can be run: the program it starts
+// with is installed. Editors given as shell builtins, like ":", count.
+func HasEditor() bool {
+	editor, err := Editor()
+	if err != nil || editor == "" {
+		return false
+	}
+	program := strings.Trim(strings.Fields(editor)[0], `"'`)
+	if program == ":" || program == "true" {
+		return true
+	}
+	_, err = exec.LookPath(program)
+	return err == nil
+}
+
+// EditMessage opens the user's git editor on message and returns the result
+// with comment lines and surrounding whitespace removed.
+func EditMessage(message string) (string, error) {
+	editor, err := Editor()
+	if err != nil {
+		return "", err
+	}
+
+	f, err := os.CreateTemp("", "smartcommit-*.txt")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(message + "\n"); err != nil {
+		f.Close()
+		return "", err
+	}
+	f.Close()
+
+	// Run through the shell like git does, since GIT_EDITOR may contain arguments.
+	cmd := shellCommand(editor, f.Name())
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("editor failed: %w", err)
 	}
-	return exec.Command("git", "commit", "-e", "-m", message)
+
+	data, err := os.ReadFile(f.Name())
+	if err != nil {
+		return "", err
+	}
+	var lines []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if !strings.HasPrefix(line, "#") {
+			lines = append(lines, strings.TrimRight(line, " \t\r"))
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n")), nil
+}
+
+// EditFile opens the user's git editor on the file at path.
+func EditFile(path string) error {
+	editor, err := Editor()
+	if err != nil {
+		return err
+	}
+	cmd := shellCommand(editor, path)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("editor failed: %w", err)
+	}
+	return nil
 }
 
 // GetStagedDiffSize returns the approximate number of characters in the staged diff.
@@ -56,3 +672,45 @@ func GetStagedDiffSize() (int, error) {
 	}
 	return len(diff), nil
 }
+
+// HookPath returns the path of the named hook, honoring core.hooksPath.
+func HookPath(name string) (string, error) {
+	cmd := command("rev-parse", "--git-path", "hooks/"+name)
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate %s hook: %w", name, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// CommitHooks returns which of the hooks that can reject a commit,
+// pre-commit and commit-msg, are installed.
+func CommitHooks() []string {
+	var hooks []string
+	for _, name := range []string{"pre-commit", "commit-msg"} {
+		path, err := HookPath(name)
+		if err != nil {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		// Git skips hooks that aren't executable, except on Windows.
+		if runtime.GOOS == "windows" || info.Mode()&0o111 != 0 {
+			hooks = append(hooks, name)
+		}
+	}
+	return hooks
+}
+
+// HookRejected reports whether err, from a git commit that printed output
+// to stderr, means one of hooks rejected the commit: git exits with status
+// 1 then, and has nothing of its own to say.
+func HookRejected(err error, output string, hooks []string) bool {
+	var exitErr *exec.ExitError
+	if len(hooks) == 0 || !errors.As(err, &exitErr) || exitErr.ExitCode() != 1 {
+		return false
+	}
+	return !strings.Contains(output, "Aborting commit")
+}
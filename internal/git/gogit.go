@@ -0,0 +1,596 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	gogit "github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	fdiff "github.com/go-git/go-git/v5/plumbing/format/diff"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/utils/binary"
+	"github.com/go-git/go-git/v5/utils/diff"
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// GoGitBackend reads and writes the repository with go-git, which is built
+// in, for containers and CI images without git. Its diffs take no git diff
+// options and don't detect renames, and its commits aren't signed and
+// don't run hooks.
+func GoGitBackend() Backend {
+	return goGit{}
+}
+
+// goGit is the Backend that uses go-git.
+type goGit struct{}
+
+// contextLines is how many unchanged lines surround each hunk, as git's
+// default.
+const contextLines = 3
+
+// needsGit is the error for options only git understands.
+func needsGit(command string, args []string) error {
+	return fmt.Errorf("git %s %s needs git; set git_backend to exec", command, strings.Join(args, " "))
+}
+
+// open opens the repository the current directory is in.
+func (goGit) open() (*gogit.Repository, error) {
+	r, err := gogit.PlainOpenWithOptions(".", &gogit.PlainOpenOptions{DetectDotGit: true, EnableDotGitCommonDir: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open repository: %w", err)
+	}
+	return r, nil
+}
+
+// headTree returns the tree of HEAD, or nil when the branch is unborn.
+func headTree(r *gogit.Repository) (*object.Tree, error) {
+	ref, err := r.Head()
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+	commit, err := r.CommitObject(ref.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read HEAD: %w", err)
+	}
+	return commit.Tree()
+}
+
+func (g goGit) IsRepo() bool {
+	r, err := g.open()
+	if err != nil {
+		return false
+	}
+	_, err = r.Worktree()
+	return err == nil
+}
+
+func (g goGit) RepoRoot() (string, error) {
+	r, err := g.open()
+	if err != nil {
+		return "", fmt.Errorf("failed to find repository root: %w", err)
+	}
+	wt, err := r.Worktree()
+	if err != nil {
+		return "", fmt.Errorf("failed to find repository root: %w", err)
+	}
+	return filepath.Clean(wt.Filesystem.Root()), nil
+}
+
+func (g goGit) TopLevelDirs() ([]string, error) {
+	r, err := g.open()
+	if err != nil {
+		return nil, err
+	}
+	tree, err := headTree(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list top-level directories: %w", err)
+	}
+	if tree == nil {
+		return nil, nil
+	}
+	var dirs []string
+	for _, e := range tree.Entries {
+		if e.Mode == filemode.Dir {
+			dirs = append(dirs, e.Name)
+		}
+	}
+	return dirs, nil
+}
+
+// version is a file's content as of HEAD, the index, or the working tree.
+type version struct {
+	hash    plumbing.Hash
+	mode    filemode.FileMode
+	content string
+}
+
+func (g goGit) GetStagedDiff(args ...string) (string, error) {
+	if len(args) > 0 {
+		return "", needsGit("diff", args)
+	}
+	r, err := g.open()
+	if err != nil {
+		return "", err
+	}
+	idx, err := r.Storer.Index()
+	if err != nil {
+		return "", fmt.Errorf("failed to read the index: %w", err)
+	}
+	head := make(map[string]version)
+	tree, err := headTree(r)
+	if err != nil {
+		return "", err
+	}
+	if tree != nil {
+		err := tree.Files().ForEach(func(f *object.File) error {
+			head[f.Name] = version{hash: f.Hash, mode: f.Mode}
+			return nil
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to read HEAD: %w", err)
+		}
+	}
+	staged := make(map[string]version, len(idx.Entries))
+	for _, e := range idx.Entries {
+		staged[e.Name] = version{hash: e.Hash, mode: e.Mode}
+	}
+
+	var patches []fdiff.FilePatch
+	for _, path := range changedPaths(head, staged) {
+		from, to, err := loadVersions(r, head[path], staged[path])
+		if err != nil {
+			return "", fmt.Errorf("failed to get staged diff: %w", err)
+		}
+		patches = append(patches, newFilePatch(path, from, to))
+	}
+	return encode(patches)
+}
+
+func (g goGit) GetUnstagedDiff() (string, error) {
+	r, err := g.open()
+	if err != nil {
+		return "", err
+	}
+	wt, err := r.Worktree()
+	if err != nil {
+		return "", err
+	}
+	idx, err := r.Storer.Index()
+	if err != nil {
+		return "", fmt.Errorf("failed to read the index: %w", err)
+	}
+	var patches []fdiff.FilePatch
+	for _, e := range idx.Entries {
+		to, err := worktreeVersion(wt.Filesystem.Root(), e.Name)
+		if err != nil {
+			return "", fmt.Errorf("failed to get unstaged diff: %w", err)
+		}
+		if to != nil && to.hash == e.Hash && to.mode == e.Mode {
+			continue
+		}
+		from, _, err := loadVersions(r, version{hash: e.Hash, mode: e.Mode}, version{})
+		if err != nil {
+			return "", fmt.Errorf("failed to get unstaged diff: %w", err)
+		}
+		patches = append(patches, newFilePatch(e.Name, from, to))
+	}
+	return encode(patches)
+}
+
+// worktreeVersion reads path from the working tree at root, or returns nil
+// if it's been deleted.
+func worktreeVersion(root, path string) (*version, error) {
+	name := filepath.Join(root, filepath.FromSlash(path))
+	info, err := os.Lstat(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	mode, err := filemode.NewFromOSFileMode(info.Mode())
+	if err != nil {
+		return nil, err
+	}
+	var content string
+	if info.Mode()&os.ModeSymlink != 0 {
+		content, err = os.Readlink(name)
+	} else {
+		var data []byte
+		data, err = os.ReadFile(name)
+		content = string(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &version{hash: plumbing.ComputeHash(plumbing.BlobObject, []byte(content)), mode: mode, content: content}, nil
+}
+
+// changedPaths returns the paths whose versions differ between from and to,
+// sorted as git sorts them.
+func changedPaths(from, to map[string]version) []string {
+	var paths []string
+	for path, v := range from {
+		if w, ok := to[path]; !ok || w.hash != v.hash || w.mode != v.mode {
+			paths = append(paths, path)
+		}
+	}
+	for path := range to {
+		if _, ok := from[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// loadVersions reads the contents of from and to, returning nil for one
+// without a hash: the file doesn't exist on that side.
+func loadVersions(r *gogit.Repository, from, to version) (*version, *version, error) {
+	load := func(v version) (*version, error) {
+		if v.hash.IsZero() {
+			return nil, nil
+		}
+		if v.mode == filemode.Submodule {
+			// A submodule's commit isn't in this repository.
+			v.content = "Subproject commit " + v.hash.String() + "\n"
+			return &v, nil
+		}
+		blob, err := r.BlobObject(v.hash)
+		if err != nil {
+			return nil, err
+		}
+		rd, err := blob.Reader()
+		if err != nil {
+			return nil, err
+		}
+		defer rd.Close()
+		data, err := io.ReadAll(rd)
+		if err != nil {
+			return nil, err
+		}
+		v.content = string(data)
+		return &v, nil
+	}
+	a, err := load(from)
+	if err != nil {
+		return nil, nil, err
+	}
+	b, err := load(to)
+	return a, b, err
+}
+
+// indexLine matches the hashes of an index line in a diff header.
+var indexLine = regexp.MustCompile(`(?m)^index ([0-9a-f]{7})[0-9a-f]{33}\.\.([0-9a-f]{7})[0-9a-f]{33}`)
+
+// encode writes patches as git diff does, with the hashes abbreviated.
+func encode(patches []fdiff.FilePatch) (string, error) {
+	var b strings.Builder
+	if err := fdiff.NewUnifiedEncoder(&b, contextLines).Encode(patch(patches)); err != nil {
+		return "", err
+	}
+	return indexLine.ReplaceAllString(b.String(), "index $1..$2"), nil
+}
+
+// newFilePatch compares two versions of the file at path, either of which
+// may be nil for a file added or deleted.
+func newFilePatch(path string, from, to *version) *filePatch {
+	p := &filePatch{path: path, from: from, to: to}
+	var a, b string
+	if from != nil {
+		a = from.content
+	}
+	if to != nil {
+		b = to.content
+	}
+	if isBinary(a) || isBinary(b) {
+		p.binary = true
+		return p
+	}
+	for _, d := range diff.Do(a, b) {
+		op := fdiff.Equal
+		switch d.Type {
+		case diffmatchpatch.DiffDelete:
+			op = fdiff.Delete
+		case diffmatchpatch.DiffInsert:
+			op = fdiff.Add
+		}
+		p.chunks = append(p.chunks, chunk{content: d.Text, op: op})
+	}
+	return p
+}
+
+func isBinary(content string) bool {
+	b, _ := binary.IsBinary(strings.NewReader(content))
+	return b
+}
+
+type patch []fdiff.FilePatch
+
+func (p patch) FilePatches() []fdiff.FilePatch { return p }
+func (p patch) Message() string                { return "" }
+
+type filePatch struct {
+	path     string
+	from, to *version
+	binary   bool
+	chunks   []fdiff.Chunk
+}
+
+func (p *filePatch) IsBinary() bool        { return p.binary }
+func (p *filePatch) Chunks() []fdiff.Chunk { return p.chunks }
+
+func (p *filePatch) Files() (from, to fdiff.File) {
+	// A missing side must be a nil interface, not a nil *patchFile.
+	if p.from != nil {
+		from = patchFile{p.path, p.from}
+	}
+	if p.to != nil {
+		to = patchFile{p.path, p.to}
+	}
+	return from, to
+}
+
+type patchFile struct {
+	path string
+	v    *version
+}
+
+func (f patchFile) Hash() plumbing.Hash     { return f.v.hash }
+func (f patchFile) Mode() filemode.FileMode { return f.v.mode }
+func (f patchFile) Path() string            { return f.path }
+
+type chunk struct {
+	content string
+	op      fdiff.Operation
+}
+
+func (c chunk) Content() string       { return c.content }
+func (c chunk) Type() fdiff.Operation { return c.op }
+
+func (g goGit) GetHeadFile(path string) (string, error) {
+	r, err := g.open()
+	if err != nil {
+		return "", err
+	}
+	tree, err := headTree(r)
+	if err != nil || tree == nil {
+		return "", err
+	}
+	f, err := tree.File(path)
+	if errors.Is(err, object.ErrFileNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read HEAD:%s: %w", path, err)
+	}
+	return f.Contents()
+}
+
+func (g goGit) GetStagedFile(path string) (string, error) {
+	r, err := g.open()
+	if err != nil {
+		return "", err
+	}
+	idx, err := r.Storer.Index()
+	if err != nil {
+		return "", fmt.Errorf("failed to read the index: %w", err)
+	}
+	e, err := idx.Entry(path)
+	if err != nil {
+		return "", nil
+	}
+	v, _, err := loadVersions(r, version{hash: e.Hash, mode: e.Mode}, version{})
+	if err != nil {
+		return "", fmt.Errorf("failed to read :%s: %w", path, err)
+	}
+	return v.content, nil
+}
+
+func (g goGit) UntrackedFiles() ([]string, error) {
+	r, err := g.open()
+	if err != nil {
+		return nil, err
+	}
+	wt, err := r.Worktree()
+	if err != nil {
+		return nil, err
+	}
+	status, err := wt.Status()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list untracked files: %w", err)
+	}
+	var files []string
+	for path, s := range status {
+		if s.Worktree == gogit.Untracked {
+			files = append(files, path)
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func (g goGit) GetRecentHistory(n int) (string, error) {
+	commits, err := g.GetLog(n)
+	if err != nil {
+		return "", fmt.Errorf("failed to get git history: %w", err)
+	}
+	return FormatHistory(commits), nil
+}
+
+func (g goGit) GetLog(n int) ([]Commit, error) {
+	r, err := g.open()
+	if err != nil {
+		return nil, err
+	}
+	if !g.HasHead() {
+		return nil, nil
+	}
+	iter, err := r.Log(&gogit.LogOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get git log: %w", err)
+	}
+	defer iter.Close()
+	var commits []Commit
+	for len(commits) < n {
+		c, err := iter.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get git log: %w", err)
+		}
+		subject, body := splitMessage(c.Message)
+		commits = append(commits, Commit{Hash: c.Hash.String(), Subject: subject, Body: body})
+	}
+	return commits, nil
+}
+
+// splitMessage splits a commit message as git log's %s and %b do: the
+// first paragraph, on one line, and the rest.
+func splitMessage(message string) (subject, body string) {
+	subject, body, _ = strings.Cut(strings.TrimLeft(message, "\n"), "\n\n")
+	return strings.Join(strings.Fields(subject), " "), strings.TrimSpace(body)
+}
+
+func (g goGit) HasHead() bool {
+	r, err := g.open()
+	if err != nil {
+		return false
+	}
+	_, err = r.Head()
+	return err == nil
+}
+
+func (g goGit) HeadCommit() (string, error) {
+	r, err := g.open()
+	if err != nil {
+		return "", err
+	}
+	ref, err := r.Head()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+	return ref.Hash().String(), nil
+}
+
+func (g goGit) HeadMessage() (string, error) {
+	r, err := g.open()
+	if err != nil {
+		return "", err
+	}
+	ref, err := r.Head()
+	if err != nil {
+		return "", fmt.Errorf("failed to read HEAD message: %w", err)
+	}
+	c, err := r.CommitObject(ref.Hash())
+	if err != nil {
+		return "", fmt.Errorf("failed to read HEAD message: %w", err)
+	}
+	return strings.TrimSpace(c.Message), nil
+}
+
+func (g goGit) CurrentBranch() (string, error) {
+	r, err := g.open()
+	if err != nil {
+		return "", fmt.Errorf("failed to read current branch: %w", err)
+	}
+	ref, err := r.Reference(plumbing.HEAD, false)
+	if err != nil {
+		return "", fmt.Errorf("failed to read current branch: %w", err)
+	}
+	if ref.Type() != plumbing.SymbolicReference {
+		return "", nil
+	}
+	return ref.Target().Short(), nil
+}
+
+func (g goGit) UserIdent() (string, error) {
+	r, err := g.open()
+	if err != nil {
+		return "", err
+	}
+	cfg, err := r.ConfigScoped(gitconfig.SystemScope)
+	if err != nil {
+		return "", fmt.Errorf("failed to read git config: %w", err)
+	}
+	if cfg.User.Name == "" || cfg.User.Email == "" {
+		return "", fmt.Errorf("user.name and user.email must be set in git config to sign off")
+	}
+	return fmt.Sprintf("%s <%s>", cfg.User.Name, cfg.User.Email), nil
+}
+
+func (g goGit) RemoteURL(name string) string {
+	r, err := g.open()
+	if err != nil {
+		return ""
+	}
+	remote, err := r.Remote(name)
+	if err != nil || len(remote.Config().URLs) == 0 {
+		return ""
+	}
+	return remote.Config().URLs[0]
+}
+
+func (g goGit) CommitWithMessage(message string, args ...string) error {
+	for _, arg := range args {
+		// go-git never signs, so there's nothing to turn off.
+		if arg != "--no-gpg-sign" {
+			return needsGit("commit", args)
+		}
+	}
+	message = cleanupMessage(message)
+	if message == "" {
+		return errors.New("git commit failed: the message is empty")
+	}
+	r, err := g.open()
+	if err != nil {
+		return err
+	}
+	wt, err := r.Worktree()
+	if err != nil {
+		return err
+	}
+	if _, err := wt.Commit(message, &gogit.CommitOptions{}); err != nil {
+		return fmt.Errorf("git commit failed: %w", err)
+	}
+	return nil
+}
+
+// cleanupMessage strips message as git commit --cleanup=strip does: comment
+// lines, trailing whitespace, and leading, trailing, and repeated blank
+// lines go.
+func cleanupMessage(message string) string {
+	var lines []string
+	blank := false
+	for _, line := range strings.Split(message, "\n") {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" {
+			blank = len(lines) > 0
+			continue
+		}
+		if blank {
+			lines = append(lines, "")
+			blank = false
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
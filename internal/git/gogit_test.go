@@ -0,0 +1,175 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newRepo creates a repository in a temporary directory, changes into it,
+// and returns a function running git there.
+func newRepo(t *testing.T) func(args ...string) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git isn't installed")
+	}
+	dir := t.TempDir()
+	t.Chdir(dir)
+	// Keep the user's and the system's git config out of it.
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	run := func(args ...string) string {
+		t.Helper()
+		out, err := exec.Command("git", args...).CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %s: %v: %s", strings.Join(args, " "), err, out)
+		}
+		return string(out)
+	}
+	run("init", "-q", "-b", "main")
+	run("config", "user.name", "Ada Lovelace")
+	run("config", "user.email", "ada@example.com")
+	run("config", "commit.gpgsign", "false")
+	return run
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// lines returns n numbered lines, for files long enough to diff in hunks.
+func lines(n int) string {
+	var b strings.Builder
+	for i := 1; i <= n; i++ {
+		b.WriteString("line " + strings.Repeat("x", i%5) + string(rune('a'+i%26)) + "\n")
+	}
+	return b.String()
+}
+
+// sameResult fails t unless both backends return the same from call.
+func sameResult[T any](t *testing.T, name string, call func(Backend) (T, error)) {
+	t.Helper()
+	want, wantErr := call(ExecBackend())
+	got, gotErr := call(GoGitBackend())
+	if (wantErr == nil) != (gotErr == nil) {
+		t.Fatalf("%s: go-git error %v, exec error %v", name, gotErr, wantErr)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("%s: go-git returned\n%v\nexec returned\n%v", name, got, want)
+	}
+}
+
+func TestGoGitMatchesExec(t *testing.T) {
+	run := newRepo(t)
+
+	t.Run("unborn branch", func(t *testing.T) {
+		writeFile(t, "README.md", "# Project\n")
+		run("add", "README.md")
+		if ExecBackend().HasHead() || GoGitBackend().HasHead() {
+			t.Fatal("HasHead on an unborn branch")
+		}
+		sameResult(t, "GetStagedDiff", func(b Backend) (string, error) { return b.GetStagedDiff() })
+		sameResult(t, "GetLog", func(b Backend) ([]Commit, error) { return b.GetLog(5) })
+		sameResult(t, "GetRecentHistory", func(b Backend) (string, error) { return b.GetRecentHistory(5) })
+		sameResult(t, "CurrentBranch", func(b Backend) (string, error) { return b.CurrentBranch() })
+	})
+
+	run("commit", "-q", "-m", "chore: start")
+	writeFile(t, "main.go", "package main\n\nfunc main() {}\n")
+	writeFile(t, "long.txt", lines(40))
+	writeFile(t, "gone.txt", "delete me\n")
+	writeFile(t, "cmd/tool/tool.go", "package tool\n")
+	run("add", "-A")
+	run("commit", "-q", "-m", "feat: add the tool\n\nIt does things\nacross two lines.\n\nAnd a second paragraph.")
+	run("remote", "add", "origin", "git@github.com:example/project.git")
+
+	// Stage a modification in two hunks, an addition, a deletion, a mode
+	// change, and a binary file, and leave other changes unstaged.
+	long := strings.Split(lines(40), "\n")
+	long[2] = "changed near the top"
+	long[35] = "changed near the bottom"
+	writeFile(t, "long.txt", strings.Join(long, "\n"))
+	writeFile(t, "new.txt", "brand new\n")
+	writeFile(t, "image.bin", "\x00\x01\x02binary")
+	run("rm", "-q", "gone.txt")
+	run("add", "-A")
+	run("update-index", "--chmod=+x", "main.go")
+	writeFile(t, "main.go", "package main\n\nfunc main() { println() }\n")
+	writeFile(t, "untracked.txt", "not added\n")
+
+	t.Run("reads", func(t *testing.T) {
+		// Both backends failing alike would match too.
+		diff, err := GoGitBackend().GetStagedDiff()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range []string{
+			"@@ -1,6 +1,6 @@", "@@ -33,7 +33,7 @@", "+brand new", "deleted file mode 100644",
+			"new mode 100755", "Binary files /dev/null and b/image.bin differ",
+		} {
+			if !strings.Contains(diff, want) {
+				t.Errorf("staged diff lacks %q:\n%s", want, diff)
+			}
+		}
+		sameResult(t, "IsRepo", func(b Backend) (bool, error) { return b.IsRepo(), nil })
+		sameResult(t, "RepoRoot", func(b Backend) (string, error) { return b.RepoRoot() })
+		sameResult(t, "TopLevelDirs", func(b Backend) ([]string, error) { return b.TopLevelDirs() })
+		sameResult(t, "GetStagedDiff", func(b Backend) (string, error) { return b.GetStagedDiff() })
+		sameResult(t, "GetUnstagedDiff", func(b Backend) (string, error) { return b.GetUnstagedDiff() })
+		sameResult(t, "UntrackedFiles", func(b Backend) ([]string, error) { return b.UntrackedFiles() })
+		sameResult(t, "GetHeadFile", func(b Backend) (string, error) { return b.GetHeadFile("long.txt") })
+		sameResult(t, "GetHeadFile missing", func(b Backend) (string, error) { return b.GetHeadFile("new.txt") })
+		sameResult(t, "GetStagedFile", func(b Backend) (string, error) { return b.GetStagedFile("new.txt") })
+		sameResult(t, "GetLog", func(b Backend) ([]Commit, error) { return b.GetLog(5) })
+		sameResult(t, "GetRecentHistory", func(b Backend) (string, error) { return b.GetRecentHistory(5) })
+		sameResult(t, "HasHead", func(b Backend) (bool, error) { return b.HasHead(), nil })
+		sameResult(t, "HeadCommit", func(b Backend) (string, error) { return b.HeadCommit() })
+		sameResult(t, "HeadMessage", func(b Backend) (string, error) { return b.HeadMessage() })
+		sameResult(t, "CurrentBranch", func(b Backend) (string, error) { return b.CurrentBranch() })
+		sameResult(t, "UserIdent", func(b Backend) (string, error) { return b.UserIdent() })
+		sameResult(t, "RemoteURL", func(b Backend) (string, error) { return b.RemoteURL("origin"), nil })
+	})
+
+	t.Run("commit", func(t *testing.T) {
+		tree := strings.TrimSpace(run("write-tree"))
+		parent := strings.TrimSpace(run("rev-parse", "HEAD"))
+		message := "fix: handle the edge case  \n\n\n# a comment git strips\nThe body.\n\n"
+		if err := GoGitBackend().CommitWithMessage(message, "--no-gpg-sign"); err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.TrimSpace(run("rev-parse", "HEAD^{tree}")); got != tree {
+			t.Errorf("committed tree %s, want the index's %s", got, tree)
+		}
+		if got := strings.TrimSpace(run("rev-parse", "HEAD^")); got != parent {
+			t.Errorf("committed on %s, want %s", got, parent)
+		}
+		if got, want := run("log", "-1", "--format=%an <%ae>%n%B"), "Ada Lovelace <ada@example.com>\nfix: handle the edge case\n\nThe body.\n\n"; got != want {
+			t.Errorf("committed\n%q\nwant\n%q", got, want)
+		}
+		if got := run("diff", "--cached", "--name-only"); got != "" {
+			t.Errorf("still staged after the commit: %s", got)
+		}
+		run("fsck", "--strict", "--no-progress")
+		sameResult(t, "GetLog after commit", func(b Backend) ([]Commit, error) { return b.GetLog(5) })
+		sameResult(t, "HeadMessage after commit", func(b Backend) (string, error) { return b.HeadMessage() })
+	})
+
+	t.Run("git-only options", func(t *testing.T) {
+		if _, err := GoGitBackend().GetStagedDiff("-U5"); err == nil {
+			t.Error("GetStagedDiff(-U5) succeeded")
+		}
+		if err := GoGitBackend().CommitWithMessage("fix: x", "--signoff"); err == nil {
+			t.Error("CommitWithMessage(--signoff) succeeded")
+		}
+	})
+}
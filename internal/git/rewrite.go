@@ -0,0 +1,366 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ResolveCommit returns the full hash of the commit rev refers to.
+func ResolveCommit(rev string) (string, error) {
+	cmd := command("rev-parse", "--verify", "--quiet", rev+"^{commit}")
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("%s is not a commit", rev)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// CommitMessage returns the full message of the given commit.
+func CommitMessage(rev string) (string, error) {
+	cmd := command("log", "-1", "--format=%B", rev)
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to read message of %s: %w", rev, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// CommitDiff returns the patch a commit introduced.
+func CommitDiff(rev string) (string, error) {
+	cmd := command("show", "--format=", "--patch", rev)
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get diff of %s: %w", rev, err)
+	}
+	return string(out), nil
+}
+
+// emptyTree is the hash of git's empty tree, the base of a root commit.
+const emptyTree = "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+
+// AmendDiff returns the change HEAD will make once amended with what's
+// staged: the diff from HEAD's parent, or the empty tree for a root commit,
+// to the index. Extra arguments are passed through to git diff.
+func AmendDiff(args ...string) (string, error) {
+	base := "HEAD~1"
+	if command("rev-parse", "--verify", "-q", "HEAD~1").Run() != nil {
+		base = emptyTree
+	}
+	cmd := command(append(append([]string{"diff", "--cached"}, args...), base)...)
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get amended diff: %w", err)
+	}
+	return string(out), nil
+}
+
+// Amend replaces HEAD with a commit of the index and message. Hooks run as
+// usual; their output is included in the error if they fail. Extra
+// arguments, such as --gpg-sign, are passed through to git commit.
+func Amend(message string, args ...string) error {
+	cmd := command(append([]string{"commit", "--amend", "--allow-empty", "--cleanup=strip", "-F", "-"}, args...)...)
+	cmd.Stdin = strings.NewReader(message)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to amend HEAD: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// IsPushed reports whether any remote-tracking branch contains the commit.
+func IsPushed(rev string) (bool, error) {
+	cmd := command("branch", "-r", "--contains", rev)
+	out, err := cmd.Output()
+	if err != nil {
+		return false, fmt.Errorf("failed to check remote branches: %w", err)
+	}
+	return strings.TrimSpace(string(out)) != "", nil
+}
+
+// Reword replaces the message of commit sha, which must be HEAD or one of
+// its ancestors, without touching the index or working tree.
+//
+// HEAD is amended with `git commit --amend --only`, so hooks and signing
+// behave as usual. Older commits are rewritten with plumbing: the target is
+// recreated with the new message and each descendant is replayed on top with
+// its original tree, author, and message, after which the current branch is
+// moved to the new tip. Merge commits in the range aren't supported.
+//
+// args are git commit's signing arguments, --gpg-sign or --no-gpg-sign,
+// and every commit rewritten is signed as a new commit would be with them,
+// so signing doesn't stop at HEAD.
+func Reword(sha, message string, args ...string) error {
+	head, err := HeadCommit()
+	if err != nil {
+		return err
+	}
+	if sha == head {
+		cmd := command(append([]string{"commit", "--amend", "--only", "--allow-empty", "--cleanup=strip", "-F", "-"}, args...)...)
+		cmd.Stdin = strings.NewReader(message)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to amend HEAD: %w: %s", err, strings.TrimSpace(string(out)))
+		}
+		return nil
+	}
+
+	if command("merge-base", "--is-ancestor", sha, "HEAD").Run() != nil {
+		return fmt.Errorf("%s is not an ancestor of HEAD", short(sha))
+	}
+	out, err := command("rev-list", "--reverse", "--ancestry-path", sha+"..HEAD").Output()
+	if err != nil {
+		return fmt.Errorf("failed to list descendants of %s: %w", short(sha), err)
+	}
+	descendants := strings.Fields(string(out))
+
+	sign := commitTreeSignArgs(args)
+	newSHA, err := recommit(sha, nil, &message, sign)
+	if err != nil {
+		return err
+	}
+	mapped := map[string]string{sha: newSHA}
+	tip := newSHA
+	for _, c := range descendants {
+		parents, err := parentsOf(c)
+		if err != nil {
+			return err
+		}
+		if len(parents) != 1 {
+			return fmt.Errorf("cannot reword across merge commit %s", short(c))
+		}
+		newParent, ok := mapped[parents[0]]
+		if !ok {
+			newParent = parents[0]
+		}
+		if tip, err = recommit(c, []string{newParent}, nil, sign); err != nil {
+			return err
+		}
+		mapped[c] = tip
+	}
+
+	cmd := command("update-ref", "-m", "smartcommit: reword "+short(sha), "HEAD", tip, head)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to update HEAD: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// ShowCmd returns the command that shows the patch of rev in the terminal,
+// through git's pager.
+func ShowCmd(rev string) *exec.Cmd {
+	return command("show", "--format=", rev).Cmd
+}
+
+// CommitStat returns the files a commit changed, with counts of changed
+// lines, as git show --stat prints them.
+func CommitStat(rev string) (string, error) {
+	out, err := command("show", "--stat", "--format=", rev).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get stat of %s: %w", rev, err)
+	}
+	return strings.TrimRight(string(out), "\n"), nil
+}
+
+// RangeCommits returns the full hashes of the commits in revRange, such as
+// main..HEAD, oldest first. It fails if any is a merge commit or isn't an
+// ancestor of HEAD, as rewording rewrites HEAD's history.
+func RangeCommits(revRange string) ([]string, error) {
+	out, err := command("rev-list", "--reverse", revRange).Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a valid range", revRange)
+	}
+	commits := strings.Fields(string(out))
+	for _, c := range commits {
+		if command("merge-base", "--is-ancestor", c, "HEAD").Run() != nil {
+			return nil, fmt.Errorf("%s is not an ancestor of HEAD", short(c))
+		}
+		parents, err := parentsOf(c)
+		if err != nil {
+			return nil, err
+		}
+		if len(parents) > 1 {
+			return nil, fmt.Errorf("cannot reword across merge commit %s", short(c))
+		}
+	}
+	return commits, nil
+}
+
+// RewordAll replaces the messages of the commits in messages, keyed by full
+// hash, with an interactive rebase whose todo list is generated: every
+// commit from the oldest one reworded up to HEAD is picked, and each one
+// reworded is amended right after by an exec line, so hooks and signing
+// behave as usual. sign are git commit's signing arguments, as for Reword,
+// and apply to the amends and the rebase alike. Local changes are stashed
+// for the rebase and restored after it. If the rebase stops, it's aborted
+// and nothing changes.
+func RewordAll(messages map[string]string, sign ...string) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	// Replay from the oldest commit reworded: the one furthest from HEAD.
+	var oldest string
+	var distance int
+	for c := range messages {
+		out, err := command("rev-list", "--count", c+"..HEAD").Output()
+		if err != nil {
+			return fmt.Errorf("failed to find %s in HEAD's history: %w", short(c), err)
+		}
+		if n, _ := strconv.Atoi(strings.TrimSpace(string(out))); oldest == "" || n > distance {
+			oldest, distance = c, n
+		}
+	}
+	parents, err := parentsOf(oldest)
+	if err != nil {
+		return err
+	}
+	var base string
+	args := []string{"rev-list", "--reverse", "--topo-order", "HEAD"}
+	if len(parents) == 1 {
+		base = parents[0]
+		args = append(args, "^"+base)
+	}
+	out, err := command(args...).Output()
+	if err != nil {
+		return fmt.Errorf("failed to list commits: %w", err)
+	}
+	replayed := strings.Fields(string(out))
+
+	dir, err := os.MkdirTemp("", "smartcommit-reword-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	var todo strings.Builder
+	for _, c := range replayed {
+		parents, err := parentsOf(c)
+		if err != nil {
+			return err
+		}
+		if len(parents) > 1 {
+			return fmt.Errorf("cannot reword across merge commit %s", short(c))
+		}
+		fmt.Fprintf(&todo, "pick %s\n", c)
+		message, ok := messages[c]
+		if !ok {
+			continue
+		}
+		file := filepath.Join(dir, c+".txt")
+		if err := os.WriteFile(file, []byte(message+"\n"), 0600); err != nil {
+			return err
+		}
+		fmt.Fprintf(&todo, "exec git commit --amend --only --allow-empty --cleanup=strip --quiet%s -F '%s'\n", strings.Join(append([]string{""}, sign...), " "), filepath.ToSlash(file))
+	}
+	todoFile := filepath.Join(dir, "todo")
+	if err := os.WriteFile(todoFile, []byte(todo.String()), 0600); err != nil {
+		return err
+	}
+
+	args = append([]string{"rebase", "--interactive", "--autostash"}, sign...)
+	if base == "" {
+		args = append(args, "--root")
+	} else {
+		args = append(args, base)
+	}
+	cmd := command(args...)
+	// Git appends the todo file's path to the sequence editor, which
+	// replaces its contents with the generated list.
+	cmd.Env = append(os.Environ(), "GIT_SEQUENCE_EDITOR=cp '"+filepath.ToSlash(todoFile)+"'")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		command("rebase", "--abort").Run() // Ignore error, the rebase may not have started
+		return fmt.Errorf("failed to reword commits: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// commitTreeSignArgs returns the git commit-tree arguments that sign as git
+// commit would with args. Unlike git commit, commit-tree ignores
+// commit.gpgSign, so without either argument it's read here.
+func commitTreeSignArgs(args []string) []string {
+	for i := len(args) - 1; i >= 0; i-- {
+		switch {
+		case args[i] == "--no-gpg-sign":
+			return nil
+		case args[i] == "--gpg-sign", strings.HasPrefix(args[i], "--gpg-sign="), strings.HasPrefix(args[i], "-S"):
+			return []string{args[i]}
+		}
+	}
+	out, _ := command("config", "--type=bool", "commit.gpgSign").Output()
+	if strings.TrimSpace(string(out)) == "true" {
+		return []string{"--gpg-sign"}
+	}
+	return nil
+}
+
+// recommit creates a copy of commit c with the same tree and author. parents
+// and message replace the originals when non-nil. sign are commit-tree's
+// signing arguments.
+func recommit(c string, parents []string, message *string, sign []string) (string, error) {
+	out, err := command("log", "-1", "--format=%T%x1f%an%x1f%ae%x1f%ad", "--date=raw", c).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to read commit %s: %w", short(c), err)
+	}
+	fields := strings.Split(strings.TrimSpace(string(out)), "\x1f")
+	if len(fields) != 4 {
+		return "", fmt.Errorf("unexpected format for commit %s", short(c))
+	}
+
+	if parents == nil {
+		if parents, err = parentsOf(c); err != nil {
+			return "", err
+		}
+	}
+	msg := ""
+	if message != nil {
+		msg = *message
+	} else if msg, err = rawMessage(c); err != nil {
+		return "", err
+	}
+
+	args := append([]string{"commit-tree"}, sign...)
+	args = append(args, fields[0])
+	for _, p := range parents {
+		args = append(args, "-p", p)
+	}
+	cmd := command(args...)
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME="+fields[1],
+		"GIT_AUTHOR_EMAIL="+fields[2],
+		"GIT_AUTHOR_DATE="+fields[3],
+	)
+	cmd.Stdin = strings.NewReader(msg)
+	newSHA, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to rewrite %s: %w", short(c), err)
+	}
+	return strings.TrimSpace(string(newSHA)), nil
+}
+
+func parentsOf(c string) ([]string, error) {
+	out, err := command("log", "-1", "--format=%P", c).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read parents of %s: %w", short(c), err)
+	}
+	return strings.Fields(string(out)), nil
+}
+
+// rawMessage returns a commit's message exactly as stored.
+func rawMessage(c string) (string, error) {
+	out, err := command("cat-file", "commit", c).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to read commit %s: %w", short(c), err)
+	}
+	if _, msg, ok := strings.Cut(string(out), "\n\n"); ok {
+		return msg, nil
+	}
+	return "", nil
+}
+
+func short(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
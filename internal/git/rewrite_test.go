@@ -0,0 +1,80 @@
+package git
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// signingRepo is newRepo set up to sign commits with a new SSH key when
+// gpgSign is true.
+func signingRepo(t *testing.T, gpgSign bool) func(args ...string) string {
+	t.Helper()
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		t.Skip("ssh-keygen isn't installed")
+	}
+	run := newRepo(t)
+	key := filepath.Join(t.TempDir(), "key")
+	if out, err := exec.Command("ssh-keygen", "-q", "-t", "ed25519", "-N", "", "-f", key).CombinedOutput(); err != nil {
+		t.Fatalf("ssh-keygen: %v: %s", err, out)
+	}
+	run("config", "gpg.format", "ssh")
+	run("config", "user.signingkey", key)
+	if gpgSign {
+		run("config", "commit.gpgsign", "true")
+	}
+	for _, name := range []string{"a", "b", "c"} {
+		writeFile(t, name, name+"\n")
+		run("add", name)
+		run("commit", "-q", "--gpg-sign", "-m", "feat: add "+name)
+	}
+	return run
+}
+
+func TestRewordSigning(t *testing.T) {
+	tests := []struct {
+		name    string
+		gpgSign bool
+		args    []string
+		want    bool
+	}{
+		{"commit.gpgSign", true, nil, true},
+		{"--gpg-sign", false, []string{"--gpg-sign"}, true},
+		{"--no-gpg-sign over commit.gpgSign", true, []string{"--no-gpg-sign"}, false},
+		{"neither", false, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			run := signingRepo(t, tt.gpgSign)
+			first := strings.TrimSpace(run("rev-parse", "HEAD~2"))
+			if err := Reword(first, "feat: add the first file", tt.args...); err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.TrimSpace(run("log", "-1", "--format=%s", "HEAD~2")); got != "feat: add the first file" {
+				t.Fatalf("HEAD~2 is %q after rewording", got)
+			}
+			for _, rev := range []string{"HEAD", "HEAD~1", "HEAD~2"} {
+				if got := Signed(rev); got != tt.want {
+					t.Errorf("%s signed = %v, want %v", rev, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestRewordAllSigning(t *testing.T) {
+	run := signingRepo(t, false)
+	middle := strings.TrimSpace(run("rev-parse", "HEAD~1"))
+	if err := RewordAll(map[string]string{middle: "feat: add the second file"}, "--gpg-sign"); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(run("log", "-1", "--format=%s", "HEAD~1")); got != "feat: add the second file" {
+		t.Fatalf("HEAD~1 is %q after rewording", got)
+	}
+	for _, rev := range []string{"HEAD", "HEAD~1"} {
+		if !Signed(rev) {
+			t.Errorf("%s isn't signed after rewording", rev)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package git
+
+import (
+	"strings"
+)
+
+// SigningFormat returns the kind of signature git makes, as gpg.format
+// names it: "openpgp", "ssh", or "x509".
+func SigningFormat() string {
+	out, err := command("config", "gpg.format").Output()
+	if err != nil || strings.TrimSpace(string(out)) == "" {
+		return "openpgp"
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// SigningKey returns the configured user.signingkey, or "" if git picks
+// the key itself.
+func SigningKey() string {
+	out, _ := command("config", "user.signingkey").Output()
+	return strings.TrimSpace(string(out))
+}
+
+// signingFailures are what git prints when it couldn't sign a commit, for
+// each signing format.
+var signingFailures = []string{
+	"failed to sign the data",
+	"user.signingkey or gpg.ssh.defaultKeyCommand",
+	"Couldn't load public key",
+	"cannot run gpg",
+	"cannot run ssh-keygen",
+	"cannot run gpgsm",
+	"failed to write commit object",
+}
+
+// SigningFailed reports whether output, what a failed git commit printed,
+// says the commit couldn't be signed.
+func SigningFailed(output string) bool {
+	for _, s := range signingFailures {
+		if strings.Contains(output, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// HeadSigned reports whether HEAD carries a signature, whether or not it
+// can be verified here.
+func HeadSigned() bool {
+	return Signed("HEAD")
+}
+
+// Signed reports whether commit rev carries a signature, whether or not it
+// can be verified here.
+func Signed(rev string) bool {
+	out, err := command("cat-file", "commit", rev).Output()
+	if err != nil {
+		return false
+	}
+	header, _, _ := strings.Cut(string(out), "\n\n")
+	for _, line := range strings.Split(header, "\n") {
+		if strings.HasPrefix(line, "gpgsig ") || strings.HasPrefix(line, "gpgsig-sha256 ") {
+			return true
+		}
+	}
+	return false
+}
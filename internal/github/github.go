@@ -0,0 +1,199 @@
+// Package github fetches issues from the GitHub REST API so the reason for
+// a change can be given to the model along with the diff, opens pull
+// requests, and looks up smartcommit's own releases.
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultAPIURL is the REST API of github.com.
+const DefaultAPIURL = "https://api.github.com"
+
+// Issue is the part of a GitHub issue that explains a change.
+type Issue struct {
+	Number int    `json:"number"`
+	Title  string `json:"title"`
+	Body   string `json:"body"`
+	URL    string `json:"html_url"`
+}
+
+// Client reads issues from a GitHub REST API.
+type Client struct {
+	apiURL string
+	token  string
+	http   *http.Client
+}
+
+// NewClient creates a client for the API at apiURL, or DefaultAPIURL if
+// empty, sending requests with client, or with a 10 second timeout if it's
+// nil. token may be empty for public repositories.
+func NewClient(apiURL, token string, client *http.Client) *Client {
+	if apiURL == "" {
+		apiURL = DefaultAPIURL
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Client{
+		apiURL: strings.TrimRight(apiURL, "/"),
+		token:  token,
+		http:   client,
+	}
+}
+
+// Issue fetches issue number of repo, given as "owner/name".
+func (c *Client) Issue(ctx context.Context, repo string, number int) (*Issue, error) {
+	var issue Issue
+	path := fmt.Sprintf("/repos/%s/issues/%d", repo, number)
+	if err := c.do(ctx, http.MethodGet, path, nil, &issue); err != nil {
+		return nil, fmt.Errorf("failed to fetch issue #%d of %s: %w", number, repo, err)
+	}
+	return &issue, nil
+}
+
+// Release is a published release and the files attached to it.
+type Release struct {
+	Tag    string  `json:"tag_name"`
+	URL    string  `json:"html_url"`
+	Assets []Asset `json:"assets"`
+}
+
+// Asset is a file attached to a release.
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// Asset returns the asset of r called name, reporting false if there's none.
+func (r *Release) Asset(name string) (Asset, bool) {
+	for _, a := range r.Assets {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return Asset{}, false
+}
+
+// LatestRelease fetches the newest release of repo, given as "owner/name",
+// that isn't a draft or prerelease.
+func (c *Client) LatestRelease(ctx context.Context, repo string) (*Release, error) {
+	var release Release
+	if err := c.do(ctx, http.MethodGet, "/repos/"+repo+"/releases/latest", nil, &release); err != nil {
+		return nil, fmt.Errorf("failed to fetch the latest release of %s: %w", repo, err)
+	}
+	return &release, nil
+}
+
+// PullRequest is a pull request to open.
+type PullRequest struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+	// Head is the branch with the changes, which must already be pushed.
+	Head string `json:"head"`
+	// Base is the branch the changes are pulled into.
+	Base string `json:"base"`
+}
+
+// CreatePullRequest opens pr on repo, given as "owner/name", and returns
+// its URL.
+func (c *Client) CreatePullRequest(ctx context.Context, repo string, pr PullRequest) (string, error) {
+	if c.token == "" {
+		return "", fmt.Errorf("opening a pull request needs a token in GITHUB_TOKEN or GH_TOKEN")
+	}
+	var created struct {
+		URL string `json:"html_url"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/repos/"+repo+"/pulls", pr, &created); err != nil {
+		return "", fmt.Errorf("failed to open pull request on %s: %w", repo, err)
+	}
+	return created.URL, nil
+}
+
+// do sends a request with in, if not nil, as its JSON body and decodes the
+// reply into out.
+func (c *Client) do(ctx context.Context, method, path string, in, out any) error {
+	var body bytes.Buffer
+	if in != nil {
+		if err := json.NewEncoder(&body).Encode(in); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.apiURL+path, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&apiErr) == nil && apiErr.Message != "" {
+			return fmt.Errorf("%s: %s", resp.Status, apiErr.Message)
+		}
+		return fmt.Errorf("%s", resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// remotePattern matches the owner and name in HTTPS and SSH remote URLs.
+var remotePattern = regexp.MustCompile(`^(?:https?://(?:[^@/]+@)?|ssh://(?:[^@/]+@)?|[^@/]+@)([^/:]+)(?::\d+)?[/:]([^/]+)/([^/]+?)(?:\.git)?/?$`)
+
+// Repo returns the "owner/name" of a remote URL on host, such as
+// "git@github.com:owner/name.git", reporting false if it's elsewhere.
+func Repo(remoteURL, host string) (string, bool) {
+	m := remotePattern.FindStringSubmatch(strings.TrimSpace(remoteURL))
+	if m == nil || !strings.EqualFold(m[1], host) {
+		return "", false
+	}
+	return m[2] + "/" + m[3], true
+}
+
+// Host returns the host whose remotes an API URL serves: github.com for
+// DefaultAPIURL, and the server's own host for GitHub Enterprise.
+func Host(apiURL string) string {
+	if apiURL == "" || apiURL == DefaultAPIURL {
+		return "github.com"
+	}
+	host := strings.TrimPrefix(strings.TrimPrefix(apiURL, "https://"), "http://")
+	host, _, _ = strings.Cut(host, "/")
+	return strings.TrimPrefix(host, "api.")
+}
+
+// branchNumber matches an issue number at the start of a branch name or of
+// its last component, optionally prefixed, as in "123-fix-login",
+// "fix/123-login", "issue-123", or "gh-123".
+var branchNumber = regexp.MustCompile(`(?i)(?:^|/)(?:issues?[-_]?|gh[-_]?|#)?([0-9]+)(?:[-_]|$)`)
+
+// IssueNumber returns the issue number in a branch name, or 0 if there's none.
+func IssueNumber(branch string) int {
+	m := branchNumber.FindStringSubmatch(branch)
+	if m == nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(m[1])
+	return n
+}
@@ -0,0 +1,116 @@
+// Package gitlab opens merge requests through the GitLab REST API.
+package gitlab
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DefaultAPIURL is the REST API of gitlab.com.
+const DefaultAPIURL = "https://gitlab.com/api/v4"
+
+// Client talks to a GitLab REST API.
+type Client struct {
+	apiURL string
+	token  string
+	http   *http.Client
+}
+
+// NewClient creates a client for the API at apiURL, or DefaultAPIURL if
+// empty, sending requests with client, or with a 10 second timeout if it's
+// nil.
+func NewClient(apiURL, token string, client *http.Client) *Client {
+	if apiURL == "" {
+		apiURL = DefaultAPIURL
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Client{
+		apiURL: strings.TrimRight(apiURL, "/"),
+		token:  token,
+		http:   client,
+	}
+}
+
+// MergeRequest is a merge request to open.
+type MergeRequest struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	// SourceBranch is the branch with the changes, which must already be pushed.
+	SourceBranch string `json:"source_branch"`
+	// TargetBranch is the branch the changes are merged into.
+	TargetBranch string `json:"target_branch"`
+}
+
+// CreateMergeRequest opens mr on project, given as its path such as
+// "group/name", and returns its URL.
+func (c *Client) CreateMergeRequest(ctx context.Context, project string, mr MergeRequest) (string, error) {
+	if c.token == "" {
+		return "", fmt.Errorf("opening a merge request needs a token in GITLAB_TOKEN")
+	}
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(mr); err != nil {
+		return "", err
+	}
+	endpoint := c.apiURL + "/projects/" + url.PathEscape(project) + "/merge_requests"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, &body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("PRIVATE-TOKEN", c.token)
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to open merge request on %s: %w", project, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		var apiErr struct {
+			Message any `json:"message"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&apiErr) == nil && apiErr.Message != nil {
+			return "", fmt.Errorf("failed to open merge request on %s: %s: %v", project, resp.Status, apiErr.Message)
+		}
+		return "", fmt.Errorf("failed to open merge request on %s: %s", project, resp.Status)
+	}
+	var created struct {
+		URL string `json:"web_url"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return "", fmt.Errorf("failed to decode merge request: %w", err)
+	}
+	return created.URL, nil
+}
+
+// remotePattern matches the host and project path in HTTPS and SSH remote
+// URLs. Projects may be nested in subgroups.
+var remotePattern = regexp.MustCompile(`^(?:https?://(?:[^@/]+@)?|ssh://(?:[^@/]+@)?|[^@/]+@)([^/:]+)(?::\d+)?[/:]((?:[^/]+/)+[^/]+?)(?:\.git)?/?$`)
+
+// Project returns the project path of a remote URL on host, such as
+// "group/name" for "git@gitlab.com:group/name.git", reporting false if it's
+// elsewhere.
+func Project(remoteURL, host string) (string, bool) {
+	m := remotePattern.FindStringSubmatch(strings.TrimSpace(remoteURL))
+	if m == nil || !strings.EqualFold(m[1], host) {
+		return "", false
+	}
+	return m[2], true
+}
+
+// Host returns the host whose remotes an API URL serves.
+func Host(apiURL string) string {
+	if apiURL == "" {
+		apiURL = DefaultAPIURL
+	}
+	host := strings.TrimPrefix(strings.TrimPrefix(apiURL, "https://"), "http://")
+	host, _, _ = strings.Cut(host, "/")
+	return host
+}
@@ -0,0 +1,90 @@
+package glob
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Match reports whether name (a slash-separated path relative to the repo
+// root) matches pattern. Patterns follow .gitignore conventions:
+//
+//   - a pattern without a slash matches any single path component, so
+//     "vendor" matches "vendor/x.go" and "*.min.js" matches "web/app.min.js";
+//   - a pattern with a slash is matched against the whole path, where "**"
+//     matches any number of directories and "*" stays within one;
+//   - a trailing slash restricts the pattern to directories.
+func Match(pattern, name string) bool {
+	pattern = strings.TrimPrefix(pattern, "/")
+	name = strings.TrimPrefix(path.Clean(name), "/")
+	dirOnly := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+
+	if !strings.Contains(pattern, "/") {
+		parts := strings.Split(name, "/")
+		if dirOnly {
+			parts = parts[:len(parts)-1]
+		}
+		for _, part := range parts {
+			if ok, _ := path.Match(pattern, part); ok {
+				return true
+			}
+		}
+		return false
+	}
+
+	re, err := compile(pattern)
+	if err != nil {
+		return false
+	}
+	// A pattern naming a directory matches everything inside it, so try the
+	// path itself and then each of its parent directories.
+	p := name
+	if dirOnly {
+		p = path.Dir(p)
+	}
+	for ; p != "." && p != "/"; p = path.Dir(p) {
+		if re.MatchString(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchAny reports whether name matches any of the patterns.
+func MatchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if Match(p, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// compile turns a slash-containing glob into an anchored regular expression.
+func compile(pattern string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '*' && i+1 < len(pattern) && pattern[i+1] == '*':
+			i++
+			if i+1 < len(pattern) && pattern[i+1] == '/' {
+				// "**/" matches zero or more directories.
+				i++
+				b.WriteString("(?:.*/)?")
+			} else {
+				b.WriteString(".*")
+			}
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
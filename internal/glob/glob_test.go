@@ -0,0 +1,75 @@
+package glob
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		// Without a slash, a pattern matches any single component.
+		{"vendor", "vendor/x.go", true},
+		{"vendor", "third_party/vendor/x.go", true},
+		{"vendor", "vendored/x.go", false},
+		{"*.min.js", "web/app.min.js", true},
+		{"*.min.js", "web/app.js", false},
+		{".env", ".env", true},
+		{".env*", "config/.env.local", true},
+		{"?.go", "a.go", true},
+		{"?.go", "ab.go", false},
+
+		// A trailing slash restricts the pattern to directories.
+		{"build/", "build/out.bin", true},
+		{"build/", "build", false},
+		{"secrets/", "app/secrets/key.pem", true},
+
+		// With a slash, the pattern is matched against the whole path.
+		{"config/*.yml", "config/app.yml", true},
+		{"config/*.yml", "config/prod/app.yml", false},
+		{"config/*.yml", "other/config/app.yml", false},
+		{"/config/*.yml", "config/app.yml", true},
+		{"db/migrations", "db/migrations/001.sql", true},
+		{"db/migrations/", "db/migrations/001.sql", true},
+
+		// "**" spans directories.
+		{"**/secrets.json", "secrets.json", true},
+		{"**/secrets.json", "a/b/secrets.json", true},
+		{"infra/**/*.tf", "infra/main.tf", true},
+		{"infra/**/*.tf", "infra/prod/eu/main.tf", true},
+		{"infra/**", "infra/prod/main.tf", true},
+		{"infra/**/*.tf", "app/main.tf", false},
+
+		// Names are cleaned before matching.
+		{"a/b.txt", "./a/b.txt", true},
+		{"a/b.txt", "/a/b.txt", true},
+
+		// Regular expression metacharacters are literal.
+		{"a+b/(c).txt", "a+b/(c).txt", true},
+		{"a+b/(c).txt", "aab/c.txt", false},
+	}
+	for _, tt := range tests {
+		if got := Match(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMatchAny(t *testing.T) {
+	patterns := []string{"*.pem", "deploy/"}
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"certs/server.pem", true},
+		{"deploy/run.sh", true},
+		{"src/main.go", false},
+	}
+	for _, tt := range tests {
+		if got := MatchAny(patterns, tt.name); got != tt.want {
+			t.Errorf("MatchAny(%q, %q) = %v, want %v", patterns, tt.name, got, tt.want)
+		}
+	}
+	if MatchAny(nil, "anything") {
+		t.Error("MatchAny(nil, ...) = true, want false")
+	}
+}
@@ -0,0 +1,228 @@
+// Package guard keeps commit messages to the usual form: a subject of at
+// most 72 characters in the imperative mood with no trailing period, a
+// blank line before the body, and a body wrapped at 72 columns. It also
+// finds the vague words that say little about a change.
+package guard
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/arpxspace/smartcommit/internal/conventional"
+)
+
+const (
+	// DefaultSubjectLength is the subject limit when none is configured.
+	DefaultSubjectLength = 72
+	// BodyWidth is the column body lines are wrapped at.
+	BodyWidth = 72
+)
+
+// Fix returns message with its form fixed where that can be done without
+// rewording it, and a note of each fix made. Only paragraphs and list items
+// with a line over BodyWidth are rewrapped; code and trailers are kept as
+// they are.
+func Fix(message string) (string, []string) {
+	subject, body, blank := split(message)
+	var fixes []string
+	if trimmed := trimPeriod(subject); trimmed != subject {
+		subject = trimmed
+		fixes = append(fixes, "removed the trailing period from the subject")
+	}
+	if word, imperative, at := mood(subject); imperative != "" {
+		subject = subject[:at] + imperative + subject[at+len(word):]
+		fixes = append(fixes, fmt.Sprintf("changed %q to %q in the subject", word, imperative))
+	}
+	if !blank {
+		fixes = append(fixes, "added a blank line before the body")
+	}
+	if wrapped := Wrap(body, BodyWidth); wrapped != body {
+		body = wrapped
+		fixes = append(fixes, fmt.Sprintf("wrapped the body at %d columns", BodyWidth))
+	}
+	if len(fixes) == 0 {
+		return message, nil
+	}
+	if body == "" {
+		return subject, fixes
+	}
+	return subject + "\n\n" + body, fixes
+}
+
+// Check returns what's wrong with the form of message, with the subject
+// limited to limit characters, or DefaultSubjectLength when limit is 0.
+func Check(message string, limit int) []string {
+	if limit == 0 {
+		limit = DefaultSubjectLength
+	}
+	subject, body, blank := split(message)
+	var problems []string
+	if n := utf8.RuneCountInString(subject); n > limit {
+		problems = append(problems, fmt.Sprintf("subject is %d characters long; shorten it to %d or less", n, limit))
+	}
+	if trimPeriod(subject) != subject {
+		problems = append(problems, "remove the trailing period from the subject")
+	}
+	if word, imperative, _ := mood(subject); imperative != "" {
+		problems = append(problems, fmt.Sprintf("write the subject in the imperative mood: %q rather than %q", imperative, word))
+	} else if word != "" && inflected(word) {
+		problems = append(problems, fmt.Sprintf("write the subject in the imperative mood, as in \"Add\" rather than \"Added\"; it starts with %q", word))
+	}
+	if !blank {
+		problems = append(problems, "add a blank line between the subject and the body")
+	}
+	if Wrap(body, BodyWidth) != body {
+		problems = append(problems, fmt.Sprintf("wrap the body at %d columns", BodyWidth))
+	}
+	return problems
+}
+
+// SubjectFits reports whether the subject of message is at most limit
+// characters long, or DefaultSubjectLength when limit is 0.
+func SubjectFits(message string, limit int) bool {
+	if limit == 0 {
+		limit = DefaultSubjectLength
+	}
+	subject, _, _ := split(message)
+	return utf8.RuneCountInString(subject) <= limit
+}
+
+// ReplaceSubject returns message with its subject replaced by subject.
+func ReplaceSubject(message, subject string) string {
+	_, rest, _ := strings.Cut(strings.TrimSpace(message), "\n")
+	subject = strings.TrimSpace(subject)
+	if rest == "" {
+		return subject
+	}
+	return subject + "\n" + rest
+}
+
+// split returns the subject and body of message, and whether a blank line
+// separates them. A message without a body counts as separated.
+func split(message string) (subject, body string, blank bool) {
+	subject, rest, _ := strings.Cut(strings.TrimSpace(message), "\n")
+	first, _, _ := strings.Cut(rest, "\n")
+	return strings.TrimSpace(subject), strings.Trim(rest, "\n"), rest == "" || strings.TrimSpace(first) == ""
+}
+
+// trimPeriod removes a single trailing period from subject, leaving an
+// ellipsis alone.
+func trimPeriod(subject string) string {
+	if strings.HasSuffix(subject, ".") && !strings.HasSuffix(subject, "..") {
+		return strings.TrimRight(strings.TrimSuffix(subject, "."), " ")
+	}
+	return subject
+}
+
+// leadingWord matches the first word of a description, after any ticket
+// keys, emoji, or other tokens that don't start with a letter.
+var leadingWord = regexp.MustCompile(`^(?:[^\pL\s]\S*\s+)*(\pL[\pL'-]*)`)
+
+// mood finds the first word of the subject's description and, when it's a
+// known verb in a form other than the imperative, returns the imperative
+// with the word's capitalization. at is the word's offset in subject.
+func mood(subject string) (word, imperative string, at int) {
+	if h, ok := conventional.Parse(subject); ok {
+		at = len(subject) - len(h.Description)
+	}
+	m := leadingWord.FindStringSubmatchIndex(subject[at:])
+	if m == nil {
+		return "", "", 0
+	}
+	at += m[2]
+	word = subject[at : at+m[3]-m[2]]
+	base, ok := baseForm(strings.ToLower(word))
+	if !ok {
+		return word, "", at
+	}
+	if r, _ := utf8.DecodeRuneInString(word); unicode.IsUpper(r) {
+		base = strings.ToUpper(base[:1]) + base[1:]
+	}
+	return word, base, at
+}
+
+// verbs are the verbs commit subjects commonly start with, in the
+// imperative.
+var verbs = toSet(`add adjust align allow apply avoid bump cache change check clarify clean
+	close collapse combine configure convert copy correct create debounce decouple default
+	delete deprecate detect disable document drop emit enable enforce ensure expand expose
+	extend extract fetch fix flag format guard handle hide ignore implement import improve
+	include increase inline introduce invert limit load log lower make mark merge migrate
+	move normalize open optimize override parse pass pin prefer prepare prevent print
+	process prune raise read record reduce refactor reformat register reject release
+	remove rename reorder replace report require reset resolve restore restrict retry
+	return reuse revert rework rewrite run save scope separate set show simplify skip sort
+	speed split start stop store streamline support switch tidy track trim tweak unify
+	update upgrade use validate verify wrap write`)
+
+// irregular maps the past forms of irregular verbs to the imperative.
+var irregular = map[string]string{
+	"made": "make", "wrote": "write", "written": "write", "rewrote": "rewrite",
+	"rewritten": "rewrite", "ran": "run", "split": "split", "set": "set", "kept": "keep",
+	"sped": "speed", "hid": "hide", "hidden": "hide", "read": "read", "reset": "reset",
+}
+
+// baseForm returns the imperative of word when it's a past, present, or
+// gerund form of a known verb.
+func baseForm(word string) (string, bool) {
+	if verbs[word] {
+		return "", false
+	}
+	if base, ok := irregular[word]; ok && base != word {
+		return base, true
+	}
+	var stems []string
+	switch {
+	case strings.HasSuffix(word, "ing"):
+		stem := strings.TrimSuffix(word, "ing")
+		stems = []string{stem, stem + "e", undouble(stem)}
+	case strings.HasSuffix(word, "ied"), strings.HasSuffix(word, "ies"):
+		stems = []string{word[:len(word)-3] + "y"}
+	case strings.HasSuffix(word, "ed"):
+		stem := strings.TrimSuffix(word, "ed")
+		stems = []string{stem, stem + "e", undouble(stem)}
+	case strings.HasSuffix(word, "es"):
+		stems = []string{strings.TrimSuffix(word, "es"), strings.TrimSuffix(word, "s")}
+	case strings.HasSuffix(word, "s"):
+		stems = []string{strings.TrimSuffix(word, "s")}
+	}
+	for _, stem := range stems {
+		if verbs[stem] {
+			return stem, true
+		}
+	}
+	return "", false
+}
+
+// undouble drops the doubled final consonant of stems like "stopp".
+func undouble(stem string) string {
+	if n := len(stem); n >= 2 && stem[n-1] == stem[n-2] {
+		return stem[:n-1]
+	}
+	return stem
+}
+
+// notInflected are words ending like a past or gerund form that aren't one.
+var notInflected = toSet(`bed embed feed need seed shed speed bring ping ring sing spring
+	sting string swing thing nothing something anything everything during`)
+
+// inflected reports whether word looks like a past or gerund form, for
+// verbs baseForm doesn't know.
+func inflected(word string) bool {
+	word = strings.ToLower(word)
+	if len(word) < 5 || notInflected[word] {
+		return false
+	}
+	return strings.HasSuffix(word, "ed") || strings.HasSuffix(word, "ing")
+}
+
+func toSet(words string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range strings.Fields(words) {
+		set[w] = true
+	}
+	return set
+}
@@ -0,0 +1,75 @@
+package guard
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Span is a range of bytes in a message.
+type Span struct {
+	Start, End int
+}
+
+// Vague finds the words and phrases of words in message, ignoring case.
+// A single word also matches its inflections, so "improve" finds
+// "improved" and "improvements".
+func Vague(message string, words []string) []Span {
+	var alts []string
+	for _, w := range words {
+		w = strings.ToLower(strings.TrimSpace(w))
+		if w == "" {
+			continue
+		}
+		if strings.ContainsAny(w, " \t") {
+			alts = append(alts, strings.Join(strings.Fields(regexp.QuoteMeta(w)), `\s+`))
+			continue
+		}
+		stem := regexp.QuoteMeta(strings.TrimSuffix(w, "e"))
+		alts = append(alts, stem+`(?:e|es|ed|s|d|ing|ements?|ments?)?`)
+	}
+	if len(alts) == 0 {
+		return nil
+	}
+	// Longer alternatives first, so a phrase wins over a word inside it.
+	slices.SortFunc(alts, func(a, b string) int { return len(b) - len(a) })
+	pattern := regexp.MustCompile(`(?i)\b(?:` + strings.Join(alts, "|") + `)\b`)
+	var spans []Span
+	for _, m := range pattern.FindAllStringIndex(message, -1) {
+		spans = append(spans, Span{m[0], m[1]})
+	}
+	return spans
+}
+
+// sentenceEnd matches the end of a sentence: closing punctuation before
+// whitespace, or a blank line.
+var sentenceEnd = regexp.MustCompile(`[.!?](?:\s|$)|\n\s*\n|\n[-*+] `)
+
+// Sentence returns the sentence of message around the byte offset at: the
+// subject when at falls in it, and otherwise the sentence of the body,
+// which may run over several lines.
+func Sentence(message string, at int) Span {
+	subjectEnd := strings.Index(message, "\n")
+	if subjectEnd < 0 {
+		subjectEnd = len(message)
+	}
+	if at < subjectEnd {
+		return Span{0, subjectEnd}
+	}
+	start := subjectEnd
+	for _, m := range sentenceEnd.FindAllStringIndex(message[subjectEnd:at], -1) {
+		start = subjectEnd + m[1]
+	}
+	end := len(message)
+	if m := sentenceEnd.FindStringIndex(message[at:]); m != nil {
+		end = at + m[0]
+		if strings.ContainsRune(".!?", rune(message[end])) {
+			end++
+		}
+	}
+	// Trim the whitespace and list marker the sentence starts after.
+	for start < end && strings.ContainsRune(" \t\n", rune(message[start])) {
+		start++
+	}
+	return Span{start, end}
+}
@@ -0,0 +1,129 @@
+package guard
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	// listItem matches the marker of a list item and the space after it.
+	listItem = regexp.MustCompile(`^(?:[-*+]|\d+[.)])\s+`)
+	// trailerLine matches a git trailer like "Signed-off-by: Name <email>".
+	trailerLine = regexp.MustCompile(`^[A-Za-z][A-Za-z-]*: \S`)
+)
+
+// block is a paragraph or list item of a message body.
+type block struct {
+	lines []string
+	// hang indents the lines after the first, under the text of a list item.
+	hang string
+}
+
+// Wrap rewraps each paragraph and list item of body that has a line over
+// width. Fenced and indented code, quotes, and a closing block of trailers
+// are kept as they are, as are words too long to wrap, like URLs.
+func Wrap(body string, width int) string {
+	lines := strings.Split(body, "\n")
+	start := 0
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			start = i + 1
+		}
+	}
+	keep := len(lines)
+	if start < len(lines) && trailers(lines[start:]) {
+		keep = start
+	}
+
+	var out []string
+	var cur *block
+	flush := func() {
+		if cur != nil {
+			out = append(out, cur.wrap(width)...)
+			cur = nil
+		}
+	}
+	fenced := false
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case i >= keep, fenced, strings.HasPrefix(trimmed, "```"), strings.HasPrefix(trimmed, "~~~"):
+			if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+				fenced = !fenced
+			}
+			flush()
+			out = append(out, line)
+		case trimmed == "", strings.HasPrefix(line, ">"):
+			flush()
+			out = append(out, line)
+		case listItem.MatchString(line):
+			flush()
+			marker := listItem.FindString(line)
+			cur = &block{lines: []string{line}, hang: strings.Repeat(" ", utf8.RuneCountInString(marker))}
+		case strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t"):
+			if cur != nil && cur.hang != "" && strings.HasPrefix(line, cur.hang) && !strings.HasPrefix(line[len(cur.hang):], " ") {
+				cur.lines = append(cur.lines, line)
+				continue
+			}
+			flush()
+			out = append(out, line)
+		default:
+			if cur == nil || cur.hang != "" {
+				flush()
+				cur = &block{}
+			}
+			cur.lines = append(cur.lines, line)
+		}
+	}
+	flush()
+	return strings.Join(out, "\n")
+}
+
+// wrap returns the block's lines, refilled to width if any is longer.
+func (b *block) wrap(width int) []string {
+	long := false
+	for _, line := range b.lines {
+		if utf8.RuneCountInString(line) > width {
+			long = true
+			break
+		}
+	}
+	if !long {
+		return b.lines
+	}
+	first, text := "", strings.Join(b.lines, " ")
+	if b.hang != "" {
+		first = listItem.FindString(b.lines[0])
+		text = text[len(first):]
+	}
+	words := strings.Fields(text)
+	var out []string
+	line, n := first, utf8.RuneCountInString(first)
+	fresh := true
+	for _, w := range words {
+		wn := utf8.RuneCountInString(w)
+		if !fresh && n+1+wn > width {
+			out = append(out, line)
+			line, n, fresh = b.hang, len(b.hang), true
+		}
+		if !fresh {
+			line += " "
+			n++
+		}
+		line += w
+		n += wn
+		fresh = false
+	}
+	return append(out, line)
+}
+
+// trailers reports whether every line is a git trailer.
+func trailers(lines []string) bool {
+	for _, line := range lines {
+		if !trailerLine.MatchString(line) {
+			return false
+		}
+	}
+	return true
+}
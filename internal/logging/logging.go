@@ -0,0 +1,113 @@
+// Package logging records what smartcommit does, from the git commands it
+// runs to the requests it sends the provider and the screens it shows, as
+// structured lines in a log file bug reports can include. It never logs
+// prompts, diffs, messages or keys.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+)
+
+// Environment variables that configure the log. EnvFile logs everything to
+// a file, or to the default one when set to "1"; EnvLevel sets the level,
+// overriding log_level in the config.
+const (
+	EnvFile  = "SMARTCOMMIT_DEBUG"
+	EnvLevel = "SMARTCOMMIT_LOG_LEVEL"
+)
+
+// FileName is the log's name in the data directory.
+const FileName = "smartcommit.log"
+
+// Levels, by their names in the config. LevelOff turns the log off.
+const (
+	LevelDebug = "debug"
+	LevelInfo  = "info"
+	LevelWarn  = "warn"
+	LevelError = "error"
+	LevelOff   = "off"
+
+	DefaultLevel = LevelInfo
+)
+
+var (
+	logger = atomic.Pointer[slog.Logger]{}
+	output = atomic.Pointer[rotatingFile]{}
+)
+
+func init() {
+	logger.Store(slog.New(slog.DiscardHandler))
+}
+
+// ParseLevel returns the slog level named name, or false for LevelOff.
+func ParseLevel(name string) (slog.Level, bool, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case LevelDebug:
+		return slog.LevelDebug, true, nil
+	case LevelInfo, "":
+		return slog.LevelInfo, true, nil
+	case LevelWarn, "warning":
+		return slog.LevelWarn, true, nil
+	case LevelError:
+		return slog.LevelError, true, nil
+	case LevelOff:
+		return 0, false, nil
+	}
+	return 0, false, fmt.Errorf("unknown log level %q; use %s, %s, %s, %s, or %s", name, LevelDebug, LevelInfo, LevelWarn, LevelError, LevelOff)
+}
+
+// Open starts logging records at level and above to path, appending to what's
+// there and rotating it once it grows past maxSize. It replaces any log
+// already open.
+func Open(path string, level slog.Level) error {
+	f, err := openRotating(path)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	logger.Store(slog.New(slog.NewTextHandler(f, &slog.HandlerOptions{Level: level})))
+	if old := output.Swap(f); old != nil {
+		old.Close()
+	}
+	return nil
+}
+
+// Close stops logging.
+func Close() error {
+	logger.Store(slog.New(slog.DiscardHandler))
+	f := output.Swap(nil)
+	if f == nil {
+		return nil
+	}
+	return f.Close()
+}
+
+// Enabled reports whether records at level are logged, so callers can skip
+// work only the log needs.
+func Enabled(level slog.Level) bool {
+	return logger.Load().Enabled(context.Background(), level)
+}
+
+// Debug logs what only helps retrace a run step by step, like each git
+// command.
+func Debug(msg string, args ...any) {
+	logger.Load().Debug(msg, args...)
+}
+
+// Info logs the milestones of a run, like each provider request.
+func Info(msg string, args ...any) {
+	logger.Load().Info(msg, args...)
+}
+
+// Warn logs what went wrong but was recovered from, like a retried request.
+func Warn(msg string, args ...any) {
+	logger.Load().Warn(msg, args...)
+}
+
+// Error logs a failure shown to the user.
+func Error(msg string, args ...any) {
+	logger.Load().Error(msg, args...)
+}
@@ -0,0 +1,95 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// Rotation bounds. A log that would grow past maxSize is renamed with a
+// ".1" suffix, shifting older ones up, and only maxBackups are kept.
+const (
+	maxSize    = 1 << 20
+	maxBackups = 3
+)
+
+// rotatingFile is a log file that rotates itself as it's written.
+type rotatingFile struct {
+	path string
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+func openRotating(path string) (*rotatingFile, error) {
+	r := &rotatingFile{path: path}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *rotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	r.file, r.size = f, info.Size()
+	return nil
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return 0, os.ErrClosed
+	}
+	if r.size > 0 && r.size+int64(len(p)) > maxSize {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// rotate moves the current log aside and starts a new one.
+func (r *rotatingFile) rotate() error {
+	r.file.Close()
+	os.Remove(backup(r.path, maxBackups)) // Ignore error, there may be none
+	for i := maxBackups - 1; i >= 1; i-- {
+		os.Rename(backup(r.path, i), backup(r.path, i+1)) // Ignore error, there may be none
+	}
+	if err := os.Rename(r.path, backup(r.path, 1)); err != nil && !os.IsNotExist(err) {
+		r.file = nil
+		return err
+	}
+	if err := r.open(); err != nil {
+		r.file = nil
+		return err
+	}
+	return nil
+}
+
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+// backup returns the name of the nth rotated log.
+func backup(path string, n int) string {
+	return fmt.Sprintf("%s.%d", path, n)
+}
@@ -0,0 +1,129 @@
+// Package network builds the HTTP clients requests leave smartcommit
+// through, under the network settings in the config: a proxy, a CA bundle
+// to trust, or no verification at all.
+package network
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/arpxspace/smartcommit/internal/config"
+	"github.com/arpxspace/smartcommit/internal/logging"
+)
+
+var (
+	mu         sync.Mutex
+	transports = map[config.Network]*http.Transport{}
+)
+
+// Transport returns the transport for the settings n. It's made once per
+// settings, so connections are reused across clients.
+func Transport(n config.Network) (http.RoundTripper, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if t, ok := transports[n]; ok {
+		return t, nil
+	}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	if n.Proxy != "" {
+		if err := config.CheckProxy(n.Proxy); err != nil {
+			return nil, fmt.Errorf("invalid network.proxy: %w", err)
+		}
+		proxy, _ := url.Parse(n.Proxy)
+		noProxy := firstEnv("NO_PROXY", "no_proxy")
+		t.Proxy = func(req *http.Request) (*url.URL, error) {
+			if bypass(req.URL.Hostname(), noProxy) {
+				return nil, nil
+			}
+			return proxy, nil
+		}
+	}
+	if n.CABundle != "" || n.InsecureSkipVerify {
+		t.TLSClientConfig = &tls.Config{InsecureSkipVerify: n.InsecureSkipVerify}
+	}
+	if n.CABundle != "" {
+		pem, err := os.ReadFile(n.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read network.ca_bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("network.ca_bundle %s holds no PEM certificates", n.CABundle)
+		}
+		t.TLSClientConfig.RootCAs = pool
+	}
+	if n.InsecureSkipVerify {
+		logging.Warn("TLS certificates aren't verified", "setting", "network.insecure_skip_verify")
+	}
+	transports[n] = t
+	return t, nil
+}
+
+// Client returns a client sending requests through Transport(n), giving
+// up on any that take longer than timeout, or never when it's 0.
+func Client(n config.Network, timeout time.Duration) (*http.Client, error) {
+	t, err := Transport(n)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{Transport: t, Timeout: timeout}, nil
+}
+
+// bypass reports whether requests to host skip the proxy: those to the
+// local machine, as with the proxy from the environment, and to hosts
+// noProxy lists. Like NO_PROXY, it's a comma-separated list of "*", host
+// names that also cover their subdomains, IP addresses, and CIDR ranges.
+func bypass(host, noProxy string) bool {
+	host = strings.ToLower(host)
+	addr, err := netip.ParseAddr(host)
+	isIP := err == nil
+	if host == "localhost" || (isIP && addr.IsLoopback()) {
+		return true
+	}
+	for _, entry := range strings.Split(noProxy, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if h, _, err := net.SplitHostPort(entry); err == nil {
+			entry = h
+		}
+		switch {
+		case entry == "":
+		case entry == "*":
+			return true
+		case isIP:
+			if prefix, err := netip.ParsePrefix(entry); err == nil && prefix.Contains(addr) {
+				return true
+			}
+			if ip, err := netip.ParseAddr(entry); err == nil && ip == addr {
+				return true
+			}
+		default:
+			entry = strings.TrimPrefix(entry, ".")
+			if host == entry || strings.HasSuffix(host, "."+entry) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// firstEnv returns the first of the environment variables names that's set.
+func firstEnv(names ...string) string {
+	for _, name := range names {
+		if v := os.Getenv(name); v != "" {
+			return v
+		}
+	}
+	return ""
+}
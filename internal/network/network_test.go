@@ -0,0 +1,43 @@
+package network
+
+import "testing"
+
+func TestBypass(t *testing.T) {
+	tests := []struct {
+		name    string
+		host    string
+		noProxy string
+		want    bool
+	}{
+		{"localhost", "localhost", "", true},
+		{"loopback IPv4", "127.0.0.1", "", true},
+		{"loopback IPv6", "::1", "", true},
+		{"no list", "api.openai.com", "", false},
+		{"wildcard", "api.openai.com", "*", true},
+		{"exact host", "api.openai.com", "api.openai.com", true},
+		{"subdomain", "api.openai.com", "openai.com", true},
+		{"leading dot", "api.openai.com", ".openai.com", true},
+		{"suffix isn't a subdomain", "notopenai.com", "openai.com", false},
+		{"parent isn't covered", "openai.com", "api.openai.com", false},
+		{"case-insensitive", "API.OpenAI.com", "openai.COM", true},
+		{"among several", "ollama.internal", "example.com, ollama.internal ,10.0.0.0/8", true},
+		{"empty entries", "api.openai.com", ",, ,", false},
+		{"port is ignored", "ollama.internal", "ollama.internal:11434", true},
+		{"IP address", "192.168.1.5", "192.168.1.5", true},
+		{"other IP address", "192.168.1.6", "192.168.1.5", false},
+		{"CIDR range", "10.1.2.3", "10.0.0.0/8", true},
+		{"outside CIDR range", "11.1.2.3", "10.0.0.0/8", false},
+		{"IPv6 address", "2001:db8::1", "2001:db8::1", true},
+		{"IPv6 with port", "2001:db8::1", "[2001:db8::1]:443", true},
+		{"IPv6 CIDR range", "2001:db8::1", "2001:db8::/32", true},
+		{"name doesn't match IP", "10.1.2.3", "example.com", false},
+		{"CIDR doesn't match name", "example.com", "10.0.0.0/8", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := bypass(tt.host, tt.noProxy); got != tt.want {
+				t.Errorf("bypass(%q, %q) = %v, want %v", tt.host, tt.noProxy, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package provenance
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/arpxspace/smartcommit/internal/config"
+	"github.com/arpxspace/smartcommit/internal/git"
+)
+
+// NotesRef is the notes ref provenance records are stored under.
+const NotesRef = "smartcommit"
+
+const keyFile = "provenance.key"
+
+// Record describes how an AI-assisted commit message was produced.
+type Record struct {
+	Version    int       `json:"version"`
+	Tool       string    `json:"tool"`
+	Commit     string    `json:"commit"`
+	Provider   string    `json:"provider"`
+	Model      string    `json:"model"`
+	PromptHash string    `json:"prompt_sha256"`
+	Timestamp  time.Time `json:"timestamp"`
+	PublicKey  string    `json:"public_key,omitempty"`
+	Signature  string    `json:"signature,omitempty"`
+}
+
+// payload is the canonical encoding that gets signed: the record without
+// its signature.
+func (r Record) payload() ([]byte, error) {
+	r.Signature = ""
+	return json.Marshal(r)
+}
+
+// Sign signs the record with key, embedding the matching public key.
+func (r *Record) Sign(key ed25519.PrivateKey) error {
+	r.PublicKey = base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey))
+	data, err := r.payload()
+	if err != nil {
+		return err
+	}
+	r.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(key, data))
+	return nil
+}
+
+// Verify checks the record's signature against its embedded public key.
+// Callers that need to trust the signer must also compare PublicKey against
+// a known key.
+func (r Record) Verify() error {
+	pub, err := base64.StdEncoding.DecodeString(r.PublicKey)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return errors.New("invalid public key")
+	}
+	sig, err := base64.StdEncoding.DecodeString(r.Signature)
+	if err != nil {
+		return errors.New("invalid signature encoding")
+	}
+	data, err := r.payload()
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(pub, data, sig) {
+		return errors.New("signature does not match")
+	}
+	return nil
+}
+
+// Write signs the record with the local provenance key and attaches it to
+// the record's commit as a git note.
+func Write(r Record) error {
+	key, err := loadKey()
+	if err != nil {
+		return fmt.Errorf("failed to load provenance key: %w", err)
+	}
+	if err := r.Sign(key); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return git.AddNote(NotesRef, r.Commit, string(data))
+}
+
+// WriteHead records provenance for the commit at HEAD.
+func WriteHead(provider, model, promptHash string) error {
+	head, err := git.HeadCommit()
+	if err != nil {
+		return err
+	}
+	return Write(Record{
+		Version:    1,
+		Tool:       "smartcommit",
+		Commit:     head,
+		Provider:   provider,
+		Model:      model,
+		PromptHash: promptHash,
+		Timestamp:  time.Now().UTC(),
+	})
+}
+
+// loadKey reads the signing key from the config dir, generating one on first use.
+func loadKey() (ed25519.PrivateKey, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, keyFile)
+
+	data, err := os.ReadFile(path)
+	if err == nil {
+		seed, err := base64.StdEncoding.DecodeString(string(data))
+		if err != nil || len(seed) != ed25519.SeedSize {
+			return nil, fmt.Errorf("malformed key in %s", path)
+		}
+		return ed25519.NewKeyFromSeed(seed), nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	encoded := base64.StdEncoding.EncodeToString(key.Seed())
+	if err := os.WriteFile(path, []byte(encoded), 0600); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
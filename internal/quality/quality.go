@@ -0,0 +1,228 @@
+// Package quality rates how well a commit message serves a future reader:
+// whether it explains why the change was made, how specifically it says
+// what changed, and whether the change holds together as one commit.
+package quality
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/arpxspace/smartcommit/internal/ai"
+	"github.com/arpxspace/smartcommit/internal/conventional"
+	"github.com/arpxspace/smartcommit/internal/diff"
+	"github.com/arpxspace/smartcommit/internal/guard"
+)
+
+// Result rates a message from 0 to 100 on each dimension and overall.
+type Result struct {
+	Why         int
+	Specificity int
+	Cohesion    int
+	Score       int
+	// Suggestion is the single most useful improvement, or "" if the
+	// message needs none.
+	Suggestion string
+	// Judged is set when an AI judge contributed to the result.
+	Judged bool
+
+	// hints suggest how to improve each dimension.
+	hints [3]string
+}
+
+// The share of each dimension in the overall score, out of 100.
+const (
+	whyWeight         = 40
+	specificityWeight = 35
+	cohesionWeight    = 25
+)
+
+// good is the rating above which a dimension doesn't need a suggestion.
+const good = 80
+
+// because matches wording that gives a reason or a consequence.
+var because = regexp.MustCompile(`(?i)\b(because|since|so that|so it|in order to|otherwise|previously|instead of|caused|causing|led to|resulted in|which meant|without (this|it)|(fixes|closes|resolves|refs) #?\w|to (avoid|prevent|allow|support|keep|let|stop|ensure|make sure|reduce|speed up|match|handle))\b`)
+
+// specific matches the details that make a message specific: code
+// identifiers, paths, file names, options, numbers, and quoted text.
+var specific = regexp.MustCompile("`[^`]+`|\\b\\w+\\(\\)|\\b[a-z]+[A-Z]\\w*|\\b\\w+_\\w+\\b|\\S+/\\S+|\\b\\w+\\.(go|py|js|ts|rs|rb|java|md|json|ya?ml|toml|sh)\\b|--?[a-z][\\w-]+|\\b\\d+(\\.\\d+)*\\b|\"[^\"]+\"")
+
+// Rate rates message locally, for a change to paths, with the words in
+// vague counting against its specificity.
+func Rate(message string, paths []string, vague []string) Result {
+	message = strings.TrimSpace(message)
+	subject, body, _ := strings.Cut(message, "\n")
+	body = strings.TrimSpace(body)
+	var r Result
+	hints := &r.hints
+
+	// Why: a body, a reason given, and enough of it to follow.
+	switch {
+	case because.MatchString(body):
+		r.Why = 80
+	case because.MatchString(subject):
+		r.Why = 60
+	case body != "":
+		r.Why = 40
+		hints[0] = "Say why the change was made, such as the problem it solves; the body only says what changed"
+	default:
+		r.Why = 10
+		hints[0] = "Add a body explaining why the change was made"
+	}
+	if len(strings.Fields(body)) >= 20 {
+		r.Why += 20
+	}
+
+	// Specificity: no vague words, a subject that says something, and
+	// details a reader can search for.
+	r.Specificity = 100
+	spans := guard.Vague(message, vague)
+	var words []string
+	for _, s := range spans {
+		word := strings.ToLower(message[s.Start:s.End])
+		if slices.Contains(words, word) {
+			continue
+		}
+		words = append(words, word)
+		if s.Start < len(subject) {
+			r.Specificity -= 30
+		} else {
+			r.Specificity -= 15
+		}
+	}
+	if len(words) > 0 {
+		hints[1] = fmt.Sprintf("Replace %q with what specifically changed", words[0])
+	}
+	if n := len(strings.Fields(description(subject))); n < 3 {
+		r.Specificity -= 20
+		if hints[1] == "" {
+			hints[1] = "Say more in the subject than a word or two"
+		}
+	}
+	if !specific.MatchString(message) {
+		r.Specificity -= 15
+		if hints[1] == "" {
+			hints[1] = "Name the function, option, or behavior that changed"
+		}
+	}
+	if problems := guard.Check(message, 0); len(problems) > 0 {
+		r.Specificity -= 10 * min(len(problems), 2)
+		if hints[1] == "" {
+			hints[1] = capitalize(problems[0])
+		}
+	}
+	r.Specificity = max(r.Specificity, 0)
+
+	// Cohesion: the change stays in a few places and the subject describes
+	// one change, not a list of them.
+	r.Cohesion = 100
+	areas := areas(paths)
+	switch n := len(areas); {
+	case n >= 7:
+		r.Cohesion = 40
+	case n >= 5:
+		r.Cohesion = 55
+	case n == 4:
+		r.Cohesion = 70
+	case n == 3:
+		r.Cohesion = 85
+	}
+	if r.Cohesion < good {
+		hints[2] = fmt.Sprintf("The change spans %d areas (%s); consider splitting it into separate commits", len(areas), strings.Join(areas[:min(len(areas), 3)], ", ")+more(len(areas)-3))
+	}
+	if strings.Contains(description(subject), " and ") || strings.Contains(subject, "; ") {
+		r.Cohesion -= 20
+		if hints[2] == "" {
+			hints[2] = "The subject describes more than one change; consider a commit for each"
+		}
+	}
+	r.Cohesion = max(r.Cohesion, 0)
+
+	r.total()
+	return r
+}
+
+// WithJudge returns r blended evenly with an AI judge's scores. The judge's
+// verdict replaces the local suggestion when any dimension falls short.
+func (r Result) WithJudge(j *ai.ScoreResponse) Result {
+	if j == nil {
+		return r
+	}
+	r.Why = (r.Why + 10*clamp(j.WhyCoverage)) / 2
+	r.Specificity = (r.Specificity + 10*clamp(j.Clarity)) / 2
+	r.Cohesion = (r.Cohesion + 10*clamp(j.Cohesion)) / 2
+	r.Judged = true
+	r.total()
+	if r.Suggestion != "" && j.Verdict != "" {
+		r.Suggestion = j.Verdict
+	}
+	return r
+}
+
+// total computes the overall score and picks the suggestion for the
+// weakest dimension that falls short.
+func (r *Result) total() {
+	r.Score = (r.Why*whyWeight + r.Specificity*specificityWeight + r.Cohesion*cohesionWeight) / 100
+	r.Suggestion = ""
+	weakest := good
+	for i, v := range []int{r.Why, r.Specificity, r.Cohesion} {
+		if v < weakest {
+			weakest = v
+			r.Suggestion = r.hints[i]
+			if r.Suggestion == "" {
+				r.Suggestion = defaultHints[i]
+			}
+		}
+	}
+}
+
+// defaultHints suggest how to improve each dimension when no more specific
+// hint applies.
+var defaultHints = [3]string{
+	"Explain why the change was made, not just what changed",
+	"Be more specific about what changed",
+	"Keep the commit to one logical change",
+}
+
+// areas lists the directories paths are in, leaving out documentation and
+// dependency files that usually go along with a change.
+func areas(paths []string) []string {
+	var out []string
+	for _, p := range paths {
+		if diff.IsLockfile(p) || path.Ext(p) == ".md" || path.Base(p) == "go.mod" {
+			continue
+		}
+		if dir := path.Dir(p); !slices.Contains(out, dir) {
+			out = append(out, dir)
+		}
+	}
+	return out
+}
+
+// description returns the subject without its Conventional Commits prefix.
+func description(subject string) string {
+	if h, ok := conventional.Parse(subject); ok {
+		return h.Description
+	}
+	return subject
+}
+
+func more(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return fmt.Sprintf(", and %d more", n)
+}
+
+func clamp(n int) int {
+	return min(max(n, 0), 10)
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
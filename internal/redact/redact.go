@@ -0,0 +1,122 @@
+package redact
+
+import (
+	"math"
+	"regexp"
+	"strings"
+)
+
+// Placeholder replaces every redacted value.
+const Placeholder = "[REDACTED]"
+
+// pattern is a credential shape worth reporting by name.
+type pattern struct {
+	kind string
+	re   *regexp.Regexp
+}
+
+// patterns match common credential shapes: provider API keys, GitHub, AWS,
+// Slack, Google and Stripe tokens, JWTs, and PEM private key headers. A
+// shape that's also found inside ordinary words, like "sk-" in
+// "task-management-service", must start at a word boundary; as there's no
+// lookbehind, the character before it is matched as the "pre" group and
+// kept when redacting.
+var patterns = []pattern{
+	{"OpenAI API key", regexp.MustCompile(`(?P<pre>^|[^A-Za-z0-9_\-])sk-(?:proj-)?[A-Za-z0-9_\-]{20,}`)},
+	{"GitHub token", regexp.MustCompile(`gh[pousr]_[A-Za-z0-9]{20,}|github_pat_[A-Za-z0-9_]{22,}`)},
+	{"AWS access key", regexp.MustCompile(`AKIA[0-9A-Z]{16}`)},
+	{"Slack token", regexp.MustCompile(`xox[baprs]-[A-Za-z0-9\-]{10,}`)},
+	{"Google API key", regexp.MustCompile(`AIza[0-9A-Za-z_\-]{35}`)},
+	{"Stripe key", regexp.MustCompile(`[rs]k_live_[0-9A-Za-z]{16,}`)},
+	{"JSON Web Token", regexp.MustCompile(`eyJ[A-Za-z0-9_\-]{10,}\.eyJ[A-Za-z0-9_\-]{10,}\.[A-Za-z0-9_\-]{10,}`)},
+	{"private key", regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----`)},
+}
+
+// privateKeyBlock matches a whole PEM private key, or everything after its
+// header if the end isn't in s.
+var privateKeyBlock = regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?(?:-----END [A-Z ]*PRIVATE KEY-----|\z)`)
+
+// assignment matches a value assigned to a name that suggests a credential,
+// as in .env files, YAML, JSON and source code. The value is the last group.
+var assignment = regexp.MustCompile(`(?i)(key|secret|token|passw(?:or)?d|pwd|credential|auth)[A-Za-z0-9_\-]*["']?\s*[:=]\s*["']?([A-Za-z0-9+/=_\-.]{16,})`)
+
+// minEntropy is the Shannon entropy, in bits per character, above which an
+// assigned value looks random enough to be a credential rather than a word
+// or a reference like os.Getenv.
+const minEntropy = 3.5
+
+// Finding is a likely credential on one line of scanned text.
+type Finding struct {
+	// Line is the 1-based line number in the scanned text.
+	Line int
+	// Kind names what the credential looks like, e.g. "AWS access key".
+	Kind string
+}
+
+// Scan reports each line of s that looks like it holds a credential.
+func Scan(s string) []Finding {
+	var findings []Finding
+	for i, line := range strings.Split(s, "\n") {
+		if kind := lineKind(line); kind != "" {
+			findings = append(findings, Finding{Line: i + 1, Kind: kind})
+		}
+	}
+	return findings
+}
+
+// lineKind names the first credential found on line, or returns "".
+func lineKind(line string) string {
+	for _, p := range patterns {
+		if p.re.MatchString(line) {
+			return p.kind
+		}
+	}
+	for _, m := range assignment.FindAllStringSubmatch(line, -1) {
+		if entropy(m[2]) >= minEntropy {
+			return "high-entropy " + strings.ToLower(m[1])
+		}
+	}
+	return ""
+}
+
+// ContainsSecret reports whether s contains anything that looks like a credential.
+func ContainsSecret(s string) bool {
+	return len(Scan(s)) > 0
+}
+
+// Secrets replaces anything that looks like a credential in s, along with
+// any of the given known values (e.g. the configured API key).
+func Secrets(s string, known ...string) string {
+	for _, k := range known {
+		if k != "" {
+			s = strings.ReplaceAll(s, k, Placeholder)
+		}
+	}
+	s = privateKeyBlock.ReplaceAllString(s, Placeholder)
+	for _, p := range patterns {
+		// ${pre} is empty for patterns without the group.
+		s = p.re.ReplaceAllString(s, "${pre}"+Placeholder)
+	}
+	return assignment.ReplaceAllStringFunc(s, func(match string) string {
+		value := assignment.FindStringSubmatch(match)[2]
+		if entropy(value) < minEntropy {
+			return match
+		}
+		return strings.TrimSuffix(match, value) + Placeholder
+	})
+}
+
+// entropy is the Shannon entropy of s in bits per character.
+func entropy(s string) float64 {
+	counts := make(map[rune]int)
+	for _, r := range s {
+		counts[r]++
+	}
+	n := float64(len([]rune(s)))
+	var h float64
+	for _, c := range counts {
+		p := float64(c) / n
+		h -= p * math.Log2(p)
+	}
+	return h
+}
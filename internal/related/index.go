@@ -0,0 +1,147 @@
+// Package related picks the past commits most relevant to a change by
+// ranking their messages by embedding similarity to its diff.
+package related
+
+import (
+	"context"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/arpxspace/smartcommit/internal/ai"
+	"github.com/arpxspace/smartcommit/internal/config"
+	"github.com/arpxspace/smartcommit/internal/git"
+	"github.com/arpxspace/smartcommit/internal/store"
+	"github.com/arpxspace/smartcommit/internal/tokens"
+)
+
+const (
+	// maxQueryTokens bounds the part of the diff embedded, well under the
+	// input limit of common embedding models.
+	maxQueryTokens = 2000
+
+	// batchSize is how many messages are embedded per request.
+	batchSize = 100
+)
+
+// Rank returns commits ordered by how similar their messages are to diff,
+// most similar first. Message embeddings are cached in the session store
+// unless it's disabled, so each commit is only embedded once per model.
+func Rank(ctx context.Context, client ai.Provider, cfg *config.Config, commits []git.Commit, diff string) ([]git.Commit, error) {
+	vectors, err := index(ctx, client, cfg, commits)
+	if err != nil {
+		return nil, err
+	}
+	query, err := client.Embed(ctx, []string{clip(diff, maxQueryTokens)})
+	if err != nil {
+		return nil, err
+	}
+
+	scores := make(map[string]float64, len(commits))
+	for _, c := range commits {
+		scores[c.Hash] = cosine(query[0], vectors[c.Hash])
+	}
+	ranked := slices.Clone(commits)
+	slices.SortStableFunc(ranked, func(a, b git.Commit) int {
+		switch sa, sb := scores[a.Hash], scores[b.Hash]; {
+		case sa > sb:
+			return -1
+		case sa < sb:
+			return 1
+		}
+		return 0
+	})
+	return ranked, nil
+}
+
+// index returns an embedding of each commit's message by hash, reusing
+// cached ones.
+func index(ctx context.Context, client ai.Provider, cfg *config.Config, commits []git.Commit) (map[string][]float64, error) {
+	model := cfg.Embedder()
+	hashes := make([]string, len(commits))
+	for i, c := range commits {
+		hashes[i] = c.Hash
+	}
+
+	var s *store.Store
+	vectors := make(map[string][]float64)
+	if !cfg.DisableStore {
+		// The cache only saves requests; carry on without it.
+		if opened, err := store.Open(); err == nil {
+			s = opened
+			defer s.Close()
+			if cached, err := s.Embeddings(model, hashes); err == nil {
+				vectors = cached
+			}
+		}
+	}
+
+	var missing []git.Commit
+	for _, c := range commits {
+		if _, ok := vectors[c.Hash]; !ok {
+			missing = append(missing, c)
+		}
+	}
+	fresh := make(map[string][]float64)
+	for batch := range slices.Chunk(missing, batchSize) {
+		texts := make([]string, len(batch))
+		for i, c := range batch {
+			texts[i] = message(c)
+		}
+		embedded, err := client.Embed(ctx, texts)
+		if err != nil {
+			return nil, err
+		}
+		for i, c := range batch {
+			fresh[c.Hash] = embedded[i]
+			vectors[c.Hash] = embedded[i]
+		}
+	}
+	if s != nil && len(fresh) > 0 {
+		s.SaveEmbeddings(model, fresh)
+	}
+	return vectors, nil
+}
+
+func message(c git.Commit) string {
+	if c.Body == "" {
+		return c.Subject
+	}
+	return c.Subject + "\n\n" + c.Body
+}
+
+// clip keeps the lines of s that fit in n tokens.
+func clip(s string, n int) string {
+	var b strings.Builder
+	used := 0
+	for _, line := range strings.SplitAfter(s, "\n") {
+		used += tokens.Estimate(line)
+		if used > n {
+			break
+		}
+		b.WriteString(line)
+	}
+	if b.Len() == 0 {
+		// A single huge line: cut it by an estimate instead.
+		return string([]rune(s)[:min(len([]rune(s)), 4*n)])
+	}
+	return b.String()
+}
+
+// cosine returns the cosine similarity of a and b, or 0 if either is empty
+// or they differ in length.
+func cosine(a, b []float64) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += a[i] * b[i]
+		na += a[i] * a[i]
+		nb += b[i] * b[i]
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
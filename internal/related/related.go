@@ -0,0 +1,122 @@
+package related
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/arpxspace/smartcommit/internal/ai"
+	"github.com/arpxspace/smartcommit/internal/config"
+	"github.com/arpxspace/smartcommit/internal/git"
+)
+
+const (
+	// Candidates is how many commits are ranked: the latest ones for style
+	// examples, and the latest to touch the changed files for related history.
+	Candidates = 200
+
+	// Commits is how many related commits are sent, as many as the recent
+	// history they replace.
+	Commits = 10
+
+	// maxPaths bounds how many changed files' history is searched.
+	maxPaths = 50
+)
+
+// Enabled reports whether cfg replaces the recent history with related
+// commits or style examples.
+func Enabled(cfg *config.Config) bool {
+	return cfg.RelatedHistory || cfg.Examples() > 0
+}
+
+// History returns the history to send with diff, in the format of
+// git.GetRecentHistory. With related_history, it's the commits that touched
+// paths whose messages are most similar to diff, rather than the latest
+// ones. With style_examples, the latest commits most similar to diff come
+// first as examples of the project's style. It returns "" when neither is on.
+func History(ctx context.Context, client ai.Provider, cfg *config.Config, paths []string, diff string) (string, error) {
+	pool, err := Gather(cfg, paths)
+	if err != nil {
+		return "", err
+	}
+	return pool.History(ctx, client, cfg, diff)
+}
+
+// Pool is the commits History chooses from. Gathering it reads the
+// repository and choosing from it only calls the provider, so callers that
+// serialize access to the working directory can choose without holding it.
+type Pool struct {
+	// latest are the candidate style examples.
+	latest []git.Commit
+	// touching are the candidate related commits.
+	touching []git.Commit
+}
+
+// Gather reads the candidate commits for cfg from the repository in the
+// working directory.
+func Gather(cfg *config.Config, paths []string) (Pool, error) {
+	var pool Pool
+	if cfg.Examples() > 0 {
+		commits, err := git.GetLog(Candidates)
+		if err != nil {
+			return Pool{}, err
+		}
+		pool.latest = slices.DeleteFunc(commits, func(c git.Commit) bool {
+			return strings.TrimSpace(c.Subject) == ""
+		})
+	}
+	if cfg.RelatedHistory {
+		commits, err := git.GetCommitsTouchingFiles(paths[:min(len(paths), maxPaths)], Candidates)
+		if err != nil {
+			return Pool{}, err
+		}
+		if len(commits) == 0 {
+			// None of the files have history yet; the latest commits will do.
+			if commits, err = git.GetLog(Commits); err != nil {
+				return Pool{}, err
+			}
+		}
+		pool.touching = commits
+	}
+	return pool, nil
+}
+
+// History chooses from the pool as History does.
+func (p Pool) History(ctx context.Context, client ai.Provider, cfg *config.Config, diff string) (string, error) {
+	if !Enabled(cfg) {
+		return "", nil
+	}
+	var commits []git.Commit
+	if n := cfg.Examples(); n > 0 {
+		examples, err := top(ctx, client, cfg, p.latest, diff, n)
+		if err != nil {
+			return "", err
+		}
+		commits = examples
+	}
+	if cfg.RelatedHistory {
+		touching, err := top(ctx, client, cfg, p.touching, diff, Commits)
+		if err != nil {
+			return "", err
+		}
+		for _, c := range touching {
+			if !slices.ContainsFunc(commits, func(e git.Commit) bool { return e.Hash == c.Hash }) {
+				commits = append(commits, c)
+			}
+		}
+	}
+	return git.FormatHistory(commits), nil
+}
+
+// top returns the n of commits most similar to diff, or all of them if
+// there are no more than n.
+func top(ctx context.Context, client ai.Provider, cfg *config.Config, commits []git.Commit, diff string, n int) ([]git.Commit, error) {
+	if len(commits) <= n {
+		return commits, nil
+	}
+	ranked, err := Rank(ctx, client, cfg, commits, diff)
+	if err != nil {
+		return nil, err
+	}
+	return ranked[:n], nil
+}
@@ -0,0 +1,54 @@
+package secret
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// macKeychain stores secrets as generic passwords with the security tool.
+type macKeychain struct{}
+
+func keyring() (Store, error) {
+	if _, err := exec.LookPath("security"); err != nil {
+		return nil, ErrUnsupported
+	}
+	return macKeychain{}, nil
+}
+
+func (macKeychain) Get(key string) (string, error) {
+	out, err := exec.Command("security", "find-generic-password", "-s", Service, "-a", key, "-w").Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 44 {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s from the keychain: %w", key, err)
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
+
+func (macKeychain) Set(key, value string) error {
+	// Pass the command on stdin, hex-encoded, so the secret never shows up
+	// in the process list.
+	cmd := exec.Command("security", "-i")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %s -a %s -X %s\n", Service, key, hex.EncodeToString([]byte(value))))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to save %s to the keychain: %w: %s", key, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func (macKeychain) Delete(key string) error {
+	err := exec.Command("security", "delete-generic-password", "-s", Service, "-a", key).Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 44 {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete %s from the keychain: %w", key, err)
+	}
+	return nil
+}
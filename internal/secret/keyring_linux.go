@@ -0,0 +1,48 @@
+package secret
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// secretService stores secrets in the Secret Service (GNOME Keyring,
+// KWallet, KeePassXC, ...) with libsecret's secret-tool.
+type secretService struct{}
+
+func keyring() (Store, error) {
+	if _, err := exec.LookPath("secret-tool"); err != nil {
+		return nil, ErrUnsupported
+	}
+	return secretService{}, nil
+}
+
+func (secretService) Get(key string) (string, error) {
+	out, err := exec.Command("secret-tool", "lookup", "service", Service, "account", key).Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && len(exitErr.Stderr) == 0 {
+		// secret-tool exits 1 without a message when nothing matches.
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s from the Secret Service: %w", key, err)
+	}
+	return string(out), nil
+}
+
+func (secretService) Set(key, value string) error {
+	cmd := exec.Command("secret-tool", "store", "--label", Service+" "+key, "service", Service, "account", key)
+	cmd.Stdin = strings.NewReader(value)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to save %s to the Secret Service: %w: %s", key, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func (secretService) Delete(key string) error {
+	if out, err := exec.Command("secret-tool", "clear", "service", Service, "account", key).CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to delete %s from the Secret Service: %w: %s", key, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
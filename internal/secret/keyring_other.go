@@ -0,0 +1,7 @@
+//go:build !darwin && !linux && !windows
+
+package secret
+
+func keyring() (Store, error) {
+	return nil, ErrUnsupported
+}
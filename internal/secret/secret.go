@@ -0,0 +1,28 @@
+// Package secret keeps credentials such as API keys in the operating
+// system's keychain instead of the config file.
+package secret
+
+import "errors"
+
+// Service is the name secrets are filed under in the keychain.
+const Service = "smartcommit"
+
+// ErrNotFound is returned by Get when no secret is stored under the key.
+var ErrNotFound = errors.New("secret not found")
+
+// ErrUnsupported is returned by Keyring when this system has no keychain
+// smartcommit knows how to use.
+var ErrUnsupported = errors.New("no supported keychain on this system")
+
+// Store holds secrets by key.
+type Store interface {
+	Get(key string) (string, error)
+	Set(key, value string) error
+	Delete(key string) error
+}
+
+// Keyring returns the operating system's keychain: the macOS Keychain, the
+// Secret Service (via secret-tool) on Linux, or the Windows Credential Manager.
+func Keyring() (Store, error) {
+	return keyring()
+}
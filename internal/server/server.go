@@ -0,0 +1,472 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/arpxspace/smartcommit/internal/ai"
+	"github.com/arpxspace/smartcommit/internal/changelog"
+	"github.com/arpxspace/smartcommit/internal/config"
+	"github.com/arpxspace/smartcommit/internal/diff"
+	"github.com/arpxspace/smartcommit/internal/git"
+	"github.com/arpxspace/smartcommit/internal/glob"
+	"github.com/arpxspace/smartcommit/internal/redact"
+	"github.com/arpxspace/smartcommit/internal/related"
+	"github.com/arpxspace/smartcommit/internal/staged"
+	"github.com/arpxspace/smartcommit/internal/symbols"
+	"github.com/arpxspace/smartcommit/internal/usage"
+)
+
+// maxRequestBytes bounds request bodies; diffs larger than this are better
+// handled by the interactive flow anyway.
+const maxRequestBytes = 4 << 20
+
+// Server exposes smartcommit's pipeline over HTTP for the repositories listed
+// in the config.
+type Server struct {
+	cfg    *config.Config
+	client ai.Provider
+	// repos maps a repository's name (its directory name) to its root.
+	repos map[string]string
+	// token is the bearer token every request must carry.
+	token string
+	// hosts are the names requests may be addressed to.
+	hosts map[string]bool
+
+	// The git helpers run in the current directory, so requests touching a
+	// repository are serialized and chdir into it.
+	mu sync.Mutex
+}
+
+// New creates a server for cfg.ServeRepos, listening on addr and accepting
+// requests that carry token.
+func New(cfg *config.Config, client ai.Provider, addr, token string) (*Server, error) {
+	if len(cfg.ServeRepos) == 0 {
+		return nil, fmt.Errorf("no repositories configured; add their paths to serve_repos in the config")
+	}
+	if token == "" {
+		return nil, fmt.Errorf("a token is required")
+	}
+	hosts := map[string]bool{"localhost": true, "127.0.0.1": true, "::1": true}
+	if host, _, err := net.SplitHostPort(addr); err == nil && host != "" {
+		hosts[host] = true
+	}
+	repos := make(map[string]string, len(cfg.ServeRepos))
+	for _, path := range cfg.ServeRepos {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %s: %w", path, err)
+		}
+		if _, err := os.Stat(filepath.Join(abs, ".git")); err != nil {
+			return nil, fmt.Errorf("%s is not a git repository", abs)
+		}
+		name := filepath.Base(abs)
+		if other, ok := repos[name]; ok {
+			return nil, fmt.Errorf("repositories %s and %s have the same name", other, abs)
+		}
+		repos[name] = abs
+	}
+	return &Server{cfg: cfg, client: client, repos: repos, token: token, hosts: hosts}, nil
+}
+
+// Handler returns the HTTP handler serving the API.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos", s.handleRepos)
+	mux.HandleFunc("/generate", post(s.tracked(s.handleGenerate)))
+	mux.HandleFunc("/critique", post(s.tracked(s.handleCritique)))
+	mux.HandleFunc("/summarize-diff", post(s.handleSummarize))
+	mux.HandleFunc("/changelog", post(s.handleChangelog))
+	return s.guard(mux)
+}
+
+// guard refuses requests without the token, and requests addressed to any
+// other host: a web page that rebinds its own domain to the loopback
+// address can reach the server, but its requests still name that domain.
+func (s *Server) guard(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+		if !s.hosts[strings.Trim(host, "[]")] {
+			writeError(w, http.StatusForbidden, fmt.Errorf("unexpected host %q", r.Host))
+			return
+		}
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, fmt.Errorf("missing or invalid token"))
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// tracked records the provider usage of each request to the local usage log.
+// Concurrent requests may be attributed to each other, but totals are exact.
+func (s *Server) tracked(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		before := s.client.Usage()
+		h(w, r)
+		usage.Record(s.cfg, "serve", s.client, s.client.Usage().Sub(before)) // Ignore error, not critical
+	}
+}
+
+type generateRequest struct {
+	// Repo is the repository name or root. Its recent history is used as
+	// context, and its staged diff when Diff is empty.
+	Repo    string            `json:"repo"`
+	Diff    string            `json:"diff,omitempty"`
+	Answers map[string]string `json:"answers,omitempty"`
+}
+
+type generateResponse struct {
+	Message string `json:"message"`
+}
+
+func (s *Server) handleGenerate(w http.ResponseWriter, r *http.Request) {
+	var req generateRequest
+	if !decode(w, r, &req) {
+		return
+	}
+
+	var d, history string
+	var pool related.Pool
+	err := s.inRepo(req.Repo, func() error {
+		var err error
+		d, err = s.diff(req.Diff)
+		if err != nil {
+			return err
+		}
+		if history, err = git.GetRecentHistory(10); err != nil {
+			return err
+		}
+		// Related commits are best effort; the recent history will do.
+		pool, _ = related.Gather(s.cfg, diff.Paths(diff.Parse(d)))
+		return nil
+	})
+	if err != nil {
+		writeError(w, statusFor(err), err)
+		return
+	}
+	if strings.TrimSpace(d) == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("nothing to describe: the diff is empty"))
+		return
+	}
+	// Ranking the candidates calls the provider, so it's done after leaving
+	// the repository rather than holding up requests for other ones.
+	if h, err := pool.History(r.Context(), s.client, s.cfg, d); err == nil && h != "" {
+		history = h
+	}
+	history = staged.Context{Config: s.cfg}.FitHistory(history)
+
+	msg, err := s.client.GenerateCommitMessage(r.Context(), d, history, req.Answers)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJSON(w, generateResponse{Message: msg})
+}
+
+type critiqueRequest struct {
+	Repo string `json:"repo"`
+	// Commit is scored when set; otherwise Message is scored against Diff
+	// (or the repo's staged diff).
+	Commit  string `json:"commit,omitempty"`
+	Diff    string `json:"diff,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+func (s *Server) handleCritique(w http.ResponseWriter, r *http.Request) {
+	var req critiqueRequest
+	if !decode(w, r, &req) {
+		return
+	}
+
+	if strings.HasPrefix(req.Commit, "-") {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid revision"))
+		return
+	}
+	d, message := req.Diff, req.Message
+	err := s.inRepo(req.Repo, func() error {
+		if req.Commit == "" {
+			var err error
+			d, err = s.diff(d)
+			return err
+		}
+		sha, err := git.ResolveCommit(req.Commit)
+		if err != nil {
+			return badRequest(err)
+		}
+		if d, err = git.CommitDiff(sha); err != nil {
+			return err
+		}
+		message, err = git.CommitMessage(sha)
+		return err
+	})
+	if err != nil {
+		writeError(w, statusFor(err), err)
+		return
+	}
+	if strings.TrimSpace(message) == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("message or commit is required"))
+		return
+	}
+
+	score, err := s.client.ScoreMessage(r.Context(), d, message)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJSON(w, score)
+}
+
+type summarizeRequest struct {
+	Repo string `json:"repo"`
+	Diff string `json:"diff,omitempty"`
+}
+
+type summarizeFile struct {
+	Path    string `json:"path"`
+	OldPath string `json:"old_path,omitempty"`
+	Bytes   int    `json:"bytes"`
+}
+
+type summarizeResponse struct {
+	Files     []summarizeFile `json:"files"`
+	Symbols   string          `json:"symbols,omitempty"`
+	API       string          `json:"api,omitempty"`
+	Breaking  bool            `json:"breaking"`
+	Sensitive []string        `json:"sensitive,omitempty"`
+}
+
+// handleSummarize describes a diff without calling the provider: the files
+// it touches, changed Go symbols and exported API, and sensitive paths.
+// Symbols are only available for the repo's staged diff.
+func (s *Server) handleSummarize(w http.ResponseWriter, r *http.Request) {
+	var req summarizeRequest
+	if !decode(w, r, &req) {
+		return
+	}
+
+	var resp summarizeResponse
+	err := s.inRepo(req.Repo, func() error {
+		raw := req.Diff
+		if raw == "" {
+			var err error
+			if raw, err = git.GetStagedDiff(s.cfg.Diff.Args()...); err != nil {
+				return err
+			}
+		}
+		files := diff.Parse(raw)
+		paths := diff.Paths(files)
+		for _, f := range files {
+			sf := summarizeFile{Path: f.Path, Bytes: len(f.Content)}
+			if f.OldPath != f.Path {
+				sf.OldPath = f.OldPath
+			}
+			resp.Files = append(resp.Files, sf)
+			if glob.MatchAny(s.cfg.Sensitive(), f.Path) {
+				resp.Sensitive = append(resp.Sensitive, f.Path)
+			}
+		}
+		if req.Diff == "" {
+			changes := symbols.ForDiff(files)
+			resp.Symbols = symbols.Summary(changes, paths)
+			if api := symbols.API(changes, paths); len(api) > 0 {
+				resp.API = symbols.APISummary(api)
+				resp.Breaking = symbols.HasBreaking(api)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		writeError(w, statusFor(err), err)
+		return
+	}
+	writeJSON(w, resp)
+}
+
+type changelogRequest struct {
+	Repo string `json:"repo"`
+	// From is the exclusive start of the range, typically the last release
+	// tag. Empty means the whole history.
+	From string `json:"from,omitempty"`
+	// To is the inclusive end of the range; it defaults to HEAD.
+	To string `json:"to,omitempty"`
+}
+
+type changelogResponse struct {
+	Sections []changelog.Section `json:"sections"`
+	Markdown string              `json:"markdown"`
+}
+
+func (s *Server) handleChangelog(w http.ResponseWriter, r *http.Request) {
+	var req changelogRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	if strings.HasPrefix(req.From, "-") || strings.HasPrefix(req.To, "-") {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid revision"))
+		return
+	}
+	to := req.To
+	if to == "" {
+		to = "HEAD"
+	}
+	revRange := to
+	if req.From != "" {
+		revRange = req.From + ".." + to
+	}
+
+	var commits []git.Commit
+	err := s.inRepo(req.Repo, func() error {
+		var err error
+		commits, err = git.GetLogRange(revRange)
+		if err != nil {
+			return badRequest(err)
+		}
+		return nil
+	})
+	if err != nil {
+		writeError(w, statusFor(err), err)
+		return
+	}
+	sections := changelog.Build(commits)
+	writeJSON(w, changelogResponse{Sections: sections, Markdown: changelog.Markdown(sections)})
+}
+
+func (s *Server) handleRepos(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("use GET"))
+		return
+	}
+	writeJSON(w, s.repos)
+}
+
+// diff returns the text to send for a request: the given diff, or the repo's
+// staged diff prepared as in the interactive flow. Secrets are redacted when
+// privacy review is on, since there's no one to review the files.
+func (s *Server) diff(given string) (string, error) {
+	if given != "" {
+		if s.cfg.PrivacyReview {
+			return redact.Secrets(given), nil
+		}
+		return given, nil
+	}
+	change, err := staged.Collect(s.cfg)
+	if err != nil {
+		return "", badRequest(err)
+	}
+	return change.Context(s.cfg).Diff(), nil
+}
+
+// inRepo runs fn with the working directory set to the named repository.
+// An empty name is allowed when only one repository is configured.
+func (s *Server) inRepo(name string, fn func() error) error {
+	root, err := s.resolve(name)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prev, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	if err := os.Chdir(root); err != nil {
+		return fmt.Errorf("failed to enter %s: %w", root, err)
+	}
+	defer os.Chdir(prev)
+	return fn()
+}
+
+func (s *Server) resolve(name string) (string, error) {
+	if name == "" {
+		if len(s.repos) == 1 {
+			for _, root := range s.repos {
+				return root, nil
+			}
+		}
+		return "", badRequest(fmt.Errorf("repo is required"))
+	}
+	if root, ok := s.repos[name]; ok {
+		return root, nil
+	}
+	if abs, err := filepath.Abs(name); err == nil {
+		for _, root := range s.repos {
+			if root == abs {
+				return root, nil
+			}
+		}
+	}
+	return "", &requestError{status: http.StatusForbidden, err: fmt.Errorf("repository %q is not configured for serving", name)}
+}
+
+// requestError is an error caused by the request rather than the server.
+type requestError struct {
+	status int
+	err    error
+}
+
+func (e *requestError) Error() string { return e.err.Error() }
+func (e *requestError) Unwrap() error { return e.err }
+
+func badRequest(err error) error {
+	return &requestError{status: http.StatusBadRequest, err: err}
+}
+
+func statusFor(err error) int {
+	if re, ok := err.(*requestError); ok {
+		return re.status
+	}
+	return http.StatusInternalServerError
+}
+
+func post(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("use POST"))
+			return
+		}
+		h(w, r)
+	}
+}
+
+func decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	// Only JSON is accepted, so browsers have to ask before sending a
+	// request from another origin.
+	if mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mt != "application/json" {
+		writeError(w, http.StatusUnsupportedMediaType, fmt.Errorf("Content-Type must be application/json"))
+		return false
+	}
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request: %w", err))
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,681 @@
+package staged
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/arpxspace/smartcommit/internal/config"
+	"github.com/arpxspace/smartcommit/internal/diff"
+	"github.com/arpxspace/smartcommit/internal/git"
+	"github.com/arpxspace/smartcommit/internal/github"
+	"github.com/arpxspace/smartcommit/internal/glob"
+	"github.com/arpxspace/smartcommit/internal/network"
+	"github.com/arpxspace/smartcommit/internal/redact"
+	"github.com/arpxspace/smartcommit/internal/symbols"
+	"github.com/arpxspace/smartcommit/internal/ticket"
+	"github.com/arpxspace/smartcommit/internal/tokens"
+	"github.com/arpxspace/smartcommit/internal/trailer"
+)
+
+// Token budgets for one request.
+const (
+	// MaxDiffTokens caps the diff sent in one request whatever the model's
+	// context window, to keep requests quick and cheap.
+	MaxDiffTokens = 16000
+	// ReservedTokens is set aside for the system prompt, recent history,
+	// answers, and the reply. Small windows reserve half instead.
+	ReservedTokens = 6000
+	// minFileTokens is the least a file is truncated to before a change is
+	// summarized in parts instead.
+	minFileTokens = 400
+)
+
+// MaxParts bounds how many parts a change too large for one request is
+// split into for summarizing; beyond it the change is written by hand.
+const MaxParts = 20
+
+// ErrNothingStaged is returned by Collect when the index matches HEAD.
+var ErrNothingStaged = errors.New("no staged changes found")
+
+// Change is the staged change of the repository in the current directory.
+type Change struct {
+	Root       string
+	RepoConfig *config.RepoConfig
+	Files      []diff.File
+	// Patchable are the staged files as a plain `git diff --cached` shows
+	// them. Patches are built from these: Files may come from a diff with
+	// options git apply can't take back, such as -w or -U0.
+	Patchable []diff.File
+	Symbols   map[string][]symbols.Change
+	// Scopes are the enclosing declarations added to each file's hunks, as
+	// returned by diff.File.Scopes, when the config asks for them.
+	Scopes map[string][]string
+	// Ticket is the issue key found in the branch name, if any.
+	Ticket string
+	// Issue is the GitHub issue the change is for, if one was fetched.
+	Issue string
+	// Signer is who signs off the commit when the config asks for it.
+	Signer string
+}
+
+// Configure applies the repo config of the repository in the current
+// directory on top of cfg.
+func Configure(cfg *config.Config) (*config.Config, error) {
+	root, err := git.RepoRoot()
+	if err != nil {
+		return nil, err
+	}
+	repoCfg, err := config.LoadRepo(root)
+	if err != nil {
+		return nil, err
+	}
+	cfg = cfg.WithRepo(repoCfg)
+	if cfg.Conventions.ScopesFromDirs {
+		// A repository without commits has no tree to list yet.
+		dirs, _ := git.TopLevelDirs()
+		cfg.Conventions.Scopes = slices.Concat(cfg.Conventions.Scopes, dirs)
+	}
+	return cfg, nil
+}
+
+// Collect reads the staged change using the configured diff options.
+func Collect(cfg *config.Config) (*Change, error) {
+	raw, err := git.GetStagedDiff(cfg.Diff.Args()...)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(raw) == "" {
+		return nil, ErrNothingStaged
+	}
+	c, err := collect(cfg, raw)
+	if err != nil {
+		return nil, err
+	}
+	c.Patchable = c.Files
+	if len(cfg.Diff.Args()) > 0 {
+		plain, err := git.GetStagedDiff()
+		if err != nil {
+			return nil, err
+		}
+		c.Patchable = diff.Parse(plain)
+	}
+	return c, nil
+}
+
+// CollectAmend reads the change HEAD will make once amended with what's
+// staged, using the configured diff options.
+func CollectAmend(cfg *config.Config) (*Change, error) {
+	raw, err := git.AmendDiff(cfg.Diff.Args()...)
+	if err != nil {
+		return nil, err
+	}
+	return collect(cfg, raw)
+}
+
+// FromDiff reads a change from a diff made elsewhere, such as a patch or a
+// diff between branches. Nothing is read from the working tree: the repo
+// config of the repository in the current directory applies, if there is
+// one, but there are no Go API changes, enclosing declarations, ticket, or
+// sign-off.
+func FromDiff(cfg *config.Config, raw string) (*Change, error) {
+	if err := checkFilters(cfg); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(raw) == "" {
+		return nil, errors.New("the diff is empty")
+	}
+	files := diff.Parse(raw)
+	if len(files) == 0 {
+		return nil, errors.New("no changed files found in the diff; expected the output of git diff")
+	}
+	c := &Change{RepoConfig: &config.RepoConfig{}, Files: files}
+	if git.IsRepo() {
+		root, err := git.RepoRoot()
+		if err != nil {
+			return nil, err
+		}
+		if c.RepoConfig, err = config.LoadRepo(root); err != nil {
+			return nil, err
+		}
+		c.Root = root
+	}
+	return c, nil
+}
+
+func checkFilters(cfg *config.Config) error {
+	for _, name := range cfg.Filters() {
+		if !slices.Contains(diff.FilterNames, name) {
+			return fmt.Errorf("unknown diff filter %q; use any of %s", name, strings.Join(diff.FilterNames, ", "))
+		}
+	}
+	return nil
+}
+
+func collect(cfg *config.Config, raw string) (*Change, error) {
+	if err := checkFilters(cfg); err != nil {
+		return nil, err
+	}
+	root, err := git.RepoRoot()
+	if err != nil {
+		return nil, err
+	}
+	repoCfg, err := config.LoadRepo(root)
+	if err != nil {
+		return nil, err
+	}
+	key, err := Ticket(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var signer string
+	if cfg.SignOff {
+		if signer, err = git.UserIdent(); err != nil {
+			return nil, err
+		}
+	}
+	files := diff.Parse(raw)
+	return &Change{
+		Root:       root,
+		RepoConfig: repoCfg,
+		Files:      files,
+		Symbols:    symbols.ForDiff(files),
+		Scopes:     Scopes(cfg, files),
+		Ticket:     key,
+		Signer:     signer,
+	}, nil
+}
+
+// Scopes finds the start of the declaration enclosing each hunk of files,
+// reading the staged files, when the diff options ask for it.
+func Scopes(cfg *config.Config, files []diff.File) map[string][]string {
+	n := cfg.Diff.EnclosingLines
+	if n <= 0 || cfg.Diff.FunctionContext {
+		return nil
+	}
+	scopes := make(map[string][]string)
+	for _, f := range files {
+		src, err := git.GetStagedFile(f.Path)
+		if err != nil {
+			// A file that can't be read just gets no scopes.
+			continue
+		}
+		scopes[f.Path] = f.Scopes(src, n)
+	}
+	return scopes
+}
+
+// Ticket returns the issue key in the current branch name, or "" if there's
+// none.
+func Ticket(cfg *config.Config) (string, error) {
+	branch, err := git.CurrentBranch()
+	if err != nil {
+		return "", err
+	}
+	key, err := ticket.Find(branch, cfg.Ticket.Pattern)
+	if err != nil {
+		return "", fmt.Errorf("invalid ticket pattern: %w", err)
+	}
+	return key, nil
+}
+
+// maxIssueTokens bounds how much of an issue's description is sent.
+const maxIssueTokens = 1000
+
+// FetchIssue fetches the GitHub issue the change is for: number, or when
+// it's 0 and the config asks for it, the number in the branch name. It
+// does nothing when there's no number or the origin remote isn't on GitHub.
+func (c *Change) FetchIssue(ctx context.Context, cfg *config.Config, number int) error {
+	if number == 0 && cfg.GitHub.Issues {
+		branch, err := git.CurrentBranch()
+		if err != nil {
+			return err
+		}
+		number = github.IssueNumber(branch)
+	}
+	if number == 0 {
+		return nil
+	}
+	repo, ok := github.Repo(git.RemoteURL("origin"), github.Host(cfg.GitHub.APIURL))
+	if !ok {
+		return fmt.Errorf("can't fetch issue #%d: the origin remote isn't a GitHub repository", number)
+	}
+	client, err := network.Client(cfg.Network, 10*time.Second)
+	if err != nil {
+		return err
+	}
+	issue, err := github.NewClient(cfg.GitHub.APIURL, cfg.GitHub.Token(), client).Issue(ctx, repo, number)
+	if err != nil {
+		return err
+	}
+	body := strings.TrimSpace(issue.Body)
+	if n := tokens.Estimate(body); n > maxIssueTokens {
+		body = diff.File{Content: body}.Truncate(len(body) * maxIssueTokens / n).Content
+	}
+	c.Issue = fmt.Sprintf("#%d: %s\n%s", issue.Number, issue.Title, body)
+	return nil
+}
+
+// Context returns the context for sending this change with the repo's
+// remembered exclusions applied.
+// Outside the TUI nobody can confirm sending a likely secret, so it's
+// redacted.
+func (c *Change) Context(cfg *config.Config) Context {
+	sc := Context{
+		Config:    cfg,
+		Files:     c.Files,
+		Patchable: c.Patchable,
+		Symbols:   c.Symbols,
+		Scopes:    c.Scopes,
+		Excluded:  Excluded(c.Files, c.RepoConfig.Exclude),
+		Redact:    cfg.PrivacyReview,
+		Ticket:    c.Ticket,
+		Issue:     c.Issue,
+		Signer:    c.Signer,
+	}
+	sc.Redact = sc.Redact || len(sc.Secrets()) > 0
+	return sc
+}
+
+// Excluded returns the set of files in files listed in exclude.
+func Excluded(files []diff.File, exclude []string) map[string]bool {
+	skip := make(map[string]bool, len(exclude))
+	for _, path := range exclude {
+		skip[path] = true
+	}
+	excluded := make(map[string]bool)
+	for _, f := range files {
+		if skip[f.Path] {
+			excluded[f.Path] = true
+		}
+	}
+	return excluded
+}
+
+// Context is everything that shapes what is sent to the provider for a change.
+type Context struct {
+	Config *config.Config
+	Files  []diff.File
+	// Patchable are the files hunks are taken from; see Change.Patchable.
+	// When nil, Files are used.
+	Patchable []diff.File
+	Symbols   map[string][]symbols.Change
+	// Scopes are added to the hunks of included files; see Change.Scopes.
+	Scopes   map[string][]string
+	Excluded map[string]bool
+	// Redact replaces anything that looks like a secret in the outgoing diff.
+	Redact bool
+	// Ticket is the issue key found in the branch name, if any.
+	Ticket string
+	// Issue is the GitHub issue the change is for, as sent, if one was fetched.
+	Issue string
+	// CoAuthors are credited in addition to the configured ones.
+	CoAuthors []string
+	// Signer is who signs off the commit, or "" for no sign-off.
+	Signer string
+}
+
+// Included returns the files that haven't been excluded or ignored, as
+// they're sent: lock files and binary files are summarized, hunks of noise
+// are collapsed by the configured diff filters, and the declarations
+// enclosing hunks are added. They're not for applying as patches.
+func (c Context) Included() []diff.File {
+	var filters []string
+	if c.Config != nil {
+		filters = c.Config.Filters()
+	}
+	var included []diff.File
+	for _, f := range c.Files {
+		if !c.sent(f.Path) {
+			continue
+		}
+		if summary, ok := f.SummarizeLockfile(); ok {
+			f = summary
+		} else if summary, ok := f.SummarizeBinary(); ok {
+			f = summary
+		} else {
+			f = f.Filter(filters).WithScopes(c.Scopes[f.Path])
+		}
+		included = append(included, f)
+	}
+	return included
+}
+
+// IsIgnored reports whether path matches the configured ignore patterns.
+func (c Context) IsIgnored(path string) bool {
+	return c.Config != nil && glob.MatchAny(c.Config.IgnorePaths, path)
+}
+
+// Ignored returns the files left out because they match the configured
+// ignore patterns, excluding those the user excluded anyway.
+func (c Context) Ignored() []string {
+	var ignored []string
+	for _, f := range c.Files {
+		if !c.Excluded[f.Path] && c.IsIgnored(f.Path) {
+			ignored = append(ignored, f.Path)
+		}
+	}
+	return ignored
+}
+
+// sent reports whether the diff of path is sent.
+func (c Context) sent(path string) bool {
+	return !c.Excluded[path] && !c.IsIgnored(path)
+}
+
+// Diff is the diff that will actually be sent: excluded files are dropped,
+// summaries of changed Go symbols and exported API are prepended along with
+// a risk instruction when sensitive areas are touched, the files are fitted
+// to the budget, and, when redacting, anything that looks like a secret is
+// removed.
+func (c Context) Diff() string {
+	files, _, ok := c.fitted()
+	if !ok {
+		files = c.Included()
+	}
+	return c.finish(c.preamble() + diff.Join(files))
+}
+
+// TooLarge reports whether the included files can't be fitted to the budget
+// and must be summarized in parts first.
+func (c Context) TooLarge() bool {
+	_, _, ok := c.fitted()
+	return !ok
+}
+
+// Trimmed describes what was left out of Diff to fit the budget.
+func (c Context) Trimmed() []string {
+	_, notes, _ := c.fitted()
+	return notes
+}
+
+// Window is the context window of the configured model, in tokens.
+func (c Context) Window() int {
+	if c.Config == nil {
+		return tokens.DefaultWindow
+	}
+	if c.Config.ContextWindow > 0 {
+		return c.Config.ContextWindow
+	}
+	if c.Config.ModelWindow > 0 {
+		return c.Config.ModelWindow
+	}
+	return tokens.Window(c.Config.Model())
+}
+
+func (c Context) reserved() int {
+	return min(ReservedTokens, c.Window()/2)
+}
+
+// Budget is how many tokens of diff are sent in one request.
+func (c Context) Budget() int {
+	return min(MaxDiffTokens, c.Window()-c.reserved())
+}
+
+// HistoryBudget is how many tokens of recent history are sent with each request.
+func (c Context) HistoryBudget() int {
+	return c.reserved() / 3
+}
+
+// FitHistory drops the oldest commits from history, as returned by
+// git.GetRecentHistory, until it fits HistoryBudget.
+func (c Context) FitHistory(history string) string {
+	commits := strings.SplitAfter(history, "\n---")
+	for len(commits) > 0 && tokens.Estimate(strings.Join(commits, "")) > c.HistoryBudget() {
+		commits = commits[:len(commits)-1]
+	}
+	return strings.Join(commits, "")
+}
+
+// fitted returns the included files trimmed to fit Budget along with notes
+// on what was trimmed: the largest files are truncated to a common size.
+// Lock files are already summarized by Included. ok is false when that would leave less
+// than minFileTokens of some file.
+func (c Context) fitted() (files []diff.File, notes []string, ok bool) {
+	files = c.Included()
+	budget := c.Budget() - tokens.Estimate(c.preamble())
+	if tokens.Estimate(diff.Join(files)) <= budget {
+		return files, nil, true
+	}
+
+	files = slices.Clone(files)
+	counts := make([]int, len(files))
+	total := 0
+	for i, f := range files {
+		counts[i] = tokens.Estimate(f.Content)
+		total += counts[i]
+	}
+	if total <= budget {
+		return files, notes, true
+	}
+
+	// Find the largest size every file can be cut to so the total fits.
+	sorted := slices.Sorted(slices.Values(counts))
+	remaining, limit := budget, 0
+	for i, n := range sorted {
+		left := len(sorted) - i
+		if n*left > remaining {
+			limit = remaining / left
+			break
+		}
+		remaining -= n
+	}
+	if limit < minFileTokens {
+		return nil, nil, false
+	}
+	for i, f := range files {
+		if counts[i] > limit {
+			files[i] = f.Truncate(len(f.Content) * limit / counts[i])
+			notes = append(notes, f.Path+" truncated")
+		}
+	}
+	return files, notes, true
+}
+
+// Parts splits the included files into diffs of at most Budget tokens,
+// keeping each file whole unless it alone is larger, in which case it's cut.
+func (c Context) Parts() []string {
+	budget := c.Budget()
+	var parts []string
+	var cur strings.Builder
+	size := 0
+	for _, f := range c.Included() {
+		n := tokens.Estimate(f.Content)
+		if n > budget {
+			f = f.Truncate(len(f.Content) * budget / n)
+			n = budget
+		}
+		if cur.Len() > 0 && size+n > budget {
+			parts = append(parts, c.finish(cur.String()))
+			cur.Reset()
+			size = 0
+		}
+		cur.WriteString(f.Content)
+		size += n
+	}
+	if cur.Len() > 0 {
+		parts = append(parts, c.finish(cur.String()))
+	}
+	return parts
+}
+
+// Summarized is what is sent in place of Diff for a change that is
+// TooLarge: the usual preamble followed by the summary of each part.
+func (c Context) Summarized(summaries []string) string {
+	var b strings.Builder
+	b.WriteString(c.preamble())
+	b.WriteString("The full diff is too large to include. It was split into parts, summarized below.\n")
+	// The preamble lists the files of a change with more than one.
+	if paths := diff.Paths(c.Included()); len(paths) == 1 {
+		b.WriteString("File changed: " + paths[0] + "\n")
+	}
+	for i, s := range summaries {
+		fmt.Fprintf(&b, "\nPart %d of %d:\n%s\n", i+1, len(summaries), strings.TrimSpace(s))
+	}
+	return c.finish(b.String())
+}
+
+// Hunks returns the hunks of every staged file, excluded or not, in order,
+// as they can be applied to the index.
+func (c Context) Hunks() []diff.Hunk {
+	files := c.Patchable
+	if files == nil {
+		files = c.Files
+	}
+	var hunks []diff.Hunk
+	for _, f := range files {
+		hunks = append(hunks, f.Hunks()...)
+	}
+	return hunks
+}
+
+// NumberedHunks is what is sent to the split advisor: every included hunk,
+// labeled with its index in Hunks.
+func (c Context) NumberedHunks() string {
+	var b strings.Builder
+	for i, h := range c.Hunks() {
+		if !c.sent(h.Path) {
+			continue
+		}
+		content := h.Content
+		if content == "" {
+			content = h.Header
+		}
+		fmt.Fprintf(&b, "Hunk %d (%s):\n%s\n", i, h.Path, content)
+	}
+	return c.finish(b.String())
+}
+
+// preamble summarizes changed symbols, exported API, sensitive areas, and
+// the files changed.
+func (c Context) preamble() string {
+	paths := diff.Paths(c.Included())
+	var out string
+	if c.Issue != "" {
+		out += "The change is for this issue. Use it to explain why the change was made, not to restate it:\n" +
+			strings.TrimRight(c.Issue, "\n") + "\n\n"
+	}
+	if c.Ticket != "" {
+		out += "Issue: " + c.Ticket + " (from the branch name)\n"
+		if c.Config != nil && c.Config.Ticket.Footer != "" {
+			out += "Don't add a footer referencing it; one is added automatically.\n\n"
+		} else {
+			out += "Mention it where it helps explain the change.\n\n"
+		}
+	}
+	if risky := c.Sensitive(); len(risky) > 0 {
+		out += "Sensitive areas touched:\n- " + strings.Join(risky, "\n- ") + "\n" +
+			"When asking questions, ask at least one about the risk of this change and how it will be rolled out or rolled back.\n" +
+			"When writing the commit message, end the body with a paragraph starting with 'Risk:' that summarizes the risk and rollout plan.\n\n"
+	}
+	if api := symbols.API(c.Symbols, paths); len(api) > 0 {
+		out += "API changes:\n" + symbols.APISummary(api)
+		if symbols.HasBreaking(api) {
+			out += "These changes break the exported API: mark the commit as breaking with '!' after the type and a 'BREAKING CHANGE:' footer.\n"
+		}
+		out += "\n"
+	}
+	if summary := symbols.Summary(c.Symbols, paths); summary != "" {
+		out += "Changed symbols:\n" + summary + "\n"
+	}
+	if ignored := c.Ignored(); len(ignored) > 0 {
+		out += fmt.Sprintf("%d excluded files (generated, vendored, or lockfiles; their changes are not shown): %s\n\n", len(ignored), strings.Join(ignored, ", "))
+	}
+	// The shape of a multi-file change helps before the detail of the diff.
+	// The stats come from the diffs as staged, without enclosing scopes.
+	var sent []diff.File
+	for _, f := range c.Files {
+		if c.sent(f.Path) {
+			sent = append(sent, f)
+		}
+	}
+	if len(sent) > 1 {
+		out += "Files changed:\n" + diff.StatTable(sent) + "\n"
+	}
+	return out
+}
+
+// finish redacts secrets from outgoing text when asked to.
+func (c Context) finish(out string) string {
+	if c.Redact {
+		return redact.Secrets(out)
+	}
+	return out
+}
+
+// Secret is a likely credential in an included file.
+type Secret struct {
+	Path string
+	redact.Finding
+}
+
+// Secrets returns the likely credentials in the included files. Line
+// numbers count from the start of each file's diff.
+func (c Context) Secrets() []Secret {
+	var secrets []Secret
+	for _, f := range c.Included() {
+		for _, finding := range redact.Scan(f.Content) {
+			secrets = append(secrets, Secret{Path: f.Path, Finding: finding})
+		}
+	}
+	return secrets
+}
+
+// Sensitive returns the included files matching the configured sensitive path patterns.
+func (c Context) Sensitive() []string {
+	if c.Config == nil {
+		return nil
+	}
+	var risky []string
+	for _, f := range c.Included() {
+		if glob.MatchAny(c.Config.Sensitive(), f.Path) {
+			risky = append(risky, f.Path)
+		}
+	}
+	return risky
+}
+
+// WithTrailers returns message with its trailers: the configured ticket
+// footer when a key was found in the branch name, a Co-authored-by for each
+// co-author, and the sign-off last. Ones already present aren't repeated.
+func (c Context) WithTrailers(message string) string {
+	for _, line := range c.trailers() {
+		if line == trailer.SignedOffBy(c.Signer) {
+			// Re-added so it stays last when co-authors are added later.
+			message = trailer.Remove(message, line)
+		}
+		message = trailer.Add(message, line)
+	}
+	return message
+}
+
+// trailers returns the trailer lines WithTrailers adds, in order.
+func (c Context) trailers() []string {
+	if c.Config == nil {
+		return nil
+	}
+	var lines []string
+	if c.Ticket != "" && c.Config.Ticket.Footer != "" {
+		lines = append(lines, ticket.Footer(c.Config.Ticket.Footer, c.Ticket))
+	}
+	for _, a := range slices.Concat(c.Config.CoAuthors, c.CoAuthors) {
+		lines = append(lines, trailer.CoAuthoredBy(a))
+	}
+	if c.Signer != "" {
+		lines = append(lines, trailer.SignedOffBy(c.Signer))
+	}
+	return lines
+}
+
+// APIChanges is the "API changes" block appended to the message body when
+// the config asks for it, or "" when the exported API is unchanged.
+func (c Context) APIChanges() string {
+	api := symbols.API(c.Symbols, diff.Paths(c.Included()))
+	if len(api) == 0 {
+		return ""
+	}
+	return "API changes:\n" + symbols.APISummary(api)
+}
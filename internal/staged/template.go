@@ -0,0 +1,64 @@
+package staged
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/arpxspace/smartcommit/internal/conventional"
+)
+
+// MessageData is what message templates are executed with.
+type MessageData struct {
+	// Header is the whole subject line as generated.
+	Header   string
+	Emoji    string
+	Type     string
+	Scope    string
+	Breaking bool
+	// Subject is the description after the type and scope, or the whole
+	// subject line if it doesn't follow Conventional Commits.
+	Subject string
+	Body    string
+	// Ticket is the issue key found in the branch name, if any.
+	Ticket string
+	// Trailers are the ticket footer, co-authors, and sign-off, one per line.
+	Trailers string
+}
+
+// blankLines matches runs of blank lines left by empty template fields.
+var blankLines = regexp.MustCompile(`\n{3,}`)
+
+// Render lays out a generated message with the configured message template,
+// then adds any trailers it left out. Without a template, the message keeps
+// its "subject, blank line, body" form and only gains its trailers.
+func (c Context) Render(message string) (string, error) {
+	if c.Config == nil || c.Config.MessageTemplate == "" {
+		return c.WithTrailers(message), nil
+	}
+	tmpl, err := template.New("message_template").Option("missingkey=error").Parse(c.Config.MessageTemplate)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse message_template: %w", err)
+	}
+
+	header, body, _ := strings.Cut(strings.TrimSpace(message), "\n")
+	data := MessageData{
+		Header:   header,
+		Subject:  header,
+		Body:     strings.TrimSpace(body),
+		Ticket:   c.Ticket,
+		Trailers: strings.Join(c.trailers(), "\n"),
+	}
+	if h, ok := conventional.Parse(header); ok {
+		data.Emoji, data.Type, data.Scope, data.Breaking, data.Subject = h.Emoji, h.Type, h.Scope, h.Breaking, h.Description
+	}
+
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to render message_template: %w", err)
+	}
+	out := blankLines.ReplaceAllString(strings.TrimSpace(b.String()), "\n\n")
+	return c.WithTrailers(out), nil
+}
@@ -0,0 +1,420 @@
+package store
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/arpxspace/smartcommit/internal/config"
+
+	_ "modernc.org/sqlite"
+)
+
+// dbFile is the name of the session database inside the data dir.
+const dbFile = "sessions.db"
+
+// Outcome is how a session ended.
+type Outcome string
+
+const (
+	OutcomeInProgress Outcome = "in_progress"
+	OutcomeCommitted  Outcome = "committed"
+	OutcomeManual     Outcome = "manual"
+	OutcomeAborted    Outcome = "aborted"
+	OutcomeFailed     Outcome = "failed"
+	// OutcomePrinted is a message handed to another tool to commit.
+	OutcomePrinted Outcome = "printed"
+)
+
+// Session is everything recorded about one run of smartcommit. The diff
+// itself is never stored, only its hash.
+type Session struct {
+	ID               int64
+	StartedAt        time.Time
+	FinishedAt       time.Time
+	RepoRoot         string
+	DiffHash         string
+	Provider         string
+	Model            string
+	Questions        []string
+	Answers          map[string]string
+	Drafts           []string
+	FinalMessage     string
+	Outcome          Outcome
+	PromptTokens     int
+	CompletionTokens int
+	// Timings maps each pipeline stage to how long it took.
+	Timings map[string]time.Duration
+}
+
+// Messages returns the messages recorded for the session: the committed one
+// first, then each draft, newest first, without repeats.
+func (s *Session) Messages() []string {
+	var out []string
+	add := func(msg string) {
+		msg = strings.TrimSpace(msg)
+		if msg != "" && !slices.Contains(out, msg) {
+			out = append(out, msg)
+		}
+	}
+	add(s.FinalMessage)
+	for i := len(s.Drafts) - 1; i >= 0; i-- {
+		add(s.Drafts[i])
+	}
+	return out
+}
+
+// Subject returns the subject line of the session's latest message, or ""
+// if none was generated.
+func (s *Session) Subject() string {
+	messages := s.Messages()
+	if len(messages) == 0 {
+		return ""
+	}
+	subject, _, _ := strings.Cut(messages[0], "\n")
+	return subject
+}
+
+// HashDiff returns the hash sessions use to identify a diff.
+func HashDiff(diff string) string {
+	sum := sha256.Sum256([]byte(diff))
+	return hex.EncodeToString(sum[:])
+}
+
+// Store is the local SQLite session database.
+type Store struct {
+	db *sql.DB
+}
+
+const schema = `
+CREATE TABLE IF NOT EXISTS sessions (
+	id                INTEGER PRIMARY KEY AUTOINCREMENT,
+	started_at        TIMESTAMP NOT NULL,
+	finished_at       TIMESTAMP,
+	repo_root         TEXT NOT NULL,
+	diff_hash         TEXT NOT NULL,
+	provider          TEXT NOT NULL,
+	model             TEXT NOT NULL,
+	questions         TEXT NOT NULL DEFAULT '[]',
+	answers           TEXT NOT NULL DEFAULT '{}',
+	drafts            TEXT NOT NULL DEFAULT '[]',
+	final_message     TEXT NOT NULL DEFAULT '',
+	outcome           TEXT NOT NULL,
+	prompt_tokens     INTEGER NOT NULL DEFAULT 0,
+	completion_tokens INTEGER NOT NULL DEFAULT 0,
+	timings           TEXT NOT NULL DEFAULT '{}'
+);
+CREATE INDEX IF NOT EXISTS sessions_diff_hash ON sessions (diff_hash);
+CREATE INDEX IF NOT EXISTS sessions_started_at ON sessions (started_at);
+CREATE TABLE IF NOT EXISTS usage (
+	id                INTEGER PRIMARY KEY AUTOINCREMENT,
+	recorded_at       TIMESTAMP NOT NULL,
+	command           TEXT NOT NULL,
+	provider          TEXT NOT NULL,
+	model             TEXT NOT NULL,
+	requests          INTEGER NOT NULL,
+	prompt_tokens     INTEGER NOT NULL,
+	completion_tokens INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS usage_recorded_at ON usage (recorded_at);
+CREATE TABLE IF NOT EXISTS embeddings (
+	model       TEXT NOT NULL,
+	commit_hash TEXT NOT NULL,
+	vector      TEXT NOT NULL,
+	PRIMARY KEY (model, commit_hash)
+);
+CREATE TABLE IF NOT EXISTS responses (
+	key        TEXT PRIMARY KEY,
+	value      TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL
+);
+`
+
+// Open opens the session store in the data dir, creating it if needed.
+func Open() (*Store, error) {
+	dir, err := config.DataDir()
+	if err != nil {
+		return nil, err
+	}
+	return OpenPath(filepath.Join(dir, dbFile))
+}
+
+// OpenPath opens the session store at path, creating it if needed.
+func OpenPath(path string) (*Store, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open session store: %w", err)
+	}
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize session store: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+// Close closes the database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Save inserts the session, or updates it if it has already been saved,
+// setting its ID on first insert.
+func (s *Store) Save(sess *Session) error {
+	questions, answers, drafts, timings, err := encode(sess)
+	if err != nil {
+		return err
+	}
+
+	if sess.ID == 0 {
+		res, err := s.db.Exec(`INSERT INTO sessions
+			(started_at, finished_at, repo_root, diff_hash, provider, model, questions, answers, drafts, final_message, outcome, prompt_tokens, completion_tokens, timings)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			sess.StartedAt, nullTime(sess.FinishedAt), sess.RepoRoot, sess.DiffHash, sess.Provider, sess.Model,
+			questions, answers, drafts, sess.FinalMessage, string(sess.Outcome), sess.PromptTokens, sess.CompletionTokens, timings)
+		if err != nil {
+			return fmt.Errorf("failed to save session: %w", err)
+		}
+		sess.ID, err = res.LastInsertId()
+		return err
+	}
+
+	_, err = s.db.Exec(`UPDATE sessions SET
+		finished_at = ?, provider = ?, model = ?, questions = ?, answers = ?, drafts = ?, final_message = ?,
+		outcome = ?, prompt_tokens = ?, completion_tokens = ?, timings = ?
+		WHERE id = ?`,
+		nullTime(sess.FinishedAt), sess.Provider, sess.Model, questions, answers, drafts, sess.FinalMessage,
+		string(sess.Outcome), sess.PromptTokens, sess.CompletionTokens, timings, sess.ID)
+	if err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	return nil
+}
+
+// Get returns the session with the given ID.
+func (s *Store) Get(id int64) (*Session, error) {
+	sessions, err := s.query(`WHERE id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(sessions) == 0 {
+		return nil, fmt.Errorf("session %d not found", id)
+	}
+	return sessions[0], nil
+}
+
+// Recent returns up to limit sessions, newest first.
+func (s *Store) Recent(limit int) ([]*Session, error) {
+	return s.query(`ORDER BY started_at DESC, id DESC LIMIT ?`, limit)
+}
+
+// ByRepo returns up to limit sessions recorded in the repository at root,
+// newest first.
+func (s *Store) ByRepo(root string, limit int) ([]*Session, error) {
+	return s.query(`WHERE repo_root = ? ORDER BY started_at DESC, id DESC LIMIT ?`, root, limit)
+}
+
+// Since returns every session started at or after t, oldest first.
+func (s *Store) Since(t time.Time) ([]*Session, error) {
+	return s.query(`WHERE started_at >= ? ORDER BY started_at, id`, t)
+}
+
+// ByDiffHash returns the sessions recorded for a diff, newest first.
+func (s *Store) ByDiffHash(hash string) ([]*Session, error) {
+	return s.query(`WHERE diff_hash = ? ORDER BY started_at DESC, id DESC`, hash)
+}
+
+func (s *Store) query(clause string, args ...any) ([]*Session, error) {
+	rows, err := s.db.Query(`SELECT
+		id, started_at, finished_at, repo_root, diff_hash, provider, model, questions, answers, drafts,
+		final_message, outcome, prompt_tokens, completion_tokens, timings
+		FROM sessions `+clause, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var out []*Session
+	for rows.Next() {
+		var (
+			sess                                Session
+			finished                            sql.NullTime
+			questions, answers, drafts, timings string
+			outcome                             string
+		)
+		if err := rows.Scan(&sess.ID, &sess.StartedAt, &finished, &sess.RepoRoot, &sess.DiffHash, &sess.Provider, &sess.Model,
+			&questions, &answers, &drafts, &sess.FinalMessage, &outcome, &sess.PromptTokens, &sess.CompletionTokens, &timings); err != nil {
+			return nil, fmt.Errorf("failed to read session: %w", err)
+		}
+		sess.FinishedAt = finished.Time
+		sess.Outcome = Outcome(outcome)
+		if err := decode(&sess, questions, answers, drafts, timings); err != nil {
+			return nil, err
+		}
+		out = append(out, &sess)
+	}
+	return out, rows.Err()
+}
+
+// Usage is the provider usage of one run of a command. Every command that
+// talks to a provider records one, whether or not it records a session.
+type Usage struct {
+	RecordedAt       time.Time
+	Command          string
+	Provider         string
+	Model            string
+	Requests         int
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// AddUsage records provider usage.
+func (s *Store) AddUsage(u Usage) error {
+	_, err := s.db.Exec(`INSERT INTO usage
+		(recorded_at, command, provider, model, requests, prompt_tokens, completion_tokens)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		u.RecordedAt, u.Command, u.Provider, u.Model, u.Requests, u.PromptTokens, u.CompletionTokens)
+	if err != nil {
+		return fmt.Errorf("failed to record usage: %w", err)
+	}
+	return nil
+}
+
+// UsageBetween returns the usage recorded in [from, to), oldest first.
+func (s *Store) UsageBetween(from, to time.Time) ([]Usage, error) {
+	rows, err := s.db.Query(`SELECT recorded_at, command, provider, model, requests, prompt_tokens, completion_tokens
+		FROM usage WHERE recorded_at >= ? AND recorded_at < ? ORDER BY recorded_at, id`, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query usage: %w", err)
+	}
+	defer rows.Close()
+
+	var out []Usage
+	for rows.Next() {
+		var u Usage
+		if err := rows.Scan(&u.RecordedAt, &u.Command, &u.Provider, &u.Model, &u.Requests, &u.PromptTokens, &u.CompletionTokens); err != nil {
+			return nil, fmt.Errorf("failed to read usage: %w", err)
+		}
+		out = append(out, u)
+	}
+	return out, rows.Err()
+}
+
+// Embeddings returns the cached embeddings from model of the messages of the
+// given commits, by commit hash. Commits without one are left out.
+func (s *Store) Embeddings(model string, hashes []string) (map[string][]float64, error) {
+	out := make(map[string][]float64)
+	for _, hash := range hashes {
+		var raw string
+		err := s.db.QueryRow(`SELECT vector FROM embeddings WHERE model = ? AND commit_hash = ?`, model, hash).Scan(&raw)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read embedding: %w", err)
+		}
+		var v []float64
+		if err := json.Unmarshal([]byte(raw), &v); err != nil {
+			return nil, fmt.Errorf("failed to decode embedding of %s: %w", hash, err)
+		}
+		out[hash] = v
+	}
+	return out, nil
+}
+
+// SaveEmbeddings caches embeddings from model of commit messages, by commit
+// hash.
+func (s *Store) SaveEmbeddings(model string, vectors map[string][]float64) error {
+	for hash, v := range vectors {
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		if _, err := s.db.Exec(`INSERT OR REPLACE INTO embeddings (model, commit_hash, vector) VALUES (?, ?, ?)`,
+			model, hash, string(raw)); err != nil {
+			return fmt.Errorf("failed to save embedding: %w", err)
+		}
+	}
+	return nil
+}
+
+// responseTTL is how long a cached provider response is reused.
+const responseTTL = 7 * 24 * time.Hour
+
+// Response returns the provider response cached under key, if there is one
+// recent enough to reuse.
+func (s *Store) Response(key string) (string, bool, error) {
+	var value string
+	err := s.db.QueryRow(`SELECT value FROM responses WHERE key = ? AND created_at > ?`, key, time.Now().Add(-responseTTL)).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read cached response: %w", err)
+	}
+	return value, true, nil
+}
+
+// SaveResponse caches a provider response under key, dropping those too old
+// to be reused.
+func (s *Store) SaveResponse(key, value string) error {
+	now := time.Now()
+	if _, err := s.db.Exec(`DELETE FROM responses WHERE created_at <= ?`, now.Add(-responseTTL)); err != nil {
+		return fmt.Errorf("failed to expire cached responses: %w", err)
+	}
+	if _, err := s.db.Exec(`INSERT OR REPLACE INTO responses (key, value, created_at) VALUES (?, ?, ?)`, key, value, now); err != nil {
+		return fmt.Errorf("failed to cache response: %w", err)
+	}
+	return nil
+}
+
+func encode(sess *Session) (questions, answers, drafts, timings string, err error) {
+	ms := make(map[string]int64, len(sess.Timings))
+	for stage, d := range sess.Timings {
+		ms[stage] = d.Milliseconds()
+	}
+	var b []byte
+	for _, f := range []struct {
+		v   any
+		dst *string
+	}{{orEmpty(sess.Questions), &questions}, {sess.Answers, &answers}, {orEmpty(sess.Drafts), &drafts}, {ms, &timings}} {
+		if b, err = json.Marshal(f.v); err != nil {
+			return
+		}
+		*f.dst = string(b)
+	}
+	return
+}
+
+func decode(sess *Session, questions, answers, drafts, timings string) error {
+	var ms map[string]int64
+	for _, f := range []struct {
+		src string
+		v   any
+	}{{questions, &sess.Questions}, {answers, &sess.Answers}, {drafts, &sess.Drafts}, {timings, &ms}} {
+		if err := json.Unmarshal([]byte(f.src), f.v); err != nil {
+			return fmt.Errorf("failed to decode session %d: %w", sess.ID, err)
+		}
+	}
+	sess.Timings = make(map[string]time.Duration, len(ms))
+	for stage, v := range ms {
+		sess.Timings[stage] = time.Duration(v) * time.Millisecond
+	}
+	return nil
+}
+
+func orEmpty(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
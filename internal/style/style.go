@@ -0,0 +1,188 @@
+package style
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/arpxspace/smartcommit/internal/conventional"
+	"github.com/arpxspace/smartcommit/internal/git"
+)
+
+// Count is how often a value occurs in the analyzed history.
+type Count struct {
+	Value string
+	N     int
+}
+
+// Profile summarizes how a repository's commit messages are written.
+type Profile struct {
+	Commits          int
+	Conventional     int
+	WithBody         int
+	AvgSubjectLength int
+	Types            []Count
+	Scopes           []Count
+	Trailers         []Count
+}
+
+// ConventionalRatio is the fraction of commits following Conventional Commits.
+func (p Profile) ConventionalRatio() float64 {
+	if p.Commits == 0 {
+		return 0
+	}
+	return float64(p.Conventional) / float64(p.Commits)
+}
+
+// BodyRatio is the fraction of commits with a body.
+func (p Profile) BodyRatio() float64 {
+	if p.Commits == 0 {
+		return 0
+	}
+	return float64(p.WithBody) / float64(p.Commits)
+}
+
+var trailerPattern = regexp.MustCompile(`^([A-Za-z][A-Za-z-]*): \S`)
+
+// Analyze builds a profile from commits. Merge commits are skipped since
+// their subjects are generated by git.
+func Analyze(commits []git.Commit) Profile {
+	var p Profile
+	types := map[string]int{}
+	scopes := map[string]int{}
+	trailers := map[string]int{}
+	subjectTotal := 0
+
+	for _, c := range commits {
+		if strings.HasPrefix(c.Subject, "Merge ") {
+			continue
+		}
+		p.Commits++
+		subjectTotal += len(c.Subject)
+		if h, ok := conventional.Parse(c.Subject); ok {
+			p.Conventional++
+			types[h.Type]++
+			if h.Scope != "" {
+				scopes[h.Scope]++
+			}
+		}
+
+		body := c.Body
+		paragraphs := strings.Split(body, "\n\n")
+		last := paragraphs[len(paragraphs)-1]
+		isTrailerBlock := last != ""
+		for _, line := range strings.Split(last, "\n") {
+			if !trailerPattern.MatchString(line) {
+				isTrailerBlock = false
+				break
+			}
+		}
+		if isTrailerBlock {
+			for _, line := range strings.Split(last, "\n") {
+				trailers[trailerPattern.FindStringSubmatch(line)[1]]++
+			}
+			body = strings.TrimSpace(strings.Join(paragraphs[:len(paragraphs)-1], "\n\n"))
+		}
+		if body != "" {
+			p.WithBody++
+		}
+	}
+
+	if p.Commits > 0 {
+		p.AvgSubjectLength = subjectTotal / p.Commits
+	}
+	p.Types = sorted(types)
+	p.Scopes = sorted(scopes)
+	p.Trailers = sorted(trailers)
+	return p
+}
+
+// Template renders a commit.template file guiding contributors toward the
+// house style. Every line is a comment, so git strips it from the message.
+func (p Profile) Template() string {
+	var b strings.Builder
+	line := func(format string, args ...any) {
+		text := fmt.Sprintf(format, args...)
+		if text == "" {
+			b.WriteString("#\n")
+			return
+		}
+		b.WriteString("# " + text + "\n")
+	}
+
+	subjectLimit := 72
+	if p.AvgSubjectLength > 0 && p.AvgSubjectLength <= 50 {
+		subjectLimit = 50
+	}
+
+	b.WriteString("\n")
+	if p.ConventionalRatio() >= 0.5 {
+		line("<type>(<scope>): <subject>")
+		line("")
+		line("Subject: imperative mood, no trailing period, at most %d characters.", subjectLimit)
+		if len(p.Types) > 0 {
+			line("Types used here: %s", join(p.Types, 8))
+		}
+		if len(p.Scopes) > 0 {
+			line("Scopes used here: %s", join(p.Scopes, 10))
+		}
+		line("Add '!' after the type/scope for breaking changes.")
+	} else {
+		line("<subject>")
+		line("")
+		line("Subject: imperative mood, no trailing period, at most %d characters.", subjectLimit)
+	}
+
+	line("")
+	b.WriteString("\n")
+	line("<body>")
+	line("")
+	if p.BodyRatio() >= 0.5 {
+		line("Most commits here have a body (%d%%).", int(p.BodyRatio()*100))
+	}
+	line("Explain WHY this change was made, not what the diff already shows.")
+	line("What problem does it solve? What alternatives were considered?")
+	line("Wrap lines at 72 characters.")
+
+	if len(p.Trailers) > 0 {
+		line("")
+		b.WriteString("\n")
+		line("<trailers>")
+		line("")
+		for _, t := range p.Trailers {
+			if t.N*5 >= p.Commits || len(p.Trailers) <= 3 {
+				line("%s: ...", t.Value)
+			}
+		}
+	}
+
+	line("")
+	line("Generated by `smartcommit template` from the last %d commits.", p.Commits)
+	return b.String()
+}
+
+func sorted(counts map[string]int) []Count {
+	out := make([]Count, 0, len(counts))
+	for v, n := range counts {
+		out = append(out, Count{Value: v, N: n})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].N != out[j].N {
+			return out[i].N > out[j].N
+		}
+		return out[i].Value < out[j].Value
+	})
+	return out
+}
+
+func join(counts []Count, limit int) string {
+	var vals []string
+	for i, c := range counts {
+		if i == limit {
+			break
+		}
+		vals = append(vals, c.Value)
+	}
+	return strings.Join(vals, ", ")
+}
@@ -0,0 +1,123 @@
+package symbols
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// APIChange is a change to a package's exported surface.
+type APIChange struct {
+	// Package is the directory of the package, relative to the repo root.
+	Package string
+	Action  Action
+	Before  *Symbol
+	After   *Symbol
+	// Breaking reports whether existing callers may stop compiling.
+	Breaking bool
+}
+
+// API reduces per-file symbol changes to changes in each package's exported
+// API. Changes are grouped per directory so that a symbol moved between two
+// files of the same package isn't reported as removed and re-added.
+func API(changes map[string][]Change, paths []string) []APIChange {
+	type side struct{ before, after map[string]Symbol }
+	pkgs := make(map[string]*side)
+
+	for _, path := range paths {
+		dir := filepath.ToSlash(filepath.Dir(path))
+		pkg, ok := pkgs[dir]
+		if !ok {
+			pkg = &side{before: map[string]Symbol{}, after: map[string]Symbol{}}
+			pkgs[dir] = pkg
+		}
+		for _, c := range changes[path] {
+			if c.Before != nil && c.Before.Exported {
+				pkg.before[key(*c.Before)] = *c.Before
+			}
+			if c.After != nil && c.After.Exported {
+				pkg.after[key(*c.After)] = *c.After
+			}
+		}
+	}
+
+	var out []APIChange
+	for dir, pkg := range pkgs {
+		for k, b := range pkg.before {
+			a, ok := pkg.after[k]
+			switch {
+			case !ok:
+				out = append(out, APIChange{Package: dir, Action: Removed, Before: &b, Breaking: true})
+			case a.Signature != b.Signature || (b.Kind == KindType && a.Source != b.Source):
+				// Function signature changes break callers; type and value
+				// changes often don't (e.g. a new struct field), so they're
+				// reported without being flagged.
+				breaking := b.Kind == KindFunc || b.Kind == KindMethod
+				out = append(out, APIChange{Package: dir, Action: Modified, Before: &b, After: &a, Breaking: breaking})
+			}
+		}
+		for k, a := range pkg.after {
+			if _, ok := pkg.before[k]; !ok {
+				out = append(out, APIChange{Package: dir, Action: Added, After: &a})
+			}
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Package != out[j].Package {
+			return out[i].Package < out[j].Package
+		}
+		return out[i].name() < out[j].name()
+	})
+	return out
+}
+
+// HasBreaking reports whether any of the changes is breaking.
+func HasBreaking(changes []APIChange) bool {
+	for _, c := range changes {
+		if c.Breaking {
+			return true
+		}
+	}
+	return false
+}
+
+// APISummary renders API changes grouped by package.
+func APISummary(changes []APIChange) string {
+	var b strings.Builder
+	pkg := ""
+	for _, c := range changes {
+		if c.Package != pkg {
+			pkg = c.Package
+			fmt.Fprintf(&b, "%s:\n", pkg)
+		}
+		switch c.Action {
+		case Added:
+			fmt.Fprintf(&b, "  added %s\n", c.After.Signature)
+		case Removed:
+			fmt.Fprintf(&b, "  removed %s\n", c.Before.Signature)
+		case Modified:
+			if c.Before.Signature == c.After.Signature {
+				fmt.Fprintf(&b, "  changed %s\n", c.After.Signature)
+			} else {
+				fmt.Fprintf(&b, "  changed %s -> %s\n", c.Before.Signature, c.After.Signature)
+			}
+		}
+		if c.Breaking {
+			b.WriteString("    (breaking)\n")
+		}
+	}
+	return b.String()
+}
+
+func (c APIChange) name() string {
+	if c.After != nil {
+		return c.After.Name
+	}
+	return c.Before.Name
+}
+
+func key(s Symbol) string {
+	return string(s.Kind) + " " + s.Name
+}
@@ -0,0 +1,249 @@
+package symbols
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/arpxspace/smartcommit/internal/diff"
+	"github.com/arpxspace/smartcommit/internal/git"
+)
+
+// Kind is the kind of declaration a symbol comes from.
+type Kind string
+
+const (
+	KindFunc   Kind = "func"
+	KindMethod Kind = "method"
+	KindType   Kind = "type"
+	KindConst  Kind = "const"
+	KindVar    Kind = "var"
+)
+
+// Action describes what happened to a symbol in the change.
+type Action string
+
+const (
+	Added    Action = "added"
+	Removed  Action = "removed"
+	Modified Action = "modified"
+)
+
+// Symbol is a top-level declaration in a Go file.
+type Symbol struct {
+	Kind Kind
+	// Name is the identifier; methods are qualified as "Recv.Name".
+	Name string
+	// Exported reports whether the symbol is part of the package's public API.
+	Exported bool
+	// Signature is the declaration without its body (the func type or type spec).
+	Signature string
+	// Source is the full declaration, used to detect modifications.
+	Source string
+}
+
+// Change is a symbol that was added, removed, or modified.
+type Change struct {
+	Action Action
+	// Before is the symbol prior to the change; nil when added.
+	Before *Symbol
+	// After is the symbol after the change; nil when removed.
+	After *Symbol
+}
+
+// Symbol returns whichever side of the change exists, preferring the new one.
+func (c Change) Symbol() Symbol {
+	if c.After != nil {
+		return *c.After
+	}
+	return *c.Before
+}
+
+// Parse extracts the top-level functions, methods, types, constants, and
+// variables declared in src, keyed by kind and name.
+func Parse(src string) (map[string]Symbol, error) {
+	if src == "" {
+		return map[string]Symbol{}, nil
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+
+	syms := make(map[string]Symbol)
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			sym := Symbol{
+				Kind:      KindFunc,
+				Name:      d.Name.Name,
+				Exported:  d.Name.IsExported(),
+				Signature: "func " + d.Name.Name + strings.TrimPrefix(render(fset, d.Type), "func"),
+				Source:    render(fset, d),
+			}
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				recv := receiverName(d.Recv.List[0].Type)
+				sym.Kind = KindMethod
+				sym.Name = recv + "." + d.Name.Name
+				sym.Exported = sym.Exported && ast.IsExported(recv)
+				sym.Signature = fmt.Sprintf("func (%s) %s", render(fset, d.Recv.List[0].Type), strings.TrimPrefix(sym.Signature, "func "))
+			}
+			syms[key(sym)] = sym
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch sp := spec.(type) {
+				case *ast.TypeSpec:
+					src := render(fset, sp)
+					syms["type "+sp.Name.Name] = Symbol{
+						Kind:      KindType,
+						Name:      sp.Name.Name,
+						Exported:  sp.Name.IsExported(),
+						Signature: typeSignature(fset, sp),
+						Source:    src,
+					}
+				case *ast.ValueSpec:
+					kind := KindVar
+					if d.Tok == token.CONST {
+						kind = KindConst
+					}
+					src := render(fset, sp)
+					for _, name := range sp.Names {
+						if name.Name == "_" {
+							continue
+						}
+						sig := string(kind) + " " + name.Name
+						if sp.Type != nil {
+							sig += " " + render(fset, sp.Type)
+						}
+						syms[string(kind)+" "+name.Name] = Symbol{
+							Kind:      kind,
+							Name:      name.Name,
+							Exported:  name.IsExported(),
+							Signature: sig,
+							Source:    src,
+						}
+					}
+				}
+			}
+		}
+	}
+	return syms, nil
+}
+
+// Compare returns the symbols that differ between two versions of a Go file,
+// sorted by name.
+func Compare(before, after string) ([]Change, error) {
+	old, err := Parse(before)
+	if err != nil {
+		return nil, err
+	}
+	cur, err := Parse(after)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []Change
+	for key, o := range old {
+		if n, ok := cur[key]; !ok {
+			changes = append(changes, Change{Action: Removed, Before: &o})
+		} else if n.Source != o.Source {
+			changes = append(changes, Change{Action: Modified, Before: &o, After: &n})
+		}
+	}
+	for key, n := range cur {
+		if _, ok := old[key]; !ok {
+			changes = append(changes, Change{Action: Added, After: &n})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Symbol().Name < changes[j].Symbol().Name
+	})
+	return changes, nil
+}
+
+// ForDiff compares the HEAD and staged versions of every Go file in files.
+// Files that fail to parse (e.g. mid-refactor syntax errors) are skipped.
+func ForDiff(files []diff.File) map[string][]Change {
+	out := make(map[string][]Change)
+	for _, f := range files {
+		if filepath.Ext(f.Path) != ".go" {
+			continue
+		}
+		before, err := git.GetHeadFile(f.OldPath)
+		if err != nil {
+			continue
+		}
+		after, err := git.GetStagedFile(f.Path)
+		if err != nil {
+			continue
+		}
+		changes, err := Compare(before, after)
+		if err != nil || len(changes) == 0 {
+			continue
+		}
+		out[f.Path] = changes
+	}
+	return out
+}
+
+// Summary renders the changes for the given paths as a compact list the model
+// can read before the raw diff. Paths without changes are omitted.
+func Summary(changes map[string][]Change, paths []string) string {
+	var b strings.Builder
+	for _, path := range paths {
+		cs := changes[path]
+		if len(cs) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "%s:\n", path)
+		for _, c := range cs {
+			sym := c.Symbol()
+			fmt.Fprintf(&b, "  %s %s %s\n", c.Action, sym.Kind, sym.Name)
+		}
+	}
+	return b.String()
+}
+
+// typeSignature is a one-line description of a type: its kind for structs
+// and interfaces, or the full definition for anything shorter.
+func typeSignature(fset *token.FileSet, ts *ast.TypeSpec) string {
+	switch ts.Type.(type) {
+	case *ast.StructType:
+		return "type " + ts.Name.Name + " struct"
+	case *ast.InterfaceType:
+		return "type " + ts.Name.Name + " interface"
+	}
+	return "type " + render(fset, ts)
+}
+
+func render(fset *token.FileSet, node any) string {
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, fset, node); err != nil {
+		return ""
+	}
+	return buf.String()
+}
+
+// receiverName returns the base type name of a method receiver, without
+// pointers or type parameters.
+func receiverName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return receiverName(t.X)
+	case *ast.IndexExpr:
+		return receiverName(t.X)
+	case *ast.IndexListExpr:
+		return receiverName(t.X)
+	case *ast.Ident:
+		return t.Name
+	}
+	return ""
+}
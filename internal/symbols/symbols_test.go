@@ -0,0 +1,124 @@
+package symbols
+
+import (
+	"slices"
+	"testing"
+)
+
+// describe renders changes as "action kind name" for comparison.
+func describe(changes []Change) []string {
+	var out []string
+	for _, c := range changes {
+		s := c.Symbol()
+		out = append(out, string(c.Action)+" "+string(s.Kind)+" "+s.Name)
+	}
+	return out
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		name          string
+		before, after string
+		want          []string
+	}{
+		{"new file", "", "package p\n\nfunc F() {}\n", []string{"added func F"}},
+		{"deleted file", "package p\n\nfunc F() {}\n", "", []string{"removed func F"}},
+		{"unchanged", "package p\n\nfunc F() {}\n", "package p\n\nfunc F() {}\n", nil},
+		{"formatting only", "package p\n\nfunc F() {  }\n", "package p\n\nfunc F() {}\n", nil},
+		{"body change", "package p\n\nfunc F() int { return 1 }\n", "package p\n\nfunc F() int { return 2 }\n",
+			[]string{"modified func F"}},
+		{"method", "package p\n\ntype T struct{}\n", "package p\n\ntype T struct{}\n\nfunc (t *T) M() {}\n",
+			[]string{"added method T.M"}},
+		{"consts and vars", "package p\n\nconst A = 1\n\nvar _ = 0\n", "package p\n\nconst A = 2\n\nvar B, c int\n",
+			[]string{"modified const A", "added var B", "added var c"}},
+		{"type change", "package p\n\ntype S struct{ A int }\n", "package p\n\ntype S struct{ A, B int }\n",
+			[]string{"modified type S"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes, err := Compare(tt.before, tt.after)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := describe(changes); !slices.Equal(got, tt.want) {
+				t.Errorf("Compare = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareSyntaxError(t *testing.T) {
+	if _, err := Compare("package p\n", "package p\n\nfunc {"); err == nil {
+		t.Error("Compare with invalid source: want error")
+	}
+}
+
+func TestAPI(t *testing.T) {
+	type file struct{ path, before, after string }
+	tests := []struct {
+		name     string
+		files    []file
+		want     []string
+		breaking bool
+	}{
+		{"unexported changes are ignored",
+			[]file{{"p/a.go", "package p\n\nfunc f() {}\n", "package p\n\nfunc f(x int) {}\n"}},
+			nil, false},
+		{"added function",
+			[]file{{"p/a.go", "package p\n", "package p\n\nfunc F() {}\n"}},
+			[]string{"p added func F()"}, false},
+		{"removed function breaks",
+			[]file{{"p/a.go", "package p\n\nfunc F() {}\n", "package p\n"}},
+			[]string{"p removed func F()"}, true},
+		{"signature change breaks",
+			[]file{{"p/a.go", "package p\n\nfunc F() {}\n", "package p\n\nfunc F(x int) {}\n"}},
+			[]string{"p modified func F(x int)"}, true},
+		{"body change is not an API change",
+			[]file{{"p/a.go", "package p\n\nfunc F() int { return 1 }\n", "package p\n\nfunc F() int { return 2 }\n"}},
+			nil, false},
+		{"new struct field is reported but not breaking",
+			[]file{{"p/a.go", "package p\n\ntype S struct{ A int }\n", "package p\n\ntype S struct{ A, B int }\n"}},
+			[]string{"p modified type S struct"}, false},
+		{"move between files of a package",
+			[]file{
+				{"p/a.go", "package p\n\nfunc F() {}\n", "package p\n"},
+				{"p/b.go", "package p\n", "package p\n\nfunc F() {}\n"},
+			},
+			nil, false},
+		{"move between packages",
+			[]file{
+				{"p/a.go", "package p\n\nfunc F() {}\n", "package p\n"},
+				{"q/a.go", "package q\n", "package q\n\nfunc F() {}\n"},
+			},
+			[]string{"p removed func F()", "q added func F()"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes := make(map[string][]Change)
+			var paths []string
+			for _, f := range tt.files {
+				c, err := Compare(f.before, f.after)
+				if err != nil {
+					t.Fatal(err)
+				}
+				changes[f.path] = c
+				paths = append(paths, f.path)
+			}
+			api := API(changes, paths)
+			var got []string
+			for _, c := range api {
+				sym := c.Before
+				if c.After != nil {
+					sym = c.After
+				}
+				got = append(got, c.Package+" "+string(c.Action)+" "+sym.Signature)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("API = %q, want %q", got, tt.want)
+			}
+			if b := HasBreaking(api); b != tt.breaking {
+				t.Errorf("HasBreaking = %v, want %v", b, tt.breaking)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+// Package ticket finds issue keys like JIRA-1234 in branch names and adds
+// the footer that references them to commit messages.
+package ticket
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/arpxspace/smartcommit/internal/trailer"
+)
+
+// DefaultPattern matches Jira-style issue keys: a project key of capitals
+// and digits, a hyphen, and a number.
+const DefaultPattern = `[A-Z][A-Z0-9]+-[0-9]+`
+
+// Placeholder is replaced by the issue key in a footer.
+const Placeholder = "{ticket}"
+
+// Find returns the first issue key in branch matched by pattern, or "" if
+// there's none. When pattern has a capture group, the first group is the
+// key. An empty pattern uses DefaultPattern.
+func Find(branch, pattern string) (string, error) {
+	if pattern == "" {
+		pattern = DefaultPattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", err
+	}
+	m := re.FindStringSubmatch(branch)
+	switch {
+	case m == nil:
+		return "", nil
+	case len(m) > 1:
+		return m[1], nil
+	default:
+		return m[0], nil
+	}
+}
+
+// Footer returns footer with its Placeholder replaced by key.
+func Footer(footer, key string) string {
+	return strings.ReplaceAll(footer, Placeholder, key)
+}
+
+// AddFooter returns message with footer, its Placeholder replaced by key,
+// as a trailer, unless a line of the message already says the same.
+func AddFooter(message, footer, key string) string {
+	return trailer.Add(message, Footer(footer, key))
+}
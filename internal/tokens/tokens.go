@@ -0,0 +1,120 @@
+// Package tokens estimates how many tokens text is and knows how many fit
+// in a model's context window, so requests can be trimmed to fit before
+// they're sent.
+package tokens
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// piece splits text the way OpenAI's cl100k and o200k tokenizers do before
+// applying byte-pair merges: contractions, words with their leading space
+// or punctuation, numbers of up to three digits, punctuation runs, and
+// whitespace.
+var piece = regexp.MustCompile(`'(?i:[sdmt]|ll|ve|re)|[^\r\n\pL\pN]?\pL+|\pN{1,3}| ?[^\s\pL\pN]+[\r\n]*|\s*[\r\n]+|\s+`)
+
+// Estimate approximates how many tokens s is; it isn't an exact count for
+// any tokenizer. It pre-tokenizes like tiktoken, then estimates the tokens
+// in each piece without the merge tables: common words are one token, long
+// identifiers one per few characters, punctuation one per two characters,
+// and non-Latin text one per character. It tends to overcount slightly,
+// which is the safe side for a budget.
+func Estimate(s string) int {
+	n := 0
+	for _, p := range piece.FindAllString(s, -1) {
+		n += pieceTokens(p)
+	}
+	return n
+}
+
+func pieceTokens(p string) int {
+	if strings.TrimSpace(p) == "" {
+		return 1 + len(p)/16
+	}
+	if runes := utf8.RuneCountInString(p); runes != len(p) {
+		return runes
+	}
+	r, _ := utf8.DecodeRuneInString(strings.TrimLeft(p, " "))
+	switch {
+	case isLetter(r) || isLetter(rune(p[len(p)-1])):
+		if len(p) <= 8 {
+			return 1
+		}
+		return 1 + (len(p)-8+4)/5
+	case r >= '0' && r <= '9':
+		return 1
+	default:
+		return (len(strings.TrimSpace(p)) + 1) / 2
+	}
+}
+
+func isLetter(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+}
+
+// DefaultWindow is the context window assumed for models that aren't known.
+const DefaultWindow = 8192
+
+// windows are context windows in tokens by model name prefix. Longer
+// prefixes are listed before the shorter ones they start with.
+var windows = []struct {
+	prefix string
+	tokens int
+}{
+	{"gpt-5", 400000},
+	{"gpt-4.1", 1047576},
+	{"gpt-4o", 128000},
+	{"gpt-4-turbo", 128000},
+	{"gpt-4-32k", 32768},
+	{"gpt-4", 8192},
+	{"gpt-3.5-turbo", 16385},
+	{"o1-mini", 128000},
+	{"o1", 200000},
+	{"o3", 200000},
+	{"o4-mini", 200000},
+	{"claude", 200000},
+	{"gemini-1.5", 1048576},
+	{"gemini-2", 1048576},
+	{"llama-3.1", 131072},
+	{"llama-3.2", 131072},
+	{"llama-3.3", 131072},
+	{"llama3.1", 131072},
+	{"llama3.2", 131072},
+	{"llama3.3", 131072},
+	{"llama3", 8192},
+	{"llama2", 4096},
+	{"codellama", 16384},
+	{"mistral-nemo", 131072},
+	{"mistral", 32768},
+	{"mixtral", 32768},
+	{"qwen2.5-coder", 32768},
+	{"qwen2.5", 32768},
+	{"qwen3", 40960},
+	{"gemma3", 131072},
+	{"gemma2", 8192},
+	{"gemma", 8192},
+	{"phi4", 16384},
+	{"phi3", 4096},
+	{"deepseek-r1", 131072},
+	{"deepseek-chat", 65536},
+	{"deepseek-coder-v2", 163840},
+	{"deepseek-coder", 16384},
+	{"granite3", 131072},
+}
+
+// Window returns the context window of model in tokens from a table of
+// known models, or DefaultWindow when it isn't known. Ollama tags (":8b")
+// and namespaces ("library/") are ignored.
+func Window(model string) int {
+	name := strings.ToLower(model)
+	name = name[strings.LastIndex(name, "/")+1:]
+	name, _, _ = strings.Cut(name, ":")
+	for _, w := range windows {
+		if strings.HasPrefix(name, w.prefix) {
+			return w.tokens
+		}
+	}
+	return DefaultWindow
+}
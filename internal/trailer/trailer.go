@@ -0,0 +1,78 @@
+// Package trailer adds and removes git trailers such as "Co-authored-by:"
+// at the end of commit messages.
+package trailer
+
+import (
+	"regexp"
+	"strings"
+)
+
+// line matches a git trailer such as "Refs: X" or "BREAKING CHANGE: X".
+var line = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9-]*( [A-Z]+)?: `)
+
+// ident matches a git identity, "Name <email>".
+var ident = regexp.MustCompile(`^[^<>]*[^<>\s] <[^<>\s]+@[^<>\s]+>$`)
+
+// ValidIdent reports whether s is a git identity like "Jane Doe <jane@example.com>".
+func ValidIdent(s string) bool {
+	return ident.MatchString(s)
+}
+
+// CoAuthoredBy returns the trailer crediting a co-author.
+func CoAuthoredBy(ident string) string {
+	return "Co-authored-by: " + ident
+}
+
+// SignedOffBy returns the Developer Certificate of Origin sign-off trailer.
+func SignedOffBy(ident string) string {
+	return "Signed-off-by: " + ident
+}
+
+// Add returns message with each of lines as a trailer, skipping any a line
+// of the message already says. Trailers join an existing trailer block
+// rather than starting a new paragraph.
+func Add(message string, lines ...string) string {
+	message = strings.TrimRight(message, "\n")
+	for _, l := range lines {
+		if has(message, l) {
+			continue
+		}
+		paragraphs := strings.Split(message, "\n\n")
+		if len(paragraphs) > 1 && isTrailers(paragraphs[len(paragraphs)-1]) {
+			message += "\n" + l
+		} else {
+			message += "\n\n" + l
+		}
+	}
+	return message
+}
+
+// Remove returns message without the lines that say l, dropping the
+// trailer block if that leaves it empty.
+func Remove(message, l string) string {
+	var kept []string
+	for _, m := range strings.Split(strings.TrimRight(message, "\n"), "\n") {
+		if strings.TrimSpace(m) != l {
+			kept = append(kept, m)
+		}
+	}
+	return strings.TrimRight(strings.Join(kept, "\n"), "\n")
+}
+
+func has(message, l string) bool {
+	for _, m := range strings.Split(message, "\n") {
+		if strings.TrimSpace(m) == l {
+			return true
+		}
+	}
+	return false
+}
+
+func isTrailers(paragraph string) bool {
+	for _, l := range strings.Split(paragraph, "\n") {
+		if !line.MatchString(l) {
+			return false
+		}
+	}
+	return true
+}
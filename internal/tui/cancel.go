@@ -0,0 +1,58 @@
+package tui
+
+import (
+	"context"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// waiting reports whether the screen is only waiting on the provider, so
+// esc or ctrl+x cancels the request.
+func (m Model) waiting() bool {
+	switch m.State {
+	case StateSummarizing, StateHistoryAnalysis, StateGenerating, StateSplitAnalyzing:
+		return true
+	case StateCritique:
+		return m.CritiqueRunning
+	case StateReview:
+		return m.Rewriting
+	}
+	return false
+}
+
+// cancelRequests abandons every request in flight and goes back to where
+// the user was before it was sent. Results of the cancelled requests are
+// dropped when they arrive.
+func (m Model) cancelRequests() (tea.Model, tea.Cmd) {
+	m.CancelRequests()
+	m.Requests, m.CancelRequests = context.WithCancel(context.Background())
+	m.StreamText = ""
+	m.Shortening = false
+
+	switch m.State {
+	case StateCritique:
+		m.CritiqueRunning = false
+		return m, nil
+	case StateReview:
+		m.Rewriting = false
+		return m, nil
+	case StateGenerating:
+		if m.CommitMsg != "" {
+			// Regenerating: keep the message being replaced.
+			return m.enterReview()
+		}
+		if len(m.Questions) > 0 {
+			m.CurrentQIdx = len(m.Questions) - 1
+			m.TextArea.SetValue(m.Answers[m.Questions[m.CurrentQIdx]])
+			m.TextArea.Focus()
+			m.State = StateQuestioning
+			return m, nil
+		}
+	}
+	// Summaries and related history are only kept once complete, but the
+	// diff may already have been replaced by summaries.
+	m.Diff = m.outgoingDiff()
+	m.Questions = nil
+	m.State = StateWelcome
+	return m, nil
+}
@@ -0,0 +1,114 @@
+package tui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/arpxspace/smartcommit/internal/git"
+	"github.com/arpxspace/smartcommit/internal/trailer"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// authorHistory is how many commits back the picker looks for committers.
+const authorHistory = 500
+
+// coAuthorPicker chooses co-authors from the repository's recent committers
+// on the review screen.
+type coAuthorPicker struct {
+	Authors  []string
+	Selected map[string]bool
+	Cursor   int
+	Err      error
+}
+
+// openCoAuthorPicker lists the recent committers, other than the user and
+// the co-authors the config always credits, with the current ones selected.
+func (m Model) openCoAuthorPicker() (tea.Model, tea.Cmd) {
+	p := &coAuthorPicker{Selected: make(map[string]bool)}
+	recent, err := git.RecentAuthors(authorHistory)
+	if err != nil {
+		p.Err = err
+	}
+	self, _ := git.UserIdent()
+	for _, a := range slices.Concat(m.CoAuthors, recent) {
+		if a == self || slices.Contains(m.Config.CoAuthors, a) || slices.Contains(p.Authors, a) {
+			continue
+		}
+		p.Authors = append(p.Authors, a)
+	}
+	for _, a := range m.CoAuthors {
+		p.Selected[a] = true
+	}
+	m.CoAuthorPicker = p
+	return m, nil
+}
+
+func (m Model) updateCoAuthorPicker(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	p := m.CoAuthorPicker
+	switch {
+	case key.Matches(msg, keys.Up):
+		if p.Cursor > 0 {
+			p.Cursor--
+		}
+	case key.Matches(msg, keys.Down):
+		if p.Cursor < len(p.Authors)-1 {
+			p.Cursor++
+		}
+	case key.Matches(msg, keys.Toggle):
+		if len(p.Authors) > 0 {
+			a := p.Authors[p.Cursor]
+			p.Selected[a] = !p.Selected[a]
+		}
+	case key.Matches(msg, keys.Select):
+		var chosen []string
+		for _, a := range p.Authors {
+			if p.Selected[a] {
+				chosen = append(chosen, a)
+			}
+		}
+		for _, a := range m.CoAuthors {
+			if !p.Selected[a] {
+				m.CommitMsg = trailer.Remove(m.CommitMsg, trailer.CoAuthoredBy(a))
+			}
+		}
+		m.CoAuthors = chosen
+		m.CommitMsg = m.context().WithTrailers(m.CommitMsg)
+		m.CoAuthorPicker = nil
+		return m.enterReview()
+	case key.Matches(msg, keys.Back):
+		m.CoAuthorPicker = nil
+	}
+	return m, nil
+}
+
+func (m Model) viewCoAuthorPicker() string {
+	titleStyle := lipgloss.NewStyle().Bold(true)
+	infoStyle := lipgloss.NewStyle().Foreground(theme.Muted)
+	p := m.CoAuthorPicker
+
+	var b strings.Builder
+	b.WriteString("\n " + titleStyle.Render("Co-authors") + "\n\n")
+	if p.Err != nil {
+		b.WriteString(" " + infoStyle.Render(fmt.Sprintf("Couldn't list recent committers: %v", p.Err)) + "\n")
+	}
+	if len(p.Authors) == 0 {
+		b.WriteString(" No other committers found. Pass --co-author \"Name <email>\" instead.\n")
+	}
+	for i, a := range p.Authors {
+		cursor := "  "
+		if i == p.Cursor {
+			cursor = "> "
+		}
+		check := "[ ]"
+		if p.Selected[a] {
+			check = "[x]"
+		}
+		fmt.Fprintf(&b, " %s%s %s\n", cursor, check, a)
+	}
+	b.WriteString("\n " + infoStyle.Render("(↑/↓ to move, space to toggle, enter to apply, esc to cancel)") + "\n")
+	return b.String()
+}
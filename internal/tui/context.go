@@ -0,0 +1,45 @@
+package tui
+
+import (
+	"github.com/arpxspace/smartcommit/internal/diff"
+	"github.com/arpxspace/smartcommit/internal/staged"
+)
+
+// context describes what will be sent for the current change, honoring the
+// user's exclusions, privacy mode, and choice about secrets.
+func (m Model) context() staged.Context {
+	return staged.Context{
+		Config:    m.Config,
+		Files:     m.Files,
+		Patchable: m.Patchable,
+		Symbols:   m.Symbols,
+		Scopes:    m.Scopes,
+		Excluded:  m.Excluded,
+		Redact:    m.privacyReview() || m.RedactSecrets,
+		Ticket:    m.Ticket,
+		Issue:     m.Issue,
+		CoAuthors: m.CoAuthors,
+		Signer:    m.Signer,
+	}
+}
+
+// includedFiles returns the staged files the user hasn't excluded.
+func (m Model) includedFiles() []diff.File {
+	return m.context().Included()
+}
+
+// outgoingDiff is the diff that will actually be sent.
+func (m Model) outgoingDiff() string {
+	return m.context().Diff()
+}
+
+// sensitiveFiles returns the included files matching the configured sensitive path patterns.
+func (m Model) sensitiveFiles() []string {
+	return m.context().Sensitive()
+}
+
+// apiChangesSection is the "API changes" block appended to the message body
+// when the config asks for it, or "" when the exported API is unchanged.
+func (m Model) apiChangesSection() string {
+	return m.context().APIChanges()
+}
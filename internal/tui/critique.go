@@ -0,0 +1,160 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/arpxspace/smartcommit/internal/ai"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+type critiqueResultMsg struct {
+	Message  string
+	Critique *ai.CritiqueResponse
+	Err      error
+}
+
+// startCritique lets the user write the message themselves, with the
+// provider reviewing drafts on request.
+func (m Model) startCritique() (tea.Model, tea.Cmd) {
+	m.State = StateCritique
+	m.Critique = nil
+	m.CritiqueErr = nil
+	m.TextArea.Reset()
+	m.TextArea.CharLimit = 0
+	m.TextArea.Placeholder = "Write your commit message here..."
+	m.resizeCritique()
+	m.TextArea.Focus()
+	return m, nil
+}
+
+func (m *Model) resizeCritique() {
+	m.TextArea.SetWidth(critiquePaneWidth(m.Width))
+	m.TextArea.SetHeight(max(m.Height-8, 5))
+}
+
+// critiquePaneWidth splits the screen between the draft and the feedback.
+func critiquePaneWidth(width int) int {
+	return max((width-6)/2, 30)
+}
+
+func (m Model) updateCritique(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if _, ok := msg.(tea.WindowSizeMsg); ok {
+		m.resizeCritique()
+		return m, nil
+	}
+	if keyMsg, ok := msg.(tea.KeyMsg); ok {
+		switch keyMsg.String() {
+		case "ctrl+r":
+			draft := strings.TrimSpace(m.TextArea.Value())
+			if draft == "" || m.CritiqueRunning {
+				return m, nil
+			}
+			m.CritiqueRunning = true
+			m.CritiqueErr = nil
+			return m, critiqueCmd(m.Requests, m.AIClient, m.Diff, draft)
+		case "ctrl+s":
+			draft := strings.TrimSpace(m.TextArea.Value())
+			if draft == "" {
+				return m, nil
+			}
+			if err := m.Config.CheckMessage(draft); err != nil {
+				m.CritiqueErr = err
+				return m, nil
+			}
+			m.CommitMsg = draft
+			return m.commitAsIs(m.CommitMsg)
+		case "esc":
+			m.Critiquing = false
+			m.TextArea.Reset()
+			m.TextArea.Placeholder = answerPlaceholder
+			m.TextArea.SetWidth(m.Width - 4)
+			m.TextArea.SetHeight(defaultTextAreaHeight)
+			m.State = StateWelcome
+			return m, nil
+		}
+	}
+	var cmd tea.Cmd
+	m.TextArea, cmd = m.TextArea.Update(msg)
+	return m, cmd
+}
+
+func critiqueCmd(ctx context.Context, client ai.Provider, diff, message string) tea.Cmd {
+	return func() tea.Msg {
+		critique, err := client.CritiqueMessage(ctx, diff, message)
+		return critiqueResultMsg{Message: message, Critique: critique, Err: err}
+	}
+}
+
+func (m Model) viewCritique() string {
+	titleStyle := lipgloss.NewStyle().Bold(true)
+	infoStyle := lipgloss.NewStyle().Foreground(theme.Muted)
+	width := critiquePaneWidth(m.Width)
+
+	panel := lipgloss.NewStyle().
+		Width(width).
+		Height(max(m.Height-8, 5)).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(theme.Muted).
+		Padding(0, 1).
+		Render(m.renderCritique(width - 4))
+
+	save := "commit"
+	if m.Options.MessageOnly {
+		save = "use it"
+	}
+	return fmt.Sprintf("\n %s\n\n%s\n\n %s\n",
+		titleStyle.Render("Write Your Commit Message"),
+		lipgloss.JoinHorizontal(lipgloss.Top, m.TextArea.View(), "  ", panel),
+		infoStyle.Render("ctrl+r: get feedback · ctrl+s: "+save+" · esc: back"),
+	)
+}
+
+// renderCritique lays out the feedback on the last reviewed draft.
+func (m Model) renderCritique(width int) string {
+	infoStyle := lipgloss.NewStyle().Foreground(theme.Muted)
+	errorStyle := lipgloss.NewStyle().Foreground(theme.Error)
+	headingStyle := lipgloss.NewStyle().Bold(true).Foreground(theme.Accent)
+	textStyle := lipgloss.NewStyle().Width(width)
+
+	if m.CritiqueRunning {
+		return m.Spinner.View() + " Reviewing your draft..." + m.retrying() + "\n\n" + infoStyle.Render(cancelHint)
+	}
+	if m.CritiqueErr != nil {
+		return errorStyle.Render(textStyle.Render(m.CritiqueErr.Error()))
+	}
+	c := m.Critique
+	if c == nil {
+		return infoStyle.Render(textStyle.Render("Press ctrl+r for feedback on your draft: vague language, a missing \"why\", and changes the message doesn't cover."))
+	}
+
+	var b strings.Builder
+	if strings.TrimSpace(m.TextArea.Value()) != m.CritiquedMsg {
+		b.WriteString(infoStyle.Render("(feedback is for an earlier draft)") + "\n\n")
+	}
+	section := func(title string, items []string) {
+		if len(items) == 0 {
+			return
+		}
+		b.WriteString(headingStyle.Render(title) + "\n")
+		for _, item := range items {
+			b.WriteString(textStyle.Render("• "+item) + "\n")
+		}
+		b.WriteString("\n")
+	}
+	var missingWhy []string
+	if why := strings.TrimSpace(c.MissingWhy); why != "" {
+		missingWhy = []string{why}
+	}
+	section("Vague language", c.VagueLanguage)
+	section("Missing why", missingWhy)
+	section("Unrelated changes", c.UnrelatedChanges)
+	section("Suggestions", c.Suggestions)
+	if len(c.VagueLanguage)+len(missingWhy)+len(c.UnrelatedChanges)+len(c.Suggestions) == 0 {
+		b.WriteString(textStyle.Render("No problems found. Press ctrl+s to commit."))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,91 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/arpxspace/smartcommit/internal/config"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// updateCustomSetup walks through configuring an OpenAI-compatible
+// endpoint: its URL, an optional key, the model, and whether it supports
+// JSON schema response formats.
+func (m Model) updateCustomSetup(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	switch m.SetupStep {
+	case SetupStepCustomURL:
+		if msg.Type == tea.KeyEnter {
+			input := strings.TrimSpace(m.TextArea.Value())
+			if err := config.CheckURL(input); err != nil {
+				m.SetupProblem = &config.Invalid{Key: "custom_url", Err: err}
+				return m, nil
+			}
+			m.SetupProblem = nil
+			m.Config.CustomURL = input
+			m.SetupStep = SetupStepCustomKey
+			m.TextArea.Reset()
+			return m, nil
+		}
+	case SetupStepCustomKey:
+		if msg.Type == tea.KeyEnter {
+			// Servers without authentication need no key.
+			m.Config.CustomAPIKey = strings.TrimSpace(m.TextArea.Value())
+			m.SetupStep = SetupStepCustomModel
+			m.TextArea.Reset()
+			m.TextArea.SetValue(m.Config.CustomModel)
+			return m, nil
+		}
+	case SetupStepCustomModel:
+		if msg.Type == tea.KeyEnter {
+			if input := strings.TrimSpace(m.TextArea.Value()); input != "" {
+				m.Config.CustomModel = input
+				m.SetupStep = SetupStepCustomStructured
+				m.TextArea.Reset()
+			}
+			return m, nil
+		}
+	case SetupStepCustomStructured:
+		switch strings.ToLower(msg.String()) {
+		case "y", "n":
+			m.Config.Provider = config.ProviderCustom
+			m.Config.CustomStructuredOutput = strings.ToLower(msg.String()) == "y"
+			return m.saveProvider()
+		}
+		return m, nil
+	}
+	m.TextArea, cmd = m.TextArea.Update(msg)
+	return m, cmd
+}
+
+func (m Model) viewCustomSetup() string {
+	titleStyle := lipgloss.NewStyle().Bold(true)
+	infoStyle := lipgloss.NewStyle().Foreground(theme.Muted)
+
+	var title, hint string
+	switch m.SetupStep {
+	case SetupStepCustomURL:
+		title = "Please enter the endpoint's base URL:"
+		hint = "(e.g. http://localhost:8080/v1 for llama.cpp or http://localhost:1234/v1 for LM Studio; press Enter to continue)"
+	case SetupStepCustomKey:
+		title = "Please enter the endpoint's API key:"
+		hint = "(Press Enter to continue; leave empty if the server needs none)"
+	case SetupStepCustomModel:
+		title = "Please enter the model name:"
+		hint = "(Press Enter to continue)"
+	case SetupStepCustomStructured:
+		return fmt.Sprintf(
+			"\n %s\n\n Can the server constrain replies to a JSON schema (response_format\n \"json_schema\")? If you're not sure, answer no: the schema is then\n described in the prompt and malformed replies are repaired.\n\n %s\n",
+			titleStyle.Render("Structured Outputs"),
+			infoStyle.Render("(y/n)"),
+		)
+	}
+	return fmt.Sprintf(
+		"\n %s\n\n%s\n\n%s\n",
+		titleStyle.Render(title),
+		m.TextArea.View(),
+		infoStyle.Render(hint),
+	)
+}
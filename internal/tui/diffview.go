@@ -0,0 +1,133 @@
+package tui
+
+import (
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/arpxspace/smartcommit/internal/diff"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// openDiffPreview shows the staged diff, returning to the current state
+// when closed.
+func (m Model) openDiffPreview() (tea.Model, tea.Cmd) {
+	m.DiffReturn = m.State
+	m.Viewport.SetContent(renderDiff(diff.Join(m.Files)))
+	m.Viewport.Height = m.Height - 4
+	m.Viewport.GotoTop()
+	m.State = StateDiffPreview
+	return m, nil
+}
+
+func (m Model) updateDiffPreview(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if keyMsg, ok := msg.(tea.KeyMsg); ok {
+		switch {
+		case key.Matches(keyMsg, keys.Back, keys.Diff):
+			m.State = m.DiffReturn
+			if m.State == StateQuestioning {
+				m.TextArea.Focus()
+			}
+			return m, nil
+		}
+	}
+	var cmd tea.Cmd
+	m.Viewport, cmd = m.Viewport.Update(msg)
+	return m, cmd
+}
+
+var (
+	diffHeaderStyle = lipgloss.NewStyle().Bold(true)
+	diffHunkStyle   = lipgloss.NewStyle().Foreground(theme.DiffHunk)
+	diffAddStyle    = lipgloss.NewStyle().Foreground(theme.Success)
+	diffRemoveStyle = lipgloss.NewStyle().Foreground(theme.Error)
+	syntaxKeyword   = lipgloss.NewStyle().Foreground(theme.Accent)
+	syntaxString    = lipgloss.NewStyle().Foreground(theme.SyntaxString)
+	syntaxComment   = lipgloss.NewStyle().Foreground(theme.Muted)
+	syntaxNumber    = lipgloss.NewStyle().Foreground(theme.SyntaxNumber)
+)
+
+// renderDiff colors a unified diff: file headers in bold, hunk headers in
+// blue, removed lines in red, and added and context lines highlighted by
+// the lexer for the file's language.
+func renderDiff(d string) string {
+	lexer := lexers.Fallback
+	var b strings.Builder
+	for _, line := range strings.Split(strings.TrimRight(d, "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			lexer = lexerFor(line[strings.LastIndex(line, " ")+1:])
+			b.WriteString("\n" + diffHeaderStyle.Render(line))
+		case strings.HasPrefix(line, "+++ "), strings.HasPrefix(line, "--- "),
+			strings.HasPrefix(line, "index "), strings.HasPrefix(line, "new file"),
+			strings.HasPrefix(line, "deleted file"), strings.HasPrefix(line, "similarity"),
+			strings.HasPrefix(line, "rename "), strings.HasPrefix(line, "Binary files"):
+			b.WriteString(diffHeaderStyle.Render(line))
+		case strings.HasPrefix(line, "@@"):
+			b.WriteString(diffHunkStyle.Render(line))
+		case strings.HasPrefix(line, "-"):
+			b.WriteString(diffRemoveStyle.Render(line))
+		case strings.HasPrefix(line, "+"):
+			b.WriteString(diffAddStyle.Render("+") + highlight(line[1:], lexer))
+		case line == "":
+		default:
+			b.WriteString(line[:1] + highlight(line[1:], lexer))
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimLeft(b.String(), "\n")
+}
+
+// lexerFor returns the lexer for the file at path, or the plain text lexer
+// for languages chroma doesn't know.
+func lexerFor(path string) chroma.Lexer {
+	lexer := lexers.Match(filepath.Base(path))
+	if lexer == nil {
+		return lexers.Fallback
+	}
+	return chroma.Coalesce(lexer)
+}
+
+// highlight colors one line of code with lexer. Each line is lexed on its
+// own, so a comment or string spanning lines is only colored where it
+// starts.
+func highlight(code string, lexer chroma.Lexer) string {
+	tokens, err := lexer.Tokenise(nil, code)
+	if err != nil {
+		return code
+	}
+	var b strings.Builder
+	if err := themeFormatter.Format(&b, nil, tokens); err != nil {
+		return code
+	}
+	return b.String()
+}
+
+// themeFormatter is a terminal formatter that draws tokens in the TUI
+// theme's colors rather than a chroma style, so diffs follow the theme and
+// its color overrides.
+var themeFormatter = chroma.FormatterFunc(func(w io.Writer, _ *chroma.Style, tokens chroma.Iterator) error {
+	for t := tokens(); t != chroma.EOF; t = tokens() {
+		// Lexers end their input with a newline; the line has none.
+		value := strings.TrimRight(t.Value, "\n")
+		switch {
+		case t.Type.InCategory(chroma.Comment):
+			value = syntaxComment.Render(value)
+		case t.Type.InCategory(chroma.Keyword):
+			value = syntaxKeyword.Render(value)
+		case t.Type.InSubCategory(chroma.LiteralString):
+			value = syntaxString.Render(value)
+		case t.Type.InSubCategory(chroma.LiteralNumber):
+			value = syntaxNumber.Render(value)
+		}
+		if _, err := io.WriteString(w, value); err != nil {
+			return err
+		}
+	}
+	return nil
+})
@@ -0,0 +1,250 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/arpxspace/smartcommit/internal/config"
+	"github.com/arpxspace/smartcommit/internal/git"
+
+	"github.com/charmbracelet/bubbles/textarea"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// The lengths commit messages conventionally keep to: a subject of about
+// 50 characters and body lines wrapped at 72.
+const (
+	subjectGuide = 50
+	bodyWidth    = 72
+)
+
+// messageEditor is the editor built into the TUI for the final message,
+// with the subject and body edited apart and measured against the usual
+// limits, for terminals where git's editor can't run.
+type messageEditor struct {
+	Subject textinput.Model
+	Body    textarea.Model
+	// Action names what saving does, like "commit".
+	Action string
+	// Save takes the edited message where it's going.
+	Save func(m Model, message string) (tea.Model, tea.Cmd)
+	// Return is the screen esc goes back to.
+	Return SessionState
+}
+
+func newMessageEditor(message string, width, height int) *messageEditor {
+	subject, body, _ := strings.Cut(strings.TrimSpace(message), "\n")
+
+	si := textinput.New()
+	si.Prompt = ""
+	si.Placeholder = "Summarize the change in one line"
+	si.SetValue(subject)
+	si.Width = max(width-4, 20)
+	si.Focus()
+
+	ta := textarea.New()
+	ta.Prompt = ""
+	ta.ShowLineNumbers = false
+	ta.CharLimit = 0
+	ta.Placeholder = "Explain what changed and why"
+	ta.SetWidth(max(width-4, 20))
+	ta.SetHeight(max(height-14, 3))
+	ta.SetValue(strings.TrimSpace(body))
+	ta.Blur()
+	return &messageEditor{Subject: si, Body: ta}
+}
+
+// Message is the subject and body joined as a commit message.
+func (e *messageEditor) Message() string {
+	subject := strings.TrimSpace(e.Subject.Value())
+	body := strings.TrimSpace(e.Body.Value())
+	if body == "" {
+		return subject
+	}
+	return subject + "\n\n" + body
+}
+
+// useTUIEditor reports whether the final message is edited in the TUI
+// rather than in git's editor.
+func (m Model) useTUIEditor() bool {
+	if !git.UsesExec() {
+		// Only git runs git's editor.
+		return true
+	}
+	switch m.Config.Editor {
+	case config.EditorTUI:
+		return true
+	case config.EditorGit:
+		return false
+	}
+	return !git.HasEditor()
+}
+
+// openEditor edits message in the TUI, then hands the result to save.
+func (m Model) openEditor(message, action string, save func(Model, string) (tea.Model, tea.Cmd)) (tea.Model, tea.Cmd) {
+	e := newMessageEditor(message, m.Width, m.Height)
+	e.Action = action
+	e.Save = save
+	e.Return = m.State
+	if e.Return == StateCommit || e.Return == StateError {
+		e.Return = StateWelcome
+	}
+	m.Editor = e
+	m.State = StateEditor
+	return m, textinput.Blink
+}
+
+// commitEditing commits msg after the user has had a chance to edit it, in
+// git's editor or the TUI's. An empty msg is written from scratch. With
+// --message-only there's no commit for git's editor to run in, so it's
+// always the TUI's.
+func (m Model) commitEditing(msg string) (tea.Model, tea.Cmd) {
+	if !m.useTUIEditor() && !m.Options.MessageOnly {
+		m.State = StateCommit
+		return m, commitCmd(msg, m.signArgs()...)
+	}
+	action := "commit"
+	if m.Options.MessageOnly {
+		action = "use it"
+	}
+	return m.openEditor(msg, action, func(m Model, message string) (tea.Model, tea.Cmd) {
+		// A manual commit keeps an empty CommitMsg, so it's recorded as one.
+		if m.CommitMsg != "" {
+			m.CommitMsg = message
+		}
+		return m.commitAsIs(message)
+	})
+}
+
+// editInReview edits the reviewed message in the TUI and returns to the
+// review.
+func (m Model) editInReview() (tea.Model, tea.Cmd) {
+	return m.openEditor(m.CommitMsg, "save", func(m Model, message string) (tea.Model, tea.Cmd) {
+		m.CommitMsg = message
+		m.Fixes = nil
+		return m.enterReview()
+	})
+}
+
+func (m Model) updateEditor(msg tea.Msg) (tea.Model, tea.Cmd) {
+	e := m.Editor
+	if keyMsg, ok := msg.(tea.KeyMsg); ok {
+		switch keyMsg.String() {
+		case "ctrl+s":
+			if strings.TrimSpace(e.Subject.Value()) == "" {
+				return m, nil
+			}
+			m.Editor = nil
+			return e.Save(m, e.Message())
+		case "esc":
+			m.Editor = nil
+			if e.Return == StateReview {
+				return m.enterReview()
+			}
+			m.State = e.Return
+			return m, nil
+		case "tab", "shift+tab":
+			return m, e.toggleFocus()
+		case "enter", "down":
+			if e.Subject.Focused() {
+				return m, e.toggleFocus()
+			}
+		case "up":
+			if e.Body.Focused() && e.Body.Line() == 0 {
+				return m, e.toggleFocus()
+			}
+		}
+	}
+	var cmd tea.Cmd
+	if e.Subject.Focused() {
+		e.Subject, cmd = e.Subject.Update(msg)
+	} else {
+		e.Body, cmd = e.Body.Update(msg)
+	}
+	return m, cmd
+}
+
+// toggleFocus moves between the subject and the body.
+func (e *messageEditor) toggleFocus() tea.Cmd {
+	if e.Subject.Focused() {
+		e.Subject.Blur()
+		return e.Body.Focus()
+	}
+	e.Body.Blur()
+	return e.Subject.Focus()
+}
+
+func (m Model) viewEditor() string {
+	titleStyle := lipgloss.NewStyle().Bold(true)
+	labelStyle := lipgloss.NewStyle().Foreground(theme.Accent).Bold(true)
+	infoStyle := lipgloss.NewStyle().Foreground(theme.Muted)
+	okStyle := lipgloss.NewStyle().Foreground(theme.Success)
+	warnStyle := lipgloss.NewStyle().Foreground(theme.Warning)
+	errorStyle := lipgloss.NewStyle().Foreground(theme.Error)
+
+	e := m.Editor
+	limit := bodyWidth
+	if n := m.Config.Conventions.MaxSubjectLength; n > 0 {
+		limit = n
+	}
+	n := utf8.RuneCountInString(e.Subject.Value())
+	counter := okStyle
+	switch {
+	case n > limit:
+		counter = errorStyle
+	case n > subjectGuide:
+		counter = warnStyle
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n %s\n\n", titleStyle.Render("Edit Commit Message"))
+	fmt.Fprintf(&b, " %s %s\n", labelStyle.Render("Subject"), counter.Render(fmt.Sprintf("%d/%d", n, subjectGuide)))
+	fmt.Fprintf(&b, " %s\n", e.Subject.View())
+	fmt.Fprintf(&b, " %s\n\n", infoStyle.Render(ruler(subjectGuide, limit)))
+
+	lines := strings.Split(e.Body.Value(), "\n")
+	long := 0
+	for _, line := range lines {
+		if utf8.RuneCountInString(line) > bodyWidth {
+			long++
+		}
+	}
+	var status []string
+	if e.Body.Focused() {
+		info := e.Body.LineInfo()
+		status = append(status, infoStyle.Render(fmt.Sprintf("line %d, col %d/%d", e.Body.Line()+1, info.StartColumn+info.ColumnOffset+1, bodyWidth)))
+	}
+	if long > 0 {
+		status = append(status, warnStyle.Render(fmt.Sprintf("%d %s over %d", long, plural(long, "line", "lines"), bodyWidth)))
+	}
+	fmt.Fprintf(&b, " %s %s\n", labelStyle.Render("Body"), strings.Join(status, infoStyle.Render(" · ")))
+	fmt.Fprintf(&b, " %s\n", infoStyle.Render(ruler(bodyWidth, bodyWidth)))
+	for _, line := range strings.Split(e.Body.View(), "\n") {
+		b.WriteString(" " + line + "\n")
+	}
+
+	if err := m.Config.CheckMessage(e.Message()); err != nil {
+		b.WriteString("\n " + errorStyle.Render("✗ "+err.Error()) + "\n")
+	}
+	b.WriteString("\n " + infoStyle.Render("(tab to switch between subject and body, ctrl+s to "+e.Action+", esc to discard)") + "\n")
+	return b.String()
+}
+
+// ruler marks columns guide and limit, counting from 1, for text that
+// starts at the left edge.
+func ruler(guide, limit int) string {
+	r := []rune(strings.Repeat("─", max(guide, limit)))
+	r[guide-1] = '┤'
+	r[limit-1] = '┤'
+	return string(r) + fmt.Sprintf(" %d", limit)
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
@@ -0,0 +1,255 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/arpxspace/smartcommit/internal/ai"
+	"github.com/arpxspace/smartcommit/internal/config"
+	"github.com/arpxspace/smartcommit/internal/store"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// failure is what went wrong, as far as recovering from it is concerned.
+type failure int
+
+const (
+	failureOther failure = iota
+	failureAuth
+	failureQuota
+	failureRateLimited
+	failureModel
+	failureNetwork
+	failureGit
+)
+
+// classify returns the kind of failure err is.
+func classify(err error) failure {
+	switch ai.Classify(err) {
+	case ai.ErrorAuth:
+		return failureAuth
+	case ai.ErrorQuota:
+		return failureQuota
+	case ai.ErrorRateLimited:
+		return failureRateLimited
+	case ai.ErrorModelNotFound:
+		return failureModel
+	case ai.ErrorNetwork:
+		return failureNetwork
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return failureGit
+	}
+	return failureOther
+}
+
+// failed shows err on the error screen, remembering where it happened so
+// the step can be retried.
+func (m Model) failed(err error) Model {
+	m.ErrState = m.State
+	m.Err = err
+	m.State = StateError
+	return m
+}
+
+// retryable reports whether the step that failed can simply be run again.
+func (m Model) retryable() bool {
+	switch m.ErrState {
+	case StateLoading, StateSummarizing, StateHistoryAnalysis,
+		StateGenerating, StateSplitAnalyzing, StateSetup:
+		return true
+	case StateCommit:
+		return m.CommitMsg != ""
+	}
+	return false
+}
+
+// retry runs the step that failed again.
+func (m Model) retry() (tea.Model, tea.Cmd) {
+	m.Err = nil
+	m.resumeSession()
+	switch m.ErrState {
+	case StateLoading:
+		m.State = StateLoading
+		return m, m.checkPrerequisitesCmd
+	case StateSummarizing:
+		return m.startSummarizing()
+	case StateHistoryAnalysis:
+		return m.startAnalysis()
+	case StateGenerating:
+		m.State = StateGenerating
+		m.StreamText = ""
+		return m, generateCommitMsgCmd(m.Requests, m.AIClient, m.Diff, m.History, m.HistoryCtx, m.Answers)
+	case StateSplitAnalyzing:
+		return m.startSplit()
+	case StateCommit:
+		return m.commitEditing(m.CommitMsg)
+	}
+	m.State = m.ErrState
+	return m, nil
+}
+
+// reconfigure opens setup at step. Setup saves what it edits, so it starts
+// from the global config rather than one with repo overrides.
+func (m Model) reconfigure(step SetupStep) (tea.Model, tea.Cmd) {
+	global, err := config.Load()
+	if err != nil {
+		return m, func() tea.Msg { return errMsg(err) }
+	}
+	m.Err = nil
+	m.Config = global
+	// The provider setup ends with is the one to use.
+	m.Provider = ""
+	m.State = StateSetup
+	m.SetupStep = step
+	m.TextArea.Reset()
+	return m, nil
+}
+
+// keyStep returns the setup step where the configured provider's API key is
+// entered, if setup has one.
+func (m Model) keyStep() (SetupStep, bool) {
+	if m.Config == nil {
+		return 0, false
+	}
+	switch m.Config.Provider {
+	case config.ProviderOpenAI:
+		return SetupStepOpenAIKey, true
+	case config.ProviderOpenRouter:
+		return SetupStepOpenRouterKey, true
+	case config.ProviderCustom:
+		return SetupStepCustomKey, true
+	}
+	return 0, false
+}
+
+// hasDiff reports whether the staged changes were loaded, so the user can
+// still commit them with a message of their own.
+func (m Model) hasDiff() bool {
+	return m.ErrState != StateLoading && m.ErrState != StateSetup && m.Diff != ""
+}
+
+// updateError handles the recovery actions offered on the error screen.
+func (m Model) updateError(msg tea.Msg) (tea.Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+	kind := classify(m.Err)
+	switch key.String() {
+	case "r", "R":
+		if m.retryable() {
+			return m.retry()
+		}
+	case "k", "K":
+		if step, ok := m.keyStep(); ok && kind == failureAuth {
+			return m.reconfigure(step)
+		}
+	case "c", "C":
+		if kind != failureGit && kind != failureOther {
+			return m.reconfigure(SetupStepProvider)
+		}
+	case "e", "E":
+		if kind == failureGit && m.ErrState == StateCommit && m.CommitMsg != "" {
+			m.Err = nil
+			m.resumeSession()
+			return m.enterReview()
+		}
+	case "m", "M":
+		if kind != failureGit && m.hasDiff() {
+			m.Err = nil
+			m.resumeSession()
+			return m.commitEditing("")
+		}
+	}
+	return m, nil
+}
+
+// resumeSession reopens a session closed as failed, since the user is
+// carrying on with it.
+func (m *Model) resumeSession() {
+	if m.Session != nil && m.Session.Outcome == store.OutcomeFailed {
+		m.Session.Outcome = store.OutcomeInProgress
+		m.Session.FinishedAt = time.Time{}
+	}
+}
+
+// viewError explains what went wrong and lists what the user can do next.
+func (m Model) viewError() string {
+	errorStyle := lipgloss.NewStyle().Foreground(theme.Error).Bold(true)
+	infoStyle := lipgloss.NewStyle().Foreground(theme.Muted)
+	cmdStyle := lipgloss.NewStyle().Foreground(theme.Command).Bold(true)
+
+	provider := "the provider"
+	if m.Config != nil && m.Config.Provider != "" {
+		provider = string(m.Config.Provider)
+	}
+
+	var title, explanation string
+	kind := classify(m.Err)
+	switch kind {
+	case failureAuth:
+		title = "Authentication failed."
+		explanation = fmt.Sprintf("The API key for %s is missing, invalid, or lacks access to the model.", provider)
+	case failureQuota:
+		title = "Out of quota."
+		explanation = fmt.Sprintf("The %s account has run out of credits or hit its spending limit.\nAdd credits or switch to another provider.", provider)
+	case failureRateLimited:
+		title = "Rate limited."
+		explanation = fmt.Sprintf("%s kept refusing requests, even after retrying. Wait a moment and retry.", provider)
+	case failureModel:
+		title = "Model not found."
+		if m.Config != nil && m.Config.Provider == config.ProviderOllama {
+			explanation = fmt.Sprintf("You don't have '%s' installed through Ollama.\nTo install it, run the following command, then retry:\n\n   %s",
+				m.Config.OllamaModel, cmdStyle.Render("ollama pull "+m.Config.OllamaModel))
+		} else {
+			explanation = fmt.Sprintf("%s doesn't offer the configured model, or this key can't use it.", provider)
+		}
+	case failureNetwork:
+		title = "Couldn't reach the provider."
+		explanation = fmt.Sprintf("Check your connection and that %s is up, then retry.", provider)
+	case failureGit:
+		title = "Git failed."
+		explanation = "A git command, or a hook it ran, exited with an error."
+	default:
+		title = "Something went wrong."
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n %s %s\n\n", errorStyle.Render("Error:"), title)
+	if explanation != "" {
+		for _, line := range strings.Split(explanation, "\n") {
+			fmt.Fprintf(&b, " %s\n", line)
+		}
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(&b, " %s\n\n", infoStyle.Render(m.Err.Error()))
+
+	var actions []string
+	if m.retryable() {
+		actions = append(actions, "Press 'r' to retry.")
+	}
+	if _, ok := m.keyStep(); ok && kind == failureAuth {
+		actions = append(actions, "Press 'k' to enter a new API key.")
+	}
+	if kind != failureGit && kind != failureOther {
+		actions = append(actions, "Press 'c' to switch provider.")
+	}
+	if kind == failureGit && m.ErrState == StateCommit && m.CommitMsg != "" {
+		actions = append(actions, "Press 'e' to edit the message.")
+	}
+	if kind != failureGit && m.hasDiff() {
+		actions = append(actions, "Press 'm' to write the message yourself.")
+	}
+	actions = append(actions, "Press 'q' to quit.")
+	for _, a := range actions {
+		fmt.Fprintf(&b, " %s\n", a)
+	}
+	return b.String()
+}
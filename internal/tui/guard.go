@@ -0,0 +1,60 @@
+package tui
+
+import (
+	"context"
+	"errors"
+
+	"github.com/arpxspace/smartcommit/internal/ai"
+	"github.com/arpxspace/smartcommit/internal/guard"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type subjectShortenedMsg struct {
+	Subject string
+	Err     error
+}
+
+// guardMessage fixes the form of the generated message before it's
+// reviewed and, when its subject is still over the limit, has the provider
+// shorten it once.
+func (m Model) guardMessage() (tea.Model, tea.Cmd) {
+	m.Fixes = nil
+	if m.Config.DisableGuardrails {
+		return m.enterReview()
+	}
+	m.CommitMsg, m.Fixes = guard.Fix(m.CommitMsg)
+	limit := m.Config.Conventions.MaxSubjectLength
+	if guard.SubjectFits(m.CommitMsg, limit) || m.AIClient == nil {
+		return m.enterReview()
+	}
+	if limit == 0 {
+		limit = guard.DefaultSubjectLength
+	}
+	m.State = StateGenerating
+	m.Shortening = true
+	return m, shortenSubjectCmd(m.Requests, m.AIClient, m.CommitMsg, limit)
+}
+
+func (m Model) updateShortened(msg subjectShortenedMsg) (tea.Model, tea.Cmd) {
+	if errors.Is(msg.Err, context.Canceled) {
+		return m, nil
+	}
+	m.Shortening = false
+	// Without a shorter subject, the review shows what's still wrong.
+	if msg.Err == nil && msg.Subject != "" {
+		shortened, fixes := guard.Fix(guard.ReplaceSubject(m.CommitMsg, msg.Subject))
+		if len(shortened) < len(m.CommitMsg) {
+			m.CommitMsg = shortened
+			m.Fixes = append(append(m.Fixes, "shortened the subject"), fixes...)
+		}
+	}
+	return m.enterReview()
+}
+
+func shortenSubjectCmd(ctx context.Context, client ai.Provider, message string, limit int) tea.Cmd {
+	return func() tea.Msg {
+		subject, err := client.ShortenSubject(ctx, message, limit)
+		return subjectShortenedMsg{Subject: subject, Err: err}
+	}
+}
@@ -0,0 +1,199 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/arpxspace/smartcommit/internal/store"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// History browses recorded sessions so a message from an aborted run can be
+// recovered, or an earlier one reused.
+type History struct {
+	Sessions []*store.Session
+	Cursor   int
+	// Open is the session being viewed, or nil while browsing the list.
+	Open *store.Session
+	// Message indexes Open.Messages().
+	Message int
+	// Printed is the message chosen with p, printed once the program exits.
+	Printed   string
+	Committed bool
+	Err       error
+	Width     int
+	Height    int
+}
+
+// NewHistory returns a browser over sessions, newest first.
+func NewHistory(sessions []*store.Session) History {
+	return History{Sessions: sessions}
+}
+
+func (h History) Init() tea.Cmd {
+	return nil
+}
+
+func (h History) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		h.Width, h.Height = msg.Width, msg.Height
+		return h, nil
+	case commitSuccessMsg:
+		h.Committed = true
+		return h, tea.Quit
+	case errMsg:
+		h.Err = msg
+		return h, nil
+	case signingFailedMsg:
+		h.Err = msg.error()
+		return h, nil
+	case hookFailedMsg:
+		h.Err = msg.error()
+		return h, nil
+	case tea.KeyMsg:
+		h.Err = nil
+		if msg.String() == "ctrl+c" {
+			return h, tea.Quit
+		}
+		if h.Open != nil {
+			return h.updateOpen(msg)
+		}
+		return h.updateList(msg)
+	}
+	return h, nil
+}
+
+func (h History) updateList(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, keys.Back):
+		return h, tea.Quit
+	case key.Matches(msg, keys.Up):
+		if h.Cursor > 0 {
+			h.Cursor--
+		}
+	case key.Matches(msg, keys.Down):
+		if h.Cursor < len(h.Sessions)-1 {
+			h.Cursor++
+		}
+	case key.Matches(msg, keys.Select):
+		if len(h.Sessions) > 0 {
+			h.Open = h.Sessions[h.Cursor]
+			h.Message = 0
+		}
+	}
+	return h, nil
+}
+
+func (h History) updateOpen(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	messages := h.Open.Messages()
+	switch msg.String() {
+	case "q":
+		return h, tea.Quit
+	case "esc":
+		h.Open = nil
+	case "left", "h":
+		if h.Message > 0 {
+			h.Message--
+		}
+	case "right", "l":
+		if h.Message < len(messages)-1 {
+			h.Message++
+		}
+	case "p":
+		if len(messages) > 0 {
+			h.Printed = messages[h.Message]
+			return h, tea.Quit
+		}
+	case "c":
+		if len(messages) > 0 {
+			// Opens the editor, like the main flow, so the message can be
+			// adjusted to whatever is staged now.
+			return h, commitCmd(messages[h.Message])
+		}
+	}
+	return h, nil
+}
+
+// subject is the subject line shown for s in the list.
+func subject(s *store.Session) string {
+	if subject := s.Subject(); subject != "" {
+		return subject
+	}
+	return "(no message generated)"
+}
+
+func (h History) View() string {
+	titleStyle := lipgloss.NewStyle().Bold(true)
+	infoStyle := lipgloss.NewStyle().Foreground(theme.Muted)
+	errorStyle := lipgloss.NewStyle().Foreground(theme.Error).Bold(true)
+
+	var b strings.Builder
+	if h.Open != nil {
+		b.WriteString(h.viewOpen())
+	} else {
+		b.WriteString(titleStyle.Render("Commit message history") + "\n\n")
+		if len(h.Sessions) == 0 {
+			b.WriteString("No sessions recorded yet.\n")
+		}
+		width := max(h.Width-40, 30)
+		for i, s := range h.Sessions {
+			cursor := "  "
+			if i == h.Cursor {
+				cursor = "> "
+			}
+			fmt.Fprintf(&b, "%s%s  %-11s  %s\n", cursor, s.StartedAt.Local().Format("2006-01-02 15:04"), s.Outcome, clip(subject(s), width))
+		}
+		b.WriteString("\n" + infoStyle.Render("(↑/↓ to move, enter to open, q to quit)") + "\n")
+	}
+	if h.Err != nil {
+		b.WriteString("\n" + errorStyle.Render(fmt.Sprintf("Error: %v", h.Err)) + "\n")
+	}
+	return b.String()
+}
+
+func (h History) viewOpen() string {
+	titleStyle := lipgloss.NewStyle().Bold(true)
+	labelStyle := lipgloss.NewStyle().Bold(true)
+	infoStyle := lipgloss.NewStyle().Foreground(theme.Muted)
+	messageStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(theme.Border).
+		Padding(0, 1).
+		Width(max(h.Width-4, 40))
+
+	s := h.Open
+	var b strings.Builder
+	b.WriteString(titleStyle.Render(fmt.Sprintf("Session %d", s.ID)) + "\n")
+	b.WriteString(infoStyle.Render(fmt.Sprintf("%s · %s · %s/%s · %s",
+		s.StartedAt.Local().Format("2006-01-02 15:04"), s.Outcome, s.Provider, s.Model, s.RepoRoot)) + "\n\n")
+
+	if len(s.Questions) > 0 {
+		for i, q := range s.Questions {
+			answer := s.Answers[q]
+			if answer == "" {
+				answer = "(skipped)"
+			}
+			fmt.Fprintf(&b, "%s\n   %s\n", labelStyle.Render(fmt.Sprintf("%d. %s", i+1, q)), infoStyle.Render(answer))
+		}
+		b.WriteString("\n")
+	}
+
+	messages := s.Messages()
+	if len(messages) == 0 {
+		b.WriteString("No message was generated in this session.\n")
+		b.WriteString("\n" + infoStyle.Render("(esc to go back, q to quit)") + "\n")
+		return b.String()
+	}
+	label := "Draft"
+	if h.Message == 0 && strings.TrimSpace(s.FinalMessage) != "" {
+		label = "Committed message"
+	}
+	b.WriteString(labelStyle.Render(fmt.Sprintf("%s (%d of %d)", label, h.Message+1, len(messages))) + "\n")
+	b.WriteString(messageStyle.Render(messages[h.Message]) + "\n")
+	b.WriteString("\n" + infoStyle.Render("(←/→ for other messages, c to commit staged changes with it, p to print it, esc to go back, q to quit)") + "\n")
+	return b.String()
+}
@@ -0,0 +1,127 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/arpxspace/smartcommit/internal/git"
+	"github.com/arpxspace/smartcommit/internal/store"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// hookFailedMsg reports a commit rejected by a pre-commit or commit-msg
+// hook, with what it needs to be tried again.
+type hookFailedMsg struct {
+	Err error
+	// Output is what the hook, and git, printed.
+	Output string
+	// Hooks are the installed hooks that may have rejected it.
+	Hooks []string
+	// Message is the message committed with, and Edit whether it was
+	// opened in the editor first.
+	Message string
+	Edit    bool
+}
+
+// error describes the failure with the hook's output, for screens without
+// room for it.
+func (f hookFailedMsg) error() error {
+	return fmt.Errorf("%w: %s", f.Err, strings.TrimSpace(f.Output))
+}
+
+// hookName names the hook that rejected the commit, or both candidates
+// when it could have been either.
+func (f hookFailedMsg) hookName() string {
+	if len(f.Hooks) == 1 {
+		return "The " + f.Hooks[0] + " hook"
+	}
+	return "A " + strings.Join(f.Hooks, " or ") + " hook"
+}
+
+// hookFailed shows the hook's output, keeping the message and the session
+// to commit again once the problems are fixed.
+func (m Model) hookFailed(msg hookFailedMsg) (tea.Model, tea.Cmd) {
+	m.HookFailure = &msg
+	m.State = StateHookFailed
+	m.finishSession(store.OutcomeFailed)
+	output := strings.TrimRight(msg.Output, "\n")
+	m.Viewport.SetContent(output)
+	m.Viewport.Height = min(strings.Count(output, "\n")+1, max(m.Height-10, 5))
+	m.Viewport.GotoBottom()
+	return m, nil
+}
+
+// Keys for committing again after a hook rejected the commit.
+var (
+	hookRetry = bind("commit again", "r")
+	hookStage = bind("stage fixes and commit again", "a")
+	hookEdit  = bind("edit the message", "e")
+)
+
+// updateHookFailed scrolls through the hook's output until the user has
+// fixed the problems and commits again, with or without staging the fixes
+// first, or goes back to the message.
+func (m Model) updateHookFailed(msg tea.Msg) (tea.Model, tea.Cmd) {
+	failed := m.HookFailure
+	if keyMsg, ok := msg.(tea.KeyMsg); ok {
+		switch keyMsg.String() {
+		case "r", "R":
+			m.HookFailure = nil
+			return m.recommit(failed.Message, failed.Edit)
+		case "a", "A":
+			if err := m.stageFixes(); err != nil {
+				return m.Update(errMsg(err))
+			}
+			m.HookFailure = nil
+			return m.recommit(failed.Message, failed.Edit)
+		case "e", "E":
+			if m.CommitMsg == "" {
+				return m, nil
+			}
+			m.HookFailure = nil
+			m.resumeSession()
+			return m.enterReview()
+		}
+	}
+	var cmd tea.Cmd
+	m.Viewport, cmd = m.Viewport.Update(msg)
+	return m, cmd
+}
+
+// stageFixes stages what has changed since in the files being committed,
+// such as fixes a formatter hook made or the user made by hand.
+func (m Model) stageFixes() error {
+	var paths []string
+	for _, f := range m.Files {
+		// A deleted file has nothing left to stage.
+		path := filepath.Join(m.RepoRoot, f.Path)
+		if _, err := os.Stat(path); err == nil {
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+	return git.StagePaths(paths...)
+}
+
+// viewHookFailed shows what the hook printed and how to carry on.
+func (m Model) viewHookFailed() string {
+	errorStyle := lipgloss.NewStyle().Foreground(theme.Error).Bold(true)
+	infoStyle := lipgloss.NewStyle().Foreground(theme.Muted)
+
+	f := m.HookFailure
+	edit := hookEdit
+	edit.SetEnabled(m.CommitMsg != "")
+	return fmt.Sprintf("\n %s %s rejected the commit.\n\n%s\n\n %s\n %s\n",
+		errorStyle.Render("Error:"),
+		f.hookName(),
+		m.Viewport.View(),
+		"Your message is kept. Fix the problems above, then commit again.",
+		infoStyle.Render(hint(hookRetry, hookStage, edit, keys.Help)),
+	)
+}
@@ -0,0 +1,49 @@
+package tui
+
+import (
+	"fmt"
+
+	"github.com/arpxspace/smartcommit/internal/config"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// fixSetting opens setup at the step that sets the invalid setting, with
+// what's there to correct. Settings setup doesn't ask for, like Azure's,
+// are shown as an error instead.
+func (m Model) fixSetting(invalid *config.Invalid) (tea.Model, tea.Cmd) {
+	m.State = StateSetup
+	m.SetupProblem = invalid
+	m.TextArea.Reset()
+	switch invalid.Key {
+	case "provider":
+		m.SetupStep = SetupStepProvider
+	case "ollama_url":
+		m.SelectedProvider = config.ProviderOllama
+		m.SetupStep = SetupStepOllamaURL
+		m.TextArea.SetValue(m.Config.OllamaURL)
+	case "ollama_model":
+		m.SelectedProvider = config.ProviderOllama
+		return m.openOllamaPicker()
+	case "custom_url":
+		m.SelectedProvider = config.ProviderCustom
+		m.SetupStep = SetupStepCustomURL
+		m.TextArea.SetValue(m.Config.CustomURL)
+	case "custom_model":
+		m.SelectedProvider = config.ProviderCustom
+		m.SetupStep = SetupStepCustomModel
+		m.TextArea.SetValue(m.Config.CustomModel)
+	default:
+		m.State = StateLoading
+		m.SetupProblem = nil
+		return m.failed(fmt.Errorf("%w; change it with `smartcommit config set %s`", invalid, invalid.Key)), nil
+	}
+	return m, nil
+}
+
+// viewSetupProblem explains why setup is asking again.
+func (m Model) viewSetupProblem() string {
+	errorStyle := lipgloss.NewStyle().Foreground(theme.Error).Bold(true)
+	return fmt.Sprintf("\n %s %v\n", errorStyle.Render("Error:"), m.SetupProblem)
+}
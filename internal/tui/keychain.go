@@ -0,0 +1,51 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/arpxspace/smartcommit/internal/config"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// saveProvider saves the config once a provider is set up. When the API
+// key can't go to the keychain, as on Linux without secret-tool, it offers
+// to keep keys in the config file instead.
+func (m Model) saveProvider() (tea.Model, tea.Cmd) {
+	if err := m.Config.Save(); err != nil {
+		if errors.Is(err, config.ErrKeychain) {
+			m.SetupProblem = err
+			m.SetupStep = SetupStepPlaintextKeys
+			return m, nil
+		}
+		return m.failed(err), nil
+	}
+	return m.providerConfigured()
+}
+
+func (m Model) updatePlaintextKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch strings.ToLower(msg.String()) {
+	case "y":
+		m.Config.PlaintextKeys = true
+		return m.saveProvider()
+	case "n":
+		err := m.SetupProblem
+		m.SetupProblem = nil
+		return m.failed(err), nil
+	}
+	return m, nil
+}
+
+func (m Model) viewPlaintextKeys() string {
+	titleStyle := lipgloss.NewStyle().Bold(true)
+	infoStyle := lipgloss.NewStyle().Foreground(theme.Muted)
+	path, _ := config.Path()
+	return fmt.Sprintf("\n %s\n %s\n\n %s\n",
+		titleStyle.Render("Keep API keys in the config file instead?"),
+		infoStyle.Render(fmt.Sprintf("They're saved in %s, readable only by you (plaintext_keys).", path)),
+		infoStyle.Render("(y to keep them in the file, n to stop)"),
+	)
+}
@@ -0,0 +1,422 @@
+package tui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/arpxspace/smartcommit/internal/config"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// KeyMap holds the key bindings the user can remap, by action.
+type KeyMap struct {
+	Quit   key.Binding
+	Cancel key.Binding
+	Help   key.Binding
+
+	// Moving through and picking from lists.
+	Up     key.Binding
+	Down   key.Binding
+	Toggle key.Binding
+	Select key.Binding
+	Back   key.Binding
+
+	// Scrolling the message and diff.
+	ScrollUp     key.Binding
+	ScrollDown   key.Binding
+	PageUp       key.Binding
+	PageDown     key.Binding
+	HalfPageUp   key.Binding
+	HalfPageDown key.Binding
+
+	// The welcome screen.
+	Generate    key.Binding
+	Manual      key.Binding
+	Critique    key.Binding
+	Split       key.Binding
+	Stack       key.Binding
+	Stage       key.Binding
+	Diff        key.Binding
+	Preview     key.Binding
+	Language    key.Binding
+	Profile     key.Binding
+	Provider    key.Binding
+	Reconfigure key.Binding
+
+	// The clarifying questions. Diff and Skip act before an answer is
+	// typed.
+	Submit           key.Binding
+	Skip             key.Binding
+	PreviousQuestion key.Binding
+
+	// The review screen.
+	Accept        key.Binding
+	EditHere      key.Binding
+	Edit          key.Binding
+	Regenerate    key.Binding
+	CoAuthors     key.Binding
+	Specific      key.Binding
+	ChangeAnswers key.Binding
+	Abort         key.Binding
+}
+
+// DefaultKeyMap returns the bindings used unless the config remaps them.
+func DefaultKeyMap() KeyMap {
+	return KeyMap{
+		Quit:   bind("quit", "ctrl+c"),
+		Cancel: bind("cancel the request", "esc", "ctrl+x"),
+		Help:   bind("show keys", "?"),
+
+		Up:     bind("up", "up", "k", "ctrl+p"),
+		Down:   bind("down", "down", "j", "ctrl+n"),
+		Toggle: bind("toggle", " ", "x"),
+		Select: bind("confirm", "enter"),
+		Back:   bind("back", "esc", "q"),
+
+		ScrollUp:     bind("scroll up", "up", "k"),
+		ScrollDown:   bind("scroll down", "down", "j"),
+		PageUp:       bind("page up", "pgup", "ctrl+b"),
+		PageDown:     bind("page down", "pgdown", "ctrl+f"),
+		HalfPageUp:   bind("half page up", "ctrl+u"),
+		HalfPageDown: bind("half page down", "ctrl+d"),
+
+		Generate:    bind("help me write it", "1", "enter"),
+		Manual:      bind("write it myself", "2"),
+		Critique:    bind("review what I write", "3"),
+		Split:       bind("check for a split", "s", "S"),
+		Stack:       bind("commit all work in a series", "w", "W"),
+		Stage:       bind("change what's staged", "a", "A"),
+		Diff:        bind("view the diff", "d", "D"),
+		Preview:     bind("preview what's sent", "o", "O"),
+		Language:    bind("switch language", "l", "L"),
+		Profile:     bind("switch profile", "m", "M"),
+		Provider:    bind("switch provider", "p", "P"),
+		Reconfigure: bind("reconfigure provider", "c", "C"),
+
+		Submit:           bind("submit the answer", "ctrl+d"),
+		Skip:             bind("skip the rest", "s"),
+		PreviousQuestion: bind("previous question", "esc", "shift+tab"),
+
+		Accept:        bind("commit", "enter", "y", "ZZ"),
+		EditHere:      bind("edit here", "i"),
+		Edit:          bind("edit and commit", "e"),
+		Regenerate:    bind("regenerate with feedback", "r"),
+		CoAuthors:     bind("co-authors", "a"),
+		Specific:      bind("make specific", "v"),
+		ChangeAnswers: bind("change answers", "b"),
+		Abort:         bind("quit", "q", "esc"),
+	}
+}
+
+// bind makes a binding to keys, with help naming them.
+func bind(desc string, keys ...string) key.Binding {
+	names := make([]string, len(keys))
+	for i, k := range keys {
+		names[i] = k
+		if k == " " {
+			names[i] = "space"
+		}
+	}
+	return key.NewBinding(key.WithKeys(keys...), key.WithHelp(strings.Join(names, "/"), desc))
+}
+
+// actions returns k's bindings by the name they're remapped under in the
+// config.
+func (k *KeyMap) actions() map[string]*key.Binding {
+	return map[string]*key.Binding{
+		"quit":              &k.Quit,
+		"cancel":            &k.Cancel,
+		"help":              &k.Help,
+		"up":                &k.Up,
+		"down":              &k.Down,
+		"toggle":            &k.Toggle,
+		"select":            &k.Select,
+		"back":              &k.Back,
+		"scroll_up":         &k.ScrollUp,
+		"scroll_down":       &k.ScrollDown,
+		"page_up":           &k.PageUp,
+		"page_down":         &k.PageDown,
+		"half_page_up":      &k.HalfPageUp,
+		"half_page_down":    &k.HalfPageDown,
+		"generate":          &k.Generate,
+		"manual":            &k.Manual,
+		"critique":          &k.Critique,
+		"split":             &k.Split,
+		"stack":             &k.Stack,
+		"stage":             &k.Stage,
+		"diff":              &k.Diff,
+		"preview":           &k.Preview,
+		"language":          &k.Language,
+		"profile":           &k.Profile,
+		"provider":          &k.Provider,
+		"reconfigure":       &k.Reconfigure,
+		"submit":            &k.Submit,
+		"skip":              &k.Skip,
+		"previous_question": &k.PreviousQuestion,
+		"accept":            &k.Accept,
+		"edit_here":         &k.EditHere,
+		"edit":              &k.Edit,
+		"regenerate":        &k.Regenerate,
+		"co_authors":        &k.CoAuthors,
+		"specific":          &k.Specific,
+		"change_answers":    &k.ChangeAnswers,
+		"abort":             &k.Abort,
+	}
+}
+
+// viewport returns the bindings for scrolling a viewport.
+func (k KeyMap) viewport() viewport.KeyMap {
+	return viewport.KeyMap{
+		Up:           k.ScrollUp,
+		Down:         k.ScrollDown,
+		PageUp:       k.PageUp,
+		PageDown:     k.PageDown,
+		HalfPageUp:   k.HalfPageUp,
+		HalfPageDown: k.HalfPageDown,
+	}
+}
+
+// keys are the bindings the TUI responds to, set by UseKeys.
+var keys = DefaultKeyMap()
+
+// UseKeys has the TUI respond to k. Like UseTheme, it's meant to be called
+// once, before the program starts.
+func UseKeys(k KeyMap) {
+	keys = k
+}
+
+// KeysFor returns DefaultKeyMap with the bindings cfg remaps replaced.
+func KeysFor(cfg *config.Config) (KeyMap, error) {
+	k := DefaultKeyMap()
+	actions := k.actions()
+	names := make([]string, 0, len(cfg.Keys))
+	for name := range cfg.Keys {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		b, ok := actions[name]
+		if !ok {
+			known := make([]string, 0, len(actions))
+			for action := range actions {
+				known = append(known, action)
+			}
+			slices.Sort(known)
+			return KeyMap{}, fmt.Errorf("unknown key action %q; use one of %s", name, strings.Join(known, ", "))
+		}
+		var bound []string
+		for _, s := range cfg.Keys[name] {
+			switch {
+			case s == "space":
+				s = " "
+			case s == "":
+				return KeyMap{}, fmt.Errorf("keys.%s has an empty key", name)
+			}
+			bound = append(bound, s)
+		}
+		*b = bind(b.Help().Desc, bound...)
+	}
+	return k, nil
+}
+
+// sequence reports whether k is a sequence of two key presses, like "ZZ",
+// rather than the name of one key.
+func sequence(k string) bool {
+	r := []rune(k)
+	if len(r) != 2 || k == "up" || r[0] == 'f' && unicode.IsDigit(r[1]) {
+		return false
+	}
+	return unicode.IsGraphic(r[0]) && unicode.IsGraphic(r[1]) && r[0] != ' ' && r[1] != ' '
+}
+
+// chord joins msg to the key press before it when the two make a sequence
+// bound in the current state. When msg starts such a sequence and isn't
+// bound itself, it's held until the next key press, which is reported by
+// held.
+func (m Model) chord(msg tea.KeyMsg) (_ Model, _ tea.KeyMsg, held bool) {
+	pending := m.KeyPending
+	m.KeyPending = ""
+	var bound []string
+	for _, group := range m.keyHelp() {
+		for _, b := range group {
+			if b.Enabled() {
+				bound = append(bound, b.Keys()...)
+			}
+		}
+	}
+	if pending != "" {
+		if seq := pending + msg.String(); slices.Contains(bound, seq) {
+			return m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(seq)}, false
+		}
+	}
+	if msg.Type != tea.KeyRunes || len(msg.Runes) != 1 || slices.Contains(bound, msg.String()) {
+		return m, msg, false
+	}
+	for _, k := range bound {
+		if sequence(k) && strings.HasPrefix(k, msg.String()) {
+			m.KeyPending = msg.String()
+			return m, msg, true
+		}
+	}
+	return m, msg, false
+}
+
+// filterKey reports whether msg presses b in a list filtered by typing,
+// like the model pickers. Keys that type text, like j and k, go to the
+// filter instead.
+func filterKey(msg tea.KeyMsg, b key.Binding) bool {
+	return msg.Type != tea.KeyRunes && msg.Type != tea.KeySpace && key.Matches(msg, b)
+}
+
+// pickerHint describes the keys of a list filtered by typing.
+func pickerHint() string {
+	return fmt.Sprintf("(type to filter, %s/%s to move, %s to save, %s to go back)",
+		keyName(filterKeys(keys.Up)), keyName(filterKeys(keys.Down)), keyName(filterKeys(keys.Select)), keyName(filterKeys(keys.Back)))
+}
+
+// filterKeys returns b without the keys that type text, for hints in a
+// list filtered by typing.
+func filterKeys(b key.Binding) key.Binding {
+	var bound []string
+	for _, k := range b.Keys() {
+		if k != " " && len([]rune(k)) > 1 && !sequence(k) {
+			bound = append(bound, k)
+		}
+	}
+	return bind(b.Help().Desc, bound...)
+}
+
+// keyName names the first key of b, for hints.
+func keyName(b key.Binding) string {
+	if len(b.Keys()) == 0 {
+		return ""
+	}
+	name, _, _ := strings.Cut(b.Help().Key, "/")
+	return name
+}
+
+// hint describes the enabled bindings for a help line, like
+// "enter: commit · q: quit".
+func hint(bindings ...key.Binding) string {
+	var parts []string
+	for _, b := range bindings {
+		if b.Enabled() && len(b.Keys()) > 0 {
+			parts = append(parts, keyName(b)+": "+b.Help().Desc)
+		}
+	}
+	return strings.Join(parts, " · ")
+}
+
+// finishKeys returns the review's accept and edit bindings, described as
+// handing the message back rather than committing it with --message-only.
+func (m Model) finishKeys() (accept, edit key.Binding) {
+	accept, edit = keys.Accept, keys.Edit
+	if m.Options.MessageOnly {
+		accept.SetHelp(accept.Help().Key, "use this message")
+		edit.SetHelp(edit.Help().Key, "edit and use")
+	}
+	return accept, edit
+}
+
+// keyHelp groups the bindings of the current state for the help overlay,
+// or returns nil where keys are typed as text.
+func (m Model) keyHelp() [][]key.Binding {
+	scroll := []key.Binding{keys.ScrollUp, keys.ScrollDown, keys.PageUp, keys.PageDown, keys.HalfPageUp, keys.HalfPageDown}
+	list := []key.Binding{keys.Up, keys.Down, keys.Toggle, keys.Select, keys.Back}
+	switch m.State {
+	case StateWelcome:
+		language := keys.Language
+		language.SetEnabled(m.Config != nil && m.Config.Language != "")
+		provider := keys.Provider
+		provider.SetEnabled(m.Config != nil && len(m.Config.ConfiguredProviders()) > 1)
+		tooLarge := m.context().TooLarge()
+		critique, split, stack := keys.Critique, keys.Split, keys.Stack
+		critique.SetEnabled(!tooLarge)
+		split.SetEnabled(!tooLarge && !m.Options.MessageOnly)
+		stack.SetEnabled(!m.Options.MessageOnly)
+		return [][]key.Binding{
+			{keys.Generate, keys.Manual, critique, split, stack},
+			{keys.Stage, keys.Diff, keys.Preview, language, keys.Profile, provider, keys.Reconfigure},
+			{keys.Help, keys.Quit},
+		}
+	case StateQuestioning:
+		if m.CurrentQIdx >= len(m.Questions) || m.TextArea.Value() != "" {
+			// Once an answer is being typed, so is ?.
+			return nil
+		}
+		previous := keys.PreviousQuestion
+		previous.SetEnabled(m.CurrentQIdx > 0)
+		return [][]key.Binding{{keys.Submit, previous, keys.Diff, keys.Skip}, {keys.Help, keys.Quit}}
+	case StateReview:
+		if m.ReviewFeedback {
+			return nil
+		}
+		if m.CoAuthorPicker != nil {
+			return [][]key.Binding{list, {keys.Help, keys.Quit}}
+		}
+		accept, edit := m.finishKeys()
+		specific, answers := keys.Specific, keys.ChangeAnswers
+		specific.SetEnabled(len(m.vague()) > 0)
+		answers.SetEnabled(len(m.Questions) > 0)
+		return [][]key.Binding{
+			{accept, keys.EditHere, edit, keys.Regenerate},
+			{keys.CoAuthors, specific, answers, keys.Abort},
+			scroll,
+			{keys.Help, keys.Quit},
+		}
+	case StateDiffPreview:
+		back := bind("back", append(keys.Back.Keys(), keys.Diff.Keys()...)...)
+		return [][]key.Binding{scroll, {back, keys.Help, keys.Quit}}
+	case StatePromptPreview:
+		return [][]key.Binding{scroll, {keys.Back, keys.Help, keys.Quit}}
+	case StatePrivacyReview:
+		return [][]key.Binding{list, {keys.Help, keys.Quit}}
+	case StateStaging:
+		return [][]key.Binding{list, {stagingExpand, stagingCollapse, keys.Help, keys.Quit}}
+	case StateLeftBehind:
+		return [][]key.Binding{list, {leftBehindAll, leftBehindSkip, keys.Help, keys.Quit}}
+	case StateHookFailed:
+		edit := hookEdit
+		edit.SetEnabled(m.CommitMsg != "")
+		return [][]key.Binding{scroll, {hookRetry, hookStage, edit}, {keys.Help, keys.Quit}}
+	case StateSplit:
+		if m.Split != nil && m.Split.Started {
+			return [][]key.Binding{list, {splitEdit, keys.Help, keys.Quit}}
+		}
+		return [][]key.Binding{{keys.Select, keys.Back, keys.Help, keys.Quit}}
+	}
+	return nil
+}
+
+// viewHelp lists the keys of the current state.
+func (m Model) viewHelp() string {
+	titleStyle := lipgloss.NewStyle().Bold(true)
+	infoStyle := lipgloss.NewStyle().Foreground(theme.Muted)
+
+	h := help.New()
+	h.Styles.FullKey = lipgloss.NewStyle().Foreground(theme.Accent)
+	h.Styles.FullDesc = lipgloss.NewStyle()
+	h.Styles.FullSeparator = infoStyle
+	var b strings.Builder
+	b.WriteString("\n " + titleStyle.Render("Keys") + "\n\n")
+	// One group under another, so they fit narrow terminals.
+	for _, group := range m.keyHelp() {
+		view := h.FullHelpView([][]key.Binding{group})
+		if view == "" {
+			continue
+		}
+		for _, line := range strings.Split(view, "\n") {
+			b.WriteString(" " + line + "\n")
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString(" " + infoStyle.Render("Press any key to close.") + "\n")
+	return b.String()
+}
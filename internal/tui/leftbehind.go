@@ -0,0 +1,238 @@
+package tui
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/arpxspace/smartcommit/internal/diff"
+	"github.com/arpxspace/smartcommit/internal/git"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// leftover is an unstaged or untracked file that likely belongs with the
+// staged changes.
+type leftover struct {
+	Path      string
+	Untracked bool
+	// Reason says why the file looks related.
+	Reason string
+}
+
+// leftBehindState is the screen warning about leftovers before analysis,
+// where they can be staged. Every leftover starts out selected.
+type leftBehindState struct {
+	Files    []leftover
+	Selected map[string]bool
+	Cursor   int
+	// Confirmed is set once the user has decided, so the warning isn't
+	// shown again for the same changes.
+	Confirmed bool
+	Err       error
+}
+
+type leftBehindStagedMsg struct {
+	Err error
+}
+
+func newLeftBehind(files []leftover) *leftBehindState {
+	if len(files) == 0 {
+		return nil
+	}
+	s := &leftBehindState{Files: files, Selected: make(map[string]bool, len(files))}
+	for _, f := range files {
+		s.Selected[f.Path] = true
+	}
+	return s
+}
+
+// findLeftovers reads the working tree for changes related to staged: the
+// unstaged rest of a staged file, files changed next to staged ones, and
+// untracked files the staged diff names. It's best effort, so git errors
+// just mean no warning.
+func findLeftovers(staged []diff.File) []leftover {
+	raw, err := git.GetUnstagedDiff()
+	if err != nil {
+		return nil
+	}
+	untracked, err := git.UntrackedFiles()
+	if err != nil {
+		return nil
+	}
+	return relatedLeftovers(staged, diff.Parse(raw), untracked)
+}
+
+// relatedLeftovers picks the unstaged and untracked files related to staged.
+func relatedLeftovers(staged, unstaged []diff.File, untracked []string) []leftover {
+	stagedPaths := make(map[string]bool, len(staged))
+	dirs := make(map[string]bool)
+	var added strings.Builder
+	for _, f := range staged {
+		stagedPaths[f.Path] = true
+		// Files at the top level are next to everything, so only
+		// directories below it count.
+		if dir := path.Dir(f.Path); dir != "." {
+			dirs[dir] = true
+		}
+		for _, line := range strings.Split(f.Content, "\n") {
+			if strings.HasPrefix(line, "+") && !strings.HasPrefix(line, "+++") {
+				added.WriteString(line[1:] + "\n")
+			}
+		}
+	}
+
+	var out []leftover
+	for _, f := range unstaged {
+		switch {
+		case stagedPaths[f.Path]:
+			out = append(out, leftover{Path: f.Path, Reason: "partly staged"})
+		case dirs[path.Dir(f.Path)]:
+			out = append(out, leftover{Path: f.Path, Reason: "modified next to staged files"})
+		}
+	}
+	for _, p := range untracked {
+		switch {
+		case dirs[path.Dir(p)]:
+			out = append(out, leftover{Path: p, Untracked: true, Reason: "new next to staged files"})
+		case mentions(added.String(), p):
+			out = append(out, leftover{Path: p, Untracked: true, Reason: "named in the staged changes"})
+		}
+	}
+	return out
+}
+
+// mentions reports whether text names the file at p, by its name without
+// the extension, as imports and includes often do. Short names match too
+// much to count.
+func mentions(text, p string) bool {
+	name := path.Base(p)
+	name = strings.TrimSuffix(name, path.Ext(name))
+	if len(name) < 4 {
+		return false
+	}
+	return regexp.MustCompile(`\b` + regexp.QuoteMeta(name) + `\b`).MatchString(text)
+}
+
+// begin starts the chosen mode, first warning about related changes left
+// out of the commit and then, if on, reviewing what will be sent.
+func (m Model) begin() (tea.Model, tea.Cmd) {
+	if m.Splitting && m.context().TooLarge() {
+		m.Splitting = false
+		m.SplitNote = "This change is too large to split; stage part of it at a time."
+		m.State = StateWelcome
+		return m, nil
+	}
+	if lb := m.LeftBehind; lb != nil && !lb.Confirmed {
+		lb.Cursor = 0
+		lb.Err = nil
+		m.State = StateLeftBehind
+		return m, nil
+	}
+	if m.privacyReview() {
+		m.PrivacyCursor = 0
+		m.State = StatePrivacyReview
+		return m, nil
+	}
+	return m.proceed()
+}
+
+// stageLeftoversCmd stages the selected leftovers.
+func (s *leftBehindState) stageLeftoversCmd() tea.Cmd {
+	var paths []string
+	for _, f := range s.Files {
+		if s.Selected[f.Path] {
+			paths = append(paths, f.Path)
+		}
+	}
+	return func() tea.Msg {
+		return leftBehindStagedMsg{Err: git.StagePaths(paths...)}
+	}
+}
+
+// Keys for staging the selected changes and for going on without any.
+var (
+	leftBehindAll  = bind("stage the selected", "a")
+	leftBehindSkip = bind("go on without them", "c")
+)
+
+func (m Model) updateLeftBehind(msg tea.Msg) (tea.Model, tea.Cmd) {
+	s := m.LeftBehind
+	if staged, ok := msg.(leftBehindStagedMsg); ok {
+		if staged.Err != nil {
+			s.Err = staged.Err
+			return m, nil
+		}
+		// Read the staged changes afresh, then carry on where the user
+		// was going.
+		m.Resume = true
+		m.State = StateLoading
+		return m, m.checkPrerequisitesCmd
+	}
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+
+	switch {
+	case key.Matches(keyMsg, keys.Up):
+		if s.Cursor > 0 {
+			s.Cursor--
+		}
+	case key.Matches(keyMsg, keys.Down):
+		if s.Cursor < len(s.Files)-1 {
+			s.Cursor++
+		}
+	case key.Matches(keyMsg, keys.Toggle):
+		path := s.Files[s.Cursor].Path
+		s.Selected[path] = !s.Selected[path]
+	case key.Matches(keyMsg, keys.Select, leftBehindAll):
+		if !slices.ContainsFunc(s.Files, func(f leftover) bool { return s.Selected[f.Path] }) {
+			s.Confirmed = true
+			return m.begin()
+		}
+		return m, s.stageLeftoversCmd()
+	case key.Matches(keyMsg, leftBehindSkip):
+		s.Confirmed = true
+		return m.begin()
+	case key.Matches(keyMsg, keys.Back):
+		m.State = StateWelcome
+	}
+	return m, nil
+}
+
+func (m Model) viewLeftBehind() string {
+	titleStyle := lipgloss.NewStyle().Bold(true)
+	infoStyle := lipgloss.NewStyle().Foreground(theme.Muted)
+	warnStyle := lipgloss.NewStyle().Foreground(theme.Warning)
+	errorStyle := lipgloss.NewStyle().Foreground(theme.Error)
+	cursorStyle := lipgloss.NewStyle().Foreground(theme.Accent).Bold(true)
+
+	s := m.LeftBehind
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n %s\n\n", warnStyle.Bold(true).Render("⚠ Related Changes Not Staged"))
+	b.WriteString(" These changes look like part of what you're committing, but aren't staged:\n\n")
+	for i, f := range s.Files {
+		cursor := "  "
+		if i == s.Cursor {
+			cursor = cursorStyle.Render("> ")
+		}
+		check := "[ ]"
+		if s.Selected[f.Path] {
+			check = "[x]"
+		}
+		fmt.Fprintf(&b, " %s%s %s %s\n", cursor, check, titleStyle.Render(f.Path), infoStyle.Render("("+f.Reason+")"))
+	}
+	b.WriteString("\n A commit that leaves half a change behind gets a message describing only half of it.\n\n")
+	b.WriteString(" a. Stage the selected files and continue (Recommended)\n")
+	b.WriteString(" c. Continue with only what's staged\n")
+	if s.Err != nil {
+		b.WriteString("\n " + errorStyle.Render("✗ "+s.Err.Error()) + "\n")
+	}
+	b.WriteString("\n " + infoStyle.Render("(space to select, enter to stage, esc to go back)") + "\n")
+	return b.String()
+}
@@ -0,0 +1,57 @@
+package tui
+
+import (
+	"strconv"
+
+	"github.com/arpxspace/smartcommit/internal/logging"
+)
+
+// stateNames name each SessionState in the log.
+var stateNames = map[SessionState]string{
+	StateLoading:         "loading",
+	StateHistoryAnalysis: "history_analysis",
+	StateQuestioning:     "questioning",
+	StateReview:          "review",
+	StateCommit:          "commit",
+	StateError:           "error",
+	StateSuccess:         "success",
+	StateSetup:           "setup",
+	StateNoRepo:          "no_repo",
+	StateWelcome:         "welcome",
+	StateDiffTooLarge:    "diff_too_large",
+	StatePromptPreview:   "prompt_preview",
+	StatePrivacyReview:   "privacy_review",
+	StateGenerating:      "generating",
+	StateSummarizing:     "summarizing",
+	StateCritique:        "critique",
+	StateSplitAnalyzing:  "split_analyzing",
+	StateSplit:           "split",
+	StateStaging:         "staging",
+	StateSecrets:         "secrets",
+	StateDiffPreview:     "diff_preview",
+	StateLeftBehind:      "left_behind",
+	StateResume:          "resume",
+	StateEditor:          "editor",
+	StateSigningFailed:   "signing_failed",
+	StateHookFailed:      "hook_failed",
+}
+
+func (s SessionState) String() string {
+	if name, ok := stateNames[s]; ok {
+		return name
+	}
+	return strconv.Itoa(int(s))
+}
+
+// logTransition logs moving from one screen to the next, so a log shows
+// where a run stopped.
+func logTransition(from, to Model) {
+	if from.State == to.State {
+		return
+	}
+	if to.State == StateError && to.Err != nil {
+		logging.Error("failed", "state", from.State, "err", to.Err)
+		return
+	}
+	logging.Debug("state", "from", from.State, "to", to.State)
+}
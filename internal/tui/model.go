@@ -1,27 +1,41 @@
 package tui
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"os/exec"
 	"strings"
+	"time"
 
 	"github.com/arpxspace/smartcommit/internal/ai"
 	"github.com/arpxspace/smartcommit/internal/config"
+	"github.com/arpxspace/smartcommit/internal/diff"
 	"github.com/arpxspace/smartcommit/internal/git"
+	"github.com/arpxspace/smartcommit/internal/logging"
+	"github.com/arpxspace/smartcommit/internal/provenance"
+	"github.com/arpxspace/smartcommit/internal/related"
+	"github.com/arpxspace/smartcommit/internal/staged"
+	"github.com/arpxspace/smartcommit/internal/store"
+	"github.com/arpxspace/smartcommit/internal/symbols"
+	"github.com/arpxspace/smartcommit/internal/usage"
 
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textarea"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"golang.org/x/sync/errgroup"
 )
 
 type SessionState int
 
 const (
 	StateLoading SessionState = iota
-	StateAnalysis
 	StateHistoryAnalysis
 	StateQuestioning
 	StateReview
@@ -32,6 +46,21 @@ const (
 	StateNoRepo
 	StateWelcome
 	StateDiffTooLarge
+	StatePromptPreview
+	StatePrivacyReview
+	StateGenerating
+	StateSummarizing
+	StateCritique
+	StateSplitAnalyzing
+	StateSplit
+	StateStaging
+	StateSecrets
+	StateDiffPreview
+	StateLeftBehind
+	StateResume
+	StateEditor
+	StateSigningFailed
+	StateHookFailed
 )
 
 type SetupStep int
@@ -40,63 +69,220 @@ const (
 	SetupStepProvider SetupStep = iota
 	SetupStepOpenAIKey
 	SetupStepConfirmOpenAIKey
+	SetupStepOpenAIModel
 	SetupStepOllamaURL
 	SetupStepOllamaModel
+	SetupStepOpenRouterKey
+	SetupStepOpenRouterModel
+	SetupStepCustomURL
+	SetupStepCustomKey
+	SetupStepCustomModel
+	SetupStepCustomStructured
+	SetupStepIntro
+	SetupStepPrivacy
+	SetupStepSample
+	SetupStepAlias
+	SetupStepPlaintextKeys
 )
 
+// Options holds settings passed in from the command line.
+type Options struct {
+	// TraceFile, when set, records redacted provider traffic to this path.
+	TraceFile string
+	// PrivacyReview forces the outgoing file review even if the config doesn't enable it.
+	PrivacyReview bool
+	// Issue is the number of the GitHub issue the change is for, fetched as context.
+	Issue int
+	// CoAuthors are credited with Co-authored-by trailers, as "Name <email>".
+	CoAuthors []string
+	// Stack stages all uncommitted work and splits it into a series of commits.
+	Stack bool
+	// Profile is the profile to start with, in place of the configured one.
+	Profile string
+	// MessageOnly ends the run with the accepted message in Model.Output
+	// instead of committing it.
+	MessageOnly bool
+}
+
 type Model struct {
+	Options          Options
 	State            SessionState
 	Spinner          spinner.Model
 	TextArea         textarea.Model
 	Viewport         viewport.Model
 	Err              error
+	ErrState         SessionState
 	Config           *config.Config
 	AIClient         ai.Provider
 	Diff             string
+	Files            []diff.File
+	Patchable        []diff.File
+	Symbols          map[string][]symbols.Change
+	Scopes           map[string][]string
+	Ticket           string
+	Issue            string
+	CoAuthors        []string
+	CoAuthorPicker   *coAuthorPicker
+	OpenAI           *openAIPicker
+	OpenRouter       *openRouterPicker
+	Ollama           *ollamaPicker
+	Signer           string
+	Language         string
+	Profile          string
+	Provider         config.ProviderType
+	Excluded         map[string]bool
+	PrivacyCursor    int
+	RedactSecrets    bool
+	SecretsConfirmed bool
+	DiffReturn       SessionState
+	RepoRoot         string
+	BareRepo         bool
+	Detached         bool
+	RepoConfig       *config.RepoConfig
 	History          string
 	HistoryCtx       []string
 	Questions        []string
 	Answers          map[string]string
 	CurrentQIdx      int
 	CommitMsg        string
+	Output           string
+	Fixes            []string
+	Shortening       bool
+	Rewriting        bool
+	RewriteErr       error
+	Judged           *ai.ScoreResponse
+	JudgedMsg        string
+	PromptHash       string
+	ProvenanceOK     bool
+	ProvenanceErr    error
+	Signing          *signingFailedMsg
+	HookFailure      *hookFailedMsg
+	Unsigned         bool
+	Signed           bool
+	Session          *store.Session
+	Pending          int
+	Requests         context.Context
+	CancelRequests   context.CancelFunc
+	Score            *ai.ScoreResponse
+	ScoreErr         error
+	StageStart       time.Time
 	SetupStep        SetupStep
 	SelectedProvider config.ProviderType
-	Width            int
-	Height           int
+	SetupProblem     error
+	ReviewFeedback   bool
+	Parts            []string
+	Summaries        []string
+	StreamText       string
+	Critiquing       bool
+	Splitting        bool
+	Split            *splitPlan
+	SplitNote        string
+	Staging          *stagingState
+	LeftBehind       *leftBehindState
+	Resume           bool
+	Previous         *store.Session
+	Editor           *messageEditor
+	CritiqueRunning  bool
+	Critique         *ai.CritiqueResponse
+	CritiquedMsg     string
+	CritiqueErr      error
+	BudgetWarning    string
+	// UpdateChecked is set once the check for a newer release has been
+	// started, and UpdateVersion to the release it found, if any.
+	UpdateChecked bool
+	UpdateVersion string
+	Onboarding    bool
+	SampleRunning bool
+	SampleMsg     string
+	SampleErr     error
+	AliasMsg      string
+	KeyPending    string
+	StepStart     time.Time
+	ShowHelp      bool
+	Width         int
+	Height        int
 }
 
-func NewModel() Model {
+// defaultTextAreaHeight is the answer box height (the bubbles default).
+const defaultTextAreaHeight = 6
+
+// answerPlaceholder is the text area's placeholder while answering questions.
+const answerPlaceholder = "Type your answer here..."
+
+func NewModel(opts Options) Model {
 	s := spinner.New()
 	s.Spinner = spinner.Dot
-	s.Style = lipgloss.NewStyle().Foreground(lipgloss.Color("205"))
+	s.Style = lipgloss.NewStyle().Foreground(theme.Accent)
 
 	ta := textarea.New()
-	ta.Placeholder = "Type your answer here..."
+	ta.Placeholder = answerPlaceholder
 	ta.Focus()
 
 	vp := viewport.New(80, 20)
+	vp.KeyMap = keys.viewport()
+	requests, cancel := context.WithCancel(context.Background())
 
 	return Model{
-		State:    StateLoading,
-		Spinner:  s,
-		TextArea: ta,
-		Viewport: vp,
-		Answers:  make(map[string]string),
-		Width:    80, // Default width
-		Height:   24, // Default height
+		Options:        opts,
+		Profile:        opts.Profile,
+		State:          StateLoading,
+		Spinner:        s,
+		TextArea:       ta,
+		Viewport:       vp,
+		Answers:        make(map[string]string),
+		CoAuthors:      opts.CoAuthors,
+		Splitting:      opts.Stack,
+		Resume:         opts.Stack,
+		Requests:       requests,
+		CancelRequests: cancel,
+		Width:          80, // Default width
+		Height:         24, // Default height
 	}
 }
 
 func (m Model) Init() tea.Cmd {
+	if m.Options.Stack {
+		return tea.Batch(m.Spinner.Tick, m.stackCmd)
+	}
 	return tea.Batch(
 		m.Spinner.Tick,
-		checkPrerequisitesCmd,
+		m.checkPrerequisitesCmd,
 	)
 }
 
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	next, cmd := m.update(msg)
+	if n, ok := next.(Model); ok {
+		logTransition(m, n)
+		if n.step() != m.step() {
+			// Time each step of the pipeline for the header.
+			n.StepStart = time.Now()
+			next = n
+		}
+	}
+	return next, cmd
+}
+
+func (m Model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 
+	if keyMsg, ok := msg.(tea.KeyMsg); ok {
+		if m.ShowHelp {
+			// Any key closes the help.
+			m.ShowHelp = false
+			return m, nil
+		}
+		var held bool
+		if m, keyMsg, held = m.chord(keyMsg); held {
+			return m, nil
+		}
+		msg = keyMsg
+		if key.Matches(keyMsg, keys.Help) && m.keyHelp() != nil {
+			m.ShowHelp = true
+			return m, nil
+		}
+	}
+
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		m.Width = msg.Width
@@ -105,12 +291,25 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.Viewport.Height = msg.Height
 		m.TextArea.SetWidth(msg.Width - 4) // Adjust textarea width too
 	case tea.KeyMsg:
+		if m.State == StateSuccess {
+			// The commit is made; any key leaves without waiting for
+			// post-commit work such as scoring.
+			return m, tea.Quit
+		}
 
-		switch msg.String() {
-		case "ctrl+c":
+		switch {
+		case key.Matches(msg, keys.Cancel):
+			if m.waiting() {
+				return m.cancelRequests()
+			}
+		case key.Matches(msg, keys.Quit):
+			if m.Split != nil {
+				m.Split.restage() // Ignore error, best effort on the way out
+			}
+			m.finishSession(store.OutcomeAborted)
 			return m, tea.Quit
-		case "q":
-			if m.State != StateQuestioning && m.State != StateReview && m.State != StateSetup && m.State != StateWelcome && m.State != StateDiffTooLarge {
+		case msg.String() == "q":
+			if m.State != StateQuestioning && m.State != StateReview && m.State != StateSetup && m.State != StateWelcome && m.State != StateDiffTooLarge && m.State != StatePromptPreview && m.State != StatePrivacyReview && m.State != StateCritique && m.State != StateSplit && m.State != StateStaging && m.State != StateSecrets && m.State != StateDiffPreview && m.State != StateLeftBehind && m.State != StateResume && m.State != StateEditor {
 				return m, tea.Quit
 			}
 		}
@@ -119,49 +318,238 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.Spinner, cmd = m.Spinner.Update(msg)
 		return m, cmd
 	case errMsg:
-		m.Err = msg
-		m.State = StateError
+		if errors.Is(msg, context.Canceled) {
+			// A request the user cancelled; they're already elsewhere.
+			return m, nil
+		}
+		m = m.failed(msg)
+		m.finishSession(store.OutcomeFailed)
 		return m, nil
+	case updateAvailableMsg:
+		m.UpdateVersion = msg.Version
 	case diffTooLargeMsg:
 		m.State = StateDiffTooLarge
 		return m, nil
+	case openAIModelsMsg:
+		return m.openAIModelsLoaded(msg)
+	case ollamaModelsMsg:
+		return m.ollamaModelsLoaded(msg)
+	case ollamaPulledMsg:
+		return m.ollamaPulled(msg)
+	case openRouterModelsMsg:
+		if m.OpenRouter != nil {
+			m.OpenRouter.Loading = false
+			m.OpenRouter.Models = msg.Models
+			m.OpenRouter.Err = msg.Err
+		}
+		return m, nil
 	case prerequisitesCheckedMsg:
 		m.Config = msg.Config
-		client, err := ai.NewClient(m.Config)
+		m.Config.TraceFile = m.Options.TraceFile
+		client, err := newClient(m.Config)
 		if err != nil {
 			return m, func() tea.Msg { return errMsg(err) }
 		}
 		m.AIClient = client
-		m.Diff = msg.Diff
+		m.Language = m.Config.Language
+		m.Profile = m.Config.Profile
+		m.Files = msg.Files
+		m.Patchable = msg.Patchable
+		m.Symbols = msg.Symbols
+		m.Scopes = msg.Scopes
+		m.Ticket = msg.Ticket
+		m.Issue = msg.Issue
+		m.Signer = msg.Signer
+		m.RepoRoot = msg.RepoRoot
+		m.RepoConfig = msg.RepoConfig
+		m.Detached = msg.Detached
+		m.Excluded = make(map[string]bool)
+		for _, path := range m.RepoConfig.Exclude {
+			m.Excluded[path] = true
+		}
+		m.RedactSecrets = false
+		m.SecretsConfirmed = false
+		m.Diff = m.outgoingDiff()
 		m.History = msg.History
+		m.BudgetWarning = msg.BudgetWarning
+		m.LeftBehind = newLeftBehind(msg.LeftBehind)
+		if m.Session == nil && !m.Resume {
+			// Looked up before this run's session is recorded for the same change.
+			m.Previous = m.findPrevious()
+		}
+		m.startSession()
+		if m.Resume {
+			// Staged more after the warning; go on with the chosen mode.
+			m.Resume = false
+			if m.LeftBehind != nil {
+				m.LeftBehind.Confirmed = true
+			}
+			return m.begin()
+		}
+		if m.Previous != nil {
+			m.State = StateResume
+			return m, nil
+		}
 		// Transition to Welcome screen instead of History Analysis
 		m.State = StateWelcome
-		return m, nil
+		return m.checkUpdate()
 	case historyAnalysisResultMsg:
+		// The questions were generated alongside the history analysis.
+		m.markStage("history")
+		m.History = msg.History
 		m.HistoryCtx = msg.KeyContext
-		m.State = StateAnalysis
-		return m, analyzeChangesCmd(m.AIClient, m.Diff, m.History)
-	case analysisResultMsg:
 		m.Questions = msg.Questions
+		if n := m.Config.Questions(); len(m.Questions) > n {
+			m.Questions = m.Questions[:n]
+		}
 		if len(m.Questions) == 0 {
-			return m, generateCommitMsgCmd(m.AIClient, m.Diff, m.History, m.HistoryCtx, m.Answers)
+			m.State = StateGenerating
+			return m, generateCommitMsgCmd(m.Requests, m.AIClient, m.Diff, m.History, m.HistoryCtx, m.Answers)
 		}
 		m.State = StateQuestioning
 		m.TextArea.Focus()
 		return m, nil
+	case nothingStagedMsg:
+		return m.startStaging()
+	case splitProposedMsg:
+		plan := newSplitPlan(msg.Response, m.context().Hunks(), msg.PromptHash)
+		if !msg.Response.ShouldSplit || len(plan.Groups) < 2 {
+			m.SplitNote = "No split needed: " + msg.Response.Reason
+			m.State = StateWelcome
+			return m, nil
+		}
+		m.Split = plan
+		m.State = StateSplit
+		return m, nil
+	case splitStartedMsg:
+		m.Split.Started = true
+		return m, nil
+	case splitStagedMsg:
+		return m, m.splitCommitCmd(msg.Message, msg.Edit)
+	case splitCommittedMsg:
+		if msg.ProvenanceErr != nil {
+			m.ProvenanceErr = msg.ProvenanceErr
+		}
+		m.ProvenanceOK = m.Config.Provenance && m.ProvenanceErr == nil
+		m.Signed = msg.Signed
+		m.Split.Current++
+		m.Split.Cursor = 0
+		if m.Split.Current < len(m.Split.Groups) {
+			return m, nil
+		}
+		m.finishSession(store.OutcomeCommitted)
+		m.State = StateSuccess
+		return m, tea.Quit
+	case critiqueResultMsg:
+		if errors.Is(msg.Err, context.Canceled) {
+			return m, nil
+		}
+		m.CritiqueRunning = false
+		m.CritiqueErr = msg.Err
+		if msg.Err == nil {
+			m.Critique = msg.Critique
+			m.CritiquedMsg = msg.Message
+		}
+		return m, nil
+	case partSummarizedMsg:
+		return m.updateSummarizing(msg)
+	case commitMsgChunkMsg:
+		if msg.Ctx.Err() != nil {
+			// Cancelled; the state has already moved on.
+			return m, nil
+		}
+		if !msg.Chunk.Done {
+			m.StreamText = msg.Chunk.Partial
+			return m, waitForChunk(msg.Ctx, msg.Stream, msg.PromptHash)
+		}
+		m.StreamText = ""
+		if msg.Chunk.Err != nil {
+			return m.Update(errMsg(msg.Chunk.Err))
+		}
+		return m.Update(commitMsgGeneratedMsg{Message: msg.Chunk.Message, PromptHash: msg.PromptHash})
 	case commitMsgGeneratedMsg:
+		m.markStage("message")
 		m.CommitMsg = msg.Message
-		m.State = StateCommit
-		return m, commitCmd(m.CommitMsg)
+		m.PromptHash = msg.PromptHash
+		m.addDraft(m.CommitMsg)
+		if m.Config.APIChangesInBody {
+			if section := m.apiChangesSection(); section != "" {
+				m.CommitMsg = strings.TrimRight(m.CommitMsg, "\n") + "\n\n" + strings.TrimRight(section, "\n")
+			}
+		}
+		rendered, err := m.context().Render(m.CommitMsg)
+		if err != nil {
+			return m.Update(errMsg(err))
+		}
+		m.CommitMsg = rendered
+		return m.guardMessage()
+	case subjectShortenedMsg:
+		return m.updateShortened(msg)
+	case sentenceRewrittenMsg:
+		return m.updateRewritten(msg)
+	case judgedMsg:
+		return m.updateJudged(msg)
+	case hookFailedMsg:
+		logging.Warn("commit rejected by hook", "hooks", msg.Hooks)
+		return m.hookFailed(msg)
+	case signingFailedMsg:
+		logging.Warn("commit signing failed", "format", msg.Format, "err", msg.Err)
+		m.Signing = &msg
+		m.State = StateSigningFailed
+		m.finishSession(store.OutcomeFailed)
+		return m, nil
 	case commitSuccessMsg:
+		logging.Info("committed", "generated", m.CommitMsg != "" && !m.Critiquing, "signed", msg.Signed)
+		m.Signed = msg.Signed
+		if m.CommitMsg == "" {
+			m.finishSession(store.OutcomeManual)
+		} else {
+			m.finishSession(store.OutcomeCommitted)
+		}
 		m.State = StateSuccess
-		return m, tea.Quit
+
+		// Post-commit work runs while the success screen is shown; we quit
+		// once every pending task has reported back.
+		var cmds []tea.Cmd
+		// A message written in critique mode is the user's own, so there is no AI provenance to record.
+		if m.Config != nil && m.Config.Provenance && m.CommitMsg != "" && !m.Critiquing {
+			cmds = append(cmds, recordProvenanceCmd(m.Config, m.AIClient, m.PromptHash))
+		}
+		if m.Config != nil && m.Config.ScoreCommits && m.AIClient != nil {
+			cmds = append(cmds, scoreCommitCmd(m.Requests, m.AIClient, m.Diff))
+		}
+		m.Pending = len(cmds)
+		if m.Pending == 0 {
+			return m, tea.Quit
+		}
+		return m, tea.Batch(cmds...)
+	case provenanceRecordedMsg:
+		m.ProvenanceOK = msg.Err == nil
+		m.ProvenanceErr = msg.Err
+		return m.finishPending()
+	case scoreResultMsg:
+		m.Score = msg.Score
+		m.ScoreErr = msg.Err
+		return m.finishPending()
 	case setupRequiredMsg:
 		m.Config = msg.Config
+		if msg.Invalid != nil {
+			return m.fixSetting(msg.Invalid)
+		}
 		m.State = StateSetup
+		if msg.FirstRun {
+			m.Onboarding = true
+			m.SetupStep = SetupStepIntro
+		}
+		return m, nil
+	case sampleGeneratedMsg:
+		m.SampleRunning = false
+		m.SampleMsg = msg.Message
+		m.SampleErr = msg.Err
 		return m, nil
 	case noRepoMsg:
 		m.State = StateNoRepo
+		m.BareRepo = msg.Bare
 		return m, nil
 	}
 
@@ -174,8 +562,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			case "m", "enter":
 				// Manual Mode
 				m.CommitMsg = ""
-				m.State = StateCommit
-				return m, commitCmd(m.CommitMsg)
+				return m.commitEditing(m.CommitMsg)
 			case "q", "ctrl+c":
 				return m, tea.Quit
 			}
@@ -183,27 +570,128 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case StateWelcome:
 		switch msg := msg.(type) {
 		case tea.KeyMsg:
-			switch msg.String() {
-			case "1", "enter":
+			switch {
+			case key.Matches(msg, keys.Generate):
 				// AI Mode
-				m.State = StateHistoryAnalysis
-				return m, analyzeHistoryCmd(m.AIClient, m.Diff, m.History)
-			case "2":
+				m.Critiquing = false
+				m.Splitting = false
+				return m.begin()
+			case key.Matches(msg, keys.Manual):
 				// Manual Mode
 				m.CommitMsg = "" // Empty message triggers manual editor
-				m.State = StateCommit
-				return m, commitCmd(m.CommitMsg)
-			case "c", "C":
+				return m.commitEditing(m.CommitMsg)
+			case key.Matches(msg, keys.Critique):
+				// Critique Mode: the user writes, the AI reviews
+				if m.context().TooLarge() {
+					return m, nil
+				}
+				m.Critiquing = true
+				m.Splitting = false
+				return m.begin()
+			case key.Matches(msg, keys.Split):
+				// Ask whether the change should be split into several commits
+				if m.context().TooLarge() || m.Options.MessageOnly {
+					return m, nil
+				}
+				m.Critiquing = false
+				m.Splitting = true
+				return m.begin()
+			case key.Matches(msg, keys.Stage):
+				// Change what's staged before going on
+				return m.startStaging()
+			case key.Matches(msg, keys.Stack):
+				// Commit all uncommitted work as a series of commits
+				if m.Options.MessageOnly {
+					return m, nil
+				}
+				return m.startStack()
+			case key.Matches(msg, keys.Profile):
+				// Switch to the next profile and start over with it
+				m.Profile = m.nextProfile()
+				m.State = StateLoading
+				return m, m.checkPrerequisitesCmd
+			case key.Matches(msg, keys.Provider):
+				// Switch to the next provider set up, for this run only
+				if len(m.Config.ConfiguredProviders()) < 2 {
+					return m, nil
+				}
+				m.Provider = m.nextProvider()
+				m.State = StateLoading
+				return m, m.checkPrerequisitesCmd
+			case key.Matches(msg, keys.Reconfigure):
 				// Reconfigure provider
-				m.State = StateSetup
-				m.SetupStep = SetupStepProvider
+				return m.reconfigure(SetupStepProvider)
+			case key.Matches(msg, keys.Language):
+				// Switch between the configured language and English
+				if m.Config.Language == "" {
+					return m, nil
+				}
+				cfg := *m.Config
+				if m.Language != "" {
+					cfg.Language = ""
+				}
+				client, err := newClient(&cfg)
+				if err != nil {
+					return m, func() tea.Msg { return errMsg(err) }
+				}
+				m.AIClient = client
+				m.Language = cfg.Language
+				return m, nil
+			case key.Matches(msg, keys.Diff):
+				// Look over the staged diff before going on
+				return m.openDiffPreview()
+			case key.Matches(msg, keys.Preview):
+				// Preview exactly what will be sent to the provider
+				m.Viewport.SetContent(renderPromptPreview(m.AIClient.PreviewPrompts(m.Diff, m.History, m.Answers), m.Width))
+				m.Viewport.Height = m.Height - 4
+				m.Viewport.GotoTop()
+				m.State = StatePromptPreview
 				return m, nil
 			}
 		}
+	case StateError:
+		return m.updateError(msg)
+	case StateSigningFailed:
+		return m.updateSigningFailed(msg)
+	case StateHookFailed:
+		return m.updateHookFailed(msg)
+	case StatePrivacyReview:
+		return m.updatePrivacyReview(msg)
+	case StateCritique:
+		return m.updateCritique(msg)
+	case StateSplit:
+		return m.updateSplit(msg)
+	case StateStaging:
+		return m.updateStaging(msg)
+	case StateLeftBehind:
+		return m.updateLeftBehind(msg)
+	case StateResume:
+		return m.updateResume(msg)
+	case StateEditor:
+		return m.updateEditor(msg)
+	case StatePromptPreview:
+		switch msg := msg.(type) {
+		case tea.KeyMsg:
+			switch {
+			case key.Matches(msg, keys.Back):
+				m.State = StateWelcome
+				return m, nil
+			}
+		}
+		m.Viewport, cmd = m.Viewport.Update(msg)
+		return m, cmd
 	case StateSetup:
 		switch msg := msg.(type) {
 		case tea.KeyMsg:
 			switch m.SetupStep {
+			case SetupStepIntro, SetupStepPrivacy, SetupStepSample, SetupStepAlias:
+				return m.updateOnboarding(msg)
+			case SetupStepPlaintextKeys:
+				return m.updatePlaintextKeys(msg)
+			case SetupStepOpenRouterKey, SetupStepOpenRouterModel:
+				return m.updateOpenRouterSetup(msg)
+			case SetupStepCustomURL, SetupStepCustomKey, SetupStepCustomModel, SetupStepCustomStructured:
+				return m.updateCustomSetup(msg)
 			case SetupStepProvider:
 				// Provider selection
 				switch msg.String() {
@@ -223,18 +711,23 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.TextArea.Reset()
 					m.TextArea.SetValue("http://localhost:11434") // Default
 					return m, nil
+				case "3":
+					m.SelectedProvider = config.ProviderOpenRouter
+					m.SetupStep = SetupStepOpenRouterKey
+					m.TextArea.Reset()
+					return m, nil
+				case "4":
+					m.SelectedProvider = config.ProviderCustom
+					m.SetupStep = SetupStepCustomURL
+					m.TextArea.Reset()
+					m.TextArea.SetValue(m.Config.CustomURL)
+					return m, nil
 				}
 			case SetupStepConfirmOpenAIKey:
 				switch strings.ToLower(msg.String()) {
 				case "y", "enter":
-					m.Config.Provider = config.ProviderOpenAI
 					m.Config.OpenAIAPIKey = os.Getenv("OPENAI_API_KEY")
-					if err := m.Config.Save(); err != nil {
-						m.Err = err
-						m.State = StateError
-						return m, nil
-					}
-					return m, checkPrerequisitesCmd
+					return m.openOpenAIPicker()
 				case "n":
 					m.SetupStep = SetupStepOpenAIKey
 					m.TextArea.Reset()
@@ -244,43 +737,25 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				if msg.Type == tea.KeyEnter {
 					input := strings.TrimSpace(m.TextArea.Value())
 					if input != "" {
-						m.Config.Provider = config.ProviderOpenAI
 						m.Config.OpenAIAPIKey = input
-						if err := m.Config.Save(); err != nil {
-							m.Err = err
-							m.State = StateError
-							return m, nil
-						}
-						m.TextArea.Reset()
-						return m, checkPrerequisitesCmd
+						return m.openOpenAIPicker()
 					}
 				}
 			case SetupStepOllamaURL:
 				if msg.Type == tea.KeyEnter {
 					input := strings.TrimSpace(m.TextArea.Value())
-					if input != "" {
-						m.Config.OllamaURL = input
-						m.SetupStep = SetupStepOllamaModel
-						m.TextArea.Reset()
-						m.TextArea.SetValue("llama3.1") // Default
+					if err := config.CheckURL(input); err != nil {
+						m.SetupProblem = &config.Invalid{Key: "ollama_url", Err: err}
 						return m, nil
 					}
+					m.SetupProblem = nil
+					m.Config.OllamaURL = input
+					return m.openOllamaPicker()
 				}
+			case SetupStepOpenAIModel:
+				return m.updateOpenAIPicker(msg)
 			case SetupStepOllamaModel:
-				if msg.Type == tea.KeyEnter {
-					input := strings.TrimSpace(m.TextArea.Value())
-					if input != "" {
-						m.Config.Provider = config.ProviderOllama
-						m.Config.OllamaModel = input
-						if err := m.Config.Save(); err != nil {
-							m.Err = err
-							m.State = StateError
-							return m, nil
-						}
-						m.TextArea.Reset()
-						return m, checkPrerequisitesCmd
-					}
-				}
+				return m.updateOllamaPicker(msg)
 			}
 		}
 		m.TextArea, cmd = m.TextArea.Update(msg)
@@ -288,26 +763,51 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case StateQuestioning:
 		switch msg := msg.(type) {
 		case tea.KeyMsg:
-			if msg.Type == tea.KeyEnter {
-				// Submit the current answer
+			empty := m.TextArea.Value() == ""
+			if empty && key.Matches(msg, keys.Diff) {
+				// Before typing an answer, d shows the diff in question.
+				return m.openDiffPreview()
+			}
+			if key.Matches(msg, keys.PreviousQuestion) {
+				return m.previousQuestion()
+			}
+			if empty && key.Matches(msg, keys.Skip) {
+				// Before typing an answer, s skips the remaining questions
+				// and writes the message from the answers so far.
+				m.CurrentQIdx = len(m.Questions)
+				m.TextArea.Reset()
+				m.markStage("answers")
+				m.State = StateGenerating
+				return m, generateCommitMsgCmd(m.Requests, m.AIClient, m.Diff, m.History, m.HistoryCtx, m.Answers)
+			}
+			// Enter starts a new line of the answer; ctrl+d submits it, since
+			// terminals don't report ctrl+enter distinctly.
+			if key.Matches(msg, keys.Submit) {
 				answer := strings.TrimSpace(m.TextArea.Value())
 				if answer != "" {
 					m.Answers[m.Questions[m.CurrentQIdx]] = answer
 					m.CurrentQIdx++
+					// Kept as they're given, so an interrupted session can be resumed.
+					m.saveSession()
 					m.TextArea.Reset()
 					m.TextArea.Focus()
 
 					// Check if we've answered all questions
 					if m.CurrentQIdx >= len(m.Questions) {
-						m.State = StateLoading
-						return m, generateCommitMsgCmd(m.AIClient, m.Diff, m.History, m.HistoryCtx, m.Answers)
+						m.markStage("answers")
+						m.State = StateGenerating
+						return m, generateCommitMsgCmd(m.Requests, m.AIClient, m.Diff, m.History, m.HistoryCtx, m.Answers)
 					}
+					// Coming back from review, the earlier answer is kept for editing.
+					m.TextArea.SetValue(m.Answers[m.Questions[m.CurrentQIdx]])
 					return m, nil
 				}
 			}
 		}
 		m.TextArea, cmd = m.TextArea.Update(msg)
 		return m, cmd
+	case StateReview:
+		return m.updateReview(msg)
 	case StateNoRepo:
 		switch msg := msg.(type) {
 		case tea.KeyMsg:
@@ -321,28 +821,27 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (m Model) View() string {
+	view := m.viewState()
+	if m.State == StateSetup && m.SetupProblem != nil {
+		view = m.viewSetupProblem() + view
+	}
+	if m.Err == nil && !m.ShowHelp {
+		view = m.viewPipeline() + view
+	}
+	return view
+}
+
+// viewState renders the current state.
+func (m Model) viewState() string {
 	titleStyle := lipgloss.NewStyle().Bold(true)
-	infoStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("241"))
-	errorStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Bold(true)
+	infoStyle := lipgloss.NewStyle().Foreground(theme.Muted)
+	errorStyle := lipgloss.NewStyle().Foreground(theme.Error).Bold(true)
 
 	if m.Err != nil {
-		errStr := m.Err.Error()
-		// Check for Ollama model not found error
-		if m.Config != nil && m.Config.Provider == config.ProviderOllama &&
-			strings.Contains(errStr, "404") && strings.Contains(errStr, "model") && strings.Contains(errStr, "not found") {
-
-			modelName := m.Config.OllamaModel
-			cmd := fmt.Sprintf("ollama pull %s", modelName)
-			cmdStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("12")).Bold(true)
-
-			return fmt.Sprintf(
-				"\n %s Model '%s' not found.\n\n You don't have this model installed through Ollama.\n To install it, run the following command:\n\n   %s\n\n Press ctrl+c to quit.\n",
-				errorStyle.Render("Error:"),
-				modelName,
-				cmdStyle.Render(cmd),
-			)
-		}
-		return fmt.Sprintf("%s %v\nPress ctrl+c to quit.", errorStyle.Render("Error:"), m.Err)
+		return m.viewError()
+	}
+	if m.ShowHelp {
+		return m.viewHelp()
 	}
 
 	switch m.State {
@@ -352,8 +851,8 @@ func (m Model) View() string {
 		return fmt.Sprintf(`
  %s
 
- The staged changes are too large for AI analysis.
- (> 40k characters)
+ The staged changes are too large for AI analysis,
+ even when summarized in parts.
 
  You can:
  1. Press 'm' or Enter to write the commit message manually.
@@ -363,25 +862,125 @@ func (m Model) View() string {
 	case StateWelcome:
 		providerInfo := ""
 		if m.Config != nil {
-			if m.Config.Provider == config.ProviderOpenAI {
-				providerInfo = infoStyle.Render(" (using OpenAI)")
-			} else if m.Config.Provider == config.ProviderOllama {
-				providerInfo = infoStyle.Render(fmt.Sprintf(" (using Ollama: %s)", m.Config.OllamaModel))
+			if provider := m.providerLabel(); provider != "" {
+				providerInfo = infoStyle.Render(fmt.Sprintf(" (using %s, %s profile)", provider, m.Profile))
+			}
+			if m.Language != "" {
+				providerInfo += infoStyle.Render(fmt.Sprintf(" in %s", m.Language))
+			}
+		}
+		riskInfo := ""
+		if risky := m.sensitiveFiles(); len(risky) > 0 {
+			warnStyle := lipgloss.NewStyle().Foreground(theme.Warning)
+			riskInfo = "\n " + warnStyle.Render(fmt.Sprintf("⚠ This change touches sensitive areas (%s)", strings.Join(risky, ", "))) + "\n"
+		}
+		if secrets := m.context().Secrets(); len(secrets) > 0 && !m.privacyReview() {
+			warnStyle := lipgloss.NewStyle().Foreground(theme.Warning)
+			riskInfo += "\n " + warnStyle.Render(fmt.Sprintf("⚠ %d possible secret(s) staged; you'll be asked before anything is sent", len(secrets))) + "\n"
+		}
+		if lb := m.LeftBehind; lb != nil && !lb.Confirmed {
+			warnStyle := lipgloss.NewStyle().Foreground(theme.Warning)
+			riskInfo += "\n " + warnStyle.Render(fmt.Sprintf("⚠ %d related change(s) aren't staged; you'll be asked whether to stage them", len(lb.Files))) + "\n"
+		}
+		if sc := m.context(); sc.TooLarge() {
+			riskInfo += "\n " + infoStyle.Render(fmt.Sprintf("This change is large; it will be summarized in %d parts before questions are asked.", len(sc.Parts()))) + "\n"
+		}
+		if m.Detached {
+			warnStyle := lipgloss.NewStyle().Foreground(theme.Warning)
+			riskInfo += "\n " + warnStyle.Render("⚠ HEAD is detached; the commit won't be on any branch") + "\n"
+		}
+		if m.BudgetWarning != "" {
+			warnStyle := lipgloss.NewStyle().Foreground(theme.Warning)
+			riskInfo += "\n " + warnStyle.Render("⚠ "+m.BudgetWarning) + "\n"
+		}
+		if m.SplitNote != "" {
+			riskInfo += "\n " + infoStyle.Render(m.SplitNote) + "\n"
+		}
+		if m.UpdateVersion != "" {
+			riskInfo += "\n " + infoStyle.Render(fmt.Sprintf("smartcommit %s is available; run `smartcommit update` to install it", m.UpdateVersion)) + "\n"
+		}
+		critiqueOption := " 3. I'll write it, review it for me\n"
+		splitHint := "\n " + infoStyle.Render(fmt.Sprintf("Press '%s' to check whether this should be split into several commits, '%s' to do so with all uncommitted work", keyName(keys.Split), keyName(keys.Stack)))
+		stageHint := "\n " + infoStyle.Render(fmt.Sprintf("Press '%s' to change what's staged, '%s' to view it, '%s' for all keys", keyName(keys.Stage), keyName(keys.Diff), keyName(keys.Help)))
+		if m.context().TooLarge() {
+			critiqueOption = ""
+			splitHint = ""
+		}
+		if m.Options.MessageOnly {
+			// Splitting makes commits of its own.
+			splitHint = ""
+		}
+		if m.Config != nil && m.Config.Language != "" {
+			other := m.Config.Language
+			if m.Language != "" {
+				other = "English"
+			}
+			stageHint += "\n " + infoStyle.Render(fmt.Sprintf("Press '%s' to write in %s", keyName(keys.Language), other))
+		}
+		if m.Config != nil {
+			stageHint += "\n " + infoStyle.Render(fmt.Sprintf("Press '%s' to switch to the %s profile", keyName(keys.Profile), m.nextProfile()))
+			if len(m.Config.ConfiguredProviders()) > 1 {
+				stageHint += "\n " + infoStyle.Render(fmt.Sprintf("Press '%s' to switch to %s", keyName(keys.Provider), m.nextProvider()))
 			}
 		}
 		return fmt.Sprintf(`
  %s%s
-
+%s
  How would you like to proceed?
 
  1. I need help writing a commit message (Recommended)
  2. I already know what to write
-
+%s
  %s
- (Press 1 or 2)
-`, titleStyle.Render("SmartCommit"), providerInfo, infoStyle.Render("Press 'c' to reconfigure provider"))
+ %s%s%s
+ (Press a number to choose)
+`, titleStyle.Render("SmartCommit"), providerInfo, riskInfo, critiqueOption, infoStyle.Render(fmt.Sprintf("Press '%s' to reconfigure provider", keyName(keys.Reconfigure))), infoStyle.Render(fmt.Sprintf("Press '%s' to preview what will be sent", keyName(keys.Preview))), splitHint, stageHint)
+	case StatePrivacyReview:
+		return m.viewPrivacyReview()
+	case StateCritique:
+		return m.viewCritique()
+	case StateSplitAnalyzing:
+		return fmt.Sprintf("\n %s Looking for unrelated changes...%s\n\n %s\n", m.Spinner.View(), m.retrying(), infoStyle.Render(cancelHint))
+	case StateSplit:
+		return m.viewSplit()
+	case StateStaging:
+		return m.viewStaging()
+	case StateSecrets:
+		return m.viewSecrets()
+	case StateLeftBehind:
+		return m.viewLeftBehind()
+	case StateResume:
+		return m.viewResume()
+	case StateSigningFailed:
+		return m.viewSigningFailed()
+	case StateHookFailed:
+		return m.viewHookFailed()
+	case StateEditor:
+		return m.viewEditor()
+	case StateDiffPreview:
+		return fmt.Sprintf(
+			"\n %s\n%s\n %s\n",
+			titleStyle.Render("Staged Changes"),
+			m.Viewport.View(),
+			infoStyle.Render("(↑/↓ to scroll, d or esc to go back)"),
+		)
+	case StatePromptPreview:
+		return fmt.Sprintf(
+			"\n %s\n%s\n %s\n",
+			titleStyle.Render("Prompt Preview"),
+			m.Viewport.View(),
+			infoStyle.Render("(↑/↓ to scroll, esc to go back)"),
+		)
 	case StateSetup:
 		switch m.SetupStep {
+		case SetupStepIntro, SetupStepPrivacy, SetupStepSample, SetupStepAlias:
+			return m.viewOnboarding()
+		case SetupStepPlaintextKeys:
+			return m.viewPlaintextKeys()
+		case SetupStepOpenRouterKey, SetupStepOpenRouterModel:
+			return m.viewOpenRouterSetup()
+		case SetupStepCustomURL, SetupStepCustomKey, SetupStepCustomModel, SetupStepCustomStructured:
+			return m.viewCustomSetup()
 		case SetupStepProvider:
 			return fmt.Sprintf(`
 
@@ -393,10 +992,18 @@ func (m Model) View() string {
  2. Ollama (llama3.1)
     %s
 
- (Press 1 or 2)
+ 3. OpenRouter (Claude, Llama, Mistral, DeepSeek, and more)
+    %s
+
+ 4. Custom OpenAI-compatible endpoint (llama.cpp, LM Studio, vLLM)
+    %s
+
+ (Press 1, 2, 3, or 4)
 `,
 				infoStyle.Faint(true).Render("Not private, costs money, great accuracy/performance"),
 				infoStyle.Faint(true).Render("Private, free, low accuracy/performance"),
+				infoStyle.Faint(true).Render("Not private, one key for many models, prices vary by model"),
+				infoStyle.Faint(true).Render("Any server that speaks the OpenAI API, local or hosted"),
 			)
 		case SetupStepConfirmOpenAIKey:
 			return fmt.Sprintf(
@@ -418,21 +1025,30 @@ func (m Model) View() string {
 				m.TextArea.View(),
 				infoStyle.Render("(Press Enter to continue)"),
 			)
+		case SetupStepOpenAIModel:
+			return m.viewOpenAIPicker()
 		case SetupStepOllamaModel:
-			return fmt.Sprintf(
-				"\n %s\n\n%s\n\n%s\n",
-				titleStyle.Render("Please enter the Ollama model name:"),
-				m.TextArea.View(),
-				infoStyle.Render("(Press Enter to save)"),
-			)
+			return m.viewOllamaPicker()
 		}
 		return "\n Setup...\n\n"
 	case StateNoRepo:
+		if m.BareRepo {
+			return fmt.Sprintf("\n %s This is a bare repository, with no working tree to commit from.\n\n Run smartcommit in a clone or in a worktree added with `git worktree add`.\n Press q to quit.\n\n", errorStyle.Render("Error:"))
+		}
 		return fmt.Sprintf("\n %s Not a git repository.\n\n Please run smartcommit inside a git repository.\n Press q to quit.\n\n", errorStyle.Render("Error:"))
 	case StateHistoryAnalysis:
-		return fmt.Sprintf("\n %s Analyzing history context...\n\n", m.Spinner.View())
-	case StateAnalysis:
-		return fmt.Sprintf("\n %s Analyzing changes and generating questions...\n\n", m.Spinner.View())
+		var task string
+		switch analyze, questions := m.Config.AnalyzeHistory(), m.Config.Questions() > 0; {
+		case analyze && questions:
+			task = "Analyzing history and generating questions..."
+		case analyze:
+			task = "Analyzing history context..."
+		case questions:
+			task = "Generating questions..."
+		default:
+			task = "Gathering history..."
+		}
+		return fmt.Sprintf("\n %s %s%s\n\n %s\n", m.Spinner.View(), task, m.retrying(), infoStyle.Render(cancelHint))
 	case StateQuestioning:
 		if m.CurrentQIdx < len(m.Questions) {
 			// Use dynamic width, defaulting to 70 if width is small or not set
@@ -441,21 +1057,60 @@ func (m Model) View() string {
 				wrapWidth = 40
 			}
 			questionStyle := lipgloss.NewStyle().Width(wrapWidth)
+			help := fmt.Sprintf("(%s to submit, enter for a new line", keyName(keys.Submit))
+			if m.CurrentQIdx > 0 {
+				help += fmt.Sprintf(", %s to go back", keyName(keys.PreviousQuestion))
+			}
+			help += fmt.Sprintf("; before typing, %s to view the diff, %s to skip the rest, or %s for all keys)",
+				keyName(keys.Diff), keyName(keys.Skip), keyName(keys.Help))
 			return fmt.Sprintf(
-				"\n%s %s\n\n%s\n\n%s\n",
+				"\n%s%s %s\n\n%s\n\n%s\n",
+				m.viewAnswered(),
 				titleStyle.Render(fmt.Sprintf("Question %d/%d:", m.CurrentQIdx+1, len(m.Questions))),
 				questionStyle.Render(m.Questions[m.CurrentQIdx]),
 				m.TextArea.View(),
-				infoStyle.Render("(Press Enter to submit)"),
+				infoStyle.Render(help),
 			)
 		}
+	case StateSummarizing:
+		return fmt.Sprintf("\n %s Summarizing large change (part %d of %d)...%s\n\n %s\n", m.Spinner.View(), len(m.Summaries)+1, len(m.Parts), m.retrying(), infoStyle.Render(cancelHint))
+	case StateGenerating:
+		if m.Shortening {
+			return fmt.Sprintf("\n %s Shortening the subject...%s\n\n%s\n\n %s\n", m.Spinner.View(), m.retrying(), renderMessage(m.CommitMsg, m.Width), infoStyle.Render(cancelHint))
+		}
+		if m.StreamText != "" {
+			return fmt.Sprintf("\n %s Writing commit message...%s\n\n%s\n\n %s\n", m.Spinner.View(), m.retrying(), renderMessage(m.StreamText, m.Width), infoStyle.Render(cancelHint))
+		}
+		return fmt.Sprintf("\n %s Writing commit message...%s\n\n %s\n", m.Spinner.View(), m.retrying(), infoStyle.Render(cancelHint))
 	case StateReview:
-		// Deprecated state, should not be reached
-		return ""
+		return m.viewReview()
 	case StateCommit:
 		return "\n Opening editor...\n\n"
 	case StateSuccess:
 		successMsg := "Successfully committed!\n\n"
+		if m.Signed {
+			successMsg += infoStyle.Render("The commit is signed.") + "\n\n"
+		} else {
+			successMsg += infoStyle.Render("The commit isn't signed.") + "\n\n"
+		}
+		if m.ProvenanceOK {
+			successMsg += infoStyle.Render("Provenance recorded in refs/notes/"+provenance.NotesRef) + "\n\n"
+		} else if m.ProvenanceErr != nil {
+			successMsg += errorStyle.Render("Could not record provenance: ") + m.ProvenanceErr.Error() + "\n\n"
+		}
+		if m.Score != nil {
+			successMsg += fmt.Sprintf("%s why %d/10 · clarity %d/10 · convention %d/10 · cohesion %d/10 — %s\n\n",
+				titleStyle.Render("Message score:"), m.Score.WhyCoverage, m.Score.Clarity, m.Score.Convention, m.Score.Cohesion, m.Score.Verdict)
+		} else if m.ScoreErr != nil {
+			successMsg += infoStyle.Render("Could not score message: "+m.ScoreErr.Error()) + "\n\n"
+		} else if m.Pending > 0 {
+			successMsg += fmt.Sprintf("%s Finishing up... %s\n\n", m.Spinner.View(), infoStyle.Render("(press any key to exit)"))
+		}
+		if m.AIClient != nil {
+			if run := usage.Run(m.Config, m.AIClient); run != "" {
+				successMsg += infoStyle.Render("This run: "+run) + "\n\n"
+			}
+		}
 		cta := infoStyle.Render("If you're enjoying smartcommit, give us a star on GitHub: https://github.com/arpxspace/smartcommit")
 		return successMsg + cta + "\n\n"
 	}
@@ -463,6 +1118,75 @@ func (m Model) View() string {
 	return "\n Unknown state\n\n"
 }
 
+// cancelHint follows the spinner while a request is in flight.
+const cancelHint = "(esc to cancel)"
+
+// retrying describes the retry the client is waiting for, to follow a
+// spinner line, or returns "" if there is none.
+func (m Model) retrying() string {
+	if m.AIClient == nil {
+		return ""
+	}
+	status, ok := m.AIClient.Retrying()
+	if !ok {
+		return ""
+	}
+	return " " + lipgloss.NewStyle().Foreground(theme.Warning).Render("("+status.String()+")")
+}
+
+// finishPending marks one post-commit task as done and quits after the last.
+func (m Model) finishPending() (tea.Model, tea.Cmd) {
+	m.Pending--
+	if m.Pending > 0 {
+		return m, nil
+	}
+	return m, tea.Quit
+}
+
+// startAnalysis kicks off the AI pipeline with the current outgoing diff,
+// summarizing it in parts first if it's too large for one request.
+func (m Model) startAnalysis() (tea.Model, tea.Cmd) {
+	m.StageStart = time.Now()
+	if m.Previous != nil {
+		return m.restorePrevious()
+	}
+	if m.context().TooLarge() {
+		return m.startSummarizing()
+	}
+	m.State = StateHistoryAnalysis
+	return m, analyzeHistoryCmd(m.Requests, m.AIClient, m.context(), m.Diff, m.History, m.Config.AnalyzeHistory(), m.Config.Questions())
+}
+
+// renderPromptPreview lays out every stage's prompt with its size so the user
+// can audit what leaves the machine.
+func renderPromptPreview(prompts []ai.Prompt, width int) string {
+	headerStyle := lipgloss.NewStyle().Bold(true).Foreground(theme.Accent)
+	labelStyle := lipgloss.NewStyle().Bold(true)
+	infoStyle := lipgloss.NewStyle().Foreground(theme.Muted)
+	textStyle := lipgloss.NewStyle().Width(max(width-2, 40))
+
+	var b strings.Builder
+	totalBytes, totalTokens := 0, 0
+	for _, p := range prompts {
+		totalBytes += p.Bytes()
+		totalTokens += p.EstimateTokens()
+	}
+	b.WriteString(infoStyle.Render(fmt.Sprintf("%d requests, %d bytes, ~%d tokens in total", len(prompts), totalBytes, totalTokens)))
+	b.WriteString("\n")
+
+	for _, p := range prompts {
+		fmt.Fprintf(&b, "\n%s %s\n\n",
+			headerStyle.Render(strings.ToUpper(string(p.Stage))),
+			infoStyle.Render(fmt.Sprintf("(%d bytes, ~%d tokens)", p.Bytes(), p.EstimateTokens())),
+		)
+		b.WriteString(labelStyle.Render("System:") + "\n")
+		b.WriteString(textStyle.Render(strings.TrimSpace(p.System)) + "\n\n")
+		b.WriteString(labelStyle.Render("User:") + "\n")
+		b.WriteString(textStyle.Render(strings.TrimSpace(p.User)) + "\n")
+	}
+	return b.String()
+}
+
 // Messages and Commands
 
 type errMsg error
@@ -470,36 +1194,82 @@ type errMsg error
 type diffTooLargeMsg struct{}
 
 type prerequisitesCheckedMsg struct {
-	Config  *config.Config
-	Diff    string
-	History string
+	Config     *config.Config
+	Files      []diff.File
+	Patchable  []diff.File
+	Symbols    map[string][]symbols.Change
+	Scopes     map[string][]string
+	Ticket     string
+	Issue      string
+	Signer     string
+	RepoRoot   string
+	RepoConfig *config.RepoConfig
+	History    string
+	// Detached is set when HEAD isn't on a branch.
+	Detached bool
+	// LeftBehind are unstaged changes that look related to the staged ones.
+	LeftBehind []leftover
+	// BudgetWarning is set when this month's estimated spend is near or over budget.
+	BudgetWarning string
 }
 
 type setupRequiredMsg struct {
-	Config *config.Config
+	Config   *config.Config
+	FirstRun bool
+	// Invalid is the setting setup is needed to fix, if that's why.
+	Invalid *config.Invalid
 }
 
-type noRepoMsg struct{}
+type noRepoMsg struct {
+	// Bare is set in a bare repository, which has no working tree.
+	Bare bool
+}
 
 type historyAnalysisResultMsg struct {
 	KeyContext []string
-}
-
-type analysisResultMsg struct {
+	// History is the history sent, which style examples may have replaced.
+	History   string
 	Questions []string
 }
 
 type commitMsgGeneratedMsg struct {
-	Message string
+	Message    string
+	PromptHash string
+}
+
+type commitMsgChunkMsg struct {
+	// Ctx is the context the message is generated under, to tell chunks
+	// of a cancelled stream apart.
+	Ctx        context.Context
+	Chunk      ai.StreamChunk
+	Stream     <-chan ai.StreamChunk
+	PromptHash string
+}
+
+type commitSuccessMsg struct {
+	// Signed is whether the new commit carries a signature.
+	Signed bool
 }
 
-type commitSuccessMsg struct{}
+type provenanceRecordedMsg struct {
+	Err error
+}
+
+type scoreResultMsg struct {
+	Score *ai.ScoreResponse
+	Err   error
+}
 
-func checkPrerequisitesCmd() tea.Msg {
+func (m Model) checkPrerequisitesCmd() tea.Msg {
+	firstRun := !config.Exists()
 	cfg, err := config.Load()
 	if err != nil {
 		return errMsg(err)
 	}
+	if _, ok := cfg.FromEnv("provider"); firstRun && !ok {
+		// A provider in the environment is enough to run without setup.
+		return setupRequiredMsg{Config: cfg, FirstRun: true}
+	}
 
 	// Check if setup is needed - validate provider-specific requirements
 	needsSetup := false
@@ -519,26 +1289,63 @@ func checkPrerequisitesCmd() tea.Msg {
 		}
 	} else if cfg.Provider == config.ProviderOllama && (cfg.OllamaURL == "" || cfg.OllamaModel == "") {
 		needsSetup = true
+	} else if cfg.Provider == config.ProviderAzure && cfg.AzureAPIKey == "" {
+		// Azure is configured by hand; its key may live in the environment.
+		cfg.AzureAPIKey = os.Getenv("AZURE_OPENAI_API_KEY")
+	} else if cfg.Provider == config.ProviderOpenRouter && cfg.OpenRouterAPIKey == "" {
+		cfg.OpenRouterAPIKey = os.Getenv("OPENROUTER_API_KEY")
+		needsSetup = cfg.OpenRouterAPIKey == ""
+	} else if cfg.Provider == config.ProviderCustom && (cfg.CustomURL == "" || cfg.CustomModel == "") {
+		needsSetup = true
 	}
 
 	if needsSetup {
 		return setupRequiredMsg{Config: cfg}
 	}
+	var invalid *config.Invalid
+	if err := cfg.Validate(); errors.As(err, &invalid) {
+		if _, ok := cfg.FromEnv(invalid.Key); ok {
+			// Setup can't fix what the environment overrides.
+			return errMsg(err)
+		}
+		return setupRequiredMsg{Config: cfg, Invalid: invalid}
+	}
 
 	if !git.IsRepo() {
-		return noRepoMsg{}
+		return noRepoMsg{Bare: git.IsBare()}
+	}
+	if cfg, err = staged.Configure(cfg); err != nil {
+		return errMsg(err)
+	}
+	if cfg, err = cfg.WithProvider(m.Provider); err != nil {
+		return errMsg(err)
+	}
+	if cfg, err = cfg.WithProfile(m.Profile); err != nil {
+		return errMsg(err)
 	}
+	// The repo config may have broken what the global one had right.
+	if err := cfg.Validate(); err != nil {
+		return errMsg(err)
+	}
+	// The diff is budgeted against the model's window, so learn it first.
+	ai.LookupContextWindow(cfg)
 
-	diff, err := git.GetStagedDiff()
+	change, err := staged.Collect(cfg)
+	if errors.Is(err, staged.ErrNothingStaged) {
+		return nothingStagedMsg{}
+	}
 	if err != nil {
 		return errMsg(err)
 	}
-	if strings.TrimSpace(diff) == "" {
-		return errMsg(fmt.Errorf("no staged changes found"))
+	// An issue found in the branch name is optional context; one asked for
+	// by number isn't.
+	if err := change.FetchIssue(context.Background(), cfg, m.Options.Issue); err != nil && m.Options.Issue != 0 {
+		return errMsg(err)
 	}
 
-	// Warn if diff is too large (approx 12k chars ~ 3-4k tokens)
-	if len(diff) > 40000 { // ~10k tokens, safety limit
+	// Warn if the diff is too large to summarize in parts
+	sc := change.Context(cfg)
+	if sc.TooLarge() && len(sc.Parts()) > staged.MaxParts {
 		return diffTooLargeMsg{}
 	}
 
@@ -546,55 +1353,176 @@ func checkPrerequisitesCmd() tea.Msg {
 	if err != nil {
 		return errMsg(err)
 	}
+	history = sc.FitHistory(history)
+	detached, err := git.IsDetachedHead()
+	if err != nil {
+		return errMsg(err)
+	}
 
 	return prerequisitesCheckedMsg{
-		Config:  cfg,
-		Diff:    diff,
-		History: history,
+		Config:     cfg,
+		Files:      change.Files,
+		Patchable:  change.Patchable,
+		Symbols:    change.Symbols,
+		Scopes:     change.Scopes,
+		Ticket:     change.Ticket,
+		Issue:      change.Issue,
+		Signer:     change.Signer,
+		RepoRoot:   change.Root,
+		RepoConfig: change.RepoConfig,
+		History:    history,
+		Detached:   detached,
+		LeftBehind: findLeftovers(change.Files),
+
+		BudgetWarning: usage.Warning(cfg),
 	}
 }
 
-func analyzeHistoryCmd(client ai.Provider, diff, history string) tea.Cmd {
+// analyzeHistoryCmd analyzes the history for context, if analyze is set,
+// and unless questions is 0, generates the questions at the same time, since
+// they don't depend on the analysis. The history is first replaced with related commits or style
+// examples chosen for the outgoing diff when either is on. They're chosen
+// only now so nothing is sent before the privacy review.
+func analyzeHistoryCmd(ctx context.Context, client ai.Provider, sc staged.Context, d, history string, analyze bool, questions int) tea.Cmd {
 	return func() tea.Msg {
-		analysis, err := client.AnalyzeHistory(context.Background(), diff, history)
-		if err != nil {
+		// Related commits are best effort; the recent history will do.
+		if h, err := related.History(ctx, client, sc.Config, diff.Paths(sc.Included()), d); err == nil && h != "" {
+			history = sc.FitHistory(h)
+		}
+		var result historyAnalysisResultMsg
+		g, ctx := errgroup.WithContext(ctx)
+		if analyze {
+			g.Go(func() error {
+				analysis, err := client.AnalyzeHistory(ctx, d, history)
+				if err != nil {
+					return err
+				}
+				result.KeyContext = analysis.KeyContext
+				return nil
+			})
+		}
+		if questions > 0 {
+			g.Go(func() error {
+				var err error
+				result.Questions, err = client.GenerateQuestions(ctx, d, history)
+				return err
+			})
+		}
+		if err := g.Wait(); err != nil {
 			return errMsg(err)
 		}
-		return historyAnalysisResultMsg{KeyContext: analysis.KeyContext}
+		result.History = history
+		return result
 	}
 }
 
-func analyzeChangesCmd(client ai.Provider, diff, history string) tea.Cmd {
+func generateCommitMsgCmd(ctx context.Context, client ai.Provider, diff, history string, historyCtx []string, answers map[string]string) tea.Cmd {
 	return func() tea.Msg {
-		questions, err := client.GenerateQuestions(context.Background(), diff, history)
-		if err != nil {
-			return errMsg(err)
+		fullHistoryContext := history
+		if len(historyCtx) > 0 {
+			fullHistoryContext += "\n\nKey Context from History:\n- " + strings.Join(historyCtx, "\n- ")
 		}
-		return analysisResultMsg{Questions: questions}
+
+		stream := client.GenerateCommitMessageStream(ctx, diff, fullHistoryContext, answers)
+		promptHash := ai.MessagePromptHash(client, diff, fullHistoryContext, answers)
+		return waitForChunk(ctx, stream, promptHash)()
 	}
 }
 
-func generateCommitMsgCmd(client ai.Provider, diff, history string, historyCtx []string, answers map[string]string) tea.Cmd {
+// waitForChunk delivers the next update of a streamed commit message.
+func waitForChunk(ctx context.Context, stream <-chan ai.StreamChunk, promptHash string) tea.Cmd {
 	return func() tea.Msg {
-		fullHistoryContext := history
-		if len(historyCtx) > 0 {
-			fullHistoryContext += "\n\nKey Context from History:\n- " + strings.Join(historyCtx, "\n- ")
+		chunk, ok := <-stream
+		if !ok {
+			err := ctx.Err()
+			if err == nil {
+				err = fmt.Errorf("failed to generate commit message: stream ended early")
+			}
+			chunk = ai.StreamChunk{Done: true, Err: err}
 		}
+		return commitMsgChunkMsg{Ctx: ctx, Chunk: chunk, Stream: stream, PromptHash: promptHash}
+	}
+}
+
+func recordProvenanceCmd(cfg *config.Config, client ai.Provider, promptHash string) tea.Cmd {
+	return func() tea.Msg {
+		err := provenance.WriteHead(string(cfg.Provider), client.Model(), promptHash)
+		return provenanceRecordedMsg{Err: err}
+	}
+}
+
+// scoreTimeout bounds how long the success screen waits for a score before
+// quitting without one.
+const scoreTimeout = 20 * time.Second
 
-		msg, err := client.GenerateCommitMessage(context.Background(), diff, fullHistoryContext, answers)
+func scoreCommitCmd(ctx context.Context, client ai.Provider, diff string) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(ctx, scoreTimeout)
+		defer cancel()
+
+		// Read the message back from HEAD so edits made in the editor, or a
+		// manual-mode message, are what gets scored.
+		msg, err := git.HeadMessage()
 		if err != nil {
-			return errMsg(err)
+			return scoreResultMsg{Err: err}
 		}
-		return commitMsgGeneratedMsg{Message: msg}
+		score, err := client.ScoreMessage(ctx, diff, msg)
+		return scoreResultMsg{Score: score, Err: err}
 	}
 }
 
-func commitCmd(msg string) tea.Cmd {
-	c := git.CommitCmd(msg)
-	return tea.ExecProcess(c, func(err error) tea.Msg {
-		if err != nil {
+func commitCmd(msg string, args ...string) tea.Cmd {
+	if !git.UsesExec() {
+		return backendCommitCmd(msg, args...)
+	}
+	return execCommit(git.CommitCmd(msg, args...), msg, true)
+}
+
+// backendCommitCmd commits msg through a git backend that doesn't run git,
+// so there's no terminal to hand over and no hooks or signature to fail.
+func backendCommitCmd(msg string, args ...string) tea.Cmd {
+	return func() tea.Msg {
+		if err := git.CommitWithMessage(msg, args...); err != nil {
 			return errMsg(err)
 		}
 		return commitSuccessMsg{}
+	}
+}
+
+// execCommit hands the terminal to the git commit c, watching what it
+// prints to stderr for a signature git couldn't make or a hook rejecting
+// the commit, so the message can be committed again once that's fixed.
+func execCommit(c *exec.Cmd, message string, edit bool) tea.Cmd {
+	var stderr bytes.Buffer
+	c.Stderr = io.MultiWriter(os.Stderr, &stderr)
+	return tea.ExecProcess(c, func(err error) tea.Msg {
+		if err == nil {
+			return commitSuccessMsg{Signed: git.HeadSigned()}
+		}
+		output := stderr.String()
+		if git.SigningFailed(output) {
+			return signingFailedMsg{
+				Err:     err,
+				Output:  output,
+				Format:  git.SigningFormat(),
+				Key:     git.SigningKey(),
+				Message: message,
+				Edit:    edit,
+			}
+		}
+		if hooks := git.CommitHooks(); git.HookRejected(err, output, hooks) {
+			return hookFailedMsg{Err: err, Output: output, Hooks: hooks, Message: message, Edit: edit}
+		}
+		return errMsg(err)
 	})
 }
+
+// recommit commits message again after a failed commit, the way it was
+// first committed.
+func (m Model) recommit(message string, edit bool) (tea.Model, tea.Cmd) {
+	m.resumeSession()
+	if edit {
+		return m.commitEditing(message)
+	}
+	return m.commitAsIs(message)
+}
@@ -0,0 +1,234 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/arpxspace/smartcommit/internal/ai"
+	"github.com/arpxspace/smartcommit/internal/config"
+	"github.com/arpxspace/smartcommit/internal/network"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// ollamaPicker chooses an Ollama model during setup from those installed
+// on the server, plus recommended ones that can be pulled, filtered by
+// what's typed in the text area.
+type ollamaPicker struct {
+	Models  []ollamaChoice
+	Loading bool
+	Err     error
+	Note    string
+	Cursor  int
+}
+
+// ollamaChoice is a model in the picker.
+type ollamaChoice struct {
+	Name      string
+	Size      int64
+	Installed bool
+}
+
+type ollamaModelsMsg struct {
+	Models []ai.OllamaModel
+	Err    error
+}
+
+type ollamaPulledMsg struct {
+	Name string
+	Err  error
+}
+
+func listOllamaModelsCmd(n config.Network, baseURL string) tea.Cmd {
+	return func() tea.Msg {
+		client, err := network.Client(n, 10*time.Second)
+		if err != nil {
+			return ollamaModelsMsg{Err: err}
+		}
+		models, err := ai.ListOllamaModels(context.Background(), client, baseURL)
+		return ollamaModelsMsg{Models: models, Err: err}
+	}
+}
+
+// pullOllamaModelCmd runs `ollama pull` in the terminal, so its progress
+// shows, against the configured server.
+func pullOllamaModelCmd(baseURL, name string) tea.Cmd {
+	c := exec.Command("ollama", "pull", name)
+	c.Env = append(os.Environ(), "OLLAMA_HOST="+strings.TrimSuffix(strings.TrimRight(baseURL, "/"), "/v1"))
+	return tea.ExecProcess(c, func(err error) tea.Msg {
+		return ollamaPulledMsg{Name: name, Err: err}
+	})
+}
+
+// openOllamaPicker lists the models on the server at the configured URL.
+func (m Model) openOllamaPicker() (tea.Model, tea.Cmd) {
+	m.SetupStep = SetupStepOllamaModel
+	m.TextArea.Reset()
+	m.Ollama = &ollamaPicker{Loading: true}
+	return m, listOllamaModelsCmd(m.Config.Network, m.Config.OllamaURL)
+}
+
+// ollamaModelsLoaded fills the picker with the installed models, followed
+// by the recommended ones that aren't.
+func (m Model) ollamaModelsLoaded(msg ollamaModelsMsg) (tea.Model, tea.Cmd) {
+	p := m.Ollama
+	if p == nil {
+		return m, nil
+	}
+	p.Loading = false
+	p.Err = msg.Err
+	p.Models = nil
+	for _, model := range msg.Models {
+		p.Models = append(p.Models, ollamaChoice{Name: model.Name, Size: model.Size, Installed: true})
+	}
+	for _, name := range ai.RecommendedOllamaModels {
+		installed := slices.ContainsFunc(msg.Models, func(model ai.OllamaModel) bool {
+			return model.Name == name || strings.HasPrefix(model.Name, name+":")
+		})
+		if !installed {
+			p.Models = append(p.Models, ollamaChoice{Name: name})
+		}
+	}
+	return m, nil
+}
+
+// filtered returns the models whose name contains filter.
+func (p *ollamaPicker) filtered(filter string) []ollamaChoice {
+	filter = strings.ToLower(strings.TrimSpace(filter))
+	var out []ollamaChoice
+	for _, model := range p.Models {
+		if strings.Contains(strings.ToLower(model.Name), filter) {
+			out = append(out, model)
+		}
+	}
+	return out
+}
+
+func (m Model) updateOllamaPicker(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	p := m.Ollama
+	models := p.filtered(m.TextArea.Value())
+	switch {
+	case filterKey(msg, keys.Up):
+		if p.Cursor > 0 {
+			p.Cursor--
+		}
+		return m, nil
+	case filterKey(msg, keys.Down):
+		if p.Cursor < len(models)-1 {
+			p.Cursor++
+		}
+		return m, nil
+	case filterKey(msg, keys.Back):
+		m.Ollama = nil
+		m.SetupStep = SetupStepOllamaURL
+		m.TextArea.Reset()
+		m.TextArea.SetValue(m.Config.OllamaURL)
+		return m, nil
+	case filterKey(msg, keys.Select):
+		if p.Loading {
+			return m, nil
+		}
+		var chosen ollamaChoice
+		if p.Cursor < len(models) {
+			chosen = models[p.Cursor]
+		} else {
+			// Nothing matches: take the name as typed.
+			chosen = ollamaChoice{Name: strings.TrimSpace(m.TextArea.Value()), Installed: p.Err != nil}
+		}
+		if chosen.Name == "" {
+			return m, nil
+		}
+		if !chosen.Installed {
+			if _, err := exec.LookPath("ollama"); err != nil {
+				p.Note = fmt.Sprintf("Run `ollama pull %s` where Ollama runs, then choose it again.", chosen.Name)
+				return m, nil
+			}
+			p.Note = ""
+			return m, pullOllamaModelCmd(m.Config.OllamaURL, chosen.Name)
+		}
+		return m.chooseOllamaModel(chosen.Name)
+	}
+	m.TextArea, cmd = m.TextArea.Update(msg)
+	p.Cursor = 0
+	return m, cmd
+}
+
+// ollamaPulled saves the model once `ollama pull` has finished.
+func (m Model) ollamaPulled(msg ollamaPulledMsg) (tea.Model, tea.Cmd) {
+	if m.Ollama == nil {
+		return m, nil
+	}
+	if msg.Err != nil {
+		m.Ollama.Note = fmt.Sprintf("Couldn't pull %s: %v", msg.Name, msg.Err)
+		return m, nil
+	}
+	return m.chooseOllamaModel(msg.Name)
+}
+
+func (m Model) chooseOllamaModel(name string) (tea.Model, tea.Cmd) {
+	m.Config.Provider = config.ProviderOllama
+	m.Config.OllamaModel = name
+	m.Ollama = nil
+	m.TextArea.Reset()
+	return m.saveProvider()
+}
+
+func (m Model) viewOllamaPicker() string {
+	titleStyle := lipgloss.NewStyle().Bold(true)
+	infoStyle := lipgloss.NewStyle().Foreground(theme.Muted)
+	p := m.Ollama
+
+	var b strings.Builder
+	b.WriteString("\n " + titleStyle.Render("Choose an Ollama model:") + "\n\n")
+	b.WriteString(m.TextArea.View() + "\n\n")
+	switch {
+	case p.Loading:
+		fmt.Fprintf(&b, " %s Listing models at %s...\n", m.Spinner.View(), m.Config.OllamaURL)
+	default:
+		if p.Err != nil {
+			b.WriteString(" " + infoStyle.Render(fmt.Sprintf("Couldn't list installed models: %v", p.Err)) + "\n")
+			b.WriteString(" Type a model name and press Enter, or esc to change the URL.\n\n")
+		}
+		models := p.filtered(m.TextArea.Value())
+		if len(models) == 0 && p.Err == nil {
+			b.WriteString(" No models match. Press Enter to use the name as typed.\n")
+		}
+		for i, model := range models {
+			cursor := "  "
+			if i == p.Cursor {
+				cursor = "> "
+			}
+			detail := "recommended, not installed: enter to pull"
+			if model.Installed {
+				detail = formatSize(model.Size)
+			}
+			fmt.Fprintf(&b, " %s%-30s %s\n", cursor, model.Name, infoStyle.Render(detail))
+		}
+	}
+	if p.Note != "" {
+		b.WriteString("\n " + p.Note + "\n")
+	}
+	b.WriteString("\n " + infoStyle.Render(pickerHint()) + "\n")
+	return b.String()
+}
+
+// formatSize shows a size in bytes in the largest fitting unit.
+func formatSize(n int64) string {
+	const unit = 1000
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for q := n / unit; q >= unit; q /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "kMGTPE"[exp])
+}
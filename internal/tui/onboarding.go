@@ -0,0 +1,197 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/arpxspace/smartcommit/internal/ai"
+	"github.com/arpxspace/smartcommit/internal/config"
+	"github.com/arpxspace/smartcommit/internal/git"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// aliasName is the git alias offered during onboarding, so `git ci` runs smartcommit.
+const aliasName = "alias.ci"
+
+// sampleDiff is a small synthetic change used to try the provider during
+// onboarding without sending anything from the user's repository.
+const sampleDiff = `diff --git a/greet.go b/greet.go
+index 3b18e51..a2c4f1d 100644
+--- a/greet.go
++++ b/greet.go
+@@ -1,7 +1,11 @@
+ package greet
+
+-func Hello(name string) string {
+-	return "Hello, " + name
++import "strings"
++
++func Hello(name string) string {
++	if strings.TrimSpace(name) == "" {
++		name = "stranger"
++	}
++	return "Hello, " + name + "!"
+ }
+`
+
+type sampleGeneratedMsg struct {
+	Message string
+	Err     error
+}
+
+// providerConfigured is called once the provider has been saved. During the
+// first-run tour it moves on to the privacy step; otherwise setup is done.
+func (m Model) providerConfigured() (tea.Model, tea.Cmd) {
+	m.SetupProblem = nil
+	if !m.Onboarding {
+		return m, m.checkPrerequisitesCmd
+	}
+	m.SetupStep = SetupStepPrivacy
+	return m, nil
+}
+
+func (m Model) updateOnboarding(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch m.SetupStep {
+	case SetupStepIntro:
+		if msg.Type == tea.KeyEnter {
+			m.SetupStep = SetupStepProvider
+		}
+	case SetupStepPrivacy:
+		switch msg.String() {
+		case "1":
+			m.Config.PrivacyReview = !m.Config.PrivacyReview
+		case "2":
+			m.Config.DisableStore = !m.Config.DisableStore
+		case "enter":
+			if err := m.Config.Save(); err != nil {
+				return m.failed(err), nil
+			}
+			m.SetupStep = SetupStepSample
+		}
+	case SetupStepSample:
+		if m.SampleRunning {
+			return m, nil
+		}
+		done := m.SampleMsg != "" || m.SampleErr != nil
+		switch strings.ToLower(msg.String()) {
+		case "y":
+			if done {
+				break
+			}
+			m.SampleRunning = true
+			return m, tea.Batch(m.Spinner.Tick, sampleGenerationCmd(m.Config))
+		case "n", "enter":
+			m.SetupStep = SetupStepAlias
+		}
+	case SetupStepAlias:
+		switch strings.ToLower(msg.String()) {
+		case "y":
+			if err := git.SetGlobalConfig(aliasName, "!smartcommit"); err != nil {
+				m.AliasMsg = err.Error()
+				return m, nil
+			}
+			return m.finishOnboarding()
+		case "n", "enter":
+			return m.finishOnboarding()
+		}
+	}
+	return m, nil
+}
+
+func (m Model) finishOnboarding() (tea.Model, tea.Cmd) {
+	m.Onboarding = false
+	m.SampleMsg = ""
+	m.SampleErr = nil
+	m.AliasMsg = ""
+	return m, m.checkPrerequisitesCmd
+}
+
+// sampleGenerationCmd generates a message for sampleDiff with the configured
+// provider, so connection or model problems surface before the first real commit.
+func sampleGenerationCmd(cfg *config.Config) tea.Cmd {
+	return func() tea.Msg {
+		client, err := ai.NewClient(cfg)
+		if err != nil {
+			return sampleGeneratedMsg{Err: err}
+		}
+		msg, err := client.GenerateCommitMessage(context.Background(), sampleDiff, "", map[string]string{
+			"Why was this change made?": "Empty names produced an awkward greeting.",
+		})
+		return sampleGeneratedMsg{Message: msg, Err: err}
+	}
+}
+
+func (m Model) viewOnboarding() string {
+	titleStyle := lipgloss.NewStyle().Bold(true)
+	infoStyle := lipgloss.NewStyle().Foreground(theme.Muted)
+	errorStyle := lipgloss.NewStyle().Foreground(theme.Error).Bold(true)
+	commandStyle := lipgloss.NewStyle().Foreground(theme.Command)
+
+	var b strings.Builder
+	switch m.SetupStep {
+	case SetupStepIntro:
+		fmt.Fprintf(&b, "\n %s\n\n", titleStyle.Render("Welcome to SmartCommit"))
+		b.WriteString(" Before we start, here's what leaves your machine on every run:\n\n")
+		b.WriteString("  • the staged diff, minus any files you exclude\n")
+		b.WriteString("  • the messages of your last 10 commits\n")
+		b.WriteString("  • your answers to the clarifying questions\n\n")
+		b.WriteString(" With OpenAI this goes to OpenAI's API. With Ollama it stays on the\n")
+		b.WriteString(" machine running your Ollama server.\n\n")
+		fmt.Fprintf(&b, " Settings are saved in %s.\n\n", commandStyle.Render("~/.config/smartcommit/config.json"))
+		b.WriteString(" " + infoStyle.Render("(Press Enter to choose a provider)") + "\n")
+	case SetupStepPrivacy:
+		fmt.Fprintf(&b, "\n %s\n\n", titleStyle.Render("Privacy Settings"))
+		fmt.Fprintf(&b, " 1. %s Review outgoing files before each request\n", checkbox(m.Config.PrivacyReview))
+		fmt.Fprintf(&b, "    %s\n", infoStyle.Faint(true).Render("Lets you exclude files and redacts anything that looks like a secret"))
+		fmt.Fprintf(&b, " 2. %s Keep a local history of sessions\n", checkbox(!m.Config.DisableStore))
+		fmt.Fprintf(&b, "    %s\n\n", infoStyle.Faint(true).Render("Questions, answers and drafts, stored on this machine only"))
+		b.WriteString(" " + infoStyle.Render("(Press 1 or 2 to toggle, Enter to save)") + "\n")
+	case SetupStepSample:
+		fmt.Fprintf(&b, "\n %s\n\n", titleStyle.Render("Try It Out"))
+		switch {
+		case m.SampleRunning:
+			fmt.Fprintf(&b, " %s Generating a message for a sample diff...\n", m.Spinner.View())
+		case m.SampleErr != nil:
+			fmt.Fprintf(&b, " %s %v\n\n", errorStyle.Render("Sample generation failed:"), m.SampleErr)
+			b.WriteString(" Check your provider settings; you can reconfigure with 'c' later.\n\n")
+			b.WriteString(" " + infoStyle.Render("(Press Enter to continue)") + "\n")
+		case m.SampleMsg != "":
+			b.WriteString(" Your provider is working. Here's what it wrote for the sample diff:\n\n")
+			for _, line := range strings.Split(m.SampleMsg, "\n") {
+				b.WriteString("   " + line + "\n")
+			}
+			b.WriteString("\n " + infoStyle.Render("(Press Enter to continue)") + "\n")
+		default:
+			b.WriteString(" Generate a message for a small synthetic diff to check the provider works?\n")
+			b.WriteString(" Nothing from your repository is sent.\n\n")
+			b.WriteString(" " + infoStyle.Render("(y/n)") + "\n")
+		}
+	case SetupStepAlias:
+		fmt.Fprintf(&b, "\n %s\n\n", titleStyle.Render("Git Alias"))
+		if current := git.GetGlobalConfig(aliasName); current == "!smartcommit" {
+			fmt.Fprintf(&b, " %s already runs smartcommit.\n\n", commandStyle.Render("git ci"))
+			b.WriteString(" " + infoStyle.Render("(Press Enter to finish)") + "\n")
+			return b.String()
+		} else if current != "" {
+			fmt.Fprintf(&b, " %s is already set to %s.\n", commandStyle.Render("git ci"), commandStyle.Render(current))
+			b.WriteString(" Replace it so that it runs smartcommit?\n\n")
+		} else {
+			fmt.Fprintf(&b, " Add a global %s alias that runs smartcommit?\n\n", commandStyle.Render("git ci"))
+		}
+		if m.AliasMsg != "" {
+			fmt.Fprintf(&b, " %s %s\n\n", errorStyle.Render("Error:"), m.AliasMsg)
+		}
+		b.WriteString(" " + infoStyle.Render("(y/n)") + "\n")
+	}
+	return b.String()
+}
+
+func checkbox(on bool) string {
+	if on {
+		return "[x]"
+	}
+	return "[ ]"
+}
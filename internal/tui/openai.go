@@ -0,0 +1,165 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/arpxspace/smartcommit/internal/ai"
+	"github.com/arpxspace/smartcommit/internal/config"
+	"github.com/arpxspace/smartcommit/internal/network"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// openAIRows is how many models the picker shows at once.
+const openAIRows = 10
+
+// openAIPicker chooses an OpenAI model during setup from the chat models
+// the key has access to, filtered by what's typed in the text area.
+type openAIPicker struct {
+	Models  []string
+	Loading bool
+	Err     error
+	Cursor  int
+}
+
+type openAIModelsMsg struct {
+	Models []string
+	Err    error
+}
+
+func listOpenAIModelsCmd(n config.Network, apiKey string) tea.Cmd {
+	return func() tea.Msg {
+		client, err := network.Client(n, 0)
+		if err != nil {
+			return openAIModelsMsg{Err: err}
+		}
+		models, err := ai.ListOpenAIModels(context.Background(), client, apiKey)
+		return openAIModelsMsg{Models: models, Err: err}
+	}
+}
+
+// openOpenAIPicker lists the models available with the entered key.
+func (m Model) openOpenAIPicker() (tea.Model, tea.Cmd) {
+	m.SetupStep = SetupStepOpenAIModel
+	m.TextArea.Reset()
+	m.OpenAI = &openAIPicker{Loading: true}
+	return m, listOpenAIModelsCmd(m.Config.Network, m.Config.OpenAIAPIKey)
+}
+
+// openAIModelsLoaded fills the picker, starting on the configured model.
+func (m Model) openAIModelsLoaded(msg openAIModelsMsg) (tea.Model, tea.Cmd) {
+	p := m.OpenAI
+	if p == nil {
+		return m, nil
+	}
+	p.Loading = false
+	p.Models = msg.Models
+	p.Err = msg.Err
+	if i := slices.Index(p.Models, m.Config.Model()); i >= 0 {
+		p.Cursor = i
+	}
+	return m, nil
+}
+
+// filtered returns the models whose name contains filter.
+func (p *openAIPicker) filtered(filter string) []string {
+	filter = strings.ToLower(strings.TrimSpace(filter))
+	var out []string
+	for _, model := range p.Models {
+		if strings.Contains(strings.ToLower(model), filter) {
+			out = append(out, model)
+		}
+	}
+	return out
+}
+
+func (m Model) updateOpenAIPicker(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	p := m.OpenAI
+	models := p.filtered(m.TextArea.Value())
+	switch {
+	case filterKey(msg, keys.Up):
+		if p.Cursor > 0 {
+			p.Cursor--
+		}
+		return m, nil
+	case filterKey(msg, keys.Down):
+		if p.Cursor < len(models)-1 {
+			p.Cursor++
+		}
+		return m, nil
+	case filterKey(msg, keys.Back):
+		m.OpenAI = nil
+		m.SetupStep = SetupStepOpenAIKey
+		m.TextArea.Reset()
+		return m, nil
+	case filterKey(msg, keys.Select):
+		if p.Loading {
+			return m, nil
+		}
+		model := strings.TrimSpace(m.TextArea.Value())
+		if p.Cursor < len(models) {
+			model = models[p.Cursor]
+		}
+		if model == "" {
+			model = config.DefaultOpenAIModel
+		}
+		m.Config.Provider = config.ProviderOpenAI
+		m.Config.OpenAIModel = model
+		if model == config.DefaultOpenAIModel {
+			m.Config.OpenAIModel = ""
+		}
+		m.OpenAI = nil
+		m.TextArea.Reset()
+		return m.saveProvider()
+	}
+	m.TextArea, cmd = m.TextArea.Update(msg)
+	p.Cursor = 0
+	return m, cmd
+}
+
+func (m Model) viewOpenAIPicker() string {
+	titleStyle := lipgloss.NewStyle().Bold(true)
+	infoStyle := lipgloss.NewStyle().Foreground(theme.Muted)
+	p := m.OpenAI
+
+	var b strings.Builder
+	b.WriteString("\n " + titleStyle.Render("Choose an OpenAI model:") + "\n\n")
+	b.WriteString(m.TextArea.View() + "\n\n")
+	switch {
+	case p.Loading:
+		fmt.Fprintf(&b, " %s Fetching models...\n", m.Spinner.View())
+	case p.Err != nil:
+		b.WriteString(" " + infoStyle.Render(fmt.Sprintf("Couldn't list models: %v", p.Err)) + "\n")
+		fmt.Fprintf(&b, " Type a model name and press Enter, or leave it empty for %s.\n", config.DefaultOpenAIModel)
+	default:
+		models := p.filtered(m.TextArea.Value())
+		if len(models) == 0 {
+			b.WriteString(" No models match. Press Enter to use the name as typed.\n")
+		}
+		start := max(0, min(p.Cursor-openAIRows/2, len(models)-openAIRows))
+		for i := start; i < len(models) && i < start+openAIRows; i++ {
+			cursor := "  "
+			if i == p.Cursor {
+				cursor = "> "
+			}
+			var notes []string
+			if models[i] == config.DefaultOpenAIModel {
+				notes = append(notes, "default")
+			}
+			if !ai.SupportsStructuredOutputs(models[i]) {
+				notes = append(notes, "no Structured Outputs; replies are repaired")
+			}
+			fmt.Fprintf(&b, " %s%-30s %s\n", cursor, models[i], infoStyle.Render(strings.Join(notes, ", ")))
+		}
+		if len(models) > openAIRows {
+			b.WriteString(" " + infoStyle.Render(fmt.Sprintf("%d of %d models", len(models), len(p.Models))) + "\n")
+		}
+	}
+	b.WriteString("\n " + infoStyle.Render(pickerHint()) + "\n")
+	return b.String()
+}
@@ -0,0 +1,199 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/arpxspace/smartcommit/internal/ai"
+	"github.com/arpxspace/smartcommit/internal/config"
+	"github.com/arpxspace/smartcommit/internal/network"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// openRouterRows is how many models the picker shows at once.
+const openRouterRows = 10
+
+// openRouterPicker chooses an OpenRouter model during setup, filtered by
+// what's typed in the text area.
+type openRouterPicker struct {
+	Models  []ai.OpenRouterModel
+	Loading bool
+	Err     error
+	Cursor  int
+}
+
+type openRouterModelsMsg struct {
+	Models []ai.OpenRouterModel
+	Err    error
+}
+
+func listOpenRouterModelsCmd(n config.Network) tea.Cmd {
+	return func() tea.Msg {
+		client, err := network.Client(n, 10*time.Second)
+		if err != nil {
+			return openRouterModelsMsg{Err: err}
+		}
+		models, err := ai.ListOpenRouterModels(context.Background(), client)
+		return openRouterModelsMsg{Models: models, Err: err}
+	}
+}
+
+// filtered returns the models whose ID or name contains filter.
+func (p *openRouterPicker) filtered(filter string) []ai.OpenRouterModel {
+	filter = strings.ToLower(strings.TrimSpace(filter))
+	if filter == "" {
+		return p.Models
+	}
+	var out []ai.OpenRouterModel
+	for _, model := range p.Models {
+		if strings.Contains(strings.ToLower(model.ID), filter) || strings.Contains(strings.ToLower(model.Name), filter) {
+			out = append(out, model)
+		}
+	}
+	return out
+}
+
+func (m Model) updateOpenRouterSetup(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	switch m.SetupStep {
+	case SetupStepOpenRouterKey:
+		if msg.Type == tea.KeyEnter {
+			input := strings.TrimSpace(m.TextArea.Value())
+			if input == "" {
+				input = os.Getenv("OPENROUTER_API_KEY")
+			}
+			if input != "" {
+				m.Config.OpenRouterAPIKey = input
+				m.SetupStep = SetupStepOpenRouterModel
+				m.TextArea.Reset()
+				m.OpenRouter = &openRouterPicker{Loading: true}
+				return m, listOpenRouterModelsCmd(m.Config.Network)
+			}
+			return m, nil
+		}
+	case SetupStepOpenRouterModel:
+		p := m.OpenRouter
+		models := p.filtered(m.TextArea.Value())
+		switch {
+		case filterKey(msg, keys.Up):
+			if p.Cursor > 0 {
+				p.Cursor--
+			}
+			return m, nil
+		case filterKey(msg, keys.Down):
+			if p.Cursor < len(models)-1 {
+				p.Cursor++
+			}
+			return m, nil
+		case filterKey(msg, keys.Back):
+			m.OpenRouter = nil
+			m.SetupStep = SetupStepProvider
+			m.TextArea.Reset()
+			return m, nil
+		case filterKey(msg, keys.Select):
+			return m.chooseOpenRouterModel(models)
+		}
+		m.TextArea, cmd = m.TextArea.Update(msg)
+		p.Cursor = 0
+		return m, cmd
+	}
+	m.TextArea, cmd = m.TextArea.Update(msg)
+	return m, cmd
+}
+
+// chooseOpenRouterModel saves the highlighted model, or the typed ID when
+// nothing matches it, along with its price so spend can be estimated.
+func (m Model) chooseOpenRouterModel(models []ai.OpenRouterModel) (tea.Model, tea.Cmd) {
+	p := m.OpenRouter
+	var chosen ai.OpenRouterModel
+	if p.Cursor < len(models) {
+		chosen = models[p.Cursor]
+	} else {
+		chosen.ID = strings.TrimSpace(m.TextArea.Value())
+	}
+	if chosen.ID == "" {
+		return m, nil
+	}
+	m.Config.Provider = config.ProviderOpenRouter
+	m.Config.OpenRouterModel = chosen.ID
+	if chosen.Priced {
+		if m.Config.Prices == nil {
+			m.Config.Prices = make(map[string]config.Price)
+		}
+		m.Config.Prices[chosen.ID] = chosen.Price
+	}
+	m.OpenRouter = nil
+	m.TextArea.Reset()
+	return m.saveProvider()
+}
+
+func (m Model) viewOpenRouterSetup() string {
+	titleStyle := lipgloss.NewStyle().Bold(true)
+	infoStyle := lipgloss.NewStyle().Foreground(theme.Muted)
+
+	if m.SetupStep == SetupStepOpenRouterKey {
+		hint := "(Press Enter to continue)"
+		if os.Getenv("OPENROUTER_API_KEY") != "" {
+			hint = "(Press Enter to continue; leave empty to use OPENROUTER_API_KEY)"
+		}
+		return fmt.Sprintf(
+			"\n %s\n\n%s\n\n%s\n",
+			titleStyle.Render("Please enter your OpenRouter API Key:"),
+			m.TextArea.View(),
+			infoStyle.Render(hint),
+		)
+	}
+
+	p := m.OpenRouter
+	var b strings.Builder
+	b.WriteString("\n " + titleStyle.Render("Choose an OpenRouter model:") + "\n\n")
+	b.WriteString(m.TextArea.View() + "\n\n")
+	switch {
+	case p.Loading:
+		fmt.Fprintf(&b, " %s Fetching models...\n", m.Spinner.View())
+	case p.Err != nil:
+		b.WriteString(" " + infoStyle.Render(fmt.Sprintf("Couldn't list models: %v", p.Err)) + "\n")
+		b.WriteString(" Type a model ID, such as " + config.DefaultOpenRouterModel + ", and press Enter.\n")
+	default:
+		models := p.filtered(m.TextArea.Value())
+		if len(models) == 0 {
+			b.WriteString(" No models match. Press Enter to use the ID as typed.\n")
+		}
+		start := max(0, min(p.Cursor-openRouterRows/2, len(models)-openRouterRows))
+		for i := start; i < len(models) && i < start+openRouterRows; i++ {
+			cursor := "  "
+			if i == p.Cursor {
+				cursor = "> "
+			}
+			model := models[i]
+			fmt.Fprintf(&b, " %s%-45s %s\n", cursor, model.ID, infoStyle.Render(describeOpenRouterModel(model)))
+		}
+		if len(models) > openRouterRows {
+			b.WriteString(" " + infoStyle.Render(fmt.Sprintf("%d of %d models", len(models), len(p.Models))) + "\n")
+		}
+	}
+	b.WriteString("\n " + infoStyle.Render(pickerHint()) + "\n")
+	return b.String()
+}
+
+// describeOpenRouterModel shows a model's context window and price.
+func describeOpenRouterModel(model ai.OpenRouterModel) string {
+	var parts []string
+	if model.ContextLength > 0 {
+		parts = append(parts, fmt.Sprintf("%dk context", model.ContextLength/1000))
+	}
+	switch {
+	case !model.Priced:
+		parts = append(parts, "price varies")
+	case model.Price.Prompt == 0 && model.Price.Completion == 0:
+		parts = append(parts, "free")
+	default:
+		parts = append(parts, fmt.Sprintf("$%.2f in / $%.2f out per 1M tokens", model.Price.Prompt, model.Price.Completion))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,123 @@
+package tui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/arpxspace/smartcommit/internal/config"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// steps name the stages of the pipeline, in order, for the header.
+var steps = []string{"History", "Questions", "Answers", "Generate", "Review", "Commit"}
+
+// step returns the index in steps of the stage the current state belongs
+// to, or -1 outside the pipeline.
+func (m Model) step() int {
+	switch m.State {
+	case StateSummarizing, StateHistoryAnalysis:
+		return 0
+	case StateQuestioning:
+		return 2
+	case StateGenerating:
+		return 3
+	case StateReview:
+		return 4
+	case StateCommit, StateSuccess:
+		// Only a generated message went through the pipeline; one written
+		// by hand or in critique mode didn't.
+		if m.PromptHash == "" {
+			return -1
+		}
+		return 5
+	}
+	return -1
+}
+
+// viewPipeline shows the stages of the pipeline with the current one
+// highlighted, how long it has taken so far, and the provider in use, or
+// returns "" outside the pipeline.
+func (m Model) viewPipeline() string {
+	current := m.step()
+	if current < 0 {
+		return ""
+	}
+	doneStyle := lipgloss.NewStyle().Foreground(theme.Success)
+	currentStyle := lipgloss.NewStyle().Foreground(theme.Accent).Bold(true)
+	infoStyle := lipgloss.NewStyle().Foreground(theme.Muted)
+
+	names := make([]string, len(steps))
+	for i, name := range steps {
+		switch {
+		case i < current:
+			names[i] = doneStyle.Render(name)
+		case i == current, i == 1 && m.State == StateHistoryAnalysis && m.Config.Questions() > 0:
+			// The questions are generated alongside the history analysis.
+			names[i] = currentStyle.Render(name)
+		default:
+			names[i] = infoStyle.Render(name)
+		}
+	}
+	line := " " + strings.Join(names, infoStyle.Render(" ▸ "))
+	var details []string
+	if !m.StepStart.IsZero() && m.State != StateSuccess {
+		details = append(details, elapsed(time.Since(m.StepStart)))
+	}
+	if provider := m.providerLabel(); provider != "" {
+		details = append(details, provider)
+	}
+	if m.Profile != config.ProfileStandard {
+		details = append(details, m.Profile+" profile")
+	}
+	if len(details) > 0 {
+		line += infoStyle.Render("   " + strings.Join(details, " · "))
+	}
+	return "\n" + line + "\n"
+}
+
+// elapsed formats d as minutes and seconds, like "0:07" or "2:31".
+func elapsed(d time.Duration) string {
+	s := int(d.Seconds())
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
+
+// providerLabel names the configured provider and model, like
+// "OpenAI: gpt-4o-mini", or returns "" before the config is loaded.
+func (m Model) providerLabel() string {
+	if m.Config == nil {
+		return ""
+	}
+	switch m.Config.Provider {
+	case config.ProviderOpenAI:
+		return "OpenAI: " + m.Config.Model()
+	case config.ProviderOllama:
+		return "Ollama: " + m.Config.OllamaModel
+	case config.ProviderAzure:
+		return "Azure OpenAI: " + m.Config.AzureDeployment
+	case config.ProviderOpenRouter:
+		return "OpenRouter: " + m.Config.Model()
+	case config.ProviderCustom:
+		return fmt.Sprintf("%s at %s", m.Config.CustomModel, m.Config.CustomURL)
+	case config.ProviderMock:
+		return "the mock provider"
+	}
+	return ""
+}
+
+// nextProvider returns the provider set up after the current one,
+// wrapping around.
+func (m Model) nextProvider() config.ProviderType {
+	providers := m.Config.ConfiguredProviders()
+	i := slices.Index(providers, m.Config.Provider)
+	return providers[(i+1)%len(providers)]
+}
+
+// nextProfile returns the profile after the current one, wrapping around.
+func (m Model) nextProfile() string {
+	names := m.Config.ProfileNames()
+	i := slices.Index(names, m.Profile)
+	return names[(i+1)%len(names)]
+}
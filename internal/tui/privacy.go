@@ -0,0 +1,123 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/arpxspace/smartcommit/internal/config"
+	"github.com/arpxspace/smartcommit/internal/redact"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// privacyReview reports whether the user should confirm outgoing files before the first API call.
+func (m Model) privacyReview() bool {
+	return m.Options.PrivacyReview || (m.Config != nil && m.Config.PrivacyReview)
+}
+
+func (m Model) updatePrivacyReview(msg tea.Msg) (tea.Model, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+
+	switch {
+	case key.Matches(keyMsg, keys.Up):
+		if m.PrivacyCursor > 0 {
+			m.PrivacyCursor--
+		}
+	case key.Matches(keyMsg, keys.Down):
+		if m.PrivacyCursor < len(m.Files)-1 {
+			m.PrivacyCursor++
+		}
+	case key.Matches(keyMsg, keys.Toggle):
+		if len(m.Files) > 0 {
+			path := m.Files[m.PrivacyCursor].Path
+			m.Excluded[path] = !m.Excluded[path]
+		}
+	case key.Matches(keyMsg, keys.Back):
+		m.State = StateWelcome
+	case key.Matches(keyMsg, keys.Select):
+		m.Diff = m.outgoingDiff()
+		if strings.TrimSpace(m.Diff) == "" {
+			// Nothing left to send; stay on the review screen.
+			return m, nil
+		}
+		if err := m.saveExclusions(); err != nil {
+			return m, func() tea.Msg { return errMsg(err) }
+		}
+		return m.proceed()
+	}
+	return m, nil
+}
+
+// saveExclusions remembers the user's choices in the repo config. Exclusions
+// for files that aren't part of this diff are kept as they were.
+func (m Model) saveExclusions() error {
+	inDiff := make(map[string]bool, len(m.Files))
+	for _, f := range m.Files {
+		inDiff[f.Path] = true
+	}
+
+	var exclude []string
+	for _, path := range m.RepoConfig.Exclude {
+		if !inDiff[path] {
+			exclude = append(exclude, path)
+		}
+	}
+	for _, f := range m.Files {
+		if m.Excluded[f.Path] {
+			exclude = append(exclude, f.Path)
+		}
+	}
+
+	if strings.Join(exclude, "\n") == strings.Join(m.RepoConfig.Exclude, "\n") {
+		return nil
+	}
+	m.RepoConfig.Exclude = exclude
+	return m.RepoConfig.Save(m.RepoRoot)
+}
+
+func (m Model) viewPrivacyReview() string {
+	titleStyle := lipgloss.NewStyle().Bold(true)
+	infoStyle := lipgloss.NewStyle().Foreground(theme.Muted)
+	cursorStyle := lipgloss.NewStyle().Foreground(theme.Accent).Bold(true)
+	warnStyle := lipgloss.NewStyle().Foreground(theme.Warning)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n %s\n\n", titleStyle.Render("Review Outgoing Data"))
+	b.WriteString(" The content of these files will be sent to your AI provider.\n")
+	b.WriteString(" Exclude anything you don't want to leave this machine.\n\n")
+
+	sent := 0
+	sc := m.context()
+	for i, f := range m.Files {
+		cursor := "  "
+		if i == m.PrivacyCursor {
+			cursor = cursorStyle.Render("> ")
+		}
+		check := "[x]"
+		if m.Excluded[f.Path] || sc.IsIgnored(f.Path) {
+			check = "[ ]"
+		} else {
+			sent++
+		}
+		line := fmt.Sprintf(" %s%s %s %s", cursor, check, f.Path, infoStyle.Render(fmt.Sprintf("(%d bytes)", len(f.Content))))
+		if sc.IsIgnored(f.Path) {
+			line += " " + infoStyle.Render("ignored by ignore_paths")
+		} else if redact.ContainsSecret(f.Content) {
+			line += " " + warnStyle.Render("⚠ possible secret, will be redacted")
+		}
+		b.WriteString(line + "\n")
+	}
+
+	b.WriteString("\n")
+	if sent == 0 {
+		b.WriteString(" " + warnStyle.Render("All files are excluded; include at least one to continue.") + "\n")
+	}
+	b.WriteString(" " + infoStyle.Render("Exclusions are remembered in "+config.RepoConfigFile) + "\n")
+	b.WriteString(" " + infoStyle.Render("(↑/↓ to move, space to toggle, enter to send, esc to go back)") + "\n")
+	return b.String()
+}
@@ -0,0 +1,87 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/arpxspace/smartcommit/internal/ai"
+	"github.com/arpxspace/smartcommit/internal/diff"
+	"github.com/arpxspace/smartcommit/internal/quality"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+type judgedMsg struct {
+	Message string
+	Score   *ai.ScoreResponse
+	Err     error
+}
+
+// quality rates the reviewed message, with the AI judge's scores once
+// they've arrived for it.
+func (m Model) quality() quality.Result {
+	r := quality.Rate(m.CommitMsg, diff.Paths(m.Files), m.Config.Vague())
+	if m.Judged != nil && m.JudgedMsg == m.CommitMsg {
+		r = r.WithJudge(m.Judged)
+	}
+	return r
+}
+
+// judge has the AI judge the reviewed message, when score_judge is set and
+// it hasn't been judged already.
+func (m Model) judge() (Model, tea.Cmd) {
+	if !m.Config.ScoreJudge || m.AIClient == nil || m.JudgedMsg == m.CommitMsg {
+		return m, nil
+	}
+	m.JudgedMsg = m.CommitMsg
+	m.Judged = nil
+	return m, judgeCmd(m.Requests, m.AIClient, m.Diff, m.CommitMsg)
+}
+
+func (m Model) updateJudged(msg judgedMsg) (tea.Model, tea.Cmd) {
+	// A failed judge leaves the local score, which is still useful.
+	if msg.Err == nil && msg.Message == m.JudgedMsg {
+		m.Judged = msg.Score
+	} else if msg.Message == m.JudgedMsg && !errors.Is(msg.Err, context.Canceled) {
+		m.JudgedMsg = ""
+	}
+	return m, nil
+}
+
+func judgeCmd(ctx context.Context, client ai.Provider, diff, message string) tea.Cmd {
+	return func() tea.Msg {
+		score, err := client.ScoreMessage(ctx, diff, message)
+		return judgedMsg{Message: message, Score: score, Err: err}
+	}
+}
+
+// viewQuality shows the quality score of the reviewed message with its
+// top suggestion.
+func (m Model) viewQuality() string {
+	labelStyle := lipgloss.NewStyle().Bold(true)
+	infoStyle := lipgloss.NewStyle().Foreground(theme.Muted)
+
+	r := m.quality()
+	color := theme.Error
+	switch {
+	case r.Score >= 80:
+		color = theme.Success
+	case r.Score >= 50:
+		color = theme.Warning
+	}
+	line := " " + labelStyle.Render("Quality") + " " +
+		lipgloss.NewStyle().Foreground(color).Bold(true).Render(fmt.Sprintf("%d/100", r.Score)) +
+		infoStyle.Render(fmt.Sprintf("  why %d · specificity %d · cohesion %d", r.Why, r.Specificity, r.Cohesion))
+	switch {
+	case r.Judged:
+		line += infoStyle.Render(" · judged by AI")
+	case m.JudgedMsg == m.CommitMsg && m.JudgedMsg != "":
+		line += " " + m.Spinner.View()
+	}
+	if r.Suggestion != "" {
+		line += "\n " + infoStyle.Render("Tip: "+r.Suggestion)
+	}
+	return line + "\n"
+}
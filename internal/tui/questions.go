@@ -0,0 +1,52 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// previousQuestion goes back to the question before the current one to edit
+// its answer, keeping whatever was typed for the current one.
+func (m Model) previousQuestion() (tea.Model, tea.Cmd) {
+	if m.CurrentQIdx == 0 {
+		return m, nil
+	}
+	if answer := strings.TrimSpace(m.TextArea.Value()); answer != "" {
+		m.Answers[m.Questions[m.CurrentQIdx]] = answer
+	}
+	m.CurrentQIdx--
+	m.TextArea.Reset()
+	m.TextArea.SetValue(m.Answers[m.Questions[m.CurrentQIdx]])
+	m.TextArea.Focus()
+	return m, nil
+}
+
+// viewAnswered lists the questions before the current one, collapsed to a
+// line each with the answer given.
+func (m Model) viewAnswered() string {
+	if m.CurrentQIdx == 0 {
+		return ""
+	}
+	doneStyle := lipgloss.NewStyle().Foreground(theme.Success)
+	answerStyle := lipgloss.NewStyle().Foreground(theme.Muted)
+	width := max(m.Width-8, 40)
+
+	var b strings.Builder
+	for i, q := range m.Questions[:m.CurrentQIdx] {
+		fmt.Fprintf(&b, " %s %s\n", doneStyle.Render("✓"), clip(fmt.Sprintf("%d. %s", i+1, q), width))
+		fmt.Fprintf(&b, "   %s\n", answerStyle.Render(clip(m.Answers[q], width)))
+	}
+	return b.String() + "\n"
+}
+
+// clip shortens s to one line of at most n characters.
+func clip(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); len(r) > n {
+		return string(r[:n-1]) + "…"
+	}
+	return s
+}
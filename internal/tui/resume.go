@@ -0,0 +1,151 @@
+package tui
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/arpxspace/smartcommit/internal/store"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// findPrevious returns the latest session recorded for the staged change in
+// this repository with questions or a draft worth picking up again, if it
+// ended unfinished, aborted, or failed. It's best effort: store errors just
+// mean nothing to resume.
+func (m Model) findPrevious() *store.Session {
+	if m.Config.DisableStore {
+		return nil
+	}
+	s, err := store.Open()
+	if err != nil {
+		return nil
+	}
+	defer s.Close()
+
+	// The session records the diff as sent, which is redacted once the user
+	// chooses to redact secrets.
+	redacted := m.context()
+	redacted.Redact = true
+	var sessions []*store.Session
+	for _, d := range []string{m.Diff, redacted.Diff()} {
+		found, err := s.ByDiffHash(store.HashDiff(d))
+		if err != nil {
+			return nil
+		}
+		for _, sess := range found {
+			if sess.RepoRoot == m.RepoRoot {
+				sessions = append(sessions, sess)
+			}
+		}
+	}
+	slices.SortFunc(sessions, func(a, b *store.Session) int { return b.StartedAt.Compare(a.StartedAt) })
+	for _, sess := range sessions {
+		if sess.Outcome != store.OutcomeInProgress && sess.Outcome != store.OutcomeAborted && sess.Outcome != store.OutcomeFailed {
+			// Committed since.
+			return nil
+		}
+		if len(sess.Questions) > 0 || len(sess.Drafts) > 0 {
+			return sess
+		}
+		// Runs that stopped before asking anything don't count.
+	}
+	return nil
+}
+
+// restorePrevious picks up the previous session where it stopped, in place
+// of asking the provider again: at its latest draft, at the first question
+// left unanswered, or at writing the message from its answers.
+func (m Model) restorePrevious() (tea.Model, tea.Cmd) {
+	prev := m.Previous
+	m.Previous = nil
+	m.Questions = prev.Questions
+	m.Answers = maps.Clone(prev.Answers)
+	if m.Answers == nil {
+		m.Answers = make(map[string]string)
+	}
+	m.saveSession()
+
+	if n := len(prev.Drafts); n > 0 {
+		// The latest draft is recorded again as it's reviewed.
+		if m.Session != nil {
+			m.Session.Drafts = slices.Clone(prev.Drafts[:n-1])
+		}
+		return m.Update(commitMsgGeneratedMsg{Message: prev.Drafts[n-1]})
+	}
+	m.CurrentQIdx = slices.IndexFunc(m.Questions, func(q string) bool { return m.Answers[q] == "" })
+	if m.CurrentQIdx < 0 {
+		m.CurrentQIdx = len(m.Questions)
+		m.State = StateGenerating
+		return m, generateCommitMsgCmd(m.Requests, m.AIClient, m.Diff, m.History, m.HistoryCtx, m.Answers)
+	}
+	m.State = StateQuestioning
+	m.TextArea.Reset()
+	m.TextArea.Focus()
+	return m, nil
+}
+
+func (m Model) updateResume(msg tea.Msg) (tea.Model, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+	switch keyMsg.String() {
+	case "y", "enter":
+		// Resuming goes through the usual checks before anything is sent.
+		m.Critiquing = false
+		m.Splitting = false
+		return m.begin()
+	case "n", "esc":
+		m.Previous = nil
+		m.State = StateWelcome
+	case "q":
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+func (m Model) viewResume() string {
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(theme.Accent)
+	infoStyle := lipgloss.NewStyle().Foreground(theme.Muted)
+	subjectStyle := lipgloss.NewStyle().Bold(true)
+
+	prev := m.Previous
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n %s\n\n", titleStyle.Render("Resume Previous Session?"))
+	fmt.Fprintf(&b, " A session for these staged changes ended unfinished %s.\n\n", ago(prev.StartedAt))
+	if len(prev.Questions) > 0 {
+		answered := 0
+		for _, q := range prev.Questions {
+			if prev.Answers[q] != "" {
+				answered++
+			}
+		}
+		fmt.Fprintf(&b, " • %d of %d questions answered\n", answered, len(prev.Questions))
+	}
+	if subject := prev.Subject(); subject != "" {
+		fmt.Fprintf(&b, " • Draft message: %s\n", subjectStyle.Render(subject))
+	}
+	b.WriteString("\n y. Resume where it stopped (Recommended)\n")
+	b.WriteString(" n. Start over\n")
+	b.WriteString("\n " + infoStyle.Render("(enter to resume, esc to start over)") + "\n")
+	return b.String()
+}
+
+// ago describes how long ago t was, roughly.
+func ago(t time.Time) string {
+	d := time.Since(t)
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%d minutes ago", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%d hours ago", int(d.Hours()))
+	}
+	return t.Format("on Jan 2")
+}
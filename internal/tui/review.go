@@ -0,0 +1,228 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/arpxspace/smartcommit/internal/ai"
+	"github.com/arpxspace/smartcommit/internal/git"
+	"github.com/arpxspace/smartcommit/internal/guard"
+	"github.com/arpxspace/smartcommit/internal/store"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// enterReview shows the generated message for the user to accept or rework.
+func (m Model) enterReview() (tea.Model, tea.Cmd) {
+	m.State = StateReview
+	m.ReviewFeedback = false
+	m.RewriteErr = nil
+	m.TextArea.Placeholder = answerPlaceholder
+	m.TextArea.Blur()
+	m.Viewport.Height = max(m.Height-13, 5)
+	m.Viewport.SetContent(renderFlagged(m.CommitMsg, m.Width, m.Config.Vague()))
+	m.Viewport.GotoTop()
+	return m.judge()
+}
+
+func (m Model) updateReview(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	if m.ReviewFeedback {
+		if keyMsg, ok := msg.(tea.KeyMsg); ok {
+			switch keyMsg.Type {
+			case tea.KeyEnter:
+				feedback := strings.TrimSpace(m.TextArea.Value())
+				m.ReviewFeedback = false
+				m.TextArea.Reset()
+				m.TextArea.Blur()
+				m.State = StateGenerating
+				// Asked for another attempt, so a cached draft won't do.
+				return m, generateCommitMsgCmd(ai.Fresh(m.Requests), m.AIClient, m.Diff, m.History, m.HistoryCtx, m.regenerationAnswers(feedback))
+			case tea.KeyEsc:
+				m.TextArea.Reset()
+				return m.enterReview()
+			}
+		}
+		m.TextArea, cmd = m.TextArea.Update(msg)
+		return m, cmd
+	}
+	if m.CoAuthorPicker != nil {
+		if keyMsg, ok := msg.(tea.KeyMsg); ok {
+			return m.updateCoAuthorPicker(keyMsg)
+		}
+		return m, nil
+	}
+	if _, ok := msg.(tea.WindowSizeMsg); ok {
+		m.Viewport.Height = max(m.Height-13, 5)
+		m.Viewport.SetContent(renderFlagged(m.CommitMsg, m.Width, m.Config.Vague()))
+		return m, nil
+	}
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok || m.Rewriting {
+		// The message is left alone while a sentence is being rewritten.
+		m.Viewport, cmd = m.Viewport.Update(msg)
+		return m, cmd
+	}
+	switch {
+	case key.Matches(keyMsg, keys.Accept):
+		if m.Config.CheckMessage(m.CommitMsg) != nil {
+			// The view explains the violation; regenerate or edit first.
+			return m, nil
+		}
+		// Accept: commit as shown, without the editor. Trailers are restored
+		// if an edit dropped them.
+		m.CommitMsg = m.context().WithTrailers(m.CommitMsg)
+		return m.commitAsIs(m.CommitMsg)
+	case key.Matches(keyMsg, keys.Edit):
+		// Finish in the editor, then commit.
+		m.CommitMsg = m.context().WithTrailers(m.CommitMsg)
+		return m.commitEditing(m.CommitMsg)
+	case key.Matches(keyMsg, keys.EditHere):
+		return m.editInReview()
+	case key.Matches(keyMsg, keys.Regenerate):
+		// Ask how the next attempt should differ; enter alone just retries.
+		m.ReviewFeedback = true
+		m.TextArea.Reset()
+		m.TextArea.Placeholder = "Optional: what should change? (e.g. \"be shorter\")"
+		m.TextArea.SetHeight(1)
+		m.TextArea.Focus()
+		return m, nil
+	case key.Matches(keyMsg, keys.CoAuthors):
+		return m.openCoAuthorPicker()
+	case key.Matches(keyMsg, keys.Specific):
+		return m.rewriteVague()
+	case key.Matches(keyMsg, keys.ChangeAnswers):
+		if len(m.Questions) == 0 {
+			return m, nil
+		}
+		// Walk back through the questions with the previous answers filled in.
+		m.CurrentQIdx = 0
+		m.State = StateQuestioning
+		m.TextArea.Reset()
+		m.TextArea.SetHeight(defaultTextAreaHeight)
+		m.TextArea.SetValue(m.Answers[m.Questions[0]])
+		m.TextArea.Focus()
+		return m, nil
+	case key.Matches(keyMsg, keys.Abort):
+		m.finishSession(store.OutcomeAborted)
+		return m, tea.Quit
+	}
+	m.Viewport, cmd = m.Viewport.Update(msg)
+	return m, cmd
+}
+
+// regenerationAnswers is the user's answers plus, when feedback is given,
+// the draft being rejected and what to change about it.
+func (m Model) regenerationAnswers(feedback string) map[string]string {
+	if feedback == "" {
+		return m.Answers
+	}
+	answers := make(map[string]string, len(m.Answers)+2)
+	for q, a := range m.Answers {
+		answers[q] = a
+	}
+	answers["What did the previous draft of the message say?"] = m.CommitMsg
+	answers["How should this message differ from the previous draft? (Follow this instruction.)"] = feedback
+	return answers
+}
+
+func (m Model) viewReview() string {
+	titleStyle := lipgloss.NewStyle().Bold(true)
+	infoStyle := lipgloss.NewStyle().Foreground(theme.Muted)
+
+	if m.ReviewFeedback {
+		return fmt.Sprintf("\n %s\n\n%s\n\n%s\n\n %s\n",
+			titleStyle.Render("Regenerate Commit Message"),
+			m.Viewport.View(),
+			m.TextArea.View(),
+			infoStyle.Render("(enter to regenerate, esc to cancel)"),
+		)
+	}
+	if m.CoAuthorPicker != nil {
+		return m.viewCoAuthorPicker()
+	}
+	violation := ""
+	if err := m.Config.CheckMessage(m.CommitMsg); err != nil {
+		errorStyle := lipgloss.NewStyle().Foreground(theme.Error)
+		violation = " " + errorStyle.Render("✗ "+err.Error()) + "\n " + infoStyle.Render(fmt.Sprintf("Press %s to regenerate or %s to fix it.", keyName(keys.Regenerate), keyName(keys.EditHere))) + "\n\n"
+	} else {
+		// Form problems left after the fixes are worth a look but don't block.
+		warnStyle := lipgloss.NewStyle().Foreground(theme.Warning)
+		if !m.Config.DisableGuardrails {
+			for _, problem := range guard.Check(m.CommitMsg, m.Config.Conventions.MaxSubjectLength) {
+				violation += " " + warnStyle.Render("! "+problem) + "\n"
+			}
+		}
+		if len(m.Fixes) > 0 {
+			violation += " " + infoStyle.Render("Fixed: "+strings.Join(m.Fixes, "; ")) + "\n"
+		}
+		violation += m.viewVague()
+		if violation != "" {
+			violation += "\n"
+		}
+	}
+
+	accept, edit := m.finishKeys()
+	specific, answers := keys.Specific, keys.ChangeAnswers
+	specific.SetEnabled(len(m.vague()) > 0)
+	answers.SetEnabled(len(m.Questions) > 0)
+	help := hint(accept, keys.EditHere, edit, keys.Regenerate, keys.CoAuthors, specific, answers, keys.Abort, keys.Help)
+	return fmt.Sprintf("\n %s\n\n%s\n\n%s\n%s %s\n",
+		titleStyle.Render("Review Commit Message"),
+		m.Viewport.View(),
+		m.viewQuality(),
+		violation,
+		infoStyle.Render(help),
+	)
+}
+
+// renderMessage lays out a commit message with its subject highlighted.
+func renderMessage(message string, width int) string {
+	return renderFlagged(message, width, nil)
+}
+
+// renderFlagged lays out a commit message with its subject highlighted and
+// the vague words in words marked.
+func renderFlagged(message string, width int, words []string) string {
+	subjectStyle := lipgloss.NewStyle().Bold(true).Foreground(theme.Accent)
+	bodyStyle := lipgloss.NewStyle().Width(max(width-4, 40))
+
+	message = strings.TrimSpace(message)
+	spans := guard.Vague(message, words)
+	subject, body, _ := strings.Cut(message, "\n")
+	out := " " + markVague(subject, 0, spans, func(s string) string { return subjectStyle.Render(s) }) + "\n"
+	if trimmed := strings.TrimSpace(body); trimmed != "" {
+		offset := len(message) - len(strings.TrimLeft(body, " \t\n"))
+		for _, line := range strings.Split(bodyStyle.Render(markVague(trimmed, offset, spans, func(s string) string { return s })), "\n") {
+			out += "\n " + line
+		}
+		out += "\n"
+	}
+	return out
+}
+
+// commitAsIs commits msg without opening the editor or, with --message-only,
+// ends the run with it instead.
+func (m Model) commitAsIs(msg string) (tea.Model, tea.Cmd) {
+	if m.Options.MessageOnly {
+		m.Output = strings.TrimSpace(msg)
+		if m.Session != nil {
+			m.Session.FinalMessage = m.Output
+		}
+		m.finishSession(store.OutcomePrinted)
+		return m, tea.Quit
+	}
+	m.State = StateCommit
+	return m, commitNoEditCmd(msg, m.signArgs()...)
+}
+
+// commitNoEditCmd commits with msg as reviewed, without opening the editor.
+// The terminal is still handed over so hooks can prompt or print.
+func commitNoEditCmd(msg string, args ...string) tea.Cmd {
+	if !git.UsesExec() {
+		return backendCommitCmd(msg, args...)
+	}
+	return execCommit(git.CommitNoEditCmd(msg, args...), msg, false)
+}
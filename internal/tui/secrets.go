@@ -0,0 +1,65 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// proceed starts the chosen mode, first asking what to do with any likely
+// secrets that would otherwise be sent as they are.
+func (m Model) proceed() (tea.Model, tea.Cmd) {
+	sc := m.context()
+	if !sc.Redact && !m.SecretsConfirmed && len(sc.Secrets()) > 0 {
+		m.State = StateSecrets
+		return m, nil
+	}
+	if m.Critiquing {
+		return m.startCritique()
+	}
+	if m.Splitting {
+		return m.startSplit()
+	}
+	return m.startAnalysis()
+}
+
+func (m Model) updateSecrets(msg tea.Msg) (tea.Model, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+
+	switch keyMsg.String() {
+	case "r", "enter":
+		m.RedactSecrets = true
+		m.Diff = m.outgoingDiff()
+		return m.proceed()
+	case "y":
+		// Sending credentials needs an explicit yes; enter redacts.
+		m.SecretsConfirmed = true
+		return m.proceed()
+	case "esc", "q":
+		m.State = StateWelcome
+	}
+	return m, nil
+}
+
+func (m Model) viewSecrets() string {
+	titleStyle := lipgloss.NewStyle().Bold(true)
+	infoStyle := lipgloss.NewStyle().Foreground(theme.Muted)
+	warnStyle := lipgloss.NewStyle().Foreground(theme.Warning)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n %s\n\n", warnStyle.Bold(true).Render("⚠ Possible Secrets Found"))
+	b.WriteString(" These lines of the staged diff look like credentials:\n\n")
+	for _, s := range m.context().Secrets() {
+		fmt.Fprintf(&b, "   %s %s\n", titleStyle.Render(s.Path), infoStyle.Render(fmt.Sprintf("(line %d of its diff): %s", s.Line, s.Kind)))
+	}
+	b.WriteString("\n If one is real, unstage it; a key that was ever committed should be rotated.\n\n")
+	b.WriteString(" r. Redact them and continue (Recommended)\n")
+	b.WriteString(" y. Send them as they are\n\n")
+	b.WriteString(" " + infoStyle.Render("(enter to redact, esc to go back)") + "\n")
+	return b.String()
+}
@@ -0,0 +1,125 @@
+package tui
+
+import (
+	"time"
+
+	"github.com/arpxspace/smartcommit/internal/ai"
+	"github.com/arpxspace/smartcommit/internal/config"
+	"github.com/arpxspace/smartcommit/internal/git"
+	"github.com/arpxspace/smartcommit/internal/store"
+)
+
+// startSession begins recording this run in the session store.
+func (m *Model) startSession() {
+	if m.Config.DisableStore {
+		return
+	}
+	if m.Session != nil {
+		// Prerequisites are re-checked after reconfiguring the provider.
+		m.Session.Provider = string(m.Config.Provider)
+		m.Session.Model = m.AIClient.Model()
+		m.saveSession()
+		return
+	}
+	m.Session = &store.Session{
+		StartedAt: time.Now(),
+		RepoRoot:  m.RepoRoot,
+		DiffHash:  store.HashDiff(m.Diff),
+		Provider:  string(m.Config.Provider),
+		Model:     m.AIClient.Model(),
+		Outcome:   store.OutcomeInProgress,
+		Timings:   make(map[string]time.Duration),
+	}
+	m.StageStart = time.Now()
+	m.saveSession()
+}
+
+// markStage records how long the stage that just finished took.
+func (m *Model) markStage(stage string) {
+	if m.Session != nil {
+		m.Session.Timings[stage] += time.Since(m.StageStart)
+	}
+	m.StageStart = time.Now()
+}
+
+// addDraft records a generated message.
+func (m *Model) addDraft(msg string) {
+	if m.Session == nil {
+		return
+	}
+	m.Session.Drafts = append(m.Session.Drafts, msg)
+	m.saveSession()
+}
+
+// finishSession records how the session ended. For successful commits the
+// final message is read back from HEAD so edits made in the editor are kept.
+func (m *Model) finishSession(outcome store.Outcome) {
+	if m.Session == nil || m.Session.Outcome != store.OutcomeInProgress {
+		return
+	}
+	m.Session.Outcome = outcome
+	m.Session.FinishedAt = time.Now()
+	if outcome == store.OutcomeCommitted || outcome == store.OutcomeManual {
+		if msg, err := git.HeadMessage(); err == nil {
+			m.Session.FinalMessage = msg
+		}
+	}
+	m.saveSession()
+}
+
+// saveSession writes the session to the store. Failures are ignored: losing
+// a history record should never get in the way of committing.
+func (m *Model) saveSession() {
+	if m.Session == nil {
+		return
+	}
+	m.Session.DiffHash = store.HashDiff(m.Diff)
+	m.Session.Questions = m.Questions
+	m.Session.Answers = m.Answers
+	if m.AIClient != nil {
+		u := m.AIClient.Usage()
+		m.Session.PromptTokens = u.PromptTokens
+		m.Session.CompletionTokens = u.CompletionTokens
+	}
+
+	s, err := store.Open()
+	if err != nil {
+		return
+	}
+	defer s.Close()
+	s.Save(m.Session)
+}
+
+// newClient creates the provider client for cfg, caching its responses in
+// the session store unless that's turned off. The mock provider costs
+// nothing, so its responses aren't cached.
+func newClient(cfg *config.Config) (ai.Provider, error) {
+	client, err := ai.NewClient(cfg)
+	if err != nil || cfg.DisableStore || cfg.DisableCache || cfg.Provider == config.ProviderMock {
+		return client, err
+	}
+	return ai.Cached(client, responseCache{}), nil
+}
+
+// responseCache keeps provider responses in the session store, opening it
+// for each lookup like the rest of the TUI does.
+type responseCache struct{}
+
+func (responseCache) Response(key string) (string, bool) {
+	s, err := store.Open()
+	if err != nil {
+		return "", false
+	}
+	defer s.Close()
+	value, ok, err := s.Response(key)
+	return value, ok && err == nil
+}
+
+func (responseCache) SaveResponse(key, value string) {
+	s, err := store.Open()
+	if err != nil {
+		return
+	}
+	defer s.Close()
+	s.SaveResponse(key, value) // Ignore error, the cache only saves requests
+}
@@ -0,0 +1,128 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// signingFailedMsg reports a commit git couldn't sign, with what it needs
+// to be tried again.
+type signingFailedMsg struct {
+	Err error
+	// Output is what git printed to stderr.
+	Output string
+	// Format and Key are git's gpg.format and user.signingkey.
+	Format string
+	Key    string
+	// Message is the message committed with, and Edit whether it was
+	// opened in the editor first.
+	Message string
+	Edit    bool
+}
+
+// error describes the failure with git's own explanation, for screens
+// without room for guidance.
+func (f signingFailedMsg) error() error {
+	return fmt.Errorf("%w: %s", f.Err, strings.TrimSpace(f.Output))
+}
+
+// signArgs returns the git commit arguments for signing: none once the user
+// has chosen to commit unsigned, or else what the config asks for.
+func (m Model) signArgs() []string {
+	if m.Unsigned {
+		return []string{"--no-gpg-sign"}
+	}
+	if m.Config == nil {
+		return nil
+	}
+	return m.Config.SignArgs()
+}
+
+// updateSigningFailed handles the ways out of a failed signature: trying
+// again once the key is sorted out, committing unsigned, or going back to
+// the message.
+func (m Model) updateSigningFailed(msg tea.Msg) (tea.Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+	failed := m.Signing
+	switch key.String() {
+	case "r", "R":
+	case "u", "U":
+		m.Unsigned = true
+	case "e", "E":
+		if m.CommitMsg == "" {
+			return m, nil
+		}
+		m.Signing = nil
+		m.resumeSession()
+		return m.enterReview()
+	default:
+		return m, nil
+	}
+	m.Signing = nil
+	return m.recommit(failed.Message, failed.Edit)
+}
+
+// viewSigningFailed explains why git couldn't sign the commit and how to
+// fix it for the configured signing format.
+func (m Model) viewSigningFailed() string {
+	errorStyle := lipgloss.NewStyle().Foreground(theme.Error).Bold(true)
+	infoStyle := lipgloss.NewStyle().Foreground(theme.Muted)
+	cmdStyle := lipgloss.NewStyle().Foreground(theme.Command).Bold(true)
+
+	f := m.Signing
+	var guidance []string
+	switch f.Format {
+	case "ssh":
+		if f.Key == "" {
+			guidance = append(guidance, "No SSH key is set to sign with. Set one with:", cmdStyle.Render("git config --global user.signingkey ~/.ssh/id_ed25519.pub"))
+		} else {
+			guidance = append(guidance, fmt.Sprintf("Check that %s exists and its key is loaded in your agent:", f.Key), cmdStyle.Render("ssh-add -l"))
+		}
+	case "x509":
+		guidance = append(guidance, "Check that gpgsm is installed and can find your certificate:", cmdStyle.Render("gpgsm --list-secret-keys"))
+	default:
+		guidance = append(guidance, "Check that gpg is installed and has your secret key:", cmdStyle.Render("gpg --list-secret-keys --keyid-format=long"))
+		if f.Key == "" {
+			guidance = append(guidance, "", "To sign with a particular key, set it with:", cmdStyle.Render("git config --global user.signingkey <key-id>"))
+		}
+		guidance = append(guidance, "", "If gpg can't ask for your passphrase, add this to your shell profile:", cmdStyle.Render("export GPG_TTY=$(tty)"))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n %s Couldn't sign the commit.\n\n", errorStyle.Render("Error:"))
+	fmt.Fprintf(&b, " Git is set to sign commits with %s, but signing failed.\n\n", formatName(f.Format))
+	for _, line := range guidance {
+		if line == "" {
+			b.WriteString("\n")
+			continue
+		}
+		fmt.Fprintf(&b, "   %s\n", line)
+	}
+	b.WriteString("\n")
+	for _, line := range strings.Split(strings.TrimSpace(f.Output), "\n") {
+		fmt.Fprintf(&b, " %s\n", infoStyle.Render(line))
+	}
+	b.WriteString("\n Press 'r' to retry once it's fixed.\n Press 'u' to commit without signing.\n")
+	if m.CommitMsg != "" {
+		b.WriteString(" Press 'e' to edit the message.\n")
+	}
+	b.WriteString(" Press 'q' to quit.\n")
+	return b.String()
+}
+
+// formatName names a gpg.format for people.
+func formatName(format string) string {
+	switch format {
+	case "ssh":
+		return "an SSH key"
+	case "x509":
+		return "an X.509 certificate"
+	}
+	return "a GPG key"
+}
@@ -0,0 +1,368 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/arpxspace/smartcommit/internal/ai"
+	"github.com/arpxspace/smartcommit/internal/diff"
+	"github.com/arpxspace/smartcommit/internal/git"
+	"github.com/arpxspace/smartcommit/internal/provenance"
+	"github.com/arpxspace/smartcommit/internal/store"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// splitPlan is a staged change being committed as several logical commits.
+type splitPlan struct {
+	Reason     string
+	PromptHash string
+	Hunks      []diff.Hunk
+	Groups     []splitGroup
+	// Started is set once the index has been cleared to commit the groups
+	// one by one; from then on, leaving must restage what's left.
+	Started bool
+	Current int
+	Cursor  int
+}
+
+// splitGroup is one proposed commit. Hunks index into splitPlan.Hunks.
+type splitGroup struct {
+	Message  string
+	Hunks    []int
+	Selected map[int]bool
+}
+
+type splitProposedMsg struct {
+	Response   *ai.SplitResponse
+	PromptHash string
+}
+
+type splitStartedMsg struct{}
+
+type splitStagedMsg struct {
+	Message string
+	Edit    bool
+}
+
+type splitCommittedMsg struct {
+	ProvenanceErr error
+	Signed        bool
+}
+
+// startSplit asks the provider whether the staged change should be split.
+func (m Model) startSplit() (tea.Model, tea.Cmd) {
+	m.State = StateSplitAnalyzing
+	m.SplitNote = ""
+	return m, proposeSplitCmd(m.Requests, m.AIClient, m.context().NumberedHunks())
+}
+
+func proposeSplitCmd(ctx context.Context, client ai.Provider, hunks string) tea.Cmd {
+	return func() tea.Msg {
+		resp, err := client.ProposeSplit(ctx, hunks)
+		if err != nil {
+			return errMsg(err)
+		}
+		return splitProposedMsg{Response: resp, PromptHash: ai.SplitPromptHash(hunks)}
+	}
+}
+
+// newSplitPlan turns the provider's proposal into a plan covering every
+// hunk exactly once: hunks claimed twice stay in the first group, and any
+// left out, including those of excluded files, go in a final group.
+func newSplitPlan(resp *ai.SplitResponse, hunks []diff.Hunk, promptHash string) *splitPlan {
+	plan := &splitPlan{Reason: resp.Reason, PromptHash: promptHash, Hunks: hunks}
+	assigned := make(map[int]bool)
+	for _, g := range resp.Groups {
+		group := splitGroup{Message: formatSplitMessage(g.Subject, g.Body)}
+		for _, i := range g.Hunks {
+			if i >= 0 && i < len(hunks) && !assigned[i] {
+				assigned[i] = true
+				group.Hunks = append(group.Hunks, i)
+			}
+		}
+		if len(group.Hunks) > 0 {
+			plan.Groups = append(plan.Groups, group)
+		}
+	}
+	var rest []int
+	for i := range hunks {
+		if !assigned[i] {
+			rest = append(rest, i)
+		}
+	}
+	if len(rest) > 0 {
+		plan.Groups = append(plan.Groups, splitGroup{Hunks: rest})
+	}
+	for i := range plan.Groups {
+		plan.Groups[i].selectAll()
+	}
+	return plan
+}
+
+func formatSplitMessage(subject, body string) string {
+	if body = strings.TrimSpace(body); body == "" {
+		return strings.TrimSpace(subject)
+	}
+	return strings.TrimSpace(subject) + "\n\n" + body
+}
+
+func (g *splitGroup) selectAll() {
+	g.Selected = make(map[int]bool, len(g.Hunks))
+	for _, i := range g.Hunks {
+		g.Selected[i] = true
+	}
+}
+
+// remaining returns the hunks not yet committed, in diff order.
+func (p *splitPlan) remaining() []diff.Hunk {
+	var idx []int
+	for _, g := range p.Groups[p.Current:] {
+		idx = append(idx, g.Hunks...)
+	}
+	sort.Ints(idx)
+	hunks := make([]diff.Hunk, len(idx))
+	for i, h := range idx {
+		hunks[i] = p.Hunks[h]
+	}
+	return hunks
+}
+
+// splitEdit opens a commit's message in the editor before committing it.
+var splitEdit = bind("edit the message", "e")
+
+func (m Model) updateSplit(msg tea.Msg) (tea.Model, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+	plan := m.Split
+	if !plan.Started {
+		switch {
+		case key.Matches(keyMsg, keys.Select):
+			return m, func() tea.Msg {
+				if err := git.UnstageAll(); err != nil {
+					return errMsg(err)
+				}
+				return splitStartedMsg{}
+			}
+		case key.Matches(keyMsg, keys.Back):
+			m.Split = nil
+			m.State = StateWelcome
+		}
+		return m, nil
+	}
+
+	group := &plan.Groups[plan.Current]
+	switch {
+	case key.Matches(keyMsg, keys.Up):
+		if plan.Cursor > 0 {
+			plan.Cursor--
+		}
+	case key.Matches(keyMsg, keys.Down):
+		if plan.Cursor < len(group.Hunks)-1 {
+			plan.Cursor++
+		}
+	case key.Matches(keyMsg, keys.Toggle):
+		h := group.Hunks[plan.Cursor]
+		group.Selected[h] = !group.Selected[h]
+	case key.Matches(keyMsg, keys.Select, splitEdit):
+		edit := key.Matches(keyMsg, splitEdit) || group.Message == ""
+		if edit && m.useTUIEditor() {
+			// Edit here, then commit with enter as usual.
+			return m.openEditor(group.Message, "save", func(m Model, message string) (tea.Model, tea.Cmd) {
+				m.Split.Groups[m.Split.Current].Message = message
+				m.State = StateSplit
+				return m, nil
+			})
+		}
+		if !edit {
+			if err := m.Config.CheckMessage(group.Message); err != nil {
+				m.SplitNote = err.Error()
+				return m, nil
+			}
+		}
+		message := group.Message
+		if message != "" {
+			rendered, err := m.context().Render(message)
+			if err != nil {
+				m.SplitNote = err.Error()
+				return m, nil
+			}
+			message = rendered
+		}
+		patch := plan.commitSelected()
+		if patch == "" {
+			return m, nil
+		}
+		m.SplitNote = ""
+		return m, func() tea.Msg {
+			if err := git.ApplyCached(patch); err != nil {
+				return errMsg(err)
+			}
+			return splitStagedMsg{Message: message, Edit: edit}
+		}
+	case key.Matches(keyMsg, keys.Back):
+		if err := plan.restage(); err != nil {
+			return m, func() tea.Msg { return errMsg(err) }
+		}
+		m.finishSession(store.OutcomeAborted)
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+// restage puts back in the index whatever hasn't been committed yet, so
+// stopping partway loses nothing.
+func (p *splitPlan) restage() error {
+	if !p.Started {
+		return nil
+	}
+	if patch := diff.Patch(p.remaining()); patch != "" {
+		return git.ApplyCached(patch)
+	}
+	return nil
+}
+
+// commitSelected narrows the current group to its selected hunks, moving
+// the rest to the next group, and returns the patch that stages them.
+func (p *splitPlan) commitSelected() string {
+	group := &p.Groups[p.Current]
+	var keep, moved []int
+	for _, i := range group.Hunks {
+		if group.Selected[i] {
+			keep = append(keep, i)
+		} else {
+			moved = append(moved, i)
+		}
+	}
+	if len(keep) == 0 {
+		return ""
+	}
+	if len(moved) > 0 {
+		if p.Current == len(p.Groups)-1 {
+			p.Groups = append(p.Groups, splitGroup{})
+			group = &p.Groups[p.Current]
+		}
+		next := &p.Groups[p.Current+1]
+		next.Hunks = append(moved, next.Hunks...)
+		sort.Ints(next.Hunks)
+		next.selectAll()
+	}
+	group.Hunks = keep
+	hunks := make([]diff.Hunk, len(keep))
+	for i, h := range keep {
+		hunks[i] = p.Hunks[h]
+	}
+	return diff.Patch(hunks)
+}
+
+// splitCommitCmd commits the staged group, in the editor when asked or when
+// there's no message yet.
+func (m Model) splitCommitCmd(message string, edit bool) tea.Cmd {
+	if message != "" {
+		message = m.context().WithTrailers(message)
+	}
+	cmd := git.CommitNoEditCmd(message, m.signArgs()...)
+	if edit {
+		cmd = git.CommitCmd(message, m.signArgs()...)
+	}
+	record := m.Config.Provenance
+	provider, model, promptHash := string(m.Config.Provider), m.AIClient.Model(), m.Split.PromptHash
+	// The group is already staged; if the commit fails, restage the rest.
+	later := &splitPlan{Hunks: m.Split.Hunks, Groups: m.Split.Groups, Current: m.Split.Current + 1, Started: true}
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		if err != nil {
+			later.restage() // Ignore error, the commit failure is what matters
+			return errMsg(err)
+		}
+		var provErr error
+		if record {
+			provErr = provenance.WriteHead(provider, model, promptHash)
+		}
+		return splitCommittedMsg{ProvenanceErr: provErr, Signed: git.HeadSigned()}
+	})
+}
+
+func (m Model) viewSplit() string {
+	titleStyle := lipgloss.NewStyle().Bold(true)
+	infoStyle := lipgloss.NewStyle().Foreground(theme.Muted)
+	errorStyle := lipgloss.NewStyle().Foreground(theme.Error)
+	subjectStyle := lipgloss.NewStyle().Bold(true).Foreground(theme.Accent)
+	cursorStyle := lipgloss.NewStyle().Foreground(theme.Accent).Bold(true)
+
+	plan := m.Split
+	var b strings.Builder
+	if !plan.Started {
+		b.WriteString("\n " + titleStyle.Render("Suggested Split") + "\n\n")
+		b.WriteString(" " + infoStyle.Render(plan.Reason) + "\n\n")
+		for i, g := range plan.Groups {
+			subject, _, _ := strings.Cut(g.Message, "\n")
+			if subject == "" {
+				subject = infoStyle.Render("(remaining changes, you write the message)")
+			} else {
+				subject = subjectStyle.Render(subject)
+			}
+			fmt.Fprintf(&b, " %d. %s\n", i+1, subject)
+			for _, path := range plan.paths(g.Hunks) {
+				b.WriteString("      " + infoStyle.Render(path) + "\n")
+			}
+		}
+		b.WriteString("\n " + infoStyle.Render("enter: commit these one by one · esc: back") + "\n")
+		return b.String()
+	}
+
+	group := plan.Groups[plan.Current]
+	fmt.Fprintf(&b, "\n %s\n\n", titleStyle.Render(fmt.Sprintf("Commit %d of %d", plan.Current+1, len(plan.Groups))))
+	// The whole series, so it's clear how far along it is.
+	for i, g := range plan.Groups {
+		subject, _, _ := strings.Cut(g.Message, "\n")
+		if subject == "" {
+			subject = "(remaining changes)"
+		}
+		switch {
+		case i < plan.Current:
+			b.WriteString(" " + infoStyle.Render("✓ "+subject) + "\n")
+		case i == plan.Current:
+			b.WriteString(" " + subjectStyle.Render("▸ "+subject) + "\n")
+		default:
+			b.WriteString("   " + subject + "\n")
+		}
+	}
+	b.WriteString("\n")
+	if group.Message == "" {
+		b.WriteString(" " + infoStyle.Render("(no message yet; the editor will open)") + "\n")
+	} else {
+		b.WriteString(renderMessage(group.Message, m.Width))
+	}
+	b.WriteString("\n")
+	for i, h := range group.Hunks {
+		cursor := "  "
+		if i == plan.Cursor {
+			cursor = cursorStyle.Render("> ")
+		}
+		fmt.Fprintf(&b, " %s%s %s\n", cursor, checkbox(group.Selected[h]), plan.Hunks[h].Title())
+	}
+	if m.SplitNote != "" {
+		b.WriteString("\n " + errorStyle.Render("✗ "+m.SplitNote) + "\n")
+	}
+	b.WriteString("\n " + infoStyle.Render("space: toggle hunk (unselected ones move to the next commit) · enter: commit · e: edit message · esc: stop and restage the rest") + "\n")
+	return b.String()
+}
+
+// paths returns the distinct files the hunks touch.
+func (p *splitPlan) paths(hunks []int) []string {
+	var paths []string
+	seen := make(map[string]bool)
+	for _, i := range hunks {
+		if path := p.Hunks[i].Path; !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
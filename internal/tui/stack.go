@@ -0,0 +1,25 @@
+package tui
+
+import (
+	"github.com/arpxspace/smartcommit/internal/git"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// startStack stages all uncommitted work, untracked files included, and
+// has it split into a series of commits, as if it had been staged for `s`.
+func (m Model) startStack() (tea.Model, tea.Cmd) {
+	m.Critiquing = false
+	m.Splitting = true
+	m.Resume = true
+	m.State = StateLoading
+	return m, m.stackCmd
+}
+
+// stackCmd stages everything, then reads the staged changes afresh.
+func (m Model) stackCmd() tea.Msg {
+	if err := git.StageAll(); err != nil {
+		return errMsg(err)
+	}
+	return m.checkPrerequisitesCmd()
+}
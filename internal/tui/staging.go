@@ -0,0 +1,284 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/arpxspace/smartcommit/internal/diff"
+	"github.com/arpxspace/smartcommit/internal/git"
+	"github.com/arpxspace/smartcommit/internal/store"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+type stagingSection int
+
+const (
+	sectionStaged stagingSection = iota
+	sectionUnstaged
+	sectionUntracked
+)
+
+// stagingState is the working tree as shown on the staging screen.
+type stagingState struct {
+	Staged    []diff.File
+	Unstaged  []diff.File
+	Untracked []string
+	// Expanded holds the files whose hunks are listed, keyed by stagingRow.key.
+	Expanded map[string]bool
+	Cursor   int
+	Err      error
+}
+
+// stagingRow is a file, or one hunk of a file, on the staging screen.
+type stagingRow struct {
+	Section stagingSection
+	File    diff.File
+	Hunk    *diff.Hunk
+}
+
+func (r stagingRow) key() string {
+	return fmt.Sprintf("%d:%s", r.Section, r.File.Path)
+}
+
+type nothingStagedMsg struct{}
+
+type stagingLoadedMsg struct {
+	Staged    []diff.File
+	Unstaged  []diff.File
+	Untracked []string
+	Err       error
+}
+
+// startStaging shows the staging screen so the user can pick what to commit
+// without leaving the tool.
+func (m Model) startStaging() (tea.Model, tea.Cmd) {
+	m.State = StateStaging
+	if m.Staging == nil {
+		m.Staging = &stagingState{Expanded: make(map[string]bool)}
+	}
+	return m, loadStagingCmd(nil)
+}
+
+// loadStagingCmd runs op, if any, then reads the working tree afresh.
+func loadStagingCmd(op func() error) tea.Cmd {
+	return func() tea.Msg {
+		var opErr error
+		if op != nil {
+			opErr = op()
+		}
+		staged, err := git.GetStagedDiff()
+		if err != nil {
+			return errMsg(err)
+		}
+		unstaged, err := git.GetUnstagedDiff()
+		if err != nil {
+			return errMsg(err)
+		}
+		untracked, err := git.UntrackedFiles()
+		if err != nil {
+			return errMsg(err)
+		}
+		return stagingLoadedMsg{
+			Staged:    diff.Parse(staged),
+			Unstaged:  diff.Parse(unstaged),
+			Untracked: untracked,
+			Err:       opErr,
+		}
+	}
+}
+
+// rows lists the files of every section, each followed by its hunks when expanded.
+func (s *stagingState) rows() []stagingRow {
+	var rows []stagingRow
+	add := func(section stagingSection, files []diff.File) {
+		for _, f := range files {
+			row := stagingRow{Section: section, File: f}
+			rows = append(rows, row)
+			if !s.Expanded[row.key()] {
+				continue
+			}
+			for _, h := range stagingHunks(f) {
+				rows = append(rows, stagingRow{Section: section, File: f, Hunk: &h})
+			}
+		}
+	}
+	add(sectionStaged, s.Staged)
+	add(sectionUnstaged, s.Unstaged)
+	for _, path := range s.Untracked {
+		rows = append(rows, stagingRow{Section: sectionUntracked, File: diff.File{Path: path, OldPath: path}})
+	}
+	return rows
+}
+
+// stagingHunks returns the hunks of f that can be staged on their own;
+// changes without any, such as binary files, only move as a whole file.
+func stagingHunks(f diff.File) []diff.Hunk {
+	var hunks []diff.Hunk
+	for _, h := range f.Hunks() {
+		if h.Content != "" {
+			hunks = append(hunks, h)
+		}
+	}
+	return hunks
+}
+
+// toggle moves the row to the other side of the index.
+func toggle(row stagingRow) func() error {
+	if row.Hunk != nil {
+		patch := diff.Patch([]diff.Hunk{*row.Hunk})
+		if row.Section == sectionStaged {
+			return func() error { return git.UnapplyCached(patch) }
+		}
+		return func() error { return git.ApplyCached(patch) }
+	}
+	paths := []string{row.File.Path}
+	if row.File.OldPath != "" && row.File.OldPath != row.File.Path {
+		paths = append(paths, row.File.OldPath)
+	}
+	if row.Section == sectionStaged {
+		return func() error { return git.UnstagePaths(paths...) }
+	}
+	return func() error { return git.StagePaths(paths...) }
+}
+
+// Keys for showing a file's hunks and hiding them again.
+var (
+	stagingExpand   = bind("show hunks", "right", "l")
+	stagingCollapse = bind("hide hunks", "left", "h")
+)
+
+func (m Model) updateStaging(msg tea.Msg) (tea.Model, tea.Cmd) {
+	s := m.Staging
+	if loaded, ok := msg.(stagingLoadedMsg); ok {
+		s.Staged, s.Unstaged, s.Untracked, s.Err = loaded.Staged, loaded.Unstaged, loaded.Untracked, loaded.Err
+		s.Cursor = min(s.Cursor, max(len(s.rows())-1, 0))
+		return m, nil
+	}
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+
+	rows := s.rows()
+	switch {
+	case key.Matches(keyMsg, keys.Up):
+		if s.Cursor > 0 {
+			s.Cursor--
+		}
+	case key.Matches(keyMsg, keys.Down):
+		if s.Cursor < len(rows)-1 {
+			s.Cursor++
+		}
+	case key.Matches(keyMsg, stagingExpand):
+		if s.Cursor < len(rows) && rows[s.Cursor].Hunk == nil && len(stagingHunks(rows[s.Cursor].File)) > 0 {
+			s.Expanded[rows[s.Cursor].key()] = true
+		}
+	case key.Matches(keyMsg, stagingCollapse):
+		if s.Cursor < len(rows) {
+			row := rows[s.Cursor]
+			s.Expanded[row.key()] = false
+			// Land on the file row the hunk belonged to.
+			for i, r := range s.rows() {
+				if r.Hunk == nil && r.key() == row.key() {
+					s.Cursor = i
+					break
+				}
+			}
+		}
+	case key.Matches(keyMsg, keys.Toggle):
+		if s.Cursor < len(rows) {
+			return m, loadStagingCmd(toggle(rows[s.Cursor]))
+		}
+	case key.Matches(keyMsg, keys.Select):
+		if len(s.Staged) == 0 {
+			return m, nil
+		}
+		// Start over with what's now staged.
+		m.State = StateLoading
+		return m, m.checkPrerequisitesCmd
+	case key.Matches(keyMsg, keys.Back):
+		m.finishSession(store.OutcomeAborted)
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+func (m Model) viewStaging() string {
+	titleStyle := lipgloss.NewStyle().Bold(true)
+	infoStyle := lipgloss.NewStyle().Foreground(theme.Muted)
+	errorStyle := lipgloss.NewStyle().Foreground(theme.Error)
+	headingStyle := lipgloss.NewStyle().Bold(true).Foreground(theme.Accent)
+	cursorStyle := lipgloss.NewStyle().Foreground(theme.Accent).Bold(true)
+
+	s := m.Staging
+	rows := s.rows()
+	var b strings.Builder
+	b.WriteString("\n " + titleStyle.Render("Stage Changes") + "\n")
+	if len(rows) == 0 {
+		b.WriteString("\n Nothing to commit: the working tree is clean.\n\n " + infoStyle.Render("(q to quit)") + "\n")
+		return b.String()
+	}
+
+	// Keep the list to half the screen, scrolled to the cursor; the preview gets the rest.
+	listHeight := max((m.Height-8)/2, 5)
+	start := max(min(s.Cursor-listHeight/2, len(rows)-listHeight), 0)
+	end := min(start+listHeight, len(rows))
+	headings := map[stagingSection]string{
+		sectionStaged:    "Staged",
+		sectionUnstaged:  "Not staged",
+		sectionUntracked: "Untracked",
+	}
+	for i := start; i < end; i++ {
+		row := rows[i]
+		if i == start || rows[i-1].Section != row.Section {
+			b.WriteString("\n " + headingStyle.Render(headings[row.Section]) + "\n")
+		}
+		cursor := "  "
+		if i == s.Cursor {
+			cursor = cursorStyle.Render("> ")
+		}
+		if row.Hunk != nil {
+			line, _, _ := strings.Cut(row.Hunk.Content, "\n")
+			b.WriteString(" " + cursor + "    " + infoStyle.Render(line) + "\n")
+			continue
+		}
+		label := row.File.Path
+		if row.File.OldPath != "" && row.File.OldPath != row.File.Path {
+			label = row.File.OldPath + " → " + row.File.Path
+		}
+		if n := len(stagingHunks(row.File)); n > 0 && row.Section != sectionUntracked {
+			arrow := "▸"
+			if s.Expanded[row.key()] {
+				arrow = "▾"
+			}
+			label = fmt.Sprintf("%s %s %s", arrow, label, infoStyle.Render(fmt.Sprintf("(%d hunks)", n)))
+		} else {
+			label = "  " + label
+		}
+		b.WriteString(" " + cursor + label + "\n")
+	}
+
+	if s.Cursor < len(rows) {
+		row := rows[s.Cursor]
+		preview := row.File.Content
+		if row.Hunk != nil {
+			preview = row.Hunk.Content
+		}
+		if preview != "" {
+			lines := strings.Split(strings.TrimRight(preview, "\n"), "\n")
+			if room := max(m.Height-listHeight-12, 3); len(lines) > room {
+				lines = append(lines[:room], "...")
+			}
+			b.WriteString("\n" + infoStyle.Render(strings.Join(lines, "\n")) + "\n")
+		}
+	}
+	if s.Err != nil {
+		b.WriteString("\n " + errorStyle.Render("✗ "+s.Err.Error()) + "\n")
+	}
+	help := "space: stage/unstage · →/←: show/hide hunks · enter: continue with staged changes · q: quit"
+	b.WriteString("\n " + infoStyle.Render(help) + "\n")
+	return b.String()
+}
@@ -0,0 +1,48 @@
+package tui
+
+import (
+	"context"
+
+	"github.com/arpxspace/smartcommit/internal/ai"
+	"github.com/arpxspace/smartcommit/internal/staged"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type partSummarizedMsg struct {
+	Summary string
+}
+
+// startSummarizing splits a change too large for one request into parts
+// and summarizes them one at a time; the summaries then stand in for the diff.
+func (m Model) startSummarizing() (tea.Model, tea.Cmd) {
+	m.Parts = m.context().Parts()
+	if len(m.Parts) > staged.MaxParts {
+		m.State = StateDiffTooLarge
+		return m, nil
+	}
+	m.Summaries = nil
+	m.State = StateSummarizing
+	return m, summarizePartCmd(m.Requests, m.AIClient, m.Parts, 0)
+}
+
+func (m Model) updateSummarizing(msg partSummarizedMsg) (tea.Model, tea.Cmd) {
+	m.Summaries = append(m.Summaries, msg.Summary)
+	if len(m.Summaries) < len(m.Parts) {
+		return m, summarizePartCmd(m.Requests, m.AIClient, m.Parts, len(m.Summaries))
+	}
+	m.markStage("summaries")
+	m.Diff = m.context().Summarized(m.Summaries)
+	m.State = StateHistoryAnalysis
+	return m, analyzeHistoryCmd(m.Requests, m.AIClient, m.context(), m.Diff, m.History, m.Config.AnalyzeHistory(), m.Config.Questions())
+}
+
+func summarizePartCmd(ctx context.Context, client ai.Provider, parts []string, i int) tea.Cmd {
+	return func() tea.Msg {
+		summary, err := client.SummarizeDiff(ctx, parts[i], i+1, len(parts))
+		if err != nil {
+			return errMsg(err)
+		}
+		return partSummarizedMsg{Summary: summary}
+	}
+}
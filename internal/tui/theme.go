@@ -0,0 +1,172 @@
+package tui
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/arpxspace/smartcommit/internal/config"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Theme holds the colors the TUI draws with, by role.
+type Theme struct {
+	// Accent marks headings, the cursor, the spinner, and keywords in diffs.
+	Accent lipgloss.TerminalColor
+	// Muted is for hints, help lines, and comments in diffs.
+	Muted lipgloss.TerminalColor
+	// Warning is for problems that don't stop a commit.
+	Warning lipgloss.TerminalColor
+	// Error is for failures and removed lines in diffs.
+	Error lipgloss.TerminalColor
+	// Success is for what's done and added lines in diffs.
+	Success lipgloss.TerminalColor
+	// Command is for commands to run.
+	Command lipgloss.TerminalColor
+	// Border frames boxes.
+	Border lipgloss.TerminalColor
+	// DiffHunk is for hunk headers in diffs.
+	DiffHunk lipgloss.TerminalColor
+	// SyntaxString and SyntaxNumber are for literals in diffs.
+	SyntaxString lipgloss.TerminalColor
+	SyntaxNumber lipgloss.TerminalColor
+}
+
+// darkTheme is drawn for dark backgrounds, in the TUI's original colors.
+var darkTheme = Theme{
+	Accent:       lipgloss.Color("205"),
+	Muted:        lipgloss.Color("241"),
+	Warning:      lipgloss.Color("214"),
+	Error:        lipgloss.Color("196"),
+	Success:      lipgloss.Color("42"),
+	Command:      lipgloss.Color("12"),
+	Border:       lipgloss.Color("62"),
+	DiffHunk:     lipgloss.Color("39"),
+	SyntaxString: lipgloss.Color("180"),
+	SyntaxNumber: lipgloss.Color("141"),
+}
+
+// lightTheme is drawn for light backgrounds, with darker shades that keep
+// their contrast on white.
+var lightTheme = Theme{
+	Accent:       lipgloss.Color("162"),
+	Muted:        lipgloss.Color("238"),
+	Warning:      lipgloss.Color("130"),
+	Error:        lipgloss.Color("160"),
+	Success:      lipgloss.Color("28"),
+	Command:      lipgloss.Color("19"),
+	Border:       lipgloss.Color("61"),
+	DiffHunk:     lipgloss.Color("25"),
+	SyntaxString: lipgloss.Color("94"),
+	SyntaxNumber: lipgloss.Color("91"),
+}
+
+// highContrastTheme uses the terminal's basic colors, at their brightest
+// against the background, and draws muted text at full strength.
+var highContrastTheme = Theme{
+	Accent:       lipgloss.AdaptiveColor{Light: "5", Dark: "13"},
+	Muted:        lipgloss.AdaptiveColor{Light: "0", Dark: "15"},
+	Warning:      lipgloss.AdaptiveColor{Light: "130", Dark: "11"},
+	Error:        lipgloss.AdaptiveColor{Light: "1", Dark: "9"},
+	Success:      lipgloss.AdaptiveColor{Light: "22", Dark: "10"},
+	Command:      lipgloss.AdaptiveColor{Light: "4", Dark: "14"},
+	Border:       lipgloss.AdaptiveColor{Light: "0", Dark: "15"},
+	DiffHunk:     lipgloss.AdaptiveColor{Light: "4", Dark: "14"},
+	SyntaxString: lipgloss.AdaptiveColor{Light: "130", Dark: "11"},
+	SyntaxNumber: lipgloss.AdaptiveColor{Light: "5", Dark: "13"},
+}
+
+// adaptiveTheme is the default: lightTheme on light backgrounds and
+// darkTheme on dark ones.
+var adaptiveTheme = Theme{
+	Accent:       adapt(lightTheme.Accent, darkTheme.Accent),
+	Muted:        adapt(lightTheme.Muted, darkTheme.Muted),
+	Warning:      adapt(lightTheme.Warning, darkTheme.Warning),
+	Error:        adapt(lightTheme.Error, darkTheme.Error),
+	Success:      adapt(lightTheme.Success, darkTheme.Success),
+	Command:      adapt(lightTheme.Command, darkTheme.Command),
+	Border:       adapt(lightTheme.Border, darkTheme.Border),
+	DiffHunk:     adapt(lightTheme.DiffHunk, darkTheme.DiffHunk),
+	SyntaxString: adapt(lightTheme.SyntaxString, darkTheme.SyntaxString),
+	SyntaxNumber: adapt(lightTheme.SyntaxNumber, darkTheme.SyntaxNumber),
+}
+
+func adapt(light, dark lipgloss.TerminalColor) lipgloss.AdaptiveColor {
+	return lipgloss.AdaptiveColor{Light: string(light.(lipgloss.Color)), Dark: string(dark.(lipgloss.Color))}
+}
+
+// theme is the theme views draw with, set by UseTheme.
+var theme = adaptiveTheme
+
+// UseTheme has the TUI draw with t. It's meant to be called once, before
+// the program starts.
+func UseTheme(t Theme) {
+	theme = t
+	diffHunkStyle = lipgloss.NewStyle().Foreground(t.DiffHunk)
+	diffAddStyle = lipgloss.NewStyle().Foreground(t.Success)
+	diffRemoveStyle = lipgloss.NewStyle().Foreground(t.Error)
+	syntaxKeyword = lipgloss.NewStyle().Foreground(t.Accent)
+	syntaxString = lipgloss.NewStyle().Foreground(t.SyntaxString)
+	syntaxComment = lipgloss.NewStyle().Foreground(t.Muted)
+	syntaxNumber = lipgloss.NewStyle().Foreground(t.SyntaxNumber)
+}
+
+// hexColor matches the hex colors a theme color can be set to.
+var hexColor = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// ThemeFor returns the theme cfg picks, with its color overrides applied.
+func ThemeFor(cfg *config.Config) (Theme, error) {
+	var t Theme
+	switch cfg.Theme {
+	case "":
+		t = adaptiveTheme
+	case config.ThemeDark:
+		t = darkTheme
+	case config.ThemeLight:
+		t = lightTheme
+	case config.ThemeHighContrast:
+		t = highContrastTheme
+	default:
+		return Theme{}, fmt.Errorf("unknown theme %q; use one of %s", cfg.Theme,
+			strings.Join([]string{config.ThemeDark, config.ThemeLight, config.ThemeHighContrast}, ", "))
+	}
+
+	roles := map[string]*lipgloss.TerminalColor{
+		"accent":        &t.Accent,
+		"muted":         &t.Muted,
+		"warning":       &t.Warning,
+		"error":         &t.Error,
+		"success":       &t.Success,
+		"command":       &t.Command,
+		"border":        &t.Border,
+		"diff_hunk":     &t.DiffHunk,
+		"syntax_string": &t.SyntaxString,
+		"syntax_number": &t.SyntaxNumber,
+	}
+	// Sorted, so the same mistake is always the one reported.
+	names := make([]string, 0, len(cfg.Colors))
+	for name := range cfg.Colors {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		value := cfg.Colors[name]
+		color, ok := roles[name]
+		if !ok {
+			known := make([]string, 0, len(roles))
+			for role := range roles {
+				known = append(known, role)
+			}
+			slices.Sort(known)
+			return Theme{}, fmt.Errorf("unknown color %q; use one of %s", name, strings.Join(known, ", "))
+		}
+		if n, err := strconv.Atoi(value); !hexColor.MatchString(value) && (err != nil || n < 0 || n > 255) {
+			return Theme{}, fmt.Errorf("colors.%s is %q; use a hex color like #ff5f87 or an ANSI color number from 0 to 255", name, value)
+		}
+		*color = lipgloss.Color(value)
+	}
+	return t, nil
+}
@@ -0,0 +1,50 @@
+package tui
+
+import (
+	"context"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/arpxspace/smartcommit/internal/config"
+	"github.com/arpxspace/smartcommit/internal/logging"
+	"github.com/arpxspace/smartcommit/internal/network"
+	"github.com/arpxspace/smartcommit/internal/update"
+)
+
+// updateAvailableMsg reports a newer release of smartcommit.
+type updateAvailableMsg struct {
+	Version string
+}
+
+// checkUpdate starts looking for a newer release in the background, once
+// per run and unless the config opts out, so the welcome screen can mention
+// it without waiting.
+func (m Model) checkUpdate() (tea.Model, tea.Cmd) {
+	if m.UpdateChecked || m.Config == nil || m.Config.DisableUpdateCheck {
+		return m, nil
+	}
+	m.UpdateChecked = true
+	return m, func() tea.Msg {
+		dir, err := config.DataDir()
+		if err != nil {
+			return nil
+		}
+		client, err := network.Client(m.Config.Network, 0)
+		if err != nil {
+			return nil
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		version, err := update.Available(ctx, client, dir)
+		if err != nil {
+			// Offline or rate limited; not worth interrupting a commit for.
+			logging.Warn("update check failed", "err", err)
+			return nil
+		}
+		if version == "" {
+			return nil
+		}
+		return updateAvailableMsg{Version: version}
+	}
+}
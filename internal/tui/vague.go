@@ -0,0 +1,142 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/arpxspace/smartcommit/internal/ai"
+	"github.com/arpxspace/smartcommit/internal/guard"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+type sentenceRewrittenMsg struct {
+	Original string
+	Sentence string
+	Err      error
+}
+
+// vague finds the configured vague words in the reviewed message.
+func (m Model) vague() []guard.Span {
+	return guard.Vague(strings.TrimSpace(m.CommitMsg), m.Config.Vague())
+}
+
+// flaggedSentence is the first sentence of the reviewed message with a
+// vague word in it, or "" if there is none.
+func (m Model) flaggedSentence() string {
+	spans := m.vague()
+	if len(spans) == 0 {
+		return ""
+	}
+	message := strings.TrimSpace(m.CommitMsg)
+	s := guard.Sentence(message, spans[0].Start)
+	return message[s.Start:s.End]
+}
+
+// rewriteVague asks the provider to make the first flagged sentence
+// specific, leaving the rest of the message as it is.
+func (m Model) rewriteVague() (tea.Model, tea.Cmd) {
+	sentence := m.flaggedSentence()
+	if sentence == "" || m.AIClient == nil {
+		return m, nil
+	}
+	m.Rewriting = true
+	m.RewriteErr = nil
+	return m, rewriteSentenceCmd(m.Requests, m.AIClient, m.Diff, m.CommitMsg, sentence)
+}
+
+func (m Model) updateRewritten(msg sentenceRewrittenMsg) (tea.Model, tea.Cmd) {
+	if errors.Is(msg.Err, context.Canceled) {
+		return m, nil
+	}
+	m.Rewriting = false
+	if msg.Err != nil {
+		m.RewriteErr = msg.Err
+		return m, nil
+	}
+	rewritten := strings.Join(strings.Fields(msg.Sentence), " ")
+	if rewritten == "" || !strings.Contains(m.CommitMsg, msg.Original) {
+		// Edited in the meantime, or nothing came back.
+		return m, nil
+	}
+	m.CommitMsg = strings.Replace(m.CommitMsg, msg.Original, rewritten, 1)
+	if !m.Config.DisableGuardrails {
+		// The sentence is one line now; rewrap its paragraph.
+		m.CommitMsg, _ = guard.Fix(m.CommitMsg)
+	}
+	return m.enterReview()
+}
+
+func rewriteSentenceCmd(ctx context.Context, client ai.Provider, diff, message, sentence string) tea.Cmd {
+	return func() tea.Msg {
+		rewritten, err := client.RewriteSentence(ctx, diff, message, strings.Join(strings.Fields(sentence), " "))
+		return sentenceRewrittenMsg{Original: sentence, Sentence: rewritten, Err: err}
+	}
+}
+
+// viewVague lists the vague words in the reviewed message, with the
+// progress or failure of a rewrite.
+func (m Model) viewVague() string {
+	warnStyle := lipgloss.NewStyle().Foreground(theme.Warning)
+	infoStyle := lipgloss.NewStyle().Foreground(theme.Muted)
+	errorStyle := lipgloss.NewStyle().Foreground(theme.Error)
+
+	spans := m.vague()
+	if len(spans) == 0 && m.RewriteErr == nil {
+		return ""
+	}
+	message := strings.TrimSpace(m.CommitMsg)
+	var words []string
+	for _, s := range spans {
+		word := strings.ToLower(strings.Join(strings.Fields(message[s.Start:s.End]), " "))
+		if !slices.Contains(words, strconv.Quote(word)) {
+			words = append(words, strconv.Quote(word))
+		}
+	}
+	var b strings.Builder
+	if len(words) > 0 {
+		b.WriteString(" " + warnStyle.Render("! vague: "+strings.Join(words, ", ")) + "\n")
+	}
+	switch {
+	case m.Rewriting:
+		b.WriteString(" " + m.Spinner.View() + " Making the sentence specific... " + infoStyle.Render(cancelHint) + "\n")
+	case m.RewriteErr != nil:
+		b.WriteString(" " + errorStyle.Render("Could not rewrite the sentence: "+m.RewriteErr.Error()) + "\n")
+	case len(words) > 0:
+		sentence := strings.Join(strings.Fields(m.flaggedSentence()), " ")
+		if r := []rune(sentence); len(r) > 60 {
+			sentence = string(r[:59]) + "…"
+		}
+		b.WriteString(" " + infoStyle.Render(fmt.Sprintf("Press %s to have the AI make %q specific.", keyName(keys.Specific), sentence)) + "\n")
+	}
+	return b.String()
+}
+
+// markVague renders text, which starts at offset in the message spans are
+// found in, with the spans marked and the rest passed through render.
+func markVague(text string, offset int, spans []guard.Span, render func(string) string) string {
+	markStyle := lipgloss.NewStyle().Foreground(theme.Warning).Underline(true)
+	var b strings.Builder
+	at := 0
+	for _, s := range spans {
+		start, end := s.Start-offset, s.End-offset
+		if end <= at || start >= len(text) {
+			continue
+		}
+		start, end = max(start, at), min(end, len(text))
+		if start > at {
+			b.WriteString(render(text[at:start]))
+		}
+		b.WriteString(markStyle.Render(text[start:end]))
+		at = end
+	}
+	if at < len(text) {
+		b.WriteString(render(text[at:]))
+	}
+	return b.String()
+}
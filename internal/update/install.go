@@ -0,0 +1,175 @@
+package update
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/arpxspace/smartcommit/internal/github"
+)
+
+// Assets every release carries beside the binaries: ChecksumsName lists
+// their SHA-256 sums as sha256sum prints them, and SignatureName is the
+// base64 ed25519 signature of ChecksumsName by the release key.
+const (
+	ChecksumsName = "checksums.txt"
+	SignatureName = "checksums.txt.sig"
+)
+
+// PublicKey is the base64 ed25519 key releases are signed with, set at
+// build time like Version; scripts/release sets it from the key it signs
+// with. When it's set, an update whose checksums aren't signed by it is
+// refused; without it, only the checksum is verified.
+var PublicKey = ""
+
+// maxDownload bounds what is read of any one asset.
+const maxDownload = 256 << 20
+
+// Download fetches this platform's binary from release with client and
+// verifies it against the release's checksums, and their signature when
+// PublicKey is set.
+func Download(ctx context.Context, client *http.Client, release *github.Release) ([]byte, error) {
+	name := AssetName()
+	asset, ok := release.Asset(name)
+	if !ok {
+		return nil, fmt.Errorf("%s has no build for %s/%s", release.Tag, runtime.GOOS, runtime.GOARCH)
+	}
+	sums, ok := release.Asset(ChecksumsName)
+	if !ok {
+		return nil, fmt.Errorf("%s has no %s to verify the download with", release.Tag, ChecksumsName)
+	}
+	checksums, err := fetch(ctx, client, sums.URL)
+	if err != nil {
+		return nil, err
+	}
+	if PublicKey != "" {
+		sig, ok := release.Asset(SignatureName)
+		if !ok {
+			return nil, fmt.Errorf("%s has no %s; refusing an unsigned update", release.Tag, SignatureName)
+		}
+		signature, err := fetch(ctx, client, sig.URL)
+		if err != nil {
+			return nil, err
+		}
+		if err := verifySignature(checksums, signature); err != nil {
+			return nil, err
+		}
+	}
+	want, err := checksum(checksums, name)
+	if err != nil {
+		return nil, err
+	}
+	binary, err := fetch(ctx, client, asset.URL)
+	if err != nil {
+		return nil, err
+	}
+	if got := sha256.Sum256(binary); hex.EncodeToString(got[:]) != want {
+		return nil, fmt.Errorf("checksum mismatch for %s; the download may be corrupt or tampered with", name)
+	}
+	return binary, nil
+}
+
+// fetch downloads url with client.
+func fetch(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("failed to download %s: %s", url, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxDownload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	return data, nil
+}
+
+// checksum returns the SHA-256 sum listed for name in checksums.
+func checksum(checksums []byte, name string) (string, error) {
+	for _, line := range strings.Split(string(checksums), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	return "", fmt.Errorf("%s lists no checksum for %s", ChecksumsName, name)
+}
+
+// verifySignature checks signature over checksums against PublicKey.
+func verifySignature(checksums, signature []byte) error {
+	pub, err := base64.StdEncoding.DecodeString(PublicKey)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return fmt.Errorf("invalid release key")
+	}
+	sig, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(signature)))
+	if err != nil || !ed25519.Verify(pub, checksums, sig) {
+		return fmt.Errorf("%s isn't signed by the release key; refusing the update", ChecksumsName)
+	}
+	return nil
+}
+
+// Install replaces the running binary with binary and returns its path.
+// The new one is written beside it first and renamed over it, so a failure
+// leaves the old one in place.
+func Install(binary []byte) (string, error) {
+	path, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	if path, err = filepath.EvalSymlinks(path); err != nil {
+		return "", err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, ".smartcommit-update-*")
+	if err != nil {
+		return "", fmt.Errorf("can't write to %s: %w", dir, err)
+	}
+	defer os.Remove(tmp.Name()) // Ignore error, it's been renamed on success
+	if _, err := tmp.Write(binary); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Chmod(tmp.Name(), info.Mode().Perm()|0o111); err != nil {
+		return "", err
+	}
+	if runtime.GOOS == "windows" {
+		// A running executable can't be replaced, but it can be renamed.
+		old := path + ".old"
+		os.Remove(old) // Ignore error, left by an earlier update if at all
+		if err := os.Rename(path, old); err != nil {
+			return "", err
+		}
+		if err := os.Rename(tmp.Name(), path); err != nil {
+			os.Rename(old, path) // Ignore error, best effort to restore
+			return "", err
+		}
+		return path, nil
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,139 @@
+// Package update finds newer releases of smartcommit on GitHub and installs
+// them over the running binary, once their checksum, and signature when the
+// build carries a release key, check out.
+package update
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/arpxspace/smartcommit/internal/github"
+)
+
+// Repo is where smartcommit is released.
+const Repo = "arpxspace/smartcommit"
+
+// Version is the release this binary was built from, set at build time with
+//
+//	-ldflags "-X github.com/arpxspace/smartcommit/internal/update.Version=v1.2.3"
+//
+// Builds without it fall back to the module version go install records.
+var Version = ""
+
+// Current returns the version of the running binary, or "" for a
+// development build, which is never told to update.
+func Current() string {
+	if Version != "" {
+		return Version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return ""
+}
+
+// AssetName is the release asset holding the binary for this platform, like
+// "smartcommit_linux_amd64".
+func AssetName() string {
+	return AssetNameFor(runtime.GOOS, runtime.GOARCH)
+}
+
+// AssetNameFor is the release asset holding the binary for goos and goarch.
+func AssetNameFor(goos, goarch string) string {
+	name := "smartcommit_" + goos + "_" + goarch
+	if goos == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// Latest fetches the newest release, asking GitHub with client.
+func Latest(ctx context.Context, client *http.Client) (*github.Release, error) {
+	return github.NewClient("", "", client).LatestRelease(ctx, Repo)
+}
+
+// Newer reports whether version latest is later than current. Versions
+// are compared by their dot-separated numbers, so "v1.10.0" is later than
+// "v1.9.2"; a prerelease suffix, as in "v1.2.0-rc.1", sorts before its
+// release.
+func Newer(latest, current string) bool {
+	l, lpre := parseVersion(latest)
+	c, cpre := parseVersion(current)
+	for i := 0; i < max(len(l), len(c)); i++ {
+		var a, b int
+		if i < len(l) {
+			a = l[i]
+		}
+		if i < len(c) {
+			b = c[i]
+		}
+		if a != b {
+			return a > b
+		}
+	}
+	return cpre && !lpre
+}
+
+// parseVersion returns the numbers of a version like "v1.2.3-rc.1" and
+// whether it's a prerelease.
+func parseVersion(v string) ([]int, bool) {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	v, pre, _ := strings.Cut(v, "-")
+	v, _, _ = strings.Cut(v, "+")
+	var nums []int
+	for _, part := range strings.Split(v, ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			break
+		}
+		nums = append(nums, n)
+	}
+	return nums, pre != ""
+}
+
+// checkInterval is how long the latest version found is remembered before
+// GitHub is asked again.
+const checkInterval = 24 * time.Hour
+
+// checked is what the last check found, kept in the data directory.
+type checked struct {
+	At     time.Time `json:"at"`
+	Latest string    `json:"latest"`
+}
+
+// Available returns the latest version if it's newer than the running one,
+// or "". GitHub is asked with client at most once a day; in between, the
+// version it gave is remembered in dataDir.
+func Available(ctx context.Context, client *http.Client, dataDir string) (string, error) {
+	current := Current()
+	if current == "" {
+		return "", nil
+	}
+	path := filepath.Join(dataDir, "update.json")
+	var last checked
+	if data, err := os.ReadFile(path); err == nil {
+		json.Unmarshal(data, &last) // Ignore error, check again
+	}
+	if time.Since(last.At) > checkInterval {
+		release, err := Latest(ctx, client)
+		if err != nil {
+			return "", err
+		}
+		last = checked{At: time.Now(), Latest: release.Tag}
+		if data, err := json.Marshal(last); err == nil {
+			os.WriteFile(path, data, 0o600) // Ignore error, check again next time
+		}
+	}
+	if !Newer(last.Latest, current) {
+		return "", nil
+	}
+	return last.Latest, nil
+}
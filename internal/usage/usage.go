@@ -0,0 +1,207 @@
+package usage
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/arpxspace/smartcommit/internal/ai"
+	"github.com/arpxspace/smartcommit/internal/config"
+	"github.com/arpxspace/smartcommit/internal/store"
+)
+
+// DefaultPrices are list prices in USD per million tokens for the models
+// smartcommit is commonly used with. Add or override entries with "prices"
+// in the config.
+var DefaultPrices = map[string]config.Price{
+	"gpt-4o":                 {Prompt: 2.50, Completion: 10.00},
+	"gpt-4o-2024-08-06":      {Prompt: 2.50, Completion: 10.00},
+	"gpt-4o-mini":            {Prompt: 0.15, Completion: 0.60},
+	"gpt-4o-mini-2024-07-18": {Prompt: 0.15, Completion: 0.60},
+	"gpt-4.1":                {Prompt: 2.00, Completion: 8.00},
+	"gpt-4.1-mini":           {Prompt: 0.40, Completion: 1.60},
+	"gpt-4.1-nano":           {Prompt: 0.10, Completion: 0.40},
+	"o1":                     {Prompt: 15.00, Completion: 60.00},
+	"o3":                     {Prompt: 2.00, Completion: 8.00},
+	"o3-mini":                {Prompt: 1.10, Completion: 4.40},
+	"o4-mini":                {Prompt: 1.10, Completion: 4.40},
+}
+
+// BudgetWarningRatio is the fraction of the monthly budget at which
+// smartcommit starts warning.
+const BudgetWarningRatio = 0.8
+
+// Cost estimates what tokens cost on a model, reporting false when the
+// model's price isn't known. Local Ollama models and the mock provider are
+// free, as are custom endpoints unless a price is configured for the model.
+func Cost(cfg *config.Config, provider, model string, promptTokens, completionTokens int) (float64, bool) {
+	if p := config.ProviderType(provider); p == config.ProviderOllama || p == config.ProviderMock {
+		return 0, true
+	}
+	price, ok := cfg.Prices[model]
+	if !ok {
+		price, ok = DefaultPrices[model]
+	}
+	if !ok {
+		return 0, config.ProviderType(provider) == config.ProviderCustom
+	}
+	return (float64(promptTokens)*price.Prompt + float64(completionTokens)*price.Completion) / 1e6, true
+}
+
+// Record adds the usage of one run of command to the local store. Nothing is
+// recorded when the store is disabled or no requests were made.
+func Record(cfg *config.Config, command string, client ai.Provider, u ai.Usage) error {
+	if cfg.DisableStore || u.Requests == 0 {
+		return nil
+	}
+	s, err := store.Open()
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	return s.AddUsage(store.Usage{
+		RecordedAt:       time.Now(),
+		Command:          command,
+		Provider:         string(cfg.Provider),
+		Model:            client.Model(),
+		Requests:         u.Requests,
+		PromptTokens:     u.PromptTokens,
+		CompletionTokens: u.CompletionTokens,
+	})
+}
+
+// Row totals the usage of one provider and model.
+type Row struct {
+	Provider         string
+	Model            string
+	Runs             int
+	Requests         int
+	PromptTokens     int
+	CompletionTokens int
+	Cost             float64
+	// Priced is false when the cost of some usage couldn't be estimated.
+	Priced bool
+}
+
+// Summary is the usage for one calendar month.
+type Summary struct {
+	Month time.Time
+	Rows  []Row
+	Total Row
+}
+
+// MonthStart returns the first instant of t's month.
+func MonthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+}
+
+// ForMonth totals the recorded usage for the month containing t.
+func ForMonth(cfg *config.Config, t time.Time) (*Summary, error) {
+	from := MonthStart(t)
+	s, err := store.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+	records, err := s.UsageBetween(from, from.AddDate(0, 1, 0))
+	if err != nil {
+		return nil, err
+	}
+	return summarize(cfg, from, records), nil
+}
+
+// ByMonth totals the usage recorded in the months from the one containing
+// since up to now, oldest first. Months without usage are left out.
+func ByMonth(cfg *config.Config, since time.Time) ([]*Summary, error) {
+	s, err := store.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+	records, err := s.UsageBetween(MonthStart(since), time.Now().AddDate(0, 1, 0))
+	if err != nil {
+		return nil, err
+	}
+
+	var out []*Summary
+	for len(records) > 0 {
+		month := MonthStart(records[0].RecordedAt.Local())
+		n := 1
+		for n < len(records) && MonthStart(records[n].RecordedAt.Local()).Equal(month) {
+			n++
+		}
+		out = append(out, summarize(cfg, month, records[:n]))
+		records = records[n:]
+	}
+	return out, nil
+}
+
+// summarize totals records by provider and model, most expensive first.
+func summarize(cfg *config.Config, month time.Time, records []store.Usage) *Summary {
+	sum := &Summary{Month: month, Total: Row{Priced: true}}
+	rows := map[[2]string]*Row{}
+	for _, u := range records {
+		k := [2]string{u.Provider, u.Model}
+		row, ok := rows[k]
+		if !ok {
+			row = &Row{Provider: u.Provider, Model: u.Model, Priced: true}
+			rows[k] = row
+		}
+		cost, priced := Cost(cfg, u.Provider, u.Model, u.PromptTokens, u.CompletionTokens)
+		for _, r := range []*Row{row, &sum.Total} {
+			r.Runs++
+			r.Requests += u.Requests
+			r.PromptTokens += u.PromptTokens
+			r.CompletionTokens += u.CompletionTokens
+			r.Cost += cost
+			r.Priced = r.Priced && priced
+		}
+	}
+	for _, row := range rows {
+		sum.Rows = append(sum.Rows, *row)
+	}
+	sort.Slice(sum.Rows, func(i, j int) bool { return sum.Rows[i].Cost > sum.Rows[j].Cost })
+	return sum
+}
+
+// Run describes the usage of one run for the end of it, e.g. "3 requests,
+// 5120 prompt + 310 completion tokens, about $0.0031".
+func Run(cfg *config.Config, client ai.Provider) string {
+	u := client.Usage()
+	if u.Requests == 0 {
+		return ""
+	}
+	s := fmt.Sprintf("%d requests, %d prompt + %d completion tokens", u.Requests, u.PromptTokens, u.CompletionTokens)
+	if cost, ok := Cost(cfg, string(cfg.Provider), client.Model(), u.PromptTokens, u.CompletionTokens); ok {
+		s += fmt.Sprintf(", about $%.4f", cost)
+	}
+	return s
+}
+
+// BudgetWarning returns a warning when the month's estimated spend has reached
+// BudgetWarningRatio of budget, or "" when there's no budget or it's fine.
+func (s *Summary) BudgetWarning(budget float64) string {
+	if budget <= 0 {
+		return ""
+	}
+	switch spent := s.Total.Cost; {
+	case spent >= budget:
+		return fmt.Sprintf("Monthly budget exceeded: $%.2f of $%.2f spent in %s", spent, budget, s.Month.Format("January"))
+	case spent >= BudgetWarningRatio*budget:
+		return fmt.Sprintf("Approaching monthly budget: $%.2f of $%.2f spent in %s", spent, budget, s.Month.Format("January"))
+	}
+	return ""
+}
+
+// Warning returns the budget warning for the current month, if any. Errors
+// reading the store are ignored since the warning is only advisory.
+func Warning(cfg *config.Config) string {
+	if cfg.MonthlyBudget <= 0 || cfg.DisableStore {
+		return ""
+	}
+	sum, err := ForMonth(cfg, time.Now())
+	if err != nil {
+		return ""
+	}
+	return sum.BudgetWarning(cfg.MonthlyBudget)
+}
@@ -1,18 +1,11 @@
 package main
 
 import (
-	"fmt"
 	"os"
 
-	"github.com/arpxspace/smartcommit/internal/tui"
-
-	tea "github.com/charmbracelet/bubbletea"
+	"github.com/arpxspace/smartcommit/internal/cli"
 )
 
 func main() {
-	p := tea.NewProgram(tui.NewModel())
-	if _, err := p.Run(); err != nil {
-		fmt.Printf("Alas, there's been an error: %v", err)
-		os.Exit(1)
-	}
+	os.Exit(cli.Run(os.Args[1:]))
 }
@@ -0,0 +1,160 @@
+// Command release builds the assets of a smartcommit release into a
+// directory, ready to upload to its GitHub release:
+//
+//	go run ./scripts/release -key ~/smartcommit-release.key v1.2.3
+//
+// It cross-compiles a smartcommit_<os>_<arch> binary for each target with
+// the version and the release key's public half stamped in, lists their
+// SHA-256 sums in checksums.txt, and signs that with the release key into
+// checksums.txt.sig. The binaries then refuse any later update that isn't
+// signed by the same key. The key file holds a base64 ed25519 private key,
+// which -keygen writes; keep it out of the repository, and use the same
+// one for every release.
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/arpxspace/smartcommit/internal/update"
+)
+
+// defaultTargets are the platforms a release is built for.
+const defaultTargets = "darwin/amd64,darwin/arm64,linux/amd64,linux/arm64,windows/amd64,windows/arm64"
+
+func main() {
+	key := flag.String("key", "", "file holding the base64 ed25519 release key")
+	keygen := flag.Bool("keygen", false, "write a new release key to -key")
+	out := flag.String("out", "dist", "directory to write the assets to")
+	targets := flag.String("targets", defaultTargets, "comma-separated os/arch pairs to build for")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: go run ./scripts/release -key <file> [-out dir] [-targets os/arch,...] <version>")
+		fmt.Fprintln(os.Stderr, "       go run ./scripts/release -key <file> -keygen")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *key == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *keygen {
+		if err := generateKey(*key); err != nil {
+			fail(err)
+		}
+		return
+	}
+	if flag.NArg() != 1 || !strings.HasPrefix(flag.Arg(0), "v") {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := release(flag.Arg(0), *key, *out, strings.Split(*targets, ",")); err != nil {
+		fail(err)
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "release:", err)
+	os.Exit(1)
+}
+
+// generateKey writes a new release key to path, refusing to overwrite one.
+func generateKey(path string) error {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(f, base64.StdEncoding.EncodeToString(priv)); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %s, with public key %s.\n", path, base64.StdEncoding.EncodeToString(pub))
+	return nil
+}
+
+// readKey reads the release key from path.
+func readKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(data)))
+	if err != nil || len(key) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("%s doesn't hold a base64 ed25519 private key", path)
+	}
+	return ed25519.PrivateKey(key), nil
+}
+
+// release builds version for targets into out, with its checksums and
+// their signature by the key in keyPath.
+func release(version, keyPath, out string, targets []string) error {
+	priv, err := readKey(keyPath)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(out, 0o755); err != nil {
+		return err
+	}
+	pub := base64.StdEncoding.EncodeToString(priv.Public().(ed25519.PublicKey))
+	var checksums strings.Builder
+	for _, target := range targets {
+		goos, goarch, ok := strings.Cut(strings.TrimSpace(target), "/")
+		if !ok {
+			return fmt.Errorf("invalid target %q, want os/arch", target)
+		}
+		name := update.AssetNameFor(goos, goarch)
+		path := filepath.Join(out, name)
+		fmt.Fprintf(os.Stderr, "Building %s...\n", name)
+		if err := build(version, pub, goos, goarch, path); err != nil {
+			return err
+		}
+		binary, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(binary)
+		fmt.Fprintf(&checksums, "%s  %s\n", hex.EncodeToString(sum[:]), name)
+	}
+
+	sums := []byte(checksums.String())
+	signature := []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(priv, sums)) + "\n")
+	if err := os.WriteFile(filepath.Join(out, update.ChecksumsName), sums, 0o644); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(out, update.SignatureName), signature, 0o644); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote the %s assets to %s. Upload them with\n\n\tgh release create %s %s\n", version, out, version, filepath.Join(out, "*"))
+	return nil
+}
+
+// build compiles smartcommit for goos and goarch into path, stamped with
+// version and the release key pub.
+func build(version, pub, goos, goarch, path string) error {
+	const pkg = "github.com/arpxspace/smartcommit/internal/update"
+	ldflags := fmt.Sprintf("-s -w -X %s.Version=%s -X %s.PublicKey=%s", pkg, version, pkg, pub)
+	cmd := exec.Command("go", "build", "-trimpath", "-ldflags", ldflags, "-o", path, "github.com/arpxspace/smartcommit")
+	cmd.Env = append(os.Environ(), "GOOS="+goos, "GOARCH="+goarch, "CGO_ENABLED=0")
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to build for %s/%s: %w", goos, goarch, err)
+	}
+	return nil
+}